@@ -4,8 +4,11 @@ import (
 	"encoding/json"
 	"errors"
 	"log/slog"
+	"math/rand/v2"
 	"net/http"
 	"net/url"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 
@@ -14,6 +17,7 @@ import (
 
 	"fusionaly/internal/config"
 	"fusionaly/internal/events"
+	"fusionaly/internal/http/middleware"
 	"fusionaly/internal/websites"
 )
 
@@ -21,6 +25,7 @@ const (
 	msgEventAdded     = "Event added successfully"
 	errInvalidRequest = "Invalid request"
 	errInvalidOrigin  = "Invalid origin"
+	errDatabaseBusy   = "Database is busy, please retry later"
 )
 
 type CreateEventParams struct {
@@ -32,6 +37,24 @@ type CreateEventParams struct {
 	EventKey      string                 `json:"eventKey"`
 	EventMetadata map[string]interface{} `json:"eventMetadata"`
 	UserAgent     string                 `json:"userAgent"`
+	EventID       string                 `json:"event_id"` // Optional client-generated idempotency key
+	ScreenWidth   int                    `json:"screen_width"`
+	ScreenHeight  int                    `json:"screen_height"`
+	Properties    map[string]interface{} `json:"properties"`    // Custom dimensions, e.g. {"account_tier": "pro"}
+	IsErrorPage   bool                   `json:"is_error_page"` // The page is a not-found page; implies status_code 404
+	StatusCode    int                    `json:"status_code"`   // HTTP status of the page; absent for a normal page
+}
+
+// pageStatusCode returns the status reported for the page, 404 when it's only
+// flagged as an error page, and 0 (a normal page) when neither was sent
+func (p *CreateEventParams) pageStatusCode() int {
+	if p.StatusCode != 0 {
+		return p.StatusCode
+	}
+	if p.IsErrorPage {
+		return http.StatusNotFound
+	}
+	return 0
 }
 
 func CreateEventPublicAPIHandler(ctx *cartridge.Context) error {
@@ -53,19 +76,46 @@ func CreateEventPublicAPIHandler(ctx *cartridge.Context) error {
 		IPAddress:       getClientIP(ctx.Ctx),
 		UserAgent:       params.UserAgent,
 		SecChUa:         ctx.Get("Sec-CH-UA"),
+		AcceptLanguage:  ctx.Get("Accept-Language"),
 		ReferrerURL:     params.Referrer,
 		EventType:       params.EventType,
 		CustomEventName: params.EventKey,
 		CustomEventMeta: metadataFromMap(params.EventMetadata),
 		Timestamp:       params.Timestamp,
 		RawUrl:          params.URL,
+		UserID:          params.UserID,
+		DoNotTrack:      ctx.Get("DNT") == "1",
+		EventID:         params.EventID,
+		ScreenWidth:     params.ScreenWidth,
+		ScreenHeight:    params.ScreenHeight,
+		Properties:      propertiesFromMap(params.Properties),
+		StatusCode:      params.pageStatusCode(),
 	}
 
 	// Pass dbManager directly to CollectEvent
 	if err := events.CollectEvent(ctx.DBManager, ctx.Logger, input); err != nil {
 		ctx.Logger.Error("Failed to collect event", slog.Any("error", err))
-		if strings.Contains(err.Error(), "database is locked") || strings.Contains(err.Error(), "busy") {
-			return ctx.Status(599).JSON(fiber.Map{}) // custom status code
+		if errors.Is(err, events.ErrDatabaseBusy) {
+			return ctx.Status(http.StatusServiceUnavailable).JSON(databaseBusyResponse(ctx.Ctx, fiber.Map{}))
+		}
+
+		if errors.Is(err, events.ErrEventMetaTooLarge) {
+			return ctx.Status(http.StatusRequestEntityTooLarge).JSON(fiber.Map{
+				"error": err.Error(),
+				"code":  "EVENT_META_TOO_LARGE",
+			})
+		}
+		if errors.Is(err, events.ErrInvalidEventMeta) {
+			return ctx.Status(http.StatusBadRequest).JSON(fiber.Map{
+				"error": err.Error(),
+				"code":  "INVALID_EVENT_META",
+			})
+		}
+		if errors.Is(err, events.ErrTimestampOutOfRange) {
+			return ctx.Status(http.StatusBadRequest).JSON(fiber.Map{
+				"error": err.Error(),
+				"code":  "TIMESTAMP_OUT_OF_RANGE",
+			})
 		}
 
 		// Check for website not found error using the custom error type
@@ -90,6 +140,135 @@ func CreateEventPublicAPIHandler(ctx *cartridge.Context) error {
 	})
 }
 
+// BatchEventResult reports the outcome of a single event in a batch request
+type BatchEventResult struct {
+	Index  int    `json:"index"`
+	Status int    `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// CreateEventsBatchPublicAPIHandler accepts a JSON array of events so clients that
+// buffer events can send them in one request. Each event is validated on its own;
+// the response lists a result per index and failures don't affect other events.
+func CreateEventsBatchPublicAPIHandler(ctx *cartridge.Context) error {
+	var batch []CreateEventParams
+	if err := json.Unmarshal(ctx.Body(), &batch); err != nil {
+		ctx.Logger.Debug("Failed to parse batch request", slog.Any("error", err))
+		return handleError(ctx.Ctx, fiber.NewError(http.StatusBadRequest, errInvalidRequest))
+	}
+
+	maxBatchSize := config.GetConfig().MaxEventBatchSize
+	if len(batch) > maxBatchSize {
+		return ctx.Status(http.StatusRequestEntityTooLarge).JSON(fiber.Map{
+			"error":          "Too many events in batch",
+			"code":           "BATCH_TOO_LARGE",
+			"max_batch_size": maxBatchSize,
+		})
+	}
+
+	if err := validateOrigin(ctx.Ctx, ctx.DBManager, ctx.Logger); err != nil {
+		return handleError(ctx.Ctx, err)
+	}
+
+	userAgentHeader := ctx.Get("User-Agent")
+	if forwardedUA := ctx.Get("X-Forwarded-User-Agent"); forwardedUA != "" {
+		userAgentHeader = forwardedUA
+	}
+
+	ipAddress := getClientIP(ctx.Ctx)
+	inputs := make([]*events.CollectEventInput, len(batch))
+	for i, params := range batch {
+		userAgent := params.UserAgent
+		if userAgent == "" {
+			userAgent = userAgentHeader
+		}
+		inputs[i] = &events.CollectEventInput{
+			IPAddress:       ipAddress,
+			UserAgent:       userAgent,
+			SecChUa:         ctx.Get("Sec-CH-UA"),
+			AcceptLanguage:  ctx.Get("Accept-Language"),
+			ReferrerURL:     params.Referrer,
+			EventType:       params.EventType,
+			CustomEventName: params.EventKey,
+			CustomEventMeta: metadataFromMap(params.EventMetadata),
+			Timestamp:       params.Timestamp,
+			RawUrl:          params.URL,
+			UserID:          params.UserID,
+			DoNotTrack:      ctx.Get("DNT") == "1",
+			EventID:         params.EventID,
+			ScreenWidth:     params.ScreenWidth,
+			ScreenHeight:    params.ScreenHeight,
+			Properties:      propertiesFromMap(params.Properties),
+			StatusCode:      params.pageStatusCode(),
+		}
+	}
+
+	errs := events.CollectEvents(ctx.DBManager, ctx.Logger, inputs)
+
+	results := make([]BatchEventResult, len(errs))
+	failed := 0
+	busy := false
+	for i, err := range errs {
+		results[i] = BatchEventResult{Index: i, Status: http.StatusAccepted}
+		if err == nil {
+			continue
+		}
+		failed++
+
+		var websiteNotFoundErr *websites.WebsiteNotFoundError
+		switch {
+		case errors.Is(err, events.ErrDatabaseBusy):
+			busy = true
+			results[i].Status = http.StatusServiceUnavailable
+			results[i].Error = errDatabaseBusy
+		case errors.Is(err, events.ErrEventMetaTooLarge):
+			results[i].Status = http.StatusRequestEntityTooLarge
+			results[i].Error = err.Error()
+		case errors.Is(err, events.ErrInvalidEventMeta), errors.Is(err, events.ErrTimestampOutOfRange):
+			results[i].Status = http.StatusBadRequest
+			results[i].Error = err.Error()
+		case errors.As(err, &websiteNotFoundErr):
+			results[i].Status = http.StatusBadRequest
+			results[i].Error = "Website not found - please register your domain first"
+		case strings.Contains(err.Error(), "failed to parse URL"):
+			results[i].Status = http.StatusBadRequest
+			results[i].Error = errInvalidRequest
+		default:
+			results[i].Status = http.StatusInternalServerError
+			results[i].Error = "Failed to collect event"
+		}
+	}
+
+	ctx.Logger.Info("Collected event batch",
+		slog.Int("count", len(batch)),
+		slog.Int("failed", failed))
+
+	response := fiber.Map{
+		"accepted": len(batch) - failed,
+		"failed":   failed,
+		"results":  results,
+	}
+	// The batch is stored in one write, so contention fails all of it
+	if busy {
+		return ctx.Status(http.StatusServiceUnavailable).JSON(databaseBusyResponse(ctx.Ctx, response))
+	}
+	return ctx.Status(http.StatusAccepted).JSON(response)
+}
+
+// databaseBusyResponse sets a Retry-After header and adds the DATABASE_BUSY
+// error to body. The delay is jittered so clients turned away together don't
+// retry together; it's also in the body since cross-origin scripts can't read
+// Retry-After.
+func databaseBusyResponse(c *fiber.Ctx, body fiber.Map) fiber.Map {
+	retryAfter := 1 + rand.IntN(3)
+	c.Set(fiber.HeaderRetryAfter, strconv.Itoa(retryAfter))
+
+	body["error"] = errDatabaseBusy
+	body["code"] = "DATABASE_BUSY"
+	body["retry_after"] = retryAfter
+	return body
+}
+
 func validateAndParseRequest(c *fiber.Ctx, dbManager cartridge.DBManager, logger *slog.Logger) (*CreateEventParams, error) {
 	var params CreateEventParams
 	if err := c.BodyParser(&params); err != nil {
@@ -157,6 +336,7 @@ func validateOrigin(c *fiber.Ctx, dbManager cartridge.DBManager, logger *slog.Lo
 	logger.Debug("Origin validated successfully",
 		slog.String("origin", origin),
 		slog.String("baseDomain", baseDomain))
+	c.Locals(middleware.WebsiteDomainKey, baseDomain)
 
 	return nil
 }
@@ -198,12 +378,20 @@ func CreateEventBeaconHandler(ctx *cartridge.Context) error {
 		IPAddress:       getClientIP(ctx.Ctx),
 		UserAgent:       userAgentHeader,
 		SecChUa:         ctx.Get("Sec-CH-UA"),
+		AcceptLanguage:  ctx.Get("Accept-Language"),
 		ReferrerURL:     params.Referrer,
 		EventType:       params.EventType,
 		CustomEventName: params.EventKey,
 		CustomEventMeta: metadataFromMap(params.EventMetadata),
 		Timestamp:       params.Timestamp,
 		RawUrl:          params.URL,
+		UserID:          params.UserID,
+		DoNotTrack:      ctx.Get("DNT") == "1",
+		EventID:         params.EventID,
+		ScreenWidth:     params.ScreenWidth,
+		ScreenHeight:    params.ScreenHeight,
+		Properties:      propertiesFromMap(params.Properties),
+		StatusCode:      params.pageStatusCode(),
 	}
 
 	// Collect the event
@@ -243,3 +431,63 @@ func metadataFromMap(metadata map[string]interface{}) string {
 	}
 	return string(data)
 }
+
+// Limits on custom dimensions so a misbehaving client can't bloat events
+const (
+	maxProperties          = 20
+	maxPropertyKeyLength   = 64
+	maxPropertyValueLength = 256
+)
+
+// propertiesFromMap converts custom dimensions to a JSON object of strings.
+// Numbers and booleans are stringified so a dimension groups the same way
+// whatever type the SDK sent; nulls, nested values, empty keys and keys or
+// values over the length limits are dropped, as are keys past maxProperties
+// in sorted order.
+func propertiesFromMap(properties map[string]interface{}) string {
+	if len(properties) == 0 {
+		return ""
+	}
+
+	keys := make([]string, 0, len(properties))
+	for key := range properties {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	normalized := make(map[string]string)
+	for _, key := range keys {
+		if len(normalized) == maxProperties {
+			break
+		}
+		name := strings.TrimSpace(key)
+		if name == "" || len(name) > maxPropertyKeyLength {
+			continue
+		}
+
+		var value string
+		switch v := properties[key].(type) {
+		case string:
+			value = strings.TrimSpace(v)
+		case float64:
+			value = strconv.FormatFloat(v, 'f', -1, 64)
+		case bool:
+			value = strconv.FormatBool(v)
+		default:
+			continue
+		}
+		if value == "" || len(value) > maxPropertyValueLength {
+			continue
+		}
+		normalized[name] = value
+	}
+
+	if len(normalized) == 0 {
+		return ""
+	}
+	data, err := json.Marshal(normalized)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
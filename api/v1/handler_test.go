@@ -9,6 +9,7 @@ import (
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
 	"strings"
 	"testing"
 	"time"
@@ -19,6 +20,7 @@ import (
 
 	"fusionaly/internal/config"
 	"fusionaly/internal/events"
+	"fusionaly/internal/settings"
 	"fusionaly/internal/testsupport"
 )
 
@@ -626,3 +628,371 @@ func TestGetVisitorInfoHandler(t *testing.T) {
 		assert.Equal(t, float64(events.EventTypePageView), event["eventType"])
 	})
 }
+
+func TestCreateEventsBatchPublicAPIHandler(t *testing.T) {
+	newBatchRequest := func(t *testing.T, batch []map[string]interface{}) *http.Request {
+		jsonPayload, err := json.Marshal(batch)
+		require.NoError(t, err)
+
+		req := httptest.NewRequest("POST", "/x/api/v1/events/batch", bytes.NewReader(jsonPayload))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("User-Agent", "Mozilla/5.0 (Test Agent)")
+		req.Header.Set("Origin", "https://example.com")
+		req.Header.Set("X-Forwarded-For", "127.0.0.1")
+		req.Header.Set("Sec-Fetch-Site", "cross-site")
+		return req
+	}
+	pageView := func(url string) map[string]interface{} {
+		return map[string]interface{}{
+			"url":       url,
+			"timestamp": time.Now(),
+			"eventType": events.EventTypePageView,
+		}
+	}
+
+	t.Run("collects valid events and reports failed indices", func(t *testing.T) {
+		dbManager, _ := testsupport.SetupTestDBManager(t)
+		db := dbManager.GetConnection()
+		testsupport.CleanAllTables(db)
+		testsupport.CreateTestWebsite(db, "example.com")
+
+		app := testsupport.CreateMinimalTestApp(t, db)
+
+		resp, err := app.Test(newBatchRequest(t, []map[string]interface{}{
+			pageView("https://example.com/"),
+			pageView("https://unregistered-site.com/"),
+			pageView("not a url"),
+			pageView("https://example.com/pricing"),
+		}), 30000)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusAccepted, resp.StatusCode)
+
+		var respBody struct {
+			Accepted int `json:"accepted"`
+			Failed   int `json:"failed"`
+			Results  []struct {
+				Index  int    `json:"index"`
+				Status int    `json:"status"`
+				Error  string `json:"error"`
+			} `json:"results"`
+		}
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&respBody))
+
+		assert.Equal(t, 2, respBody.Accepted)
+		assert.Equal(t, 2, respBody.Failed)
+		require.Len(t, respBody.Results, 4)
+		for i, expected := range []int{http.StatusAccepted, http.StatusBadRequest, http.StatusBadRequest, http.StatusAccepted} {
+			assert.Equal(t, i, respBody.Results[i].Index)
+			assert.Equal(t, expected, respBody.Results[i].Status, "index %d", i)
+		}
+		assert.Empty(t, respBody.Results[0].Error)
+		assert.NotEmpty(t, respBody.Results[1].Error)
+
+		var count int64
+		require.NoError(t, db.Model(&events.IngestedEvent{}).Count(&count).Error)
+		assert.Equal(t, int64(2), count, "failed items must not roll back collected events")
+	})
+
+	t.Run("rejects oversized batches", func(t *testing.T) {
+		dbManager, _ := testsupport.SetupTestDBManager(t)
+		db := dbManager.GetConnection()
+		testsupport.CleanAllTables(db)
+		testsupport.CreateTestWebsite(db, "example.com")
+
+		app := testsupport.CreateMinimalTestApp(t, db)
+
+		batch := make([]map[string]interface{}, config.GetConfig().MaxEventBatchSize+1)
+		for i := range batch {
+			batch[i] = pageView("https://example.com/")
+		}
+
+		resp, err := app.Test(newBatchRequest(t, batch), 30000)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusRequestEntityTooLarge, resp.StatusCode)
+
+		var count int64
+		require.NoError(t, db.Model(&events.IngestedEvent{}).Count(&count).Error)
+		assert.Zero(t, count)
+	})
+
+	t.Run("rejects a body that is not an array", func(t *testing.T) {
+		dbManager, _ := testsupport.SetupTestDBManager(t)
+		db := dbManager.GetConnection()
+		testsupport.CleanAllTables(db)
+		testsupport.CreateTestWebsite(db, "example.com")
+
+		app := testsupport.CreateMinimalTestApp(t, db)
+
+		req := httptest.NewRequest("POST", "/x/api/v1/events/batch", strings.NewReader(`{"url":"https://example.com/"}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Origin", "https://example.com")
+		req.Header.Set("Sec-Fetch-Site", "cross-site")
+
+		resp, err := app.Test(req, 30000)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+}
+
+func TestCreateEventPublicAPIHandlerDoNotTrack(t *testing.T) {
+	dbManager, _ := testsupport.SetupTestDBManager(t)
+	db := dbManager.GetConnection()
+	testsupport.CleanAllTables(db)
+	testsupport.CreateTestWebsite(db, "example.com")
+	require.NoError(t, settings.SetupDefaultSettings(db))
+
+	app := testsupport.CreateMinimalTestApp(t, db)
+
+	sendWithDNT := func(t *testing.T) int64 {
+		t.Helper()
+		db.Exec("DELETE FROM ingested_events")
+
+		jsonPayload, err := json.Marshal(map[string]interface{}{
+			"url":       "https://example.com/dnt",
+			"timestamp": time.Now(),
+			"eventType": events.EventTypePageView,
+		})
+		require.NoError(t, err)
+
+		req := httptest.NewRequest("POST", "/x/api/v1/events", bytes.NewReader(jsonPayload))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("User-Agent", "Mozilla/5.0 (Test Agent)")
+		req.Header.Set("Origin", "https://example.com")
+		req.Header.Set("X-Forwarded-For", "127.0.0.1")
+		req.Header.Set("Sec-Fetch-Site", "cross-site")
+		req.Header.Set("DNT", "1")
+
+		resp, err := app.Test(req, 30000)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusAccepted, resp.StatusCode)
+
+		var count int64
+		require.NoError(t, db.Model(&events.IngestedEvent{}).Count(&count).Error)
+		return count
+	}
+
+	t.Run("records the event when respect_dnt is off", func(t *testing.T) {
+		assert.Equal(t, int64(1), sendWithDNT(t))
+	})
+
+	t.Run("skips the event when respect_dnt is on", func(t *testing.T) {
+		require.NoError(t, settings.SaveDoNotTrackRespected(db, true))
+		t.Cleanup(func() { settings.SaveDoNotTrackRespected(db, false) })
+
+		assert.Zero(t, sendWithDNT(t))
+	})
+}
+
+func TestCreateEventPublicAPIHandlerEventID(t *testing.T) {
+	dbManager, _ := testsupport.SetupTestDBManager(t)
+	db := dbManager.GetConnection()
+	testsupport.CleanAllTables(db)
+	testsupport.CreateTestWebsite(db, "example.com")
+	require.NoError(t, settings.SetupDefaultSettings(db))
+
+	app := testsupport.CreateMinimalTestApp(t, db)
+
+	jsonPayload, err := json.Marshal(map[string]interface{}{
+		"url":       "https://example.com/retry",
+		"timestamp": time.Now(),
+		"eventType": events.EventTypePageView,
+		"event_id":  "0d6c5d3e-2f7a-4b8e-9a61-52a3c1f0b7aa",
+	})
+	require.NoError(t, err)
+
+	for i := 0; i < 2; i++ {
+		req := httptest.NewRequest("POST", "/x/api/v1/events", bytes.NewReader(jsonPayload))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("User-Agent", "Mozilla/5.0 (Test Agent)")
+		req.Header.Set("Origin", "https://example.com")
+		req.Header.Set("X-Forwarded-For", "127.0.0.1")
+		req.Header.Set("Sec-Fetch-Site", "cross-site")
+
+		resp, err := app.Test(req, 30000)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusAccepted, resp.StatusCode, "retries are accepted silently")
+	}
+
+	var count int64
+	require.NoError(t, db.Model(&events.IngestedEvent{}).Count(&count).Error)
+	assert.Equal(t, int64(1), count)
+}
+
+func TestCreateEventPublicAPIHandlerProperties(t *testing.T) {
+	dbManager, _ := testsupport.SetupTestDBManager(t)
+	db := dbManager.GetConnection()
+	testsupport.CleanAllTables(db)
+	testsupport.CreateTestWebsite(db, "example.com")
+	require.NoError(t, settings.SetupDefaultSettings(db))
+
+	app := testsupport.CreateMinimalTestApp(t, db)
+
+	jsonPayload, err := json.Marshal(map[string]interface{}{
+		"url":       "https://example.com/pricing",
+		"timestamp": time.Now(),
+		"eventType": events.EventTypePageView,
+		"properties": map[string]interface{}{
+			"account_tier": " pro ",
+			"seats":        12,
+			"trial":        false,
+			"nested":       map[string]interface{}{"ignored": true},
+			"empty":        "",
+			"missing":      nil,
+		},
+	})
+	require.NoError(t, err)
+
+	req := httptest.NewRequest("POST", "/x/api/v1/events", bytes.NewReader(jsonPayload))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Mozilla/5.0 (Test Agent)")
+	req.Header.Set("Origin", "https://example.com")
+	req.Header.Set("X-Forwarded-For", "127.0.0.1")
+	req.Header.Set("Sec-Fetch-Site", "cross-site")
+
+	resp, err := app.Test(req, 30000)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusAccepted, resp.StatusCode)
+
+	var ingested events.IngestedEvent
+	require.NoError(t, db.First(&ingested).Error)
+	assert.JSONEq(t, `{"account_tier":"pro","seats":"12","trial":"false"}`, ingested.Properties)
+}
+
+func TestCreateEventPublicAPIHandlerStatusCode(t *testing.T) {
+	dbManager, _ := testsupport.SetupTestDBManager(t)
+	db := dbManager.GetConnection()
+	testsupport.CleanAllTables(db)
+	testsupport.CreateTestWebsite(db, "example.com")
+	require.NoError(t, settings.SetupDefaultSettings(db))
+
+	app := testsupport.CreateMinimalTestApp(t, db)
+
+	tests := []struct {
+		name   string
+		fields map[string]interface{}
+		want   int
+	}{
+		{"normal page", map[string]interface{}{}, 0},
+		{"flagged error page", map[string]interface{}{"is_error_page": true}, http.StatusNotFound},
+		{"explicit status code", map[string]interface{}{"is_error_page": true, "status_code": http.StatusGone}, http.StatusGone},
+		{"out of range status code", map[string]interface{}{"status_code": 1000}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.NoError(t, db.Where("1 = 1").Delete(&events.IngestedEvent{}).Error)
+
+			payload := map[string]interface{}{
+				"url":       "https://example.com/missing",
+				"timestamp": time.Now(),
+				"eventType": events.EventTypePageView,
+			}
+			for key, value := range tt.fields {
+				payload[key] = value
+			}
+			jsonPayload, err := json.Marshal(payload)
+			require.NoError(t, err)
+
+			req := httptest.NewRequest("POST", "/x/api/v1/events", bytes.NewReader(jsonPayload))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("User-Agent", "Mozilla/5.0 (Test Agent)")
+			req.Header.Set("Origin", "https://example.com")
+			req.Header.Set("X-Forwarded-For", "127.0.0.1")
+			req.Header.Set("Sec-Fetch-Site", "cross-site")
+
+			resp, err := app.Test(req, 30000)
+			require.NoError(t, err)
+			assert.Equal(t, http.StatusAccepted, resp.StatusCode)
+
+			var ingested events.IngestedEvent
+			require.NoError(t, db.First(&ingested).Error)
+			assert.Equal(t, tt.want, ingested.StatusCode)
+		})
+	}
+}
+
+func TestCreateEventPublicAPIHandlerOversizedMetadata(t *testing.T) {
+	dbManager, _ := testsupport.SetupTestDBManager(t)
+	db := dbManager.GetConnection()
+	testsupport.CleanAllTables(db)
+	testsupport.CreateTestWebsite(db, "example.com")
+	require.NoError(t, settings.SetupDefaultSettings(db))
+	require.NoError(t, settings.SaveMaxEventMetaBytes(db, 32))
+
+	app := testsupport.CreateMinimalTestApp(t, db)
+
+	jsonPayload, err := json.Marshal(map[string]interface{}{
+		"url":           "https://example.com/checkout",
+		"timestamp":     time.Now(),
+		"eventType":     events.EventTypeCustomEvent,
+		"eventKey":      "checkout",
+		"eventMetadata": map[string]interface{}{"notes": strings.Repeat("x", 64)},
+	})
+	require.NoError(t, err)
+
+	req := httptest.NewRequest("POST", "/x/api/v1/events", bytes.NewReader(jsonPayload))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Mozilla/5.0 (Test Agent)")
+	req.Header.Set("Origin", "https://example.com")
+	req.Header.Set("X-Forwarded-For", "127.0.0.1")
+	req.Header.Set("Sec-Fetch-Site", "cross-site")
+
+	resp, err := app.Test(req, 30000)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, resp.StatusCode)
+
+	var body map[string]interface{}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+	assert.Equal(t, "EVENT_META_TOO_LARGE", body["code"])
+}
+
+func TestCreateEventPublicAPIHandlerDatabaseBusy(t *testing.T) {
+	dbManager, _ := testsupport.SetupTestDBManager(t)
+	db := dbManager.GetConnection()
+	testsupport.CleanAllTables(db)
+	testsupport.CreateTestWebsite(db, "example.com")
+	require.NoError(t, settings.SetupDefaultSettings(db))
+
+	app := testsupport.CreateMinimalTestApp(t, db)
+
+	// Simulate write contention: every insert fails as if SQLite stayed locked
+	require.NoError(t, db.Exec(`CREATE TRIGGER simulate_busy BEFORE INSERT ON ingested_events
+		BEGIN SELECT RAISE(ABORT, 'database is locked'); END`).Error)
+	t.Cleanup(func() { db.Exec("DROP TRIGGER IF EXISTS simulate_busy") })
+
+	jsonPayload, err := json.Marshal(map[string]interface{}{
+		"url":       "https://example.com/pricing",
+		"timestamp": time.Now(),
+		"eventType": events.EventTypePageView,
+	})
+	require.NoError(t, err)
+
+	req := httptest.NewRequest("POST", "/x/api/v1/events", bytes.NewReader(jsonPayload))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Mozilla/5.0 (Test Agent)")
+	req.Header.Set("Origin", "https://example.com")
+	req.Header.Set("X-Forwarded-For", "127.0.0.1")
+	req.Header.Set("Sec-Fetch-Site", "cross-site")
+
+	resp, err := app.Test(req, 30000)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+
+	retryAfter, err := strconv.Atoi(resp.Header.Get("Retry-After"))
+	require.NoError(t, err, "Retry-After must be a number of seconds")
+	assert.GreaterOrEqual(t, retryAfter, 1)
+	assert.LessOrEqual(t, retryAfter, 3)
+
+	var body struct {
+		Error      string `json:"error"`
+		Code       string `json:"code"`
+		RetryAfter int    `json:"retry_after"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+	assert.Equal(t, "DATABASE_BUSY", body.Code)
+	assert.NotEmpty(t, body.Error)
+	assert.Equal(t, retryAfter, body.RetryAfter)
+
+	var count int64
+	require.NoError(t, db.Model(&events.IngestedEvent{}).Count(&count).Error)
+	assert.Zero(t, count)
+}
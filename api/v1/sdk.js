@@ -6,6 +6,7 @@
 		eventTypes: {
 			pageView: 1,
 			customEvent: 2,
+			download: 3,
 		},
 		sendInterval: 200,
 		maxRetries: 3,
@@ -20,6 +21,12 @@
 		scrollSectionEventKey: "scroll:section",
 		scrollSectionThreshold: 0.5,
 		useKeepaliveFetch: false,
+		trackDownloads: true,
+		downloadExtensions: [
+			"pdf", "zip", "gz", "tar", "rar", "7z", "dmg", "exe", "msi", "pkg",
+			"deb", "rpm", "apk", "csv", "xlsx", "xls", "docx", "doc", "pptx",
+			"ppt", "txt", "epub", "mp3", "mp4", "mov", "iso",
+		],
 	};
 
 	window.Fusionaly.config = Object.assign(defaults, window.Fusionaly.config || {});
@@ -113,6 +120,8 @@
 			return;
 		}
 		eventData.userAgent = navigator.userAgent;
+		eventData.screen_width = window.innerWidth;
+		eventData.screen_height = window.innerHeight;
 
 		fetch(`${baseUrl}/x/api/v1/events`, {
 			method: "POST",
@@ -128,7 +137,15 @@
 						`Error sending event: ${response.status} ${response.statusText}`,
 						"error",
 					);
-					retry(eventData, retryCount);
+					if (response.status === 503) {
+						// The server is busy and says how long to back off
+						response
+							.json()
+							.then((data) => retry(eventData, retryCount, data.retry_after))
+							.catch(() => retry(eventData, retryCount));
+					} else {
+						retry(eventData, retryCount);
+					}
 				} else {
 					response
 						.json()
@@ -144,9 +161,12 @@
 			});
 	};
 
-	const retry = (eventData, retryCount) => {
+	const retry = (eventData, retryCount, retryAfterSeconds) => {
 		if (retryCount < window.Fusionaly.config.maxRetries) {
-			const delay = 2 ** retryCount * 1000 * (0.8 + Math.random() * 0.4);
+			const delay =
+				retryAfterSeconds > 0
+					? retryAfterSeconds * 1000
+					: 2 ** retryCount * 1000 * (0.8 + Math.random() * 0.4);
 			log(`Retrying in ${Math.round(delay / 1000)} seconds...`);
 			setTimeout(() => sendEventWithRetry(eventData, retryCount + 1), delay);
 		} else {
@@ -197,7 +217,11 @@
 		}
 	};
 
+	// Custom dimensions set with setProperties ride along with every event
 	const bufferEvent = (eventData) => {
+		if (window.Fusionaly.properties) {
+			eventData.properties = { ...window.Fusionaly.properties };
+		}
 		eventBuffer.push(eventData);
 	};
 
@@ -206,13 +230,18 @@
 			return;
 		}
 
-		bufferEvent({
+		const eventData = {
 			timestamp: new Date().toISOString(),
 			referrer: document.referrer,
 			url: window.location.href,
 			userId: window.Fusionaly.userId,
 			eventType: window.Fusionaly.config.eventTypes.pageView,
-		});
+		};
+		// Error pages set config.statusCode (e.g. 404) so they're reported apart
+		if (window.Fusionaly.config.statusCode) {
+			eventData.status_code = window.Fusionaly.config.statusCode;
+		}
+		bufferEvent(eventData);
 	};
 
 	const registerPurchase = (priceInCents, currency = 'USD', metadata = {}) => {
@@ -255,10 +284,31 @@
 		});
 	};
 
+	const trackDownload = (fileUrl, metadata) => {
+		if (!shouldTrack() || !fileUrl) {
+			return;
+		}
+
+		bufferEvent({
+			url: window.location.href,
+			timestamp: new Date().toISOString(),
+			userId: window.Fusionaly.userId,
+			eventType: window.Fusionaly.config.eventTypes.download,
+			eventMetadata: metadata || {},
+			eventKey: fileUrl,
+		});
+	};
+
 	const setUser = (data) => {
 		window.Fusionaly.userId = data.userId;
 	};
 
+	// Sets custom dimensions (e.g. { account_tier: "pro" }) sent with every
+	// following event; pass null to stop sending them.
+	const setProperties = (properties) => {
+		window.Fusionaly.properties = properties ? { ...properties } : null;
+	};
+
 	// Send event reliably during page navigation.
 	// Uses fetch+keepalive when configured (avoids ad blocker ping blocking),
 	// falls back to sendBeacon.
@@ -295,6 +345,8 @@
 
 		for (const eventData of eventsToSend) {
 			eventData.userAgent = navigator.userAgent;
+			eventData.screen_width = window.innerWidth;
+			eventData.screen_height = window.innerHeight;
 
 			if (!sendBeaconEvent(eventData)) {
 				storeEventLocally(eventData);
@@ -490,7 +542,10 @@
 						eventType: window.Fusionaly.config.eventTypes.customEvent,
 						eventMetadata: eventData.metadata || {},  // Ensure metadata is never undefined
 						eventKey: originalEventName,  // Use the original event name directly
-						userAgent: navigator.userAgent
+						properties: window.Fusionaly.properties || undefined,
+						userAgent: navigator.userAgent,
+						screen_width: window.innerWidth,
+						screen_height: window.innerHeight
 					};
 
 					// For debugging - log the exact payload being sent
@@ -520,6 +575,32 @@
 		});
 	};
 
+	const isDownloadLink = (link) => {
+		if (link.hasAttribute("download")) {
+			return true;
+		}
+		const pathname = (link.pathname || "").toLowerCase();
+		const extension = pathname.includes(".") ? pathname.split(".").pop() : "";
+		return window.Fusionaly.config.downloadExtensions.includes(extension);
+	};
+
+	const setupDownloadTracking = () => {
+		if (!shouldTrack() || !window.Fusionaly.config.trackDownloads) {
+			return;
+		}
+
+		document.addEventListener("click", (event) => {
+			const link = event.target.closest("a[href]");
+			// Links with an explicit event name are tracked as custom events instead
+			if (!link || link.hasAttribute("data-fusionaly-event-name") || !isDownloadLink(link)) {
+				return;
+			}
+
+			trackDownload(link.href, { text: (link.textContent || "").trim() });
+			log(`Tracked download: ${link.href}`);
+		});
+	};
+
 	// Helper function to process link events and extract event data
 	const processLinkEvent = (link, href) => {
 		let eventName = getDataAttribute(link, 'event-name');
@@ -898,6 +979,7 @@
 	}
 	setupFormTracking();
 	setupDataDrivenLinkTracking();
+	setupDownloadTracking();
 	setupScrollTrackingFromAttributes();
 
 	if (document && typeof document.addEventListener === "function") {
@@ -918,7 +1000,10 @@
 		window.Fusionaly.sendPageView || sendPageView;
 	window.Fusionaly.sendCustomEvent =
 		window.Fusionaly.sendCustomEvent || sendCustomEvent;
+	window.Fusionaly.trackDownload =
+		window.Fusionaly.trackDownload || trackDownload;
 	window.Fusionaly.setUser = window.Fusionaly.setUser || setUser;
+	window.Fusionaly.setProperties = window.Fusionaly.setProperties || setProperties;
 	window.Fusionaly.registerPurchase = window.Fusionaly.registerPurchase || registerPurchase;
 	window.Fusionaly.trackScrollDepth =
 		window.Fusionaly.trackScrollDepth || trackScrollDepth;
@@ -8,69 +8,87 @@ import (
 	"strings"
 
 	"github.com/gofiber/fiber/v2"
-	"log/slog"
+
+	"fusionaly/internal/config"
 )
 
+// getClientIP returns the visitor's address. Forwarding headers are only
+// honoured when the connection comes from a trusted proxy, so clients that
+// reach the app directly can't spoof their IP.
 func getClientIP(c *fiber.Ctx) string {
-	// Try standard headers first
-	if ip := selectPreferredIP(strings.Split(c.Get("X-Forwarded-For"), ",")); ip != "" {
-		return ip
-	}
-
-	// Other reverse-proxy headers
-	for _, header := range []string{
-		"X-Real-IP",
-		"CF-Connecting-IP",
-		"True-Client-IP",
-		"X-Client-IP",
-	} {
-		if value := c.Get(header); value != "" {
-			if ip := selectPreferredIP([]string{value}); ip != "" {
-				return ip
-			}
+	peer, _ := netip.AddrFromSlice(c.Context().RemoteIP())
+	header := func(name string) string { return c.Get(name) }
+	return resolveClientIP(peer, header, config.GetConfig().GetTrustedProxies())
+}
+
+// proxyIPHeaders carry a single client address set by the outermost proxy.
+var proxyIPHeaders = []string{
+	"CF-Connecting-IP",
+	"True-Client-IP",
+	"X-Real-IP",
+	"X-Client-IP",
+}
+
+// resolveClientIP picks the client address for a request from peer.
+// X-Forwarded-For (or Forwarded) is walked from the right and the first hop
+// that isn't a trusted proxy wins, so entries a client prepends itself are
+// never preferred. When every hop is trusted, single-value headers such as
+// CF-Connecting-IP set by the outermost proxy are used.
+func resolveClientIP(peer netip.Addr, header func(string) string, trusted []netip.Prefix) string {
+	peer = peer.Unmap()
+	// In-memory listeners report an unspecified peer; treat it as local
+	if peer.IsValid() && !peer.IsUnspecified() && !isTrustedProxy(peer, trusted) {
+		return peer.String()
+	}
+
+	var hops []string
+	if forwardedFor := header("X-Forwarded-For"); forwardedFor != "" {
+		hops = strings.Split(forwardedFor, ",")
+	} else {
+		hops = parseForwardedHeader(header("Forwarded"))
+	}
+
+	var outermost string
+	for i := len(hops) - 1; i >= 0; i-- {
+		clean, parsed := normalizeIP(hops[i])
+		if parsed == nil {
+			continue
+		}
+		addr, _ := netip.ParseAddr(clean)
+		if !isTrustedProxy(addr, trusted) {
+			return clean
 		}
+		outermost = clean
 	}
 
-	if forwarded := c.Get("Forwarded"); forwarded != "" {
-		if ip := selectPreferredIP(parseForwardedHeader(forwarded)); ip != "" {
-			return ip
+	for _, name := range proxyIPHeaders {
+		if clean, parsed := normalizeIP(header(name)); parsed != nil {
+			return clean
 		}
 	}
 
-	// Try the remote address from the request directly
-	remoteAddr := c.Context().RemoteAddr().String()
-	if remoteAddr != "" {
-		// Extract IP from IP:port format
-		host, _, err := net.SplitHostPort(remoteAddr)
-		if err == nil && host != "" {
-			parsedIP := net.ParseIP(host)
-			if parsedIP != nil && !isPrivateIP(parsedIP) {
-				return host
-			}
-		} else {
-			// Try to use the address directly if SplitHostPort fails
-			parsedIP := net.ParseIP(remoteAddr)
-			if parsedIP != nil && !isPrivateIP(parsedIP) {
-				return remoteAddr
-			}
-		}
+	if outermost != "" {
+		return outermost
 	}
+	if peer.IsValid() && !peer.IsUnspecified() {
+		return peer.String()
+	}
+	return "127.0.0.1"
+}
 
-	// Finally, use Fiber's built-in method
-	ip := c.IP()
-	if ip != "" && ip != "0.0.0.0" && ip != "::" {
-		parsedIP := net.ParseIP(strings.TrimSpace(ip))
-		if parsedIP != nil && !isPrivateIP(parsedIP) {
-			return ip
+// isTrustedProxy reports whether addr is one of the configured proxies.
+// Without configuration, loopback and private-network peers are trusted,
+// which covers a reverse proxy on the same host or Docker network.
+func isTrustedProxy(addr netip.Addr, trusted []netip.Prefix) bool {
+	if len(trusted) == 0 {
+		return isPrivateIP(net.IP(addr.AsSlice()))
+	}
+	for _, prefix := range trusted {
+		if prefix.Contains(addr) {
+			return true
 		}
 	}
-
-	// If we get here, we couldn't determine a public IP
-	// In a production environment, you might want to log this occurrence
-	slog.Default().Info("Fallback to loopback IP for request",
-		slog.String("path", c.Path()),
-		slog.Any("headers", c.GetReqHeaders()))
-	return "127.0.0.1"
+	return false
 }
 
 // Helper function to check if an IP is private
@@ -120,27 +138,6 @@ func parseCIDR(s string) *net.IPNet {
 	return block
 }
 
-func selectPreferredIP(values []string) string {
-	var ipv6Fallback string
-
-	for _, raw := range values {
-		clean, parsed := normalizeIP(raw)
-		if parsed == nil || isPrivateIP(parsed) {
-			continue
-		}
-
-		if parsed.To4() != nil {
-			return clean
-		}
-
-		if ipv6Fallback == "" {
-			ipv6Fallback = clean
-		}
-	}
-
-	return ipv6Fallback
-}
-
 func normalizeIP(raw string) (string, net.IP) {
 	clean := strings.TrimSpace(raw)
 	clean = strings.Trim(clean, "\"")
@@ -2,6 +2,7 @@ package v1
 
 import (
 	"net"
+	"net/netip"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -47,38 +48,102 @@ func TestNormalizeIPVariants(t *testing.T) {
 	}
 }
 
-func TestSelectPreferredIP(t *testing.T) {
+func TestResolveClientIP(t *testing.T) {
+	cloudflare := []netip.Prefix{
+		netip.MustParsePrefix("173.245.48.0/20"),
+		netip.MustParsePrefix("2400:cb00::/32"),
+	}
+	cloudflareAndDocker := append([]netip.Prefix{netip.MustParsePrefix("172.16.0.0/12")}, cloudflare...)
+
 	tests := []struct {
-		name   string
-		values []string
-		want   string
+		name    string
+		peer    string
+		headers map[string]string
+		trusted []netip.Prefix
+		want    string
 	}{
 		{
-			name:   "prefers public ipv4 over ipv6",
-			values: []string{"2001:db8::1", "203.0.113.20"},
-			want:   "203.0.113.20",
+			name:    "untrusted peer cannot spoof forwarding headers",
+			peer:    "198.51.100.7",
+			headers: map[string]string{"X-Forwarded-For": "203.0.113.20", "CF-Connecting-IP": "203.0.113.21"},
+			want:    "198.51.100.7",
+		},
+		{
+			name:    "private peer is trusted by default",
+			peer:    "172.18.0.2",
+			headers: map[string]string{"X-Forwarded-For": "203.0.113.20"},
+			want:    "203.0.113.20",
+		},
+		{
+			name:    "client-prepended entries are ignored",
+			peer:    "127.0.0.1",
+			headers: map[string]string{"X-Forwarded-For": "1.1.1.1, 198.51.100.7"},
+			want:    "198.51.100.7",
+		},
+		{
+			name:    "trusted private hops are skipped",
+			peer:    "127.0.0.1",
+			headers: map[string]string{"X-Forwarded-For": "198.51.100.7, 10.0.0.5, 192.168.1.10"},
+			want:    "198.51.100.7",
+		},
+		{
+			name:    "invalid hops are skipped",
+			peer:    "127.0.0.1",
+			headers: map[string]string{"X-Forwarded-For": "203.0.113.20, not-an-ip"},
+			want:    "203.0.113.20",
 		},
 		{
-			name:   "skips private addresses",
-			values: []string{"192.168.1.10", "10.0.0.5", "::1", "198.51.100.7"},
-			want:   "198.51.100.7",
+			name:    "cloudflare connecting directly",
+			peer:    "173.245.48.10",
+			headers: map[string]string{"X-Forwarded-For": "2001:db8::2", "CF-Connecting-IP": "2001:db8::2"},
+			trusted: cloudflare,
+			want:    "2001:db8::2",
 		},
 		{
-			name:   "returns ipv6 fallback when no ipv4",
-			values: []string{"2001:db8::2"},
-			want:   "2001:db8::2",
+			name:    "cloudflare behind a local reverse proxy",
+			peer:    "172.18.0.2",
+			headers: map[string]string{"X-Forwarded-For": "173.245.48.10", "CF-Connecting-IP": "203.0.113.9"},
+			trusted: cloudflareAndDocker,
+			want:    "203.0.113.9",
 		},
 		{
-			name:   "returns empty when no valid candidates",
-			values: []string{"", "   ", "not-an-ip"},
-			want:   "",
+			name:    "configured list replaces the private default",
+			peer:    "10.0.0.2",
+			headers: map[string]string{"X-Forwarded-For": "203.0.113.20"},
+			trusted: cloudflare,
+			want:    "10.0.0.2",
+		},
+		{
+			name:    "Forwarded header when X-Forwarded-For is absent",
+			peer:    "::1",
+			headers: map[string]string{"Forwarded": `for="[2001:db8::7]:4711";proto=https`},
+			want:    "2001:db8::7",
+		},
+		{
+			name:    "ipv4-mapped peer is unmapped",
+			peer:    "::ffff:8.8.8.8",
+			headers: map[string]string{"X-Forwarded-For": "203.0.113.20"},
+			want:    "8.8.8.8",
+		},
+		{
+			name:    "in-memory peer honours headers",
+			peer:    "0.0.0.0",
+			headers: map[string]string{"X-Forwarded-For": "203.0.113.20"},
+			want:    "203.0.113.20",
+		},
+		{
+			name: "falls back to loopback without any address",
+			peer: "0.0.0.0",
+			want: "127.0.0.1",
 		},
 	}
 
 	for _, tc := range tests {
 		tc := tc
 		t.Run(tc.name, func(t *testing.T) {
-			assert.Equal(t, tc.want, selectPreferredIP(tc.values))
+			header := func(name string) string { return tc.headers[name] }
+			got := resolveClientIP(netip.MustParseAddr(tc.peer), header, tc.trusted)
+			assert.Equal(t, tc.want, got)
 		})
 	}
 }
@@ -225,7 +225,7 @@ func buildVisitorEventFromIngested(evt events.IngestedEvent) visitorEvent {
 }
 
 func safeReferrer(host, path string) string {
-	if host == "" || host == events.DirectOrUnknownReferrer {
+	if host == "" || host == events.DirectOrUnknownReferrer || host == events.SelfReferral {
 		return ""
 	}
 	return host + path
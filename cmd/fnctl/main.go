@@ -10,6 +10,7 @@ import (
 	"log"
 	"os"
 	"os/signal"
+	"strconv"
 	"strings"
 	"syscall"
 	"time"
@@ -17,6 +18,9 @@ import (
 	"gorm.io/gorm"
 
 	"fusionaly/internal"
+	"fusionaly/internal/config"
+	"fusionaly/internal/database"
+	"fusionaly/internal/events"
 	"fusionaly/internal/seeder"
 	"fusionaly/internal/users"
 	"fusionaly/internal/websites"
@@ -42,9 +46,20 @@ type Command interface {
 var commands = []Command{
 	&CreateAdminUserCommand{},
 	&ChangeAdminPasswordCommand{},
+	&ListUsersCommand{},
+	&DeleteUserCommand{},
 	&CreateWebsiteCommand{},
 	&MigrateCommand{},
 	&SeedCommand{},
+	&ReprocessCommand{},
+	&PurgeCommand{},
+	&BackupCommand{},
+	&ListBackupsCommand{},
+	&ExportCommand{},
+	&ImportCommand{},
+	&ImportEventsCommand{},
+	&FailedEventsCommand{},
+	&OptimizeCommand{},
 	&StatusCommand{},
 	&HelpCommand{},
 }
@@ -236,6 +251,80 @@ func (c *ChangeAdminPasswordCommand) Execute(ctx context.Context, app *internal.
 	return nil
 }
 
+// ListUsersCommand prints every user account
+type ListUsersCommand struct{}
+
+func (c *ListUsersCommand) Name() string        { return "list-users" }
+func (c *ListUsersCommand) Description() string { return "Lists all users" }
+
+func (c *ListUsersCommand) Execute(ctx context.Context, app *internal.Application, args []string) error {
+	if app == nil {
+		return fmt.Errorf("app initialization failed, cannot connect to database")
+	}
+
+	list, err := users.ListUsers(app.DBManager.GetConnection())
+	if err != nil {
+		return fmt.Errorf("failed to list users: %w", err)
+	}
+
+	if len(list) == 0 {
+		fmt.Println("No users found")
+		return nil
+	}
+
+	for _, user := range list {
+		fmt.Printf("%-5d %-40s %s\n", user.ID, user.Email, user.CreatedAt.UTC().Format(time.RFC3339))
+	}
+	return nil
+}
+
+// DeleteUserCommand removes a user account after confirmation
+type DeleteUserCommand struct{}
+
+func (c *DeleteUserCommand) Name() string { return "delete-user" }
+func (c *DeleteUserCommand) Description() string {
+	return "Deletes a user by email (--yes skips the confirmation)"
+}
+
+func (c *DeleteUserCommand) Execute(ctx context.Context, app *internal.Application, args []string) error {
+	fs := flag.NewFlagSet("delete-user", flag.ContinueOnError)
+	yes := fs.Bool("yes", false, "skip the confirmation prompt")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if fs.NArg() < 1 {
+		return fmt.Errorf("usage: %s [--yes] <email>", c.Name())
+	}
+	email := fs.Arg(0)
+
+	if app == nil {
+		return fmt.Errorf("app initialization failed, cannot connect to database")
+	}
+
+	db := app.DBManager.GetConnection()
+	if _, err := users.FindByEmail(db, email); err != nil {
+		return fmt.Errorf("user lookup failed: %w", err)
+	}
+
+	if !*yes {
+		fmt.Printf("Delete user %s? This cannot be undone. [y/N]: ", email)
+		input, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+		answer := strings.ToLower(strings.TrimSpace(input))
+		if answer != "y" && answer != "yes" {
+			fmt.Println("Deletion cancelled")
+			return nil
+		}
+	}
+
+	if err := users.DeleteUser(db, email); err != nil {
+		return fmt.Errorf("failed to delete user: %w", err)
+	}
+
+	fmt.Printf("User %s deleted\n", email)
+	return nil
+}
+
 // CreateWebsiteCommand implements the command to create a website
 type CreateWebsiteCommand struct{}
 
@@ -399,8 +488,494 @@ func (c *SeedCommand) Execute(ctx context.Context, app *internal.Application, ar
 	return nil
 }
 
+// ReprocessCommand replays raw events through the current processing logic
+type ReprocessCommand struct{}
+
+func (c *ReprocessCommand) Name() string { return "reprocess" }
+func (c *ReprocessCommand) Description() string {
+	return "Rebuilds aggregates from raw events (--domain [--from --to] --confirm)"
+}
+
+func (c *ReprocessCommand) Execute(ctx context.Context, app *internal.Application, args []string) error {
+	fs := flag.NewFlagSet("reprocess", flag.ContinueOnError)
+	domain := fs.String("domain", "", "website domain to reprocess")
+	fromStr := fs.String("from", "", "start date (YYYY-MM-DD or RFC3339), defaults to the oldest raw event")
+	toStr := fs.String("to", "", "end date, exclusive (YYYY-MM-DD or RFC3339), defaults to now")
+	confirm := fs.Bool("confirm", false, "rewrite the aggregates; without it only the event count is printed")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *domain == "" {
+		return fmt.Errorf("usage: %s --domain <domain> [--from <date>] [--to <date>] --confirm", c.Name())
+	}
+
+	if app == nil {
+		return fmt.Errorf("app initialization failed, cannot connect to database")
+	}
+
+	db := app.DBManager.GetConnection()
+	website, err := websites.GetWebsiteByDomain(db, *domain)
+	if err != nil {
+		return fmt.Errorf("website %s not found: %w", *domain, err)
+	}
+
+	var from time.Time
+	if *fromStr != "" {
+		if from, err = parseDateFlag(*fromStr); err != nil {
+			return fmt.Errorf("invalid --from: %w", err)
+		}
+	} else {
+		if from, err = events.EarliestIngestedEventTime(db, website.ID); err != nil {
+			return fmt.Errorf("failed to find oldest raw event: %w", err)
+		}
+		if from.IsZero() {
+			log.Printf("No raw events to reprocess for %s", *domain)
+			return nil
+		}
+	}
+
+	to := time.Now().UTC()
+	if *toStr != "" {
+		if to, err = parseDateFlag(*toStr); err != nil {
+			return fmt.Errorf("invalid --to: %w", err)
+		}
+	}
+
+	count, err := events.CountReprocessableEvents(db, website.ID, from, to)
+	if err != nil {
+		return err
+	}
+	log.Printf("%d raw events for %s between %s and %s will be reprocessed",
+		count, *domain, from.Format(time.RFC3339), to.Format(time.RFC3339))
+
+	if !*confirm {
+		log.Println("Aggregates in this window are rebuilt from scratch; re-run with --confirm to proceed")
+		return nil
+	}
+
+	result, err := events.ReprocessEvents(app.DBManager, slog.Default(), website.ID, from, to)
+	if err != nil {
+		return fmt.Errorf("reprocess failed: %w", err)
+	}
+
+	log.Printf("Reprocessed %d of %d raw events for %s between %s and %s",
+		result.ProcessedEvents, result.RawEvents, *domain,
+		result.From.Format(time.RFC3339), result.To.Format(time.RFC3339))
+	return nil
+}
+
+// PurgeCommand deletes raw events past a cutoff while keeping the aggregates
+type PurgeCommand struct{}
+
+func (c *PurgeCommand) Name() string { return "purge" }
+func (c *PurgeCommand) Description() string {
+	return "Deletes raw events older than N days, keeping aggregates (--older-than <days> --confirm)"
+}
+
+func (c *PurgeCommand) Execute(ctx context.Context, app *internal.Application, args []string) error {
+	fs := flag.NewFlagSet("purge", flag.ContinueOnError)
+	olderThan := fs.String("older-than", "", "age in days (e.g. 90 or 90d) past which raw events are deleted")
+	confirm := fs.Bool("confirm", false, "delete the rows; without it only the counts are printed")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	days, err := parseDaysFlag(*olderThan)
+	if err != nil {
+		return fmt.Errorf("usage: %s --older-than <days> --confirm: %w", c.Name(), err)
+	}
+
+	if app == nil {
+		return fmt.Errorf("app initialization failed, cannot connect to database")
+	}
+
+	db := app.DBManager.GetConnection()
+	cutoff := time.Now().UTC().AddDate(0, 0, -days)
+
+	count, err := events.CountPurgeableRawEvents(db, cutoff)
+	if err != nil {
+		return err
+	}
+	log.Printf("%d raw events and %d processed ingested events are older than %s",
+		count.Events, count.IngestedEvents, cutoff.Format(time.RFC3339))
+
+	if !*confirm {
+		log.Println("Aggregates and revenue events are kept, but visit duration, 404s, flows, funnels and retention lose this history; re-run with --confirm to proceed")
+		return nil
+	}
+
+	result, err := events.PurgeRawEvents(db, cutoff)
+	if err != nil {
+		return fmt.Errorf("purge failed: %w", err)
+	}
+
+	log.Printf("Purged %d raw events and %d ingested events older than %s",
+		result.Events, result.IngestedEvents, cutoff.Format(time.RFC3339))
+	return nil
+}
+
+// BackupCommand takes an on-demand database backup
+type BackupCommand struct{}
+
+func (c *BackupCommand) Name() string { return "backup" }
+func (c *BackupCommand) Description() string {
+	return "Backs up the database to the backups directory and prunes old backups"
+}
+
+func (c *BackupCommand) Execute(ctx context.Context, app *internal.Application, args []string) error {
+	if app == nil {
+		return fmt.Errorf("app initialization failed, cannot connect to database")
+	}
+
+	cfg := config.GetConfig()
+	dir := cfg.GetBackupsDirectory()
+	now := time.Now()
+
+	path, err := database.BackupDatabase(app.DBManager.GetConnection(), dir, now)
+	if err != nil {
+		return err
+	}
+	log.Printf("Database backed up to %s", path)
+
+	maxAge := time.Duration(cfg.BackupRetentionDays) * 24 * time.Hour
+	deleted, err := database.PruneBackups(dir, cfg.BackupRetentionCount, maxAge, now)
+	if err != nil {
+		return err
+	}
+	for _, name := range deleted {
+		log.Printf("Pruned old backup %s", name)
+	}
+	return nil
+}
+
+// ListBackupsCommand prints the available database backups
+type ListBackupsCommand struct{}
+
+func (c *ListBackupsCommand) Name() string { return "list-backups" }
+func (c *ListBackupsCommand) Description() string {
+	return "Lists database backups with sizes and timestamps"
+}
+
+func (c *ListBackupsCommand) Execute(ctx context.Context, app *internal.Application, args []string) error {
+	dir := config.GetConfig().GetBackupsDirectory()
+	backups, err := database.ListBackups(dir)
+	if err != nil {
+		return err
+	}
+
+	if len(backups) == 0 {
+		fmt.Printf("No backups found in %s\n", dir)
+		return nil
+	}
+
+	for _, backup := range backups {
+		fmt.Printf("%-36s %10s  %s\n", backup.Name, formatBytes(backup.Size), backup.CreatedAt.Format(time.RFC3339))
+	}
+	return nil
+}
+
+// ExportCommand copies the live database to a file for moving between hosts
+type ExportCommand struct{}
+
+func (c *ExportCommand) Name() string        { return "export" }
+func (c *ExportCommand) Description() string { return "Exports the database to a SQLite file (--out)" }
+
+func (c *ExportCommand) Execute(ctx context.Context, app *internal.Application, args []string) error {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	out := fs.String("out", "", "path of the SQLite file to write")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *out == "" {
+		return fmt.Errorf("usage: %s --out <file.db>", c.Name())
+	}
+
+	if app == nil {
+		return fmt.Errorf("app initialization failed, cannot connect to database")
+	}
+
+	if err := database.ExportDatabase(app.DBManager.GetConnection(), *out); err != nil {
+		return err
+	}
+
+	log.Printf("Database exported to %s", *out)
+	return nil
+}
+
+// importActivityWindow is how far back ingested events count as the app still writing
+const importActivityWindow = 2 * time.Minute
+
+// ImportCommand replaces the live database with an exported file
+type ImportCommand struct{}
+
+func (c *ImportCommand) Name() string { return "import" }
+func (c *ImportCommand) Description() string {
+	return "Replaces the database with a SQLite file (--in), backing up the current one first"
+}
+
+func (c *ImportCommand) Execute(ctx context.Context, app *internal.Application, args []string) error {
+	fs := flag.NewFlagSet("import", flag.ContinueOnError)
+	in := fs.String("in", "", "path of the SQLite file to import")
+	force := fs.Bool("force", false, "import even while the app is still ingesting events")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *in == "" {
+		return fmt.Errorf("usage: %s --in <file.db> [--force]", c.Name())
+	}
+
+	if app == nil {
+		return fmt.Errorf("app initialization failed, cannot connect to database")
+	}
+
+	db := app.DBManager.GetConnection()
+
+	if !*force {
+		active, err := database.HasRecentWrites(db, time.Now().Add(-importActivityWindow))
+		if err != nil {
+			return fmt.Errorf("failed to check for recent writes: %w", err)
+		}
+		if active {
+			return fmt.Errorf("events were ingested in the last %s; stop the app or pass --force", importActivityWindow)
+		}
+	}
+
+	backupPath, err := database.BackupDatabase(db, config.GetConfig().GetBackupsDirectory(), time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to back up the current database: %w", err)
+	}
+	log.Printf("Current database backed up to %s", backupPath)
+
+	if err := database.ImportDatabase(db, *in); err != nil {
+		return err
+	}
+
+	if err := app.DBManager.MigrateDatabase(); err != nil {
+		return fmt.Errorf("imported, but migrations failed: %w", err)
+	}
+
+	log.Printf("Database imported from %s", *in)
+	return nil
+}
+
+// ImportEventsCommand backfills historical events from another analytics tool's export
+type ImportEventsCommand struct{}
+
+func (c *ImportEventsCommand) Name() string { return "import-events" }
+func (c *ImportEventsCommand) Description() string {
+	return "Imports historical events from a Plausible, Umami or NDJSON export (--file --domain --format [--dry-run])"
+}
+
+func (c *ImportEventsCommand) Execute(ctx context.Context, app *internal.Application, args []string) error {
+	fs := flag.NewFlagSet("import-events", flag.ContinueOnError)
+	file := fs.String("file", "", "path of the export file")
+	domain := fs.String("domain", "", "website domain the events belong to")
+	format := fs.String("format", "", "export format: plausible, umami or ndjson")
+	dryRun := fs.Bool("dry-run", false, "parse the file and print the counts without storing anything")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *file == "" || *domain == "" || *format == "" {
+		return fmt.Errorf("usage: %s --file <path> --domain <domain> --format plausible|umami|ndjson [--dry-run]", c.Name())
+	}
+
+	if app == nil {
+		return fmt.Errorf("app initialization failed, cannot connect to database")
+	}
+
+	db := app.DBManager.GetConnection()
+	if _, err := websites.GetWebsiteByDomain(db, *domain); err != nil {
+		return fmt.Errorf("website %s not found: %w", *domain, err)
+	}
+
+	f, err := os.Open(*file)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", *file, err)
+	}
+	defer f.Close()
+
+	result, err := events.ImportEvents(app.DBManager, slog.Default(), f, events.ImportFormat(strings.ToLower(*format)), *domain, *dryRun)
+	if err != nil {
+		return fmt.Errorf("import failed: %w", err)
+	}
+
+	log.Printf("%d rows read: %d page views, %d custom events, %d skipped",
+		result.Rows, result.PageViews, result.CustomEvents, result.Skipped)
+	if !result.From.IsZero() {
+		log.Printf("Events span %s to %s", result.From.Format(time.RFC3339), result.To.Format(time.RFC3339))
+	}
+
+	if *dryRun {
+		log.Println("Dry run, nothing was stored; re-run without --dry-run to import")
+		return nil
+	}
+
+	log.Printf("Imported %d events into %s", result.Stored, *domain)
+	return nil
+}
+
+// FailedEventsCommand lists events that repeatedly failed processing and can requeue them
+type FailedEventsCommand struct{}
+
+func (c *FailedEventsCommand) Name() string { return "failed-events" }
+func (c *FailedEventsCommand) Description() string {
+	return "Lists events that failed processing (--limit); --requeue [id...] sends them back for processing"
+}
+
+func (c *FailedEventsCommand) Execute(ctx context.Context, app *internal.Application, args []string) error {
+	fs := flag.NewFlagSet("failed-events", flag.ContinueOnError)
+	limit := fs.Int("limit", 50, "number of failed events to list")
+	requeue := fs.Bool("requeue", false, "requeue the failed events with the given IDs, or all of them when none are given")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	var ids []uint
+	for _, arg := range fs.Args() {
+		id, err := strconv.ParseUint(arg, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid failed event ID %q", arg)
+		}
+		ids = append(ids, uint(id))
+	}
+	if len(ids) > 0 && !*requeue {
+		return fmt.Errorf("usage: %s [--limit <n>] | %s --requeue [id...]", c.Name(), c.Name())
+	}
+
+	if app == nil {
+		return fmt.Errorf("app initialization failed, cannot connect to database")
+	}
+
+	db := app.DBManager.GetConnection()
+
+	if *requeue {
+		count, err := events.RequeueFailedEvents(db, ids)
+		if err != nil {
+			return fmt.Errorf("requeue failed: %w", err)
+		}
+		log.Printf("Requeued %d failed events; they're processed on the next run", count)
+		return nil
+	}
+
+	failed, err := events.ListFailedEvents(db, *limit)
+	if err != nil {
+		return err
+	}
+
+	if len(failed) == 0 {
+		fmt.Println("No failed events")
+		return nil
+	}
+
+	for _, event := range failed {
+		fmt.Printf("%-6d website=%-4d type=%d %s  attempts=%d  failed_at=%s\n       %s\n",
+			event.ID, event.WebsiteID, event.EventType, event.Timestamp.UTC().Format(time.RFC3339),
+			event.Attempts, event.FailedAt.UTC().Format(time.RFC3339), event.Error)
+	}
+	return nil
+}
+
+// OptimizeCommand compacts the database and refreshes planner statistics
+type OptimizeCommand struct{}
+
+func (c *OptimizeCommand) Name() string { return "optimize" }
+func (c *OptimizeCommand) Description() string {
+	return "Runs VACUUM, ANALYZE and PRAGMA optimize (--vacuum-into writes a compacted copy instead)"
+}
+
+func (c *OptimizeCommand) Execute(ctx context.Context, app *internal.Application, args []string) error {
+	fs := flag.NewFlagSet("optimize", flag.ContinueOnError)
+	vacuumInto := fs.String("vacuum-into", "", "write a compacted copy to this path without touching the live database")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if app == nil {
+		return fmt.Errorf("app initialization failed, cannot connect to database")
+	}
+
+	db := app.DBManager.GetConnection()
+	dbPath := config.GetConfig().GetDatabasePath()
+
+	before, err := fileSize(dbPath)
+	if err != nil {
+		return err
+	}
+
+	if *vacuumInto != "" {
+		if err := database.ExportDatabase(db, *vacuumInto); err != nil {
+			return err
+		}
+		after, err := fileSize(*vacuumInto)
+		if err != nil {
+			return err
+		}
+		log.Printf("Compacted copy written to %s: %s -> %s", *vacuumInto, formatBytes(before), formatBytes(after))
+		return nil
+	}
+
+	if err := database.OptimizeDatabase(db, slog.Default()); err != nil {
+		return err
+	}
+
+	after, err := fileSize(dbPath)
+	if err != nil {
+		return err
+	}
+	log.Printf("Database optimized: %s -> %s", formatBytes(before), formatBytes(after))
+	return nil
+}
+
 // Helper functions
 
+// fileSize returns the size of the file at path in bytes
+func fileSize(path string) (int64, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return 0, fmt.Errorf("failed to stat %s: %w", path, err)
+	}
+	return info.Size(), nil
+}
+
+// formatBytes renders a file size with a binary unit, e.g. 1.5 MB
+func formatBytes(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(size)/float64(div), "KMGTPE"[exp])
+}
+
+// parseDateFlag accepts either a plain date or an RFC3339 timestamp, in UTC
+func parseDateFlag(value string) (time.Time, error) {
+	if t, err := time.Parse("2006-01-02", value); err == nil {
+		return t.UTC(), nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return t.UTC(), nil
+}
+
+// parseDaysFlag accepts a positive number of days, optionally suffixed with "d"
+func parseDaysFlag(value string) (int, error) {
+	days, err := strconv.Atoi(strings.TrimSuffix(strings.TrimSpace(value), "d"))
+	if err != nil || days <= 0 {
+		return 0, fmt.Errorf("invalid number of days %q", value)
+	}
+	return days, nil
+}
+
 // parseArgs parses the command name and arguments
 func parseArgs() (string, []string) {
 	args := os.Args[1:]
@@ -54,6 +54,16 @@ func main() {
 			fmt.Printf("Error: %v\n", err)
 			os.Exit(1)
 		}
+	case "backup":
+		if err := m.Exec("/app/fnctl", "backup"); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+	case "list-backups":
+		if err := m.Exec("/app/fnctl", "list-backups"); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
 	case "change-admin-password":
 		if err := runAdminPasswordChange(m); err != nil {
 			fmt.Printf("Error: %v\n", err)
@@ -80,7 +90,7 @@ func newMatcha() *matcha.Matcha {
 	return matcha.New(matcha.Config{
 		Name:           "fusionaly",
 		AppImage:       "karloscodes/fusionaly:latest",
-		HealthPath:     "/_health",
+		HealthPath:     "/_ready",
 		Volumes:        []string{"/app/storage", "/app/logs"},
 		CronUpdates:    true,
 		Backups:        true,
@@ -327,6 +337,8 @@ func printUsage() {
 	fmt.Println("  migrate-to-oss              Switch a Fusionaly Pro install to Fusionaly")
 	fmt.Println("  reload                      Reload containers with latest .env config")
 	fmt.Println("  restore-db                  Interactively restore database from a backup")
+	fmt.Println("  backup                      Back up the database now and prune old backups")
+	fmt.Println("  list-backups                List database backups with sizes and timestamps")
 	fmt.Println("  change-admin-password       Change the admin user password")
 	fmt.Println("  version                     Show version information")
 	fmt.Println("  check                       Check server security")
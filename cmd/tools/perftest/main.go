@@ -30,6 +30,17 @@ import (
 // Global counter for debug messages
 var debugCounter int64
 
+// Load profiles selectable with -profile
+const (
+	ProfileConstant = "constant" // Fixed rate for the whole test
+	ProfileRamp     = "ramp"     // Rate grows linearly from zero to -rate over the duration
+	ProfileSpike    = "spike"    // Idle for the first half, then bursts at -rate
+)
+
+// ratePollInterval bounds how long a worker waits before re-reading the target
+// rate, so rate changes during a ramp or spike are picked up promptly
+const ratePollInterval = 100 * time.Millisecond
+
 // PerfConfig holds the configuration for the performance test
 type PerfConfig struct {
 	BaseURL       string
@@ -37,8 +48,39 @@ type PerfConfig struct {
 	Concurrency   int
 	Duration      time.Duration
 	EventsPerSec  int
+	Profile       string
 	VerboseOutput bool
 	Timeout       time.Duration
+	Payloads      []v1.CreateEventParams // Templates loaded with -payloads; empty uses the synthetic generator
+	SLOs          SLOThresholds
+}
+
+// SLOThresholds are the limits a run must meet for the tool to exit zero.
+// A zero MaxP95 or MinSuccessRate and a negative MaxErrorRate disable that check.
+type SLOThresholds struct {
+	MaxP95         time.Duration
+	MinSuccessRate float64 // percent
+	MaxErrorRate   float64 // percent
+}
+
+// Enabled reports whether any threshold is set
+func (s SLOThresholds) Enabled() bool {
+	return s.MaxP95 > 0 || s.MinSuccessRate > 0 || s.MaxErrorRate >= 0
+}
+
+// String lists the enabled thresholds as their command line flags
+func (s SLOThresholds) String() string {
+	var parts []string
+	if s.MaxP95 > 0 {
+		parts = append(parts, fmt.Sprintf("-max-p95=%v", s.MaxP95))
+	}
+	if s.MinSuccessRate > 0 {
+		parts = append(parts, fmt.Sprintf("-min-success-rate=%g", s.MinSuccessRate))
+	}
+	if s.MaxErrorRate >= 0 {
+		parts = append(parts, fmt.Sprintf("-max-error-rate=%g", s.MaxErrorRate))
+	}
+	return strings.Join(parts, " ")
 }
 
 // PerfStats holds statistics about the performance test
@@ -61,14 +103,21 @@ type PerfStats struct {
 	// Track requests over time for time-series analysis
 	RequestsOverTime map[string]int64 // Map of time bucket (minute) to count
 	RequestTimeMutex sync.Mutex
+	// Track which payload types were sent
+	PayloadTypes      map[string]int64
+	PayloadTypesMutex sync.Mutex
+	// Requests started per second since StartTime, to compare against the profile's target
+	RequestsPerSecond      map[int64]int64
+	RequestsPerSecondMutex sync.Mutex
 }
 
 // Result captures the result of a single request
 type Result struct {
-	Duration   time.Duration
-	StatusCode int
-	Error      error
-	Timestamp  time.Time // When the request was made
+	Duration    time.Duration
+	StatusCode  int
+	Error       error
+	Timestamp   time.Time // When the request was made
+	PayloadType string    // e.g. "pageview" or "custom:revenue:purchased"
 }
 
 func main() {
@@ -77,13 +126,30 @@ func main() {
 	concurrency := flag.Int("c", 10, "Number of concurrent clients")
 	duration := flag.Duration("d", 30*time.Second, "Duration of the test")
 	eventsPerSec := flag.Int("rate", 0, "Target events per second (0 = unlimited)")
+	profile := flag.String("profile", ProfileConstant, "Load profile: constant, ramp (linear increase to -rate) or spike (idle, then burst at -rate)")
 	verbose := flag.Bool("verbose", false, "Enable verbose output")
 	timeout := flag.Duration("timeout", 10*time.Second, "Request timeout")
+	maxP95 := flag.Duration("max-p95", 0, "Fail if p95 latency exceeds this, e.g. 200ms (0 = no limit)")
+	minSuccessRate := flag.Float64("min-success-rate", 0, "Fail if the success rate percentage is below this (0 = no limit)")
+	maxErrorRate := flag.Float64("max-error-rate", -1, "Fail if the error rate percentage is above this (-1 = no limit)")
+	payloadsPath := flag.String("payloads", "", "JSON file with an array of event payload templates, see cmd/tools/perftest/payloads.example.json (default: synthetic page views)")
 	flag.Parse()
 
 	// Initialize logger
 	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}))
 
+	switch *profile {
+	case ProfileConstant:
+	case ProfileRamp, ProfileSpike:
+		if *eventsPerSec <= 0 {
+			logger.Error("The ramp and spike profiles need a target rate", slog.String("profile", *profile))
+			os.Exit(1)
+		}
+	default:
+		logger.Error("Unknown load profile", slog.String("profile", *profile))
+		os.Exit(1)
+	}
+
 	// Get origin from environment or use default (must match a registered website)
 	origin := os.Getenv("FUSIONALY_ORIGIN")
 	if origin == "" {
@@ -100,8 +166,24 @@ func main() {
 		Concurrency:   *concurrency,
 		Duration:      *duration,
 		EventsPerSec:  *eventsPerSec,
+		Profile:       *profile,
 		VerboseOutput: *verbose,
 		Timeout:       *timeout,
+		SLOs: SLOThresholds{
+			MaxP95:         *maxP95,
+			MinSuccessRate: *minSuccessRate,
+			MaxErrorRate:   *maxErrorRate,
+		},
+	}
+
+	if *payloadsPath != "" {
+		payloads, err := loadPayloads(*payloadsPath)
+		if err != nil {
+			logger.Error("Failed to load payloads", slog.String("path", *payloadsPath), slog.Any("error", err))
+			os.Exit(1)
+		}
+		config.Payloads = payloads
+		logger.Info("Loaded payload templates", slog.String("path", *payloadsPath), slog.Int("count", len(payloads)))
 	}
 
 	// Set up signal handling
@@ -126,22 +208,33 @@ func main() {
 	fmt.Printf("  Concurrency (-c):     %d - Number of concurrent clients sending requests\n", config.Concurrency)
 	fmt.Printf("  Duration (-d):        %v - How long the test will run\n", config.Duration)
 	fmt.Printf("  Events/sec (-rate):   %d - Target events per second (0 = unlimited requests)\n", config.EventsPerSec)
+	fmt.Printf("  Profile (-profile):   %s - How the target rate changes over the test\n", config.Profile)
+	if config.SLOs.Enabled() {
+		fmt.Printf("  SLOs:                 %s - Exit non-zero when violated\n", config.SLOs)
+	}
 	fmt.Printf("  Timeout (-timeout):   %v - Maximum time to wait for each request\n", config.Timeout)
 	fmt.Printf("  Verbose (-verbose):   %v - Whether to show detailed output\n", config.VerboseOutput)
+	if len(config.Payloads) > 0 {
+		fmt.Printf("  Payloads (-payloads): %s - %d templates selected uniformly at random\n", *payloadsPath, len(config.Payloads))
+	} else {
+		fmt.Println("  Payloads (-payloads): none - synthetic page views")
+	}
 	fmt.Println("================================================")
 
 	// Initialize stats
 	stats := &PerfStats{
-		StatusCodes:      make(map[int]int64),
-		StatusCodesMutex: sync.Mutex{},
-		StartTime:        time.Now(),
+		StatusCodes:       make(map[int]int64),
+		StatusCodesMutex:  sync.Mutex{},
+		PayloadTypes:      make(map[string]int64),
+		RequestsPerSecond: make(map[int64]int64),
+		StartTime:         time.Now(),
 	}
 
 	// Run the test
 	fmt.Printf("Starting performance test with %d concurrent clients for %v\n", config.Concurrency, config.Duration)
 	fmt.Printf("Target URL: %s/x/api/v1/events\n", config.BaseURL)
 	if config.EventsPerSec > 0 {
-		fmt.Printf("Target rate: %d events/second\n", config.EventsPerSec)
+		fmt.Printf("Target rate: %d events/second (%s profile)\n", config.EventsPerSec, config.Profile)
 	} else {
 		fmt.Println("Target rate: unlimited")
 	}
@@ -151,7 +244,7 @@ func main() {
 	defer testCancel()
 
 	// Run the test
-	resultChan := runTest(testCtx, config, logger)
+	resultChan := runTest(testCtx, config, stats.StartTime, logger)
 
 	// Process results
 	for result := range resultChan {
@@ -163,24 +256,73 @@ func main() {
 	stats.TotalDuration = stats.EndTime.Sub(stats.StartTime)
 
 	// Print results
-	printResults(stats)
+	printResults(stats, config)
+
+	// Fail the run (e.g. in CI) when any SLO threshold is violated
+	if violations := evaluateSLOs(stats, config.SLOs); len(violations) > 0 {
+		fmt.Println("\nSLO check FAILED:")
+		for _, violation := range violations {
+			fmt.Printf("  - %s\n", violation)
+		}
+		os.Exit(1)
+	}
 
 	// Cleanup
 	fmt.Println("Test completed successfully!")
 }
 
+// evaluateSLOs checks the final stats against the thresholds and returns a
+// message for each violation; an empty result means the run passed
+func evaluateSLOs(stats *PerfStats, slos SLOThresholds) []string {
+	if !slos.Enabled() {
+		return nil
+	}
+	if stats.TotalRequests == 0 {
+		return []string{"no requests completed"}
+	}
+
+	var violations []string
+
+	if slos.MaxP95 > 0 {
+		if p95 := percentileLatency(stats.ResponseTimes, 0.95); p95 > slos.MaxP95 {
+			violations = append(violations, fmt.Sprintf("p95 latency %v exceeds the maximum of %v", p95, slos.MaxP95))
+		}
+	}
+
+	successRate := 100 * float64(stats.SuccessfulRequests) / float64(stats.TotalRequests)
+	if slos.MinSuccessRate > 0 && successRate < slos.MinSuccessRate {
+		violations = append(violations, fmt.Sprintf("success rate %.2f%% is below the minimum of %.2f%%", successRate, slos.MinSuccessRate))
+	}
+
+	errorRate := 100 * float64(stats.FailedRequests) / float64(stats.TotalRequests)
+	if slos.MaxErrorRate >= 0 && errorRate > slos.MaxErrorRate {
+		violations = append(violations, fmt.Sprintf("error rate %.2f%% exceeds the maximum of %.2f%%", errorRate, slos.MaxErrorRate))
+	}
+
+	return violations
+}
+
+// percentileLatency returns the response time at quantile q (0-1), using the
+// same index as the summary dashboard. The input is not modified.
+func percentileLatency(responseTimes []time.Duration, q float64) time.Duration {
+	if len(responseTimes) == 0 {
+		return 0
+	}
+	sorted := append([]time.Duration(nil), responseTimes...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	return sorted[min(len(sorted)-1, int(float64(len(sorted))*q))]
+}
+
 // runTest starts the performance test and returns a channel for results
-func runTest(ctx context.Context, config *PerfConfig, logger *slog.Logger) <-chan Result {
+func runTest(ctx context.Context, config *PerfConfig, start time.Time, logger *slog.Logger) <-chan Result {
 	resultChan := make(chan Result, config.Concurrency*10)
 	var wg sync.WaitGroup
 
-	// Calculate requests per worker based on rate limit
-	requestsPerSecPerWorker := 0.0
 	if config.EventsPerSec > 0 {
-		requestsPerSecPerWorker = float64(config.EventsPerSec) / float64(config.Concurrency)
 		logger.Info("Rate limiting enabled",
 			slog.Int("totalRequestsPerSec", config.EventsPerSec),
-			slog.Float64("requestsPerSecPerWorker", requestsPerSecPerWorker))
+			slog.Float64("requestsPerSecPerWorker", float64(config.EventsPerSec)/float64(config.Concurrency)),
+			slog.String("profile", config.Profile))
 	} else {
 		logger.Info("No rate limiting, running at maximum speed")
 	}
@@ -194,29 +336,19 @@ func runTest(ctx context.Context, config *PerfConfig, logger *slog.Logger) <-cha
 				Timeout: config.Timeout,
 			}
 
-			// Create a ticker for this worker if rate limiting is enabled
-			var ticker *time.Ticker
-			if requestsPerSecPerWorker > 0 {
-				interval := time.Duration(float64(time.Second) / requestsPerSecPerWorker)
-				ticker = time.NewTicker(interval)
-				defer ticker.Stop()
-			}
-
+			var lastSend time.Time
 			for {
 				select {
 				case <-ctx.Done():
 					// Context canceled or timed out
 					return
 				default:
-					// Rate limiting if enabled
-					if ticker != nil {
-						select {
-						case <-ticker.C:
-							// Time to send next request
-						case <-ctx.Done():
-							return
-						}
+					// Rate limiting if enabled: the per-worker interval is recomputed
+					// from the profile's current target rate before every request
+					if config.EventsPerSec > 0 && !waitForNextSend(ctx, config, start, lastSend) {
+						return
 					}
+					lastSend = time.Now()
 
 					// Send a request
 					result := sendRequest(client, config, workerID)
@@ -240,13 +372,59 @@ func runTest(ctx context.Context, config *PerfConfig, logger *slog.Logger) <-cha
 	return resultChan
 }
 
+// waitForNextSend blocks until a worker that last sent at lastSend may send
+// again under the current target rate. It returns false if ctx is done first.
+func waitForNextSend(ctx context.Context, config *PerfConfig, start, lastSend time.Time) bool {
+	for {
+		wait := ratePollInterval
+		perWorker := targetRate(config, time.Since(start)) / float64(config.Concurrency)
+		if perWorker > 0 {
+			interval := time.Duration(float64(time.Second) / perWorker)
+			wait = time.Until(lastSend.Add(interval))
+			if wait <= 0 {
+				return true
+			}
+			wait = min(wait, ratePollInterval)
+		}
+
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return false
+		}
+	}
+}
+
+// targetRate returns the total requests per second the profile asks for
+// after elapsed time into the test
+func targetRate(config *PerfConfig, elapsed time.Duration) float64 {
+	rate := float64(config.EventsPerSec)
+	if config.Duration <= 0 {
+		return rate
+	}
+	progress := math.Min(1, float64(elapsed)/float64(config.Duration))
+
+	switch config.Profile {
+	case ProfileRamp:
+		return rate * progress
+	case ProfileSpike:
+		if progress < 0.5 {
+			return 0
+		}
+		return rate
+	default:
+		return rate
+	}
+}
+
 // sendRequest sends a single request to the API
 func sendRequest(client *http.Client, config *PerfConfig, workerID int) Result {
 	// Prepare the request payload
 	eventData := generateEventData(config, workerID)
+	payloadType := payloadTypeOf(eventData)
 	jsonData, err := json.Marshal(eventData)
 	if err != nil {
-		return Result{Error: fmt.Errorf("failed to marshal JSON: %w", err)}
+		return Result{Error: fmt.Errorf("failed to marshal JSON: %w", err), PayloadType: payloadType}
 	}
 
 	// Log event data for debugging (only for the first few events)
@@ -264,7 +442,7 @@ func sendRequest(client *http.Client, config *PerfConfig, workerID int) Result {
 	// Create the request
 	req, err := http.NewRequest("POST", config.BaseURL+"/x/api/v1/events", bytes.NewBuffer(jsonData))
 	if err != nil {
-		return Result{Error: fmt.Errorf("failed to create request: %w", err)}
+		return Result{Error: fmt.Errorf("failed to create request: %w", err), PayloadType: payloadType}
 	}
 
 	// Set headers
@@ -281,7 +459,7 @@ func sendRequest(client *http.Client, config *PerfConfig, workerID int) Result {
 	duration := time.Since(startTime)
 
 	if err != nil {
-		return Result{Duration: duration, Error: fmt.Errorf("request failed: %w", err), Timestamp: startTime}
+		return Result{Duration: duration, Error: fmt.Errorf("request failed: %w", err), Timestamp: startTime, PayloadType: payloadType}
 	}
 	defer resp.Body.Close()
 
@@ -303,10 +481,11 @@ func sendRequest(client *http.Client, config *PerfConfig, workerID int) Result {
 	atomic.AddInt64(&debugCounter, 1)
 
 	return Result{
-		Duration:   duration,
-		StatusCode: resp.StatusCode,
-		Error:      nil,
-		Timestamp:  startTime,
+		Duration:    duration,
+		StatusCode:  resp.StatusCode,
+		Error:       nil,
+		Timestamp:   startTime,
+		PayloadType: payloadType,
 	}
 }
 
@@ -315,6 +494,10 @@ func generateEventData(config *PerfConfig, workerID int) v1.CreateEventParams {
 	randSource := rand.NewSource(time.Now().UnixNano() + int64(workerID))
 	randGen := rand.New(randSource)
 
+	if len(config.Payloads) > 0 {
+		return eventFromTemplate(config.Payloads[randGen.Intn(len(config.Payloads))], randGen, workerID)
+	}
+
 	// Generate a random URL path
 	paths := []string{
 		"/",
@@ -346,20 +529,10 @@ func generateEventData(config *PerfConfig, workerID int) v1.CreateEventParams {
 		referrer = referrers[randGen.Intn(len(referrers))]
 	}
 
-	// Generate a timestamp in the past (within the last 12 hours)
-	// This ensures events fall within the dashboard's query range
-	now := time.Now().UTC()
-	hoursAgo := randGen.Intn(12) + 1 // Random time between 1-12 hours ago
-	minutesAgo := randGen.Intn(60)
-	secondsAgo := randGen.Intn(60)
-	pastTime := now.Add(-time.Duration(hoursAgo)*time.Hour -
-		time.Duration(minutesAgo)*time.Minute -
-		time.Duration(secondsAgo)*time.Second)
-
 	return v1.CreateEventParams{
 		URL:       url,
 		Referrer:  referrer,
-		Timestamp: pastTime,
+		Timestamp: randomPastTime(randGen),
 		EventType: events.EventTypePageView,
 		UserID:    fmt.Sprintf("test-user-%d-%d", workerID, randGen.Intn(1000)),
 		UserAgent: generateUserAgent(),
@@ -371,6 +544,81 @@ func generateEventData(config *PerfConfig, workerID int) v1.CreateEventParams {
 	}
 }
 
+// randomPastTime returns a timestamp within the last 12 hours.
+// This ensures events fall within the dashboard's query range
+func randomPastTime(randGen *rand.Rand) time.Time {
+	hoursAgo := randGen.Intn(12) + 1 // Random time between 1-12 hours ago
+	minutesAgo := randGen.Intn(60)
+	secondsAgo := randGen.Intn(60)
+	return time.Now().UTC().Add(-time.Duration(hoursAgo)*time.Hour -
+		time.Duration(minutesAgo)*time.Minute -
+		time.Duration(secondsAgo)*time.Second)
+}
+
+// loadPayloads reads an array of event templates from a JSON file and checks
+// that each one is a payload the events API would accept
+func loadPayloads(path string) ([]v1.CreateEventParams, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var payloads []v1.CreateEventParams
+	if err := json.Unmarshal(data, &payloads); err != nil {
+		return nil, fmt.Errorf("expected a JSON array of event payloads: %w", err)
+	}
+	if len(payloads) == 0 {
+		return nil, fmt.Errorf("payload file contains no events")
+	}
+
+	for i, payload := range payloads {
+		if payload.URL == "" {
+			return nil, fmt.Errorf("payload %d: url is required", i)
+		}
+		switch payload.EventType {
+		case events.EventTypePageView:
+		case events.EventTypeCustomEvent, events.EventTypeDownload:
+			if payload.EventKey == "" {
+				return nil, fmt.Errorf("payload %d: eventKey is required for eventType %d", i, payload.EventType)
+			}
+		default:
+			return nil, fmt.Errorf("payload %d: unsupported eventType %d", i, payload.EventType)
+		}
+	}
+
+	return payloads, nil
+}
+
+// eventFromTemplate fills in the per-request fields a template leaves empty
+// so repeated sends look like distinct visitors
+func eventFromTemplate(template v1.CreateEventParams, randGen *rand.Rand, workerID int) v1.CreateEventParams {
+	event := template
+	if event.Timestamp.IsZero() {
+		event.Timestamp = randomPastTime(randGen)
+	}
+	if event.UserID == "" {
+		event.UserID = fmt.Sprintf("test-user-%d-%d", workerID, randGen.Intn(1000))
+	}
+	if event.UserAgent == "" {
+		event.UserAgent = generateUserAgent()
+	}
+	return event
+}
+
+// payloadTypeOf labels an event for the payload type summary
+func payloadTypeOf(event v1.CreateEventParams) string {
+	switch event.EventType {
+	case events.EventTypePageView:
+		return "pageview"
+	case events.EventTypeCustomEvent:
+		return "custom:" + event.EventKey
+	case events.EventTypeDownload:
+		return "download:" + event.EventKey
+	default:
+		return fmt.Sprintf("type-%d", event.EventType)
+	}
+}
+
 // generateUserAgent returns a random user agent string
 func generateUserAgent() string {
 	userAgents := []string{
@@ -390,6 +638,20 @@ func generateUserAgent() string {
 func processResult(result Result, stats *PerfStats) {
 	atomic.AddInt64(&stats.TotalRequests, 1)
 
+	if result.PayloadType != "" {
+		stats.PayloadTypesMutex.Lock()
+		stats.PayloadTypes[result.PayloadType]++
+		stats.PayloadTypesMutex.Unlock()
+	}
+
+	// Track achieved throughput per second of the test
+	if !result.Timestamp.IsZero() {
+		second := int64(max(0, result.Timestamp.Sub(stats.StartTime)/time.Second))
+		stats.RequestsPerSecondMutex.Lock()
+		stats.RequestsPerSecond[second]++
+		stats.RequestsPerSecondMutex.Unlock()
+	}
+
 	if result.Error != nil {
 		atomic.AddInt64(&stats.FailedRequests, 1)
 		return
@@ -439,7 +701,7 @@ func processResult(result Result, stats *PerfStats) {
 }
 
 // printResults displays the test results in a nicely formatted table
-func printResults(stats *PerfStats) {
+func printResults(stats *PerfStats, config *PerfConfig) {
 	fmt.Println("\nPerformance Test Results:")
 	fmt.Printf("Test Duration: %v\n", stats.TotalDuration.Round(time.Millisecond))
 	fmt.Printf("Start Time: %v\n", stats.StartTime.Format(time.RFC3339))
@@ -511,6 +773,26 @@ func printResults(stats *PerfStats) {
 		w.Flush()
 	}
 
+	// Show which payload types were exercised
+	if len(stats.PayloadTypes) > 0 {
+		fmt.Println("\nPayload Types Exercised:")
+		w = tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintf(w, "%s\t%s\t%s\n", "PAYLOAD TYPE", "COUNT", "PERCENTAGE")
+		fmt.Fprintf(w, "%s\t%s\t%s\n", "------------", "-----", "----------")
+
+		var payloadTypes []string
+		for payloadType := range stats.PayloadTypes {
+			payloadTypes = append(payloadTypes, payloadType)
+		}
+		sort.Strings(payloadTypes)
+
+		for _, payloadType := range payloadTypes {
+			count := stats.PayloadTypes[payloadType]
+			fmt.Fprintf(w, "%s\t%d\t%.2f%%\n", payloadType, count, 100*float64(count)/float64(stats.TotalRequests))
+		}
+		w.Flush()
+	}
+
 	// Create response time histogram
 	if len(stats.ResponseTimes) > 0 {
 		printResponseTimeHistogram(stats)
@@ -521,11 +803,79 @@ func printResults(stats *PerfStats) {
 		printRequestsOverTime(stats)
 	}
 
+	// Print achieved vs target throughput, to see whether the server kept up
+	samples := rpsOverTime(stats, config)
+	if len(samples) > 0 {
+		printRPSOverTime(samples, config)
+	}
+
 	// Print summary dashboard at the end
 	printSummaryDashboard(stats, requestsPerSecond, avgLatency)
 
 	// Export results to JSON
-	exportResults(stats, requestsPerSecond, avgLatency)
+	exportResults(stats, config, samples, requestsPerSecond, avgLatency)
+}
+
+// RPSSample is the throughput over one window of the test
+type RPSSample struct {
+	Second      int64   `json:"second"` // Window start, in seconds since the test started
+	TargetRPS   float64 `json:"targetRps"`
+	AchievedRPS float64 `json:"achievedRps"`
+}
+
+// rpsOverTime groups per-second request counts into at most ~30 windows
+func rpsOverTime(stats *PerfStats, config *PerfConfig) []RPSSample {
+	if len(stats.RequestsPerSecond) == 0 {
+		return nil
+	}
+
+	var lastSecond int64
+	for second := range stats.RequestsPerSecond {
+		lastSecond = max(lastSecond, second)
+	}
+	windowSize := max(1, int64(math.Ceil(config.Duration.Seconds()/30)))
+
+	var samples []RPSSample
+	for windowStart := int64(0); windowStart <= lastSecond; windowStart += windowSize {
+		windowEnd := min(windowStart+windowSize, lastSecond+1)
+		var count int64
+		for second := windowStart; second < windowEnd; second++ {
+			count += stats.RequestsPerSecond[second]
+		}
+
+		sample := RPSSample{
+			Second:      windowStart,
+			AchievedRPS: float64(count) / float64(windowEnd-windowStart),
+		}
+		if config.EventsPerSec > 0 {
+			midpoint := time.Duration(float64(windowStart+windowEnd) / 2 * float64(time.Second))
+			sample.TargetRPS = targetRate(config, midpoint)
+		}
+		samples = append(samples, sample)
+	}
+	return samples
+}
+
+// printRPSOverTime prints achieved throughput next to the profile's target
+func printRPSOverTime(samples []RPSSample, config *PerfConfig) {
+	fmt.Printf("\nThroughput Over Time (%s profile):\n", config.Profile)
+
+	maxRPS := 1.0
+	for _, sample := range samples {
+		maxRPS = math.Max(maxRPS, math.Max(sample.AchievedRPS, sample.TargetRPS))
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", "SECOND", "TARGET RPS", "ACHIEVED RPS", "GRAPH")
+	fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", "------", "----------", "------------", "-----")
+	for _, sample := range samples {
+		target := "unlimited"
+		if config.EventsPerSec > 0 {
+			target = fmt.Sprintf("%.1f", sample.TargetRPS)
+		}
+		fmt.Fprintf(w, "%d\t%s\t%.1f\t%s\n", sample.Second, target, sample.AchievedRPS, createScaledBar(sample.AchievedRPS, maxRPS, 50))
+	}
+	w.Flush()
 }
 
 // printResponseTimeHistogram generates and prints a histogram of response times
@@ -746,7 +1096,7 @@ func createScaledBar(value float64, maxValue float64, maxLength int) string {
 }
 
 // exportResults saves test results to a JSON file for external visualization
-func exportResults(stats *PerfStats, rps float64, avgLatency time.Duration) {
+func exportResults(stats *PerfStats, config *PerfConfig, samples []RPSSample, rps float64, avgLatency time.Duration) {
 	// Calculate percentiles
 	var p50, p90, p95, p99 int64
 	totalResponses := len(stats.ResponseTimes)
@@ -764,6 +1114,8 @@ func exportResults(stats *PerfStats, rps float64, avgLatency time.Duration) {
 	// Create result object
 	result := map[string]interface{}{
 		"summary": map[string]interface{}{
+			"profile":            config.Profile,
+			"targetRate":         config.EventsPerSec,
 			"totalRequests":      stats.TotalRequests,
 			"successfulRequests": stats.SuccessfulRequests,
 			"failedRequests":     stats.FailedRequests,
@@ -782,6 +1134,7 @@ func exportResults(stats *PerfStats, rps float64, avgLatency time.Duration) {
 		},
 		"statusCodes": stats.StatusCodes,
 		"timeSeries":  stats.RequestsOverTime,
+		"rpsOverTime": samples,
 	}
 
 	// Convert to JSON
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEvaluateSLOs(t *testing.T) {
+	// 100 requests, 98 successful, latencies 1ms..100ms (p95 = 96ms)
+	newStats := func() *PerfStats {
+		stats := &PerfStats{TotalRequests: 100, SuccessfulRequests: 98, FailedRequests: 2}
+		for i := 1; i <= 100; i++ {
+			stats.ResponseTimes = append(stats.ResponseTimes, time.Duration(i)*time.Millisecond)
+		}
+		return stats
+	}
+	disabled := SLOThresholds{MaxErrorRate: -1}
+
+	tests := []struct {
+		name       string
+		slos       SLOThresholds
+		violations []string
+	}{
+		{
+			name: "no thresholds",
+			slos: disabled,
+		},
+		{
+			name: "all thresholds met",
+			slos: SLOThresholds{MaxP95: 200 * time.Millisecond, MinSuccessRate: 98, MaxErrorRate: 2},
+		},
+		{
+			name:       "p95 too slow",
+			slos:       SLOThresholds{MaxP95: 50 * time.Millisecond, MaxErrorRate: -1},
+			violations: []string{"p95 latency 96ms exceeds the maximum of 50ms"},
+		},
+		{
+			name:       "success rate too low",
+			slos:       SLOThresholds{MinSuccessRate: 99, MaxErrorRate: -1},
+			violations: []string{"success rate 98.00% is below the minimum of 99.00%"},
+		},
+		{
+			name:       "error rate too high",
+			slos:       SLOThresholds{MaxErrorRate: 1},
+			violations: []string{"error rate 2.00% exceeds the maximum of 1.00%"},
+		},
+		{
+			name: "zero error rate allowed",
+			slos: SLOThresholds{MaxErrorRate: 0},
+			violations: []string{
+				"error rate 2.00% exceeds the maximum of 0.00%",
+			},
+		},
+		{
+			name: "several violations",
+			slos: SLOThresholds{MaxP95: 10 * time.Millisecond, MinSuccessRate: 99.9, MaxErrorRate: 0.5},
+			violations: []string{
+				"p95 latency 96ms exceeds the maximum of 10ms",
+				"success rate 98.00% is below the minimum of 99.90%",
+				"error rate 2.00% exceeds the maximum of 0.50%",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.violations, evaluateSLOs(newStats(), tt.slos))
+		})
+	}
+
+	t.Run("fails when nothing completed", func(t *testing.T) {
+		assert.Equal(t, []string{"no requests completed"}, evaluateSLOs(&PerfStats{}, SLOThresholds{MinSuccessRate: 99, MaxErrorRate: -1}))
+		assert.Empty(t, evaluateSLOs(&PerfStats{}, disabled))
+	})
+
+	t.Run("does not reorder response times", func(t *testing.T) {
+		stats := &PerfStats{TotalRequests: 3, SuccessfulRequests: 3, ResponseTimes: []time.Duration{3, 1, 2}}
+		evaluateSLOs(stats, SLOThresholds{MaxP95: time.Second, MaxErrorRate: -1})
+		assert.Equal(t, []time.Duration{3, 1, 2}, stats.ResponseTimes)
+	})
+}
@@ -507,9 +507,9 @@ RULES:
 - NEVER specify colors in vega_spec - let the app's theme handle colors
 
 CRITICAL - Table and Column names (use EXACTLY these):
-- Table ref_stats: hostname (for referrer domain), pathname, visitors_count, page_views_count
-- Table site_stats: visitors, page_views, sessions, bounce_count
-- Table page_stats: pathname, visitors_count, page_views_count, entrances, exits
+- Table ref_stats: hostname (for referrer domain), pathname, visitors_count, page_views_count, entrances, bounce_count
+- Table site_stats: visitors, new_visitors, returning_visitors, page_views, sessions, bounce_count
+- Table page_stats: pathname, visitors_count, page_views_count, entrances, exits, bounce_count
 - Table country_stats: country (lowercase ISO codes: us, gb, de, fr, jp)
 - NEVER use referrer_domain, referrer_stats, or pageviews - those don't exist
 
@@ -733,9 +733,9 @@ FOLLOW-UP RULES:
 - Make them specific to the topic, not generic
 
 CRITICAL - Table and Column names (use EXACTLY these):
-- Table ref_stats: hostname (referrer domain), pathname, visitors_count, page_views_count
-- Table site_stats: visitors, page_views, sessions, bounce_count
-- Table page_stats: pathname, visitors_count, page_views_count, entrances, exits
+- Table ref_stats: hostname (referrer domain), pathname, visitors_count, page_views_count, entrances, bounce_count
+- Table site_stats: visitors, new_visitors, returning_visitors, page_views, sessions, bounce_count
+- Table page_stats: pathname, visitors_count, page_views_count, entrances, exits, bounce_count
 - Table country_stats: country (lowercase ISO codes like "us", "gb", "de")
 - NEVER use referrer_domain or referrer_stats - those don't exist
 
@@ -0,0 +1,283 @@
+// Package alerts evaluates per-website alert rules against current traffic and
+// notifies by email or webhook, with a cool-down between notifications.
+package alerts
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/mail"
+	"net/url"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Metric is the traffic measure an alert rule watches
+type Metric string
+
+const (
+	MetricVisitors  Metric = "visitors"
+	MetricPageViews Metric = "page_views"
+	MetricSessions  Metric = "sessions"
+	MetricRevenue   Metric = "revenue"
+)
+
+// Operator compares a metric against its threshold
+type Operator string
+
+const (
+	OperatorLessThan       Operator = "lt"
+	OperatorLessOrEqual    Operator = "lte"
+	OperatorGreaterThan    Operator = "gt"
+	OperatorGreaterOrEqual Operator = "gte"
+)
+
+// Baseline decides what the threshold is measured against
+type Baseline string
+
+const (
+	// BaselineAbsolute compares the metric with the threshold itself
+	BaselineAbsolute Baseline = "absolute"
+	// BaselineRollingAverage treats the threshold as a percentage of the
+	// metric's average over the same time of day on the previous days
+	BaselineRollingAverage Baseline = "rolling_average"
+)
+
+// Channel is where a fired alert is sent
+type Channel string
+
+const (
+	ChannelEmail   Channel = "email"
+	ChannelWebhook Channel = "webhook"
+)
+
+const (
+	// DefaultCooldownMinutes is the minimum gap between two notifications of a rule
+	DefaultCooldownMinutes = 360
+	// DefaultBaselineDays is how many previous days a rolling baseline averages
+	DefaultBaselineDays = 7
+	// MaxBaselineDays bounds the rolling baseline window
+	MaxBaselineDays = 90
+)
+
+// webhookSecretPrefix marks signing secrets so they are recognizable in receiver configs
+const webhookSecretPrefix = "whsec_"
+
+// Rule is an alert condition on one website's traffic for the current UTC
+// day so far, e.g. "visitors lt 50% of the 7-day rolling average". Absolute
+// "below" rules are daily floors instead, checked against the previous full
+// UTC day (see JudgesPreviousDay).
+type Rule struct {
+	ID              uint       `gorm:"primaryKey" json:"id"`
+	WebsiteID       uint       `gorm:"not null;index" json:"website_id"`
+	Name            string     `gorm:"not null" json:"name"`
+	Metric          Metric     `gorm:"not null;size:50" json:"metric"`
+	Operator        Operator   `gorm:"not null;size:10" json:"operator"`
+	Threshold       float64    `gorm:"not null" json:"threshold"`
+	Baseline        Baseline   `gorm:"not null;size:50;default:'absolute'" json:"baseline"`
+	BaselineDays    int        `gorm:"not null;default:0" json:"baseline_days"`
+	Channel         Channel    `gorm:"not null;size:20" json:"channel"`
+	Target          string     `gorm:"not null" json:"target"` // email address or webhook URL
+	Secret          string     `json:"-"`                      // webhook channel: signs deliveries
+	CooldownMinutes int        `gorm:"not null;default:360" json:"cooldown_minutes"`
+	LastFiredAt     *time.Time `json:"last_fired_at"`
+	CreatedAt       time.Time  `json:"created_at"`
+}
+
+// TableName sets the table name for Rule
+func (Rule) TableName() string {
+	return "alert_rules"
+}
+
+// Validate checks the rule is complete and fills in defaults
+func (r *Rule) Validate() error {
+	r.Name = strings.TrimSpace(r.Name)
+	r.Target = strings.TrimSpace(r.Target)
+	if r.Name == "" {
+		return fmt.Errorf("alert name is required")
+	}
+
+	switch r.Metric {
+	case MetricVisitors, MetricPageViews, MetricSessions, MetricRevenue:
+	default:
+		return fmt.Errorf("unknown alert metric %q", r.Metric)
+	}
+
+	switch r.Operator {
+	case OperatorLessThan, OperatorLessOrEqual, OperatorGreaterThan, OperatorGreaterOrEqual:
+	default:
+		return fmt.Errorf("unknown alert operator %q", r.Operator)
+	}
+
+	if r.Threshold < 0 {
+		return fmt.Errorf("alert threshold can't be negative")
+	}
+
+	switch r.Baseline {
+	case "", BaselineAbsolute:
+		r.Baseline = BaselineAbsolute
+		r.BaselineDays = 0
+	case BaselineRollingAverage:
+		if r.BaselineDays == 0 {
+			r.BaselineDays = DefaultBaselineDays
+		}
+		if r.BaselineDays < 1 || r.BaselineDays > MaxBaselineDays {
+			return fmt.Errorf("baseline days must be between 1 and %d", MaxBaselineDays)
+		}
+	default:
+		return fmt.Errorf("unknown alert baseline %q", r.Baseline)
+	}
+
+	switch r.Channel {
+	case ChannelEmail:
+		if _, err := mail.ParseAddress(r.Target); err != nil {
+			return fmt.Errorf("alert email address is invalid")
+		}
+	case ChannelWebhook:
+		parsed, err := url.Parse(r.Target)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			return fmt.Errorf("alert webhook URL must be an absolute http or https URL")
+		}
+	default:
+		return fmt.Errorf("unknown alert channel %q", r.Channel)
+	}
+
+	if r.CooldownMinutes == 0 {
+		r.CooldownMinutes = DefaultCooldownMinutes
+	}
+	if r.CooldownMinutes < 0 {
+		return fmt.Errorf("cool-down can't be negative")
+	}
+	return nil
+}
+
+// JudgesPreviousDay reports whether the rule is checked against the previous
+// full UTC day rather than today so far. An absolute "below" threshold is a
+// daily floor, which today's partial count would cross right after midnight.
+func (r *Rule) JudgesPreviousDay() bool {
+	below := r.Operator == OperatorLessThan || r.Operator == OperatorLessOrEqual
+	return below && r.Baseline != BaselineRollingAverage
+}
+
+// CoolingDown reports whether the rule fired too recently to fire again at
+// now. Rules judging the previous day fire at most once per UTC day, since
+// their value doesn't change until the next one.
+func (r *Rule) CoolingDown(now time.Time) bool {
+	if r.LastFiredAt == nil {
+		return false
+	}
+	if r.JudgesPreviousDay() && !r.LastFiredAt.Before(startOfDay(now)) {
+		return true
+	}
+	return now.Before(r.LastFiredAt.Add(time.Duration(r.CooldownMinutes) * time.Minute))
+}
+
+// startOfDay returns midnight (UTC) of t's UTC day
+func startOfDay(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// EffectiveThreshold is the value the metric is compared with: the threshold
+// itself, or that percentage of the baseline average for rolling rules.
+func (r *Rule) EffectiveThreshold(baseline float64) float64 {
+	if r.Baseline == BaselineRollingAverage {
+		return baseline * r.Threshold / 100
+	}
+	return r.Threshold
+}
+
+// Crossed reports whether value meets the rule's condition given the baseline
+// average (ignored by absolute rules). Rolling rules never fire without any
+// baseline traffic, since every percentage of zero is zero.
+func (r *Rule) Crossed(value, baseline float64) bool {
+	if r.Baseline == BaselineRollingAverage && baseline <= 0 {
+		return false
+	}
+
+	threshold := r.EffectiveThreshold(baseline)
+	switch r.Operator {
+	case OperatorLessThan:
+		return value < threshold
+	case OperatorLessOrEqual:
+		return value <= threshold
+	case OperatorGreaterThan:
+		return value > threshold
+	case OperatorGreaterOrEqual:
+		return value >= threshold
+	}
+	return false
+}
+
+// Describe renders the rule's condition for humans, e.g. "visitors < 50% of the 7-day average"
+func (r *Rule) Describe() string {
+	symbols := map[Operator]string{
+		OperatorLessThan:       "<",
+		OperatorLessOrEqual:    "<=",
+		OperatorGreaterThan:    ">",
+		OperatorGreaterOrEqual: ">=",
+	}
+	if r.Baseline == BaselineRollingAverage {
+		return fmt.Sprintf("%s %s %g%% of the %d-day average", r.Metric, symbols[r.Operator], r.Threshold, r.BaselineDays)
+	}
+	return fmt.Sprintf("%s %s %g", r.Metric, symbols[r.Operator], r.Threshold)
+}
+
+// CreateRule validates and stores an alert rule. Webhook rules get a freshly
+// generated signing secret.
+func CreateRule(db *gorm.DB, rule *Rule) error {
+	if rule.WebsiteID == 0 {
+		return fmt.Errorf("website ID is required")
+	}
+	if err := rule.Validate(); err != nil {
+		return err
+	}
+
+	if rule.Channel == ChannelWebhook {
+		secret := make([]byte, 24)
+		if _, err := rand.Read(secret); err != nil {
+			return fmt.Errorf("failed to generate alert secret: %w", err)
+		}
+		rule.Secret = webhookSecretPrefix + hex.EncodeToString(secret)
+	}
+
+	return db.Create(rule).Error
+}
+
+// ListRules returns the website's alert rules, newest first
+func ListRules(db *gorm.DB, websiteID uint) ([]Rule, error) {
+	var rules []Rule
+	err := db.Where("website_id = ?", websiteID).Order("created_at desc").Find(&rules).Error
+	return rules, err
+}
+
+// ListAllRules returns every alert rule, for the evaluator
+func ListAllRules(db *gorm.DB) ([]Rule, error) {
+	var rules []Rule
+	err := db.Order("id").Find(&rules).Error
+	return rules, err
+}
+
+// DeleteRule removes an alert rule, scoped to its website
+func DeleteRule(db *gorm.DB, websiteID, ruleID uint) error {
+	result := db.Where("id = ? AND website_id = ?", ruleID, websiteID).Delete(&Rule{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// MarkRuleFired records when a rule last notified, starting its cool-down
+func MarkRuleFired(db *gorm.DB, ruleID uint, at time.Time) error {
+	return db.Model(&Rule{}).Where("id = ?", ruleID).Update("last_fired_at", at.UTC()).Error
+}
+
+// DeleteRulesForWebsite removes every alert rule of a deleted website
+func DeleteRulesForWebsite(db *gorm.DB, websiteID uint) error {
+	return db.Where("website_id = ?", websiteID).Delete(&Rule{}).Error
+}
@@ -0,0 +1,236 @@
+package alerts_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"fusionaly/internal/alerts"
+	"fusionaly/internal/analytics"
+	"fusionaly/internal/testsupport"
+	"fusionaly/internal/webhooks"
+)
+
+func TestRuleCrossed(t *testing.T) {
+	tests := []struct {
+		name     string
+		rule     alerts.Rule
+		value    float64
+		baseline float64
+		want     bool
+	}{
+		{"absolute below", alerts.Rule{Operator: alerts.OperatorLessThan, Threshold: 100}, 99, 0, true},
+		{"absolute at threshold is not below", alerts.Rule{Operator: alerts.OperatorLessThan, Threshold: 100}, 100, 0, false},
+		{"absolute at threshold with lte", alerts.Rule{Operator: alerts.OperatorLessOrEqual, Threshold: 100}, 100, 0, true},
+		{"absolute above", alerts.Rule{Operator: alerts.OperatorGreaterThan, Threshold: 100}, 101, 0, true},
+		{"absolute not above", alerts.Rule{Operator: alerts.OperatorGreaterThan, Threshold: 100}, 100, 0, false},
+		{"absolute at threshold with gte", alerts.Rule{Operator: alerts.OperatorGreaterOrEqual, Threshold: 100}, 100, 0, true},
+		{"rolling drop below half", alerts.Rule{Operator: alerts.OperatorLessThan, Threshold: 50, Baseline: alerts.BaselineRollingAverage}, 49, 100, true},
+		{"rolling within half", alerts.Rule{Operator: alerts.OperatorLessThan, Threshold: 50, Baseline: alerts.BaselineRollingAverage}, 51, 100, false},
+		{"rolling spike above double", alerts.Rule{Operator: alerts.OperatorGreaterThan, Threshold: 200, Baseline: alerts.BaselineRollingAverage}, 201, 100, true},
+		{"rolling without baseline never fires", alerts.Rule{Operator: alerts.OperatorLessThan, Threshold: 50, Baseline: alerts.BaselineRollingAverage}, 0, 0, false},
+		{"rolling spike without baseline never fires", alerts.Rule{Operator: alerts.OperatorGreaterThan, Threshold: 200, Baseline: alerts.BaselineRollingAverage}, 1000, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.rule.Crossed(tt.value, tt.baseline))
+		})
+	}
+}
+
+func TestRuleCoolingDown(t *testing.T) {
+	now := time.Date(2024, 7, 1, 12, 0, 0, 0, time.UTC)
+
+	rule := alerts.Rule{CooldownMinutes: 60}
+	assert.False(t, rule.CoolingDown(now), "a rule that never fired isn't cooling down")
+
+	firedAt := now.Add(-30 * time.Minute)
+	rule.LastFiredAt = &firedAt
+	assert.True(t, rule.CoolingDown(now))
+	assert.False(t, rule.CoolingDown(now.Add(30*time.Minute)), "the cool-down ends after CooldownMinutes")
+}
+
+func TestRuleValidate(t *testing.T) {
+	valid := func() alerts.Rule {
+		return alerts.Rule{
+			Name:      " Traffic drop ",
+			Metric:    alerts.MetricVisitors,
+			Operator:  alerts.OperatorLessThan,
+			Threshold: 50,
+			Baseline:  alerts.BaselineRollingAverage,
+			Channel:   alerts.ChannelEmail,
+			Target:    "ops@example.com",
+		}
+	}
+
+	rule := valid()
+	require.NoError(t, rule.Validate())
+	assert.Equal(t, "Traffic drop", rule.Name)
+	assert.Equal(t, alerts.DefaultBaselineDays, rule.BaselineDays)
+	assert.Equal(t, alerts.DefaultCooldownMinutes, rule.CooldownMinutes)
+
+	absolute := valid()
+	absolute.Baseline = ""
+	absolute.BaselineDays = 14
+	require.NoError(t, absolute.Validate())
+	assert.Equal(t, alerts.BaselineAbsolute, absolute.Baseline)
+	assert.Zero(t, absolute.BaselineDays)
+
+	invalid := map[string]func(*alerts.Rule){
+		"missing name":      func(r *alerts.Rule) { r.Name = " " },
+		"unknown metric":    func(r *alerts.Rule) { r.Metric = "bounce_rate" },
+		"unknown operator":  func(r *alerts.Rule) { r.Operator = "eq" },
+		"negative":          func(r *alerts.Rule) { r.Threshold = -1 },
+		"baseline too long": func(r *alerts.Rule) { r.BaselineDays = alerts.MaxBaselineDays + 1 },
+		"bad email":         func(r *alerts.Rule) { r.Target = "not-an-email" },
+		"relative webhook":  func(r *alerts.Rule) { r.Channel = alerts.ChannelWebhook; r.Target = "/hooks" },
+		"unknown channel":   func(r *alerts.Rule) { r.Channel = "sms" },
+	}
+	for name, mutate := range invalid {
+		t.Run(name, func(t *testing.T) {
+			rule := valid()
+			mutate(&rule)
+			assert.Error(t, rule.Validate())
+		})
+	}
+}
+
+func TestRunFiresWebhookOnceWithinCooldown(t *testing.T) {
+	dbManager, _ := testsupport.SetupTestDBManager(t)
+	db := dbManager.GetConnection()
+	testsupport.CleanAllTables(db)
+
+	website := testsupport.CreateTestWebsite(db, "alerts.com")
+	now := time.Date(2024, 7, 8, 12, 30, 0, 0, time.UTC)
+	dayStart := time.Date(2024, 7, 8, 0, 0, 0, 0, time.UTC)
+
+	// 100 visitors each morning of the previous week, 20 this morning.
+	// Afternoon traffic on previous days falls outside the compared window.
+	var stats []analytics.SiteStat
+	for day := 1; day <= 7; day++ {
+		stats = append(stats,
+			analytics.SiteStat{WebsiteID: website.ID, Visitors: 100, PageViews: 100, Sessions: 100, Hour: dayStart.AddDate(0, 0, -day).Add(9 * time.Hour)},
+			analytics.SiteStat{WebsiteID: website.ID, Visitors: 500, PageViews: 500, Sessions: 500, Hour: dayStart.AddDate(0, 0, -day).Add(18 * time.Hour)},
+		)
+	}
+	stats = append(stats, analytics.SiteStat{WebsiteID: website.ID, Visitors: 20, PageViews: 20, Sessions: 20, Hour: dayStart.Add(9 * time.Hour)})
+	require.NoError(t, db.CreateInBatches(stats, len(stats)).Error)
+
+	var received atomic.Int32
+	var payload webhooks.Payload
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received.Add(1)
+		_ = json.NewDecoder(r.Body).Decode(&payload)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	rule := &alerts.Rule{
+		WebsiteID:       website.ID,
+		Name:            "Traffic drop",
+		Metric:          alerts.MetricVisitors,
+		Operator:        alerts.OperatorLessThan,
+		Threshold:       50,
+		Baseline:        alerts.BaselineRollingAverage,
+		Channel:         alerts.ChannelWebhook,
+		Target:          server.URL,
+		CooldownMinutes: 60,
+	}
+	require.NoError(t, alerts.CreateRule(db, rule))
+	assert.NotEmpty(t, rule.Secret)
+
+	check, err := alerts.EvaluateRule(db, *rule, now)
+	require.NoError(t, err)
+	assert.Equal(t, 20.0, check.Value)
+	assert.Equal(t, 100.0, check.Baseline)
+	assert.Equal(t, 50.0, check.Threshold)
+	assert.True(t, check.Fired)
+
+	notifier := &alerts.Notifier{Sender: &webhooks.Sender{Client: server.Client(), MaxAttempts: 1}}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	require.NoError(t, alerts.Run(context.Background(), db, notifier, logger, now))
+	assert.Equal(t, int32(1), received.Load())
+	assert.Equal(t, webhooks.TriggerAlert, payload.Trigger)
+	require.NotNil(t, payload.Alert)
+	assert.Equal(t, "Traffic drop", payload.Alert.Name)
+	assert.Equal(t, 20.0, payload.Alert.Value)
+
+	require.NoError(t, alerts.Run(context.Background(), db, notifier, logger, now.Add(15*time.Minute)))
+	assert.Equal(t, int32(1), received.Load(), "the rule is cooling down")
+
+	require.NoError(t, alerts.Run(context.Background(), db, notifier, logger, now.Add(61*time.Minute)))
+	assert.Equal(t, int32(2), received.Load(), "the rule fires again once the cool-down is over")
+}
+
+func TestEvaluateAbsoluteBelowRuleJudgesPreviousDay(t *testing.T) {
+	dbManager, _ := testsupport.SetupTestDBManager(t)
+	db := dbManager.GetConnection()
+	testsupport.CleanAllTables(db)
+
+	website := testsupport.CreateTestWebsite(db, "daily-floor.com")
+	day := time.Date(2024, 7, 8, 0, 0, 0, 0, time.UTC)
+
+	// A normal day, then a quiet one starting with an early visit at midnight
+	require.NoError(t, db.Create([]analytics.SiteStat{
+		{WebsiteID: website.ID, Visitors: 40, PageViews: 40, Sessions: 40, Hour: day.AddDate(0, 0, -1).Add(9 * time.Hour)},
+		{WebsiteID: website.ID, Visitors: 60, PageViews: 60, Sessions: 60, Hour: day.AddDate(0, 0, -1).Add(18 * time.Hour)},
+		{WebsiteID: website.ID, Visitors: 5, PageViews: 5, Sessions: 5, Hour: day},
+		{WebsiteID: website.ID, Visitors: 20, PageViews: 20, Sessions: 20, Hour: day.Add(9 * time.Hour)},
+	}).Error)
+
+	rule := &alerts.Rule{
+		WebsiteID:       website.ID,
+		Name:            "Daily floor",
+		Metric:          alerts.MetricVisitors,
+		Operator:        alerts.OperatorLessThan,
+		Threshold:       50,
+		Channel:         alerts.ChannelWebhook,
+		Target:          "https://example.com/hook",
+		CooldownMinutes: 60,
+	}
+	require.NoError(t, alerts.CreateRule(db, rule))
+	require.True(t, rule.JudgesPreviousDay())
+
+	t.Run("doesn't fire just after midnight on a normal day", func(t *testing.T) {
+		check, err := alerts.EvaluateRule(db, *rule, day.Add(15*time.Minute))
+		require.NoError(t, err)
+		assert.True(t, check.PreviousDay)
+		assert.Equal(t, 100.0, check.Value, "yesterday's full day, without today's first hour")
+		assert.False(t, check.Fired)
+	})
+
+	t.Run("fires after a day below the floor", func(t *testing.T) {
+		now := day.AddDate(0, 0, 1).Add(15 * time.Minute)
+		check, err := alerts.EvaluateRule(db, *rule, now)
+		require.NoError(t, err)
+		assert.Equal(t, 25.0, check.Value)
+		assert.Equal(t, "yesterday", check.Window())
+		assert.True(t, check.Fired)
+
+		rule.LastFiredAt = &now
+		assert.True(t, rule.CoolingDown(now.Add(23*time.Hour)), "fires once per day")
+		assert.False(t, rule.CoolingDown(now.Add(24*time.Hour)), "the next day is judged again")
+	})
+
+	t.Run("above-threshold rules still read today so far", func(t *testing.T) {
+		spike := *rule
+		spike.Operator = alerts.OperatorGreaterThan
+		spike.Threshold = 10
+		check, err := alerts.EvaluateRule(db, spike, day.Add(12*time.Hour))
+		require.NoError(t, err)
+		assert.False(t, check.PreviousDay)
+		assert.Equal(t, 25.0, check.Value)
+		assert.True(t, check.Fired)
+	})
+}
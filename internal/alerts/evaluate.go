@@ -0,0 +1,197 @@
+package alerts
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"gorm.io/gorm"
+
+	"fusionaly/internal/analytics"
+	"fusionaly/internal/config"
+	"fusionaly/internal/digest"
+	"fusionaly/internal/timeframe"
+	"fusionaly/internal/webhooks"
+	"fusionaly/internal/websites"
+)
+
+// Check is the outcome of evaluating one rule
+type Check struct {
+	Rule        Rule
+	Value       float64 // the metric for today (UTC) so far, or yesterday when PreviousDay is set
+	PreviousDay bool    // Value covers the previous full UTC day (see Rule.JudgesPreviousDay)
+	Baseline    float64 // rolling rules: average of the same window on previous days
+	Threshold   float64 // what Value was compared with
+	Fired       bool
+}
+
+// Window names the period Value covers: "today" so far or "yesterday" (UTC)
+func (c Check) Window() string {
+	if c.PreviousDay {
+		return "yesterday"
+	}
+	return "today"
+}
+
+// MetricValue computes a rule metric for the website between from and to
+// with the dashboard's analytics functions.
+func MetricValue(db *gorm.DB, websiteID uint, metric Metric, from, to time.Time) (float64, error) {
+	tf := &timeframe.TimeFrame{From: from.UTC(), To: to.UTC(), BucketSize: timeframe.TimeFrameBucketSizeHour}
+	params := analytics.NewWebsiteScopedQueryParams(tf, int(websiteID))
+
+	switch metric {
+	case MetricVisitors:
+		count, err := analytics.GetTotalVisitorsInTimeFrame(db, params)
+		return float64(count), err
+	case MetricPageViews:
+		count, err := analytics.GetTotalPageViewsInTimeFrame(db, params)
+		return float64(count), err
+	case MetricSessions:
+		count, err := analytics.GetTotalSessionsInTimeFrame(db, params)
+		return float64(count), err
+	case MetricRevenue:
+		revenue, err := analytics.GetRevenueMetrics(db, params)
+		if err != nil {
+			return 0, err
+		}
+		return revenue.TotalRevenue, nil
+	}
+	return 0, fmt.Errorf("unknown alert metric %q", metric)
+}
+
+// EvaluateRule compares the metric for today (UTC) up to now against the rule.
+// Rolling rules average the same midnight-to-now window of the previous
+// BaselineDays days, so a partial day is never compared with full days.
+// Absolute below-threshold rules compare the whole previous day instead.
+func EvaluateRule(db *gorm.DB, rule Rule, now time.Time) (Check, error) {
+	now = now.UTC()
+	dayStart := startOfDay(now)
+	check := Check{Rule: rule}
+
+	from, to := dayStart, now
+	if rule.JudgesPreviousDay() {
+		check.PreviousDay = true
+		from, to = dayStart.AddDate(0, 0, -1), dayStart.Add(-time.Second)
+	}
+
+	value, err := MetricValue(db, rule.WebsiteID, rule.Metric, from, to)
+	if err != nil {
+		return check, err
+	}
+	check.Value = value
+
+	if rule.Baseline == BaselineRollingAverage {
+		var total float64
+		for day := 1; day <= rule.BaselineDays; day++ {
+			past, err := MetricValue(db, rule.WebsiteID, rule.Metric, dayStart.AddDate(0, 0, -day), now.AddDate(0, 0, -day))
+			if err != nil {
+				return check, err
+			}
+			total += past
+		}
+		check.Baseline = total / float64(rule.BaselineDays)
+	}
+
+	check.Threshold = rule.EffectiveThreshold(check.Baseline)
+	check.Fired = rule.Crossed(check.Value, check.Baseline)
+	return check, nil
+}
+
+// Notifier sends fired alerts through their rule's channel
+type Notifier struct {
+	Config *config.Config
+	Sender *webhooks.Sender
+}
+
+// Notify sends one fired check for the website with the given domain
+func (n *Notifier) Notify(ctx context.Context, check Check, domain string) error {
+	rule := check.Rule
+	switch rule.Channel {
+	case ChannelEmail:
+		subject := fmt.Sprintf("[Fusionaly] %s: %s", domain, rule.Name)
+		valueLabel := "Today so far"
+		if check.PreviousDay {
+			valueLabel = "Yesterday"
+		}
+		body := fmt.Sprintf("Alert %q fired for %s.\n\nCondition: %s\n%s: %g\nThreshold: %g\n",
+			rule.Name, domain, rule.Describe(), valueLabel, check.Value, check.Threshold)
+		if rule.Baseline == BaselineRollingAverage {
+			body += fmt.Sprintf("%d-day average: %g\n", rule.BaselineDays, check.Baseline)
+		}
+		return digest.SendMail(n.Config, []string{rule.Target}, subject, body)
+
+	case ChannelWebhook:
+		return n.Sender.Send(ctx, webhooks.Delivery{
+			Webhook: websites.Webhook{ID: rule.ID, URL: rule.Target, Secret: rule.Secret},
+			Payload: webhooks.Payload{
+				Trigger:   webhooks.TriggerAlert,
+				WebsiteID: rule.WebsiteID,
+				Domain:    domain,
+				FiredAt:   time.Now().UTC(),
+				Alert: &webhooks.AlertPayload{
+					RuleID:    rule.ID,
+					Name:      rule.Name,
+					Condition: rule.Describe(),
+					Metric:    string(rule.Metric),
+					Value:     check.Value,
+					Window:    check.Window(),
+					Threshold: check.Threshold,
+					Baseline:  check.Baseline,
+				},
+			},
+		})
+	}
+	return fmt.Errorf("unknown alert channel %q", rule.Channel)
+}
+
+// Run evaluates every rule that isn't cooling down and notifies the ones that
+// fire. A rule's cool-down only starts once its notification went out, so a
+// failed delivery is retried on the next run.
+func Run(ctx context.Context, db *gorm.DB, notifier *Notifier, logger *slog.Logger, now time.Time) error {
+	rules, err := ListAllRules(db)
+	if err != nil {
+		return fmt.Errorf("failed to load alert rules: %w", err)
+	}
+
+	domains := make(map[uint]string)
+	for _, rule := range rules {
+		if rule.CoolingDown(now) {
+			continue
+		}
+
+		check, err := EvaluateRule(db, rule, now)
+		if err != nil {
+			logger.Warn("Failed to evaluate alert rule", slog.Uint64("rule_id", uint64(rule.ID)), slog.Any("error", err))
+			continue
+		}
+		if !check.Fired {
+			continue
+		}
+
+		if _, ok := domains[rule.WebsiteID]; !ok {
+			website, err := websites.GetWebsiteByID(db, rule.WebsiteID)
+			if err != nil {
+				logger.Warn("Alert rule website not found", slog.Uint64("rule_id", uint64(rule.ID)), slog.Any("error", err))
+				continue
+			}
+			domains[rule.WebsiteID] = website.Domain
+		}
+
+		if err := notifier.Notify(ctx, check, domains[rule.WebsiteID]); err != nil {
+			logger.Warn("Alert notification failed",
+				slog.Uint64("rule_id", uint64(rule.ID)),
+				slog.String("channel", string(rule.Channel)),
+				slog.Any("error", err))
+			continue
+		}
+		if err := MarkRuleFired(db, rule.ID, now); err != nil {
+			return fmt.Errorf("failed to mark alert rule %d as fired: %w", rule.ID, err)
+		}
+		logger.Info("Alert fired",
+			slog.Uint64("rule_id", uint64(rule.ID)),
+			slog.String("condition", rule.Describe()),
+			slog.Float64("value", check.Value))
+	}
+	return nil
+}
@@ -32,6 +32,8 @@ type RefStat struct {
 	Pathname       string    `gorm:"uniqueIndex:idx_ref_unique"`
 	VisitorsCount  int       `gorm:"not null;default:0"`
 	PageViewsCount int       `gorm:"not null;default:0"`
+	Entrances      int       `gorm:"not null;default:0"`
+	BounceCount    int       `gorm:"not null;default:0"`
 	Hour           time.Time `gorm:"uniqueIndex:idx_ref_unique;type:datetime;not null"`
 	CreatedAt      time.Time
 	UpdatedAt      time.Time
@@ -49,6 +51,20 @@ type BrowserStat struct {
 	UpdatedAt      time.Time
 }
 
+// BrowserVersionStat represents aggregated browser statistics by major version.
+// Version is empty when the user agent doesn't report one.
+type BrowserVersionStat struct {
+	ID             uint      `gorm:"primaryKey;autoIncrement"`
+	WebsiteID      uint      `gorm:"uniqueIndex:idx_browser_version_unique;not null"`
+	Browser        string    `gorm:"uniqueIndex:idx_browser_version_unique;not null"`
+	Version        string    `gorm:"uniqueIndex:idx_browser_version_unique;not null"`
+	VisitorsCount  int       `gorm:"not null;default:0"`
+	PageViewsCount int       `gorm:"not null;default:0"`
+	Hour           time.Time `gorm:"uniqueIndex:idx_browser_version_unique;type:datetime;not null"`
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+}
+
 // OSStat represents aggregated operating system statistics
 type OSStat struct {
 	ID              uint      `gorm:"primaryKey;autoIncrement"`
@@ -73,6 +89,18 @@ type DeviceStat struct {
 	UpdatedAt      time.Time
 }
 
+// DeviceModelStat represents aggregated brand/model statistics for mobile and tablet devices
+type DeviceModelStat struct {
+	ID             uint      `gorm:"primaryKey;autoIncrement"`
+	WebsiteID      uint      `gorm:"uniqueIndex:idx_device_model_unique;not null"`
+	DeviceModel    string    `gorm:"uniqueIndex:idx_device_model_unique;not null"`
+	VisitorsCount  int       `gorm:"not null;default:0"`
+	PageViewsCount int       `gorm:"not null;default:0"`
+	Hour           time.Time `gorm:"uniqueIndex:idx_device_model_unique;type:datetime;not null"`
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+}
+
 // CountryStat represents aggregated country statistics
 type CountryStat struct {
 	ID             uint      `gorm:"primaryKey;autoIncrement"`
@@ -85,6 +113,56 @@ type CountryStat struct {
 	UpdatedAt      time.Time
 }
 
+// LanguageStat represents aggregated visitor language statistics from Accept-Language
+type LanguageStat struct {
+	ID             uint      `gorm:"primaryKey;autoIncrement"`
+	WebsiteID      uint      `gorm:"uniqueIndex:idx_language_unique;not null"`
+	Language       string    `gorm:"uniqueIndex:idx_language_unique;not null"`
+	VisitorsCount  int       `gorm:"not null;default:0"`
+	PageViewsCount int       `gorm:"not null;default:0"`
+	Hour           time.Time `gorm:"uniqueIndex:idx_language_unique;type:datetime;not null"`
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+}
+
+// ScreenStat represents aggregated viewport width bucket statistics
+type ScreenStat struct {
+	ID             uint      `gorm:"primaryKey;autoIncrement"`
+	WebsiteID      uint      `gorm:"uniqueIndex:idx_screen_unique;not null"`
+	ScreenSize     string    `gorm:"uniqueIndex:idx_screen_unique;not null"`
+	VisitorsCount  int       `gorm:"not null;default:0"`
+	PageViewsCount int       `gorm:"not null;default:0"`
+	Hour           time.Time `gorm:"uniqueIndex:idx_screen_unique;type:datetime;not null"`
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+}
+
+// RegionStat represents aggregated region (state, province) statistics
+type RegionStat struct {
+	ID             uint      `gorm:"primaryKey;autoIncrement"`
+	WebsiteID      uint      `gorm:"uniqueIndex:idx_region_unique;not null"`
+	Country        string    `gorm:"uniqueIndex:idx_region_unique;not null"`
+	Region         string    `gorm:"uniqueIndex:idx_region_unique;not null"`
+	VisitorsCount  int       `gorm:"not null;default:0"`
+	PageViewsCount int       `gorm:"not null;default:0"`
+	Hour           time.Time `gorm:"uniqueIndex:idx_region_unique;type:datetime;not null"`
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+}
+
+// CityStat represents aggregated city statistics
+type CityStat struct {
+	ID             uint      `gorm:"primaryKey;autoIncrement"`
+	WebsiteID      uint      `gorm:"uniqueIndex:idx_city_unique;not null"`
+	Country        string    `gorm:"uniqueIndex:idx_city_unique;not null"`
+	City           string    `gorm:"uniqueIndex:idx_city_unique;not null"`
+	VisitorsCount  int       `gorm:"not null;default:0"`
+	PageViewsCount int       `gorm:"not null;default:0"`
+	Hour           time.Time `gorm:"uniqueIndex:idx_city_unique;type:datetime;not null"`
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+}
+
 // UTMStat represents aggregated UTM parameter statistics
 type UTMStat struct {
 	ID             uint      `gorm:"primaryKey;autoIncrement"`
@@ -114,6 +192,33 @@ type EventStat struct {
 	UpdatedAt      time.Time
 }
 
+// DownloadStat represents aggregated file download statistics
+type DownloadStat struct {
+	ID             uint      `gorm:"primaryKey;autoIncrement"`
+	WebsiteID      uint      `gorm:"uniqueIndex:idx_download_unique;not null"`
+	Filename       string    `gorm:"uniqueIndex:idx_download_unique;not null"`
+	Extension      string    `gorm:"index"`
+	VisitorsCount  int       `gorm:"not null;default:0"`
+	DownloadsCount int       `gorm:"not null;default:0"`
+	Hour           time.Time `gorm:"uniqueIndex:idx_download_unique;type:datetime;not null"`
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+}
+
+// ScrollStat represents the deepest scroll bucket (25/50/75/100) reached per
+// page and session
+type ScrollStat struct {
+	ID            uint      `gorm:"primaryKey;autoIncrement"`
+	WebsiteID     uint      `gorm:"uniqueIndex:idx_scroll_unique;not null"`
+	Hostname      string    `gorm:"uniqueIndex:idx_scroll_unique;not null"`
+	Pathname      string    `gorm:"uniqueIndex:idx_scroll_unique;not null"`
+	Depth         int       `gorm:"uniqueIndex:idx_scroll_unique;not null"`
+	SessionsCount int       `gorm:"not null;default:0"`
+	Hour          time.Time `gorm:"uniqueIndex:idx_scroll_unique;type:datetime;not null"`
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+}
+
 // QueryParamStat represents aggregated query string parameter statistics
 type QueryParamStat struct {
 	ID             uint      `gorm:"primaryKey;autoIncrement"`
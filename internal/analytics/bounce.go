@@ -0,0 +1,127 @@
+package analytics
+
+import (
+	"fmt"
+	"sort"
+
+	"gorm.io/gorm"
+
+	"fusionaly/internal/events"
+	"fusionaly/internal/websites"
+)
+
+// BounceRateResult is the bounce rate of the sessions attributed to one
+// referrer or entry page. BounceRate is a percentage between 0 and 100.
+type BounceRateResult struct {
+	Name       string  `json:"name"`
+	Sessions   int64   `json:"sessions"`
+	Bounces    int64   `json:"bounces"`
+	BounceRate float64 `json:"bounce_rate"`
+}
+
+func newBounceRateResult(name string, sessions, bounces int64) BounceRateResult {
+	result := BounceRateResult{Name: name, Sessions: sessions, Bounces: bounces}
+	if sessions > 0 {
+		result.BounceRate = float64(bounces) / float64(sessions) * 100
+	}
+	return result
+}
+
+// GetBounceRateByReferrer returns the bounce rate of sessions per normalized
+// referrer, ordered by number of sessions.
+func GetBounceRateByReferrer(db *gorm.DB, params WebsiteScopedQueryParams) ([]BounceRateResult, error) {
+	var website websites.Website
+	if err := db.First(&website, params.WebsiteID).Error; err != nil {
+		return nil, fmt.Errorf("failed to get website domain for self-referral filtering: %w", err)
+	}
+
+	segment, segmentArgs := segmentClause(db, params, "ref_stats")
+	query := fmt.Sprintf(`
+		SELECT hostname, SUM(entrances) as sessions, SUM(bounce_count) as bounces
+		FROM ref_stats
+		WHERE hour BETWEEN ? AND ?
+		AND website_id = ?%s
+		GROUP BY hostname
+		HAVING sessions > 0
+	`, segment)
+
+	var rawResults []struct {
+		Hostname string
+		Sessions int64
+		Bounces  int64
+	}
+	args := append([]interface{}{params.TimeFrame.From.UTC(), params.TimeFrame.To.UTC(), params.WebsiteID}, segmentArgs...)
+	if err := db.Raw(query, args...).Scan(&rawResults).Error; err != nil {
+		return nil, fmt.Errorf("error fetching bounce rate by referrer: %w", err)
+	}
+
+	// Several hostnames can normalize to the same referrer, so sum before
+	// computing the rate
+	type counts struct{ sessions, bounces int64 }
+	normalized := make(map[string]*counts)
+	for _, raw := range rawResults {
+		if events.IsSelfReferral(raw.Hostname, website.Domain) {
+			continue
+		}
+		name := NormalizeReferrerHostname(raw.Hostname)
+		if !matchesSearch(params, name) {
+			continue
+		}
+		if normalized[name] == nil {
+			normalized[name] = &counts{}
+		}
+		normalized[name].sessions += raw.Sessions
+		normalized[name].bounces += raw.Bounces
+	}
+
+	results := make([]BounceRateResult, 0, len(normalized))
+	for name, c := range normalized {
+		results = append(results, newBounceRateResult(name, c.sessions, c.bounces))
+	}
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Sessions != results[j].Sessions {
+			return results[i].Sessions > results[j].Sessions
+		}
+		return results[i].Name < results[j].Name
+	})
+
+	return pageResults(results, params), nil
+}
+
+// GetBounceRateByEntryPage returns the bounce rate of sessions per entry page,
+// ordered by number of sessions.
+func GetBounceRateByEntryPage(db *gorm.DB, params WebsiteScopedQueryParams) ([]BounceRateResult, error) {
+	segment, segmentArgs := segmentClause(db, params, "page_stats")
+	search, searchArgs := searchClause(params, "hostname || pathname")
+	query := fmt.Sprintf(`
+    SELECT
+        hostname || pathname as name,
+        SUM(entrances) as sessions,
+        SUM(bounce_count) as bounces
+    FROM page_stats
+    WHERE hour BETWEEN ? AND ?
+    AND website_id = ?%s%s
+    GROUP BY hostname, pathname
+    HAVING sessions > 0
+    ORDER BY sessions DESC, name ASC
+    LIMIT ? OFFSET ?
+    `, segment, search)
+
+	var rawResults []struct {
+		Name     string
+		Sessions int64
+		Bounces  int64
+	}
+	args := append([]interface{}{params.TimeFrame.From.UTC(), params.TimeFrame.To.UTC(), params.WebsiteID}, segmentArgs...)
+	args = append(args, searchArgs...)
+	args = append(args, params.Limit, params.Offset)
+	if err := db.Raw(query, args...).Scan(&rawResults).Error; err != nil {
+		return nil, fmt.Errorf("error fetching bounce rate by entry page: %w", err)
+	}
+
+	results := make([]BounceRateResult, len(rawResults))
+	for i, raw := range rawResults {
+		results[i] = newBounceRateResult(raw.Name, raw.Sessions, raw.Bounces)
+	}
+	return results, nil
+}
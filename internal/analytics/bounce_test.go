@@ -0,0 +1,107 @@
+package analytics_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"fusionaly/internal/analytics"
+	"fusionaly/internal/events"
+	"fusionaly/internal/testsupport"
+	"fusionaly/internal/timeframe"
+)
+
+func TestBounceRateByDimension(t *testing.T) {
+	dbManager, logger, website := testsupport.SetupTestDBManagerWithWebsite(t, "bounce.com")
+	db := dbManager.GetConnection()
+
+	base := time.Date(2024, 7, 1, 10, 0, 0, 0, time.UTC)
+	pageViews := []struct {
+		visitor  string
+		pathname string
+		referrer string
+		offset   time.Duration
+	}{
+		{"google-bounce", "/pricing", "www.google.com", 0},
+		{"google-stays", "/pricing", "www.google.com", 0},
+		{"google-stays", "/signup", website.Domain, time.Minute},
+		{"hn-bounce", "/blog", "news.ycombinator.com", 0},
+		{"direct-stays", "/blog", events.DirectOrUnknownReferrer, 0},
+		{"direct-stays", "/pricing", website.Domain, time.Minute},
+		{"direct-bounce", "/blog", events.DirectOrUnknownReferrer, 0},
+	}
+	for _, pv := range pageViews {
+		ts := base.Add(pv.offset)
+		require.NoError(t, db.Create(&events.IngestedEvent{
+			WebsiteID:        website.ID,
+			UserSignature:    pv.visitor,
+			Hostname:         website.Domain,
+			Pathname:         pv.pathname,
+			RawURL:           "https://" + website.Domain + pv.pathname,
+			ReferrerHostname: pv.referrer,
+			EventType:        events.EventTypePageView,
+			Timestamp:        ts,
+			UserAgent:        "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36",
+			Country:          "US",
+			CreatedAt:        ts,
+		}).Error)
+	}
+	require.NoError(t, testsupport.ProcessAllTestEvents(dbManager, logger))
+
+	tf, err := timeframe.NewTimeFrame(timeframe.TimeFrameParams{
+		FromTime:      time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC),
+		ToTime:        time.Date(2024, 7, 1, 23, 59, 59, 0, time.UTC),
+		TimeFrameSize: timeframe.DailyTimeFrame,
+	}, time.UTC)
+	require.NoError(t, err)
+	params := analytics.NewWebsiteScopedQueryParams(tf, int(website.ID))
+
+	t.Run("by referrer", func(t *testing.T) {
+		results, err := analytics.GetBounceRateByReferrer(db, params)
+		require.NoError(t, err)
+
+		byName := make(map[string]analytics.BounceRateResult)
+		for _, r := range results {
+			byName[r.Name] = r
+		}
+		require.Len(t, byName, 3, "self-referrals must not show up as a referrer")
+
+		assert.Equal(t, int64(2), byName["Google"].Sessions)
+		assert.InDelta(t, 50.0, byName["Google"].BounceRate, 0.01)
+		assert.Equal(t, int64(1), byName["Hacker News"].Sessions)
+		assert.InDelta(t, 100.0, byName["Hacker News"].BounceRate, 0.01)
+		assert.Equal(t, int64(2), byName["Direct / Unknown"].Sessions)
+		assert.InDelta(t, 50.0, byName["Direct / Unknown"].BounceRate, 0.01)
+	})
+
+	t.Run("by entry page", func(t *testing.T) {
+		results, err := analytics.GetBounceRateByEntryPage(db, params)
+		require.NoError(t, err)
+		require.Len(t, results, 2, "/signup is never an entry page")
+
+		assert.Equal(t, "bounce.com/blog", results[0].Name)
+		assert.Equal(t, int64(3), results[0].Sessions)
+		assert.Equal(t, int64(2), results[0].Bounces)
+		assert.InDelta(t, 66.67, results[0].BounceRate, 0.01)
+
+		assert.Equal(t, "bounce.com/pricing", results[1].Name)
+		assert.Equal(t, int64(2), results[1].Sessions)
+		assert.InDelta(t, 50.0, results[1].BounceRate, 0.01)
+	})
+
+	t.Run("per-dimension bounces add up to the site total", func(t *testing.T) {
+		results, err := analytics.GetBounceRateByEntryPage(db, params)
+		require.NoError(t, err)
+		var sessions, bounces int64
+		for _, r := range results {
+			sessions += r.Sessions
+			bounces += r.Bounces
+		}
+
+		overall, err := analytics.GetBounceRateInTimeFrame(db, params)
+		require.NoError(t, err)
+		assert.InDelta(t, overall, float64(bounces)/float64(sessions), 0.001)
+	})
+}
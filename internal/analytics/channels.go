@@ -0,0 +1,196 @@
+package analytics
+
+import (
+	"fmt"
+	"sort"
+
+	"fusionaly/internal/events"
+	"fusionaly/internal/timeframe"
+	"fusionaly/internal/websites"
+
+	"gorm.io/gorm"
+)
+
+// Traffic channels referrers are grouped into
+const (
+	ChannelDirect   = "Direct"
+	ChannelSearch   = "Organic Search"
+	ChannelSocial   = "Social"
+	ChannelReferral = "Referral"
+	ChannelPaid     = "Paid"
+)
+
+// Channels lists the traffic channels derived from referrer hosts, in display order.
+// Paid traffic is identified from UTM data instead (see paidUTMMediums).
+var Channels = []string{ChannelDirect, ChannelSearch, ChannelSocial, ChannelReferral}
+
+// paidUTMMediums are the utm_medium values counted as paid traffic
+var paidUTMMediums = []string{"cpc", "ppc"}
+
+// channelByReferrer maps normalized referrer names (see ReferrerMappings) to channels.
+// Anything not listed is a plain referral.
+var channelByReferrer = map[string]string{
+	"Direct / Unknown": ChannelDirect,
+	SelfReferralName:   ChannelDirect,
+	"Google":           ChannelSearch,
+	"Bing":             ChannelSearch,
+	"DuckDuckGo":       ChannelSearch,
+	"Yahoo":            ChannelSearch,
+	"Facebook":         ChannelSocial,
+	"Twitter":          ChannelSocial,
+	"LinkedIn":         ChannelSocial,
+	"YouTube":          ChannelSocial,
+	"Reddit":           ChannelSocial,
+	"Instagram":        ChannelSocial,
+	"Pinterest":        ChannelSocial,
+	"TikTok":           ChannelSocial,
+	"Discord":          ChannelSocial,
+	"WhatsApp":         ChannelSocial,
+	"Hacker News":      ChannelSocial,
+}
+
+// ClassifyReferrerChannel returns the traffic channel for a raw referrer hostname
+func ClassifyReferrerChannel(hostname string) string {
+	if channel, ok := channelByReferrer[NormalizeReferrerHostname(hostname)]; ok {
+		return channel
+	}
+	return ChannelReferral
+}
+
+// ChannelTimeSeries holds the visitor time series for a single traffic channel
+type ChannelTimeSeries struct {
+	Channel string               `json:"channel"`
+	Points  []timeframe.DateStat `json:"points"`
+}
+
+// GetChannelTimeSeries returns per-bucket visitor counts for each traffic channel,
+// suitable for a stacked chart. Every channel is returned, with zero-filled buckets.
+func GetChannelTimeSeries(db *gorm.DB, params WebsiteScopedQueryParams) ([]ChannelTimeSeries, error) {
+	var website websites.Website
+	if err := db.First(&website, params.WebsiteID).Error; err != nil {
+		return nil, fmt.Errorf("failed to get website domain for self-referral filtering: %w", err)
+	}
+
+	groupByExpression, err := params.TimeFrame.GetSQLiteGroupByExpression()
+	if err != nil {
+		return nil, err
+	}
+
+	query := fmt.Sprintf(`
+		SELECT %s AS date, hostname, SUM(visitors_count) AS count
+		FROM ref_stats
+		WHERE hour >= ? AND hour <= ?
+		AND website_id = ?
+		GROUP BY %s, hostname
+		HAVING count > 0
+	`, groupByExpression, groupByExpression)
+
+	var rawResults []struct {
+		Date     string
+		Hostname string
+		Count    int
+	}
+	err = db.Raw(query,
+		params.TimeFrame.From.UTC(),
+		params.TimeFrame.To.UTC(),
+		params.WebsiteID,
+	).Scan(&rawResults).Error
+	if err != nil {
+		return nil, fmt.Errorf("error fetching channel time series: %w", err)
+	}
+
+	// Sum per channel and bucket in Go since classification isn't expressible in SQL
+	bucketCounts := make(map[string]map[string]int, len(Channels))
+	for _, result := range rawResults {
+		channel := ChannelDirect
+		if !events.IsSelfReferral(result.Hostname, website.Domain) {
+			channel = ClassifyReferrerChannel(result.Hostname)
+		}
+		if bucketCounts[channel] == nil {
+			bucketCounts[channel] = make(map[string]int)
+		}
+		bucketCounts[channel][result.Date] += result.Count
+	}
+
+	series := make([]ChannelTimeSeries, len(Channels))
+	for i, channel := range Channels {
+		grouped := make([]timeframe.DateStat, 0, len(bucketCounts[channel]))
+		for date, count := range bucketCounts[channel] {
+			grouped = append(grouped, timeframe.DateStat{Date: date, Count: count})
+		}
+		series[i] = ChannelTimeSeries{
+			Channel: channel,
+			Points:  params.TimeFrame.BuildTimeSeriesPoints(grouped),
+		}
+	}
+
+	return series, nil
+}
+
+// GetTrafficChannelsInTimeFrame returns visitor counts per traffic channel, ordered by count.
+// Referrer hosts are grouped using the same normalization as GetTopReferrersInTimeFrame,
+// and visitors tagged with a paid utm_medium are reported under ChannelPaid. Paid visitors
+// are also counted under the channel of their referrer, since aggregates don't link the two.
+func GetTrafficChannelsInTimeFrame(db *gorm.DB, params WebsiteScopedQueryParams) ([]MetricCountResult, error) {
+	var website websites.Website
+	if err := db.First(&website, params.WebsiteID).Error; err != nil {
+		return nil, fmt.Errorf("failed to get website domain for self-referral filtering: %w", err)
+	}
+
+	segment, segmentArgs := segmentClause(db, params, "ref_stats")
+	query := fmt.Sprintf(`
+		SELECT hostname, SUM(visitors_count) AS count
+		FROM ref_stats
+		WHERE hour BETWEEN ? AND ?
+		AND website_id = ?%s
+		GROUP BY hostname
+		HAVING count > 0
+	`, segment)
+
+	var rawResults []struct {
+		Hostname string
+		Count    int64
+	}
+	args := append([]interface{}{params.TimeFrame.From.UTC(), params.TimeFrame.To.UTC(), params.WebsiteID}, segmentArgs...)
+	if err := db.Raw(query, args...).Scan(&rawResults).Error; err != nil {
+		return nil, fmt.Errorf("error fetching referrer data for channels: %w", err)
+	}
+
+	channelCounts := make(map[string]int64, len(Channels)+1)
+	for _, result := range rawResults {
+		channel := ChannelDirect
+		if !events.IsSelfReferral(result.Hostname, website.Domain) {
+			channel = ClassifyReferrerChannel(result.Hostname)
+		}
+		channelCounts[channel] += result.Count
+	}
+
+	utmSegment, utmSegmentArgs := segmentClause(db, params, "utm_stats")
+	paidQuery := fmt.Sprintf(`
+		SELECT COALESCE(SUM(visitors_count), 0)
+		FROM utm_stats
+		WHERE hour BETWEEN ? AND ?
+		AND website_id = ?%s
+		AND LOWER(utm_medium) IN ?
+	`, utmSegment)
+
+	var paidCount int64
+	paidArgs := append([]interface{}{params.TimeFrame.From.UTC(), params.TimeFrame.To.UTC(), params.WebsiteID}, utmSegmentArgs...)
+	paidArgs = append(paidArgs, paidUTMMediums)
+	if err := db.Raw(paidQuery, paidArgs...).Scan(&paidCount).Error; err != nil {
+		return nil, fmt.Errorf("error fetching paid traffic for channels: %w", err)
+	}
+	channelCounts[ChannelPaid] += paidCount
+
+	results := make([]MetricCountResult, 0, len(channelCounts))
+	for _, channel := range append(Channels, ChannelPaid) {
+		if count := channelCounts[channel]; count > 0 {
+			results = append(results, MetricCountResult{Name: channel, Count: count})
+		}
+	}
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Count > results[j].Count
+	})
+
+	return results, nil
+}
@@ -0,0 +1,144 @@
+package analytics_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"fusionaly/internal/analytics"
+	"fusionaly/internal/events"
+	"fusionaly/internal/testsupport"
+	"fusionaly/internal/timeframe"
+)
+
+func TestClassifyReferrerChannel(t *testing.T) {
+	tests := []struct {
+		hostname string
+		expected string
+	}{
+		{events.DirectOrUnknownReferrer, analytics.ChannelDirect},
+		{"", analytics.ChannelDirect},
+		{"www.google.com", analytics.ChannelSearch},
+		{"duckduckgo.com", analytics.ChannelSearch},
+		{"bing.com", analytics.ChannelSearch},
+		{"l.facebook.com", analytics.ChannelSocial},
+		{"www.linkedin.com", analytics.ChannelSocial},
+		{"t.co", analytics.ChannelSocial},
+		{"news.ycombinator.com", analytics.ChannelSocial},
+		{"github.com", analytics.ChannelReferral},
+		{"someblog.example", analytics.ChannelReferral},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.hostname, func(t *testing.T) {
+			assert.Equal(t, tt.expected, analytics.ClassifyReferrerChannel(tt.hostname))
+		})
+	}
+}
+
+func TestGetChannelTimeSeries(t *testing.T) {
+	dbManager, logger, website := testsupport.SetupTestDBManagerWithWebsite(t, "channels.com")
+	db := dbManager.GetConnection()
+
+	day1 := time.Date(2024, 7, 1, 10, 0, 0, 0, time.UTC)
+	day2 := day1.AddDate(0, 0, 1)
+
+	visits := []struct {
+		timestamp time.Time
+		referrer  string
+	}{
+		{day1, events.DirectOrUnknownReferrer},
+		{day1, "google.com"},
+		{day1, "google.com"},
+		{day1, "twitter.com"},
+		{day2, "bing.com"},
+		{day2, "facebook.com"},
+		{day2, "someblog.example"},
+		{day2, website.Domain}, // self-referral counts as direct
+	}
+	for i, visit := range visits {
+		require.NoError(t, db.Create(&events.IngestedEvent{
+			WebsiteID:        website.ID,
+			UserSignature:    fmt.Sprintf("visitor-%d", i),
+			Hostname:         website.Domain,
+			Pathname:         "/",
+			RawURL:           "https://" + website.Domain + "/",
+			ReferrerHostname: visit.referrer,
+			EventType:        events.EventTypePageView,
+			Timestamp:        visit.timestamp,
+			UserAgent:        "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36",
+			Country:          "US",
+			CreatedAt:        visit.timestamp,
+		}).Error)
+	}
+	require.NoError(t, testsupport.ProcessAllTestEvents(dbManager, logger))
+
+	timeFrame, err := timeframe.NewTimeFrame(timeframe.TimeFrameParams{
+		FromTime:      time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC),
+		ToTime:        time.Date(2024, 7, 2, 23, 59, 59, 0, time.UTC),
+		TimeFrameSize: timeframe.DailyTimeFrame,
+	}, time.UTC)
+	require.NoError(t, err)
+
+	series, err := analytics.GetChannelTimeSeries(db, analytics.NewWebsiteScopedQueryParams(timeFrame, int(website.ID)))
+	require.NoError(t, err)
+	require.Len(t, series, len(analytics.Channels))
+
+	expected := map[string][]int{
+		analytics.ChannelDirect:   {1, 1},
+		analytics.ChannelSearch:   {2, 1},
+		analytics.ChannelSocial:   {1, 1},
+		analytics.ChannelReferral: {0, 1},
+	}
+	for _, channelSeries := range series {
+		require.Len(t, channelSeries.Points, 2, channelSeries.Channel)
+		counts := []int{channelSeries.Points[0].Count, channelSeries.Points[1].Count}
+		assert.Equal(t, expected[channelSeries.Channel], counts, channelSeries.Channel)
+	}
+	assert.Equal(t, "2024-07-01T00:00:00Z", series[0].Points[0].Date)
+}
+
+func TestGetTrafficChannelsInTimeFrame(t *testing.T) {
+	dbManager, _, website := testsupport.SetupTestDBManagerWithWebsite(t, "traffic-channels.com")
+	db := dbManager.GetConnection()
+
+	hour := time.Date(2024, 7, 1, 10, 0, 0, 0, time.UTC)
+	refStats := []analytics.RefStat{
+		{WebsiteID: website.ID, Hostname: events.DirectOrUnknownReferrer, VisitorsCount: 4, Hour: hour},
+		{WebsiteID: website.ID, Hostname: "google.com", VisitorsCount: 5, Hour: hour},
+		{WebsiteID: website.ID, Hostname: "www.bing.com", VisitorsCount: 1, Hour: hour},
+		{WebsiteID: website.ID, Hostname: "t.co", VisitorsCount: 3, Hour: hour},
+		{WebsiteID: website.ID, Hostname: "someblog.example", VisitorsCount: 2, Hour: hour},
+		{WebsiteID: website.ID, Hostname: website.Domain, VisitorsCount: 1, Hour: hour},                // self-referral counts as direct
+		{WebsiteID: website.ID, Hostname: "google.com", VisitorsCount: 9, Hour: hour.AddDate(0, 0, 5)}, // outside the time frame
+	}
+	require.NoError(t, db.Create(&refStats).Error)
+
+	utmStats := []analytics.UTMStat{
+		{WebsiteID: website.ID, UTMSource: "google", UTMMedium: "cpc", VisitorsCount: 2, Hour: hour},
+		{WebsiteID: website.ID, UTMSource: "bing", UTMMedium: "CPC", UTMCampaign: "brand", VisitorsCount: 1, Hour: hour},
+		{WebsiteID: website.ID, UTMSource: "newsletter", UTMMedium: "email", VisitorsCount: 7, Hour: hour},
+	}
+	require.NoError(t, db.Create(&utmStats).Error)
+
+	timeFrame, err := timeframe.NewTimeFrame(timeframe.TimeFrameParams{
+		FromTime:      time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC),
+		ToTime:        time.Date(2024, 7, 1, 23, 59, 59, 0, time.UTC),
+		TimeFrameSize: timeframe.DailyTimeFrame,
+	}, time.UTC)
+	require.NoError(t, err)
+
+	results, err := analytics.GetTrafficChannelsInTimeFrame(db, analytics.NewWebsiteScopedQueryParams(timeFrame, int(website.ID)))
+	require.NoError(t, err)
+
+	assert.Equal(t, []analytics.MetricCountResult{
+		{Name: analytics.ChannelSearch, Count: 6},
+		{Name: analytics.ChannelDirect, Count: 5},
+		{Name: analytics.ChannelSocial, Count: 3},
+		{Name: analytics.ChannelPaid, Count: 3},
+		{Name: analytics.ChannelReferral, Count: 2},
+	}, results)
+}
@@ -2,28 +2,55 @@ package analytics
 
 // ComparisonMetrics represents period-over-period percentage changes for key metrics
 type ComparisonMetrics struct {
-	VisitorsChange   *float64 `json:"visitors_change,omitempty"`
-	ViewsChange      *float64 `json:"views_change,omitempty"`
-	SessionsChange   *float64 `json:"sessions_change,omitempty"`
-	BounceRateChange *float64 `json:"bounce_rate_change,omitempty"`
-	AvgTimeChange    *float64 `json:"avg_time_change,omitempty"`
-	RevenueChange    *float64 `json:"revenue_change,omitempty"`
+	VisitorsChange        *float64 `json:"visitors_change,omitempty"`
+	ViewsChange           *float64 `json:"views_change,omitempty"`
+	SessionsChange        *float64 `json:"sessions_change,omitempty"`
+	BounceRateChange      *float64 `json:"bounce_rate_change,omitempty"`
+	AvgTimeChange         *float64 `json:"avg_time_change,omitempty"`
+	PagesPerSessionChange *float64 `json:"pages_per_session_change,omitempty"`
+	RevenueChange         *float64 `json:"revenue_change,omitempty"`
+
+	TopLists *ComparisonTopLists `json:"top_lists,omitempty"`
+
+	// Current period series with ChangePct set per bucket
+	PageViews []TimeSeriesPoint `json:"page_views,omitempty"`
+	Visitors  []TimeSeriesPoint `json:"visitors,omitempty"`
+}
+
+// ComparisonTopLists holds per-item period-over-period changes for top lists
+type ComparisonTopLists struct {
+	Referrers []TopListChange `json:"referrers"`
+	URLs      []TopListChange `json:"urls"`
+}
+
+// TopListChange is a top list entry's count in both periods. Change is nil
+// when the item is New (absent from the previous top list); Gone items are
+// absent from the current top list and count as 0 there.
+type TopListChange struct {
+	Name     string   `json:"name"`
+	Current  int64    `json:"current"`
+	Previous int64    `json:"previous"`
+	Change   *float64 `json:"change,omitempty"`
+	New      bool     `json:"new,omitempty"`
+	Gone     bool     `json:"gone,omitempty"`
 }
 
 // ComparisonData holds current and previous period metrics for comparison
 type ComparisonData struct {
-	CurrentVisitors    int64
-	PreviousVisitors   int64
-	CurrentViews       int64
-	PreviousViews      int64
-	CurrentSessions    int64
-	PreviousSessions   int64
-	CurrentBounceRate  float64
-	PreviousBounceRate float64
-	CurrentAvgTime     float64
-	PreviousAvgTime    float64
-	CurrentRevenue     float64
-	PreviousRevenue    float64
+	CurrentVisitors         int64
+	PreviousVisitors        int64
+	CurrentViews            int64
+	PreviousViews           int64
+	CurrentSessions         int64
+	PreviousSessions        int64
+	CurrentBounceRate       float64
+	PreviousBounceRate      float64
+	CurrentAvgTime          float64
+	PreviousAvgTime         float64
+	CurrentPagesPerSession  float64
+	PreviousPagesPerSession float64
+	CurrentRevenue          float64
+	PreviousRevenue         float64
 }
 
 // CalculateComparisonMetrics computes period-over-period percentage changes
@@ -79,6 +106,14 @@ func CalculateComparisonMetrics(data ComparisonData) *ComparisonMetrics {
 		)
 	}
 
+	// Pages per session change
+	if data.PreviousPagesPerSession > 0 {
+		comparison.PagesPerSessionChange = calculatePercentageChange(
+			data.CurrentPagesPerSession,
+			data.PreviousPagesPerSession,
+		)
+	}
+
 	// Revenue change
 	if data.PreviousRevenue > 0 {
 		comparison.RevenueChange = calculatePercentageChange(
@@ -89,3 +124,57 @@ func CalculateComparisonMetrics(data ComparisonData) *ComparisonMetrics {
 
 	return comparison
 }
+
+// CalculateTopListChanges pairs current and previous top list entries by name.
+// Current entries keep their order, followed by previous-only entries in theirs.
+func CalculateTopListChanges(current, previous []MetricCountResult) []TopListChange {
+	previousCounts := make(map[string]int64, len(previous))
+	for _, item := range previous {
+		previousCounts[item.Name] += item.Count
+	}
+
+	changes := make([]TopListChange, 0, len(current)+len(previous))
+	seen := make(map[string]bool, len(current))
+	for _, item := range current {
+		if seen[item.Name] {
+			continue
+		}
+		seen[item.Name] = true
+
+		change := TopListChange{Name: item.Name, Current: item.Count}
+		if prev, ok := previousCounts[item.Name]; ok && prev > 0 {
+			change.Previous = prev
+			delta := (float64(item.Count) - float64(prev)) / float64(prev) * 100
+			change.Change = &delta
+		} else {
+			change.New = true
+		}
+		changes = append(changes, change)
+	}
+
+	for _, item := range previous {
+		if seen[item.Name] {
+			continue
+		}
+		seen[item.Name] = true
+		changes = append(changes, TopListChange{Name: item.Name, Previous: previousCounts[item.Name], Gone: true})
+	}
+
+	return changes
+}
+
+// AlignTimeSeriesChanges returns a copy of current with each point's ChangePct
+// set against the comparison bucket at the same position. Buckets with no
+// comparison counterpart, or a zero previous count, keep a nil ChangePct.
+func AlignTimeSeriesChanges(current, previous []TimeSeriesPoint) []TimeSeriesPoint {
+	aligned := make([]TimeSeriesPoint, len(current))
+	for i, point := range current {
+		aligned[i] = TimeSeriesPoint{Date: point.Date, Count: point.Count}
+		if i >= len(previous) || previous[i].Count <= 0 {
+			continue
+		}
+		change := float64(point.Count-previous[i].Count) / float64(previous[i].Count) * 100
+		aligned[i].ChangePct = &change
+	}
+	return aligned
+}
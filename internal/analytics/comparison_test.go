@@ -0,0 +1,156 @@
+package analytics_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"fusionaly/internal/analytics"
+	"fusionaly/internal/testsupport"
+	"fusionaly/internal/timeframe"
+)
+
+func TestFetchComparisonMetricsCustomTimeFrame(t *testing.T) {
+	dbManager, logger := testsupport.SetupTestDBManager(t)
+	db := dbManager.GetConnection()
+	website := testsupport.CreateTestWebsite(db, "comparison.com")
+	websiteID := int(website.ID)
+
+	require.NoError(t, db.Create([]analytics.SiteStat{
+		// July 2024 (current period)
+		{WebsiteID: website.ID, Visitors: 150, PageViews: 300, Sessions: 180, Hour: time.Date(2024, 7, 10, 12, 0, 0, 0, time.UTC)},
+		// June 2024 (the auto-calculated previous period)
+		{WebsiteID: website.ID, Visitors: 50, PageViews: 100, Sessions: 60, Hour: time.Date(2024, 6, 10, 12, 0, 0, 0, time.UTC)},
+		// July 2023 (same month last year)
+		{WebsiteID: website.ID, Visitors: 100, PageViews: 200, Sessions: 120, Hour: time.Date(2023, 7, 10, 12, 0, 0, 0, time.UTC)},
+	}).Error)
+
+	monthFrame := func(year int) *timeframe.TimeFrame {
+		tf, err := timeframe.NewTimeFrame(timeframe.TimeFrameParams{
+			FromTime:      time.Date(year, 7, 1, 0, 0, 0, 0, time.UTC),
+			ToTime:        time.Date(year, 7, 31, 23, 59, 59, 0, time.UTC),
+			TimeFrameSize: timeframe.DailyTimeFrame,
+		}, time.UTC)
+		require.NoError(t, err)
+		return tf
+	}
+	current := &analytics.DashboardMetrics{TotalVisitors: 150, TotalViews: 300, TotalSessions: 180}
+
+	t.Run("compares against the custom window", func(t *testing.T) {
+		comparison := analytics.FetchComparisonMetrics(db, monthFrame(2024), monthFrame(2023), websiteID, current, logger)
+		require.NotNil(t, comparison.VisitorsChange)
+		require.NotNil(t, comparison.ViewsChange)
+		require.NotNil(t, comparison.SessionsChange)
+		assert.InDelta(t, 50.0, *comparison.VisitorsChange, 0.01)
+		assert.InDelta(t, 50.0, *comparison.ViewsChange, 0.01)
+		assert.InDelta(t, 50.0, *comparison.SessionsChange, 0.01)
+	})
+
+	t.Run("defaults to the previous period", func(t *testing.T) {
+		comparison := analytics.FetchComparisonMetrics(db, monthFrame(2024), nil, websiteID, current, logger)
+		require.NotNil(t, comparison.VisitorsChange)
+		assert.InDelta(t, 200.0, *comparison.VisitorsChange, 0.01)
+	})
+
+	t.Run("aligns the visitors series with the comparison window", func(t *testing.T) {
+		withSeries := *current
+		withSeries.Visitors = make([]analytics.TimeSeriesPoint, 31)
+		for i := range withSeries.Visitors {
+			withSeries.Visitors[i] = analytics.TimeSeriesPoint{Date: time.Date(2024, 7, i+1, 0, 0, 0, 0, time.UTC).Format("2006-01-02")}
+		}
+		withSeries.Visitors[9].Count = 150
+
+		comparison := analytics.FetchComparisonMetrics(db, monthFrame(2024), monthFrame(2023), websiteID, &withSeries, logger)
+		require.Len(t, comparison.Visitors, 31)
+		require.NotNil(t, comparison.Visitors[9].ChangePct, "July 10th against July 10th")
+		assert.InDelta(t, 50.0, *comparison.Visitors[9].ChangePct, 0.01)
+		assert.Nil(t, comparison.Visitors[0].ChangePct)
+	})
+
+	t.Run("compares top URLs against the comparison window", func(t *testing.T) {
+		require.NoError(t, db.Create([]analytics.PageStat{
+			{WebsiteID: website.ID, Hostname: "comparison.com", Pathname: "/pricing", VisitorsCount: 10, Hour: time.Date(2023, 7, 10, 12, 0, 0, 0, time.UTC)},
+			{WebsiteID: website.ID, Hostname: "comparison.com", Pathname: "/old", VisitorsCount: 5, Hour: time.Date(2023, 7, 10, 12, 0, 0, 0, time.UTC)},
+		}).Error)
+		withURLs := *current
+		withURLs.TopURLs = []analytics.MetricCountResult{{Name: "comparison.com/pricing", Count: 15}}
+
+		comparison := analytics.FetchComparisonMetrics(db, monthFrame(2024), monthFrame(2023), websiteID, &withURLs, logger)
+		require.NotNil(t, comparison.TopLists)
+		require.Len(t, comparison.TopLists.URLs, 2)
+		require.NotNil(t, comparison.TopLists.URLs[0].Change)
+		assert.InDelta(t, 50.0, *comparison.TopLists.URLs[0].Change, 0.01)
+		assert.Equal(t, analytics.TopListChange{Name: "comparison.com/old", Previous: 5, Gone: true}, comparison.TopLists.URLs[1])
+		assert.Empty(t, comparison.TopLists.Referrers)
+	})
+}
+
+func TestCalculateTopListChanges(t *testing.T) {
+	current := []analytics.MetricCountResult{
+		{Name: "twitter.com", Count: 134},
+		{Name: "news.ycombinator.com", Count: 40},
+		{Name: "reddit.com", Count: 20},
+	}
+	previous := []analytics.MetricCountResult{
+		{Name: "news.ycombinator.com", Count: 80},
+		{Name: "twitter.com", Count: 100},
+		{Name: "facebook.com", Count: 12},
+	}
+
+	changes := analytics.CalculateTopListChanges(current, previous)
+	require.Len(t, changes, 4)
+
+	assert.Equal(t, "twitter.com", changes[0].Name)
+	assert.Equal(t, int64(134), changes[0].Current)
+	assert.Equal(t, int64(100), changes[0].Previous)
+	require.NotNil(t, changes[0].Change)
+	assert.InDelta(t, 34.0, *changes[0].Change, 0.01)
+
+	require.NotNil(t, changes[1].Change)
+	assert.InDelta(t, -50.0, *changes[1].Change, 0.01)
+
+	assert.Equal(t, analytics.TopListChange{Name: "reddit.com", Current: 20, New: true}, changes[2], "only in the current period")
+	assert.Equal(t, analytics.TopListChange{Name: "facebook.com", Previous: 12, Gone: true}, changes[3], "only in the previous period")
+
+	assert.Empty(t, analytics.CalculateTopListChanges(nil, nil))
+	for _, change := range analytics.CalculateTopListChanges(current, nil) {
+		assert.True(t, change.New)
+		assert.Nil(t, change.Change)
+	}
+}
+
+func TestAlignTimeSeriesChanges(t *testing.T) {
+	current := []analytics.TimeSeriesPoint{
+		{Date: "2024-07-01", Count: 150},
+		{Date: "2024-07-02", Count: 40},
+		{Date: "2024-07-03", Count: 10},
+		{Date: "2024-07-04", Count: 0},
+	}
+	previous := []analytics.TimeSeriesPoint{
+		{Date: "2024-06-27", Count: 100},
+		{Date: "2024-06-28", Count: 80},
+		{Date: "2024-06-29", Count: 0},
+		{Date: "2024-06-30", Count: 20},
+	}
+
+	aligned := analytics.AlignTimeSeriesChanges(current, previous)
+	require.Len(t, aligned, 4)
+	for i, point := range aligned {
+		assert.Equal(t, current[i].Date, point.Date, "keeps the current buckets")
+		assert.Equal(t, current[i].Count, point.Count)
+	}
+	require.NotNil(t, aligned[0].ChangePct)
+	assert.InDelta(t, 50.0, *aligned[0].ChangePct, 0.01)
+	require.NotNil(t, aligned[1].ChangePct)
+	assert.InDelta(t, -50.0, *aligned[1].ChangePct, 0.01)
+	assert.Nil(t, aligned[2].ChangePct, "no change from an empty bucket")
+	require.NotNil(t, aligned[3].ChangePct)
+	assert.InDelta(t, -100.0, *aligned[3].ChangePct, 0.01)
+	assert.Nil(t, current[0].ChangePct, "the input series is left untouched")
+
+	shorter := analytics.AlignTimeSeriesChanges(current, previous[:1])
+	assert.NotNil(t, shorter[0].ChangePct)
+	assert.Nil(t, shorter[1].ChangePct, "buckets past the comparison series stay nil")
+}
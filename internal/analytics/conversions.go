@@ -182,6 +182,53 @@ func aggregatedGoalConversionsInTimeFrameRaw(db *gorm.DB, params WebsiteScopedQu
 	return results, nil
 }
 
+// GetGoalConversionRates returns, per goal, the percentage of visitors in the time
+// frame who completed it. Every goal is present in the result, with 0 when it had
+// no conversions or the time frame had no visitors.
+func GetGoalConversionRates(db *gorm.DB, params WebsiteScopedQueryParams, goals []string) (map[string]float64, error) {
+	rates := make(map[string]float64, len(goals))
+	for _, goal := range goals {
+		rates[goal] = 0
+	}
+	if len(goals) == 0 {
+		return rates, nil
+	}
+
+	totalVisitors, err := GetTotalVisitorsInTimeFrame(db, params)
+	if err != nil {
+		return nil, err
+	}
+	if totalVisitors <= 0 {
+		return rates, nil
+	}
+
+	var results []struct {
+		EventName string
+		Visitors  int64
+	}
+	query := fmt.Sprintf(`
+        SELECT event_name, COALESCE(SUM(visitors_count), 0) AS visitors
+        FROM event_stats
+        WHERE hour >= ? AND hour <= ?
+            AND website_id = ?
+            AND event_name IN (%s)
+        GROUP BY event_name
+    `, generatePlaceholders(len(goals)))
+
+	args := []interface{}{params.TimeFrame.From.UTC(), params.TimeFrame.To.UTC(), params.WebsiteID}
+	for _, goal := range goals {
+		args = append(args, goal)
+	}
+	if err := db.Raw(query, args...).Scan(&results).Error; err != nil {
+		return nil, fmt.Errorf("error fetching goal conversion rates: %w", err)
+	}
+
+	for _, result := range results {
+		rates[result.EventName] = float64(result.Visitors) / float64(totalVisitors) * 100
+	}
+	return rates, nil
+}
+
 // generatePlaceholders generates a string of SQL placeholders for IN clause
 func generatePlaceholders(count int) string {
 	if count == 0 {
@@ -2,8 +2,12 @@ package analytics
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
+	"reflect"
+	"slices"
+	"strings"
 
 	"fusionaly/internal/pkg/async"
 	"fusionaly/internal/settings"
@@ -14,96 +18,93 @@ import (
 
 // DashboardMetrics contains all metrics displayed on the analytics dashboard.
 type DashboardMetrics struct {
-	PageViews            []TimeSeriesPoint    `json:"page_views"`
-	Visitors             []TimeSeriesPoint    `json:"visitors"`
-	Sessions             []TimeSeriesPoint    `json:"sessions"`
-	GoalConversions      []TimeSeriesPoint    `json:"goal_conversions"`
-	Revenue              []TimeSeriesPoint    `json:"revenue"`
-	TopURLs              []MetricCountResult  `json:"top_urls"`
-	TopCountries         []MetricCountResult  `json:"top_countries"`
-	TopDevices           []MetricCountResult  `json:"top_devices"`
-	TopReferrers         []MetricCountResult  `json:"top_referrers"`
-	TopBrowsers          []MetricCountResult  `json:"top_browsers"`
-	TopCustomEvents      []MetricCountResult  `json:"top_custom_events"`
-	EventConversionRates map[string]float64   `json:"event_conversion_rates"`
-	TopOperatingSystems  []MetricCountResult  `json:"top_operating_systems"`
-	EventRevenueTotals   map[string]float64   `json:"event_revenue_totals"`
-	BounceRate           float64              `json:"bounce_rate"`
-	VisitsDuration       float64              `json:"visits_duration"`
-	RevenuePerVisitor    float64              `json:"revenue_per_visitor"`
-	TopEntryPages        []MetricCountResult  `json:"top_entry_pages"`
-	TopExitPages         []MetricCountResult  `json:"top_exit_pages"`
-	TopUTMMediums        []MetricCountResult  `json:"top_utm_mediums"`
-	TopUTMSources        []MetricCountResult  `json:"top_utm_sources"`
-	TopUTMCampaigns      []MetricCountResult  `json:"top_utm_campaigns"`
-	TopUTMTerms          []MetricCountResult  `json:"top_utm_terms"`
-	TopUTMContents       []MetricCountResult  `json:"top_utm_contents"`
-	TopRefParams         []MetricCountResult  `json:"top_ref_params"`
-	BucketSize           string               `json:"bucket_size"`
-	TotalVisitors        int64                `json:"total_visitors"`
-	TotalViews           int64                `json:"total_views"`
-	TotalSessions        int64                `json:"total_sessions"`
-	TotalEntryCount      int64                `json:"total_entry_count"`
-	TotalExitCount       int64                `json:"total_exit_count"`
-	TotalCustomEvents    int64                `json:"total_custom_events"`
-	RevenueMetrics       *RevenueMetrics      `json:"revenue_metrics"`
-	TopRevenueEvents     []MetricCountResult  `json:"top_revenue_events"`
-	ConversionGoals      []string             `json:"conversion_goals"`
-	Insights             []interface{}        `json:"insights"`
-	Comparison           *ComparisonMetrics   `json:"comparison,omitempty"`
-	UserFlow             []UserFlowLink       `json:"user_flow"`
+	PageViews               []TimeSeriesPoint      `json:"page_views"`
+	Visitors                []TimeSeriesPoint      `json:"visitors"`
+	Sessions                []TimeSeriesPoint      `json:"sessions"`
+	GoalConversions         []TimeSeriesPoint      `json:"goal_conversions"`
+	Revenue                 []TimeSeriesPoint      `json:"revenue"`
+	TopURLs                 []MetricCountResult    `json:"top_urls"`
+	TopCountries            []MetricCountResult    `json:"top_countries"`
+	TopRegions              []MetricCountResult    `json:"top_regions"`
+	TopCities               []MetricCountResult    `json:"top_cities"`
+	TopLanguages            []MetricCountResult    `json:"top_languages"`
+	TopDevices              []MetricCountResult    `json:"top_devices"`
+	TopDeviceModels         []MetricCountResult    `json:"top_device_models"`
+	TopScreenSizes          []MetricCountResult    `json:"top_screen_sizes"`
+	TopReferrers            []MetricCountResult    `json:"top_referrers"`
+	TrafficChannels         []MetricCountResult    `json:"traffic_channels"`
+	TopBrowsers             []MetricCountResult    `json:"top_browsers"`
+	TopBrowserVersions      []MetricCountResult    `json:"top_browser_versions"`
+	TopCustomEvents         []MetricCountResult    `json:"top_custom_events"`
+	TopDownloads            []MetricCountResult    `json:"top_downloads"`
+	EventConversionRates    map[string]float64     `json:"event_conversion_rates"`
+	GoalConversionRates     map[string]float64     `json:"goal_conversion_rates"`
+	TopOperatingSystems     []MetricCountResult    `json:"top_operating_systems"`
+	EventRevenueTotals      map[string]float64     `json:"event_revenue_totals"`
+	BounceRate              float64                `json:"bounce_rate"`
+	VisitsDuration          float64                `json:"visits_duration"`
+	PagesPerSession         float64                `json:"pages_per_session"`
+	RevenuePerVisitor       float64                `json:"revenue_per_visitor"`
+	AverageOrderValue       float64                `json:"average_order_value"`
+	TopEntryPages           []MetricCountResult    `json:"top_entry_pages"`
+	TopExitPages            []MetricCountResult    `json:"top_exit_pages"`
+	Top404s                 []MetricCountResult    `json:"top_404s"`
+	EntryExitPairs          []EntryExitPair        `json:"entry_exit_pairs"`
+	TopUTMMediums           []MetricCountResult    `json:"top_utm_mediums"`
+	TopUTMSources           []MetricCountResult    `json:"top_utm_sources"`
+	TopUTMCampaigns         []MetricCountResult    `json:"top_utm_campaigns"`
+	TopUTMTerms             []MetricCountResult    `json:"top_utm_terms"`
+	TopUTMContents          []MetricCountResult    `json:"top_utm_contents"`
+	UTMTermConversions      []UTMConversionResult  `json:"utm_term_conversions"`
+	UTMContentConversions   []UTMConversionResult  `json:"utm_content_conversions"`
+	TopUTMCombinations      []UTMCombinationResult `json:"top_utm_combinations"`
+	TopRefParams            []MetricCountResult    `json:"top_ref_params"`
+	TopQueryParams          QueryParamBreakdowns   `json:"top_query_params,omitempty"` // Set by the admin dashboard only, down to TopSubdomains
+	PropertyKeys            []string               `json:"property_keys,omitempty"`
+	TopPropertyValues       []MetricCountResult    `json:"top_property_values,omitempty"`
+	MetricKeys              []string               `json:"metric_keys,omitempty"`
+	MetricAggregation       *NumericAggregation    `json:"metric_aggregation,omitempty"`
+	TopSubdomains           []MetricCountResult    `json:"top_subdomains,omitempty"`
+	BucketSize              string                 `json:"bucket_size"`
+	TotalVisitors           int64                  `json:"total_visitors"`
+	NewVisitors             int64                  `json:"new_visitors"`
+	ReturningVisitors       int64                  `json:"returning_visitors"`
+	NewVisitorsSeries       []TimeSeriesPoint      `json:"new_visitors_series"`
+	ReturningVisitorsSeries []TimeSeriesPoint      `json:"returning_visitors_series"`
+	TrafficHeatmap          *TrafficHeatmap        `json:"traffic_heatmap"`
+	TotalViews              int64                  `json:"total_views"`
+	TotalSessions           int64                  `json:"total_sessions"`
+	TotalEntryCount         int64                  `json:"total_entry_count"`
+	TotalExitCount          int64                  `json:"total_exit_count"`
+	TotalCustomEvents       int64                  `json:"total_custom_events"`
+	RevenueMetrics          *RevenueMetrics        `json:"revenue_metrics"`
+	TopRevenueEvents        []MetricCountResult    `json:"top_revenue_events"`
+	RevenueByReferrer       []RevenueSourceResult  `json:"revenue_by_referrer"`
+	RevenueByUTMSource      []RevenueSourceResult  `json:"revenue_by_utm_source"`
+	ConversionGoals         []string               `json:"conversion_goals"`
+	Insights                []interface{}          `json:"insights"`
+	Comparison              *ComparisonMetrics     `json:"comparison,omitempty"`
+	UserFlow                []UserFlowLink         `json:"user_flow"`
+	Filters                 map[string]string      `json:"filters"`
+	UnscopedPanels          []string               `json:"unscoped_panels,omitempty"` // Panels ignoring part of the segment (see UnscopedPanels)
+	HiddenPanels            []string               `json:"hidden_panels,omitempty"`   // Panels left empty by the segment (see HiddenPanels)
 }
 
 // TimeSeriesPoint represents a single data point in a time series chart.
 type TimeSeriesPoint struct {
-	Date  string `json:"date"`
-	Count int    `json:"count"`
+	Date      string   `json:"date"`
+	Count     int      `json:"count"`
+	ChangePct *float64 `json:"change_pct,omitempty"` // vs the same bucket of the comparison period, when compared
 }
 
-// FetchDashboardMetrics loads all dashboard metrics in parallel for the given timeframe and website.
-func FetchDashboardMetrics(db *gorm.DB, tf *timeframe.TimeFrame, websiteId int, logger *slog.Logger) (*DashboardMetrics, error) {
+// FetchDashboardMetrics loads all dashboard metrics in parallel for the given timeframe and website,
+// scoped to the segment described by filters (see SegmentFilterKeys). Top-N lists hold up to
+// limit entries.
+func FetchDashboardMetrics(db *gorm.DB, tf *timeframe.TimeFrame, websiteId int, filters map[string]string, limit int, logger *slog.Logger) (*DashboardMetrics, error) {
 	queryParams := NewWebsiteScopedQueryParams(tf, websiteId)
-
-	conversionGoals, err := settings.GetWebsiteGoals(db, uint(websiteId))
-	if err != nil {
-		logger.Error("Error fetching conversion goals", slog.Any("error", err))
-		conversionGoals = []string{}
-	}
-
-	tasks := []async.Task{
-		timeSeriesTask("pageViews", func() ([]timeframe.DateStat, error) { return AggregatedPageViewsInTimeFrame(db, queryParams) }, logger),
-		timeSeriesTask("visitors", func() ([]timeframe.DateStat, error) { return AggregatedVisitorsInTimeFrame(db, queryParams) }, logger),
-		timeSeriesTask("sessions", func() ([]timeframe.DateStat, error) { return AggregatedSessionsInTimeFrame(db, queryParams) }, logger),
-		timeSeriesTask("revenue", func() ([]timeframe.DateStat, error) { return AggregatedRevenueInTimeFrame(db, queryParams) }, logger),
-		formattedMetricTask("topCountries", func() ([]MetricCountResult, error) { return GetTopCountriesInTimeFrame(db, queryParams) }, FormatCountryStats),
-		formattedMetricTask("topDevices", func() ([]MetricCountResult, error) { return GetTopDeviceTypesInTimeFrame(db, queryParams) }, FormatDeviceStats),
-		formattedMetricTask("topReferrers", func() ([]MetricCountResult, error) { return GetTopReferrersInTimeFrame(db, queryParams) }, FormatReferrerStats),
-		formattedMetricTask("topBrowsers", func() ([]MetricCountResult, error) { return GetTopBrowsersInTimeFrame(db, queryParams) }, FormatBrowserStats),
-		formattedMetricTask("topOperatingSystems", func() ([]MetricCountResult, error) { return GetTopOsInTimeFrame(db, queryParams) }, FormatOSStats),
-		passthroughTask("topUrls", func() (interface{}, error) { return GetTopURLsInTimeFrame(db, queryParams) }),
-		passthroughTask("topCustomEvents", func() (interface{}, error) { return GetTopCustomEventsInTimeFrame(db, queryParams) }),
-		passthroughTask("eventRevenueTotals", func() (interface{}, error) { return GetEventRevenueTotals(db, queryParams) }),
-		passthroughTask("bounceRate", func() (interface{}, error) { return GetBounceRateInTimeFrame(db, queryParams) }),
-		passthroughTask("visitsDuration", func() (interface{}, error) { return GetVisitDurationInTimeFrame(db, queryParams) }),
-		passthroughTask("revenuePerVisitor", func() (interface{}, error) { return GetRevenuePerVisitor(db, queryParams) }),
-		passthroughTask("topEntryPages", func() (interface{}, error) { return GetTopEntryPagesInTimeFrame(db, queryParams) }),
-		passthroughTask("topExitPages", func() (interface{}, error) { return GetTopExitPagesInTimeFrame(db, queryParams) }),
-		passthroughTask("topUTMMediums", func() (interface{}, error) { return GetTopUTMMediumsInTimeFrame(db, queryParams) }),
-		passthroughTask("topUTMSources", func() (interface{}, error) { return GetTopUTMSourcesInTimeFrame(db, queryParams) }),
-		passthroughTask("topUTMCampaigns", func() (interface{}, error) { return GetTopUTMCampaignsInTimeFrame(db, queryParams) }),
-		passthroughTask("topUTMTerms", func() (interface{}, error) { return GetTopUTMTermsInTimeFrame(db, queryParams) }),
-		passthroughTask("topUTMContents", func() (interface{}, error) { return GetTopUTMContentsInTimeFrame(db, queryParams) }),
-		passthroughTask("topRefParams", func() (interface{}, error) { return GetTopQueryParamValuesInTimeFrame(db, queryParams, "ref") }),
-		passthroughTask("totalVisitors", func() (interface{}, error) { return GetTotalVisitorsInTimeFrame(db, queryParams) }),
-		passthroughTask("totalViews", func() (interface{}, error) { return GetTotalPageViewsInTimeFrame(db, queryParams) }),
-		passthroughTask("totalSessions", func() (interface{}, error) { return GetTotalSessionsInTimeFrame(db, queryParams) }),
-		passthroughTask("totalEntryCount", func() (interface{}, error) { return GetTotalEntryCountInTimeFrame(db, queryParams) }),
-		passthroughTask("totalExitCount", func() (interface{}, error) { return GetTotalExitCountInTimeFrame(db, queryParams) }),
-		passthroughTask("totalCustomEvents", func() (interface{}, error) { return GetTotalCustomEventsInTimeFrame(db, queryParams) }),
-		passthroughTask("revenueMetrics", func() (interface{}, error) { return GetRevenueMetrics(db, queryParams) }),
-		passthroughTask("topRevenueEvents", func() (interface{}, error) { return GetTopRevenueEvents(db, queryParams) }),
-		{Name: "conversionGoals", Execute: func() (interface{}, error) { return conversionGoals, nil }},
-	}
+	queryParams.Filters = NewSegmentFilters(filters)
+	queryParams.Limit = limit
+	tasks := dashboardTasks(db, queryParams, logger)
 
 	pool := async.NewPool(12)
 	results := pool.Execute(context.Background(), tasks)
@@ -118,22 +119,34 @@ func FetchDashboardMetrics(db *gorm.DB, tf *timeframe.TimeFrame, websiteId int,
 		PageViews:            results["pageViews"].Data.([]TimeSeriesPoint),
 		Visitors:             results["visitors"].Data.([]TimeSeriesPoint),
 		Sessions:             results["sessions"].Data.([]TimeSeriesPoint),
-		GoalConversions:      results["revenue"].Data.([]TimeSeriesPoint),
+		GoalConversions:      results["goalConversions"].Data.([]TimeSeriesPoint),
 		Revenue:              results["revenue"].Data.([]TimeSeriesPoint),
 		TopURLs:              ensureNonNil(metricResultsOrEmpty(results, "topUrls")),
 		TopCountries:         ensureNonNil(metricResultsOrEmpty(results, "topCountries")),
+		TopRegions:           ensureNonNil(metricResultsOrEmpty(results, "topRegions")),
+		TopCities:            ensureNonNil(metricResultsOrEmpty(results, "topCities")),
+		TopLanguages:         ensureNonNil(metricResultsOrEmpty(results, "topLanguages")),
 		TopDevices:           ensureNonNil(metricResultsOrEmpty(results, "topDevices")),
+		TopDeviceModels:      ensureNonNil(metricResultsOrEmpty(results, "topDeviceModels")),
+		TopScreenSizes:       ensureNonNil(metricResultsOrEmpty(results, "topScreenSizes")),
 		TopReferrers:         ensureNonNil(metricResultsOrEmpty(results, "topReferrers")),
+		TrafficChannels:      ensureNonNil(metricResultsOrEmpty(results, "trafficChannels")),
 		TopBrowsers:          ensureNonNil(metricResultsOrEmpty(results, "topBrowsers")),
+		TopBrowserVersions:   ensureNonNil(metricResultsOrEmpty(results, "topBrowserVersions")),
 		TopCustomEvents:      ensureNonNil(metricResultsOrEmpty(results, "topCustomEvents")),
+		TopDownloads:         ensureNonNil(metricResultsOrEmpty(results, "topDownloads")),
 		EventConversionRates: map[string]float64{},
+		GoalConversionRates:  results["goalConversionRates"].Data.(map[string]float64),
 		TopOperatingSystems:  ensureNonNil(metricResultsOrEmpty(results, "topOperatingSystems")),
 		EventRevenueTotals:   revenueTotalsOrEmpty(results, "eventRevenueTotals"),
 		BounceRate:           results["bounceRate"].Data.(float64),
 		VisitsDuration:       results["visitsDuration"].Data.(float64),
+		PagesPerSession:      results["pagesPerSession"].Data.(float64),
 		RevenuePerVisitor:    results["revenuePerVisitor"].Data.(float64),
+		AverageOrderValue:    results["averageOrderValue"].Data.(float64),
 		TopEntryPages:        ensureNonNil(metricResultsOrEmpty(results, "topEntryPages")),
 		TopExitPages:         ensureNonNil(metricResultsOrEmpty(results, "topExitPages")),
+		Top404s:              ensureNonNil(metricResultsOrEmpty(results, "top404s")),
 		TopUTMMediums:        ensureNonNil(metricResultsOrEmpty(results, "topUTMMediums")),
 		TopUTMSources:        ensureNonNil(metricResultsOrEmpty(results, "topUTMSources")),
 		TopUTMCampaigns:      ensureNonNil(metricResultsOrEmpty(results, "topUTMCampaigns")),
@@ -149,28 +162,328 @@ func FetchDashboardMetrics(db *gorm.DB, tf *timeframe.TimeFrame, websiteId int,
 		TotalCustomEvents:    results["totalCustomEvents"].Data.(int64),
 		RevenueMetrics:       results["revenueMetrics"].Data.(*RevenueMetrics),
 		TopRevenueEvents:     ensureNonNil(metricResultsOrEmpty(results, "topRevenueEvents")),
+		RevenueByReferrer:    revenueSourcesOrEmpty(results, "revenueByReferrer"),
+		RevenueByUTMSource:   revenueSourcesOrEmpty(results, "revenueByUTMSource"),
 		ConversionGoals:      results["conversionGoals"].Data.([]string),
 		Insights:             []interface{}{},
 		UserFlow:             []UserFlowLink{},
+		Filters:              queryParams.Filters,
+		UnscopedPanels:       UnscopedPanels(queryParams.Filters),
+		HiddenPanels:         HiddenPanels(queryParams.Filters),
 	}
 
 	resp.EventConversionRates = buildEventConversionRates(resp)
+	resp.NewVisitors, resp.ReturningVisitors, resp.NewVisitorsSeries, resp.ReturningVisitorsSeries = newVsReturningOrEmpty(results, "newVsReturning")
+	resp.TrafficHeatmap, _ = results["trafficHeatmap"].Data.(*TrafficHeatmap)
+	resp.UTMTermConversions = utmConversionsOrEmpty(results, "utmTermConversions")
+	resp.UTMContentConversions = utmConversionsOrEmpty(results, "utmContentConversions")
+	resp.TopUTMCombinations = utmCombinationsOrEmpty(results, "topUTMCombinations")
+	resp.EntryExitPairs = entryExitPairsOrEmpty(results, "entryExitPairs")
 
 	return resp, nil
 }
 
-// FetchComparisonMetrics loads comparison period metrics for deferred rendering.
-func FetchComparisonMetrics(db *gorm.DB, tf *timeframe.TimeFrame, websiteId int, currentMetrics *DashboardMetrics, logger *slog.Logger) *ComparisonMetrics {
-	duration := tf.To.Sub(tf.From)
-	comparisonFrom := tf.From.Add(-duration)
-	comparisonTo := tf.From
+// ErrUnknownMetric is returned when a requested metric is not a dashboard metric.
+var ErrUnknownMetric = errors.New("unknown metric")
+
+// dashboardMetricTasks maps the DashboardMetrics JSON fields that can be
+// requested on their own to the task that computes them.
+var dashboardMetricTasks = map[string]string{
+	"page_views":                "pageViews",
+	"visitors":                  "visitors",
+	"sessions":                  "sessions",
+	"goal_conversions":          "goalConversions",
+	"goal_conversion_rates":     "goalConversionRates",
+	"revenue":                   "revenue",
+	"top_urls":                  "topUrls",
+	"top_countries":             "topCountries",
+	"top_regions":               "topRegions",
+	"top_cities":                "topCities",
+	"top_languages":             "topLanguages",
+	"top_devices":               "topDevices",
+	"top_device_models":         "topDeviceModels",
+	"top_screen_sizes":          "topScreenSizes",
+	"top_referrers":             "topReferrers",
+	"traffic_channels":          "trafficChannels",
+	"top_browsers":              "topBrowsers",
+	"top_browser_versions":      "topBrowserVersions",
+	"top_custom_events":         "topCustomEvents",
+	"top_downloads":             "topDownloads",
+	"top_operating_systems":     "topOperatingSystems",
+	"event_revenue_totals":      "eventRevenueTotals",
+	"bounce_rate":               "bounceRate",
+	"visits_duration":           "visitsDuration",
+	"pages_per_session":         "pagesPerSession",
+	"revenue_per_visitor":       "revenuePerVisitor",
+	"average_order_value":       "averageOrderValue",
+	"top_entry_pages":           "topEntryPages",
+	"top_exit_pages":            "topExitPages",
+	"top_404s":                  "top404s",
+	"entry_exit_pairs":          "entryExitPairs",
+	"top_utm_mediums":           "topUTMMediums",
+	"top_utm_sources":           "topUTMSources",
+	"top_utm_campaigns":         "topUTMCampaigns",
+	"top_utm_terms":             "topUTMTerms",
+	"top_utm_contents":          "topUTMContents",
+	"utm_term_conversions":      "utmTermConversions",
+	"utm_content_conversions":   "utmContentConversions",
+	"top_utm_combinations":      "topUTMCombinations",
+	"top_ref_params":            "topRefParams",
+	"total_visitors":            "totalVisitors",
+	"new_visitors":              "newVsReturning",
+	"returning_visitors":        "newVsReturning",
+	"new_visitors_series":       "newVsReturning",
+	"returning_visitors_series": "newVsReturning",
+	"traffic_heatmap":           "trafficHeatmap",
+	"total_views":               "totalViews",
+	"total_sessions":            "totalSessions",
+	"total_entry_count":         "totalEntryCount",
+	"total_exit_count":          "totalExitCount",
+	"total_custom_events":       "totalCustomEvents",
+	"revenue_metrics":           "revenueMetrics",
+	"top_revenue_events":        "topRevenueEvents",
+	"revenue_by_referrer":       "revenueByReferrer",
+	"revenue_by_utm_source":     "revenueByUTMSource",
+	"conversion_goals":          "conversionGoals",
+}
+
+// ValidateDashboardMetrics checks that every name is a DashboardMetrics field
+// that can be requested on its own.
+func ValidateDashboardMetrics(metrics []string) error {
+	for _, metric := range metrics {
+		if _, ok := dashboardMetricTasks[metric]; !ok {
+			return fmt.Errorf("%w: %s", ErrUnknownMetric, metric)
+		}
+	}
+	return nil
+}
+
+// RestrictDashboardMetrics returns a copy of the metrics with every requestable
+// metric outside allowed blanked out. Slices and maps are emptied rather than
+// nulled so read-only dashboards keep rendering; an empty allowed list keeps
+// everything.
+func RestrictDashboardMetrics(metrics *DashboardMetrics, allowed []string) *DashboardMetrics {
+	restricted := *metrics
+	if len(allowed) == 0 {
+		return &restricted
+	}
+
+	keep := make(map[string]bool, len(allowed))
+	for _, metric := range allowed {
+		keep[metric] = true
+	}
+
+	value := reflect.ValueOf(&restricted).Elem()
+	for i := 0; i < value.NumField(); i++ {
+		name, _, _ := strings.Cut(value.Type().Field(i).Tag.Get("json"), ",")
+		if _, requestable := dashboardMetricTasks[name]; !requestable || keep[name] {
+			continue
+		}
+		field := value.Field(i)
+		switch field.Kind() {
+		case reflect.Slice:
+			field.Set(reflect.MakeSlice(field.Type(), 0, 0))
+		case reflect.Map:
+			field.Set(reflect.MakeMap(field.Type()))
+		default:
+			field.Set(reflect.Zero(field.Type()))
+		}
+	}
+	return &restricted
+}
+
+// FetchDashboardMetricsSubset loads only the requested dashboard metrics,
+// identified by their DashboardMetrics JSON field names, so API callers don't
+// pay for the full task pool. The result uses the same keys and value shapes
+// as DashboardMetrics, plus bucket_size, filters and unscoped_panels.
+func FetchDashboardMetricsSubset(db *gorm.DB, tf *timeframe.TimeFrame, websiteId int, filters map[string]string, metrics []string, limit int, logger *slog.Logger) (map[string]interface{}, error) {
+	selected := make(map[string]bool, len(metrics))
+	for _, metric := range metrics {
+		taskName, ok := dashboardMetricTasks[metric]
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownMetric, metric)
+		}
+		selected[taskName] = true
+	}
+
+	queryParams := NewWebsiteScopedQueryParams(tf, websiteId)
+	queryParams.Filters = NewSegmentFilters(filters)
+	queryParams.Limit = limit
+
+	var tasks []async.Task
+	for _, task := range dashboardTasks(db, queryParams, logger) {
+		if selected[task.Name] {
+			tasks = append(tasks, task)
+		}
+	}
+
+	pool := async.NewPool(12)
+	results := pool.Execute(context.Background(), tasks)
 
-	comparisonTF := &timeframe.TimeFrame{
-		From:       comparisonFrom,
-		To:         comparisonTo,
-		BucketSize: tf.BucketSize,
+	for name, result := range results {
+		if result.Err != nil {
+			return nil, fmt.Errorf("error fetching %s: %w", name, result.Err)
+		}
+	}
+
+	resp := map[string]interface{}{"bucket_size": string(tf.BucketSize), "filters": queryParams.Filters}
+	var unscoped []string
+	for _, panel := range UnscopedPanels(queryParams.Filters) {
+		if slices.Contains(metrics, panel) {
+			unscoped = append(unscoped, panel)
+		}
+	}
+	if len(unscoped) > 0 {
+		resp["unscoped_panels"] = unscoped
+	}
+	var hidden []string
+	for _, panel := range HiddenPanels(queryParams.Filters) {
+		if slices.Contains(metrics, panel) {
+			hidden = append(hidden, panel)
+		}
+	}
+	if len(hidden) > 0 {
+		resp["hidden_panels"] = hidden
+	}
+	for _, metric := range metrics {
+		taskName := dashboardMetricTasks[metric]
+		newVisitors, returningVisitors, newSeries, returningSeries := newVsReturningOrEmpty(results, taskName)
+		switch {
+		case metric == "new_visitors":
+			resp[metric] = newVisitors
+		case metric == "returning_visitors":
+			resp[metric] = returningVisitors
+		case metric == "new_visitors_series":
+			resp[metric] = newSeries
+		case metric == "returning_visitors_series":
+			resp[metric] = returningSeries
+		case metric == "event_revenue_totals":
+			resp[metric] = revenueTotalsOrEmpty(results, taskName)
+		case strings.HasPrefix(metric, "revenue_by_"):
+			resp[metric] = revenueSourcesOrEmpty(results, taskName)
+		case strings.HasPrefix(metric, "utm_"):
+			resp[metric] = utmConversionsOrEmpty(results, taskName)
+		case metric == "top_utm_combinations":
+			resp[metric] = utmCombinationsOrEmpty(results, taskName)
+		case metric == "entry_exit_pairs":
+			resp[metric] = entryExitPairsOrEmpty(results, taskName)
+		case strings.HasPrefix(metric, "top_"):
+			resp[metric] = ensureNonNil(metricResultsOrEmpty(results, taskName))
+		default:
+			resp[metric] = results[taskName].Data
+		}
+	}
+
+	return resp, nil
+}
+
+// dashboardTasks builds the query tasks behind every dashboard metric, keyed by task name.
+func dashboardTasks(db *gorm.DB, queryParams WebsiteScopedQueryParams, logger *slog.Logger) []async.Task {
+	return []async.Task{
+		timeSeriesTask("pageViews", func() ([]timeframe.DateStat, error) { return AggregatedPageViewsInTimeFrame(db, queryParams) }, logger),
+		timeSeriesTask("visitors", func() ([]timeframe.DateStat, error) { return AggregatedVisitorsInTimeFrame(db, queryParams) }, logger),
+		timeSeriesTask("sessions", func() ([]timeframe.DateStat, error) { return AggregatedSessionsInTimeFrame(db, queryParams) }, logger),
+		timeSeriesTask("revenue", func() ([]timeframe.DateStat, error) { return AggregatedRevenueInTimeFrame(db, queryParams) }, logger),
+		timeSeriesTask("goalConversions", func() ([]timeframe.DateStat, error) {
+			conversionGoals, err := settings.GetWebsiteGoals(db, uint(queryParams.WebsiteID))
+			if err != nil {
+				return nil, err
+			}
+			return AggregatedGoalConversionsInTimeFrame(db, queryParams, conversionGoals)
+		}, logger),
+		passthroughTask("goalConversionRates", func() (interface{}, error) {
+			conversionGoals, err := settings.GetWebsiteGoals(db, uint(queryParams.WebsiteID))
+			if err != nil {
+				return nil, err
+			}
+			return GetGoalConversionRates(db, queryParams, conversionGoals)
+		}),
+		formattedMetricTask("topCountries", func() ([]MetricCountResult, error) { return GetTopCountriesInTimeFrame(db, queryParams) }, FormatCountryStats),
+		formattedMetricTask("topLanguages", func() ([]MetricCountResult, error) { return GetTopLanguagesInTimeFrame(db, queryParams) }, FormatLanguageStats),
+		formattedMetricTask("topDevices", func() ([]MetricCountResult, error) { return GetTopDeviceTypesInTimeFrame(db, queryParams) }, FormatDeviceStats),
+		formattedMetricTask("topDeviceModels", func() ([]MetricCountResult, error) { return GetTopDeviceModelsInTimeFrame(db, queryParams) }, FormatDeviceModelStats),
+		formattedMetricTask("topScreenSizes", func() ([]MetricCountResult, error) { return GetTopScreenSizesInTimeFrame(db, queryParams) }, FormatScreenSizeStats),
+		formattedMetricTask("topReferrers", func() ([]MetricCountResult, error) { return GetTopReferrersInTimeFrame(db, queryParams) }, FormatReferrerStats),
+		formattedMetricTask("topBrowsers", func() ([]MetricCountResult, error) { return GetTopBrowsersInTimeFrame(db, queryParams) }, FormatBrowserStats),
+		formattedMetricTask("topBrowserVersions", func() ([]MetricCountResult, error) { return GetTopBrowserVersionsInTimeFrame(db, queryParams) }, FormatBrowserStats),
+		formattedMetricTask("topOperatingSystems", func() ([]MetricCountResult, error) { return GetTopOsInTimeFrame(db, queryParams) }, FormatOSStats),
+		passthroughTask("topUrls", func() (interface{}, error) { return GetTopURLsInTimeFrame(db, queryParams) }),
+		passthroughTask("topRegions", func() (interface{}, error) { return GetTopRegionsInTimeFrame(db, queryParams) }),
+		passthroughTask("topCities", func() (interface{}, error) { return GetTopCitiesInTimeFrame(db, queryParams) }),
+		passthroughTask("trafficChannels", func() (interface{}, error) { return GetTrafficChannelsInTimeFrame(db, queryParams) }),
+		passthroughTask("topCustomEvents", func() (interface{}, error) { return GetTopCustomEventsInTimeFrame(db, queryParams) }),
+		passthroughTask("topDownloads", func() (interface{}, error) { return GetTopDownloadsInTimeFrame(db, queryParams) }),
+		passthroughTask("eventRevenueTotals", func() (interface{}, error) { return GetEventRevenueTotals(db, queryParams) }),
+		passthroughTask("bounceRate", func() (interface{}, error) { return GetBounceRateInTimeFrame(db, queryParams) }),
+		passthroughTask("visitsDuration", func() (interface{}, error) { return GetVisitDurationInTimeFrame(db, queryParams) }),
+		passthroughTask("pagesPerSession", func() (interface{}, error) { return GetPagesPerSessionInTimeFrame(db, queryParams) }),
+		passthroughTask("revenuePerVisitor", func() (interface{}, error) { return GetRevenuePerVisitor(db, queryParams) }),
+		passthroughTask("averageOrderValue", func() (interface{}, error) { return GetAverageOrderValue(db, queryParams) }),
+		passthroughTask("topEntryPages", func() (interface{}, error) { return GetTopEntryPagesInTimeFrame(db, queryParams) }),
+		passthroughTask("topExitPages", func() (interface{}, error) { return GetTopExitPagesInTimeFrame(db, queryParams) }),
+		passthroughTask("top404s", func() (interface{}, error) { return GetTop404sInTimeFrame(db, queryParams) }),
+		passthroughTask("entryExitPairs", func() (interface{}, error) { return GetEntryExitPairsInTimeFrame(db, queryParams) }),
+		passthroughTask("topUTMMediums", func() (interface{}, error) { return GetTopUTMMediumsInTimeFrame(db, queryParams) }),
+		passthroughTask("topUTMSources", func() (interface{}, error) { return GetTopUTMSourcesInTimeFrame(db, queryParams) }),
+		passthroughTask("topUTMCampaigns", func() (interface{}, error) { return GetTopUTMCampaignsInTimeFrame(db, queryParams) }),
+		passthroughTask("topUTMTerms", func() (interface{}, error) { return GetTopUTMTermsInTimeFrame(db, queryParams) }),
+		passthroughTask("topUTMContents", func() (interface{}, error) { return GetTopUTMContentsInTimeFrame(db, queryParams) }),
+		passthroughTask("utmTermConversions", func() (interface{}, error) {
+			conversionGoals, err := settings.GetWebsiteGoals(db, uint(queryParams.WebsiteID))
+			if err != nil {
+				return nil, err
+			}
+			return GetTopUTMTermConversions(db, queryParams, conversionGoals)
+		}),
+		passthroughTask("utmContentConversions", func() (interface{}, error) {
+			conversionGoals, err := settings.GetWebsiteGoals(db, uint(queryParams.WebsiteID))
+			if err != nil {
+				return nil, err
+			}
+			return GetTopUTMContentConversions(db, queryParams, conversionGoals)
+		}),
+		passthroughTask("topUTMCombinations", func() (interface{}, error) { return GetUTMCombinationsInTimeFrame(db, queryParams) }),
+		passthroughTask("topRefParams", func() (interface{}, error) { return GetTopQueryParamValuesInTimeFrame(db, queryParams, "ref") }),
+		passthroughTask("totalVisitors", func() (interface{}, error) { return GetTotalVisitorsInTimeFrame(db, queryParams) }),
+		passthroughTask("newVsReturning", func() (interface{}, error) { return GetNewVsReturningInTimeFrame(db, queryParams) }),
+		passthroughTask("trafficHeatmap", func() (interface{}, error) { return GetTrafficHeatmap(db, queryParams) }),
+		passthroughTask("totalViews", func() (interface{}, error) { return GetTotalPageViewsInTimeFrame(db, queryParams) }),
+		passthroughTask("totalSessions", func() (interface{}, error) { return GetTotalSessionsInTimeFrame(db, queryParams) }),
+		passthroughTask("totalEntryCount", func() (interface{}, error) { return GetTotalEntryCountInTimeFrame(db, queryParams) }),
+		passthroughTask("totalExitCount", func() (interface{}, error) { return GetTotalExitCountInTimeFrame(db, queryParams) }),
+		passthroughTask("totalCustomEvents", func() (interface{}, error) { return GetTotalCustomEventsInTimeFrame(db, queryParams) }),
+		passthroughTask("revenueMetrics", func() (interface{}, error) { return GetRevenueMetrics(db, queryParams) }),
+		passthroughTask("topRevenueEvents", func() (interface{}, error) { return GetTopRevenueEvents(db, queryParams) }),
+		passthroughTask("revenueByReferrer", func() (interface{}, error) { return GetRevenueByReferrer(db, queryParams) }),
+		passthroughTask("revenueByUTMSource", func() (interface{}, error) { return GetRevenueByUTMSource(db, queryParams) }),
+		{Name: "conversionGoals", Execute: func() (interface{}, error) {
+			conversionGoals, err := settings.GetWebsiteGoals(db, uint(queryParams.WebsiteID))
+			if err != nil {
+				logger.Error("Error fetching conversion goals", slog.Any("error", err))
+				conversionGoals = []string{}
+			}
+			return conversionGoals, nil
+		}},
+	}
+}
+
+// FetchComparisonMetrics loads comparison period metrics for deferred rendering,
+// scoped to the same segment as currentMetrics. comparisonTF is compared against
+// when set, otherwise the equal-length period right before tf.
+func FetchComparisonMetrics(db *gorm.DB, tf *timeframe.TimeFrame, comparisonTF *timeframe.TimeFrame, websiteId int, currentMetrics *DashboardMetrics, logger *slog.Logger) *ComparisonMetrics {
+	if comparisonTF == nil {
+		duration := tf.To.Sub(tf.From)
+		comparisonTF = &timeframe.TimeFrame{
+			From:       tf.From.Add(-duration),
+			To:         tf.From,
+			BucketSize: tf.BucketSize,
+			Tz:         tf.Tz,
+		}
 	}
 	comparisonParams := NewWebsiteScopedQueryParams(comparisonTF, websiteId)
+	comparisonParams.Filters = NewSegmentFilters(currentMetrics.Filters)
+	// Match the current lists' depth so entries past the default limit aren't reported as new
+	comparisonParams.Limit = max(DefaultTopLimit, len(currentMetrics.TopReferrers), len(currentMetrics.TopURLs))
 
 	tasks := []async.Task{
 		passthroughTask("comparisonVisitors", func() (interface{}, error) { return GetTotalVisitorsInTimeFrame(db, comparisonParams) }),
@@ -178,18 +491,24 @@ func FetchComparisonMetrics(db *gorm.DB, tf *timeframe.TimeFrame, websiteId int,
 		passthroughTask("comparisonSessions", func() (interface{}, error) { return GetTotalSessionsInTimeFrame(db, comparisonParams) }),
 		passthroughTask("comparisonBounceRate", func() (interface{}, error) { return GetBounceRateInTimeFrame(db, comparisonParams) }),
 		passthroughTask("comparisonVisitsDuration", func() (interface{}, error) { return GetVisitDurationInTimeFrame(db, comparisonParams) }),
+		passthroughTask("comparisonPagesPerSession", func() (interface{}, error) { return GetPagesPerSessionInTimeFrame(db, comparisonParams) }),
 		passthroughTask("comparisonRevenueMetrics", func() (interface{}, error) { return GetRevenueMetrics(db, comparisonParams) }),
+		timeSeriesTask("comparisonPageViewsSeries", func() ([]timeframe.DateStat, error) { return AggregatedPageViewsInTimeFrame(db, comparisonParams) }, logger),
+		timeSeriesTask("comparisonVisitorsSeries", func() ([]timeframe.DateStat, error) { return AggregatedVisitorsInTimeFrame(db, comparisonParams) }, logger),
+		formattedMetricTask("comparisonTopReferrers", func() ([]MetricCountResult, error) { return GetTopReferrersInTimeFrame(db, comparisonParams) }, FormatReferrerStats),
+		passthroughTask("comparisonTopUrls", func() (interface{}, error) { return GetTopURLsInTimeFrame(db, comparisonParams) }),
 	}
 
 	pool := async.NewPool(6)
 	results := pool.Execute(context.Background(), tasks)
 
 	data := ComparisonData{
-		CurrentVisitors:   currentMetrics.TotalVisitors,
-		CurrentViews:      currentMetrics.TotalViews,
-		CurrentSessions:   currentMetrics.TotalSessions,
-		CurrentBounceRate: currentMetrics.BounceRate,
-		CurrentAvgTime:    currentMetrics.VisitsDuration,
+		CurrentVisitors:        currentMetrics.TotalVisitors,
+		CurrentViews:           currentMetrics.TotalViews,
+		CurrentSessions:        currentMetrics.TotalSessions,
+		CurrentBounceRate:      currentMetrics.BounceRate,
+		CurrentAvgTime:         currentMetrics.VisitsDuration,
+		CurrentPagesPerSession: currentMetrics.PagesPerSession,
 	}
 
 	if v, ok := results["comparisonVisitors"].Data.(int64); ok {
@@ -207,6 +526,9 @@ func FetchComparisonMetrics(db *gorm.DB, tf *timeframe.TimeFrame, websiteId int,
 	if v, ok := results["comparisonVisitsDuration"].Data.(float64); ok {
 		data.PreviousAvgTime = v
 	}
+	if v, ok := results["comparisonPagesPerSession"].Data.(float64); ok {
+		data.PreviousPagesPerSession = v
+	}
 	if currentMetrics.RevenueMetrics != nil {
 		data.CurrentRevenue = currentMetrics.RevenueMetrics.TotalRevenue
 	}
@@ -214,7 +536,22 @@ func FetchComparisonMetrics(db *gorm.DB, tf *timeframe.TimeFrame, websiteId int,
 		data.PreviousRevenue = v.TotalRevenue
 	}
 
-	return CalculateComparisonMetrics(data)
+	comparison := CalculateComparisonMetrics(data)
+	if previous, ok := results["comparisonPageViewsSeries"].Data.([]TimeSeriesPoint); ok {
+		comparison.PageViews = AlignTimeSeriesChanges(currentMetrics.PageViews, previous)
+	}
+	if previous, ok := results["comparisonVisitorsSeries"].Data.([]TimeSeriesPoint); ok {
+		comparison.Visitors = AlignTimeSeriesChanges(currentMetrics.Visitors, previous)
+	}
+	// Without the previous lists every current entry would look new
+	if results["comparisonTopReferrers"].Err == nil && results["comparisonTopUrls"].Err == nil {
+		comparison.TopLists = &ComparisonTopLists{
+			Referrers: CalculateTopListChanges(currentMetrics.TopReferrers, metricResultsOrEmpty(results, "comparisonTopReferrers")),
+			URLs:      CalculateTopListChanges(currentMetrics.TopURLs, metricResultsOrEmpty(results, "comparisonTopUrls")),
+		}
+	}
+
+	return comparison
 }
 
 // Task builder helpers
@@ -292,6 +629,46 @@ func revenueTotalsOrEmpty(results map[string]async.Result, name string) map[stri
 	return map[string]float64{}
 }
 
+func revenueSourcesOrEmpty(results map[string]async.Result, name string) []RevenueSourceResult {
+	if sources, ok := results[name].Data.([]RevenueSourceResult); ok {
+		return sources
+	}
+	return []RevenueSourceResult{}
+}
+
+func utmConversionsOrEmpty(results map[string]async.Result, name string) []UTMConversionResult {
+	if rows, ok := results[name].Data.([]UTMConversionResult); ok && rows != nil {
+		return rows
+	}
+	return []UTMConversionResult{}
+}
+
+// utmCombinationsOrEmpty unpacks a UTM combinations task result
+func utmCombinationsOrEmpty(results map[string]async.Result, name string) []UTMCombinationResult {
+	if rows, ok := results[name].Data.([]UTMCombinationResult); ok && rows != nil {
+		return rows
+	}
+	return []UTMCombinationResult{}
+}
+
+// entryExitPairsOrEmpty unpacks an entry/exit pairs task result
+func entryExitPairsOrEmpty(results map[string]async.Result, name string) []EntryExitPair {
+	if rows, ok := results[name].Data.([]EntryExitPair); ok && rows != nil {
+		return rows
+	}
+	return []EntryExitPair{}
+}
+
+// newVsReturningOrEmpty unpacks the new vs returning split, which is empty
+// when segment filters are active.
+func newVsReturningOrEmpty(results map[string]async.Result, name string) (int64, int64, []TimeSeriesPoint, []TimeSeriesPoint) {
+	split, ok := results[name].Data.(*NewVsReturning)
+	if !ok || split == nil {
+		return 0, 0, []TimeSeriesPoint{}, []TimeSeriesPoint{}
+	}
+	return split.NewVisitors, split.ReturningVisitors, convertToTimeSeries(split.NewSeries), convertToTimeSeries(split.ReturningSeries)
+}
+
 func ensureNonNil(items []MetricCountResult) []MetricCountResult {
 	if items == nil {
 		return []MetricCountResult{}
@@ -0,0 +1,202 @@
+package analytics
+
+import (
+	"log/slog"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+
+	"fusionaly/internal/timeframe"
+)
+
+// maxDashboardCacheEntries bounds memory when many distinct timeframes are viewed
+const maxDashboardCacheEntries = 500
+
+// DashboardCacheKey identifies one dashboard query.
+type DashboardCacheKey struct {
+	WebsiteID int
+	From      int64
+	To        int64
+	Bucket    string
+	TimeZone  string
+	Filters   string
+	Limit     int
+	Panels    string // DashboardPanelOptions of admin dashboards, "" otherwise
+}
+
+// NewDashboardCacheKey builds the cache key for a FetchDashboardMetrics call.
+func NewDashboardCacheKey(tf *timeframe.TimeFrame, websiteId int, filters map[string]string, limit int) DashboardCacheKey {
+	pairs := make([]string, 0, len(filters))
+	for key, value := range filters {
+		if value != "" {
+			pairs = append(pairs, key+"="+value)
+		}
+	}
+	sort.Strings(pairs)
+
+	tz := ""
+	if tf.Tz != nil {
+		tz = tf.Tz.String()
+	}
+
+	return DashboardCacheKey{
+		WebsiteID: websiteId,
+		From:      tf.From.UnixNano(),
+		To:        tf.To.UnixNano(),
+		Bucket:    string(tf.BucketSize),
+		TimeZone:  tz,
+		Filters:   strings.Join(pairs, "&"),
+		Limit:     limit,
+	}
+}
+
+type dashboardCacheEntry struct {
+	metrics   *DashboardMetrics
+	loadedAt  time.Time
+	expiresAt time.Time
+}
+
+// DashboardEntry is dashboard metrics as served by DashboardCache.
+type DashboardEntry struct {
+	Metrics *DashboardMetrics
+	// LoadedAt is when the cached Metrics were queried, zero when they weren't
+	// cached (caching disabled or invalidated while loading)
+	LoadedAt time.Time
+	// InvalidatedAt is when the website's entries were last invalidated, e.g.
+	// after new events for it were processed; zero if not since startup
+	InvalidatedAt time.Time
+}
+
+// Version identifies the data of a cached entry without serializing it, or is
+// "" when the metrics weren't cached. It changes whenever the metrics are
+// queried again or the website's data changes.
+func (e DashboardEntry) Version() string {
+	if e.LoadedAt.IsZero() {
+		return ""
+	}
+	return strconv.FormatInt(e.LoadedAt.UnixNano(), 36) + "." + strconv.FormatInt(e.InvalidatedAt.UnixNano(), 36)
+}
+
+// DashboardCache is an in-process TTL cache of dashboard metrics, so several
+// admins refreshing the same dashboard don't each run the full query pool.
+// A zero TTL disables caching.
+type DashboardCache struct {
+	mu            sync.Mutex
+	ttl           time.Duration
+	entries       map[DashboardCacheKey]dashboardCacheEntry
+	invalidatedAt map[int]time.Time
+	now           func() time.Time
+}
+
+// NewDashboardCache creates a cache whose entries live for ttl.
+func NewDashboardCache(ttl time.Duration) *DashboardCache {
+	return &DashboardCache{
+		ttl:           ttl,
+		entries:       make(map[DashboardCacheKey]dashboardCacheEntry),
+		invalidatedAt: make(map[int]time.Time),
+		now:           time.Now,
+	}
+}
+
+// SetTTL changes the entry lifetime and drops existing entries.
+func (c *DashboardCache) SetTTL(ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.ttl = ttl
+	c.entries = make(map[DashboardCacheKey]dashboardCacheEntry)
+}
+
+// Get returns the cached metrics for key, calling load on a miss and caching
+// its result. Errors are never cached.
+func (c *DashboardCache) Get(key DashboardCacheKey, load func() (*DashboardMetrics, error)) (*DashboardMetrics, error) {
+	entry, err := c.GetEntry(key, load)
+	return entry.Metrics, err
+}
+
+// GetEntry is Get returning the entry's timestamps along with the metrics.
+func (c *DashboardCache) GetEntry(key DashboardCacheKey, load func() (*DashboardMetrics, error)) (DashboardEntry, error) {
+	c.mu.Lock()
+	ttl := c.ttl
+	invalidatedAt := c.invalidatedAt[key.WebsiteID]
+	if entry, ok := c.entries[key]; ok && c.now().Before(entry.expiresAt) {
+		c.mu.Unlock()
+		return DashboardEntry{Metrics: entry.metrics, LoadedAt: entry.loadedAt, InvalidatedAt: invalidatedAt}, nil
+	}
+	loadedAt := c.now()
+	c.mu.Unlock()
+
+	metrics, err := load()
+	result := DashboardEntry{Metrics: metrics, InvalidatedAt: invalidatedAt}
+	if err != nil || ttl <= 0 {
+		return result, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.invalidatedAt[key.WebsiteID] != invalidatedAt {
+		// Invalidated while loading, so the metrics may already be stale
+		return result, nil
+	}
+	result.LoadedAt = loadedAt
+	now := c.now()
+	if len(c.entries) >= maxDashboardCacheEntries {
+		for k, entry := range c.entries {
+			if !now.Before(entry.expiresAt) {
+				delete(c.entries, k)
+			}
+		}
+		if len(c.entries) >= maxDashboardCacheEntries {
+			c.entries = make(map[DashboardCacheKey]dashboardCacheEntry)
+		}
+	}
+	c.entries[key] = dashboardCacheEntry{metrics: metrics, loadedAt: loadedAt, expiresAt: now.Add(ttl)}
+	return result, nil
+}
+
+// Invalidate drops every cached entry for the website.
+func (c *DashboardCache) Invalidate(websiteId int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.invalidatedAt[websiteId] = c.now()
+	for key := range c.entries {
+		if key.WebsiteID == websiteId {
+			delete(c.entries, key)
+		}
+	}
+}
+
+// dashboardCache backs CachedDashboardMetrics. It is disabled until the app
+// sets a TTL via SetDashboardCacheTTL.
+var dashboardCache = NewDashboardCache(0)
+
+// SetDashboardCacheTTL configures the shared dashboard cache. Zero disables it.
+func SetDashboardCacheTTL(ttl time.Duration) {
+	dashboardCache.SetTTL(ttl)
+}
+
+// InvalidateDashboardCache drops cached dashboard metrics for a website, e.g.
+// after new events for it were processed.
+func InvalidateDashboardCache(websiteId int) {
+	dashboardCache.Invalidate(websiteId)
+}
+
+// CachedDashboardMetrics is FetchDashboardMetrics behind the shared dashboard cache.
+func CachedDashboardMetrics(db *gorm.DB, tf *timeframe.TimeFrame, websiteId int, filters map[string]string, limit int, logger *slog.Logger) (*DashboardMetrics, error) {
+	return dashboardCache.Get(NewDashboardCacheKey(tf, websiteId, filters, limit), func() (*DashboardMetrics, error) {
+		return FetchDashboardMetrics(db, tf, websiteId, filters, limit, logger)
+	})
+}
+
+// CachedAdminDashboardMetrics is FetchAdminDashboardMetrics behind the shared
+// dashboard cache, keyed by the panel options as well.
+func CachedAdminDashboardMetrics(db *gorm.DB, tf *timeframe.TimeFrame, websiteId int, filters map[string]string, limit int, opts DashboardPanelOptions, logger *slog.Logger) (DashboardEntry, error) {
+	key := NewDashboardCacheKey(tf, websiteId, filters, limit)
+	key.Panels = opts.cacheKey()
+	return dashboardCache.GetEntry(key, func() (*DashboardMetrics, error) {
+		return FetchAdminDashboardMetrics(db, tf, websiteId, filters, limit, opts, logger)
+	})
+}
@@ -0,0 +1,154 @@
+package analytics_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"fusionaly/internal/analytics"
+	"fusionaly/internal/timeframe"
+)
+
+func TestDashboardCache(t *testing.T) {
+	july, err := timeframe.NewTimeFrame(timeframe.TimeFrameParams{
+		FromTime:      time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC),
+		ToTime:        time.Date(2024, 7, 31, 23, 59, 59, 0, time.UTC),
+		TimeFrameSize: timeframe.DailyTimeFrame,
+	}, time.UTC)
+	require.NoError(t, err)
+	august, err := timeframe.NewTimeFrame(timeframe.TimeFrameParams{
+		FromTime:      time.Date(2024, 8, 1, 0, 0, 0, 0, time.UTC),
+		ToTime:        time.Date(2024, 8, 31, 23, 59, 59, 0, time.UTC),
+		TimeFrameSize: timeframe.DailyTimeFrame,
+	}, time.UTC)
+	require.NoError(t, err)
+
+	// Counting fake standing in for the dashboard query pool
+	loads := 0
+	load := func() (*analytics.DashboardMetrics, error) {
+		loads++
+		return &analytics.DashboardMetrics{BucketSize: "day"}, nil
+	}
+
+	t.Run("second call within the TTL is served from cache", func(t *testing.T) {
+		cache := analytics.NewDashboardCache(time.Minute)
+		loads = 0
+		key := analytics.NewDashboardCacheKey(july, 1, nil, 10)
+
+		first, err := cache.Get(key, load)
+		require.NoError(t, err)
+		second, err := cache.Get(key, load)
+		require.NoError(t, err)
+
+		assert.Equal(t, 1, loads)
+		assert.Same(t, first, second)
+	})
+
+	t.Run("different timeframe, website, filters or limit misses", func(t *testing.T) {
+		cache := analytics.NewDashboardCache(time.Minute)
+		loads = 0
+
+		keys := []analytics.DashboardCacheKey{
+			analytics.NewDashboardCacheKey(july, 1, nil, 10),
+			analytics.NewDashboardCacheKey(august, 1, nil, 10),
+			analytics.NewDashboardCacheKey(july, 2, nil, 10),
+			analytics.NewDashboardCacheKey(july, 1, map[string]string{"country": "US"}, 10),
+			analytics.NewDashboardCacheKey(july, 1, nil, 25),
+		}
+		for _, key := range keys {
+			_, err := cache.Get(key, load)
+			require.NoError(t, err)
+		}
+		assert.Equal(t, len(keys), loads)
+	})
+
+	t.Run("filter order and empty filters don't change the key", func(t *testing.T) {
+		a := analytics.NewDashboardCacheKey(july, 1, map[string]string{"country": "US", "device": "mobile", "browser": ""}, 10)
+		b := analytics.NewDashboardCacheKey(july, 1, map[string]string{"device": "mobile", "country": "US"}, 10)
+		assert.Equal(t, a, b)
+	})
+
+	t.Run("entries expire after the TTL", func(t *testing.T) {
+		cache := analytics.NewDashboardCache(20 * time.Millisecond)
+		loads = 0
+		key := analytics.NewDashboardCacheKey(july, 1, nil, 10)
+
+		_, _ = cache.Get(key, load)
+		time.Sleep(30 * time.Millisecond)
+		_, _ = cache.Get(key, load)
+		assert.Equal(t, 2, loads)
+	})
+
+	t.Run("invalidation drops only that website", func(t *testing.T) {
+		cache := analytics.NewDashboardCache(time.Minute)
+		loads = 0
+		site1 := analytics.NewDashboardCacheKey(july, 1, nil, 10)
+		site2 := analytics.NewDashboardCacheKey(july, 2, nil, 10)
+
+		_, _ = cache.Get(site1, load)
+		_, _ = cache.Get(site2, load)
+		cache.Invalidate(1)
+		_, _ = cache.Get(site1, load)
+		_, _ = cache.Get(site2, load)
+		assert.Equal(t, 3, loads)
+	})
+
+	t.Run("errors are not cached", func(t *testing.T) {
+		cache := analytics.NewDashboardCache(time.Minute)
+		key := analytics.NewDashboardCacheKey(july, 1, nil, 10)
+
+		_, err := cache.Get(key, func() (*analytics.DashboardMetrics, error) { return nil, errors.New("database is locked") })
+		require.Error(t, err)
+
+		loads = 0
+		_, err = cache.Get(key, load)
+		require.NoError(t, err)
+		assert.Equal(t, 1, loads)
+	})
+
+	t.Run("entry version is stable until the website is invalidated", func(t *testing.T) {
+		cache := analytics.NewDashboardCache(time.Minute)
+		loads = 0
+		key := analytics.NewDashboardCacheKey(july, 1, nil, 10)
+
+		first, err := cache.GetEntry(key, load)
+		require.NoError(t, err)
+		second, err := cache.GetEntry(key, load)
+		require.NoError(t, err)
+		require.NotEmpty(t, first.Version())
+		assert.Equal(t, first.Version(), second.Version())
+
+		time.Sleep(time.Millisecond)
+		cache.Invalidate(1)
+		third, err := cache.GetEntry(key, load)
+		require.NoError(t, err)
+		assert.NotEqual(t, first.Version(), third.Version())
+		assert.Equal(t, 2, loads)
+	})
+
+	t.Run("panel options are part of the key", func(t *testing.T) {
+		cache := analytics.NewDashboardCache(time.Minute)
+		loads = 0
+		withProperty := analytics.NewDashboardCacheKey(july, 1, nil, 10)
+		withProperty.Panels = "plan"
+
+		_, _ = cache.Get(analytics.NewDashboardCacheKey(july, 1, nil, 10), load)
+		_, _ = cache.Get(withProperty, load)
+		_, _ = cache.Get(withProperty, load)
+		assert.Equal(t, 2, loads)
+	})
+
+	t.Run("zero TTL disables caching", func(t *testing.T) {
+		cache := analytics.NewDashboardCache(0)
+		loads = 0
+		key := analytics.NewDashboardCacheKey(july, 1, nil, 10)
+
+		_, _ = cache.Get(key, load)
+		entry, _ := cache.GetEntry(key, load)
+		assert.Equal(t, 2, loads)
+		assert.Empty(t, entry.Version(), "uncached metrics have no version")
+	})
+}
@@ -0,0 +1,87 @@
+package analytics
+
+import (
+	"log/slog"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+
+	"fusionaly/internal/timeframe"
+)
+
+// DashboardPanelOptions selects the admin dashboard panels loaded on top of
+// FetchDashboardMetrics, as picked in the dashboard's query string.
+type DashboardPanelOptions struct {
+	TrackParams []string // query parameters broken down besides ref
+	PropertyKey string   // custom property whose values are listed, "" for none
+	MetricEvent string   // custom event whose numeric metadata is listed and aggregated
+	MetricKey   string   // metadata key aggregated with MetricOp, "" for none
+	MetricOp    AggOp
+	Subdomains  bool // break page views down per subdomain
+}
+
+// cacheKey encodes the options for DashboardCacheKey.Panels
+func (o DashboardPanelOptions) cacheKey() string {
+	return strings.Join([]string{
+		strings.Join(o.TrackParams, ","),
+		o.PropertyKey,
+		o.MetricEvent,
+		o.MetricKey,
+		string(o.MetricOp),
+		strconv.FormatBool(o.Subdomains),
+	}, "\x00")
+}
+
+// FetchAdminDashboardMetrics is FetchDashboardMetrics plus the admin dashboard
+// panels selected by opts. A failing panel is logged and left empty rather
+// than failing the dashboard.
+func FetchAdminDashboardMetrics(db *gorm.DB, tf *timeframe.TimeFrame, websiteId int, filters map[string]string, limit int, opts DashboardPanelOptions, logger *slog.Logger) (*DashboardMetrics, error) {
+	metrics, err := FetchDashboardMetrics(db, tf, websiteId, filters, limit, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	params := NewWebsiteScopedQueryParams(tf, websiteId)
+	params.Filters = metrics.Filters
+	params.Limit = limit
+
+	metrics.TopQueryParams, err = GetTopQueryParamBreakdowns(db, params, opts.TrackParams)
+	if err != nil {
+		logger.Error("Failed to fetch query parameter breakdowns", slog.Any("error", err))
+		metrics.TopQueryParams = QueryParamBreakdowns{}
+	}
+
+	metrics.PropertyKeys, err = GetPropertyKeysInTimeFrame(db, params)
+	if err != nil {
+		logger.Error("Failed to fetch property keys", slog.Any("error", err))
+		metrics.PropertyKeys = []string{}
+	}
+	metrics.TopPropertyValues, err = GetTopPropertyValuesInTimeFrame(db, params, opts.PropertyKey)
+	if err != nil {
+		logger.Error("Failed to fetch property values", slog.String("property", opts.PropertyKey), slog.Any("error", err))
+		metrics.TopPropertyValues = []MetricCountResult{}
+	}
+
+	metrics.MetricKeys, err = GetEventNumericMetaKeysInTimeFrame(db, params, opts.MetricEvent)
+	if err != nil {
+		logger.Error("Failed to fetch numeric metadata keys", slog.String("event", opts.MetricEvent), slog.Any("error", err))
+		metrics.MetricKeys = []string{}
+	}
+	if opts.MetricEvent != "" && opts.MetricKey != "" {
+		metrics.MetricAggregation, err = GetEventNumericAggregation(db, params, opts.MetricEvent, opts.MetricKey, opts.MetricOp)
+		if err != nil {
+			logger.Error("Failed to aggregate event metadata", slog.String("event", opts.MetricEvent), slog.String("key", opts.MetricKey), slog.Any("error", err))
+		}
+	}
+
+	if opts.Subdomains {
+		metrics.TopSubdomains, err = GetSubdomainBreakdownInTimeFrame(db, params)
+		if err != nil {
+			logger.Error("Failed to fetch subdomain breakdown", slog.Any("error", err))
+			metrics.TopSubdomains = []MetricCountResult{}
+		}
+	}
+
+	return metrics, nil
+}
@@ -0,0 +1,93 @@
+package analytics
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+
+	"fusionaly/internal/events"
+)
+
+// EntryExitPair counts the sessions that landed on Entry and left from Exit
+type EntryExitPair struct {
+	Entry string `json:"entry"`
+	Exit  string `json:"exit"`
+	Count int64  `json:"count"`
+}
+
+// GetEntryExitPairsInTimeFrame returns the most common entry→exit page pairs,
+// pairing the first and last page view of each session in events. Sessions
+// are split at gaps longer than the website's session timeout. A session with
+// a single page view pairs that page with itself.
+func GetEntryExitPairsInTimeFrame(db *gorm.DB, params WebsiteScopedQueryParams) ([]EntryExitPair, error) {
+	results := []EntryExitPair{}
+
+	query := `
+	WITH page_views AS (
+		SELECT
+			id,
+			user_signature,
+			hostname || pathname AS page,
+			timestamp,
+			LAG(timestamp) OVER (
+				PARTITION BY user_signature
+				ORDER BY timestamp, id
+			) AS prev_view_time
+		FROM events
+		WHERE timestamp BETWEEN ? AND ?
+		AND website_id = ?
+		AND event_type = ?
+	),
+	sessions AS (
+		SELECT
+			id,
+			user_signature,
+			page,
+			timestamp,
+			SUM(
+				CASE
+					WHEN prev_view_time IS NULL OR
+						CAST((JULIANDAY(timestamp) - JULIANDAY(prev_view_time)) * 86400 AS INTEGER) > ?
+					THEN 1
+					ELSE 0
+				END
+			) OVER (
+				PARTITION BY user_signature
+				ORDER BY timestamp, id
+			) AS session_id
+		FROM page_views
+	),
+	session_bounds AS (
+		SELECT DISTINCT
+			user_signature,
+			session_id,
+			FIRST_VALUE(page) OVER session_pages AS entry_page,
+			LAST_VALUE(page) OVER session_pages AS exit_page
+		FROM sessions
+		WINDOW session_pages AS (
+			PARTITION BY user_signature, session_id
+			ORDER BY timestamp, id
+			ROWS BETWEEN UNBOUNDED PRECEDING AND UNBOUNDED FOLLOWING
+		)
+	)
+	SELECT
+		entry_page AS entry,
+		exit_page AS exit,
+		COUNT(*) AS count
+	FROM session_bounds
+	GROUP BY entry_page, exit_page
+	ORDER BY count DESC, entry, exit
+	LIMIT ? OFFSET ?
+	`
+
+	err := db.Raw(query,
+		params.TimeFrame.From.UTC(), params.TimeFrame.To.UTC(), params.WebsiteID, events.EventTypePageView,
+		websiteSessionTimeoutSeconds(db, params.WebsiteID),
+		params.Limit, params.Offset,
+	).Scan(&results).Error
+	if err != nil {
+		return nil, fmt.Errorf("error fetching entry/exit pairs: %w", err)
+	}
+
+	return results, nil
+}
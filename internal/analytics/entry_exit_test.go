@@ -0,0 +1,89 @@
+package analytics_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"fusionaly/internal/analytics"
+	"fusionaly/internal/events"
+	"fusionaly/internal/testsupport"
+	"fusionaly/internal/timeframe"
+)
+
+func TestGetEntryExitPairsInTimeFrame(t *testing.T) {
+	dbManager, _ := testsupport.SetupTestDBManager(t)
+	db := dbManager.GetConnection()
+	testsupport.CleanAllTables(db)
+
+	website := testsupport.CreateTestWebsite(db, "pairs.com")
+	other := testsupport.CreateTestWebsite(db, "other.com")
+	day := time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC)
+
+	pageView := func(websiteID uint, visitor, path string, at time.Time) events.Event {
+		return events.Event{WebsiteID: websiteID, UserSignature: visitor, Hostname: "pairs.com", Pathname: path,
+			EventType: events.EventTypePageView, Timestamp: at, CreatedAt: at}
+	}
+	at := func(hour, minute int) time.Time {
+		return day.Add(time.Duration(hour)*time.Hour + time.Duration(minute)*time.Minute)
+	}
+	rows := []events.Event{
+		// v1: a three-page session, then a single-page session after a long gap
+		pageView(website.ID, "v1", "/", at(9, 0)),
+		pageView(website.ID, "v1", "/pricing", at(9, 2)),
+		pageView(website.ID, "v1", "/signup", at(9, 5)),
+		pageView(website.ID, "v1", "/blog", at(14, 0)),
+		// v2 and v3 take the same path through a different middle page
+		pageView(website.ID, "v2", "/", at(10, 0)),
+		pageView(website.ID, "v2", "/features", at(10, 10)),
+		pageView(website.ID, "v2", "/signup", at(10, 20)),
+		pageView(website.ID, "v3", "/", at(11, 0)),
+		pageView(website.ID, "v3", "/signup", at(11, 1)),
+		// v4 bounces, v5 comes back to where they landed
+		pageView(website.ID, "v4", "/blog", at(12, 0)),
+		pageView(website.ID, "v5", "/docs", at(13, 0)),
+		pageView(website.ID, "v5", "/docs/install", at(13, 5)),
+		pageView(website.ID, "v5", "/docs", at(13, 9)),
+		// Custom events don't move entry or exit
+		{WebsiteID: website.ID, UserSignature: "v4", Hostname: "pairs.com", Pathname: "/blog",
+			EventType: events.EventTypeCustomEvent, CustomEventName: "share", Timestamp: at(12, 1), CreatedAt: at(12, 1)},
+		// Outside the time frame, and another website
+		pageView(website.ID, "v6", "/", day.AddDate(0, 0, 3)),
+		pageView(other.ID, "v7", "/", at(9, 0)),
+	}
+	require.NoError(t, db.Create(&rows).Error)
+
+	tf, err := timeframe.NewTimeFrame(timeframe.TimeFrameParams{
+		FromTime:      day,
+		ToTime:        day.Add(24*time.Hour - time.Second),
+		TimeFrameSize: timeframe.DailyTimeFrame,
+	}, time.UTC)
+	require.NoError(t, err)
+	params := analytics.NewWebsiteScopedQueryParams(tf, int(website.ID))
+
+	pairs, err := analytics.GetEntryExitPairsInTimeFrame(db, params)
+	require.NoError(t, err)
+	assert.Equal(t, []analytics.EntryExitPair{
+		{Entry: "pairs.com/", Exit: "pairs.com/signup", Count: 3},
+		{Entry: "pairs.com/blog", Exit: "pairs.com/blog", Count: 2},
+		{Entry: "pairs.com/docs", Exit: "pairs.com/docs", Count: 1},
+	}, pairs)
+
+	t.Run("paging", func(t *testing.T) {
+		paged := params
+		paged.Limit, paged.Offset = 1, 1
+		pairs, err := analytics.GetEntryExitPairsInTimeFrame(db, paged)
+		require.NoError(t, err)
+		assert.Equal(t, []analytics.EntryExitPair{{Entry: "pairs.com/blog", Exit: "pairs.com/blog", Count: 2}}, pairs)
+	})
+
+	t.Run("website without page views", func(t *testing.T) {
+		empty := params
+		empty.WebsiteID = int(other.ID) + 1
+		pairs, err := analytics.GetEntryExitPairsInTimeFrame(db, empty)
+		require.NoError(t, err)
+		assert.Empty(t, pairs)
+	})
+}
@@ -0,0 +1,86 @@
+package analytics
+
+import (
+	"encoding/csv"
+	"io"
+)
+
+// exportTable is one top-N table in a dashboard CSV export
+type exportTable struct {
+	title string
+	rows  []MetricCountResult
+}
+
+// WriteDashboardCSV writes the dashboard time series followed by each top-N
+// table, separated by blank lines. Sections are flushed as they are written so
+// the export can be streamed. Revenue is reported in cents for the raw locale.
+func WriteDashboardCSV(w io.Writer, metrics *DashboardMetrics, locale NumberLocale) error {
+	writer := csv.NewWriter(w)
+
+	if err := writer.Write([]string{"Date", "Page Views", "Visitors", "Sessions", "Revenue"}); err != nil {
+		return err
+	}
+	for i, point := range metrics.PageViews {
+		if err := writer.Write([]string{
+			point.Date,
+			locale.FormatNumber(float64(point.Count), 0),
+			locale.FormatNumber(float64(seriesCount(metrics.Visitors, i)), 0),
+			locale.FormatNumber(float64(seriesCount(metrics.Sessions, i)), 0),
+			locale.FormatRevenue(int64(seriesCount(metrics.Revenue, i))),
+		}); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return err
+	}
+
+	tables := []exportTable{
+		{"Top Pages", metrics.TopURLs},
+		{"Entry Pages", metrics.TopEntryPages},
+		{"Exit Pages", metrics.TopExitPages},
+		{"Not Found Pages", metrics.Top404s},
+		{"Referrers", metrics.TopReferrers},
+		{"Countries", metrics.TopCountries},
+		{"Languages", metrics.TopLanguages},
+		{"Devices", metrics.TopDevices},
+		{"Device Models", metrics.TopDeviceModels},
+		{"Screen Sizes", metrics.TopScreenSizes},
+		{"Browsers", metrics.TopBrowsers},
+		{"Browser Versions", metrics.TopBrowserVersions},
+		{"Operating Systems", metrics.TopOperatingSystems},
+		{"UTM Sources", metrics.TopUTMSources},
+		{"UTM Mediums", metrics.TopUTMMediums},
+		{"UTM Campaigns", metrics.TopUTMCampaigns},
+		{"Custom Events", metrics.TopCustomEvents},
+		{"Downloads", metrics.TopDownloads},
+	}
+	for _, table := range tables {
+		if err := writer.Write(nil); err != nil {
+			return err
+		}
+		if err := writer.Write([]string{table.title, "Count"}); err != nil {
+			return err
+		}
+		for _, row := range table.rows {
+			if err := writer.Write([]string{row.Name, locale.FormatNumber(float64(row.Count), 0)}); err != nil {
+				return err
+			}
+		}
+		writer.Flush()
+		if err := writer.Error(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// seriesCount returns the count at index i, or 0 when the series is shorter
+func seriesCount(series []TimeSeriesPoint, i int) int {
+	if i < len(series) {
+		return series[i].Count
+	}
+	return 0
+}
@@ -2,12 +2,21 @@ package analytics
 
 import (
 	"fmt"
+	"strings"
 
 	"fusionaly/internal/events"
 
 	"gorm.io/gorm"
 )
 
+// DefaultFlowDepth is the deepest step flow transitions are aggregated for
+const DefaultFlowDepth = 5
+
+// ClampFlowDepth brings depth within 1 and DefaultFlowDepth
+func ClampFlowDepth(depth int) int {
+	return min(max(depth, 1), DefaultFlowDepth)
+}
+
 // UserFlowLink represents a connection between two pages in the user flow
 type UserFlowLink struct {
 	Source string `json:"source"`
@@ -21,7 +30,9 @@ type UserFlowLink struct {
 // - Middle columns: Pages visited during navigation
 // - Transitions show how visitors move between pages
 // Pages are prefixed with their step number to create proper flow columns
-func GetUserFlowData(db *gorm.DB, params WebsiteScopedQueryParams, maxDepth int) ([]UserFlowLink, error) {
+// When startPath is set, only flows entering on that pathname are returned:
+// the step 1 links leaving it and the later links reachable from them.
+func GetUserFlowData(db *gorm.DB, params WebsiteScopedQueryParams, maxDepth int, startPath string) ([]UserFlowLink, error) {
 	if maxDepth <= 0 {
 		maxDepth = 5 // Default to showing 5 levels of depth
 	}
@@ -59,12 +70,65 @@ func GetUserFlowData(db *gorm.DB, params WebsiteScopedQueryParams, maxDepth int)
 
 	// If no pre-aggregated data, fall back to raw events query
 	if len(results) == 0 {
-		return GetUserFlowDataFromEvents(db, params, maxDepth)
+		results, err = GetUserFlowDataFromEvents(db, params, maxDepth)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if startPath != "" {
+		results = filterUserFlowFromStart(results, startPath)
 	}
 
 	return results, nil
 }
 
+// filterUserFlowFromStart keeps the links of flows whose entry page has the
+// given pathname, preserving the original ordering.
+func filterUserFlowFromStart(links []UserFlowLink, startPath string) []UserFlowLink {
+	reachable := make(map[string]bool)
+	kept := make([]bool, len(links))
+
+	for changed := true; changed; {
+		changed = false
+		for i, link := range links {
+			if kept[i] {
+				continue
+			}
+			step, page := splitFlowNode(link.Source)
+			isStart := step == "step1" && flowNodePath(page) == startPath
+			if !isStart && !reachable[link.Source] {
+				continue
+			}
+			kept[i] = true
+			reachable[link.Target] = true
+			changed = true
+		}
+	}
+
+	filtered := make([]UserFlowLink, 0, len(links))
+	for i, link := range links {
+		if kept[i] {
+			filtered = append(filtered, link)
+		}
+	}
+	return filtered
+}
+
+// splitFlowNode splits a "stepN:hostname/path" node into its step and page
+func splitFlowNode(node string) (string, string) {
+	step, page, _ := strings.Cut(node, ":")
+	return step, page
+}
+
+// flowNodePath returns the pathname of a "hostname/path" flow page
+func flowNodePath(page string) string {
+	if i := strings.Index(page, "/"); i >= 0 {
+		return page[i:]
+	}
+	return "/"
+}
+
 // GetUserFlowDataFromEvents calculates page-to-page transitions directly from events table
 // This is used as a fallback when pre-aggregated data is not available
 func GetUserFlowDataFromEvents(db *gorm.DB, params WebsiteScopedQueryParams, maxDepth int) ([]UserFlowLink, error) {
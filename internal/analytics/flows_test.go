@@ -58,7 +58,7 @@ func TestGetUserFlowDataFromAggregates(t *testing.T) {
 		},
 	}
 
-	results, err := analytics.GetUserFlowData(db, params, 5)
+	results, err := analytics.GetUserFlowData(db, params, 5, "")
 	require.NoError(t, err)
 	assert.Len(t, results, 3)
 
@@ -106,7 +106,7 @@ func TestGetUserFlowDataFallbackToEvents(t *testing.T) {
 		},
 	}
 
-	results, err := analytics.GetUserFlowData(db, params, 5)
+	results, err := analytics.GetUserFlowData(db, params, 5, "")
 	require.NoError(t, err)
 	// Should have one transition from /home to /products
 	assert.Len(t, results, 1)
@@ -114,6 +114,65 @@ func TestGetUserFlowDataFallbackToEvents(t *testing.T) {
 	assert.Equal(t, "step2:example.com/products", results[0].Target)
 }
 
+func TestGetUserFlowDataDepthAndStart(t *testing.T) {
+	dbManager, _ := testsupport.SetupTestDBManager(t)
+	db := dbManager.GetConnection()
+	testsupport.CleanAllTables(db)
+
+	now := time.Now().UTC().Truncate(time.Hour)
+	flowStats := []analytics.FlowTransitionStat{
+		{WebsiteID: 1, StepPosition: 1, SourcePage: "example.com/pricing", TargetPage: "example.com/signup", Transitions: 8, Hour: now},
+		{WebsiteID: 1, StepPosition: 1, SourcePage: "example.com/home", TargetPage: "example.com/blog", Transitions: 6, Hour: now},
+		{WebsiteID: 1, StepPosition: 2, SourcePage: "example.com/signup", TargetPage: "example.com/welcome", Transitions: 5, Hour: now},
+		{WebsiteID: 1, StepPosition: 2, SourcePage: "example.com/blog", TargetPage: "example.com/post", Transitions: 4, Hour: now},
+		{WebsiteID: 1, StepPosition: 3, SourcePage: "example.com/welcome", TargetPage: "example.com/dashboard", Transitions: 3, Hour: now},
+	}
+	require.NoError(t, db.CreateInBatches(flowStats, len(flowStats)).Error)
+
+	params := analytics.WebsiteScopedQueryParams{
+		WebsiteID: 1,
+		TimeFrame: &timeframe.TimeFrame{
+			From: now.Add(-time.Hour),
+			To:   now.Add(time.Hour),
+		},
+	}
+
+	t.Run("bounds the depth", func(t *testing.T) {
+		results, err := analytics.GetUserFlowData(db, params, 2, "")
+		require.NoError(t, err)
+		assert.Equal(t, []analytics.UserFlowLink{
+			{Source: "step1:example.com/pricing", Target: "step2:example.com/signup", Value: 8},
+			{Source: "step1:example.com/home", Target: "step2:example.com/blog", Value: 6},
+			{Source: "step2:example.com/signup", Target: "step3:example.com/welcome", Value: 5},
+			{Source: "step2:example.com/blog", Target: "step3:example.com/post", Value: 4},
+		}, results)
+	})
+
+	t.Run("keeps only flows entering on the start page", func(t *testing.T) {
+		results, err := analytics.GetUserFlowData(db, params, 5, "/pricing")
+		require.NoError(t, err)
+		assert.Equal(t, []analytics.UserFlowLink{
+			{Source: "step1:example.com/pricing", Target: "step2:example.com/signup", Value: 8},
+			{Source: "step2:example.com/signup", Target: "step3:example.com/welcome", Value: 5},
+			{Source: "step3:example.com/welcome", Target: "step4:example.com/dashboard", Value: 3},
+		}, results)
+	})
+
+	t.Run("combines start page and depth", func(t *testing.T) {
+		results, err := analytics.GetUserFlowData(db, params, 1, "/pricing")
+		require.NoError(t, err)
+		assert.Equal(t, []analytics.UserFlowLink{
+			{Source: "step1:example.com/pricing", Target: "step2:example.com/signup", Value: 8},
+		}, results)
+	})
+
+	t.Run("returns nothing for an unknown start page", func(t *testing.T) {
+		results, err := analytics.GetUserFlowData(db, params, 5, "/missing")
+		require.NoError(t, err)
+		assert.Empty(t, results)
+	})
+}
+
 func TestComputeFlowTransitionsForHour(t *testing.T) {
 	dbManager, _ := testsupport.SetupTestDBManager(t)
 	db := dbManager.GetConnection()
@@ -35,6 +35,48 @@ func FormatCountryStats(items []MetricCountResult) []MetricCountResult {
 	return result
 }
 
+// FormatLanguageStats replaces the unknown language constant with a readable label.
+func FormatLanguageStats(items []MetricCountResult) []MetricCountResult {
+	if len(items) == 0 {
+		return []MetricCountResult{}
+	}
+
+	result := make([]MetricCountResult, len(items))
+	for i, item := range items {
+		name := item.Name
+		if name == events.UnknownLanguage {
+			name = "Unknown"
+		}
+		result[i] = MetricCountResult{Name: name, Count: item.Count}
+	}
+	return result
+}
+
+// screenSizeLabels describes each viewport bucket with its width range
+var screenSizeLabels = map[string]string{
+	events.ScreenSizeMobile:  "Mobile (<768px)",
+	events.ScreenSizeTablet:  "Tablet (768-1023px)",
+	events.ScreenSizeLaptop:  "Laptop (1024-1439px)",
+	events.ScreenSizeDesktop: "Desktop (1440px+)",
+}
+
+// FormatScreenSizeStats labels viewport buckets with their width ranges.
+func FormatScreenSizeStats(items []MetricCountResult) []MetricCountResult {
+	if len(items) == 0 {
+		return []MetricCountResult{}
+	}
+
+	result := make([]MetricCountResult, len(items))
+	for i, item := range items {
+		name := item.Name
+		if label, ok := screenSizeLabels[name]; ok {
+			name = label
+		}
+		result[i] = MetricCountResult{Name: name, Count: item.Count}
+	}
+	return result
+}
+
 // FormatDeviceStats title-cases device type names.
 func FormatDeviceStats(items []MetricCountResult) []MetricCountResult {
 	caser := cases.Title(language.AmericanEnglish)
@@ -54,6 +96,23 @@ func FormatDeviceStats(items []MetricCountResult) []MetricCountResult {
 	return result
 }
 
+// FormatDeviceModelStats replaces the unknown device model constant with a readable label.
+func FormatDeviceModelStats(items []MetricCountResult) []MetricCountResult {
+	if len(items) == 0 {
+		return []MetricCountResult{}
+	}
+
+	result := make([]MetricCountResult, len(items))
+	for i, item := range items {
+		name := item.Name
+		if name == events.UnknownDeviceModel {
+			name = "Unknown"
+		}
+		result[i] = MetricCountResult{Name: name, Count: item.Count}
+	}
+	return result
+}
+
 // FormatReferrerStats converts internal referrer constants to human-readable names.
 func FormatReferrerStats(items []MetricCountResult) []MetricCountResult {
 	if len(items) == 0 {
@@ -63,8 +122,11 @@ func FormatReferrerStats(items []MetricCountResult) []MetricCountResult {
 	result := make([]MetricCountResult, len(items))
 	for i, item := range items {
 		name := item.Name
-		if name == events.DirectOrUnknownReferrer {
+		switch name {
+		case events.DirectOrUnknownReferrer:
 			name = "Direct / Unknown"
+		case events.SelfReferral:
+			name = SelfReferralName
 		}
 		result[i] = MetricCountResult{Name: name, Count: item.Count}
 	}
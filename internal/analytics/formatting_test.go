@@ -25,6 +25,15 @@ func TestFormatReferrerStats(t *testing.T) {
 				{Name: "tinylaun.ch", Count: 1},
 			},
 		},
+		{
+			name: "Convert self-referral",
+			input: []MetricCountResult{
+				{Name: events.SelfReferral, Count: 4},
+			},
+			expected: []MetricCountResult{
+				{Name: "Self-referral", Count: 4},
+			},
+		},
 		{
 			name:     "Empty input",
 			input:    []MetricCountResult{},
@@ -0,0 +1,205 @@
+package analytics
+
+import (
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+
+	"fusionaly/internal/events"
+)
+
+// FunnelStepType is what a funnel step matches against
+type FunnelStepType string
+
+const (
+	FunnelStepPage  FunnelStepType = "page"
+	FunnelStepEvent FunnelStepType = "event"
+)
+
+// MaxFunnelSteps caps how many steps a funnel can have
+const MaxFunnelSteps = 10
+
+// FunnelStep is a page view of a pathname or a custom event by name
+type FunnelStep struct {
+	Type  FunnelStepType `json:"type"`
+	Value string         `json:"value"`
+}
+
+// FunnelStepResult holds how many visitors reached a funnel step
+type FunnelStepResult struct {
+	Step               FunnelStep `json:"step"`
+	Visitors           int64      `json:"visitors"`
+	ConversionRate     float64    `json:"conversion_rate"`      // % of visitors who entered the funnel
+	StepConversionRate float64    `json:"step_conversion_rate"` // % of visitors who reached the previous step
+	DropOff            int64      `json:"drop_off"`             // visitors lost since the previous step
+}
+
+// ParseFunnelStep parses "page:/pricing" or "event:signup". Without a prefix,
+// values starting with "/" are pages and anything else is a custom event.
+func ParseFunnelStep(raw string) (FunnelStep, error) {
+	raw = strings.TrimSpace(raw)
+	step := FunnelStep{Value: raw}
+	switch {
+	case strings.HasPrefix(raw, "page:"):
+		step = FunnelStep{Type: FunnelStepPage, Value: strings.TrimSpace(strings.TrimPrefix(raw, "page:"))}
+	case strings.HasPrefix(raw, "event:"):
+		step = FunnelStep{Type: FunnelStepEvent, Value: strings.TrimSpace(strings.TrimPrefix(raw, "event:"))}
+	case strings.HasPrefix(raw, "/"):
+		step.Type = FunnelStepPage
+	default:
+		step.Type = FunnelStepEvent
+	}
+
+	if step.Value == "" {
+		return step, fmt.Errorf("funnel step %q is empty", raw)
+	}
+	if step.Type == FunnelStepPage && !strings.HasPrefix(step.Value, "/") {
+		step.Value = "/" + step.Value
+	}
+	return step, nil
+}
+
+func (s FunnelStep) matches(eventType events.EventType, pathname, customEventName string) bool {
+	switch s.Type {
+	case FunnelStepPage:
+		return eventType == events.EventTypePageView && pathname == s.Value
+	case FunnelStepEvent:
+		return eventType == events.EventTypeCustomEvent && customEventName == s.Value
+	}
+	return false
+}
+
+// GetFunnel counts the distinct visitors who completed each step in order
+// within a single session. Sessions are split on the website's session
+// timeout, and a visitor counts for step N once any of their sessions matched
+// steps 1..N in timestamp order (other events may happen in between).
+func GetFunnel(db *gorm.DB, params WebsiteScopedQueryParams, steps []FunnelStep) ([]FunnelStepResult, error) {
+	if len(steps) == 0 {
+		return nil, fmt.Errorf("funnel needs at least one step")
+	}
+	if len(steps) > MaxFunnelSteps {
+		return nil, fmt.Errorf("funnel can have at most %d steps", MaxFunnelSteps)
+	}
+
+	pathnames := []string{}
+	eventNames := []string{}
+	for _, step := range steps {
+		if step.Type == FunnelStepPage {
+			pathnames = append(pathnames, step.Value)
+		} else {
+			eventNames = append(eventNames, step.Value)
+		}
+	}
+
+	sessionTimeoutSeconds := websiteSessionTimeoutSeconds(db, params.WebsiteID)
+
+	var rows []struct {
+		UserSignature   string
+		SessionID       int64
+		EventType       events.EventType
+		Pathname        string
+		CustomEventName string
+	}
+
+	// Sessions are split over all of a visitor's events, then narrowed down to
+	// the ones matching a step.
+	query := `
+    WITH ranked_events AS (
+        SELECT
+            id,
+            user_signature,
+            event_type,
+            pathname,
+            custom_event_name,
+            timestamp,
+            LAG(timestamp) OVER (
+                PARTITION BY user_signature
+                ORDER BY timestamp, id
+            ) as prev_event_time
+        FROM events
+        WHERE timestamp BETWEEN ? AND ?
+        AND website_id = ?
+    ),
+    session_breaks AS (
+        SELECT
+            *,
+            CASE
+                WHEN prev_event_time IS NULL OR
+                     CAST((JULIANDAY(timestamp) - JULIANDAY(prev_event_time)) * 86400 as INTEGER) > ?
+                THEN 1
+                ELSE 0
+            END as is_new_session
+        FROM ranked_events
+    ),
+    sessions AS (
+        SELECT
+            *,
+            SUM(is_new_session) OVER (
+                PARTITION BY user_signature
+                ORDER BY timestamp, id
+            ) as session_id
+        FROM session_breaks
+    )
+    SELECT user_signature, session_id, event_type, pathname, custom_event_name
+    FROM sessions
+    WHERE (event_type = ? AND pathname IN ?)
+    OR (event_type = ? AND custom_event_name IN ?)
+    ORDER BY user_signature, session_id, timestamp, id
+    `
+
+	err := db.Raw(query,
+		params.TimeFrame.From.UTC(), params.TimeFrame.To.UTC(), params.WebsiteID,
+		sessionTimeoutSeconds,
+		events.EventTypePageView, pathnames,
+		events.EventTypeCustomEvent, eventNames,
+	).Scan(&rows).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to query funnel events: %w", err)
+	}
+
+	// Furthest step (1-based) each visitor reached in any session
+	reached := make(map[string]int)
+	var currentUser string
+	var currentSession int64
+	progress := 0
+	for i, row := range rows {
+		if i == 0 || row.UserSignature != currentUser || row.SessionID != currentSession {
+			currentUser, currentSession, progress = row.UserSignature, row.SessionID, 0
+		}
+		if progress < len(steps) && steps[progress].matches(row.EventType, row.Pathname, row.CustomEventName) {
+			progress++
+			if progress > reached[row.UserSignature] {
+				reached[row.UserSignature] = progress
+			}
+		}
+	}
+
+	counts := make([]int64, len(steps))
+	for _, furthest := range reached {
+		for i := 0; i < furthest; i++ {
+			counts[i]++
+		}
+	}
+
+	results := make([]FunnelStepResult, len(steps))
+	for i, step := range steps {
+		result := FunnelStepResult{Step: step, Visitors: counts[i]}
+		if counts[0] > 0 {
+			result.ConversionRate = float64(counts[i]) / float64(counts[0]) * 100
+		}
+		if i == 0 {
+			if counts[0] > 0 {
+				result.StepConversionRate = 100
+			}
+		} else {
+			result.DropOff = counts[i-1] - counts[i]
+			if counts[i-1] > 0 {
+				result.StepConversionRate = float64(counts[i]) / float64(counts[i-1]) * 100
+			}
+		}
+		results[i] = result
+	}
+
+	return results, nil
+}
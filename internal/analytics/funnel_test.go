@@ -0,0 +1,103 @@
+package analytics_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"fusionaly/internal/analytics"
+	"fusionaly/internal/events"
+	"fusionaly/internal/testsupport"
+	"fusionaly/internal/timeframe"
+)
+
+func TestGetFunnel(t *testing.T) {
+	dbManager, _ := testsupport.SetupTestDBManager(t)
+	db := dbManager.GetConnection()
+	website := testsupport.CreateTestWebsite(db, "funnel.com")
+
+	start := time.Date(2024, 7, 1, 10, 0, 0, 0, time.UTC)
+	pageView := func(visitor string, offset time.Duration, pathname string) events.Event {
+		return events.Event{WebsiteID: website.ID, UserSignature: visitor, Hostname: "funnel.com", Pathname: pathname, EventType: events.EventTypePageView, Timestamp: start.Add(offset)}
+	}
+	customEvent := func(visitor string, offset time.Duration, name string) events.Event {
+		return events.Event{WebsiteID: website.ID, UserSignature: visitor, Hostname: "funnel.com", Pathname: "/signup", EventType: events.EventTypeCustomEvent, CustomEventName: name, Timestamp: start.Add(offset)}
+	}
+
+	journeys := []events.Event{
+		// Completes the whole funnel, with an unrelated page in between
+		pageView("complete", 0, "/pricing"),
+		pageView("complete", time.Minute, "/blog"),
+		pageView("complete", 2*time.Minute, "/signup"),
+		customEvent("complete", 3*time.Minute, "account_created"),
+		// Drops off after signing up
+		pageView("signup-only", 0, "/pricing"),
+		pageView("signup-only", time.Minute, "/signup"),
+		// Visits the steps in the wrong order
+		pageView("out-of-order", 0, "/signup"),
+		pageView("out-of-order", time.Minute, "/pricing"),
+		// Signs up in a later session, so only the first step counts
+		pageView("two-sessions", 0, "/pricing"),
+		pageView("two-sessions", 3*time.Hour, "/signup"),
+		customEvent("two-sessions", 3*time.Hour+time.Minute, "account_created"),
+		// Never enters the funnel
+		customEvent("no-entry", 0, "account_created"),
+	}
+	require.NoError(t, db.Create(&journeys).Error)
+
+	tf, err := timeframe.NewTimeFrame(timeframe.TimeFrameParams{
+		FromTime:      time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC),
+		ToTime:        time.Date(2024, 7, 1, 23, 59, 59, 0, time.UTC),
+		TimeFrameSize: timeframe.DailyTimeFrame,
+	}, time.UTC)
+	require.NoError(t, err)
+	params := analytics.NewWebsiteScopedQueryParams(tf, int(website.ID))
+
+	steps := []analytics.FunnelStep{
+		{Type: analytics.FunnelStepPage, Value: "/pricing"},
+		{Type: analytics.FunnelStepPage, Value: "/signup"},
+		{Type: analytics.FunnelStepEvent, Value: "account_created"},
+	}
+
+	results, err := analytics.GetFunnel(db, params, steps)
+	require.NoError(t, err)
+	require.Len(t, results, 3)
+
+	assert.Equal(t, int64(4), results[0].Visitors)
+	assert.Equal(t, float64(100), results[0].ConversionRate)
+	assert.Equal(t, int64(0), results[0].DropOff)
+
+	assert.Equal(t, int64(2), results[1].Visitors)
+	assert.Equal(t, float64(50), results[1].ConversionRate)
+	assert.Equal(t, float64(50), results[1].StepConversionRate)
+	assert.Equal(t, int64(2), results[1].DropOff)
+
+	assert.Equal(t, int64(1), results[2].Visitors)
+	assert.Equal(t, float64(25), results[2].ConversionRate)
+	assert.Equal(t, float64(50), results[2].StepConversionRate)
+	assert.Equal(t, int64(1), results[2].DropOff)
+
+	t.Run("parses step strings", func(t *testing.T) {
+		step, err := analytics.ParseFunnelStep("/pricing")
+		require.NoError(t, err)
+		assert.Equal(t, analytics.FunnelStep{Type: analytics.FunnelStepPage, Value: "/pricing"}, step)
+
+		step, err = analytics.ParseFunnelStep("account_created")
+		require.NoError(t, err)
+		assert.Equal(t, analytics.FunnelStep{Type: analytics.FunnelStepEvent, Value: "account_created"}, step)
+
+		step, err = analytics.ParseFunnelStep("page:signup")
+		require.NoError(t, err)
+		assert.Equal(t, analytics.FunnelStep{Type: analytics.FunnelStepPage, Value: "/signup"}, step)
+
+		_, err = analytics.ParseFunnelStep("event:")
+		assert.Error(t, err)
+	})
+
+	t.Run("rejects empty funnels", func(t *testing.T) {
+		_, err := analytics.GetFunnel(db, params, nil)
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,53 @@
+package analytics
+
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+
+	"fusionaly/internal/timeframe"
+)
+
+// TrafficHeatmap holds visitor counts by weekday and hour of day in the
+// dashboard timezone. Rows run Monday (0) through Sunday (6), columns are
+// hours 0-23.
+type TrafficHeatmap [7][24]int64
+
+// GetTrafficHeatmap buckets hourly site_stats visitors into a weekday x hour
+// matrix. Stored hours are UTC, so each one is converted to the time frame's
+// timezone before bucketing. site_stats carries no dimensions, so it returns
+// nil when segment filters are active.
+func GetTrafficHeatmap(db *gorm.DB, params WebsiteScopedQueryParams) (*TrafficHeatmap, error) {
+	if len(params.Filters) > 0 {
+		return nil, nil
+	}
+
+	var rows []struct {
+		Hour     time.Time
+		Visitors int64
+	}
+	err := db.Raw(`
+        SELECT hour, visitors
+        FROM site_stats
+        WHERE hour >= ? AND hour <= ?
+        AND website_id = ?
+    `, params.TimeFrame.From.UTC(), params.TimeFrame.To.UTC(), params.WebsiteID).Scan(&rows).Error
+	if err != nil {
+		return nil, fmt.Errorf("error fetching traffic heatmap: %w", err)
+	}
+
+	tz := params.TimeFrame.Tz
+	if tz == nil {
+		tz = time.UTC
+	}
+
+	heatmap := &TrafficHeatmap{}
+	for _, row := range rows {
+		local := timeframe.TruncateToBucketInTimezone(row.Hour, timeframe.TimeFrameBucketSizeHour, tz)
+		weekday := (int(local.Weekday()) + 6) % 7
+		heatmap[weekday][local.Hour()] += row.Visitors
+	}
+
+	return heatmap, nil
+}
@@ -0,0 +1,60 @@
+package analytics_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"fusionaly/internal/analytics"
+	"fusionaly/internal/events"
+	"fusionaly/internal/testsupport"
+	"fusionaly/internal/timeframe"
+)
+
+func TestGetTrafficHeatmap(t *testing.T) {
+	dbManager, logger := testsupport.SetupTestDBManager(t)
+	db := dbManager.GetConnection()
+	testsupport.CleanAllTables(db)
+	website := testsupport.CreateTestWebsite(db, "heatmap.com")
+
+	// Monday 02:30 UTC is Sunday 22:30 in New York (EDT, UTC-4)
+	require.NoError(t, events.CollectEvent(dbManager, logger, &events.CollectEventInput{
+		IPAddress: "203.0.113.10",
+		UserAgent: "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36",
+		EventType: events.EventTypePageView,
+		Timestamp: time.Date(2024, 7, 1, 2, 30, 0, 0, time.UTC),
+		RawUrl:    "https://heatmap.com/",
+	}))
+	require.NoError(t, testsupport.ProcessAllTestEvents(dbManager, logger))
+
+	newYork, err := time.LoadLocation("America/New_York")
+	require.NoError(t, err)
+	tf, err := timeframe.NewTimeFrame(timeframe.TimeFrameParams{
+		FromTime:      time.Date(2024, 6, 24, 0, 0, 0, 0, newYork),
+		ToTime:        time.Date(2024, 7, 7, 23, 59, 59, 0, newYork),
+		TimeFrameSize: timeframe.DailyTimeFrame,
+	}, newYork)
+	require.NoError(t, err)
+	params := analytics.NewWebsiteScopedQueryParams(tf, int(website.ID))
+
+	heatmap, err := analytics.GetTrafficHeatmap(db, params)
+	require.NoError(t, err)
+	require.NotNil(t, heatmap)
+	assert.Equal(t, int64(1), heatmap[6][22], "Sunday 22:00 in the dashboard timezone")
+	assert.Equal(t, int64(0), heatmap[0][2], "not bucketed by the stored UTC hour")
+
+	var total int64
+	for _, day := range heatmap {
+		for _, count := range day {
+			total += count
+		}
+	}
+	assert.Equal(t, int64(1), total)
+
+	params.Filters = map[string]string{"country": "us"}
+	filtered, err := analytics.GetTrafficHeatmap(db, params)
+	require.NoError(t, err)
+	assert.Nil(t, filtered, "site_stats can't be segmented")
+}
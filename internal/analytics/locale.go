@@ -0,0 +1,97 @@
+package analytics
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+// NumberLocale describes how numbers are rendered for a spreadsheet locale.
+// The zero value is the raw machine format: "." decimals and no grouping.
+type NumberLocale struct {
+	Decimal   string
+	Thousands string
+}
+
+// RawNumberLocale is the default export format, safe for machine parsing.
+var RawNumberLocale = NumberLocale{Decimal: "."}
+
+var numberLocales = map[string]NumberLocale{
+	"en": {Decimal: ".", Thousands: ","},
+	"de": {Decimal: ",", Thousands: "."},
+	"es": {Decimal: ",", Thousands: "."},
+	"it": {Decimal: ",", Thousands: "."},
+	"nl": {Decimal: ",", Thousands: "."},
+	"pt": {Decimal: ",", Thousands: "."},
+	"fr": {Decimal: ",", Thousands: " "},
+	"sv": {Decimal: ",", Thousands: " "},
+	"pl": {Decimal: ",", Thousands: " "},
+	"ch": {Decimal: ".", Thousands: "'"},
+}
+
+// ParseNumberLocale resolves a locale tag such as "de-DE" or "fr" to its number
+// format. Empty or unknown tags fall back to RawNumberLocale.
+func ParseNumberLocale(tag string) NumberLocale {
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	if tag == "" {
+		return RawNumberLocale
+	}
+	tag = strings.ReplaceAll(tag, "_", "-")
+
+	// Swiss German/French use an apostrophe for grouping
+	if strings.HasSuffix(tag, "-ch") {
+		return numberLocales["ch"]
+	}
+
+	lang, _, _ := strings.Cut(tag, "-")
+	if loc, ok := numberLocales[lang]; ok {
+		return loc
+	}
+	return RawNumberLocale
+}
+
+// FormatNumber renders value with the given number of decimals using the
+// locale's decimal and thousands separators.
+func (l NumberLocale) FormatNumber(value float64, decimals int) string {
+	decimal := l.Decimal
+	if decimal == "" {
+		decimal = "."
+	}
+
+	raw := strconv.FormatFloat(math.Abs(value), 'f', decimals, 64)
+	intPart, fracPart, _ := strings.Cut(raw, ".")
+
+	if l.Thousands != "" && len(intPart) > 3 {
+		var b strings.Builder
+		lead := len(intPart) % 3
+		if lead > 0 {
+			b.WriteString(intPart[:lead])
+		}
+		for i := lead; i < len(intPart); i += 3 {
+			if b.Len() > 0 {
+				b.WriteString(l.Thousands)
+			}
+			b.WriteString(intPart[i : i+3])
+		}
+		intPart = b.String()
+	}
+
+	result := intPart
+	if fracPart != "" {
+		result += decimal + fracPart
+	}
+	if value < 0 && strings.Trim(raw, "0.") != "" {
+		result = "-" + result
+	}
+	return result
+}
+
+// FormatRevenue renders an amount in cents for the locale. The raw format keeps
+// cents as an integer so existing exports stay machine-readable; any other
+// locale renders currency units with two decimals.
+func (l NumberLocale) FormatRevenue(cents int64) string {
+	if l == RawNumberLocale {
+		return strconv.FormatInt(cents, 10)
+	}
+	return l.FormatNumber(float64(cents)/100, 2)
+}
@@ -0,0 +1,44 @@
+package analytics
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNumberLocaleFormatRevenue(t *testing.T) {
+	tests := []struct {
+		name     string
+		locale   string
+		cents    int64
+		expected string
+	}{
+		{name: "raw format by default", locale: "", cents: 123456789, expected: "123456789"},
+		{name: "unknown locale falls back to raw", locale: "xx-YY", cents: 123456789, expected: "123456789"},
+		{name: "en-US", locale: "en-US", cents: 123456789, expected: "1,234,567.89"},
+		{name: "de-DE", locale: "de-DE", cents: 123456789, expected: "1.234.567,89"},
+		{name: "fr-FR", locale: "fr_FR", cents: 123456789, expected: "1 234 567,89"},
+		{name: "de-CH", locale: "de-CH", cents: 123456789, expected: "1'234'567.89"},
+		{name: "small amount", locale: "de-DE", cents: 5, expected: "0,05"},
+		{name: "negative amount", locale: "en-US", cents: -100050, expected: "-1,000.50"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, ParseNumberLocale(tt.locale).FormatRevenue(tt.cents))
+		})
+	}
+}
+
+func TestNumberLocaleFormatNumber(t *testing.T) {
+	t.Run("groups thousands only when needed", func(t *testing.T) {
+		loc := ParseNumberLocale("en")
+		assert.Equal(t, "999", loc.FormatNumber(999, 0))
+		assert.Equal(t, "1,000", loc.FormatNumber(1000, 0))
+		assert.Equal(t, "100,000.5", loc.FormatNumber(100000.5, 1))
+	})
+
+	t.Run("raw format never groups", func(t *testing.T) {
+		assert.Equal(t, "1234567.89", RawNumberLocale.FormatNumber(1234567.89, 2))
+	})
+}
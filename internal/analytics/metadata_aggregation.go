@@ -0,0 +1,138 @@
+package analytics
+
+import (
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+
+	"fusionaly/internal/events"
+)
+
+// AggOp is an aggregation over a numeric custom event metadata key
+type AggOp string
+
+const (
+	AggSum AggOp = "sum"
+	AggAvg AggOp = "avg"
+	AggMin AggOp = "min"
+	AggMax AggOp = "max"
+)
+
+// aggOpFunctions maps each AggOp to its SQLite aggregate function
+var aggOpFunctions = map[AggOp]string{
+	AggSum: "SUM",
+	AggAvg: "AVG",
+	AggMin: "MIN",
+	AggMax: "MAX",
+}
+
+// ParseAggOp parses an aggregation name, defaulting to AggSum when empty
+func ParseAggOp(value string) (AggOp, error) {
+	op := AggOp(strings.ToLower(strings.TrimSpace(value)))
+	if op == "" {
+		return AggSum, nil
+	}
+	if _, ok := aggOpFunctions[op]; !ok {
+		return "", fmt.Errorf("unknown aggregation %q", value)
+	}
+	return op, nil
+}
+
+// NumericAggregation is the result of aggregating one metadata key of a custom event
+type NumericAggregation struct {
+	EventName string  `json:"event_name"`
+	MetaKey   string  `json:"meta_key"`
+	Op        AggOp   `json:"op"`
+	Value     float64 `json:"value"`
+	Events    int64   `json:"events"` // Events that had a numeric value for the key
+}
+
+// numericMetaValue extracts a metadata key as a number, NULL when the metadata
+// isn't valid JSON or the key is missing or not a number. The CASEs are nested
+// so json_type never sees invalid JSON.
+const numericMetaValue = `
+	CASE WHEN json_valid(custom_event_meta) = 1 THEN
+		CASE WHEN json_type(custom_event_meta, ?) IN ('integer', 'real')
+		THEN CAST(json_extract(custom_event_meta, ?) AS REAL)
+		END
+	END`
+
+// GetEventNumericAggregation aggregates a numeric metadata key over the custom
+// events named eventName in the time frame, e.g. the sum of items_count on
+// "checkout". Events with invalid metadata or without a numeric value for the
+// key are ignored, and the value is 0 when no event has one.
+func GetEventNumericAggregation(db *gorm.DB, params WebsiteScopedQueryParams, eventName, metaKey string, op AggOp) (*NumericAggregation, error) {
+	function, ok := aggOpFunctions[op]
+	if !ok {
+		return nil, fmt.Errorf("unknown aggregation %q", op)
+	}
+
+	result := &NumericAggregation{EventName: eventName, MetaKey: metaKey, Op: op}
+	metaKey = strings.TrimSpace(metaKey)
+	if eventName == "" || metaKey == "" {
+		return result, nil
+	}
+
+	query := fmt.Sprintf(`
+		SELECT
+			COALESCE(%s(value), 0) AS value,
+			COUNT(value) AS events
+		FROM (
+			SELECT %s AS value
+			FROM events
+			WHERE website_id = ?
+			AND timestamp BETWEEN ? AND ?
+			AND event_type = ?
+			AND custom_event_name = ?
+		)
+	`, function, numericMetaValue)
+
+	path := propertyPath(metaKey)
+	err := db.Raw(query,
+		path, path,
+		params.WebsiteID,
+		params.TimeFrame.From.UTC(),
+		params.TimeFrame.To.UTC(),
+		events.EventTypeCustomEvent,
+		eventName,
+	).Row().Scan(&result.Value, &result.Events)
+	if err != nil {
+		return nil, fmt.Errorf("error aggregating %s of %q on %q: %w", op, metaKey, eventName, err)
+	}
+
+	return result, nil
+}
+
+// GetEventNumericMetaKeysInTimeFrame lists, alphabetically, the top-level
+// metadata keys holding numbers on the custom events named eventName, so the
+// dashboard can offer them for aggregation.
+func GetEventNumericMetaKeysInTimeFrame(db *gorm.DB, params WebsiteScopedQueryParams, eventName string) ([]string, error) {
+	keys := []string{}
+	if eventName == "" {
+		return keys, nil
+	}
+
+	query := `
+		SELECT DISTINCT m.key
+		FROM events, json_each(CASE WHEN json_valid(events.custom_event_meta) = 1 THEN events.custom_event_meta ELSE '{}' END) AS m
+		WHERE events.website_id = ?
+		AND events.timestamp BETWEEN ? AND ?
+		AND events.event_type = ?
+		AND events.custom_event_name = ?
+		AND typeof(m.key) = 'text'
+		AND m.type IN ('integer', 'real')
+		ORDER BY m.key
+		LIMIT 100
+	`
+	if err := db.Raw(query,
+		params.WebsiteID,
+		params.TimeFrame.From.UTC(),
+		params.TimeFrame.To.UTC(),
+		events.EventTypeCustomEvent,
+		eventName,
+	).Scan(&keys).Error; err != nil {
+		return nil, fmt.Errorf("error fetching numeric metadata keys of %q: %w", eventName, err)
+	}
+	return keys, nil
+}
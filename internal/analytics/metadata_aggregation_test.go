@@ -0,0 +1,100 @@
+package analytics_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"fusionaly/internal/analytics"
+	"fusionaly/internal/events"
+	"fusionaly/internal/testsupport"
+)
+
+func TestGetEventNumericAggregation(t *testing.T) {
+	dbManager, _ := testsupport.SetupTestDBManager(t)
+	db := dbManager.GetConnection()
+	testsupport.CleanAllTables(db)
+
+	website := testsupport.CreateTestWebsite(db, "shop.com")
+	other := testsupport.CreateTestWebsite(db, "other-shop.com")
+	baseTime := time.Date(2024, 7, 1, 12, 0, 0, 0, time.UTC)
+
+	checkout := func(websiteID uint, name, meta string, at time.Time) events.Event {
+		return events.Event{WebsiteID: websiteID, UserSignature: "buyer", Hostname: "shop.com", Pathname: "/checkout",
+			EventType: events.EventTypeCustomEvent, CustomEventName: name, CustomEventMeta: meta, Timestamp: at, CreatedAt: at}
+	}
+	rows := []events.Event{
+		checkout(website.ID, "checkout", `{"items_count": 2, "plan_value": 10.5}`, baseTime),
+		checkout(website.ID, "checkout", `{"items_count": 5, "plan_value": 4}`, baseTime.Add(time.Hour)),
+		checkout(website.ID, "checkout", `{"items_count": 1}`, baseTime.Add(2*time.Hour)),
+		// Missing key, non-numeric value and invalid JSON are ignored
+		checkout(website.ID, "checkout", `{"plan_value": 99}`, baseTime.Add(3*time.Hour)),
+		checkout(website.ID, "checkout", `{"items_count": "many"}`, baseTime.Add(4*time.Hour)),
+		checkout(website.ID, "checkout", `{invalid json`, baseTime.Add(5*time.Hour)),
+		// Another event, outside the time frame, and another website
+		checkout(website.ID, "refund", `{"items_count": 100}`, baseTime),
+		checkout(website.ID, "checkout", `{"items_count": 100}`, baseTime.AddDate(0, 0, 3)),
+		checkout(other.ID, "checkout", `{"items_count": 100}`, baseTime),
+	}
+	require.NoError(t, db.Create(&rows).Error)
+
+	params := analytics.NewWebsiteScopedQueryParams(setupTimeFrame(t), int(website.ID))
+
+	tests := []struct {
+		op   analytics.AggOp
+		want float64
+	}{
+		{analytics.AggSum, 8},
+		{analytics.AggAvg, 8.0 / 3},
+		{analytics.AggMin, 1},
+		{analytics.AggMax, 5},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.op), func(t *testing.T) {
+			result, err := analytics.GetEventNumericAggregation(db, params, "checkout", "items_count", tt.op)
+			require.NoError(t, err)
+			assert.InDelta(t, tt.want, result.Value, 0.001)
+			assert.Equal(t, int64(3), result.Events, "rows without a numeric items_count are ignored")
+		})
+	}
+
+	t.Run("decimal values", func(t *testing.T) {
+		result, err := analytics.GetEventNumericAggregation(db, params, "checkout", "plan_value", analytics.AggSum)
+		require.NoError(t, err)
+		assert.InDelta(t, 113.5, result.Value, 0.001)
+		assert.Equal(t, int64(3), result.Events)
+	})
+
+	t.Run("key nobody sent", func(t *testing.T) {
+		result, err := analytics.GetEventNumericAggregation(db, params, "checkout", "discount", analytics.AggAvg)
+		require.NoError(t, err)
+		assert.Zero(t, result.Value)
+		assert.Zero(t, result.Events)
+	})
+
+	t.Run("unknown aggregation", func(t *testing.T) {
+		_, err := analytics.GetEventNumericAggregation(db, params, "checkout", "items_count", analytics.AggOp("median"))
+		assert.Error(t, err)
+	})
+
+	t.Run("numeric keys", func(t *testing.T) {
+		keys, err := analytics.GetEventNumericMetaKeysInTimeFrame(db, params, "checkout")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"items_count", "plan_value"}, keys)
+	})
+}
+
+func TestParseAggOp(t *testing.T) {
+	op, err := analytics.ParseAggOp("")
+	require.NoError(t, err)
+	assert.Equal(t, analytics.AggSum, op)
+
+	op, err = analytics.ParseAggOp(" AVG ")
+	require.NoError(t, err)
+	assert.Equal(t, analytics.AggAvg, op)
+
+	_, err = analytics.ParseAggOp("median")
+	assert.Error(t, err)
+}
@@ -0,0 +1,83 @@
+package analytics
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// MaxMetricPageSize caps the page size of GetMetricPage
+const MaxMetricPageSize = 100
+
+// MetricPage is one page of a top-N list plus the number of matching entries
+type MetricPage struct {
+	Items  []MetricCountResult `json:"items"`
+	Total  int                 `json:"total"`
+	Offset int                 `json:"offset"`
+	Limit  int                 `json:"limit"`
+}
+
+type metricListQuery struct {
+	Fetch  func(db *gorm.DB, params WebsiteScopedQueryParams) ([]MetricCountResult, error)
+	Format func([]MetricCountResult) []MetricCountResult
+}
+
+// metricLists maps the DashboardMetrics JSON fields of top-N lists to their queries
+var metricLists = map[string]metricListQuery{
+	"top_urls":              {Fetch: GetTopURLsInTimeFrame},
+	"top_countries":         {Fetch: GetTopCountriesInTimeFrame, Format: FormatCountryStats},
+	"top_languages":         {Fetch: GetTopLanguagesInTimeFrame, Format: FormatLanguageStats},
+	"top_devices":           {Fetch: GetTopDeviceTypesInTimeFrame, Format: FormatDeviceStats},
+	"top_device_models":     {Fetch: GetTopDeviceModelsInTimeFrame, Format: FormatDeviceModelStats},
+	"top_screen_sizes":      {Fetch: GetTopScreenSizesInTimeFrame, Format: FormatScreenSizeStats},
+	"top_referrers":         {Fetch: GetTopReferrersInTimeFrame, Format: FormatReferrerStats},
+	"top_browsers":          {Fetch: GetTopBrowsersInTimeFrame, Format: FormatBrowserStats},
+	"top_browser_versions":  {Fetch: GetTopBrowserVersionsInTimeFrame, Format: FormatBrowserStats},
+	"top_operating_systems": {Fetch: GetTopOsInTimeFrame, Format: FormatOSStats},
+	"top_custom_events":     {Fetch: GetTopCustomEventsInTimeFrame},
+	"top_downloads":         {Fetch: GetTopDownloadsInTimeFrame},
+	"top_entry_pages":       {Fetch: GetTopEntryPagesInTimeFrame},
+	"top_exit_pages":        {Fetch: GetTopExitPagesInTimeFrame},
+	"top_404s":              {Fetch: GetTop404sInTimeFrame},
+	"top_utm_mediums":       {Fetch: GetTopUTMMediumsInTimeFrame},
+	"top_utm_sources":       {Fetch: GetTopUTMSourcesInTimeFrame},
+	"top_utm_campaigns":     {Fetch: GetTopUTMCampaignsInTimeFrame},
+	"top_utm_terms":         {Fetch: GetTopUTMTermsInTimeFrame},
+	"top_utm_contents":      {Fetch: GetTopUTMContentsInTimeFrame},
+	"top_ref_params": {Fetch: func(db *gorm.DB, params WebsiteScopedQueryParams) ([]MetricCountResult, error) {
+		return GetTopQueryParamValuesInTimeFrame(db, params, "ref")
+	}},
+}
+
+// GetMetricPage returns the page of the named top-N list selected by
+// params.Offset, params.Limit and params.Search, along with the total number
+// of entries matching the search.
+func GetMetricPage(db *gorm.DB, params WebsiteScopedQueryParams, metric string) (*MetricPage, error) {
+	list, ok := metricLists[metric]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownMetric, metric)
+	}
+
+	allParams := params
+	allParams.Limit = -1
+	allParams.Offset = 0
+	all, err := list.Fetch(db, allParams)
+	if err != nil {
+		return nil, err
+	}
+
+	items, err := list.Fetch(db, params)
+	if err != nil {
+		return nil, err
+	}
+	if list.Format != nil {
+		items = list.Format(items)
+	}
+
+	return &MetricPage{
+		Items:  ensureNonNil(items),
+		Total:  len(all),
+		Offset: params.Offset,
+		Limit:  params.Limit,
+	}, nil
+}
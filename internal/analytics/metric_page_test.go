@@ -0,0 +1,109 @@
+package analytics_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"fusionaly/internal/analytics"
+	"fusionaly/internal/testsupport"
+)
+
+func TestTopListPagingAndSearch(t *testing.T) {
+	dbManager, _ := testsupport.SetupTestDBManager(t)
+	db := dbManager.GetConnection()
+	website := testsupport.CreateTestWebsite(db, "paging.com")
+
+	hour := time.Date(2024, 7, 1, 12, 0, 0, 0, time.UTC)
+	require.NoError(t, db.Create([]analytics.PageStat{
+		{WebsiteID: website.ID, Hostname: "paging.com", Pathname: "/Pricing", VisitorsCount: 50, PageViewsCount: 50, Hour: hour},
+		{WebsiteID: website.ID, Hostname: "paging.com", Pathname: "/pricing-old", VisitorsCount: 40, PageViewsCount: 40, Hour: hour},
+		{WebsiteID: website.ID, Hostname: "paging.com", Pathname: "/blog", VisitorsCount: 30, PageViewsCount: 30, Hour: hour},
+		{WebsiteID: website.ID, Hostname: "paging.com", Pathname: "/docs_v2", VisitorsCount: 20, PageViewsCount: 20, Hour: hour},
+		{WebsiteID: website.ID, Hostname: "paging.com", Pathname: "/docsv2", VisitorsCount: 10, PageViewsCount: 10, Hour: hour},
+	}).Error)
+	require.NoError(t, db.Create([]analytics.RefStat{
+		{WebsiteID: website.ID, Hostname: "www.google.com", VisitorsCount: 5, PageViewsCount: 5, Hour: hour},
+		{WebsiteID: website.ID, Hostname: "news.ycombinator.com", VisitorsCount: 3, PageViewsCount: 3, Hour: hour},
+		{WebsiteID: website.ID, Hostname: "duckduckgo.com", VisitorsCount: 1, PageViewsCount: 1, Hour: hour},
+	}).Error)
+
+	params := analytics.NewWebsiteScopedQueryParams(setupTimeFrame(t), int(website.ID))
+	names := func(results []analytics.MetricCountResult) []string {
+		out := make([]string, len(results))
+		for i, r := range results {
+			out[i] = r.Name
+		}
+		return out
+	}
+
+	t.Run("offset pages through the list", func(t *testing.T) {
+		p := params
+		p.Limit = 2
+
+		first, err := analytics.GetTopURLsInTimeFrame(db, p)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"paging.com/Pricing", "paging.com/pricing-old"}, names(first))
+
+		p.Offset = 2
+		second, err := analytics.GetTopURLsInTimeFrame(db, p)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"paging.com/blog", "paging.com/docs_v2"}, names(second))
+
+		p.Offset = 4
+		last, err := analytics.GetTopURLsInTimeFrame(db, p)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"paging.com/docsv2"}, names(last))
+	})
+
+	t.Run("search is case-insensitive", func(t *testing.T) {
+		p := params
+		p.Search = "PRICING"
+		results, err := analytics.GetTopURLsInTimeFrame(db, p)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"paging.com/Pricing", "paging.com/pricing-old"}, names(results))
+	})
+
+	t.Run("search treats wildcards literally", func(t *testing.T) {
+		p := params
+		p.Search = "docs_"
+		results, err := analytics.GetTopURLsInTimeFrame(db, p)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"paging.com/docs_v2"}, names(results))
+	})
+
+	t.Run("metric page reports the matching total", func(t *testing.T) {
+		p := params
+		p.Limit = 1
+		p.Offset = 1
+		p.Search = "pricing"
+		page, err := analytics.GetMetricPage(db, p, "top_urls")
+		require.NoError(t, err)
+		assert.Equal(t, 2, page.Total)
+		assert.Equal(t, []string{"paging.com/pricing-old"}, names(page.Items))
+	})
+
+	t.Run("referrers page and search after normalization", func(t *testing.T) {
+		p := params
+		p.Limit = 1
+		p.Offset = 1
+		page, err := analytics.GetMetricPage(db, p, "top_referrers")
+		require.NoError(t, err)
+		assert.Equal(t, 3, page.Total)
+		require.Len(t, page.Items, 1)
+
+		p = params
+		p.Search = "google"
+		results, err := analytics.GetTopReferrersInTimeFrame(db, p)
+		require.NoError(t, err)
+		require.Len(t, results, 1)
+		assert.Equal(t, int64(5), results[0].Count)
+	})
+
+	t.Run("rejects unknown lists", func(t *testing.T) {
+		_, err := analytics.GetMetricPage(db, params, "top_planets")
+		assert.ErrorIs(t, err, analytics.ErrUnknownMetric)
+	})
+}
@@ -2,8 +2,12 @@ package analytics
 
 import (
 	"fmt"
+	"net/http"
+	"strings"
 
 	"gorm.io/gorm"
+
+	"fusionaly/internal/events"
 )
 
 // GetTopURLsInTimeFrame fetches top URLs from PageStat
@@ -13,25 +17,25 @@ func GetTopURLsInTimeFrame(db *gorm.DB, params WebsiteScopedQueryParams) ([]Metr
 		Count int64
 	}
 
-	query := `
+	segment, segmentArgs := segmentClause(db, params, "page_stats")
+	search, searchArgs := searchClause(params, "hostname || pathname")
+	query := fmt.Sprintf(`
     SELECT 
         hostname || pathname as url, 
         SUM(visitors_count) as count
     FROM page_stats
     WHERE hour BETWEEN ? AND ?
-    AND website_id = ?
+    AND website_id = ?%s%s
     GROUP BY hostname, pathname
     HAVING count > 0
     ORDER BY count DESC
-    LIMIT ?
-    `
-
-	err := db.Raw(query,
-		params.TimeFrame.From.UTC(),
-		params.TimeFrame.To.UTC(),
-		params.WebsiteID,
-		params.Limit,
-	).Scan(&rawResults).Error
+    LIMIT ? OFFSET ?
+    `, segment, search)
+
+	args := append([]interface{}{params.TimeFrame.From.UTC(), params.TimeFrame.To.UTC(), params.WebsiteID}, segmentArgs...)
+	args = append(args, searchArgs...)
+	args = append(args, params.Limit, params.Offset)
+	err := db.Raw(query, args...).Scan(&rawResults).Error
 	if err != nil {
 		return nil, fmt.Errorf("error fetching top URLs from PageStat: %w", err)
 	}
@@ -51,25 +55,25 @@ func GetTopBrowsersInTimeFrame(db *gorm.DB, params WebsiteScopedQueryParams) ([]
 		Count   int64
 	}
 
-	query := `
+	segment, segmentArgs := segmentClause(db, params, "browser_stats")
+	search, searchArgs := searchClause(params, "browser")
+	query := fmt.Sprintf(`
     SELECT 
         browser as browser, 
         SUM(visitors_count) as count
     FROM browser_stats
     WHERE hour BETWEEN ? AND ?
-    AND website_id = ?
+    AND website_id = ?%s%s
     GROUP BY browser
     HAVING count > 0
     ORDER BY count DESC
-    LIMIT ?
-    `
-
-	err := db.Raw(query,
-		params.TimeFrame.From.UTC(),
-		params.TimeFrame.To.UTC(),
-		params.WebsiteID,
-		params.Limit,
-	).Scan(&rawResults).Error
+    LIMIT ? OFFSET ?
+    `, segment, search)
+
+	args := append([]interface{}{params.TimeFrame.From.UTC(), params.TimeFrame.To.UTC(), params.WebsiteID}, segmentArgs...)
+	args = append(args, searchArgs...)
+	args = append(args, params.Limit, params.Offset)
+	err := db.Raw(query, args...).Scan(&rawResults).Error
 	if err != nil {
 		return nil, fmt.Errorf("error fetching top browsers from BrowserStat: %w", err)
 	}
@@ -82,6 +86,44 @@ func GetTopBrowsersInTimeFrame(db *gorm.DB, params WebsiteScopedQueryParams) ([]
 	return results, nil
 }
 
+// GetTopBrowserVersionsInTimeFrame fetches top browser major versions (e.g. "chrome 120")
+// from BrowserVersionStat. Browsers without a reported version are listed by name only.
+func GetTopBrowserVersionsInTimeFrame(db *gorm.DB, params WebsiteScopedQueryParams) ([]MetricCountResult, error) {
+	var rawResults []struct {
+		Browser string
+		Count   int64
+	}
+
+	browserVersionExpr := "TRIM(browser || ' ' || version)"
+	search, searchArgs := searchClause(params, browserVersionExpr)
+	query := fmt.Sprintf(`
+    SELECT
+        %s as browser,
+        SUM(visitors_count) as count
+    FROM browser_version_stats
+    WHERE hour BETWEEN ? AND ?
+    AND website_id = ?%s
+    GROUP BY browser, version
+    HAVING count > 0
+    ORDER BY count DESC
+    LIMIT ? OFFSET ?
+    `, browserVersionExpr, search)
+
+	args := append([]interface{}{params.TimeFrame.From.UTC(), params.TimeFrame.To.UTC(), params.WebsiteID}, searchArgs...)
+	args = append(args, params.Limit, params.Offset)
+	err := db.Raw(query, args...).Scan(&rawResults).Error
+	if err != nil {
+		return nil, fmt.Errorf("error fetching top browser versions from BrowserVersionStat: %w", err)
+	}
+
+	results := make([]MetricCountResult, len(rawResults))
+	for i, r := range rawResults {
+		results[i] = MetricCountResult{Name: r.Browser, Count: r.Count}
+	}
+
+	return results, nil
+}
+
 // GetTopOsInTimeFrame fetches top operating systems from OSStat
 func GetTopOsInTimeFrame(db *gorm.DB, params WebsiteScopedQueryParams) ([]MetricCountResult, error) {
 	var rawResults []struct {
@@ -89,40 +131,26 @@ func GetTopOsInTimeFrame(db *gorm.DB, params WebsiteScopedQueryParams) ([]Metric
 		Count int64
 	}
 
-	query := `
+	segment, segmentArgs := segmentClause(db, params, "os_stats")
+	search, searchArgs := searchClause(params, normalizedOSExpr)
+	query := fmt.Sprintf(`
     SELECT 
-        CASE 
-            WHEN LOWER(operating_system) LIKE '%mac%' OR LOWER(operating_system) LIKE '%darwin%' THEN 'MacOS'
-            WHEN LOWER(operating_system) LIKE '%linux%' OR LOWER(operating_system) LIKE '%gnu/linux%' THEN 'Linux'
-            WHEN LOWER(operating_system) LIKE '%ios%' OR LOWER(operating_system) LIKE '%iphone os%' THEN 'iOS'
-            WHEN LOWER(operating_system) LIKE '%android%' THEN 'Android'
-            WHEN LOWER(operating_system) LIKE '%windows%' THEN 'Windows'
-            ELSE operating_system
-        END as os, 
+        %s as os, 
         SUM(visitors_count) as count
     FROM os_stats
     WHERE hour BETWEEN ? AND ?
-    AND website_id = ?
+    AND website_id = ?%s%s
     GROUP BY 
-        CASE 
-            WHEN LOWER(operating_system) LIKE '%mac%' OR LOWER(operating_system) LIKE '%darwin%' THEN 'MacOS'
-            WHEN LOWER(operating_system) LIKE '%linux%' OR LOWER(operating_system) LIKE '%gnu/linux%' THEN 'Linux'
-            WHEN LOWER(operating_system) LIKE '%ios%' OR LOWER(operating_system) LIKE '%iphone os%' THEN 'iOS'
-            WHEN LOWER(operating_system) LIKE '%android%' THEN 'Android'
-            WHEN LOWER(operating_system) LIKE '%windows%' THEN 'Windows'
-            ELSE operating_system
-        END
+        %s
     HAVING count > 0
     ORDER BY count DESC
-    LIMIT ?
-    `
-
-	err := db.Raw(query,
-		params.TimeFrame.From.UTC(),
-		params.TimeFrame.To.UTC(),
-		params.WebsiteID,
-		params.Limit,
-	).Scan(&rawResults).Error
+    LIMIT ? OFFSET ?
+    `, normalizedOSExpr, segment, search, normalizedOSExpr)
+
+	args := append([]interface{}{params.TimeFrame.From.UTC(), params.TimeFrame.To.UTC(), params.WebsiteID}, segmentArgs...)
+	args = append(args, searchArgs...)
+	args = append(args, params.Limit, params.Offset)
+	err := db.Raw(query, args...).Scan(&rawResults).Error
 	if err != nil {
 		return nil, fmt.Errorf("error fetching top operating systems from OSStat: %w", err)
 	}
@@ -142,25 +170,25 @@ func GetTopCountriesInTimeFrame(db *gorm.DB, params WebsiteScopedQueryParams) ([
 		Count   int64
 	}
 
-	query := `
+	segment, segmentArgs := segmentClause(db, params, "country_stats")
+	search, searchArgs := searchClause(params, "country")
+	query := fmt.Sprintf(`
     SELECT 
         country as country, 
         SUM(visitors_count) as count
     FROM country_stats
     WHERE hour BETWEEN ? AND ?
-    AND website_id = ?
+    AND website_id = ?%s%s
     GROUP BY country
     HAVING count > 0
     ORDER BY count DESC
-    LIMIT ?
-    `
-
-	err := db.Raw(query,
-		params.TimeFrame.From.UTC(),
-		params.TimeFrame.To.UTC(),
-		params.WebsiteID,
-		params.Limit,
-	).Scan(&rawResults).Error
+    LIMIT ? OFFSET ?
+    `, segment, search)
+
+	args := append([]interface{}{params.TimeFrame.From.UTC(), params.TimeFrame.To.UTC(), params.WebsiteID}, segmentArgs...)
+	args = append(args, searchArgs...)
+	args = append(args, params.Limit, params.Offset)
+	err := db.Raw(query, args...).Scan(&rawResults).Error
 	if err != nil {
 		return nil, fmt.Errorf("error fetching top countries from CountryStat: %w", err)
 	}
@@ -173,6 +201,126 @@ func GetTopCountriesInTimeFrame(db *gorm.DB, params WebsiteScopedQueryParams) ([
 	return results, nil
 }
 
+// GetTopRegionsInTimeFrame fetches top regions from RegionStat, named "Region, CC"
+func GetTopRegionsInTimeFrame(db *gorm.DB, params WebsiteScopedQueryParams) ([]MetricCountResult, error) {
+	return getTopLocationsInTimeFrame(db, params, "region_stats", "region")
+}
+
+// GetTopCitiesInTimeFrame fetches top cities from CityStat, named "City, CC"
+func GetTopCitiesInTimeFrame(db *gorm.DB, params WebsiteScopedQueryParams) ([]MetricCountResult, error) {
+	return getTopLocationsInTimeFrame(db, params, "city_stats", "city")
+}
+
+// getTopLocationsInTimeFrame ranks a location column of table. Locations are
+// grouped with their country since names like "Georgia" aren't unique.
+func getTopLocationsInTimeFrame(db *gorm.DB, params WebsiteScopedQueryParams, table, column string) ([]MetricCountResult, error) {
+	var rawResults []struct {
+		Country  string
+		Location string
+		Count    int64
+	}
+
+	search, searchArgs := searchClause(params, column)
+	query := fmt.Sprintf(`
+    SELECT
+        country,
+        %[2]s as location,
+        SUM(visitors_count) as count
+    FROM %[1]s
+    WHERE hour BETWEEN ? AND ?
+    AND website_id = ?%[3]s
+    GROUP BY country, %[2]s
+    HAVING count > 0
+    ORDER BY count DESC
+    LIMIT ? OFFSET ?
+    `, table, column, search)
+
+	args := append([]interface{}{params.TimeFrame.From.UTC(), params.TimeFrame.To.UTC(), params.WebsiteID}, searchArgs...)
+	args = append(args, params.Limit, params.Offset)
+	if err := db.Raw(query, args...).Scan(&rawResults).Error; err != nil {
+		return nil, fmt.Errorf("error fetching top locations from %s: %w", table, err)
+	}
+
+	results := make([]MetricCountResult, len(rawResults))
+	for i, r := range rawResults {
+		results[i] = MetricCountResult{Name: r.Location + ", " + strings.ToUpper(r.Country), Count: r.Count}
+	}
+
+	return results, nil
+}
+
+// GetTopLanguagesInTimeFrame fetches top visitor languages from LanguageStat
+func GetTopLanguagesInTimeFrame(db *gorm.DB, params WebsiteScopedQueryParams) ([]MetricCountResult, error) {
+	var rawResults []struct {
+		Language string
+		Count    int64
+	}
+
+	search, searchArgs := searchClause(params, "language")
+	query := fmt.Sprintf(`
+    SELECT
+        language,
+        SUM(visitors_count) as count
+    FROM language_stats
+    WHERE hour BETWEEN ? AND ?
+    AND website_id = ?%s
+    GROUP BY language
+    HAVING count > 0
+    ORDER BY count DESC
+    LIMIT ? OFFSET ?
+    `, search)
+
+	args := append([]interface{}{params.TimeFrame.From.UTC(), params.TimeFrame.To.UTC(), params.WebsiteID}, searchArgs...)
+	args = append(args, params.Limit, params.Offset)
+	err := db.Raw(query, args...).Scan(&rawResults).Error
+	if err != nil {
+		return nil, fmt.Errorf("error fetching top languages from LanguageStat: %w", err)
+	}
+
+	results := make([]MetricCountResult, len(rawResults))
+	for i, r := range rawResults {
+		results[i] = MetricCountResult{Name: r.Language, Count: r.Count}
+	}
+
+	return results, nil
+}
+
+// GetTopScreenSizesInTimeFrame fetches top viewport width buckets from ScreenStat
+func GetTopScreenSizesInTimeFrame(db *gorm.DB, params WebsiteScopedQueryParams) ([]MetricCountResult, error) {
+	var rawResults []struct {
+		ScreenSize string
+		Count      int64
+	}
+
+	search, searchArgs := searchClause(params, "screen_size")
+	query := fmt.Sprintf(`
+    SELECT
+        screen_size,
+        SUM(visitors_count) as count
+    FROM screen_stats
+    WHERE hour BETWEEN ? AND ?
+    AND website_id = ?%s
+    GROUP BY screen_size
+    HAVING count > 0
+    ORDER BY count DESC
+    LIMIT ? OFFSET ?
+    `, search)
+
+	args := append([]interface{}{params.TimeFrame.From.UTC(), params.TimeFrame.To.UTC(), params.WebsiteID}, searchArgs...)
+	args = append(args, params.Limit, params.Offset)
+	err := db.Raw(query, args...).Scan(&rawResults).Error
+	if err != nil {
+		return nil, fmt.Errorf("error fetching top screen sizes from ScreenStat: %w", err)
+	}
+
+	results := make([]MetricCountResult, len(rawResults))
+	for i, r := range rawResults {
+		results[i] = MetricCountResult{Name: r.ScreenSize, Count: r.Count}
+	}
+
+	return results, nil
+}
+
 // GetTopDeviceTypesInTimeFrame fetches top device types from DeviceStat
 func GetTopDeviceTypesInTimeFrame(db *gorm.DB, params WebsiteScopedQueryParams) ([]MetricCountResult, error) {
 	var rawResults []struct {
@@ -180,25 +328,25 @@ func GetTopDeviceTypesInTimeFrame(db *gorm.DB, params WebsiteScopedQueryParams)
 		Count  int64
 	}
 
-	query := `
+	segment, segmentArgs := segmentClause(db, params, "device_stats")
+	search, searchArgs := searchClause(params, "device_type")
+	query := fmt.Sprintf(`
     SELECT 
         device_type as device, 
         SUM(visitors_count) as count
     FROM device_stats
     WHERE hour BETWEEN ? AND ?
-    AND website_id = ?
+    AND website_id = ?%s%s
     GROUP BY device_type
     HAVING count > 0
     ORDER BY count DESC
-    LIMIT ?
-    `
-
-	err := db.Raw(query,
-		params.TimeFrame.From.UTC(),
-		params.TimeFrame.To.UTC(),
-		params.WebsiteID,
-		params.Limit,
-	).Scan(&rawResults).Error
+    LIMIT ? OFFSET ?
+    `, segment, search)
+
+	args := append([]interface{}{params.TimeFrame.From.UTC(), params.TimeFrame.To.UTC(), params.WebsiteID}, segmentArgs...)
+	args = append(args, searchArgs...)
+	args = append(args, params.Limit, params.Offset)
+	err := db.Raw(query, args...).Scan(&rawResults).Error
 	if err != nil {
 		return nil, fmt.Errorf("error fetching top device types from DeviceStat: %w", err)
 	}
@@ -211,6 +359,42 @@ func GetTopDeviceTypesInTimeFrame(db *gorm.DB, params WebsiteScopedQueryParams)
 	return results, nil
 }
 
+// GetTopDeviceModelsInTimeFrame fetches top mobile and tablet device models from DeviceModelStat
+func GetTopDeviceModelsInTimeFrame(db *gorm.DB, params WebsiteScopedQueryParams) ([]MetricCountResult, error) {
+	var rawResults []struct {
+		DeviceModel string
+		Count       int64
+	}
+
+	search, searchArgs := searchClause(params, "device_model")
+	query := fmt.Sprintf(`
+    SELECT
+        device_model,
+        SUM(visitors_count) as count
+    FROM device_model_stats
+    WHERE hour BETWEEN ? AND ?
+    AND website_id = ?%s
+    GROUP BY device_model
+    HAVING count > 0
+    ORDER BY count DESC
+    LIMIT ? OFFSET ?
+    `, search)
+
+	args := append([]interface{}{params.TimeFrame.From.UTC(), params.TimeFrame.To.UTC(), params.WebsiteID}, searchArgs...)
+	args = append(args, params.Limit, params.Offset)
+	err := db.Raw(query, args...).Scan(&rawResults).Error
+	if err != nil {
+		return nil, fmt.Errorf("error fetching top device models from DeviceModelStat: %w", err)
+	}
+
+	results := make([]MetricCountResult, len(rawResults))
+	for i, r := range rawResults {
+		results[i] = MetricCountResult{Name: r.DeviceModel, Count: r.Count}
+	}
+
+	return results, nil
+}
+
 // GetTopCustomEventsInTimeFrame fetches top custom events from EventStat
 func GetTopCustomEventsInTimeFrame(db *gorm.DB, params WebsiteScopedQueryParams) ([]MetricCountResult, error) {
 	var rawResults []struct {
@@ -218,25 +402,23 @@ func GetTopCustomEventsInTimeFrame(db *gorm.DB, params WebsiteScopedQueryParams)
 		Count       int64
 	}
 
-	query := `
+	search, searchArgs := searchClause(params, "event_key")
+	query := fmt.Sprintf(`
     SELECT 
         event_key as custom_event, 
         SUM(visitors_count) as count
     FROM event_stats
     WHERE hour BETWEEN ? AND ?
-    AND website_id = ?
+    AND website_id = ?%s
     GROUP BY event_key
     HAVING SUM(visitors_count) > 0
     ORDER BY count DESC
-    LIMIT ?
-    `
-
-	err := db.Raw(query,
-		params.TimeFrame.From.UTC(),
-		params.TimeFrame.To.UTC(),
-		params.WebsiteID,
-		params.Limit,
-	).Scan(&rawResults).Error
+    LIMIT ? OFFSET ?
+    `, search)
+
+	args := append([]interface{}{params.TimeFrame.From.UTC(), params.TimeFrame.To.UTC(), params.WebsiteID}, searchArgs...)
+	args = append(args, params.Limit, params.Offset)
+	err := db.Raw(query, args...).Scan(&rawResults).Error
 	if err != nil {
 		return nil, fmt.Errorf("error fetching top custom events from EventStat: %w", err)
 	}
@@ -249,29 +431,58 @@ func GetTopCustomEventsInTimeFrame(db *gorm.DB, params WebsiteScopedQueryParams)
 	return results, nil
 }
 
+// GetTopDownloadsInTimeFrame fetches the most downloaded files from DownloadStat.
+// Counts are total downloads; repeat downloads by the same visitor are included.
+func GetTopDownloadsInTimeFrame(db *gorm.DB, params WebsiteScopedQueryParams) ([]MetricCountResult, error) {
+	var results []MetricCountResult
+
+	search, searchArgs := searchClause(params, "filename")
+	query := fmt.Sprintf(`
+    SELECT 
+        filename as name, 
+        SUM(downloads_count) as count
+    FROM download_stats
+    WHERE hour BETWEEN ? AND ?
+    AND website_id = ?%s
+    GROUP BY filename
+    HAVING count > 0
+    ORDER BY count DESC
+    LIMIT ? OFFSET ?
+    `, search)
+
+	args := append([]interface{}{params.TimeFrame.From.UTC(), params.TimeFrame.To.UTC(), params.WebsiteID}, searchArgs...)
+	args = append(args, params.Limit, params.Offset)
+	err := db.Raw(query, args...).Scan(&results).Error
+	if err != nil {
+		return nil, fmt.Errorf("error fetching top downloads from DownloadStat: %w", err)
+	}
+
+	return results, nil
+}
+
 // GetTopEntryPagesInTimeFrame fetches top entry pages from PageStat
 func GetTopEntryPagesInTimeFrame(db *gorm.DB, params WebsiteScopedQueryParams) ([]MetricCountResult, error) {
 	var results []MetricCountResult
 
-	query := `
+	segment, segmentArgs := segmentClause(db, params, "page_stats")
+	search, searchArgs := searchClause(params, "hostname || pathname")
+	query := fmt.Sprintf(`
     SELECT 
         hostname || pathname as name, 
         SUM(entrances) as count
     FROM page_stats
     WHERE hour BETWEEN ? AND ?
-    AND website_id = ?
+    AND website_id = ?%s%s
     GROUP BY hostname, pathname
     HAVING count > 0
     ORDER BY count DESC
-    LIMIT ?
-    `
-
-	err := db.Raw(query,
-		params.TimeFrame.From.UTC(),
-		params.TimeFrame.To.UTC(),
-		params.WebsiteID,
-		params.Limit,
-	).Scan(&results).Error
+    LIMIT ? OFFSET ?
+    `, segment, search)
+
+	args := append([]interface{}{params.TimeFrame.From.UTC(), params.TimeFrame.To.UTC(), params.WebsiteID}, segmentArgs...)
+	args = append(args, searchArgs...)
+	args = append(args, params.Limit, params.Offset)
+	err := db.Raw(query, args...).Scan(&results).Error
 	if err != nil {
 		return nil, fmt.Errorf("error fetching top entry pages from PageStat: %w", err)
 	}
@@ -283,28 +494,58 @@ func GetTopEntryPagesInTimeFrame(db *gorm.DB, params WebsiteScopedQueryParams) (
 func GetTopExitPagesInTimeFrame(db *gorm.DB, params WebsiteScopedQueryParams) ([]MetricCountResult, error) {
 	var results []MetricCountResult
 
-	query := `
+	segment, segmentArgs := segmentClause(db, params, "page_stats")
+	search, searchArgs := searchClause(params, "hostname || pathname")
+	query := fmt.Sprintf(`
     SELECT 
         hostname || pathname as name, 
         SUM(exits) as count
     FROM page_stats
     WHERE hour BETWEEN ? AND ?
-    AND website_id = ?
+    AND website_id = ?%s%s
     GROUP BY hostname, pathname
     HAVING count > 0
     ORDER BY count DESC
-    LIMIT ?
-    `
-
-	err := db.Raw(query,
-		params.TimeFrame.From.UTC(),
-		params.TimeFrame.To.UTC(),
-		params.WebsiteID,
-		params.Limit,
-	).Scan(&results).Error
+    LIMIT ? OFFSET ?
+    `, segment, search)
+
+	args := append([]interface{}{params.TimeFrame.From.UTC(), params.TimeFrame.To.UTC(), params.WebsiteID}, segmentArgs...)
+	args = append(args, searchArgs...)
+	args = append(args, params.Limit, params.Offset)
+	err := db.Raw(query, args...).Scan(&results).Error
 	if err != nil {
 		return nil, fmt.Errorf("error fetching top exit pages from PageStat: %w", err)
 	}
 
 	return results, nil
 }
+
+// GetTop404sInTimeFrame fetches the most-hit not-found pages, i.e. page views
+// the SDK reported with a 404 status. Pages without a status are normal pages.
+func GetTop404sInTimeFrame(db *gorm.DB, params WebsiteScopedQueryParams) ([]MetricCountResult, error) {
+	var results []MetricCountResult
+
+	search, searchArgs := searchClause(params, "hostname || pathname")
+	query := fmt.Sprintf(`
+    SELECT
+        hostname || pathname as name,
+        COUNT(*) as count
+    FROM events
+    WHERE timestamp BETWEEN ? AND ?
+    AND website_id = ?
+    AND event_type = ?
+    AND status_code = ?%s
+    GROUP BY hostname, pathname
+    ORDER BY count DESC
+    LIMIT ? OFFSET ?
+    `, search)
+
+	args := append([]interface{}{params.TimeFrame.From.UTC(), params.TimeFrame.To.UTC(), params.WebsiteID, events.EventTypePageView, http.StatusNotFound}, searchArgs...)
+	args = append(args, params.Limit, params.Offset)
+	err := db.Raw(query, args...).Scan(&results).Error
+	if err != nil {
+		return nil, fmt.Errorf("error fetching top 404 pages: %w", err)
+	}
+
+	return results, nil
+}
@@ -5,6 +5,7 @@ import (
 	"fusionaly/internal/timeframe"
 
 	"fmt"
+	"log/slog"
 	"testing"
 	"time"
 
@@ -12,6 +13,7 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"fusionaly/internal/events"
+	"fusionaly/internal/settings"
 	"fusionaly/internal/websites"
 	"fusionaly/internal/testsupport"
 )
@@ -65,6 +67,15 @@ func TestGetVisitDurationInTimeFrame(t *testing.T) {
 	expectedDuration := float64(1200)
 	assert.InDelta(t, expectedDuration, duration, 1.0,
 		"Expected average duration of %.2f seconds, got %.2f", expectedDuration, duration)
+
+	t.Run("uses the website's session timeout", func(t *testing.T) {
+		// 10 minute gaps split every view into its own session
+		require.NoError(t, settings.SaveSessionTimeoutMinutes(db, websiteID, 5))
+
+		duration, err := analytics.GetVisitDurationInTimeFrame(db, queryParams)
+		require.NoError(t, err)
+		assert.Zero(t, duration)
+	})
 }
 
 // TestMetricsFromAggregationTables tests metrics that use aggregation tables
@@ -521,6 +532,13 @@ func TestTotalCounts(t *testing.T) {
 		assert.Equal(t, int64(50), totalSessions, "Expected 50 total sessions (20+30)")
 	})
 
+	// Test pages per session
+	t.Run("PagesPerSession", func(t *testing.T) {
+		pagesPerSession, err := analytics.GetPagesPerSessionInTimeFrame(db, queryParams)
+		require.NoError(t, err)
+		assert.InDelta(t, 1.4, pagesPerSession, 0.001, "Expected 1.4 pages per session (70/50)")
+	})
+
 	// Test total entries
 	t.Run("TotalEntryCount", func(t *testing.T) {
 		totalEntries, err := analytics.GetTotalEntryCountInTimeFrame(db, queryParams)
@@ -719,6 +737,66 @@ func TestAggregatedGoalConversionsInTimeFrame(t *testing.T) {
 		assert.Equal(t, 0, result[0].Count, "First day should have 0 goal conversions for other website")
 		assert.Equal(t, 0, result[1].Count, "Second day should have 0 goal conversions for other website")
 	})
+
+	t.Run("DashboardUsesConfiguredGoals", func(t *testing.T) {
+		require.NoError(t, settings.SaveWebsiteGoals(db, websiteID, []string{"newsletter_signup", "demo_requested"}))
+
+		result, err := analytics.FetchDashboardMetricsSubset(db, timeFrame, int(websiteID), nil, []string{"goal_conversions"}, 10, slog.Default())
+		require.NoError(t, err)
+
+		series, ok := result["goal_conversions"].([]analytics.TimeSeriesPoint)
+		require.True(t, ok)
+		require.Len(t, series, 2)
+		assert.Equal(t, 5, series[0].Count, "First day should count only newsletter signups")
+		assert.Equal(t, 10, series[1].Count, "Second day should count newsletter signups and demo requests")
+	})
+}
+
+func TestGetGoalConversionRates(t *testing.T) {
+	dbManager, _ := testsupport.SetupTestDBManager(t)
+	db := dbManager.GetConnection()
+	website := testsupport.CreateTestWebsite(db, "goal-rates.com")
+
+	timeFrame, err := timeframe.NewTimeFrame(timeframe.TimeFrameParams{
+		FromTime:      time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC),
+		ToTime:        time.Date(2024, 7, 2, 23, 59, 59, 0, time.UTC),
+		TimeFrameSize: timeframe.DailyTimeFrame,
+	}, time.UTC)
+	require.NoError(t, err)
+	queryParams := analytics.NewWebsiteScopedQueryParams(timeFrame, int(website.ID))
+
+	require.NoError(t, db.Create([]analytics.EventStat{
+		{WebsiteID: website.ID, EventName: "signup", EventKey: "signup", VisitorsCount: 5, Hour: time.Date(2024, 7, 1, 10, 0, 0, 0, time.UTC)},
+		{WebsiteID: website.ID, EventName: "signup", EventKey: "signup", VisitorsCount: 5, Hour: time.Date(2024, 7, 2, 10, 0, 0, 0, time.UTC)},
+		{WebsiteID: website.ID, EventName: "purchase", EventKey: "purchase", VisitorsCount: 2, Hour: time.Date(2024, 7, 1, 12, 0, 0, 0, time.UTC)},
+		// Outside the time frame
+		{WebsiteID: website.ID, EventName: "purchase", EventKey: "purchase", VisitorsCount: 50, Hour: time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)},
+	}).Error)
+
+	t.Run("returns zero rates when there are no visitors", func(t *testing.T) {
+		rates, err := analytics.GetGoalConversionRates(db, queryParams, []string{"signup", "purchase"})
+		require.NoError(t, err)
+		assert.Equal(t, map[string]float64{"signup": 0, "purchase": 0}, rates)
+	})
+
+	t.Run("returns an empty map without goals", func(t *testing.T) {
+		rates, err := analytics.GetGoalConversionRates(db, queryParams, nil)
+		require.NoError(t, err)
+		assert.Empty(t, rates)
+	})
+
+	t.Run("divides goal visitors by total visitors per goal", func(t *testing.T) {
+		require.NoError(t, db.Create([]analytics.SiteStat{
+			{WebsiteID: website.ID, Visitors: 30, PageViews: 60, Sessions: 35, Hour: time.Date(2024, 7, 1, 10, 0, 0, 0, time.UTC)},
+			{WebsiteID: website.ID, Visitors: 20, PageViews: 40, Sessions: 25, Hour: time.Date(2024, 7, 2, 10, 0, 0, 0, time.UTC)},
+		}).Error)
+
+		rates, err := analytics.GetGoalConversionRates(db, queryParams, []string{"signup", "purchase", "never_fired"})
+		require.NoError(t, err)
+		assert.InDelta(t, 20.0, rates["signup"], 0.001)
+		assert.InDelta(t, 4.0, rates["purchase"], 0.001)
+		assert.Equal(t, 0.0, rates["never_fired"])
+	})
 }
 
 // TestAggregatedRevenueInTimeFrame tests the revenue aggregation function
@@ -972,6 +1050,11 @@ func TestGetEventRevenueTotals(t *testing.T) {
 	assert.InDelta(t, 75.0, totals["revenue:purchased"], 0.01)
 	assert.InDelta(t, 15.0, totals["upsell:purchased"], 0.01)
 	assert.NotContains(t, totals, "revenue:ignored")
+
+	// $75 over the two revenue:purchased events with a price
+	averageOrderValue, err := analytics.GetAverageOrderValue(db, queryParams)
+	require.NoError(t, err)
+	assert.InDelta(t, 37.5, averageOrderValue, 0.01)
 }
 
 func TestGetEventRevenueTotalsEmpty(t *testing.T) {
@@ -990,4 +1073,110 @@ func TestGetEventRevenueTotalsEmpty(t *testing.T) {
 	totals, err := analytics.GetEventRevenueTotals(db, queryParams)
 	require.NoError(t, err)
 	assert.Empty(t, totals)
+
+	averageOrderValue, err := analytics.GetAverageOrderValue(db, queryParams)
+	require.NoError(t, err)
+	assert.Zero(t, averageOrderValue)
+}
+
+func TestGetTopCitiesAndRegionsInTimeFrame(t *testing.T) {
+	dbManager, logger := testsupport.SetupTestDBManager(t)
+	db := dbManager.GetConnection()
+	website := testsupport.CreateTestWebsite(db, "local-shop.com")
+	otherWebsite := testsupport.CreateTestWebsite(db, "other-shop.com")
+
+	day := time.Date(2024, 7, 1, 10, 0, 0, 0, time.UTC)
+	pageView := func(site websites.Website, visitor, country, region, city string, offset int) events.IngestedEvent {
+		ts := day.Add(time.Duration(offset) * time.Minute)
+		return events.IngestedEvent{
+			WebsiteID:        site.ID,
+			UserSignature:    visitor,
+			Hostname:         site.Domain,
+			Pathname:         "/",
+			RawURL:           "https://" + site.Domain + "/",
+			ReferrerHostname: events.DirectOrUnknownReferrer,
+			EventType:        events.EventTypePageView,
+			Timestamp:        ts,
+			UserAgent:        "Mozilla/5.0 (test)",
+			Country:          country,
+			Region:           region,
+			City:             city,
+			CreatedAt:        ts,
+		}
+	}
+	ingested := []events.IngestedEvent{
+		pageView(website, "visitor-1", "us", "Texas", "Austin", 0),
+		pageView(website, "visitor-2", "us", "Texas", "Austin", 1),
+		pageView(website, "visitor-3", "us", "Georgia", "Atlanta", 2),
+		pageView(website, "visitor-4", "ge", "Tbilisi", "Tbilisi", 3),
+		// No geo database: country only, never aggregated by region or city
+		pageView(website, "visitor-5", "us", "", "", 4),
+		pageView(otherWebsite, "visitor-6", "fr", "Île-de-France", "Paris", 5),
+	}
+	require.NoError(t, db.Create(&ingested).Error)
+	require.NoError(t, testsupport.ProcessAllTestEvents(dbManager, logger))
+
+	timeFrame := setupTimeFrame(t)
+	params := analytics.NewWebsiteScopedQueryParams(timeFrame, int(website.ID))
+
+	t.Run("cities are scoped to the website", func(t *testing.T) {
+		cities, err := analytics.GetTopCitiesInTimeFrame(db, params)
+		require.NoError(t, err)
+		require.Len(t, cities, 3)
+		assert.Equal(t, analytics.MetricCountResult{Name: "Austin, US", Count: 2}, cities[0])
+		assert.ElementsMatch(t, []analytics.MetricCountResult{
+			{Name: "Atlanta, US", Count: 1},
+			{Name: "Tbilisi, GE", Count: 1},
+		}, cities[1:])
+
+		otherCities, err := analytics.GetTopCitiesInTimeFrame(db, analytics.NewWebsiteScopedQueryParams(timeFrame, int(otherWebsite.ID)))
+		require.NoError(t, err)
+		assert.Equal(t, []analytics.MetricCountResult{{Name: "Paris, FR", Count: 1}}, otherCities)
+	})
+
+	t.Run("regions keep countries apart", func(t *testing.T) {
+		regions, err := analytics.GetTopRegionsInTimeFrame(db, params)
+		require.NoError(t, err)
+		require.Len(t, regions, 3)
+		assert.Equal(t, analytics.MetricCountResult{Name: "Texas, US", Count: 2}, regions[0])
+	})
+
+	t.Run("events without a location are not aggregated", func(t *testing.T) {
+		var count int64
+		require.NoError(t, db.Model(&analytics.CityStat{}).Where("city = ''").Count(&count).Error)
+		assert.Zero(t, count)
+	})
+}
+
+func TestGetTop404sInTimeFrame(t *testing.T) {
+	dbManager, _ := testsupport.SetupTestDBManager(t)
+	db := dbManager.GetConnection()
+	testsupport.CleanAllTables(db)
+
+	website := testsupport.CreateTestWebsite(db, "example.com")
+	baseTime := time.Date(2024, 7, 1, 12, 0, 0, 0, time.UTC)
+
+	pageView := func(path string, statusCode int) events.Event {
+		return events.Event{WebsiteID: website.ID, UserSignature: "visitor", Hostname: "example.com", Pathname: path,
+			EventType: events.EventTypePageView, StatusCode: statusCode, Timestamp: baseTime, CreatedAt: baseTime}
+	}
+	rows := []events.Event{
+		pageView("/old-post", 404),
+		pageView("/old-post", 404),
+		pageView("/typo", 404),
+		// Normal pages and other errors stay out of the list
+		pageView("/old-post", 0),
+		pageView("/home", 0),
+		pageView("/home", 0),
+		pageView("/home", 0),
+		pageView("/broken", 500),
+	}
+	require.NoError(t, db.Create(&rows).Error)
+
+	results, err := analytics.GetTop404sInTimeFrame(db, analytics.NewWebsiteScopedQueryParams(setupTimeFrame(t), int(website.ID)))
+	require.NoError(t, err)
+	assert.Equal(t, []analytics.MetricCountResult{
+		{Name: "example.com/old-post", Count: 2},
+		{Name: "example.com/typo", Count: 1},
+	}, results)
 }
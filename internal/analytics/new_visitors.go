@@ -0,0 +1,80 @@
+package analytics
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+
+	"fusionaly/internal/timeframe"
+)
+
+// NewVsReturning splits visitors into those who came once and those who came
+// back for another session. NewVisitors + ReturningVisitors equals
+// GetTotalVisitorsInTimeFrame for the same time frame.
+type NewVsReturning struct {
+	NewVisitors       int64
+	ReturningVisitors int64
+	NewSeries         []timeframe.DateStat
+	ReturningSeries   []timeframe.DateStat
+}
+
+// GetNewVsReturningInTimeFrame returns new and returning visitor totals and
+// time series from site_stats. Dimension tables don't track the split, so it
+// returns nil when segment filters are active.
+func GetNewVsReturningInTimeFrame(db *gorm.DB, params WebsiteScopedQueryParams) (*NewVsReturning, error) {
+	if len(params.Filters) > 0 {
+		return nil, nil
+	}
+
+	groupByExpression, err := params.TimeFrame.GetSQLiteGroupByExpression()
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []struct {
+		Date              string
+		NewVisitors       int
+		ReturningVisitors int
+	}
+	query := fmt.Sprintf(`
+        SELECT
+            %s AS date,
+            COALESCE(SUM(new_visitors), 0) AS new_visitors,
+            COALESCE(SUM(returning_visitors), 0) AS returning_visitors
+        FROM site_stats
+        WHERE hour >= ? AND hour <= ?
+        AND website_id = ?
+        GROUP BY %s
+        ORDER BY date ASC
+    `, groupByExpression, groupByExpression)
+
+	err = db.Raw(query, params.TimeFrame.From.UTC(), params.TimeFrame.To.UTC(), params.WebsiteID).Scan(&rows).Error
+	if err != nil {
+		return nil, fmt.Errorf("error fetching new vs returning visitors: %w", err)
+	}
+
+	result := &NewVsReturning{}
+	newStats := make([]timeframe.DateStat, len(rows))
+	returningStats := make([]timeframe.DateStat, len(rows))
+	for i, row := range rows {
+		result.NewVisitors += int64(row.NewVisitors)
+		result.ReturningVisitors += int64(row.ReturningVisitors)
+		newStats[i] = timeframe.DateStat{Date: row.Date, Count: row.NewVisitors}
+		returningStats[i] = timeframe.DateStat{Date: row.Date, Count: row.ReturningVisitors}
+	}
+	result.NewSeries = params.TimeFrame.BuildTimeSeriesPoints(newStats)
+	result.ReturningSeries = params.TimeFrame.BuildTimeSeriesPoints(returningStats)
+
+	return result, nil
+}
+
+// BackfillNewVisitors counts visitors aggregated before new and returning
+// visitors were tracked as new, so the split adds up for older periods too.
+// Safe to run on every boot.
+func BackfillNewVisitors(db *gorm.DB) error {
+	return db.Exec(`
+        UPDATE site_stats
+        SET new_visitors = visitors - returning_visitors
+        WHERE new_visitors + returning_visitors != visitors
+    `).Error
+}
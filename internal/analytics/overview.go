@@ -0,0 +1,89 @@
+package analytics
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+
+	"fusionaly/internal/events"
+	"fusionaly/internal/timeframe"
+)
+
+// SiteTotals holds the core totals of one website, or of all of them
+type SiteTotals struct {
+	WebsiteID uint    `json:"website_id,omitempty"`
+	Domain    string  `json:"domain,omitempty"`
+	Visitors  int64   `json:"visitors"`
+	PageViews int64   `json:"page_views"`
+	Sessions  int64   `json:"sessions"`
+	Revenue   float64 `json:"revenue"`
+}
+
+// SitesOverview is the "all sites" rollup: one row per website plus the grand total
+type SitesOverview struct {
+	Sites []SiteTotals `json:"sites"`
+	Total SiteTotals   `json:"total"`
+}
+
+// GetSitesOverviewInTimeFrame computes visitors, page views, sessions and
+// revenue for every website in one grouped query over site_stats (revenue
+// comes from revenue:purchased events, like GetRevenueMetrics). Websites
+// without traffic are included with zero totals.
+func GetSitesOverviewInTimeFrame(db *gorm.DB, tf *timeframe.TimeFrame) (*SitesOverview, error) {
+	var rows []SiteTotals
+
+	query := `
+		SELECT
+			w.id AS website_id,
+			w.domain AS domain,
+			COALESCE(s.visitors, 0) AS visitors,
+			COALESCE(s.page_views, 0) AS page_views,
+			COALESCE(s.sessions, 0) AS sessions,
+			COALESCE(r.revenue, 0) AS revenue
+		FROM websites w
+		LEFT JOIN (
+			SELECT
+				website_id,
+				SUM(visitors) AS visitors,
+				SUM(page_views) AS page_views,
+				SUM(sessions) AS sessions
+			FROM site_stats
+			WHERE hour BETWEEN ? AND ?
+			GROUP BY website_id
+		) s ON s.website_id = w.id
+		LEFT JOIN (
+			SELECT
+				website_id,
+				SUM(
+					(CAST(json_extract(custom_event_meta, '$.price') AS REAL) / 100.0) *
+					COALESCE(CAST(json_extract(custom_event_meta, '$.quantity') AS INTEGER), 1)
+				) AS revenue
+			FROM events
+			WHERE timestamp BETWEEN ? AND ?
+			AND event_type = ?
+			AND LOWER(custom_event_name) LIKE 'revenue:purchased'
+			AND json_valid(custom_event_meta) = 1
+			AND json_extract(custom_event_meta, '$.price') IS NOT NULL
+			AND CAST(json_extract(custom_event_meta, '$.price') AS REAL) > 0
+			GROUP BY website_id
+		) r ON r.website_id = w.id
+		ORDER BY visitors DESC, page_views DESC, w.domain
+	`
+
+	from, to := tf.From.UTC(), tf.To.UTC()
+	if err := db.Raw(query, from, to, from, to, events.EventTypeCustomEvent).Scan(&rows).Error; err != nil {
+		return nil, fmt.Errorf("error calculating sites overview: %w", err)
+	}
+
+	overview := &SitesOverview{Sites: rows}
+	if overview.Sites == nil {
+		overview.Sites = []SiteTotals{}
+	}
+	for _, row := range overview.Sites {
+		overview.Total.Visitors += row.Visitors
+		overview.Total.PageViews += row.PageViews
+		overview.Total.Sessions += row.Sessions
+		overview.Total.Revenue += row.Revenue
+	}
+	return overview, nil
+}
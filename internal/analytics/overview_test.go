@@ -0,0 +1,67 @@
+package analytics_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"fusionaly/internal/analytics"
+	"fusionaly/internal/events"
+	"fusionaly/internal/testsupport"
+	"fusionaly/internal/timeframe"
+)
+
+func TestGetSitesOverviewInTimeFrame(t *testing.T) {
+	dbManager, _ := testsupport.SetupTestDBManager(t)
+	db := dbManager.GetConnection()
+	testsupport.CleanAllTables(db)
+
+	shop := testsupport.CreateTestWebsite(db, "shop.com")
+	blog := testsupport.CreateTestWebsite(db, "blog.com")
+	idle := testsupport.CreateTestWebsite(db, "idle.com")
+
+	day := time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC)
+	siteStats := []analytics.SiteStat{
+		{WebsiteID: shop.ID, Visitors: 10, PageViews: 30, Sessions: 12, Hour: day.Add(9 * time.Hour)},
+		{WebsiteID: shop.ID, Visitors: 5, PageViews: 8, Sessions: 6, Hour: day.Add(15 * time.Hour)},
+		{WebsiteID: blog.ID, Visitors: 7, PageViews: 9, Sessions: 7, Hour: day.Add(10 * time.Hour)},
+		// Outside the time frame
+		{WebsiteID: blog.ID, Visitors: 100, PageViews: 100, Sessions: 100, Hour: day.AddDate(0, 0, 5)},
+	}
+	require.NoError(t, db.CreateInBatches(siteStats, len(siteStats)).Error)
+
+	purchases := []events.Event{
+		{WebsiteID: shop.ID, UserSignature: "buyer-1", Hostname: shop.Domain, Pathname: "/checkout", EventType: events.EventTypeCustomEvent,
+			CustomEventName: "revenue:purchased", CustomEventMeta: `{"price": 2500, "quantity": 2}`, Timestamp: day.Add(9 * time.Hour), CreatedAt: day},
+		{WebsiteID: blog.ID, UserSignature: "buyer-2", Hostname: blog.Domain, Pathname: "/tip", EventType: events.EventTypeCustomEvent,
+			CustomEventName: "revenue:purchased", CustomEventMeta: `{"price": 500}`, Timestamp: day.Add(11 * time.Hour), CreatedAt: day},
+	}
+	require.NoError(t, db.Create(&purchases).Error)
+
+	tf, err := timeframe.NewTimeFrame(timeframe.TimeFrameParams{
+		FromTime:      day,
+		ToTime:        day.Add(24*time.Hour - time.Second),
+		TimeFrameSize: timeframe.DailyTimeFrame,
+	}, time.UTC)
+	require.NoError(t, err)
+
+	overview, err := analytics.GetSitesOverviewInTimeFrame(db, tf)
+	require.NoError(t, err)
+	require.Len(t, overview.Sites, 3)
+
+	assert.Equal(t, analytics.SiteTotals{WebsiteID: shop.ID, Domain: "shop.com", Visitors: 15, PageViews: 38, Sessions: 18, Revenue: 50}, overview.Sites[0])
+	assert.Equal(t, analytics.SiteTotals{WebsiteID: blog.ID, Domain: "blog.com", Visitors: 7, PageViews: 9, Sessions: 7, Revenue: 5}, overview.Sites[1])
+	assert.Equal(t, analytics.SiteTotals{WebsiteID: idle.ID, Domain: "idle.com"}, overview.Sites[2])
+
+	var sum analytics.SiteTotals
+	for _, site := range overview.Sites {
+		sum.Visitors += site.Visitors
+		sum.PageViews += site.PageViews
+		sum.Sessions += site.Sessions
+		sum.Revenue += site.Revenue
+	}
+	assert.Equal(t, sum, overview.Total)
+	assert.Equal(t, analytics.SiteTotals{Visitors: 22, PageViews: 47, Sessions: 25, Revenue: 55}, overview.Total)
+}
@@ -30,26 +30,24 @@ func aggregatedPageViewsInTimeFrameRaw(db *gorm.DB, params WebsiteScopedQueryPar
 	}
 
 	// Use a more reliable query format that works better with SQLite's date handling
+	source := siteSource(db, params)
 	query := fmt.Sprintf(`
         SELECT
             %s AS date,
-            COALESCE(SUM(page_views), 0) AS count
+            COALESCE(SUM(%s), 0) AS count
         FROM
-            site_stats
+            %s
         WHERE
             hour >= ? AND hour <= ?
-            AND website_id = ?
+            AND website_id = ?%s
         GROUP BY
             %s
         ORDER BY
             date ASC
-    `, groupByExpression, groupByExpression)
+    `, groupByExpression, source.PageViews, source.Table, source.Where, groupByExpression)
 	// Execute query
-	err = db.Raw(query,
-		params.TimeFrame.From.UTC(),
-		params.TimeFrame.To.UTC(),
-		params.WebsiteID,
-	).Scan(&results).Error
+	args := append([]interface{}{params.TimeFrame.From.UTC(), params.TimeFrame.To.UTC(), params.WebsiteID}, source.Args...)
+	err = db.Raw(query, args...).Scan(&results).Error
 	if err != nil {
 		return nil, fmt.Errorf("error fetching aggregated page views from SiteStat: %w", err)
 	}
@@ -84,6 +82,7 @@ type PageStat struct {
 	VisitorsCount  int       `gorm:"not null;default:0"`
 	Entrances      int       `gorm:"not null;default:0"`
 	Exits          int       `gorm:"not null;default:0"`
+	BounceCount    int       `gorm:"not null;default:0"`
 	Hour           time.Time `gorm:"uniqueIndex:idx_page_unique;type:datetime;not null"`
 	CreatedAt      time.Time
 	UpdatedAt      time.Time
@@ -1,16 +1,31 @@
 package analytics
 
 import (
+	"strings"
 	"time"
 
 	"fusionaly/internal/timeframe"
 )
 
+// Bounds of the number of entries shown in each dashboard top-N list
+const (
+	DefaultTopLimit = 50
+	MinTopLimit     = 5
+	MaxTopLimit     = 100
+)
+
+// ClampTopLimit brings limit within MinTopLimit and MaxTopLimit
+func ClampTopLimit(limit int) int {
+	return min(max(limit, MinTopLimit), MaxTopLimit)
+}
+
 // WebsiteScopedQueryParams contains common parameters for website-scoped queries
 type WebsiteScopedQueryParams struct {
 	TimeFrame *timeframe.TimeFrame
 	WebsiteID int
-	Limit     int               // Number of records to return
+	Limit     int               // Number of records to return (-1 for all)
+	Offset    int               // Number of records to skip, for paging through top-N lists
+	Search    string            // Case-insensitive substring the name of top-N list entries must contain
 	Filters   map[string]string // Dynamic filters (e.g., {"country": "US", "browser": "Chrome"})
 }
 
@@ -28,7 +43,7 @@ func NewWebsiteScopedQueryParams(timeFrame *timeframe.TimeFrame, websiteID int)
 		return WebsiteScopedQueryParams{
 			TimeFrame: defaultTimeFrame,
 			WebsiteID: websiteID,
-			Limit:     50, // Default limit
+			Limit:     DefaultTopLimit,
 			Filters:   make(map[string]string),
 		}
 	}
@@ -36,7 +51,38 @@ func NewWebsiteScopedQueryParams(timeFrame *timeframe.TimeFrame, websiteID int)
 	return WebsiteScopedQueryParams{
 		TimeFrame: timeFrame,
 		WebsiteID: websiteID,
-		Limit:     50, // Default limit
+		Limit:     DefaultTopLimit,
 		Filters:   make(map[string]string),
 	}
 }
+
+// likeEscaper escapes LIKE wildcards so searches match them literally
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// searchClause returns an " AND ..." clause matching expr against params.Search
+// case-insensitively, or "" when there is no search.
+func searchClause(params WebsiteScopedQueryParams, expr string) (string, []interface{}) {
+	if params.Search == "" {
+		return "", nil
+	}
+	pattern := "%" + likeEscaper.Replace(strings.ToLower(params.Search)) + "%"
+	return " AND LOWER(" + expr + `) LIKE ? ESCAPE '\'`, []interface{}{pattern}
+}
+
+// matchesSearch reports whether name contains params.Search, for lists
+// filtered after being normalized in Go.
+func matchesSearch(params WebsiteScopedQueryParams, name string) bool {
+	return params.Search == "" || strings.Contains(strings.ToLower(name), strings.ToLower(params.Search))
+}
+
+// pageResults applies params.Offset and params.Limit to results sorted in Go
+func pageResults[T any](results []T, params WebsiteScopedQueryParams) []T {
+	if params.Offset >= len(results) {
+		return []T{}
+	}
+	results = results[params.Offset:]
+	if params.Limit >= 0 && len(results) > params.Limit {
+		results = results[:params.Limit]
+	}
+	return results
+}
@@ -0,0 +1,69 @@
+package analytics
+
+import (
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// propertyPath builds the SQLite JSON path of a custom property key. The key
+// is quoted so dots and brackets in it aren't read as path syntax.
+func propertyPath(key string) string {
+	return `$."` + strings.ReplaceAll(key, `"`, `\"`) + `"`
+}
+
+// GetTopPropertyValuesInTimeFrame returns how many visitors sent each value of
+// a custom property (the properties map of CreateEventParams, stored as JSON
+// on the event), most common first. Events without the property are left
+// out, so the counts only cover visitors who sent it.
+func GetTopPropertyValuesInTimeFrame(db *gorm.DB, params WebsiteScopedQueryParams, key string) ([]MetricCountResult, error) {
+	results := []MetricCountResult{}
+	key = strings.TrimSpace(key)
+	if key == "" {
+		return results, nil
+	}
+
+	path := propertyPath(key)
+	search, searchArgs := searchClause(params, "name")
+	query := fmt.Sprintf(`
+		SELECT
+			json_extract(properties, ?) AS name,
+			COUNT(DISTINCT user_signature) AS count
+		FROM events
+		WHERE timestamp BETWEEN ? AND ?
+		AND website_id = ?
+		AND properties != ''
+		AND json_valid(properties) = 1
+		GROUP BY name
+		HAVING name IS NOT NULL AND name != ''%s
+		ORDER BY count DESC, name
+		LIMIT ? OFFSET ?
+	`, search)
+
+	args := append([]interface{}{path, params.TimeFrame.From.UTC(), params.TimeFrame.To.UTC(), params.WebsiteID}, searchArgs...)
+	args = append(args, params.Limit, params.Offset)
+	if err := db.Raw(query, args...).Scan(&results).Error; err != nil {
+		return nil, fmt.Errorf("error fetching top values of property %q: %w", key, err)
+	}
+	return results, nil
+}
+
+// GetPropertyKeysInTimeFrame lists the custom property keys sent to the
+// website in the time frame, alphabetically, so the dashboard can offer them.
+func GetPropertyKeysInTimeFrame(db *gorm.DB, params WebsiteScopedQueryParams) ([]string, error) {
+	keys := []string{}
+	query := `
+		SELECT DISTINCT p.key
+		FROM events, json_each(CASE WHEN json_valid(events.properties) = 1 THEN events.properties ELSE '{}' END) AS p
+		WHERE events.timestamp BETWEEN ? AND ?
+		AND events.website_id = ?
+		AND events.properties != ''
+		ORDER BY p.key
+		LIMIT 100
+	`
+	if err := db.Raw(query, params.TimeFrame.From.UTC(), params.TimeFrame.To.UTC(), params.WebsiteID).Scan(&keys).Error; err != nil {
+		return nil, fmt.Errorf("error fetching property keys: %w", err)
+	}
+	return keys, nil
+}
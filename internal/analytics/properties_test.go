@@ -0,0 +1,84 @@
+package analytics_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"fusionaly/internal/analytics"
+	"fusionaly/internal/events"
+	"fusionaly/internal/testsupport"
+	"fusionaly/internal/timeframe"
+)
+
+func TestGetTopPropertyValuesInTimeFrame(t *testing.T) {
+	dbManager, _ := testsupport.SetupTestDBManager(t)
+	db := dbManager.GetConnection()
+	testsupport.CleanAllTables(db)
+
+	website := testsupport.CreateTestWebsite(db, "props.com")
+	other := testsupport.CreateTestWebsite(db, "other.com")
+	day := time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC)
+
+	pageView := func(websiteID uint, visitor, properties string, at time.Time) events.Event {
+		return events.Event{WebsiteID: websiteID, UserSignature: visitor, Hostname: "props.com", Pathname: "/",
+			EventType: events.EventTypePageView, Properties: properties, Timestamp: at, CreatedAt: at}
+	}
+	rows := []events.Event{
+		pageView(website.ID, "v1", `{"account_tier":"pro","plan_interval":"yearly"}`, day.Add(9*time.Hour)),
+		pageView(website.ID, "v1", `{"account_tier":"pro","plan_interval":"yearly"}`, day.Add(10*time.Hour)),
+		pageView(website.ID, "v2", `{"account_tier":"pro"}`, day.Add(11*time.Hour)),
+		pageView(website.ID, "v3", `{"account_tier":"free","plan_interval":"monthly"}`, day.Add(12*time.Hour)),
+		// No properties at all, or none of the requested key
+		pageView(website.ID, "v4", "", day.Add(13*time.Hour)),
+		pageView(website.ID, "v5", `{"plan_interval":"monthly"}`, day.Add(14*time.Hour)),
+		// Outside the time frame, and another website
+		pageView(website.ID, "v6", `{"account_tier":"enterprise"}`, day.AddDate(0, 0, 3)),
+		pageView(other.ID, "v7", `{"account_tier":"enterprise"}`, day.Add(9*time.Hour)),
+	}
+	require.NoError(t, db.Create(&rows).Error)
+
+	tf, err := timeframe.NewTimeFrame(timeframe.TimeFrameParams{
+		FromTime:      day,
+		ToTime:        day.Add(24*time.Hour - time.Second),
+		TimeFrameSize: timeframe.DailyTimeFrame,
+	}, time.UTC)
+	require.NoError(t, err)
+	params := analytics.NewWebsiteScopedQueryParams(tf, int(website.ID))
+
+	t.Run("account tier counts distinct visitors per value", func(t *testing.T) {
+		results, err := analytics.GetTopPropertyValuesInTimeFrame(db, params, "account_tier")
+		require.NoError(t, err)
+		assert.Equal(t, []analytics.MetricCountResult{
+			{Name: "pro", Count: 2},
+			{Name: "free", Count: 1},
+		}, results)
+	})
+
+	t.Run("visitors missing the key are left out", func(t *testing.T) {
+		results, err := analytics.GetTopPropertyValuesInTimeFrame(db, params, "plan_interval")
+		require.NoError(t, err)
+		assert.Equal(t, []analytics.MetricCountResult{
+			{Name: "monthly", Count: 2},
+			{Name: "yearly", Count: 1},
+		}, results)
+	})
+
+	t.Run("unknown or empty key", func(t *testing.T) {
+		results, err := analytics.GetTopPropertyValuesInTimeFrame(db, params, "company_size")
+		require.NoError(t, err)
+		assert.Empty(t, results)
+
+		results, err = analytics.GetTopPropertyValuesInTimeFrame(db, params, " ")
+		require.NoError(t, err)
+		assert.Empty(t, results)
+	})
+
+	t.Run("keys seen in the time frame", func(t *testing.T) {
+		keys, err := analytics.GetPropertyKeysInTimeFrame(db, params)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"account_tier", "plan_interval"}, keys)
+	})
+}
@@ -1,14 +1,20 @@
 package analytics
 
 import (
+	"context"
+	"fmt"
+
 	"gorm.io/gorm"
+
+	"fusionaly/internal/pkg/async"
 )
 
 // GetTopQueryParamValuesInTimeFrame fetches top values for a specific query parameter
 func GetTopQueryParamValuesInTimeFrame(db *gorm.DB, params WebsiteScopedQueryParams, paramName string) ([]MetricCountResult, error) {
 	var results []MetricCountResult
 
-	query := `
+	search, searchArgs := searchClause(params, "param_value")
+	query := fmt.Sprintf(`
 		SELECT 
 			param_value AS name, 
 			SUM(visitors_count) AS count
@@ -16,23 +22,56 @@ func GetTopQueryParamValuesInTimeFrame(db *gorm.DB, params WebsiteScopedQueryPar
 		WHERE hour BETWEEN ? AND ?
         AND website_id = ?
         AND param_name = ?
-        AND param_value != ''
+        AND param_value != ''%s
 		GROUP BY param_value
 		HAVING count > 0
 		ORDER BY count DESC
-		LIMIT ?
-	`
-
-	err := db.Raw(query,
-		params.TimeFrame.From.UTC(),
-		params.TimeFrame.To.UTC(),
-		params.WebsiteID,
-		paramName,
-		params.Limit,
-	).Scan(&results).Error
+		LIMIT ? OFFSET ?
+	`, search)
+
+	args := append([]interface{}{params.TimeFrame.From.UTC(), params.TimeFrame.To.UTC(), params.WebsiteID, paramName}, searchArgs...)
+	args = append(args, params.Limit, params.Offset)
+	err := db.Raw(query, args...).Scan(&results).Error
 	if err != nil {
 		return []MetricCountResult{}, nil
 	}
 
 	return results, nil
 }
+
+// RefQueryParam is the query parameter the dashboard always breaks down
+const RefQueryParam = "ref"
+
+// QueryParamBreakdowns maps query parameter names to their top values
+type QueryParamBreakdowns map[string][]MetricCountResult
+
+// GetTopQueryParamBreakdowns runs GetTopQueryParamValuesInTimeFrame for ref
+// and each of paramNames in parallel, keyed by parameter name. Every name gets
+// an entry, empty when the parameter wasn't seen.
+func GetTopQueryParamBreakdowns(db *gorm.DB, params WebsiteScopedQueryParams, paramNames []string) (QueryParamBreakdowns, error) {
+	names := []string{RefQueryParam}
+	for _, name := range paramNames {
+		if name != RefQueryParam {
+			names = append(names, name)
+		}
+	}
+
+	tasks := make([]async.Task, 0, len(names))
+	for _, name := range names {
+		tasks = append(tasks, passthroughTask(name, func() (interface{}, error) {
+			return GetTopQueryParamValuesInTimeFrame(db, params, name)
+		}))
+	}
+
+	results := async.NewPool(4).Execute(context.Background(), tasks)
+	breakdowns := make(QueryParamBreakdowns, len(names))
+	for _, name := range names {
+		result := results[name]
+		if result.Err != nil {
+			return nil, fmt.Errorf("error fetching values of query parameter %q: %w", name, result.Err)
+		}
+		values, _ := result.Data.([]MetricCountResult)
+		breakdowns[name] = ensureNonNil(values)
+	}
+	return breakdowns, nil
+}
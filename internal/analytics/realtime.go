@@ -0,0 +1,72 @@
+package analytics
+
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// RealtimeMinute holds the distinct visitors seen during one minute
+type RealtimeMinute struct {
+	Minute   time.Time `json:"minute"`
+	Visitors int64     `json:"visitors"`
+}
+
+// GetActiveVisitors counts distinct visitors with an event in the last window.
+// It reads the raw events table so it is accurate to the second.
+func GetActiveVisitors(db *gorm.DB, websiteID int, window time.Duration) (int64, error) {
+	var count int64
+	err := db.Raw(`
+        SELECT COUNT(DISTINCT user_signature)
+        FROM events
+        WHERE website_id = ?
+        AND timestamp >= ?
+    `, websiteID, time.Now().UTC().Add(-window)).Scan(&count).Error
+	if err != nil {
+		return 0, fmt.Errorf("failed to count active visitors: %w", err)
+	}
+	return count, nil
+}
+
+// GetActiveVisitorsByMinute returns distinct visitors per minute for the last
+// minutes minutes, oldest first, including minutes without visitors.
+func GetActiveVisitorsByMinute(db *gorm.DB, websiteID int, minutes int) ([]RealtimeMinute, error) {
+	now := time.Now().UTC()
+	first := now.Truncate(time.Minute).Add(-time.Duration(minutes-1) * time.Minute)
+
+	var rows []struct {
+		UserSignature string
+		Timestamp     time.Time
+	}
+	err := db.Raw(`
+        SELECT user_signature, timestamp
+        FROM events
+        WHERE website_id = ?
+        AND timestamp >= ?
+    `, websiteID, first).Scan(&rows).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to query realtime events: %w", err)
+	}
+
+	seen := make([]map[string]struct{}, minutes)
+	for _, row := range rows {
+		i := int(row.Timestamp.UTC().Sub(first) / time.Minute)
+		if i < 0 || i >= minutes {
+			continue
+		}
+		if seen[i] == nil {
+			seen[i] = make(map[string]struct{})
+		}
+		seen[i][row.UserSignature] = struct{}{}
+	}
+
+	breakdown := make([]RealtimeMinute, minutes)
+	for i := range breakdown {
+		breakdown[i] = RealtimeMinute{
+			Minute:   first.Add(time.Duration(i) * time.Minute),
+			Visitors: int64(len(seen[i])),
+		}
+	}
+	return breakdown, nil
+}
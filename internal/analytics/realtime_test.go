@@ -0,0 +1,57 @@
+package analytics_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"fusionaly/internal/analytics"
+	"fusionaly/internal/events"
+	"fusionaly/internal/testsupport"
+)
+
+func TestActiveVisitors(t *testing.T) {
+	dbManager, _ := testsupport.SetupTestDBManager(t)
+	db := dbManager.GetConnection()
+	website := testsupport.CreateTestWebsite(db, "realtime.com")
+	otherWebsite := testsupport.CreateTestWebsite(db, "other-realtime.com")
+
+	now := time.Now().UTC()
+	event := func(websiteID uint, visitor string, ago time.Duration) events.Event {
+		return events.Event{WebsiteID: websiteID, UserSignature: visitor, Hostname: "realtime.com", Pathname: "/", EventType: events.EventTypePageView, Timestamp: now.Add(-ago)}
+	}
+	seeded := []events.Event{
+		event(website.ID, "a", 30*time.Second),
+		event(website.ID, "a", 2*time.Minute), // same visitor counted once
+		event(website.ID, "b", 4*time.Minute),
+		event(website.ID, "c", 10*time.Minute),
+		event(website.ID, "d", 2*time.Hour),
+		event(otherWebsite.ID, "e", time.Minute),
+	}
+	require.NoError(t, db.Create(&seeded).Error)
+
+	t.Run("counts distinct visitors within the window", func(t *testing.T) {
+		active, err := analytics.GetActiveVisitors(db, int(website.ID), 5*time.Minute)
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), active)
+
+		active, err = analytics.GetActiveVisitors(db, int(website.ID), 15*time.Minute)
+		require.NoError(t, err)
+		assert.Equal(t, int64(3), active)
+	})
+
+	t.Run("breaks down the last minutes", func(t *testing.T) {
+		breakdown, err := analytics.GetActiveVisitorsByMinute(db, int(website.ID), 30)
+		require.NoError(t, err)
+		require.Len(t, breakdown, 30)
+		assert.True(t, breakdown[0].Minute.Before(breakdown[29].Minute))
+
+		var total int64
+		for _, minute := range breakdown {
+			total += minute.Visitors
+		}
+		assert.Equal(t, int64(4), total, "a, a, b and c fall in distinct minutes; d is too old")
+	})
+}
@@ -82,12 +82,20 @@ var DirectReferrerKeywords = []string{
 	"", "direct / unknown", "(direct)", "unknown", events.DirectOrUnknownReferrer,
 }
 
+// SelfReferralName is how self-referrals are shown when they're recorded
+// apart from direct traffic
+const SelfReferralName = "Self-referral"
+
 // NormalizeReferrerHostname cleans and normalizes a referrer hostname
 func NormalizeReferrerHostname(hostname string) string {
 	if hostname == "" {
 		return "Direct / Unknown"
 	}
 
+	if hostname == events.SelfReferral {
+		return SelfReferralName
+	}
+
 	// Convert to lowercase for consistent matching
 	lowerHostname := strings.ToLower(hostname)
 
@@ -127,15 +135,16 @@ func GetTopReferrersInTimeFrame(db *gorm.DB, params WebsiteScopedQueryParams) ([
 	}
 
 	// Simple query to get raw hostname data
-	query := `
+	segment, segmentArgs := segmentClause(db, params, "ref_stats")
+	query := fmt.Sprintf(`
 		SELECT hostname, SUM(visitors_count) as count
 		FROM ref_stats
 		WHERE hour BETWEEN ? AND ?
-		AND website_id = ?
+		AND website_id = ?%s
 		GROUP BY hostname
 		HAVING count > 0
 		ORDER BY count DESC
-	`
+	`, segment)
 
 	type RawReferrerResult struct {
 		Hostname string
@@ -143,11 +152,8 @@ func GetTopReferrersInTimeFrame(db *gorm.DB, params WebsiteScopedQueryParams) ([
 	}
 
 	var rawResults []RawReferrerResult
-	err := db.Raw(query,
-		params.TimeFrame.From.UTC(),
-		params.TimeFrame.To.UTC(),
-		params.WebsiteID,
-	).Scan(&rawResults).Error
+	args := append([]interface{}{params.TimeFrame.From.UTC(), params.TimeFrame.To.UTC(), params.WebsiteID}, segmentArgs...)
+	err := db.Raw(query, args...).Scan(&rawResults).Error
 	if err != nil {
 		return nil, fmt.Errorf("error fetching raw referrer data: %w", err)
 	}
@@ -163,6 +169,9 @@ func GetTopReferrersInTimeFrame(db *gorm.DB, params WebsiteScopedQueryParams) ([
 
 		// Normalize the hostname
 		normalized := NormalizeReferrerHostname(result.Hostname)
+		if !matchesSearch(params, normalized) {
+			continue
+		}
 		normalizedCounts[normalized] += result.Count
 	}
 
@@ -180,9 +189,5 @@ func GetTopReferrersInTimeFrame(db *gorm.DB, params WebsiteScopedQueryParams) ([
 		return results[i].Count > results[j].Count
 	})
 
-	if len(results) > params.Limit {
-		results = results[:params.Limit]
-	}
-
-	return results, nil
+	return pageResults(results, params), nil
 }
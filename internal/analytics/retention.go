@@ -0,0 +1,106 @@
+package analytics
+
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// MaxRetentionWeeks caps how many weekly cohorts can be requested
+const MaxRetentionWeeks = 52
+
+// RetentionCohort groups visitors by the week (Monday, UTC) of their first event.
+// Retention[n] is the percentage of the cohort active n weeks later, so
+// Retention[0] is always 100 and newer cohorts have fewer entries.
+type RetentionCohort struct {
+	WeekStart time.Time `json:"week_start"`
+	Visitors  int64     `json:"visitors"`
+	Retention []float64 `json:"retention"`
+}
+
+// weekStart returns the Monday 00:00 UTC of t's week
+func weekStart(t time.Time) time.Time {
+	t = t.UTC()
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	offset := (int(day.Weekday()) + 6) % 7
+	return day.AddDate(0, 0, -offset)
+}
+
+// GetRetentionCohorts returns weekly retention for the last weeks cohorts,
+// oldest first, including the current week.
+func GetRetentionCohorts(db *gorm.DB, websiteID int, weeks int) ([]RetentionCohort, error) {
+	if weeks < 1 || weeks > MaxRetentionWeeks {
+		return nil, fmt.Errorf("weeks must be between 1 and %d", MaxRetentionWeeks)
+	}
+
+	start := weekStart(time.Now()).AddDate(0, 0, -7*(weeks-1))
+
+	var rows []struct {
+		UserSignature string
+		Timestamp     time.Time
+	}
+	// Only visitors whose first-ever event falls in the range join a cohort
+	err := db.Raw(`
+        SELECT e.user_signature, e.timestamp
+        FROM events e
+        JOIN (
+            SELECT user_signature, MIN(timestamp) as first_seen
+            FROM events
+            WHERE website_id = ?
+            GROUP BY user_signature
+            HAVING MIN(timestamp) >= ?
+        ) f ON f.user_signature = e.user_signature
+        WHERE e.website_id = ?
+    `, websiteID, start, websiteID).Scan(&rows).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to query retention events: %w", err)
+	}
+
+	cohorts := make([]RetentionCohort, weeks)
+	active := make([][]map[string]struct{}, weeks)
+	for i := range cohorts {
+		cohorts[i] = RetentionCohort{
+			WeekStart: start.AddDate(0, 0, 7*i),
+			Retention: make([]float64, weeks-i),
+		}
+		active[i] = make([]map[string]struct{}, weeks-i)
+		for n := range active[i] {
+			active[i][n] = make(map[string]struct{})
+		}
+	}
+
+	// SQLite returns MIN(timestamp) as text, so derive first visits here
+	firstSeen := make(map[string]time.Time)
+	for _, row := range rows {
+		if seen, ok := firstSeen[row.UserSignature]; !ok || row.Timestamp.Before(seen) {
+			firstSeen[row.UserSignature] = row.Timestamp
+		}
+	}
+
+	for _, row := range rows {
+		first := firstSeen[row.UserSignature]
+		cohort := int(weekStart(first).Sub(start).Hours() / (24 * 7))
+		if cohort < 0 || cohort >= weeks {
+			continue
+		}
+		offset := int(weekStart(row.Timestamp).Sub(weekStart(first)).Hours() / (24 * 7))
+		if offset < 0 || offset >= len(active[cohort]) {
+			continue
+		}
+		active[cohort][offset][row.UserSignature] = struct{}{}
+	}
+
+	for i := range cohorts {
+		size := int64(len(active[i][0]))
+		cohorts[i].Visitors = size
+		if size == 0 {
+			continue
+		}
+		for n := range cohorts[i].Retention {
+			cohorts[i].Retention[n] = float64(len(active[i][n])) / float64(size) * 100
+		}
+	}
+
+	return cohorts, nil
+}
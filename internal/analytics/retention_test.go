@@ -0,0 +1,70 @@
+package analytics_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"fusionaly/internal/analytics"
+	"fusionaly/internal/events"
+	"fusionaly/internal/testsupport"
+)
+
+func TestGetRetentionCohorts(t *testing.T) {
+	dbManager, _ := testsupport.SetupTestDBManager(t)
+	db := dbManager.GetConnection()
+	website := testsupport.CreateTestWebsite(db, "retention.com")
+	otherWebsite := testsupport.CreateTestWebsite(db, "other-retention.com")
+
+	now := time.Now().UTC()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	thisWeek := today.AddDate(0, 0, -((int(today.Weekday()) + 6) % 7))
+	week := func(n int) time.Time { return thisWeek.AddDate(0, 0, 7*n) }
+
+	event := func(websiteID uint, visitor string, ts time.Time) events.Event {
+		return events.Event{WebsiteID: websiteID, UserSignature: visitor, Hostname: "retention.com", Pathname: "/", EventType: events.EventTypePageView, Timestamp: ts}
+	}
+	seeded := []events.Event{
+		// Cohort two weeks ago
+		event(website.ID, "a1", week(-2)),
+		event(website.ID, "a1", week(-1).Add(time.Hour)),
+		event(website.ID, "a1", week(0)),
+		event(website.ID, "a2", week(-2).Add(48*time.Hour)),
+		event(website.ID, "a2", week(-1).Add(24*time.Hour)),
+		event(website.ID, "a3", week(-2).Add(time.Hour)), // single event
+		event(website.ID, "a4", week(-2).Add(2*time.Hour)),
+		event(website.ID, "a4", week(0)),
+		// Cohort last week
+		event(website.ID, "b1", week(-1)),
+		event(website.ID, "b1", week(0)),
+		event(website.ID, "b2", week(-1).Add(time.Hour)), // single event
+		// First seen before the range, so never part of a cohort
+		event(website.ID, "old", week(-5)),
+		event(website.ID, "old", week(-1)),
+		// Other websites are ignored
+		event(otherWebsite.ID, "x", week(-2)),
+		event(otherWebsite.ID, "a3", week(-1)),
+	}
+	require.NoError(t, db.Create(&seeded).Error)
+
+	cohorts, err := analytics.GetRetentionCohorts(db, int(website.ID), 3)
+	require.NoError(t, err)
+	require.Len(t, cohorts, 3)
+
+	assert.True(t, cohorts[0].WeekStart.Equal(week(-2)))
+	assert.Equal(t, int64(4), cohorts[0].Visitors)
+	assert.Equal(t, []float64{100, 50, 50}, cohorts[0].Retention)
+
+	assert.True(t, cohorts[1].WeekStart.Equal(week(-1)))
+	assert.Equal(t, int64(2), cohorts[1].Visitors)
+	assert.Equal(t, []float64{100, 50}, cohorts[1].Retention)
+
+	assert.True(t, cohorts[2].WeekStart.Equal(week(0)))
+	assert.Equal(t, int64(0), cohorts[2].Visitors)
+	assert.Equal(t, []float64{0}, cohorts[2].Retention)
+
+	_, err = analytics.GetRetentionCohorts(db, int(website.ID), 0)
+	assert.Error(t, err)
+}
@@ -2,10 +2,12 @@ package analytics
 
 import (
 	"fmt"
+	"sort"
 	"strings"
 
 	"fusionaly/internal/events"
 	"fusionaly/internal/timeframe"
+	"fusionaly/internal/websites"
 
 	"gorm.io/gorm"
 )
@@ -19,14 +21,17 @@ type RevenueMetrics struct {
 	Currency          string  `json:"currency"`
 }
 
-// GetRevenueMetrics calculates revenue metrics for events with "revenue:purchased" naming convention
-func GetRevenueMetrics(db *gorm.DB, params WebsiteScopedQueryParams) (*RevenueMetrics, error) {
-	// Get total sales count and revenue from events with revenue naming convention
-	var result struct {
-		TotalRevenue float64
-		TotalSales   int64
-		Currency     string
-	}
+// purchaseTotals is the revenue and number of revenue:purchased events with a
+// positive price in a time frame
+type purchaseTotals struct {
+	TotalRevenue float64
+	TotalSales   int64
+	Currency     string
+}
+
+// getPurchaseTotals sums the revenue of revenue:purchased events with a price
+func getPurchaseTotals(db *gorm.DB, params WebsiteScopedQueryParams) (*purchaseTotals, error) {
+	var result purchaseTotals
 
 	query := `
 		SELECT 
@@ -65,6 +70,17 @@ func GetRevenueMetrics(db *gorm.DB, params WebsiteScopedQueryParams) (*RevenueMe
 		return nil, fmt.Errorf("error calculating revenue metrics: %w", err)
 	}
 
+	return &result, nil
+}
+
+// GetRevenueMetrics calculates revenue metrics for events with "revenue:purchased" naming convention
+func GetRevenueMetrics(db *gorm.DB, params WebsiteScopedQueryParams) (*RevenueMetrics, error) {
+	// Get total sales count and revenue from events with revenue naming convention
+	result, err := getPurchaseTotals(db, params)
+	if err != nil {
+		return nil, err
+	}
+
 	// Calculate average order value
 	averageOrderValue := 0.0
 	if result.TotalSales > 0 {
@@ -175,6 +191,20 @@ func GetEventRevenueTotals(db *gorm.DB, params WebsiteScopedQueryParams) (map[st
 	return totals, nil
 }
 
+// GetAverageOrderValue returns the revenue per conversion: total revenue divided
+// by the number of revenue:purchased events with a price, 0 without any.
+func GetAverageOrderValue(db *gorm.DB, params WebsiteScopedQueryParams) (float64, error) {
+	totals, err := getPurchaseTotals(db, params)
+	if err != nil {
+		return 0, err
+	}
+
+	if totals.TotalSales == 0 {
+		return 0, nil
+	}
+	return totals.TotalRevenue / float64(totals.TotalSales), nil
+}
+
 // GetRevenuePerVisitor calculates revenue per visitor for the given time frame
 func GetRevenuePerVisitor(db *gorm.DB, params WebsiteScopedQueryParams) (float64, error) {
 	// Get revenue metrics
@@ -251,3 +281,169 @@ func aggregatedRevenueInTimeFrameRaw(db *gorm.DB, params WebsiteScopedQueryParam
 
 	return results, nil
 }
+
+// RevenueSourceResult is the revenue of the purchases attributed to one
+// acquisition source.
+type RevenueSourceResult struct {
+	Name    string  `json:"name"`
+	Revenue float64 `json:"revenue"`
+	Sales   int64   `json:"sales"`
+}
+
+// attributedPurchase is a revenue:purchased event along with the referrer and
+// UTM source of the session it happened in.
+type attributedPurchase struct {
+	Revenue          float64
+	ReferrerHostname string
+	UTMSource        string
+}
+
+// getAttributedPurchases attributes each revenue:purchased event with a price
+// to the first event of its session, splitting sessions over all of the
+// buyer's events the way the session timeout does during processing.
+func getAttributedPurchases(db *gorm.DB, params WebsiteScopedQueryParams) ([]attributedPurchase, error) {
+	var purchases []attributedPurchase
+
+	query := `
+    WITH purchases AS (
+        SELECT
+            id,
+            user_signature,
+            timestamp,
+            (CAST(json_extract(custom_event_meta, '$.price') AS REAL) / 100.0) *
+                COALESCE(CAST(json_extract(custom_event_meta, '$.quantity') AS INTEGER), 1) AS revenue
+        FROM events
+        WHERE website_id = ?
+        AND timestamp BETWEEN ? AND ?
+        AND event_type = ?
+        AND LOWER(custom_event_name) LIKE 'revenue:purchased'
+        AND json_valid(custom_event_meta) = 1
+        AND json_extract(custom_event_meta, '$.price') IS NOT NULL
+        AND CAST(json_extract(custom_event_meta, '$.price') AS REAL) > 0
+    ),
+    ranked_events AS (
+        SELECT
+            id,
+            user_signature,
+            timestamp,
+            referrer_hostname,
+            utm_source,
+            LAG(timestamp) OVER (
+                PARTITION BY user_signature
+                ORDER BY timestamp, id
+            ) as prev_event_time
+        FROM events
+        WHERE website_id = ?
+        AND timestamp <= ?
+        AND user_signature IN (SELECT user_signature FROM purchases)
+    ),
+    session_starts AS (
+        SELECT id, user_signature, timestamp, referrer_hostname, utm_source
+        FROM ranked_events
+        WHERE prev_event_time IS NULL
+        OR CAST((JULIANDAY(timestamp) - JULIANDAY(prev_event_time)) * 86400 as INTEGER) > ?
+    ),
+    purchase_sessions AS (
+        SELECT
+            p.revenue,
+            (
+                SELECT s.id FROM session_starts s
+                WHERE s.user_signature = p.user_signature
+                AND (s.timestamp < p.timestamp OR (s.timestamp = p.timestamp AND s.id <= p.id))
+                ORDER BY s.timestamp DESC, s.id DESC
+                LIMIT 1
+            ) AS start_id
+        FROM purchases p
+    )
+    SELECT
+        ps.revenue,
+        COALESCE(s.referrer_hostname, '') AS referrer_hostname,
+        COALESCE(s.utm_source, '') AS utm_source
+    FROM purchase_sessions ps
+    LEFT JOIN session_starts s ON s.id = ps.start_id
+    `
+
+	err := db.Raw(query,
+		params.WebsiteID,
+		params.TimeFrame.From.UTC(),
+		params.TimeFrame.To.UTC(),
+		events.EventTypeCustomEvent,
+		params.WebsiteID,
+		params.TimeFrame.To.UTC(),
+		websiteSessionTimeoutSeconds(db, params.WebsiteID),
+	).Scan(&purchases).Error
+	if err != nil {
+		return nil, fmt.Errorf("error attributing purchases to sessions: %w", err)
+	}
+
+	return purchases, nil
+}
+
+// sortRevenueSources orders sources by revenue, highest first
+func sortRevenueSources(totals map[string]*RevenueSourceResult) []RevenueSourceResult {
+	results := make([]RevenueSourceResult, 0, len(totals))
+	for _, result := range totals {
+		results = append(results, *result)
+	}
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Revenue != results[j].Revenue {
+			return results[i].Revenue > results[j].Revenue
+		}
+		return results[i].Name < results[j].Name
+	})
+	return results
+}
+
+// GetRevenueByReferrer returns the revenue of revenue:purchased events per
+// normalized referrer of the session they happened in.
+func GetRevenueByReferrer(db *gorm.DB, params WebsiteScopedQueryParams) ([]RevenueSourceResult, error) {
+	var website websites.Website
+	if err := db.First(&website, params.WebsiteID).Error; err != nil {
+		return nil, fmt.Errorf("failed to get website domain for self-referral filtering: %w", err)
+	}
+
+	purchases, err := getAttributedPurchases(db, params)
+	if err != nil {
+		return nil, err
+	}
+
+	totals := make(map[string]*RevenueSourceResult)
+	for _, purchase := range purchases {
+		hostname := purchase.ReferrerHostname
+		if events.IsSelfReferral(hostname, website.Domain) {
+			hostname = ""
+		}
+		name := NormalizeReferrerHostname(hostname)
+		if totals[name] == nil {
+			totals[name] = &RevenueSourceResult{Name: name}
+		}
+		totals[name].Revenue += purchase.Revenue
+		totals[name].Sales++
+	}
+
+	return sortRevenueSources(totals), nil
+}
+
+// GetRevenueByUTMSource returns the revenue of revenue:purchased events per
+// utm_source of the session they happened in. Sessions without a UTM source
+// are left out, as in GetTopUTMSourcesInTimeFrame.
+func GetRevenueByUTMSource(db *gorm.DB, params WebsiteScopedQueryParams) ([]RevenueSourceResult, error) {
+	purchases, err := getAttributedPurchases(db, params)
+	if err != nil {
+		return nil, err
+	}
+
+	totals := make(map[string]*RevenueSourceResult)
+	for _, purchase := range purchases {
+		if purchase.UTMSource == "" {
+			continue
+		}
+		if totals[purchase.UTMSource] == nil {
+			totals[purchase.UTMSource] = &RevenueSourceResult{Name: purchase.UTMSource}
+		}
+		totals[purchase.UTMSource].Revenue += purchase.Revenue
+		totals[purchase.UTMSource].Sales++
+	}
+
+	return sortRevenueSources(totals), nil
+}
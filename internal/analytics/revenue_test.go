@@ -0,0 +1,123 @@
+package analytics_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"fusionaly/internal/analytics"
+	"fusionaly/internal/events"
+	"fusionaly/internal/testsupport"
+	"fusionaly/internal/websites"
+)
+
+func TestRevenueBySource(t *testing.T) {
+	dbManager, _, website := testsupport.SetupTestDBManagerWithWebsite(t, "shop.com")
+	db := dbManager.GetConnection()
+
+	base := time.Date(2024, 7, 1, 12, 0, 0, 0, time.UTC)
+	pageView := func(user, referrer, utmSource string, ts time.Time) events.Event {
+		return events.Event{
+			WebsiteID:        website.ID,
+			UserSignature:    user,
+			Hostname:         website.Domain,
+			Pathname:         "/",
+			ReferrerHostname: referrer,
+			UTMSource:        utmSource,
+			EventType:        events.EventTypePageView,
+			Timestamp:        ts,
+			CreatedAt:        ts,
+		}
+	}
+	purchase := func(user, meta string, ts time.Time) events.Event {
+		return events.Event{
+			WebsiteID:       website.ID,
+			UserSignature:   user,
+			Hostname:        website.Domain,
+			Pathname:        "/checkout",
+			EventType:       events.EventTypeCustomEvent,
+			CustomEventName: "revenue:purchased",
+			CustomEventMeta: meta,
+			Timestamp:       ts,
+			CreatedAt:       ts,
+		}
+	}
+
+	testEvents := []events.Event{
+		pageView("user-1", "www.google.com", "", base),
+		purchase("user-1", `{"price": 2500, "quantity": 2}`, base.Add(5*time.Minute)), // $50.00
+
+		pageView("user-2", events.DirectOrUnknownReferrer, "newsletter", base),
+		purchase("user-2", `{"price": 2500}`, base.Add(10*time.Minute)), // $25.00
+		// A later session from another source gets its own attribution
+		pageView("user-2", "news.ycombinator.com", "", base.Add(3*time.Hour)),
+		purchase("user-2", `{"price": 1000}`, base.Add(3*time.Hour+time.Minute)), // $10.00
+
+		// Internal navigation doesn't take over the session's referrer
+		pageView("user-3", "www.google.com", "newsletter", base.Add(time.Hour)),
+		pageView("user-3", website.Domain, "", base.Add(time.Hour+time.Minute)),
+		purchase("user-3", `{"price": 1500}`, base.Add(time.Hour+2*time.Minute)), // $15.00
+
+		pageView("user-4", "www.bing.com", "ads", base),
+		purchase("user-4", `{"quantity": 3}`, base.Add(time.Minute)), // Missing price, should be ignored
+	}
+	for _, event := range testEvents {
+		require.NoError(t, db.Create(&event).Error)
+	}
+
+	// Purchases on another website should be excluded
+	otherWebsite := websites.Website{Domain: "othershop.com", CreatedAt: time.Now()}
+	require.NoError(t, db.Create(&otherWebsite).Error)
+	require.NoError(t, db.Create(&events.Event{
+		WebsiteID:        otherWebsite.ID,
+		UserSignature:    "user-1",
+		Hostname:         "othershop.com",
+		Pathname:         "/checkout",
+		ReferrerHostname: "www.bing.com",
+		UTMSource:        "ads",
+		EventType:        events.EventTypeCustomEvent,
+		CustomEventName:  "revenue:purchased",
+		CustomEventMeta:  `{"price": 9999}`,
+		Timestamp:        base,
+		CreatedAt:        base,
+	}).Error)
+
+	params := analytics.NewWebsiteScopedQueryParams(setupTimeFrame(t), int(website.ID))
+
+	t.Run("by referrer", func(t *testing.T) {
+		results, err := analytics.GetRevenueByReferrer(db, params)
+		require.NoError(t, err)
+		require.Len(t, results, 3)
+
+		assert.Equal(t, "Google", results[0].Name)
+		assert.InDelta(t, 65.0, results[0].Revenue, 0.01)
+		assert.Equal(t, int64(2), results[0].Sales)
+
+		assert.Equal(t, "Direct / Unknown", results[1].Name)
+		assert.InDelta(t, 25.0, results[1].Revenue, 0.01)
+
+		assert.Equal(t, "Hacker News", results[2].Name)
+		assert.InDelta(t, 10.0, results[2].Revenue, 0.01)
+	})
+
+	t.Run("by UTM source", func(t *testing.T) {
+		results, err := analytics.GetRevenueByUTMSource(db, params)
+		require.NoError(t, err)
+		require.Len(t, results, 1)
+
+		assert.Equal(t, "newsletter", results[0].Name)
+		assert.InDelta(t, 40.0, results[0].Revenue, 0.01)
+		assert.Equal(t, int64(2), results[0].Sales)
+	})
+
+	t.Run("no purchases", func(t *testing.T) {
+		empty := testsupport.CreateTestWebsite(db, "empty-shop.com")
+		emptyParams := analytics.NewWebsiteScopedQueryParams(setupTimeFrame(t), int(empty.ID))
+
+		results, err := analytics.GetRevenueByReferrer(db, emptyParams)
+		require.NoError(t, err)
+		assert.Empty(t, results)
+	})
+}
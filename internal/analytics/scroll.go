@@ -0,0 +1,64 @@
+package analytics
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+
+	"fusionaly/internal/events"
+)
+
+// ScrollDepthBucket is the share of sessions whose deepest scroll on a page
+// reached a given depth bucket.
+type ScrollDepthBucket struct {
+	Depth      int     `json:"depth"`
+	Sessions   int64   `json:"sessions"`
+	Percentage float64 `json:"percentage"`
+}
+
+// GetScrollDepthForPage returns the max scroll depth distribution for a page,
+// one entry per bucket (25/50/75/100) in ascending order.
+func GetScrollDepthForPage(db *gorm.DB, params WebsiteScopedQueryParams, pathname string) ([]ScrollDepthBucket, error) {
+	var rawResults []struct {
+		Depth    int
+		Sessions int64
+	}
+
+	query := `
+    SELECT 
+        depth, 
+        SUM(sessions_count) as sessions
+    FROM scroll_stats
+    WHERE hour BETWEEN ? AND ?
+    AND website_id = ?
+    AND pathname = ?
+    GROUP BY depth
+    `
+
+	err := db.Raw(query,
+		params.TimeFrame.From.UTC(),
+		params.TimeFrame.To.UTC(),
+		params.WebsiteID,
+		pathname,
+	).Scan(&rawResults).Error
+	if err != nil {
+		return nil, fmt.Errorf("error fetching scroll depth from ScrollStat: %w", err)
+	}
+
+	sessionsByDepth := make(map[int]int64, len(rawResults))
+	var total int64
+	for _, r := range rawResults {
+		sessionsByDepth[r.Depth] = r.Sessions
+		total += r.Sessions
+	}
+
+	results := make([]ScrollDepthBucket, len(events.ScrollDepthBuckets))
+	for i, depth := range events.ScrollDepthBuckets {
+		results[i] = ScrollDepthBucket{Depth: depth, Sessions: sessionsByDepth[depth]}
+		if total > 0 {
+			results[i].Percentage = float64(results[i].Sessions) / float64(total) * 100
+		}
+	}
+
+	return results, nil
+}
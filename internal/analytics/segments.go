@@ -0,0 +1,307 @@
+package analytics
+
+import (
+	"maps"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/pariz/gountries"
+	"gorm.io/gorm"
+
+	"fusionaly/internal/events"
+)
+
+// Segment filter keys accepted in WebsiteScopedQueryParams.Filters
+const (
+	FilterPathname  = "pathname"
+	FilterReferrer  = "referrer"
+	FilterUTMSource = "utm_source"
+	FilterCountry   = "country"
+	FilterBrowser   = "browser"
+	FilterOS        = "os"
+	FilterDevice    = "device"
+)
+
+// normalizedOSExpr groups raw operating system names the way the dashboard shows them
+const normalizedOSExpr = `CASE
+            WHEN LOWER(operating_system) LIKE '%mac%' OR LOWER(operating_system) LIKE '%darwin%' THEN 'MacOS'
+            WHEN LOWER(operating_system) LIKE '%linux%' OR LOWER(operating_system) LIKE '%gnu/linux%' THEN 'Linux'
+            WHEN LOWER(operating_system) LIKE '%ios%' OR LOWER(operating_system) LIKE '%iphone os%' THEN 'iOS'
+            WHEN LOWER(operating_system) LIKE '%android%' THEN 'Android'
+            WHEN LOWER(operating_system) LIKE '%windows%' THEN 'Windows'
+            ELSE operating_system
+        END`
+
+// segmentDimension describes the *_stats table holding a filterable dimension.
+type segmentDimension struct {
+	Key     string
+	Table   string
+	Column  string // SQL expression the filter value is compared against
+	Unknown string // stored placeholder the dashboard shows as "Unknown"
+}
+
+// segmentDimensions lists the filterable dimensions. Aggregates are rolled up
+// one dimension at a time, so when several filters are active the first one in
+// this order scopes site-level totals and every other panel only honors the
+// filter on its own dimension. Raw events don't keep the device, browser, OS or
+// country either, so panels can't be re-scoped from them; UnscopedPanels
+// reports the panels that ignore part of the segment instead.
+var segmentDimensions = []segmentDimension{
+	{Key: FilterPathname, Table: "page_stats", Column: "pathname"},
+	{Key: FilterReferrer, Table: "ref_stats", Column: "hostname", Unknown: events.DirectOrUnknownReferrer},
+	{Key: FilterUTMSource, Table: "utm_stats", Column: "utm_source"},
+	{Key: FilterCountry, Table: "country_stats", Column: "country", Unknown: events.UnknownCountry},
+	{Key: FilterBrowser, Table: "browser_stats", Column: "browser", Unknown: events.UnknownBrowser},
+	{Key: FilterOS, Table: "os_stats", Column: normalizedOSExpr, Unknown: events.UnknownOS},
+	{Key: FilterDevice, Table: "device_stats", Column: "device_type", Unknown: events.UnknownDevice},
+}
+
+// siteStatsTable stands for site-level totals in scopedPanels. They are read
+// through siteSource, which follows the first active filter.
+const siteStatsTable = "site_stats"
+
+// scopedPanels maps the dashboard panels, by DashboardMetrics JSON name, that
+// honor segment filters to the tables they apply them on.
+var scopedPanels = map[string][]string{
+	"page_views":            {siteStatsTable},
+	"visitors":              {siteStatsTable},
+	"sessions":              {siteStatsTable},
+	"total_visitors":        {siteStatsTable},
+	"total_views":           {siteStatsTable},
+	"total_sessions":        {siteStatsTable},
+	"pages_per_session":     {siteStatsTable},
+	"top_urls":              {"page_stats"},
+	"top_entry_pages":       {"page_stats"},
+	"top_exit_pages":        {"page_stats"},
+	"total_entry_count":     {"page_stats"},
+	"total_exit_count":      {"page_stats"},
+	"top_referrers":         {"ref_stats"},
+	"traffic_channels":      {"ref_stats", "utm_stats"},
+	"top_utm_sources":       {"utm_stats"},
+	"top_utm_mediums":       {"utm_stats"},
+	"top_utm_campaigns":     {"utm_stats"},
+	"top_utm_terms":         {"utm_stats"},
+	"top_utm_contents":      {"utm_stats"},
+	"top_utm_combinations":  {"utm_stats"},
+	"top_countries":         {"country_stats"},
+	"top_browsers":          {"browser_stats"},
+	"top_operating_systems": {"os_stats"},
+	"top_devices":           {"device_stats"},
+}
+
+// segmentHiddenPanels are left empty while a segment is active rather than
+// shown unscoped, and the goal list isn't a panel at all.
+var segmentHiddenPanels = map[string]bool{
+	"traffic_heatmap":           true,
+	"new_visitors":              true,
+	"returning_visitors":        true,
+	"new_visitors_series":       true,
+	"returning_visitors_series": true,
+	"conversion_goals":          true,
+}
+
+// sessionPanels read sessions, which dimension tables other than page_stats
+// don't track. They are left empty when the first active filter isn't on
+// page_stats (see siteSource).
+var sessionPanels = []string{"sessions", "total_sessions", "pages_per_session"}
+
+// adminDashboardPanels are loaded by the admin dashboard on top of
+// DashboardMetrics. They read raw events and flow transitions, which none of
+// the segment filters are applied to.
+var adminDashboardPanels = []string{
+	"top_query_params",
+	"top_property_values",
+	"metric_aggregation",
+	"top_subdomains",
+	"user_flow",
+}
+
+// activeSegmentDimensions returns the dimensions with an active filter, in
+// segmentDimensions order.
+func activeSegmentDimensions(filters map[string]string) []segmentDimension {
+	var active []segmentDimension
+	for _, dim := range segmentDimensions {
+		if _, ok := filters[dim.Key]; ok {
+			active = append(active, dim)
+		}
+	}
+	return active
+}
+
+// HiddenPanels returns the dashboard panels, by DashboardMetrics JSON name,
+// left empty under the active filters because they can't be scoped to them,
+// sorted by name, so the UI can tell them apart from real zeros. It returns
+// nil when no filter is active.
+func HiddenPanels(filters map[string]string) []string {
+	active := activeSegmentDimensions(filters)
+	if len(active) == 0 {
+		return nil
+	}
+
+	var hidden []string
+	for name := range segmentHiddenPanels {
+		if name != "conversion_goals" {
+			hidden = append(hidden, name)
+		}
+	}
+	if active[0].Table != "page_stats" {
+		hidden = append(hidden, sessionPanels...)
+	}
+	sort.Strings(hidden)
+	return hidden
+}
+
+// UnscopedPanels returns the dashboard panels, by DashboardMetrics JSON name,
+// that ignore at least one of the active filters, sorted by name, so the UI
+// can mark them. It returns nil when no filter is active.
+func UnscopedPanels(filters map[string]string) []string {
+	active := activeSegmentDimensions(filters)
+	if len(active) == 0 {
+		return nil
+	}
+
+	panels := slices.Collect(maps.Keys(dashboardMetricTasks))
+	panels = append(panels, adminDashboardPanels...)
+	hidden := HiddenPanels(filters)
+
+	var unscoped []string
+	for _, name := range panels {
+		if segmentHiddenPanels[name] || slices.Contains(hidden, name) {
+			continue
+		}
+		tables := scopedPanels[name]
+		for i, dim := range active {
+			followsSite := i == 0 && slices.Contains(tables, siteStatsTable)
+			if !followsSite && !slices.Contains(tables, dim.Table) {
+				unscoped = append(unscoped, name)
+				break
+			}
+		}
+	}
+	sort.Strings(unscoped)
+	return unscoped
+}
+
+// SegmentFilterKeys returns the accepted segment filter keys.
+func SegmentFilterKeys() []string {
+	keys := make([]string, len(segmentDimensions))
+	for i, dim := range segmentDimensions {
+		keys[i] = dim.Key
+	}
+	return keys
+}
+
+// NewSegmentFilters keeps the known, non-empty filters from values.
+func NewSegmentFilters(values map[string]string) map[string]string {
+	filters := make(map[string]string)
+	for _, dim := range segmentDimensions {
+		if value := strings.TrimSpace(values[dim.Key]); value != "" {
+			filters[dim.Key] = value
+		}
+	}
+	return filters
+}
+
+// segmentCondition returns the SQL condition restricting dim's table to the
+// filter value. Values are accepted as displayed on the dashboard (e.g.
+// "United States", "macOS", "Google") as well as in their stored form.
+func segmentCondition(db *gorm.DB, params WebsiteScopedQueryParams, dim segmentDimension, value string) (string, []interface{}) {
+	if dim.Unknown != "" && strings.EqualFold(value, "Unknown") {
+		value = dim.Unknown
+	}
+
+	switch dim.Key {
+	case FilterPathname:
+		// Top pages are shown as hostname + pathname
+		if i := strings.Index(value, "/"); i > 0 {
+			value = value[i:]
+		}
+		return "pathname = ?", []interface{}{value}
+	case FilterCountry:
+		if country, err := gountries.New().FindCountryByName(value); err == nil {
+			value = country.Codes.Alpha2
+		}
+	case FilterReferrer:
+		if strings.EqualFold(value, "Direct / Unknown") {
+			value = dim.Unknown
+		}
+		return "hostname IN ?", []interface{}{matchingReferrerHostnames(db, params, value)}
+	}
+
+	return "LOWER(" + dim.Column + ") = LOWER(?)", []interface{}{value}
+}
+
+// matchingReferrerHostnames resolves a referrer filter to the raw hostnames
+// stored in ref_stats that normalize to it.
+func matchingReferrerHostnames(db *gorm.DB, params WebsiteScopedQueryParams, value string) []string {
+	var hostnames []string
+	db.Raw(`
+        SELECT DISTINCT hostname FROM ref_stats
+        WHERE hour BETWEEN ? AND ?
+        AND website_id = ?
+    `, params.TimeFrame.From.UTC(), params.TimeFrame.To.UTC(), params.WebsiteID).Scan(&hostnames)
+
+	matches := []string{value}
+	for _, hostname := range hostnames {
+		if hostname != value && strings.EqualFold(NormalizeReferrerHostname(hostname), value) {
+			matches = append(matches, hostname)
+		}
+	}
+	return matches
+}
+
+// segmentClause returns an " AND ..." clause applying the active filters on
+// table's own dimension, or "" when none apply.
+func segmentClause(db *gorm.DB, params WebsiteScopedQueryParams, table string) (string, []interface{}) {
+	var clause strings.Builder
+	var args []interface{}
+	for _, dim := range segmentDimensions {
+		value, ok := params.Filters[dim.Key]
+		if !ok || dim.Table != table {
+			continue
+		}
+		condition, conditionArgs := segmentCondition(db, params, dim, value)
+		clause.WriteString(" AND " + condition)
+		args = append(args, conditionArgs...)
+	}
+	return clause.String(), args
+}
+
+// siteStatsSource is the table and columns site-level visitors, page views
+// and sessions are read from. Sessions is empty when the table has none.
+type siteStatsSource struct {
+	Table     string
+	Visitors  string
+	PageViews string
+	Sessions  string
+	Where     string
+	Args      []interface{}
+}
+
+// siteSource returns site_stats when no filter is active, otherwise the table
+// of the first active filter's dimension. Dimension tables don't track
+// sessions, so segmented sessions count entrances for page filters and are
+// unsupported (Sessions is empty) for the rest.
+func siteSource(db *gorm.DB, params WebsiteScopedQueryParams) siteStatsSource {
+	for _, dim := range segmentDimensions {
+		value, ok := params.Filters[dim.Key]
+		if !ok {
+			continue
+		}
+		condition, args := segmentCondition(db, params, dim, value)
+		sessions := ""
+		if dim.Table == "page_stats" {
+			sessions = "entrances"
+		}
+		return siteStatsSource{
+			Table:     dim.Table,
+			Visitors:  "visitors_count",
+			PageViews: "page_views_count",
+			Sessions:  sessions,
+			Where:     " AND " + condition,
+			Args:      args,
+		}
+	}
+	return siteStatsSource{Table: "site_stats", Visitors: "visitors", PageViews: "page_views", Sessions: "sessions"}
+}
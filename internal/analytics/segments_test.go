@@ -0,0 +1,165 @@
+package analytics_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"fusionaly/internal/analytics"
+	"fusionaly/internal/testsupport"
+	"fusionaly/internal/timeframe"
+)
+
+func TestSegmentFilters(t *testing.T) {
+	dbManager, logger := testsupport.SetupTestDBManager(t)
+	db := dbManager.GetConnection()
+	website := testsupport.CreateTestWebsite(db, "segments.com")
+	websiteID := int(website.ID)
+
+	hour := time.Date(2024, 7, 1, 12, 0, 0, 0, time.UTC)
+	require.NoError(t, db.Create(&analytics.SiteStat{WebsiteID: website.ID, Visitors: 10, PageViews: 25, Sessions: 12, Hour: hour}).Error)
+	require.NoError(t, db.Create([]analytics.BrowserStat{
+		{WebsiteID: website.ID, Browser: "Chrome", VisitorsCount: 7, PageViewsCount: 18, Hour: hour},
+		{WebsiteID: website.ID, Browser: "Firefox", VisitorsCount: 3, PageViewsCount: 7, Hour: hour},
+	}).Error)
+	require.NoError(t, db.Create([]analytics.CountryStat{
+		{WebsiteID: website.ID, Country: "US", VisitorsCount: 6, PageViewsCount: 15, Hour: hour},
+		{WebsiteID: website.ID, Country: "DE", VisitorsCount: 4, PageViewsCount: 10, Hour: hour},
+	}).Error)
+	require.NoError(t, db.Create([]analytics.OSStat{
+		{WebsiteID: website.ID, OperatingSystem: "Mac OS X", VisitorsCount: 2, PageViewsCount: 4, Hour: hour},
+		{WebsiteID: website.ID, OperatingSystem: "Windows", VisitorsCount: 8, PageViewsCount: 21, Hour: hour},
+	}).Error)
+	require.NoError(t, db.Create([]analytics.RefStat{
+		{WebsiteID: website.ID, Hostname: "www.google.com", VisitorsCount: 2, PageViewsCount: 3, Hour: hour},
+		{WebsiteID: website.ID, Hostname: "news.ycombinator.com", VisitorsCount: 1, PageViewsCount: 1, Hour: hour},
+	}).Error)
+	require.NoError(t, db.Create([]analytics.PageStat{
+		{WebsiteID: website.ID, Hostname: "segments.com", Pathname: "/", VisitorsCount: 8, PageViewsCount: 15, Entrances: 9, Hour: hour},
+		{WebsiteID: website.ID, Hostname: "segments.com", Pathname: "/pricing", VisitorsCount: 4, PageViewsCount: 10, Entrances: 3, Hour: hour},
+	}).Error)
+
+	tf, err := timeframe.NewTimeFrame(timeframe.TimeFrameParams{
+		FromTime:      time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC),
+		ToTime:        time.Date(2024, 7, 1, 23, 59, 59, 0, time.UTC),
+		TimeFrameSize: timeframe.DailyTimeFrame,
+	}, time.UTC)
+	require.NoError(t, err)
+
+	paramsWith := func(filters map[string]string) analytics.WebsiteScopedQueryParams {
+		params := analytics.NewWebsiteScopedQueryParams(tf, websiteID)
+		params.Filters = analytics.NewSegmentFilters(filters)
+		return params
+	}
+
+	t.Run("no filters read site-wide totals", func(t *testing.T) {
+		visitors, err := analytics.GetTotalVisitorsInTimeFrame(db, paramsWith(nil))
+		require.NoError(t, err)
+		assert.Equal(t, int64(10), visitors)
+	})
+
+	t.Run("browser filter scopes totals and time series", func(t *testing.T) {
+		params := paramsWith(map[string]string{analytics.FilterBrowser: "chrome"})
+
+		visitors, err := analytics.GetTotalVisitorsInTimeFrame(db, params)
+		require.NoError(t, err)
+		assert.Equal(t, int64(7), visitors)
+
+		views, err := analytics.GetTotalPageViewsInTimeFrame(db, params)
+		require.NoError(t, err)
+		assert.Equal(t, int64(18), views)
+
+		series, err := analytics.AggregatedVisitorsInTimeFrame(db, params)
+		require.NoError(t, err)
+		total := 0
+		for _, point := range series {
+			total += point.Count
+		}
+		assert.Equal(t, 7, total)
+	})
+
+	t.Run("filter narrows its own dimension panel", func(t *testing.T) {
+		browsers, err := analytics.GetTopBrowsersInTimeFrame(db, paramsWith(map[string]string{analytics.FilterBrowser: "Chrome"}))
+		require.NoError(t, err)
+		require.Len(t, browsers, 1)
+		assert.Equal(t, "Chrome", browsers[0].Name)
+	})
+
+	t.Run("marks panels outside the filtered dimension as unscoped", func(t *testing.T) {
+		filters := map[string]string{analytics.FilterBrowser: "chrome"}
+
+		countries, err := analytics.GetTopCountriesInTimeFrame(db, paramsWith(filters))
+		require.NoError(t, err)
+		require.Len(t, countries, 2, "country_stats can't be narrowed to a browser")
+
+		metrics, err := analytics.FetchDashboardMetrics(db, tf, websiteID, filters, analytics.DefaultTopLimit, logger)
+		require.NoError(t, err)
+		assert.Contains(t, metrics.UnscopedPanels, "top_countries")
+		assert.Contains(t, metrics.UnscopedPanels, "top_operating_systems")
+		assert.NotContains(t, metrics.UnscopedPanels, "top_browsers")
+		assert.NotContains(t, metrics.UnscopedPanels, "total_visitors")
+		assert.NotContains(t, metrics.UnscopedPanels, "traffic_heatmap", "hidden while segmented")
+		assert.Contains(t, metrics.UnscopedPanels, "top_property_values", "raw event panels are never segmented")
+		assert.Contains(t, metrics.UnscopedPanels, "user_flow")
+	})
+
+	t.Run("sessions are unsupported outside page segments", func(t *testing.T) {
+		filters := map[string]string{analytics.FilterBrowser: "chrome"}
+
+		sessions, err := analytics.GetTotalSessionsInTimeFrame(db, paramsWith(filters))
+		require.NoError(t, err)
+		assert.Zero(t, sessions)
+
+		hidden := analytics.HiddenPanels(filters)
+		assert.Contains(t, hidden, "total_sessions")
+		assert.Contains(t, hidden, "pages_per_session")
+		assert.NotContains(t, analytics.UnscopedPanels(filters), "total_sessions")
+		assert.NotContains(t, analytics.HiddenPanels(map[string]string{analytics.FilterPathname: "/pricing"}), "total_sessions")
+		assert.Empty(t, analytics.HiddenPanels(nil))
+	})
+
+	t.Run("site totals only follow the first filter", func(t *testing.T) {
+		unscoped := analytics.UnscopedPanels(map[string]string{analytics.FilterCountry: "US", analytics.FilterBrowser: "Chrome"})
+		assert.Contains(t, unscoped, "total_visitors")
+		assert.Contains(t, unscoped, "top_browsers")
+		assert.Empty(t, analytics.UnscopedPanels(nil))
+	})
+
+	t.Run("country accepts display names", func(t *testing.T) {
+		visitors, err := analytics.GetTotalVisitorsInTimeFrame(db, paramsWith(map[string]string{analytics.FilterCountry: "United States"}))
+		require.NoError(t, err)
+		assert.Equal(t, int64(6), visitors)
+	})
+
+	t.Run("os matches normalized names", func(t *testing.T) {
+		visitors, err := analytics.GetTotalVisitorsInTimeFrame(db, paramsWith(map[string]string{analytics.FilterOS: "macOS"}))
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), visitors)
+	})
+
+	t.Run("referrer matches normalized hostnames", func(t *testing.T) {
+		visitors, err := analytics.GetTotalVisitorsInTimeFrame(db, paramsWith(map[string]string{analytics.FilterReferrer: "Google"}))
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), visitors)
+	})
+
+	t.Run("pathname accepts top page URLs", func(t *testing.T) {
+		params := paramsWith(map[string]string{analytics.FilterPathname: "segments.com/pricing"})
+
+		visitors, err := analytics.GetTotalVisitorsInTimeFrame(db, params)
+		require.NoError(t, err)
+		assert.Equal(t, int64(4), visitors)
+
+		sessions, err := analytics.GetTotalSessionsInTimeFrame(db, params)
+		require.NoError(t, err)
+		assert.Equal(t, int64(3), sessions, "page segments count entrances as sessions")
+	})
+
+	t.Run("ignores unknown filter keys", func(t *testing.T) {
+		visitors, err := analytics.GetTotalVisitorsInTimeFrame(db, paramsWith(map[string]string{"planet": "mars"}))
+		require.NoError(t, err)
+		assert.Equal(t, int64(10), visitors)
+	})
+}
@@ -31,28 +31,29 @@ func aggregatedSessionsInTimeFrameRaw(db *gorm.DB, params WebsiteScopedQueryPara
 		return nil, err
 	}
 
-	// Query to get aggregated sessions from SiteStat
+	// Query to get aggregated sessions from SiteStat, or the segment's dimension table
+	source := siteSource(db, params)
+	if source.Sessions == "" {
+		return results, nil
+	}
 	query := fmt.Sprintf(`
         SELECT
             %s AS date,
-            COALESCE(SUM(sessions), 0) AS count
+            COALESCE(SUM(%s), 0) AS count
         FROM
-            site_stats
+            %s
         WHERE
             hour >= ? AND hour <= ?
-            AND website_id = ?
+            AND website_id = ?%s
         GROUP BY
             %s
         ORDER BY
             date ASC
-    `, groupByExpression, groupByExpression)
+    `, groupByExpression, source.Sessions, source.Table, source.Where, groupByExpression)
 
 	// Execute the query
-	err = db.Raw(query,
-		params.TimeFrame.From.UTC(),
-		params.TimeFrame.To.UTC(),
-		params.WebsiteID,
-	).Scan(&results).Error
+	args := append([]interface{}{params.TimeFrame.From.UTC(), params.TimeFrame.To.UTC(), params.WebsiteID}, source.Args...)
+	err = db.Raw(query, args...).Scan(&results).Error
 	if err != nil {
 		return nil, fmt.Errorf("error fetching aggregated sessions: %w", err)
 	}
@@ -62,13 +63,15 @@ func aggregatedSessionsInTimeFrameRaw(db *gorm.DB, params WebsiteScopedQueryPara
 
 // SiteStat represents aggregated site-wide statistics including sessions
 type SiteStat struct {
-	ID          uint      `gorm:"primaryKey;autoIncrement"`
-	WebsiteID   uint      `gorm:"uniqueIndex:idx_site_hour;not null"`
-	PageViews   int       `gorm:"not null;default:0"`
-	Visitors    int       `gorm:"not null;default:0"`
-	Sessions    int       `gorm:"not null;default:0"`
-	BounceCount int       `gorm:"not null;default:0"`
-	Hour        time.Time `gorm:"uniqueIndex:idx_site_hour;type:datetime;not null"`
-	CreatedAt   time.Time
-	UpdatedAt   time.Time
+	ID                uint      `gorm:"primaryKey;autoIncrement"`
+	WebsiteID         uint      `gorm:"uniqueIndex:idx_site_hour;not null"`
+	PageViews         int       `gorm:"not null;default:0"`
+	Visitors          int       `gorm:"not null;default:0"`
+	NewVisitors       int       `gorm:"not null;default:0"` // Visitors who haven't come back for another session
+	ReturningVisitors int       `gorm:"not null;default:0"` // Visitors who have; together with NewVisitors adds up to Visitors
+	Sessions          int       `gorm:"not null;default:0"`
+	BounceCount       int       `gorm:"not null;default:0"`
+	Hour              time.Time `gorm:"uniqueIndex:idx_site_hour;type:datetime;not null"`
+	CreatedAt         time.Time
+	UpdatedAt         time.Time
 }
@@ -0,0 +1,41 @@
+package analytics
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+
+	"fusionaly/internal/events"
+)
+
+// subdomainExpr is the hostname an event's page was served from
+const subdomainExpr = "COALESCE(NULLIF(original_hostname, ''), hostname)"
+
+// GetSubdomainBreakdownInTimeFrame returns the page views of a website with
+// subdomain rollup per subdomain the pages were served from, most viewed
+// first. Page views stored before rollup was enabled fall under their own
+// hostname, so the split always sums to the website's page views. Visitors
+// stay unified across subdomains in the website totals.
+func GetSubdomainBreakdownInTimeFrame(db *gorm.DB, params WebsiteScopedQueryParams) ([]MetricCountResult, error) {
+	results := []MetricCountResult{}
+	search, searchArgs := searchClause(params, subdomainExpr)
+	query := fmt.Sprintf(`
+		SELECT
+			%s AS name,
+			COUNT(*) AS count
+		FROM events
+		WHERE timestamp BETWEEN ? AND ?
+		AND website_id = ?
+		AND event_type = ?%s
+		GROUP BY name
+		ORDER BY count DESC, name
+		LIMIT ? OFFSET ?
+	`, subdomainExpr, search)
+
+	args := append([]interface{}{params.TimeFrame.From.UTC(), params.TimeFrame.To.UTC(), params.WebsiteID, events.EventTypePageView}, searchArgs...)
+	args = append(args, params.Limit, params.Offset)
+	if err := db.Raw(query, args...).Scan(&results).Error; err != nil {
+		return nil, fmt.Errorf("error fetching subdomain breakdown: %w", err)
+	}
+	return results, nil
+}
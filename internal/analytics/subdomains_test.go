@@ -0,0 +1,90 @@
+package analytics_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"fusionaly/internal/analytics"
+	"fusionaly/internal/events"
+	"fusionaly/internal/settings"
+	"fusionaly/internal/testsupport"
+	"fusionaly/internal/timeframe"
+)
+
+func TestGetSubdomainBreakdownInTimeFrame(t *testing.T) {
+	dbManager, logger := testsupport.SetupTestDBManager(t)
+	db := dbManager.GetConnection()
+	testsupport.CleanAllTables(db)
+	require.NoError(t, settings.SetupDefaultSettings(db))
+
+	website := testsupport.CreateTestWebsite(db, "example.com")
+	require.NoError(t, settings.UpdateSubdomainTrackingSettings(db, "example.com", true))
+	require.NoError(t, settings.UpdateSubdomainRollupSettings(db, "example.com", true))
+
+	day := time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC)
+	collect := func(rawURL, ip string, at time.Time) {
+		input := testsupport.CreateTestEventInput(ip, "Mozilla/5.0 (subdomain-agent)", events.EventTypePageView, at, rawURL, "", "", "")
+		require.NoError(t, events.CollectEvent(dbManager, logger, input))
+	}
+
+	// The same visitor reads the blog and then uses the app
+	collect("https://blog.example.com/post", "10.0.0.1", day.Add(9*time.Hour))
+	collect("https://app.example.com/login", "10.0.0.1", day.Add(9*time.Hour+5*time.Minute))
+	collect("https://app.example.com/home", "10.0.0.1", day.Add(9*time.Hour+6*time.Minute))
+	collect("https://blog.example.com/post", "10.0.0.2", day.Add(10*time.Hour))
+	collect("https://blog.example.com/other", "10.0.0.3", day.Add(11*time.Hour))
+	collect("https://example.com/", "10.0.0.4", day.Add(12*time.Hour))
+	// Outside the time frame
+	collect("https://app.example.com/home", "10.0.0.5", day.AddDate(0, 0, 3))
+	require.NoError(t, testsupport.ProcessAllTestEvents(dbManager, logger))
+
+	tf, err := timeframe.NewTimeFrame(timeframe.TimeFrameParams{
+		FromTime:      day,
+		ToTime:        day.Add(24*time.Hour - time.Second),
+		TimeFrameSize: timeframe.DailyTimeFrame,
+	}, time.UTC)
+	require.NoError(t, err)
+	params := analytics.NewWebsiteScopedQueryParams(tf, int(website.ID))
+
+	results, err := analytics.GetSubdomainBreakdownInTimeFrame(db, params)
+	require.NoError(t, err)
+	assert.Equal(t, []analytics.MetricCountResult{
+		{Name: "blog.example.com", Count: 3},
+		{Name: "app.example.com", Count: 2},
+		{Name: "example.com", Count: 1},
+	}, results)
+
+	t.Run("split sums to the website total", func(t *testing.T) {
+		totalPageViews, err := analytics.GetTotalPageViewsInTimeFrame(db, params)
+		require.NoError(t, err)
+
+		var sum int64
+		for _, result := range results {
+			sum += result.Count
+		}
+		assert.Equal(t, totalPageViews, sum)
+	})
+
+	t.Run("visitors stay unified across subdomains", func(t *testing.T) {
+		totalVisitors, err := analytics.GetTotalVisitorsInTimeFrame(db, params)
+		require.NoError(t, err)
+		assert.Equal(t, int64(4), totalVisitors)
+	})
+
+	t.Run("search and paging", func(t *testing.T) {
+		searched := params
+		searched.Search = "APP."
+		results, err := analytics.GetSubdomainBreakdownInTimeFrame(db, searched)
+		require.NoError(t, err)
+		assert.Equal(t, []analytics.MetricCountResult{{Name: "app.example.com", Count: 2}}, results)
+
+		paged := params
+		paged.Limit, paged.Offset = 1, 1
+		results, err = analytics.GetSubdomainBreakdownInTimeFrame(db, paged)
+		require.NoError(t, err)
+		assert.Equal(t, []analytics.MetricCountResult{{Name: "app.example.com", Count: 2}}, results)
+	})
+}
@@ -9,11 +9,21 @@ import (
 
 	"fusionaly/internal/config"
 	"fusionaly/internal/events"
+	"fusionaly/internal/settings"
 )
 
+// websiteSessionTimeoutSeconds returns the website's session timeout, falling
+// back to the global one when the website doesn't override it.
+func websiteSessionTimeoutSeconds(db *gorm.DB, websiteID int) int {
+	if minutes := settings.GetSessionTimeoutMinutes(db, uint(websiteID)); minutes > 0 {
+		return minutes * 60
+	}
+	return config.GetConfig().SessionTimeoutSeconds
+}
+
 // GetVisitDurationInTimeFrame calculates the average visit duration
 func GetVisitDurationInTimeFrame(db *gorm.DB, params WebsiteScopedQueryParams) (float64, error) {
-	sessionTimeoutSeconds := config.GetConfig().SessionTimeoutSeconds
+	sessionTimeoutSeconds := websiteSessionTimeoutSeconds(db, params.WebsiteID)
 
 	var result struct {
 		AverageDuration float64
@@ -111,6 +121,37 @@ func GetBounceRateInTimeFrame(db *gorm.DB, params WebsiteScopedQueryParams) (flo
 	return result.BounceRate, nil
 }
 
+// GetPagesPerSessionInTimeFrame returns the average number of page views per
+// session, or 0 when there are no sessions.
+func GetPagesPerSessionInTimeFrame(db *gorm.DB, params WebsiteScopedQueryParams) (float64, error) {
+	var result struct {
+		PagesPerSession float64
+	}
+
+	source := siteSource(db, params)
+	if source.Sessions == "" {
+		return 0, nil
+	}
+	query := fmt.Sprintf(`
+    SELECT
+        CASE WHEN COALESCE(SUM(%s), 0) > 0
+            THEN CAST(SUM(%s) AS FLOAT) / CAST(SUM(%s) AS FLOAT)
+            ELSE 0
+        END as pages_per_session
+    FROM %s
+    WHERE hour BETWEEN ? AND ?
+    AND website_id = ?%s
+    `, source.Sessions, source.PageViews, source.Sessions, source.Table, source.Where)
+
+	args := append([]interface{}{params.TimeFrame.From.UTC(), params.TimeFrame.To.UTC(), params.WebsiteID}, source.Args...)
+	err := db.Raw(query, args...).Scan(&result).Error
+	if err != nil {
+		return 0, fmt.Errorf("error calculating pages per session: %w", err)
+	}
+
+	return result.PagesPerSession, nil
+}
+
 // GetTotalEvents returns the total number of events for the given website and time frame.
 func GetTotalEvents(db *gorm.DB, params WebsiteScopedQueryParams, logger *slog.Logger) (int64, error) {
 	logger.Debug("GetTotalEvents called",
@@ -141,18 +182,16 @@ func GetTotalEntryCountInTimeFrame(db *gorm.DB, params WebsiteScopedQueryParams)
 		TotalEntries int64
 	}
 
-	query := `
+	segment, segmentArgs := segmentClause(db, params, "page_stats")
+	query := fmt.Sprintf(`
     SELECT COALESCE(SUM(entrances), 0) as total_entries
     FROM page_stats
     WHERE hour BETWEEN ? AND ?
-    AND website_id = ?
-    `
+    AND website_id = ?%s
+    `, segment)
 
-	err := db.Raw(query,
-		params.TimeFrame.From.UTC(),
-		params.TimeFrame.To.UTC(),
-		params.WebsiteID,
-	).Scan(&result).Error
+	args := append([]interface{}{params.TimeFrame.From.UTC(), params.TimeFrame.To.UTC(), params.WebsiteID}, segmentArgs...)
+	err := db.Raw(query, args...).Scan(&result).Error
 	if err != nil {
 		return 0, fmt.Errorf("error calculating total entry count: %w", err)
 	}
@@ -166,18 +205,16 @@ func GetTotalExitCountInTimeFrame(db *gorm.DB, params WebsiteScopedQueryParams)
 		TotalExits int64
 	}
 
-	query := `
+	segment, segmentArgs := segmentClause(db, params, "page_stats")
+	query := fmt.Sprintf(`
     SELECT COALESCE(SUM(exits), 0) as total_exits
     FROM page_stats
     WHERE hour BETWEEN ? AND ?
-    AND website_id = ?
-    `
+    AND website_id = ?%s
+    `, segment)
 
-	err := db.Raw(query,
-		params.TimeFrame.From.UTC(),
-		params.TimeFrame.To.UTC(),
-		params.WebsiteID,
-	).Scan(&result).Error
+	args := append([]interface{}{params.TimeFrame.From.UTC(), params.TimeFrame.To.UTC(), params.WebsiteID}, segmentArgs...)
+	err := db.Raw(query, args...).Scan(&result).Error
 	if err != nil {
 		return 0, fmt.Errorf("error calculating total exit count: %w", err)
 	}
@@ -191,18 +228,16 @@ func GetTotalPageViewsInTimeFrame(db *gorm.DB, params WebsiteScopedQueryParams)
 		TotalPageViews int64
 	}
 
-	query := `
-    SELECT COALESCE(SUM(page_views), 0) as total_page_views
-    FROM site_stats
+	source := siteSource(db, params)
+	query := fmt.Sprintf(`
+    SELECT COALESCE(SUM(%s), 0) as total_page_views
+    FROM %s
     WHERE hour BETWEEN ? AND ?
-    AND website_id = ?
-    `
+    AND website_id = ?%s
+    `, source.PageViews, source.Table, source.Where)
 
-	err := db.Raw(query,
-		params.TimeFrame.From.UTC(),
-		params.TimeFrame.To.UTC(),
-		params.WebsiteID,
-	).Scan(&result).Error
+	args := append([]interface{}{params.TimeFrame.From.UTC(), params.TimeFrame.To.UTC(), params.WebsiteID}, source.Args...)
+	err := db.Raw(query, args...).Scan(&result).Error
 	if err != nil {
 		return 0, fmt.Errorf("error calculating total page views: %w", err)
 	}
@@ -235,18 +270,16 @@ func GetTotalVisitorsInTimeFrame(db *gorm.DB, params WebsiteScopedQueryParams) (
 		TotalVisitors int64
 	}
 
-	query := `
-    SELECT COALESCE(SUM(visitors), 0) as total_visitors
-    FROM site_stats
+	source := siteSource(db, params)
+	query := fmt.Sprintf(`
+    SELECT COALESCE(SUM(%s), 0) as total_visitors
+    FROM %s
     WHERE hour BETWEEN ? AND ?
-    AND website_id = ?
-    `
+    AND website_id = ?%s
+    `, source.Visitors, source.Table, source.Where)
 
-	err := db.Raw(query,
-		params.TimeFrame.From.UTC(),
-		params.TimeFrame.To.UTC(),
-		params.WebsiteID,
-	).Scan(&result).Error
+	args := append([]interface{}{params.TimeFrame.From.UTC(), params.TimeFrame.To.UTC(), params.WebsiteID}, source.Args...)
+	err := db.Raw(query, args...).Scan(&result).Error
 	if err != nil {
 		return 0, fmt.Errorf("error calculating total visitors: %w", err)
 	}
@@ -260,18 +293,19 @@ func GetTotalSessionsInTimeFrame(db *gorm.DB, params WebsiteScopedQueryParams) (
 		TotalSessions int64
 	}
 
-	query := `
-    SELECT COALESCE(SUM(sessions), 0) as total_sessions
-    FROM site_stats
+	source := siteSource(db, params)
+	if source.Sessions == "" {
+		return 0, nil
+	}
+	query := fmt.Sprintf(`
+    SELECT COALESCE(SUM(%s), 0) as total_sessions
+    FROM %s
     WHERE hour BETWEEN ? AND ?
-    AND website_id = ?
-    `
+    AND website_id = ?%s
+    `, source.Sessions, source.Table, source.Where)
 
-	err := db.Raw(query,
-		params.TimeFrame.From.UTC(),
-		params.TimeFrame.To.UTC(),
-		params.WebsiteID,
-	).Scan(&result).Error
+	args := append([]interface{}{params.TimeFrame.From.UTC(), params.TimeFrame.To.UTC(), params.WebsiteID}, source.Args...)
+	err := db.Raw(query, args...).Scan(&result).Error
 	if err != nil {
 		return 0, fmt.Errorf("error calculating total sessions: %w", err)
 	}
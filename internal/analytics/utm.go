@@ -1,6 +1,9 @@
 package analytics
 
 import (
+	"fmt"
+	"strings"
+
 	"fusionaly/internal/events"
 
 	"gorm.io/gorm"
@@ -10,27 +13,26 @@ import (
 func GetTopUTMMediumsInTimeFrame(db *gorm.DB, params WebsiteScopedQueryParams) ([]MetricCountResult, error) {
 	var results []MetricCountResult
 
-	query := `
+	segment, segmentArgs := segmentClause(db, params, "utm_stats")
+	search, searchArgs := searchClause(params, "utm_medium")
+	query := fmt.Sprintf(`
 		SELECT
 			utm_medium AS name,
 			SUM(visitors_count) AS count
 		FROM utm_stats
 		WHERE hour BETWEEN ? AND ?
-        AND website_id = ?
+        AND website_id = ?%s%s
         AND utm_medium != '' AND utm_medium != ?
 		GROUP BY utm_medium
 		HAVING count > 0
 		ORDER BY count DESC
-		LIMIT ?
-	`
+		LIMIT ? OFFSET ?
+	`, segment, search)
 
-	err := db.Raw(query,
-		params.TimeFrame.From.UTC(),
-		params.TimeFrame.To.UTC(),
-		params.WebsiteID,
-		events.EmptyUTMAttr,
-		params.Limit,
-	).Scan(&results).Error
+	args := append([]interface{}{params.TimeFrame.From.UTC(), params.TimeFrame.To.UTC(), params.WebsiteID}, segmentArgs...)
+	args = append(args, searchArgs...)
+	args = append(args, events.EmptyUTMAttr, params.Limit, params.Offset)
+	err := db.Raw(query, args...).Scan(&results).Error
 	if err != nil {
 		return []MetricCountResult{}, nil
 	}
@@ -42,27 +44,26 @@ func GetTopUTMMediumsInTimeFrame(db *gorm.DB, params WebsiteScopedQueryParams) (
 func GetTopUTMSourcesInTimeFrame(db *gorm.DB, params WebsiteScopedQueryParams) ([]MetricCountResult, error) {
 	var results []MetricCountResult
 
-	query := `
+	segment, segmentArgs := segmentClause(db, params, "utm_stats")
+	search, searchArgs := searchClause(params, "utm_source")
+	query := fmt.Sprintf(`
 		SELECT
 			utm_source AS name,
 			SUM(visitors_count) AS count
 		FROM utm_stats
 		WHERE hour BETWEEN ? AND ?
-        AND website_id = ?
+        AND website_id = ?%s%s
         AND utm_source != '' AND utm_source != ?
 		GROUP BY utm_source
 		HAVING count > 0
 		ORDER BY count DESC
-		LIMIT ?
-	`
+		LIMIT ? OFFSET ?
+	`, segment, search)
 
-	err := db.Raw(query,
-		params.TimeFrame.From.UTC(),
-		params.TimeFrame.To.UTC(),
-		params.WebsiteID,
-		events.EmptyUTMAttr,
-		params.Limit,
-	).Scan(&results).Error
+	args := append([]interface{}{params.TimeFrame.From.UTC(), params.TimeFrame.To.UTC(), params.WebsiteID}, segmentArgs...)
+	args = append(args, searchArgs...)
+	args = append(args, events.EmptyUTMAttr, params.Limit, params.Offset)
+	err := db.Raw(query, args...).Scan(&results).Error
 	if err != nil {
 		return []MetricCountResult{}, nil
 	}
@@ -74,27 +75,26 @@ func GetTopUTMSourcesInTimeFrame(db *gorm.DB, params WebsiteScopedQueryParams) (
 func GetTopUTMCampaignsInTimeFrame(db *gorm.DB, params WebsiteScopedQueryParams) ([]MetricCountResult, error) {
 	var results []MetricCountResult
 
-	query := `
+	segment, segmentArgs := segmentClause(db, params, "utm_stats")
+	search, searchArgs := searchClause(params, "utm_campaign")
+	query := fmt.Sprintf(`
 		SELECT
 			utm_campaign AS name,
 			SUM(visitors_count) AS count
 		FROM utm_stats
 		WHERE hour BETWEEN ? AND ?
-        AND website_id = ?
+        AND website_id = ?%s%s
         AND utm_campaign != '' AND utm_campaign != ?
 		GROUP BY utm_campaign
 		HAVING count > 0
 		ORDER BY count DESC
-		LIMIT ?
-	`
+		LIMIT ? OFFSET ?
+	`, segment, search)
 
-	err := db.Raw(query,
-		params.TimeFrame.From.UTC(),
-		params.TimeFrame.To.UTC(),
-		params.WebsiteID,
-		events.EmptyUTMAttr,
-		params.Limit,
-	).Scan(&results).Error
+	args := append([]interface{}{params.TimeFrame.From.UTC(), params.TimeFrame.To.UTC(), params.WebsiteID}, segmentArgs...)
+	args = append(args, searchArgs...)
+	args = append(args, events.EmptyUTMAttr, params.Limit, params.Offset)
+	err := db.Raw(query, args...).Scan(&results).Error
 	if err != nil {
 		return []MetricCountResult{}, nil
 	}
@@ -106,27 +106,26 @@ func GetTopUTMCampaignsInTimeFrame(db *gorm.DB, params WebsiteScopedQueryParams)
 func GetTopUTMTermsInTimeFrame(db *gorm.DB, params WebsiteScopedQueryParams) ([]MetricCountResult, error) {
 	var results []MetricCountResult
 
-	query := `
+	segment, segmentArgs := segmentClause(db, params, "utm_stats")
+	search, searchArgs := searchClause(params, "utm_term")
+	query := fmt.Sprintf(`
 		SELECT
 			utm_term AS name,
 			SUM(visitors_count) AS count
 		FROM utm_stats
 		WHERE hour BETWEEN ? AND ?
-        AND website_id = ?
+        AND website_id = ?%s%s
         AND utm_term != '' AND utm_term != ?
 		GROUP BY utm_term
 		HAVING count > 0
 		ORDER BY count DESC
-		LIMIT ?
-	`
+		LIMIT ? OFFSET ?
+	`, segment, search)
 
-	err := db.Raw(query,
-		params.TimeFrame.From.UTC(),
-		params.TimeFrame.To.UTC(),
-		params.WebsiteID,
-		events.EmptyUTMAttr,
-		params.Limit,
-	).Scan(&results).Error
+	args := append([]interface{}{params.TimeFrame.From.UTC(), params.TimeFrame.To.UTC(), params.WebsiteID}, segmentArgs...)
+	args = append(args, searchArgs...)
+	args = append(args, events.EmptyUTMAttr, params.Limit, params.Offset)
+	err := db.Raw(query, args...).Scan(&results).Error
 	if err != nil {
 		return []MetricCountResult{}, nil
 	}
@@ -138,29 +137,225 @@ func GetTopUTMTermsInTimeFrame(db *gorm.DB, params WebsiteScopedQueryParams) ([]
 func GetTopUTMContentsInTimeFrame(db *gorm.DB, params WebsiteScopedQueryParams) ([]MetricCountResult, error) {
 	var results []MetricCountResult
 
-	query := `
+	segment, segmentArgs := segmentClause(db, params, "utm_stats")
+	search, searchArgs := searchClause(params, "utm_content")
+	query := fmt.Sprintf(`
 		SELECT
 			utm_content AS name,
 			SUM(visitors_count) AS count
 		FROM utm_stats
 		WHERE hour BETWEEN ? AND ?
-        AND website_id = ?
+        AND website_id = ?%s%s
         AND utm_content != '' AND utm_content != ?
 		GROUP BY utm_content
 		HAVING count > 0
 		ORDER BY count DESC
-		LIMIT ?
-	`
+		LIMIT ? OFFSET ?
+	`, segment, search)
+
+	args := append([]interface{}{params.TimeFrame.From.UTC(), params.TimeFrame.To.UTC(), params.WebsiteID}, segmentArgs...)
+	args = append(args, searchArgs...)
+	args = append(args, events.EmptyUTMAttr, params.Limit, params.Offset)
+	err := db.Raw(query, args...).Scan(&results).Error
+	if err != nil {
+		return []MetricCountResult{}, nil
+	}
+
+	return results, nil
+}
+
+// UTMConversionResult is a UTM term or content with its visitors and the
+// goal-converting sessions attributed to it
+type UTMConversionResult struct {
+	Name        string `json:"name"`
+	Count       int64  `json:"count"`
+	Conversions int64  `json:"conversions"`
+}
+
+// goalSessionUTM is the UTM term and content of a session that fired a goal
+type goalSessionUTM struct {
+	UTMTerm    string
+	UTMContent string
+}
+
+// getGoalSessionUTMs returns one row per session that fired any of the
+// conversion goals in the time frame, carrying the UTM term and content of the
+// session's first event. Sessions are split over the visitor's events the way
+// the session timeout does during processing, as for revenue attribution.
+func getGoalSessionUTMs(db *gorm.DB, params WebsiteScopedQueryParams, conversionGoals []string) ([]goalSessionUTM, error) {
+	var sessions []goalSessionUTM
+	if len(conversionGoals) == 0 {
+		return sessions, nil
+	}
+
+	query := `
+    WITH goal_events AS (
+        SELECT id, user_signature, timestamp
+        FROM events
+        WHERE website_id = ?
+        AND timestamp BETWEEN ? AND ?
+        AND event_type = ?
+        AND custom_event_name IN ?
+    ),
+    ranked_events AS (
+        SELECT
+            id,
+            user_signature,
+            timestamp,
+            utm_term,
+            utm_content,
+            LAG(timestamp) OVER (
+                PARTITION BY user_signature
+                ORDER BY timestamp, id
+            ) as prev_event_time
+        FROM events
+        WHERE website_id = ?
+        AND timestamp <= ?
+        AND user_signature IN (SELECT user_signature FROM goal_events)
+    ),
+    session_starts AS (
+        SELECT id, user_signature, timestamp, utm_term, utm_content
+        FROM ranked_events
+        WHERE prev_event_time IS NULL
+        OR CAST((JULIANDAY(timestamp) - JULIANDAY(prev_event_time)) * 86400 as INTEGER) > ?
+    ),
+    goal_sessions AS (
+        SELECT DISTINCT (
+            SELECT s.id FROM session_starts s
+            WHERE s.user_signature = g.user_signature
+            AND (s.timestamp < g.timestamp OR (s.timestamp = g.timestamp AND s.id <= g.id))
+            ORDER BY s.timestamp DESC, s.id DESC
+            LIMIT 1
+        ) AS start_id
+        FROM goal_events g
+    )
+    SELECT
+        COALESCE(s.utm_term, '') AS utm_term,
+        COALESCE(s.utm_content, '') AS utm_content
+    FROM goal_sessions gs
+    JOIN session_starts s ON s.id = gs.start_id
+    `
 
 	err := db.Raw(query,
+		params.WebsiteID,
 		params.TimeFrame.From.UTC(),
 		params.TimeFrame.To.UTC(),
+		events.EventTypeCustomEvent,
+		conversionGoals,
 		params.WebsiteID,
-		events.EmptyUTMAttr,
-		params.Limit,
-	).Scan(&results).Error
+		params.TimeFrame.To.UTC(),
+		websiteSessionTimeoutSeconds(db, params.WebsiteID),
+	).Scan(&sessions).Error
 	if err != nil {
-		return []MetricCountResult{}, nil
+		return nil, fmt.Errorf("error attributing goal conversions to UTM parameters: %w", err)
+	}
+
+	return sessions, nil
+}
+
+// withUTMConversions pairs top UTM rows with the number of goal-converting
+// sessions whose UTM value (picked by value) matches the row name. Sessions
+// without that UTM parameter are left out of the breakdown.
+func withUTMConversions(rows []MetricCountResult, sessions []goalSessionUTM, value func(goalSessionUTM) string) []UTMConversionResult {
+	conversions := make(map[string]int64)
+	for _, session := range sessions {
+		if name := value(session); name != "" && name != events.EmptyUTMAttr {
+			conversions[name]++
+		}
+	}
+
+	results := make([]UTMConversionResult, len(rows))
+	for i, row := range rows {
+		results[i] = UTMConversionResult{Name: row.Name, Count: row.Count, Conversions: conversions[row.Name]}
+	}
+	return results
+}
+
+// GetTopUTMTermConversions returns the top UTM terms with the goal conversions attributed to each
+func GetTopUTMTermConversions(db *gorm.DB, params WebsiteScopedQueryParams, conversionGoals []string) ([]UTMConversionResult, error) {
+	rows, err := GetTopUTMTermsInTimeFrame(db, params)
+	if err != nil {
+		return nil, err
+	}
+	sessions, err := getGoalSessionUTMs(db, params, conversionGoals)
+	if err != nil {
+		return nil, err
+	}
+	return withUTMConversions(rows, sessions, func(s goalSessionUTM) string { return s.UTMTerm }), nil
+}
+
+// GetTopUTMContentConversions returns the top UTM contents with the goal conversions attributed to each
+func GetTopUTMContentConversions(db *gorm.DB, params WebsiteScopedQueryParams, conversionGoals []string) ([]UTMConversionResult, error) {
+	rows, err := GetTopUTMContentsInTimeFrame(db, params)
+	if err != nil {
+		return nil, err
+	}
+	sessions, err := getGoalSessionUTMs(db, params, conversionGoals)
+	if err != nil {
+		return nil, err
+	}
+	return withUTMConversions(rows, sessions, func(s goalSessionUTM) string { return s.UTMContent }), nil
+}
+
+// UTMCombinationResult is one source / medium / campaign tuple with its traffic.
+// Name joins the three parts for display, with missing parts shown as "(none)".
+type UTMCombinationResult struct {
+	Name      string `json:"name"`
+	Source    string `json:"source"`
+	Medium    string `json:"medium"`
+	Campaign  string `json:"campaign"`
+	Count     int64  `json:"count"`
+	PageViews int64  `json:"page_views"`
+}
+
+// utmCombinationNone stands in for a missing part of a UTM combination's name
+const utmCombinationNone = "(none)"
+
+// GetUTMCombinationsInTimeFrame fetches the top UTM source / medium / campaign
+// tuples, so a campaign can be read as one row instead of three lists. Tuples
+// without any of the three parameters are left out.
+func GetUTMCombinationsInTimeFrame(db *gorm.DB, params WebsiteScopedQueryParams) ([]UTMCombinationResult, error) {
+	var results []UTMCombinationResult
+
+	// Tagged-but-empty parameters are stored as EmptyUTMAttr; both mean "not set" here
+	part := func(column string) string {
+		return fmt.Sprintf("COALESCE(NULLIF(NULLIF(%s, ?), ''), '')", column)
+	}
+	segment, segmentArgs := segmentClause(db, params, "utm_stats")
+	search, searchArgs := searchClause(params, "utm_source || ' ' || utm_medium || ' ' || utm_campaign")
+	query := fmt.Sprintf(`
+		SELECT
+			%s AS source,
+			%s AS medium,
+			%s AS campaign,
+			SUM(visitors_count) AS count,
+			SUM(page_views_count) AS page_views
+		FROM utm_stats
+		WHERE hour BETWEEN ? AND ?
+        AND website_id = ?%s%s
+		GROUP BY 1, 2, 3
+		HAVING count > 0 AND (source != '' OR medium != '' OR campaign != '')
+		ORDER BY count DESC, page_views DESC, source, medium, campaign
+		LIMIT ? OFFSET ?
+	`, part("utm_source"), part("utm_medium"), part("utm_campaign"), segment, search)
+
+	args := []interface{}{events.EmptyUTMAttr, events.EmptyUTMAttr, events.EmptyUTMAttr, params.TimeFrame.From.UTC(), params.TimeFrame.To.UTC(), params.WebsiteID}
+	args = append(args, segmentArgs...)
+	args = append(args, searchArgs...)
+	args = append(args, params.Limit, params.Offset)
+	err := db.Raw(query, args...).Scan(&results).Error
+	if err != nil {
+		return []UTMCombinationResult{}, nil
+	}
+
+	for i := range results {
+		parts := []string{results[i].Source, results[i].Medium, results[i].Campaign}
+		for j, p := range parts {
+			if p == "" {
+				parts[j] = utmCombinationNone
+			}
+		}
+		results[i].Name = strings.Join(parts, " / ")
 	}
 
 	return results, nil
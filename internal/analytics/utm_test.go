@@ -5,8 +5,11 @@ import (
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"fusionaly/internal/analytics"
+	"fusionaly/internal/events"
+	"fusionaly/internal/settings"
 	"fusionaly/internal/timeframe"
 	"fusionaly/internal/testsupport"
 )
@@ -151,3 +154,129 @@ func TestGetTopUTMContentsInTimeFrame(t *testing.T) {
 		{Name: "banner2", Count: 1},
 	}, results)
 }
+
+func TestGetTopUTMConversions(t *testing.T) {
+	dbManager, logger, website := testsupport.SetupTestDBManagerWithWebsite(t, "shop.com")
+	db := dbManager.GetConnection()
+	require.NoError(t, settings.SaveWebsiteGoals(db, website.ID, []string{"signup"}))
+
+	hour := time.Date(2024, 7, 1, 10, 0, 0, 0, time.UTC)
+	stats := []analytics.UTMStat{
+		{WebsiteID: website.ID, UTMTerm: "shoes", UTMContent: "banner1", VisitorsCount: 2, Hour: hour},
+		{WebsiteID: website.ID, UTMTerm: "bags", UTMContent: "banner2", VisitorsCount: 1, Hour: hour},
+	}
+	require.NoError(t, db.CreateInBatches(stats, len(stats)).Error)
+
+	base := hour.Add(5 * time.Minute)
+	pageView := func(user, term, content string, ts time.Time) events.Event {
+		return events.Event{
+			WebsiteID:     website.ID,
+			UserSignature: user,
+			Hostname:      website.Domain,
+			Pathname:      "/",
+			UTMTerm:       term,
+			UTMContent:    content,
+			EventType:     events.EventTypePageView,
+			Timestamp:     ts,
+			CreatedAt:     ts,
+		}
+	}
+	customEvent := func(user, name string, ts time.Time) events.Event {
+		return events.Event{
+			WebsiteID:       website.ID,
+			UserSignature:   user,
+			Hostname:        website.Domain,
+			Pathname:        "/signup",
+			EventType:       events.EventTypeCustomEvent,
+			CustomEventName: name,
+			Timestamp:       ts,
+			CreatedAt:       ts,
+		}
+	}
+
+	testEvents := []events.Event{
+		// Converts in the session that landed from the ad
+		pageView("user-1", "shoes", "banner1", base),
+		pageView("user-1", "", "", base.Add(time.Minute)),
+		customEvent("user-1", "signup", base.Add(2*time.Minute)),
+		customEvent("user-1", "signup", base.Add(3*time.Minute)), // Same session counts once
+
+		// Visits from the ad but never converts
+		pageView("user-2", "shoes", "banner1", base),
+		customEvent("user-2", "newsletter", base.Add(time.Minute)),
+
+		// Converts in a later session without UTM parameters
+		pageView("user-3", "bags", "banner2", base),
+		pageView("user-3", "", "", base.Add(3*time.Hour)),
+		customEvent("user-3", "signup", base.Add(3*time.Hour+time.Minute)),
+	}
+	for _, event := range testEvents {
+		require.NoError(t, db.Create(&event).Error)
+	}
+
+	params := analytics.NewWebsiteScopedQueryParams(setupTimeFrame(t), int(website.ID))
+
+	t.Run("by term", func(t *testing.T) {
+		results, err := analytics.GetTopUTMTermConversions(db, params, []string{"signup"})
+		require.NoError(t, err)
+		assert.Equal(t, []analytics.UTMConversionResult{
+			{Name: "shoes", Count: 2, Conversions: 1},
+			{Name: "bags", Count: 1, Conversions: 0},
+		}, results)
+	})
+
+	t.Run("by content", func(t *testing.T) {
+		results, err := analytics.GetTopUTMContentConversions(db, params, []string{"signup"})
+		require.NoError(t, err)
+		assert.Equal(t, []analytics.UTMConversionResult{
+			{Name: "banner1", Count: 2, Conversions: 1},
+			{Name: "banner2", Count: 1, Conversions: 0},
+		}, results)
+	})
+
+	t.Run("no goals", func(t *testing.T) {
+		results, err := analytics.GetTopUTMTermConversions(db, params, nil)
+		require.NoError(t, err)
+		assert.Equal(t, []analytics.UTMConversionResult{
+			{Name: "shoes", Count: 2, Conversions: 0},
+			{Name: "bags", Count: 1, Conversions: 0},
+		}, results)
+	})
+
+	t.Run("dashboard", func(t *testing.T) {
+		metrics, err := analytics.FetchDashboardMetricsSubset(db, setupTimeFrame(t), int(website.ID), nil, []string{"utm_term_conversions"}, 10, logger)
+		require.NoError(t, err)
+		results, ok := metrics["utm_term_conversions"].([]analytics.UTMConversionResult)
+		require.True(t, ok)
+		require.Len(t, results, 2)
+		assert.Equal(t, int64(1), results[0].Conversions)
+	})
+}
+
+func TestGetUTMCombinationsInTimeFrame(t *testing.T) {
+	dbManager, _ := testsupport.SetupTestDBManager(t)
+	db := dbManager.GetConnection()
+
+	testData := []analytics.UTMStat{
+		{WebsiteID: 1, UTMSource: "google", UTMMedium: "cpc", UTMCampaign: "spring_sale", VisitorsCount: 2, PageViewsCount: 3, Hour: time.Date(2024, 7, 1, 10, 0, 0, 0, time.UTC)},
+		// Same tuple in another hour and with a different term merges into one row
+		{WebsiteID: 1, UTMSource: "google", UTMMedium: "cpc", UTMCampaign: "spring_sale", UTMTerm: "shoes", VisitorsCount: 1, PageViewsCount: 2, Hour: time.Date(2024, 7, 1, 11, 0, 0, 0, time.UTC)},
+		{WebsiteID: 1, UTMSource: "google", UTMMedium: "email", UTMCampaign: "spring_sale", VisitorsCount: 1, PageViewsCount: 1, Hour: time.Date(2024, 7, 1, 12, 0, 0, 0, time.UTC)},
+		// Missing parts are shown as "(none)"
+		{WebsiteID: 1, UTMSource: "newsletter", UTMMedium: events.EmptyUTMAttr, UTMCampaign: events.EmptyUTMAttr, VisitorsCount: 1, PageViewsCount: 1, Hour: time.Date(2024, 7, 1, 12, 0, 0, 0, time.UTC)},
+		// Untagged traffic and other websites are left out
+		{WebsiteID: 1, UTMSource: events.EmptyUTMAttr, UTMMedium: events.EmptyUTMAttr, UTMCampaign: events.EmptyUTMAttr, VisitorsCount: 5, PageViewsCount: 5, Hour: time.Date(2024, 7, 1, 12, 0, 0, 0, time.UTC)},
+		{WebsiteID: 2, UTMSource: "google", UTMMedium: "cpc", UTMCampaign: "spring_sale", VisitorsCount: 9, PageViewsCount: 9, Hour: time.Date(2024, 7, 1, 12, 0, 0, 0, time.UTC)},
+	}
+
+	require.NoError(t, db.CreateInBatches(testData, len(testData)).Error)
+
+	params := analytics.NewWebsiteScopedQueryParams(setupTimeFrame(t), 1)
+	results, err := analytics.GetUTMCombinationsInTimeFrame(db, params)
+	require.NoError(t, err)
+	assert.Equal(t, []analytics.UTMCombinationResult{
+		{Name: "google / cpc / spring_sale", Source: "google", Medium: "cpc", Campaign: "spring_sale", Count: 3, PageViews: 5},
+		{Name: "google / email / spring_sale", Source: "google", Medium: "email", Campaign: "spring_sale", Count: 1, PageViews: 1},
+		{Name: "newsletter / (none) / (none)", Source: "newsletter", Count: 1, PageViews: 1},
+	}, results)
+}
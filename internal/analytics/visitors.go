@@ -32,28 +32,26 @@ func aggregatedVisitorsInTimeFrameRaw(db *gorm.DB, params WebsiteScopedQueryPara
 		return nil, err
 	}
 
-	// Query to get aggregated visitors from SiteStat
+	// Query to get aggregated visitors from SiteStat, or the segment's dimension table
+	source := siteSource(db, params)
 	query := fmt.Sprintf(`
         SELECT
             %s AS date,
-            COALESCE(SUM(visitors), 0) AS count
+            COALESCE(SUM(%s), 0) AS count
         FROM
-            site_stats
+            %s
         WHERE
             hour >= ? AND hour <= ?
-            AND website_id = ?
+            AND website_id = ?%s
         GROUP BY
             %s
         ORDER BY
             date ASC
-    `, groupByExpression, groupByExpression)
+    `, groupByExpression, source.Visitors, source.Table, source.Where, groupByExpression)
 
 	// Execute the query
-	err = db.Raw(query,
-		params.TimeFrame.From.UTC(),
-		params.TimeFrame.To.UTC(),
-		params.WebsiteID,
-	).Scan(&results).Error
+	args := append([]interface{}{params.TimeFrame.From.UTC(), params.TimeFrame.To.UTC(), params.WebsiteID}, source.Args...)
+	err = db.Raw(query, args...).Scan(&results).Error
 	if err != nil {
 		return nil, fmt.Errorf("error fetching aggregated visitors: %w", err)
 	}
@@ -11,6 +11,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"fusionaly/internal/events"
 	"fusionaly/internal/testsupport"
 )
 
@@ -317,3 +318,86 @@ func TestVisitorsEdgeCases(t *testing.T) {
 		})
 	}
 }
+
+func TestNewVsReturningVisitors(t *testing.T) {
+	dbManager, logger, website := testsupport.SetupTestDBManagerWithWebsite(t, "returning.com")
+	db := dbManager.GetConnection()
+
+	base := time.Date(2024, 7, 1, 10, 0, 0, 0, time.UTC)
+	visits := []struct {
+		visitor string
+		offset  time.Duration
+	}{
+		{"single-session", 0},
+		{"single-session", 5 * time.Minute},
+		{"returns-twice", 0},
+		{"returns-twice", time.Hour},     // first return
+		{"returns-twice", 2 * time.Hour}, // second return, counted once
+		{"one-page", 10 * time.Minute},
+	}
+	for _, visit := range visits {
+		ts := base.Add(visit.offset)
+		require.NoError(t, db.Create(&events.IngestedEvent{
+			WebsiteID:        website.ID,
+			UserSignature:    visit.visitor,
+			Hostname:         website.Domain,
+			Pathname:         "/",
+			RawURL:           "https://" + website.Domain + "/",
+			ReferrerHostname: events.DirectOrUnknownReferrer,
+			EventType:        events.EventTypePageView,
+			Timestamp:        ts,
+			UserAgent:        "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36",
+			Country:          "US",
+			CreatedAt:        ts,
+		}).Error)
+	}
+	require.NoError(t, testsupport.ProcessAllTestEvents(dbManager, logger))
+
+	var stats []analytics.SiteStat
+	require.NoError(t, db.Where("website_id = ?", website.ID).Find(&stats).Error)
+	for _, stat := range stats {
+		assert.Equal(t, stat.Visitors, stat.NewVisitors+stat.ReturningVisitors, "split must add up for bucket %s", stat.Hour)
+	}
+
+	tf, err := timeframe.NewTimeFrame(timeframe.TimeFrameParams{
+		FromTime:      time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC),
+		ToTime:        time.Date(2024, 7, 1, 23, 59, 59, 0, time.UTC),
+		TimeFrameSize: timeframe.HourlyTimeFrame,
+	}, time.UTC)
+	require.NoError(t, err)
+	params := analytics.NewWebsiteScopedQueryParams(tf, int(website.ID))
+
+	split, err := analytics.GetNewVsReturningInTimeFrame(db, params)
+	require.NoError(t, err)
+	require.NotNil(t, split)
+	assert.Equal(t, int64(2), split.NewVisitors)
+	assert.Equal(t, int64(1), split.ReturningVisitors)
+
+	total, err := analytics.GetTotalVisitorsInTimeFrame(db, params)
+	require.NoError(t, err)
+	assert.Equal(t, total, split.NewVisitors+split.ReturningVisitors)
+
+	seriesTotal := 0
+	for _, point := range split.ReturningSeries {
+		seriesTotal += point.Count
+	}
+	assert.Equal(t, 1, seriesTotal)
+
+	t.Run("not available for segments", func(t *testing.T) {
+		segmented := params
+		segmented.Filters = analytics.NewSegmentFilters(map[string]string{analytics.FilterBrowser: "Chrome"})
+		split, err := analytics.GetNewVsReturningInTimeFrame(db, segmented)
+		require.NoError(t, err)
+		assert.Nil(t, split)
+	})
+
+	t.Run("backfills rows aggregated before the split", func(t *testing.T) {
+		legacy := analytics.SiteStat{WebsiteID: website.ID, Visitors: 4, PageViews: 6, Hour: time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)}
+		require.NoError(t, db.Create(&legacy).Error)
+		require.NoError(t, analytics.BackfillNewVisitors(db))
+
+		require.NoError(t, db.First(&legacy, legacy.ID).Error)
+		assert.Equal(t, 4, legacy.NewVisitors)
+		assert.Equal(t, 0, legacy.ReturningVisitors)
+	})
+}
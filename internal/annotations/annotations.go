@@ -17,6 +17,12 @@ const (
 	AnnotationGeneral    AnnotationType = "general"
 )
 
+// MinAnnotationDate is the earliest date an annotation can be placed on
+var MinAnnotationDate = time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// maxAnnotationLeadTime bounds how far ahead planned events can be annotated
+const maxAnnotationLeadTime = 365 * 24 * time.Hour
+
 // Annotation represents a marker on the dashboard timeline
 type Annotation struct {
 	ID             uint           `gorm:"primaryKey;autoIncrement" json:"id"`
@@ -55,6 +61,17 @@ func IsValidAnnotationType(t AnnotationType) bool {
 	return false
 }
 
+// ValidateAnnotationDate checks the date is between MinAnnotationDate and one year from now
+func ValidateAnnotationDate(date time.Time) error {
+	if date.Before(MinAnnotationDate) {
+		return fmt.Errorf("annotation date must be on or after %s", MinAnnotationDate.Format("2006-01-02"))
+	}
+	if date.After(time.Now().Add(maxAnnotationLeadTime)) {
+		return fmt.Errorf("annotation date must be within a year from now")
+	}
+	return nil
+}
+
 // CreateAnnotation creates a new annotation in the database
 func CreateAnnotation(db *gorm.DB, annotation *Annotation) error {
 	if annotation.Title == "" {
@@ -66,6 +83,9 @@ func CreateAnnotation(db *gorm.DB, annotation *Annotation) error {
 	if annotation.AnnotationDate.IsZero() {
 		return fmt.Errorf("annotation date is required")
 	}
+	if err := ValidateAnnotationDate(annotation.AnnotationDate); err != nil {
+		return err
+	}
 
 	// Set defaults
 	if annotation.AnnotationType == "" {
@@ -125,6 +145,9 @@ func UpdateAnnotation(db *gorm.DB, annotation *Annotation) error {
 	if annotation.Title == "" {
 		return fmt.Errorf("annotation title is required")
 	}
+	if err := ValidateAnnotationDate(annotation.AnnotationDate); err != nil {
+		return err
+	}
 
 	annotation.UpdatedAt = time.Now().UTC()
 
@@ -355,6 +355,42 @@ func TestUpdateAnnotation_Validation(t *testing.T) {
 	}
 }
 
+func TestValidateAnnotationDate(t *testing.T) {
+	tests := []struct {
+		name    string
+		date    time.Time
+		wantErr bool
+	}{
+		{"today", time.Now(), false},
+		{"earliest allowed", MinAnnotationDate, false},
+		{"planned launch next month", time.Now().AddDate(0, 1, 0), false},
+		{"before 2000", time.Date(1999, 12, 31, 0, 0, 0, 0, time.UTC), true},
+		{"more than a year ahead", time.Now().AddDate(1, 1, 0), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateAnnotationDate(tt.date)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateAnnotationDate(%s) error = %v, wantErr %v", tt.date, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCreateAnnotation_RejectsOutOfRangeDate(t *testing.T) {
+	db := setupTestDB(t)
+
+	err := CreateAnnotation(db, &Annotation{
+		WebsiteID:      1,
+		Title:          "Typo year",
+		AnnotationDate: time.Date(202, 5, 1, 0, 0, 0, 0, time.UTC),
+	})
+	if err == nil {
+		t.Error("expected error for a date before 2000, got nil")
+	}
+}
+
 func TestDeleteAnnotation(t *testing.T) {
 	db := setupTestDB(t)
 
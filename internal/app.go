@@ -2,21 +2,36 @@
 package internal
 
 import (
+	"context"
 	"fmt"
 	"io/fs"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
 
 	"github.com/karloscodes/cartridge"
 	"github.com/karloscodes/cartridge/inertia"
 
+	"fusionaly/internal/analytics"
 	"fusionaly/internal/config"
 	"fusionaly/internal/database"
+	"fusionaly/internal/events"
+	"fusionaly/internal/health"
 	"fusionaly/internal/jobs"
+	"fusionaly/internal/metrics"
 )
 
 // Application wraps cartridge.Application with fusionaly-specific components
 type Application struct {
 	*cartridge.Application
 	DBManager *database.DBManager // Fusionaly-specific DB manager with migration methods
+
+	jobs         *jobs.Scheduler
+	ingestWriter *events.BufferedWriter // nil unless ingestion is buffered
+	drainTimeout time.Duration
+	shutdownOnce sync.Once
+	shutdownErr  error
 }
 
 // AppOption configures the application
@@ -77,7 +92,7 @@ func NewAppWithConfig(cfg *config.Config, opts ...AppOption) (*Application, erro
 	}
 
 	// Create logger
-	logger := cartridge.NewLogger(cfg, nil)
+	logger := newLogger(cfg, os.Stdout)
 
 	// Initialize database manager (fusionaly-specific with migration methods)
 	dbManager := database.NewDBManager(cfg, logger)
@@ -85,6 +100,23 @@ func NewAppWithConfig(cfg *config.Config, opts ...AppOption) (*Application, erro
 		return nil, fmt.Errorf("failed to initialize database: %w", err)
 	}
 
+	health.SetBacklogThreshold(int64(cfg.ReadinessBacklogThreshold))
+	analytics.SetDashboardCacheTTL(time.Duration(cfg.DashboardCacheTTLSeconds) * time.Second)
+
+	// Expose application internals on /metrics when enabled
+	if cfg.MetricsEnabled {
+		metrics.RegisterCore(metrics.Default)
+	}
+
+	// Buffered ingestion: collected events are written in batches by a background writer
+	var ingestWriter *events.BufferedWriter
+	if cfg.IngestionMode == config.IngestionModeBuffered {
+		ingestWriter = events.NewBufferedWriter(dbManager, logger, cfg.IngestBufferSize, cfg.IngestFlushBatchSize,
+			time.Duration(cfg.IngestFlushIntervalMilliseconds)*time.Millisecond)
+		ingestWriter.Start()
+		events.SetBufferedWriter(ingestWriter)
+	}
+
 	// Initialize jobs system
 	jobsManager, err := jobs.NewJobs(dbManager, logger)
 	if err != nil {
@@ -96,6 +128,7 @@ func NewAppWithConfig(cfg *config.Config, opts ...AppOption) (*Application, erro
 	serverConfig := cartridge.DefaultServerConfig()
 	serverConfig.SecFetchSiteAllowedValues = []string{"cross-site", "same-site", "same-origin"}
 	serverConfig.ProxyHeader = "X-Forwarded-For"
+	serverConfig.EnableRequestLogger = false // Replaced by middleware.RequestLogger, which adds the website
 
 	// Static assets: embedded in production, disk in development
 	if !cfg.IsDevelopment() && options.staticFS != nil {
@@ -118,7 +151,47 @@ func NewAppWithConfig(cfg *config.Config, opts ...AppOption) (*Application, erro
 	}
 
 	return &Application{
-		Application: app,
-		DBManager:   dbManager,
+		Application:  app,
+		DBManager:    dbManager,
+		jobs:         jobsManager,
+		ingestWriter: ingestWriter,
+		drainTimeout: time.Duration(cfg.ShutdownDrainTimeoutSeconds) * time.Second,
 	}, nil
 }
+
+// Shutdown stops the application without losing events. The HTTP server stops
+// first so in-flight ingests finish writing and buffered events are flushed,
+// then background jobs drain the current aggregation batch and run a final
+// pass (skipped once the drain timeout or ctx expires), and only after the
+// batch has returned is the database checkpointed and closed. Safe to call
+// more than once.
+func (a *Application) Shutdown(ctx context.Context) error {
+	a.shutdownOnce.Do(func() {
+		if err := a.Server.Shutdown(ctx); err != nil {
+			a.Logger.Warn("HTTP server shutdown error", slog.Any("error", err))
+		}
+
+		if a.ingestWriter != nil {
+			events.SetBufferedWriter(nil)
+			a.ingestWriter.Stop()
+		}
+
+		drainCtx := ctx
+		if a.drainTimeout > 0 {
+			var cancel context.CancelFunc
+			drainCtx, cancel = context.WithTimeout(ctx, a.drainTimeout)
+			defer cancel()
+		}
+		if err := a.jobs.Drain(drainCtx); err != nil {
+			a.shutdownErr = fmt.Errorf("failed to drain background jobs: %w", err)
+		}
+
+		if err := a.DBManager.CheckpointWAL("TRUNCATE"); err != nil {
+			a.Logger.Warn("Failed to checkpoint WAL on shutdown", slog.Any("error", err))
+		}
+		if err := a.DBManager.Close(); err != nil && a.shutdownErr == nil {
+			a.shutdownErr = fmt.Errorf("failed to close database: %w", err)
+		}
+	})
+	return a.shutdownErr
+}
@@ -4,8 +4,11 @@ package config
 import (
 	"fmt"
 	"log"
+	"net/netip"
 	"path/filepath"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/spf13/viper"
 )
@@ -28,11 +31,23 @@ const (
 	LogLevelError LogLevel = "error"
 )
 
+// Log formats
+const (
+	LogFormatText = "text"
+	LogFormatJSON = "json"
+)
+
 // Database types
 const (
 	SQLiteDatabase = "sqlite"
 )
 
+// Ingestion modes
+const (
+	IngestionModeSync     = "sync"     // each collected event is written in its own transaction
+	IngestionModeBuffered = "buffered" // events are queued in memory and written in batches
+)
+
 // Config holds all configuration parameters for the application
 type Config struct {
 	// Application settings
@@ -40,6 +55,7 @@ type Config struct {
 	AppPort               string   `mapstructure:"appport"`
 	Environment           string   `mapstructure:"environment"`
 	LogLevel              LogLevel `mapstructure:"loglevel"`
+	LogFormat             string   `mapstructure:"logformat"`
 	PrivateKey            string `mapstructure:"privatekey"`
 	SessionTimeoutSeconds      int `mapstructure:"sessiontimeoutseconds"`
 	LoginSessionTimeoutSeconds int `mapstructure:"loginsessiontimeoutseconds"`
@@ -71,8 +87,69 @@ type Config struct {
 	// Job scheduling settings
 	JobIntervalSeconds int `mapstructure:"jobintervalseconds"`
 
+	// Events processed per transaction; grows with the backlog up to the max
+	ProcessingBatchSize    int `mapstructure:"processingbatchsize"`
+	ProcessingMaxBatchSize int `mapstructure:"processingmaxbatchsize"`
+
+	// Maximum time shutdown waits for in-flight event processing to drain
+	ShutdownDrainTimeoutSeconds int `mapstructure:"shutdowndraintimeoutseconds"`
+
+	// Aggregation lag warning: backlog size and how long it must persist
+	AggregationLagThreshold        int `mapstructure:"aggregationlagthreshold"`
+	AggregationLagWarnAfterSeconds int `mapstructure:"aggregationlagwarnafterseconds"`
+
+	// Backlog size above which /_ready reports not ready (0 disables the check)
+	ReadinessBacklogThreshold int `mapstructure:"readinessbacklogthreshold"`
+
+	// Minimum seconds between partial "today" recomputes per website (0 = every job run)
+	PartialAggregationIntervalSeconds int `mapstructure:"partialaggregationintervalseconds"`
+
+	// Lifetime of cached dashboard metrics (0 disables the cache)
+	DashboardCacheTTLSeconds int `mapstructure:"dashboardcachettlseconds"`
+
+	// Maximum number of events accepted by the batch ingestion endpoint
+	MaxEventBatchSize int `mapstructure:"maxeventbatchsize"`
+
+	// Buffered ingestion queues events in memory (up to the buffer size) and
+	// writes them in batches, once a batch is full or every flush interval
+	IngestionMode                   string `mapstructure:"ingestionmode"`
+	IngestBufferSize                int    `mapstructure:"ingestbuffersize"`
+	IngestFlushBatchSize            int    `mapstructure:"ingestflushbatchsize"`
+	IngestFlushIntervalMilliseconds int    `mapstructure:"ingestflushintervalms"`
+
 	// Data retention settings
 	IngestedEventsRetentionDays int `mapstructure:"ingestedeventsretentiondays"`
+	// Raw events older than this are purged; aggregates are kept (0 keeps raw events forever)
+	RawEventsRetentionDays int `mapstructure:"raweventsretentiondays"`
+
+	// Aggregate full language tags (en-US) instead of base languages (en)
+	LanguageFullTag bool `mapstructure:"languagefulltag"`
+
+	// Scheduled database backups (interval 0 disables them)
+	BackupIntervalHours  int `mapstructure:"backupintervalhours"`
+	BackupRetentionCount int `mapstructure:"backupretentioncount"`
+	BackupRetentionDays  int `mapstructure:"backupretentiondays"`
+
+	// Comma-separated CIDRs or IPs of reverse proxies whose forwarding headers
+	// are trusted (empty trusts loopback and private-network peers only)
+	TrustedProxies string `mapstructure:"trustedproxies"`
+
+	// Prometheus /metrics endpoint (disabled by default; optional bearer token)
+	MetricsEnabled bool   `mapstructure:"metricsenabled"`
+	MetricsToken   string `mapstructure:"metricstoken"`
+
+	// Outgoing mail (empty host disables email)
+	SMTPHost     string `mapstructure:"smtphost"`
+	SMTPPort     int    `mapstructure:"smtpport"`
+	SMTPUsername string `mapstructure:"smtpusername"`
+	SMTPPassword string `mapstructure:"smtppassword"`
+	SMTPFrom     string `mapstructure:"smtpfrom"`
+
+	// Weekly stats digest, sent on DigestWeekday at DigestHour (UTC) to opted-in
+	// users plus the comma-separated DigestRecipients
+	DigestWeekday    string `mapstructure:"digestweekday"`
+	DigestHour       int    `mapstructure:"digesthour"`
+	DigestRecipients string `mapstructure:"digestrecipients"`
 }
 
 var (
@@ -90,6 +167,7 @@ func GetConfig() *Config {
 		v.SetDefault("appport", "3000")
 		v.SetDefault("environment", Development)
 		v.SetDefault("loglevel", "") // Let cartridge determine based on environment
+		v.SetDefault("logformat", "")
 		v.SetDefault("privatekey", "88888888888888888888888888888888")
 		v.SetDefault("sessiontimeoutseconds", 1800)
 		v.SetDefault("loginsessiontimeoutseconds", 604800) // 1 week
@@ -105,13 +183,36 @@ func GetConfig() *Config {
 		v.SetDefault("dbmaxopenconns", 0)
 		v.SetDefault("dbmaxidleconns", 0)
 		v.SetDefault("jobintervalseconds", 60)
+		v.SetDefault("processingbatchsize", 100)
+		v.SetDefault("processingmaxbatchsize", 1000)
+		v.SetDefault("shutdowndraintimeoutseconds", 20)
+		v.SetDefault("aggregationlagthreshold", 10000)
+		v.SetDefault("aggregationlagwarnafterseconds", 600)
+		v.SetDefault("readinessbacklogthreshold", 100000)
+		v.SetDefault("partialaggregationintervalseconds", 0)
+		v.SetDefault("dashboardcachettlseconds", 30)
+		v.SetDefault("maxeventbatchsize", 100)
+		v.SetDefault("ingestionmode", IngestionModeSync)
+		v.SetDefault("ingestbuffersize", 10000)
+		v.SetDefault("ingestflushbatchsize", 500)
+		v.SetDefault("ingestflushintervalms", 1000)
 		v.SetDefault("ingestedeventsretentiondays", 90)
+		v.SetDefault("raweventsretentiondays", 90)
+		v.SetDefault("languagefulltag", false)
+		v.SetDefault("backupintervalhours", 24)
+		v.SetDefault("backupretentioncount", 7)
+		v.SetDefault("backupretentiondays", 30)
+		v.SetDefault("metricsenabled", false)
+		v.SetDefault("smtpport", 587)
+		v.SetDefault("digestweekday", "monday")
+		v.SetDefault("digesthour", 8)
 
 		// Bind environment variables (same names as envconfig)
 		v.BindEnv("appname", "FUSIONALY_APP_NAME")
 		v.BindEnv("appport", "FUSIONALY_APP_PORT")
 		v.BindEnv("environment", "FUSIONALY_ENV")
 		v.BindEnv("loglevel", "FUSIONALY_LOG_LEVEL")
+		v.BindEnv("logformat", "FUSIONALY_LOG_FORMAT")
 		v.BindEnv("privatekey", "FUSIONALY_PRIVATE_KEY")
 		v.BindEnv("sessiontimeoutseconds", "FUSIONALY_SESSION_TIMEOUT_SECONDS")
 		v.BindEnv("loginsessiontimeoutseconds", "FUSIONALY_LOGIN_SESSION_TIMEOUT_SECONDS")
@@ -130,7 +231,36 @@ func GetConfig() *Config {
 		v.BindEnv("dbmaxidleconns", "FUSIONALY_DB_MAX_IDLE_CONNS")
 		v.BindEnv("openaiapikey", "OPENAI_API_KEY")
 		v.BindEnv("jobintervalseconds", "FUSIONALY_JOB_INTERVAL_SECONDS")
+		v.BindEnv("processingbatchsize", "FUSIONALY_PROCESSING_BATCH_SIZE")
+		v.BindEnv("processingmaxbatchsize", "FUSIONALY_PROCESSING_MAX_BATCH_SIZE")
+		v.BindEnv("shutdowndraintimeoutseconds", "FUSIONALY_SHUTDOWN_DRAIN_TIMEOUT_SECONDS")
+		v.BindEnv("aggregationlagthreshold", "FUSIONALY_AGGREGATION_LAG_THRESHOLD")
+		v.BindEnv("aggregationlagwarnafterseconds", "FUSIONALY_AGGREGATION_LAG_WARN_AFTER_SECONDS")
+		v.BindEnv("readinessbacklogthreshold", "FUSIONALY_READINESS_BACKLOG_THRESHOLD")
+		v.BindEnv("partialaggregationintervalseconds", "FUSIONALY_PARTIAL_AGGREGATION_INTERVAL_SECONDS")
+		v.BindEnv("dashboardcachettlseconds", "FUSIONALY_DASHBOARD_CACHE_TTL_SECONDS")
+		v.BindEnv("maxeventbatchsize", "FUSIONALY_MAX_EVENT_BATCH_SIZE")
+		v.BindEnv("ingestionmode", "FUSIONALY_INGESTION_MODE")
+		v.BindEnv("ingestbuffersize", "FUSIONALY_INGEST_BUFFER_SIZE")
+		v.BindEnv("ingestflushbatchsize", "FUSIONALY_INGEST_FLUSH_BATCH_SIZE")
+		v.BindEnv("ingestflushintervalms", "FUSIONALY_INGEST_FLUSH_INTERVAL_MS")
 		v.BindEnv("ingestedeventsretentiondays", "FUSIONALY_INGESTED_EVENTS_RETENTION_DAYS")
+		v.BindEnv("raweventsretentiondays", "FUSIONALY_RAW_EVENTS_RETENTION_DAYS")
+		v.BindEnv("languagefulltag", "FUSIONALY_LANGUAGE_FULL_TAG")
+		v.BindEnv("backupintervalhours", "FUSIONALY_BACKUP_INTERVAL_HOURS")
+		v.BindEnv("backupretentioncount", "FUSIONALY_BACKUP_RETENTION_COUNT")
+		v.BindEnv("backupretentiondays", "FUSIONALY_BACKUP_RETENTION_DAYS")
+		v.BindEnv("trustedproxies", "FUSIONALY_TRUSTED_PROXIES")
+		v.BindEnv("metricsenabled", "FUSIONALY_METRICS_ENABLED")
+		v.BindEnv("metricstoken", "FUSIONALY_METRICS_TOKEN")
+		v.BindEnv("smtphost", "FUSIONALY_SMTP_HOST")
+		v.BindEnv("smtpport", "FUSIONALY_SMTP_PORT")
+		v.BindEnv("smtpusername", "FUSIONALY_SMTP_USERNAME")
+		v.BindEnv("smtppassword", "FUSIONALY_SMTP_PASSWORD")
+		v.BindEnv("smtpfrom", "FUSIONALY_SMTP_FROM")
+		v.BindEnv("digestweekday", "FUSIONALY_DIGEST_WEEKDAY")
+		v.BindEnv("digesthour", "FUSIONALY_DIGEST_HOUR")
+		v.BindEnv("digestrecipients", "FUSIONALY_DIGEST_RECIPIENTS")
 
 		cfg = &Config{
 			CSRFContextKey: "csrf",
@@ -177,9 +307,106 @@ func (c *Config) validate() error {
 		return fmt.Errorf("invalid database type: %s", c.DatabaseType)
 	}
 
+	if _, err := parseTrustedProxies(c.TrustedProxies); err != nil {
+		return err
+	}
+
+	if _, err := parseWeekday(c.DigestWeekday); err != nil {
+		return err
+	}
+	if c.DigestHour < 0 || c.DigestHour > 23 {
+		return fmt.Errorf("invalid digest hour: %d", c.DigestHour)
+	}
+
+	switch c.LogFormat {
+	case "", LogFormatText, LogFormatJSON:
+	default:
+		return fmt.Errorf("invalid log format: %s", c.LogFormat)
+	}
+
+	switch c.IngestionMode {
+	case IngestionModeSync:
+	case IngestionModeBuffered:
+		if c.IngestBufferSize < 1 || c.IngestFlushBatchSize < 1 || c.IngestFlushIntervalMilliseconds < 1 {
+			return fmt.Errorf("buffered ingestion needs a positive buffer size, flush batch size and flush interval")
+		}
+	default:
+		return fmt.Errorf("invalid ingestion mode: %s", c.IngestionMode)
+	}
+
+	if c.ProcessingBatchSize < 1 {
+		return fmt.Errorf("invalid processing batch size: %d", c.ProcessingBatchSize)
+	}
+	if c.ProcessingMaxBatchSize < c.ProcessingBatchSize {
+		return fmt.Errorf("processing max batch size %d is below the batch size %d", c.ProcessingMaxBatchSize, c.ProcessingBatchSize)
+	}
+
 	return nil
 }
 
+// SMTPConfigured reports whether outgoing mail can be sent
+func (c *Config) SMTPConfigured() bool {
+	return c.SMTPHost != "" && c.SMTPFrom != ""
+}
+
+// GetDigestWeekday returns the day the weekly digest is sent on
+func (c *Config) GetDigestWeekday() time.Weekday {
+	weekday, _ := parseWeekday(c.DigestWeekday)
+	return weekday
+}
+
+// GetDigestRecipients returns the configured digest addresses besides opted-in users
+func (c *Config) GetDigestRecipients() []string {
+	var recipients []string
+	for _, entry := range strings.Split(c.DigestRecipients, ",") {
+		if entry = strings.TrimSpace(entry); entry != "" {
+			recipients = append(recipients, entry)
+		}
+	}
+	return recipients
+}
+
+func parseWeekday(value string) (time.Weekday, error) {
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		if strings.EqualFold(strings.TrimSpace(value), day.String()) {
+			return day, nil
+		}
+	}
+	return time.Monday, fmt.Errorf("invalid digest weekday: %q", value)
+}
+
+// GetTrustedProxies returns the configured trusted proxy ranges. Single
+// addresses are returned as /32 or /128 prefixes.
+func (c *Config) GetTrustedProxies() []netip.Prefix {
+	prefixes, _ := parseTrustedProxies(c.TrustedProxies)
+	return prefixes
+}
+
+func parseTrustedProxies(value string) ([]netip.Prefix, error) {
+	var prefixes []netip.Prefix
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if strings.Contains(entry, "/") {
+			prefix, err := netip.ParsePrefix(entry)
+			if err != nil {
+				return nil, fmt.Errorf("invalid trusted proxy range %q: %w", entry, err)
+			}
+			prefixes = append(prefixes, prefix.Masked())
+			continue
+		}
+		addr, err := netip.ParseAddr(entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy address %q: %w", entry, err)
+		}
+		addr = addr.Unmap()
+		prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+	}
+	return prefixes, nil
+}
+
 // GetDatabasePath returns the appropriate database path based on environment
 func (c *Config) GetDatabasePath() string {
 	if c.DatabaseName == "" {
@@ -189,6 +416,11 @@ func (c *Config) GetDatabasePath() string {
 	return c.DatabaseName
 }
 
+// GetBackupsDirectory returns the directory scheduled and manual backups are written to
+func (c *Config) GetBackupsDirectory() string {
+	return filepath.Join(c.DatabasePath, "backups")
+}
+
 // IsDevelopment returns true if the environment is development
 func (c *Config) IsDevelopment() bool {
 	return c.Environment == Development
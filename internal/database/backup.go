@@ -0,0 +1,209 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+	"gorm.io/gorm"
+
+	"fusionaly/internal/events"
+)
+
+const (
+	backupFilePrefix = "fusionaly-"
+	backupFileSuffix = ".db"
+	backupTimeLayout = "20060102-150405"
+)
+
+// BackupFile describes a database backup on disk
+type BackupFile struct {
+	Name      string
+	Path      string
+	Size      int64
+	CreatedAt time.Time
+}
+
+// BackupDatabase writes a consistent copy of the database into dir using
+// SQLite's VACUUM INTO, which is safe while the app keeps writing.
+// Returns the path of the new backup file.
+func BackupDatabase(db *gorm.DB, dir string, now time.Time) (string, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create backups directory: %w", err)
+	}
+
+	name := backupFilePrefix + now.UTC().Format(backupTimeLayout) + backupFileSuffix
+	path := filepath.Join(dir, name)
+	if err := ExportDatabase(db, path); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// ExportDatabase writes a consistent copy of the database to path with
+// VACUUM INTO. It refuses to overwrite an existing file.
+func ExportDatabase(db *gorm.DB, path string) error {
+	if _, err := os.Stat(path); err == nil {
+		return fmt.Errorf("%s already exists", path)
+	}
+
+	if err := db.Exec("VACUUM INTO ?", path).Error; err != nil {
+		return fmt.Errorf("failed to back up database: %w", err)
+	}
+	return nil
+}
+
+// HasRecentWrites reports whether events were ingested since the given time,
+// which means a running app is still writing to the database.
+func HasRecentWrites(db *gorm.DB, since time.Time) (bool, error) {
+	var count int64
+	if err := db.Model(&events.IngestedEvent{}).Where("created_at >= ?", since).Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// ImportDatabase replaces the contents of the live database with the SQLite
+// file at path using SQLite's online backup API, so open connections see the
+// new data instead of a swapped-out file. Callers should back up the current
+// database first and run migrations afterwards.
+func ImportDatabase(db *gorm.DB, path string) error {
+	if _, err := os.Stat(path); err != nil {
+		return fmt.Errorf("cannot read %s: %w", path, err)
+	}
+
+	src, err := sql.Open("sqlite3", "file:"+path+"?mode=ro")
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer src.Close()
+
+	var check string
+	if err := src.QueryRow("PRAGMA quick_check").Scan(&check); err != nil {
+		return fmt.Errorf("%s is not a valid SQLite database: %w", path, err)
+	}
+	if check != "ok" {
+		return fmt.Errorf("%s failed the integrity check: %s", path, check)
+	}
+
+	ctx := context.Background()
+	srcConn, err := src.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer srcConn.Close()
+
+	liveDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+	destConn, err := liveDB.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer destConn.Close()
+
+	return destConn.Raw(func(destDriverConn any) error {
+		return srcConn.Raw(func(srcDriverConn any) error {
+			dest, ok := destDriverConn.(*sqlite3.SQLiteConn)
+			if !ok {
+				return fmt.Errorf("unexpected database driver %T", destDriverConn)
+			}
+			source, ok := srcDriverConn.(*sqlite3.SQLiteConn)
+			if !ok {
+				return fmt.Errorf("unexpected database driver %T", srcDriverConn)
+			}
+
+			backup, err := dest.Backup("main", source, "main")
+			if err != nil {
+				return fmt.Errorf("failed to start import: %w", err)
+			}
+			done, err := backup.Step(-1)
+			if err != nil {
+				backup.Finish()
+				return fmt.Errorf("failed to import database: %w", err)
+			}
+			if !done {
+				backup.Finish()
+				return fmt.Errorf("failed to import database: live database is busy")
+			}
+			return backup.Finish()
+		})
+	})
+}
+
+// ListBackups returns the backups in dir, newest first.
+// A missing directory means no backups have been taken yet.
+func ListBackups(dir string) ([]BackupFile, error) {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return []BackupFile{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read backups directory: %w", err)
+	}
+
+	backups := []BackupFile{}
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, backupFilePrefix) || !strings.HasSuffix(name, backupFileSuffix) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+
+		// Prefer the timestamp in the name; copies may not keep the mtime
+		createdAt := info.ModTime().UTC()
+		stamp := strings.TrimSuffix(strings.TrimPrefix(name, backupFilePrefix), backupFileSuffix)
+		if t, err := time.Parse(backupTimeLayout, stamp); err == nil {
+			createdAt = t
+		}
+
+		backups = append(backups, BackupFile{
+			Name:      name,
+			Path:      filepath.Join(dir, name),
+			Size:      info.Size(),
+			CreatedAt: createdAt,
+		})
+	}
+
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].CreatedAt.After(backups[j].CreatedAt)
+	})
+	return backups, nil
+}
+
+// PruneBackups deletes backups beyond the newest keep files and those older
+// than maxAge. Zero disables either limit. The newest backup is never removed.
+// Returns the names of the deleted files.
+func PruneBackups(dir string, keep int, maxAge time.Duration, now time.Time) ([]string, error) {
+	backups, err := ListBackups(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	deleted := []string{}
+	for i, backup := range backups {
+		if i == 0 {
+			continue
+		}
+		tooMany := keep > 0 && i >= keep
+		tooOld := maxAge > 0 && now.Sub(backup.CreatedAt) > maxAge
+		if !tooMany && !tooOld {
+			continue
+		}
+		if err := os.Remove(backup.Path); err != nil {
+			return deleted, fmt.Errorf("failed to delete backup %s: %w", backup.Name, err)
+		}
+		deleted = append(deleted, backup.Name)
+	}
+	return deleted, nil
+}
@@ -0,0 +1,176 @@
+package database_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"fusionaly/internal/database"
+	"fusionaly/internal/testsupport"
+	"fusionaly/internal/websites"
+)
+
+// writeFakeBackup creates an empty backup file named for the given time
+func writeFakeBackup(t *testing.T, dir string, at time.Time) string {
+	t.Helper()
+
+	name := "fusionaly-" + at.UTC().Format("20060102-150405") + ".db"
+	require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte("backup"), 0644))
+	return name
+}
+
+func TestBackupDatabase(t *testing.T) {
+	dbManager, _ := testsupport.SetupTestDBManager(t)
+	db := dbManager.GetConnection()
+	require.NoError(t, websites.CreateWebsite(db, &websites.Website{Domain: "backup.com"}))
+
+	dir := filepath.Join(t.TempDir(), "backups")
+	now := time.Date(2025, 3, 10, 2, 0, 0, 0, time.UTC)
+
+	path, err := database.BackupDatabase(db, dir, now)
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "fusionaly-20250310-020000.db"), path)
+
+	// The backup is a standalone SQLite database with the same rows
+	backupDB, err := gorm.Open(sqlite.Open(path), &gorm.Config{})
+	require.NoError(t, err)
+	var count int64
+	require.NoError(t, backupDB.Model(&websites.Website{}).Where("domain = ?", "backup.com").Count(&count).Error)
+	assert.Equal(t, int64(1), count)
+	sqlDB, _ := backupDB.DB()
+	sqlDB.Close()
+
+	// A second backup in the same second must not overwrite the first
+	_, err = database.BackupDatabase(db, dir, now)
+	assert.Error(t, err)
+}
+
+func TestListBackups(t *testing.T) {
+	t.Run("missing directory has no backups", func(t *testing.T) {
+		backups, err := database.ListBackups(filepath.Join(t.TempDir(), "missing"))
+		require.NoError(t, err)
+		assert.Empty(t, backups)
+	})
+
+	t.Run("lists backups newest first and ignores other files", func(t *testing.T) {
+		dir := t.TempDir()
+		base := time.Date(2025, 3, 10, 2, 0, 0, 0, time.UTC)
+		writeFakeBackup(t, dir, base)
+		writeFakeBackup(t, dir, base.Add(48*time.Hour))
+		writeFakeBackup(t, dir, base.Add(24*time.Hour))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("x"), 0644))
+
+		backups, err := database.ListBackups(dir)
+		require.NoError(t, err)
+		require.Len(t, backups, 3)
+		assert.Equal(t, "fusionaly-20250312-020000.db", backups[0].Name)
+		assert.Equal(t, "fusionaly-20250311-020000.db", backups[1].Name)
+		assert.Equal(t, "fusionaly-20250310-020000.db", backups[2].Name)
+		assert.Equal(t, base, backups[2].CreatedAt)
+		assert.Equal(t, int64(len("backup")), backups[0].Size)
+	})
+}
+
+func TestPruneBackups(t *testing.T) {
+	base := time.Date(2025, 3, 10, 2, 0, 0, 0, time.UTC)
+
+	t.Run("keeps only the newest count", func(t *testing.T) {
+		dir := t.TempDir()
+		for i := 0; i < 5; i++ {
+			writeFakeBackup(t, dir, base.Add(time.Duration(i)*24*time.Hour))
+		}
+
+		deleted, err := database.PruneBackups(dir, 3, 0, base.Add(5*24*time.Hour))
+		require.NoError(t, err)
+		assert.ElementsMatch(t, []string{"fusionaly-20250310-020000.db", "fusionaly-20250311-020000.db"}, deleted)
+
+		backups, err := database.ListBackups(dir)
+		require.NoError(t, err)
+		assert.Len(t, backups, 3)
+	})
+
+	t.Run("removes backups older than max age", func(t *testing.T) {
+		dir := t.TempDir()
+		writeFakeBackup(t, dir, base)
+		writeFakeBackup(t, dir, base.Add(20*24*time.Hour))
+		writeFakeBackup(t, dir, base.Add(40*24*time.Hour))
+
+		deleted, err := database.PruneBackups(dir, 0, 30*24*time.Hour, base.Add(41*24*time.Hour))
+		require.NoError(t, err)
+		assert.Equal(t, []string{"fusionaly-20250310-020000.db"}, deleted)
+	})
+
+	t.Run("never removes the newest backup", func(t *testing.T) {
+		dir := t.TempDir()
+		writeFakeBackup(t, dir, base)
+
+		deleted, err := database.PruneBackups(dir, 1, 24*time.Hour, base.Add(90*24*time.Hour))
+		require.NoError(t, err)
+		assert.Empty(t, deleted)
+	})
+}
+
+func TestExportDatabase(t *testing.T) {
+	dbManager, _ := testsupport.SetupTestDBManager(t)
+	db := dbManager.GetConnection()
+	require.NoError(t, websites.CreateWebsite(db, &websites.Website{Domain: "export.com"}))
+
+	path := filepath.Join(t.TempDir(), "export.db")
+	require.NoError(t, database.ExportDatabase(db, path))
+
+	// The export is a valid SQLite file that can be queried on its own
+	exported, err := gorm.Open(sqlite.Open(path), &gorm.Config{})
+	require.NoError(t, err)
+	sqlDB, _ := exported.DB()
+	defer sqlDB.Close()
+
+	var check string
+	require.NoError(t, exported.Raw("PRAGMA integrity_check").Scan(&check).Error)
+	assert.Equal(t, "ok", check)
+
+	var domains []string
+	require.NoError(t, exported.Model(&websites.Website{}).Pluck("domain", &domains).Error)
+	assert.Equal(t, []string{"export.com"}, domains)
+
+	// Exports never overwrite an existing file
+	assert.Error(t, database.ExportDatabase(db, path))
+}
+
+func TestImportDatabase(t *testing.T) {
+	dbManager, _ := testsupport.SetupTestDBManager(t)
+	db := dbManager.GetConnection()
+	require.NoError(t, websites.CreateWebsite(db, &websites.Website{Domain: "kept.com"}))
+
+	path := filepath.Join(t.TempDir(), "export.db")
+	require.NoError(t, database.ExportDatabase(db, path))
+	require.NoError(t, websites.CreateWebsite(db, &websites.Website{Domain: "after-export.com"}))
+
+	t.Run("replaces the live data with the file", func(t *testing.T) {
+		require.NoError(t, database.ImportDatabase(db, path))
+
+		var domains []string
+		require.NoError(t, db.Model(&websites.Website{}).Pluck("domain", &domains).Error)
+		assert.Equal(t, []string{"kept.com"}, domains)
+	})
+
+	t.Run("rejects a file that is not a SQLite database", func(t *testing.T) {
+		bogus := filepath.Join(t.TempDir(), "bogus.db")
+		require.NoError(t, os.WriteFile(bogus, []byte("not a database"), 0644))
+
+		assert.Error(t, database.ImportDatabase(db, bogus))
+
+		var count int64
+		require.NoError(t, db.Model(&websites.Website{}).Count(&count).Error)
+		assert.Equal(t, int64(1), count, "live data must be untouched")
+	})
+
+	t.Run("rejects a missing file", func(t *testing.T) {
+		assert.Error(t, database.ImportDatabase(db, filepath.Join(t.TempDir(), "missing.db")))
+	})
+}
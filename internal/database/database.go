@@ -7,11 +7,13 @@ import (
 	"gorm.io/gorm"
 
 	"fusionaly/internal/ai"
+	"fusionaly/internal/alerts"
 	"fusionaly/internal/analytics"
 	"fusionaly/internal/annotations"
 	"fusionaly/internal/config"
 	"fusionaly/internal/events"
 	"fusionaly/internal/feed"
+	"fusionaly/internal/health"
 	"fusionaly/internal/onboarding"
 	"github.com/karloscodes/cartridge/cache"
 	"fusionaly/internal/settings"
@@ -56,25 +58,41 @@ func (dm *DBManager) MigrateDatabase() error {
 		return gorm.ErrInvalidDB
 	}
 
+	// Not ready to serve until the schema matches this build
+	health.SetMigrated(false)
+
 	// Run migrations in a transaction
 	err := db.Transaction(func(tx *gorm.DB) error {
 		return tx.AutoMigrate(
 			&cache.CacheRecord{},
 			&events.Event{},
 			&events.IngestedEvent{},
+			&events.FailedEvent{},
 			&users.User{},
 			&settings.Setting{},
 			&websites.Website{},
+			&websites.APIToken{},
+			&websites.Webhook{},
+			&websites.SharedLink{},
+			&alerts.Rule{},
 			&analytics.SiteStat{},
 			&analytics.PageStat{},
 			&analytics.RefStat{},
 			&analytics.BrowserStat{},
+			&analytics.BrowserVersionStat{},
 			&analytics.OSStat{},
 			&analytics.DeviceStat{},
+			&analytics.DeviceModelStat{},
 			&analytics.CountryStat{},
+			&analytics.LanguageStat{},
+			&analytics.ScreenStat{},
+			&analytics.RegionStat{},
+			&analytics.CityStat{},
 			&analytics.UTMStat{},
 			&analytics.EventStat{},
 			&analytics.QueryParamStat{},
+			&analytics.DownloadStat{},
+			&analytics.ScrollStat{},
 			&analytics.FlowTransitionStat{},
 			&onboarding.OnboardingSession{},
 			&annotations.Annotation{},
@@ -105,10 +123,17 @@ func (dm *DBManager) MigrateDatabase() error {
 		return err
 	}
 
+	// Visitors aggregated before the new/returning split was tracked count as new
+	if err := analytics.BackfillNewVisitors(db); err != nil {
+		dm.logger.Error("Failed to backfill new visitors", slog.Any("error", err))
+		return err
+	}
+
 	if err := dm.CheckpointWAL("FULL"); err != nil {
 		dm.logger.Warn("Failed to checkpoint WAL after migration", slog.Any("error", err))
 	}
 
+	health.SetMigrated(true)
 	dm.logger.Info("Database migration completed successfully")
 	return nil
 }
@@ -0,0 +1,52 @@
+package database
+
+import (
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/karloscodes/cartridge/sqlite"
+	"gorm.io/gorm"
+)
+
+const (
+	optimizeMaxRetries = 5
+	optimizeRetryDelay = time.Second
+)
+
+// OptimizeDatabase compacts the database and refreshes query planner
+// statistics. VACUUM can't run inside a transaction, so instead of
+// PerformWrite each statement retries on its own while writers hold the lock.
+func OptimizeDatabase(db *gorm.DB, logger *slog.Logger) error {
+	statements := []string{
+		"VACUUM",
+		"ANALYZE",
+		"PRAGMA optimize",
+		// Fold the WAL back in so the main file reflects the compacted size
+		"PRAGMA wal_checkpoint(TRUNCATE)",
+	}
+
+	for _, statement := range statements {
+		if err := execWithBusyRetry(db, logger, statement); err != nil {
+			return fmt.Errorf("%s failed: %w", statement, err)
+		}
+	}
+	return nil
+}
+
+// execWithBusyRetry runs statement, backing off while the database is busy.
+func execWithBusyRetry(db *gorm.DB, logger *slog.Logger, statement string) error {
+	var err error
+	for attempt := 1; attempt <= optimizeMaxRetries; attempt++ {
+		err = db.Exec(statement).Error
+		if !sqlite.IsBusyError(err) {
+			return err
+		}
+
+		logger.Info("Database busy, retrying",
+			slog.String("statement", statement),
+			slog.Int("attempt", attempt))
+		time.Sleep(time.Duration(attempt) * optimizeRetryDelay)
+	}
+	return err
+}
@@ -0,0 +1,33 @@
+package database_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"fusionaly/internal/database"
+	"fusionaly/internal/testsupport"
+	"fusionaly/internal/websites"
+)
+
+func TestOptimizeDatabase(t *testing.T) {
+	dbManager, logger := testsupport.SetupTestDBManager(t)
+	db := dbManager.GetConnection()
+
+	for _, domain := range []string{"one.com", "two.com", "three.com"} {
+		require.NoError(t, websites.CreateWebsite(db, &websites.Website{Domain: domain}))
+	}
+	// Leave free pages behind for VACUUM to reclaim
+	require.NoError(t, db.Where("domain = ?", "two.com").Delete(&websites.Website{}).Error)
+
+	require.NoError(t, database.OptimizeDatabase(db, logger))
+
+	var domains []string
+	require.NoError(t, db.Model(&websites.Website{}).Order("domain").Pluck("domain", &domains).Error)
+	assert.Equal(t, []string{"one.com", "three.com"}, domains)
+
+	var check string
+	require.NoError(t, db.Raw("PRAGMA integrity_check").Scan(&check).Error)
+	assert.Equal(t, "ok", check)
+}
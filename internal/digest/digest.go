@@ -0,0 +1,116 @@
+// Package digest builds and emails the weekly stats summary.
+package digest
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+	"text/template"
+	"time"
+
+	"gorm.io/gorm"
+
+	"fusionaly/internal/analytics"
+	"fusionaly/internal/timeframe"
+	"fusionaly/internal/websites"
+)
+
+// topPagesLimit is how many pages each website lists in the digest
+const topPagesLimit = 5
+
+// digestMetrics are the dashboard metrics a website summary is built from
+var digestMetrics = []string{"total_visitors", "total_views", "top_urls", "revenue_metrics"}
+
+// Digest summarises one week of traffic across every website.
+type Digest struct {
+	From     time.Time
+	To       time.Time
+	Websites []WebsiteSummary
+}
+
+// WebsiteSummary is one website's section of the digest.
+type WebsiteSummary struct {
+	Domain    string
+	Visitors  int64
+	PageViews int64
+	TopPages  []analytics.MetricCountResult
+	Revenue   float64
+	Sales     int64
+	Currency  string
+}
+
+// PreviousWeek returns the Monday-to-Sunday UTC week before the one containing now.
+func PreviousWeek(now time.Time) (from, to time.Time) {
+	now = now.UTC()
+	daysSinceMonday := (int(now.Weekday()) + 6) % 7
+	thisMonday := time.Date(now.Year(), now.Month(), now.Day()-daysSinceMonday, 0, 0, 0, 0, time.UTC)
+	return thisMonday.AddDate(0, 0, -7), thisMonday.Add(-time.Second)
+}
+
+// Build gathers every website's visitors, page views, top pages and revenue
+// between from and to, using the same metric tasks as the dashboard.
+func Build(db *gorm.DB, from, to time.Time, logger *slog.Logger) (*Digest, error) {
+	sites, err := websites.GetAllWebsites(db)
+	if err != nil {
+		return nil, err
+	}
+
+	tf, err := timeframe.NewTimeFrame(timeframe.TimeFrameParams{
+		FromTime:      from.UTC(),
+		ToTime:        to.UTC(),
+		TimeFrameSize: timeframe.DailyTimeFrame,
+	}, time.UTC)
+	if err != nil {
+		return nil, err
+	}
+
+	digest := &Digest{From: from.UTC(), To: to.UTC()}
+	for _, site := range sites {
+		metrics, err := analytics.FetchDashboardMetricsSubset(db, tf, int(site.ID), nil, digestMetrics, topPagesLimit, logger)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load stats for %s: %w", site.Domain, err)
+		}
+
+		summary := WebsiteSummary{Domain: site.Domain, Currency: "USD"}
+		summary.Visitors, _ = metrics["total_visitors"].(int64)
+		summary.PageViews, _ = metrics["total_views"].(int64)
+		summary.TopPages, _ = metrics["top_urls"].([]analytics.MetricCountResult)
+		if revenue, ok := metrics["revenue_metrics"].(*analytics.RevenueMetrics); ok && revenue != nil {
+			summary.Revenue = revenue.TotalRevenue
+			summary.Sales = revenue.TotalSales
+			summary.Currency = revenue.Currency
+		}
+		digest.Websites = append(digest.Websites, summary)
+	}
+
+	return digest, nil
+}
+
+var bodyTemplate = template.Must(template.New("digest").Parse(`Your Fusionaly stats for {{.From.Format "Jan 2"}} – {{.To.Format "Jan 2, 2006"}}
+{{range .Websites}}
+{{.Domain}}
+  Visitors:   {{.Visitors}}
+  Page views: {{.PageViews}}
+{{- if .Sales}}
+  Revenue:    {{printf "%.2f" .Revenue}} {{.Currency}} from {{.Sales}} {{if eq .Sales 1}}sale{{else}}sales{{end}}
+{{- end}}
+{{- if .TopPages}}
+  Top pages:
+{{- range .TopPages}}
+    {{.Name}} ({{.Count}})
+{{- end}}
+{{- end}}
+{{else}}
+No websites are being tracked yet.
+{{end}}`))
+
+// Render returns the digest's email subject and plain-text body.
+func Render(digest *Digest) (subject string, body string, err error) {
+	subject = fmt.Sprintf("Weekly stats: %s – %s", digest.From.Format("Jan 2"), digest.To.Format("Jan 2"))
+
+	var b strings.Builder
+	if err := bodyTemplate.Execute(&b, digest); err != nil {
+		return "", "", fmt.Errorf("failed to render digest: %w", err)
+	}
+	return subject, b.String(), nil
+}
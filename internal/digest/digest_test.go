@@ -0,0 +1,78 @@
+package digest_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"fusionaly/internal/digest"
+	"fusionaly/internal/events"
+	"fusionaly/internal/testsupport"
+)
+
+func TestPreviousWeek(t *testing.T) {
+	// Monday morning and the following Sunday night both report the week before
+	for _, now := range []time.Time{
+		time.Date(2024, 7, 8, 8, 0, 0, 0, time.UTC),
+		time.Date(2024, 7, 14, 23, 59, 0, 0, time.UTC),
+	} {
+		from, to := digest.PreviousWeek(now)
+		assert.Equal(t, time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC), from, now)
+		assert.Equal(t, time.Date(2024, 7, 7, 23, 59, 59, 0, time.UTC), to, now)
+	}
+}
+
+func TestRenderDigest(t *testing.T) {
+	dbManager, logger := testsupport.SetupTestDBManager(t)
+	db := dbManager.GetConnection()
+	testsupport.CleanAllTables(db)
+	testsupport.CreateTestWebsite(db, "shop.com")
+	testsupport.CreateTestWebsite(db, "quiet.com")
+
+	chromeUA := "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36"
+	collect := func(ip, url string, ts time.Time) {
+		require.NoError(t, events.CollectEvent(dbManager, logger, &events.CollectEventInput{
+			IPAddress: ip,
+			UserAgent: chromeUA,
+			RawUrl:    url,
+			EventType: events.EventTypePageView,
+			Timestamp: ts,
+		}))
+	}
+
+	week := time.Date(2024, 7, 2, 10, 0, 0, 0, time.UTC)
+	collect("203.0.113.1", "https://shop.com/", week)
+	collect("203.0.113.5", "https://shop.com/pricing", week.Add(time.Minute))
+	collect("203.0.113.2", "https://shop.com/", week.Add(24*time.Hour))
+	collect("203.0.113.4", "https://shop.com/", week.Add(48*time.Hour))
+	// Outside the week
+	collect("203.0.113.3", "https://shop.com/old", time.Date(2024, 6, 20, 10, 0, 0, 0, time.UTC))
+	require.NoError(t, events.CollectEvent(dbManager, logger, &events.CollectEventInput{
+		IPAddress:       "203.0.113.2",
+		UserAgent:       chromeUA,
+		RawUrl:          "https://shop.com/checkout",
+		EventType:       events.EventTypeCustomEvent,
+		CustomEventName: "revenue:purchased",
+		CustomEventMeta: `{"price":4900,"currency":"USD"}`,
+		Timestamp:       week.Add(24*time.Hour + time.Minute),
+	}))
+	require.NoError(t, testsupport.ProcessAllTestEvents(dbManager, logger))
+
+	from, to := digest.PreviousWeek(time.Date(2024, 7, 8, 8, 0, 0, 0, time.UTC))
+	summary, err := digest.Build(db, from, to, logger)
+	require.NoError(t, err)
+	require.Len(t, summary.Websites, 2)
+
+	subject, body, err := digest.Render(summary)
+	require.NoError(t, err)
+	assert.Equal(t, "Weekly stats: Jul 1 – Jul 7", subject)
+
+	assert.Contains(t, body, "Your Fusionaly stats for Jul 1 – Jul 7, 2024")
+	assert.Contains(t, body, "shop.com\n  Visitors:   4\n  Page views: 4\n")
+	assert.Contains(t, body, "Revenue:    49.00 USD from 1 sale\n")
+	assert.Contains(t, body, "  Top pages:\n    shop.com/ (3)\n    shop.com/pricing (1)\n")
+	assert.NotContains(t, body, "/old")
+	assert.Contains(t, body, "quiet.com\n  Visitors:   0\n  Page views: 0\n")
+}
@@ -0,0 +1,63 @@
+package digest
+
+import (
+	"errors"
+	"fmt"
+	"mime"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+
+	"fusionaly/internal/config"
+)
+
+// SendMail delivers a plain-text email through the configured SMTP server.
+// Each recipient gets a separate message so addresses aren't disclosed to the
+// others; a failed recipient doesn't stop the rest and all failures are
+// returned. Servers advertising STARTTLS are upgraded automatically by net/smtp.
+func SendMail(cfg *config.Config, to []string, subject, body string) error {
+	if !cfg.SMTPConfigured() {
+		return fmt.Errorf("SMTP is not configured")
+	}
+	if len(to) == 0 {
+		return fmt.Errorf("no recipients")
+	}
+
+	from, err := mail.ParseAddress(cfg.SMTPFrom)
+	if err != nil {
+		return fmt.Errorf("invalid sender address %q: %w", cfg.SMTPFrom, err)
+	}
+
+	var auth smtp.Auth
+	if cfg.SMTPUsername != "" {
+		auth = smtp.PlainAuth("", cfg.SMTPUsername, cfg.SMTPPassword, cfg.SMTPHost)
+	}
+
+	addr := net.JoinHostPort(cfg.SMTPHost, strconv.Itoa(cfg.SMTPPort))
+	var errs []error
+	for _, recipient := range to {
+		message := buildMessage(from.String(), recipient, subject, body, time.Now())
+		if err := smtp.SendMail(addr, auth, from.Address, []string{recipient}, message); err != nil {
+			errs = append(errs, fmt.Errorf("failed to send to %s: %w", recipient, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// buildMessage assembles the RFC 5322 message for a single recipient.
+func buildMessage(from, to, subject, body string, date time.Time) []byte {
+	var b strings.Builder
+	b.WriteString("From: " + from + "\r\n")
+	b.WriteString("To: " + to + "\r\n")
+	b.WriteString("Subject: " + mime.QEncoding.Encode("utf-8", subject) + "\r\n")
+	b.WriteString("Date: " + date.Format(time.RFC1123Z) + "\r\n")
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	b.WriteString("Content-Transfer-Encoding: 8bit\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(body, "\n", "\r\n"))
+	return []byte(b.String())
+}
@@ -6,8 +6,6 @@ import (
 
 	"log/slog"
 	"gorm.io/gorm"
-
-	"fusionaly/internal/config"
 )
 
 const eventsTableName = "events"
@@ -34,7 +32,7 @@ func truncateToHalfHour(timestamp time.Time) time.Time {
 
 // UpdateAllAggregatesBatch updates aggregates from processed events.
 func UpdateAllAggregatesBatch(tx *gorm.DB, logger *slog.Logger, dataList []*EventProcessingData) error {
-	sessionTimeout := config.GetConfig().SessionTimeoutSeconds
+	var timeouts sessionTimeouts // Loaded once, for data without a SessionTimeout
 	for _, data := range dataList {
 		// Bounce detection: Check if this is a single-page session within sessionTimeout
 		isBounce := false
@@ -43,11 +41,19 @@ func UpdateAllAggregatesBatch(tx *gorm.DB, logger *slog.Logger, dataList []*Even
 			if data.IsBounce {
 				isBounce = true
 			} else {
+				sessionTimeout := data.SessionTimeout
+				if sessionTimeout == 0 {
+					if timeouts == nil {
+						timeouts = loadSessionTimeouts(tx)
+					}
+					sessionTimeout = timeouts.forWebsite(data.WebsiteID)
+				}
+
 				var sessionPageViews int64
 				err := tx.Table(eventsTableName).
 					Where("website_id = ? AND user_signature = ? AND event_type = ? AND timestamp >= ? AND timestamp <= ?",
 						data.WebsiteID, data.UserSignature, EventTypePageView,
-						data.Timestamp, data.Timestamp.Add(time.Duration(sessionTimeout)*time.Second)).
+						data.Timestamp, data.Timestamp.Add(sessionTimeout)).
 					Count(&sessionPageViews).Error
 				if err != nil {
 					logger.Warn("Failed to count session page views for bounce", slog.Any("error", err))
@@ -65,24 +71,57 @@ func UpdateAllAggregatesBatch(tx *gorm.DB, logger *slog.Logger, dataList []*Even
 			if err := updateSiteStatForPageView(tx, data.WebsiteID, hourTime, data.IsNewVisitor, data.IsNewSession, isBounce); err != nil {
 				return fmt.Errorf("failed to update site stats: %w", err)
 			}
-			if err := updatePageStat(tx, data.WebsiteID, data.Hostname, data.Pathname, hourTime, data.IsEntrance, data.IsExit, data.UserSignature, data.IsNewVisitor); err != nil {
+			// A visitor's first return moves them from new to returning in the
+			// bucket they were counted in
+			if data.IsFirstReturn {
+				if err := markSiteStatReturningVisitor(tx, data.WebsiteID, truncateToHalfHour(data.FirstSeenTime)); err != nil {
+					return fmt.Errorf("failed to update returning visitors: %w", err)
+				}
+			}
+			if err := updatePageStat(tx, data.WebsiteID, data.Hostname, data.Pathname, hourTime, data.IsEntrance, data.IsExit, isBounce, data.UserSignature, data.IsNewVisitor); err != nil {
 				return fmt.Errorf("failed to update page stats: %w", err)
 			}
-			if err := updateRefStat(tx, data.WebsiteID, data.ReferrerHostname, data.ReferrerPathname, hourTime, data.IsNewVisitor); err != nil {
+			if err := updateRefStat(tx, data.WebsiteID, data.ReferrerHostname, data.ReferrerPathname, hourTime, data.IsEntrance, isBounce, data.IsNewVisitor); err != nil {
 				return fmt.Errorf("failed to update ref stats: %w", err)
 			}
 			if err := updateDeviceStat(tx, data.WebsiteID, data.DeviceType, hourTime, data.IsNewVisitor); err != nil {
 				return fmt.Errorf("failed to update device stats: %w", err)
 			}
+			if data.DeviceModel != "" {
+				if err := updateDeviceModelStat(tx, data.WebsiteID, data.DeviceModel, hourTime, data.IsNewVisitor); err != nil {
+					return fmt.Errorf("failed to update device model stats: %w", err)
+				}
+			}
 			if err := updateBrowserStat(tx, data.WebsiteID, data.Browser, hourTime, data.IsNewVisitor); err != nil {
 				return fmt.Errorf("failed to update browser stats: %w", err)
 			}
+			if err := updateBrowserVersionStat(tx, data.WebsiteID, data.Browser, data.BrowserVersion, hourTime, data.IsNewVisitor); err != nil {
+				return fmt.Errorf("failed to update browser version stats: %w", err)
+			}
 			if err := updateOSStat(tx, data.WebsiteID, data.OperatingSystem, hourTime, data.IsNewVisitor); err != nil {
 				return fmt.Errorf("failed to update os stats: %w", err)
 			}
 			if err := updateCountryStat(tx, data.WebsiteID, data.Country, hourTime, data.IsNewVisitor); err != nil {
 				return fmt.Errorf("failed to update country stats: %w", err)
 			}
+			if data.Region != "" {
+				if err := updateRegionStat(tx, data.WebsiteID, data.Country, data.Region, hourTime, data.IsNewVisitor); err != nil {
+					return fmt.Errorf("failed to update region stats: %w", err)
+				}
+			}
+			if data.City != "" {
+				if err := updateCityStat(tx, data.WebsiteID, data.Country, data.City, hourTime, data.IsNewVisitor); err != nil {
+					return fmt.Errorf("failed to update city stats: %w", err)
+				}
+			}
+			if err := updateLanguageStat(tx, data.WebsiteID, data.Language, hourTime, data.IsNewVisitor); err != nil {
+				return fmt.Errorf("failed to update language stats: %w", err)
+			}
+			if data.ScreenSize != "" {
+				if err := updateScreenStat(tx, data.WebsiteID, data.ScreenSize, hourTime, data.IsNewVisitor); err != nil {
+					return fmt.Errorf("failed to update screen stats: %w", err)
+				}
+			}
 			if data.HasUTM {
 				if err := updateUTMStat(tx, data.WebsiteID, data.UTMSource, data.UTMMedium, data.UTMCampaign, data.UTMTerm, data.UTMContent, hourTime, data.IsNewVisitor); err != nil {
 					return fmt.Errorf("failed to update utm stats: %w", err)
@@ -105,6 +144,25 @@ func UpdateAllAggregatesBatch(tx *gorm.DB, logger *slog.Logger, dataList []*Even
 				return fmt.Errorf("failed to update event stats: %w", err)
 			}
 		}
+
+		// Only the deepest bucket per page and session is counted, so a deeper
+		// scroll moves the session out of the bucket it was counted in before
+		if data.ScrollDepth > data.PreviousScrollDepth {
+			if data.PreviousScrollDepth > 0 {
+				if err := updateScrollStat(tx, data.WebsiteID, data.Hostname, data.Pathname, data.PreviousScrollDepth, truncateToHalfHour(data.PreviousScrollTime), -1); err != nil {
+					return fmt.Errorf("failed to update scroll stats: %w", err)
+				}
+			}
+			if err := updateScrollStat(tx, data.WebsiteID, data.Hostname, data.Pathname, data.ScrollDepth, hourTime, 1); err != nil {
+				return fmt.Errorf("failed to update scroll stats: %w", err)
+			}
+		}
+
+		if data.EventType == EventTypeDownload && data.CustomEventName != "" {
+			if err := updateDownloadStat(tx, data.WebsiteID, data.CustomEventName, hourTime, data.IsNewVisitor); err != nil {
+				return fmt.Errorf("failed to update download stats: %w", err)
+			}
+		}
 	}
 
 	logger.Info("Updated aggregates", slog.Int("count", len(dataList)))
@@ -125,49 +183,70 @@ func updateSiteStatForPageView(tx *gorm.DB, websiteID uint, hour time.Time, isNe
 	}
 	now := time.Now().UTC()
 	query := `
-		INSERT INTO site_stats (website_id, hour, page_views, visitors, sessions, bounce_count, created_at, updated_at)
-		VALUES (?, ?, 1, ?, ?, ?, ?, ?)
+		INSERT INTO site_stats (website_id, hour, page_views, visitors, new_visitors, sessions, bounce_count, created_at, updated_at)
+		VALUES (?, ?, 1, ?, ?, ?, ?, ?, ?)
 		ON CONFLICT (website_id, hour) DO UPDATE SET
 			page_views = site_stats.page_views + 1,
 			visitors = site_stats.visitors + ?,
+			new_visitors = site_stats.new_visitors + ?,
 			sessions = site_stats.sessions + ?,
 			bounce_count = site_stats.bounce_count + ?,
 			updated_at = ?
 	`
-	return tx.Exec(query, websiteID, hour, visitorInc, sessionInc, bounceInc, now, now, visitorInc, sessionInc, bounceInc, now).Error
+	return tx.Exec(query, websiteID, hour, visitorInc, visitorInc, sessionInc, bounceInc, now, now, visitorInc, visitorInc, sessionInc, bounceInc, now).Error
 }
 
-func updatePageStat(tx *gorm.DB, websiteID uint, hostname, pathname string, hour time.Time, isEntrance, isExit bool, userSignature string, isNewVisitor bool) error {
+func markSiteStatReturningVisitor(tx *gorm.DB, websiteID uint, hour time.Time) error {
+	query := `
+		UPDATE site_stats SET
+			new_visitors = new_visitors - 1,
+			returning_visitors = returning_visitors + 1,
+			updated_at = ?
+		WHERE website_id = ? AND hour = ? AND new_visitors > 0
+	`
+	return tx.Exec(query, time.Now().UTC(), websiteID, hour).Error
+}
+
+func updatePageStat(tx *gorm.DB, websiteID uint, hostname, pathname string, hour time.Time, isEntrance, isExit, isBounce bool, userSignature string, isNewVisitor bool) error {
 	visitorInc := getVisitorIncrement(isNewVisitor)
 	now := time.Now().UTC()
 	query := `
-		INSERT INTO page_stats (website_id, hostname, pathname, hour, page_views_count, visitors_count, entrances, exits, created_at, updated_at)
-		VALUES (?, ?, ?, ?, 1, ?, ?, ?, ?, ?)
+		INSERT INTO page_stats (website_id, hostname, pathname, hour, page_views_count, visitors_count, entrances, exits, bounce_count, created_at, updated_at)
+		VALUES (?, ?, ?, ?, 1, ?, ?, ?, ?, ?, ?)
 		ON CONFLICT (website_id, hostname, pathname, hour) DO UPDATE SET
 			page_views_count = page_stats.page_views_count + 1,
 			visitors_count = page_stats.visitors_count + ?,
 			entrances = page_stats.entrances + ?,
 			exits = page_stats.exits + ?,
+			bounce_count = page_stats.bounce_count + ?,
 			updated_at = ?
 	`
 	return tx.Exec(query,
 		websiteID, hostname, pathname, hour,
-		visitorInc, isEntrance, isExit, now, now,
-		visitorInc, isEntrance, isExit, now).Error
+		visitorInc, isEntrance, isExit, isBounce, now, now,
+		visitorInc, isEntrance, isExit, isBounce, now).Error
 }
 
-func updateRefStat(tx *gorm.DB, websiteID uint, hostname, pathname string, hour time.Time, isNewVisitor bool) error {
+// updateRefStat counts the page view against its referrer. Entrances and
+// bounces are only counted for the page view that starts a session, so they
+// attribute the session to the referrer that brought it in.
+func updateRefStat(tx *gorm.DB, websiteID uint, hostname, pathname string, hour time.Time, isEntrance, isBounce, isNewVisitor bool) error {
 	visitorInc := getVisitorIncrement(isNewVisitor)
 	now := time.Now().UTC()
 	query := `
-		INSERT INTO ref_stats (website_id, hostname, pathname, hour, visitors_count, page_views_count, created_at, updated_at)
-		VALUES (?, ?, ?, ?, ?, 1, ?, ?)
+		INSERT INTO ref_stats (website_id, hostname, pathname, hour, visitors_count, page_views_count, entrances, bounce_count, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, 1, ?, ?, ?, ?)
 		ON CONFLICT (website_id, hostname, pathname, hour) DO UPDATE SET
 			visitors_count = ref_stats.visitors_count + ?,
 			page_views_count = ref_stats.page_views_count + 1,
+			entrances = ref_stats.entrances + ?,
+			bounce_count = ref_stats.bounce_count + ?,
 			updated_at = ?
 	`
-	return tx.Exec(query, websiteID, hostname, pathname, hour, visitorInc, now, now, visitorInc, now).Error
+	return tx.Exec(query,
+		websiteID, hostname, pathname, hour,
+		visitorInc, isEntrance, isBounce, now, now,
+		visitorInc, isEntrance, isBounce, now).Error
 }
 
 func updateDeviceStat(tx *gorm.DB, websiteID uint, deviceType string, hour time.Time, isNewVisitor bool) error {
@@ -184,6 +263,20 @@ func updateDeviceStat(tx *gorm.DB, websiteID uint, deviceType string, hour time.
 	return tx.Exec(query, websiteID, deviceType, hour, visitorInc, now, now, visitorInc, now).Error
 }
 
+func updateDeviceModelStat(tx *gorm.DB, websiteID uint, deviceModel string, hour time.Time, isNewVisitor bool) error {
+	visitorInc := getVisitorIncrement(isNewVisitor)
+	now := time.Now().UTC()
+	query := `
+		INSERT INTO device_model_stats (website_id, device_model, hour, visitors_count, page_views_count, created_at, updated_at)
+		VALUES (?, ?, ?, ?, 1, ?, ?)
+		ON CONFLICT (website_id, device_model, hour) DO UPDATE SET
+			visitors_count = device_model_stats.visitors_count + ?,
+			page_views_count = device_model_stats.page_views_count + 1,
+			updated_at = ?
+	`
+	return tx.Exec(query, websiteID, deviceModel, hour, visitorInc, now, now, visitorInc, now).Error
+}
+
 func updateBrowserStat(tx *gorm.DB, websiteID uint, browser string, hour time.Time, isNewVisitor bool) error {
 	visitorInc := getVisitorIncrement(isNewVisitor)
 	now := time.Now().UTC()
@@ -198,6 +291,48 @@ func updateBrowserStat(tx *gorm.DB, websiteID uint, browser string, hour time.Ti
 	return tx.Exec(query, websiteID, browser, hour, visitorInc, now, now, visitorInc, now).Error
 }
 
+func updateBrowserVersionStat(tx *gorm.DB, websiteID uint, browser, version string, hour time.Time, isNewVisitor bool) error {
+	visitorInc := getVisitorIncrement(isNewVisitor)
+	now := time.Now().UTC()
+	query := `
+		INSERT INTO browser_version_stats (website_id, browser, version, hour, visitors_count, page_views_count, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, 1, ?, ?)
+		ON CONFLICT (website_id, browser, version, hour) DO UPDATE SET
+			visitors_count = browser_version_stats.visitors_count + ?,
+			page_views_count = browser_version_stats.page_views_count + 1,
+			updated_at = ?
+	`
+	return tx.Exec(query, websiteID, browser, version, hour, visitorInc, now, now, visitorInc, now).Error
+}
+
+func updateLanguageStat(tx *gorm.DB, websiteID uint, language string, hour time.Time, isNewVisitor bool) error {
+	visitorInc := getVisitorIncrement(isNewVisitor)
+	now := time.Now().UTC()
+	query := `
+		INSERT INTO language_stats (website_id, language, hour, visitors_count, page_views_count, created_at, updated_at)
+		VALUES (?, ?, ?, ?, 1, ?, ?)
+		ON CONFLICT (website_id, language, hour) DO UPDATE SET
+			visitors_count = language_stats.visitors_count + ?,
+			page_views_count = language_stats.page_views_count + 1,
+			updated_at = ?
+	`
+	return tx.Exec(query, websiteID, language, hour, visitorInc, now, now, visitorInc, now).Error
+}
+
+func updateScreenStat(tx *gorm.DB, websiteID uint, screenSize string, hour time.Time, isNewVisitor bool) error {
+	visitorInc := getVisitorIncrement(isNewVisitor)
+	now := time.Now().UTC()
+	query := `
+		INSERT INTO screen_stats (website_id, screen_size, hour, visitors_count, page_views_count, created_at, updated_at)
+		VALUES (?, ?, ?, ?, 1, ?, ?)
+		ON CONFLICT (website_id, screen_size, hour) DO UPDATE SET
+			visitors_count = screen_stats.visitors_count + ?,
+			page_views_count = screen_stats.page_views_count + 1,
+			updated_at = ?
+	`
+	return tx.Exec(query, websiteID, screenSize, hour, visitorInc, now, now, visitorInc, now).Error
+}
+
 func updateOSStat(tx *gorm.DB, websiteID uint, os string, hour time.Time, isNewVisitor bool) error {
 	visitorInc := getVisitorIncrement(isNewVisitor)
 	now := time.Now().UTC()
@@ -226,6 +361,34 @@ func updateCountryStat(tx *gorm.DB, websiteID uint, country string, hour time.Ti
 	return tx.Exec(query, websiteID, country, hour, visitorInc, now, now, visitorInc, now).Error
 }
 
+func updateRegionStat(tx *gorm.DB, websiteID uint, country, region string, hour time.Time, isNewVisitor bool) error {
+	visitorInc := getVisitorIncrement(isNewVisitor)
+	now := time.Now().UTC()
+	query := `
+		INSERT INTO region_stats (website_id, country, region, hour, visitors_count, page_views_count, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, 1, ?, ?)
+		ON CONFLICT (website_id, country, region, hour) DO UPDATE SET
+			visitors_count = region_stats.visitors_count + ?,
+			page_views_count = region_stats.page_views_count + 1,
+			updated_at = ?
+	`
+	return tx.Exec(query, websiteID, country, region, hour, visitorInc, now, now, visitorInc, now).Error
+}
+
+func updateCityStat(tx *gorm.DB, websiteID uint, country, city string, hour time.Time, isNewVisitor bool) error {
+	visitorInc := getVisitorIncrement(isNewVisitor)
+	now := time.Now().UTC()
+	query := `
+		INSERT INTO city_stats (website_id, country, city, hour, visitors_count, page_views_count, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, 1, ?, ?)
+		ON CONFLICT (website_id, country, city, hour) DO UPDATE SET
+			visitors_count = city_stats.visitors_count + ?,
+			page_views_count = city_stats.page_views_count + 1,
+			updated_at = ?
+	`
+	return tx.Exec(query, websiteID, country, city, hour, visitorInc, now, now, visitorInc, now).Error
+}
+
 func updateUTMStat(tx *gorm.DB, websiteID uint, source, medium, campaign, term, content string, hour time.Time, isNewVisitor bool) error {
 	visitorInc := getVisitorIncrement(isNewVisitor)
 	now := time.Now().UTC()
@@ -254,6 +417,32 @@ func updateEventStat(tx *gorm.DB, websiteID uint, eventName, eventKey string, ho
 	return tx.Exec(query, websiteID, eventName, eventKey, hour, visitorInc, now, now, visitorInc, now).Error
 }
 
+func updateDownloadStat(tx *gorm.DB, websiteID uint, filename string, hour time.Time, isNewVisitor bool) error {
+	visitorInc := getVisitorIncrement(isNewVisitor)
+	now := time.Now().UTC()
+	query := `
+		INSERT INTO download_stats (website_id, filename, extension, hour, visitors_count, downloads_count, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, 1, ?, ?)
+		ON CONFLICT (website_id, filename, hour) DO UPDATE SET
+			visitors_count = download_stats.visitors_count + ?,
+			downloads_count = download_stats.downloads_count + 1,
+			updated_at = ?
+	`
+	return tx.Exec(query, websiteID, filename, downloadExtension(filename), hour, visitorInc, now, now, visitorInc, now).Error
+}
+
+func updateScrollStat(tx *gorm.DB, websiteID uint, hostname, pathname string, depth int, hour time.Time, delta int) error {
+	now := time.Now().UTC()
+	query := `
+		INSERT INTO scroll_stats (website_id, hostname, pathname, depth, hour, sessions_count, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, MAX(?, 0), ?, ?)
+		ON CONFLICT (website_id, hostname, pathname, depth, hour) DO UPDATE SET
+			sessions_count = MAX(scroll_stats.sessions_count + ?, 0),
+			updated_at = ?
+	`
+	return tx.Exec(query, websiteID, hostname, pathname, depth, hour, delta, now, now, delta, now).Error
+}
+
 func updateQueryParamStat(tx *gorm.DB, websiteID uint, paramName, paramValue string, hour time.Time, isNewVisitor bool) error {
 	visitorInc := getVisitorIncrement(isNewVisitor)
 	now := time.Now().UTC()
@@ -282,9 +471,30 @@ type FlowTransitionResult struct {
 // and stores them in flow_transition_stats. This replaces on-the-fly computation.
 // maxDepth controls how many steps into the session to track (default 5)
 func ComputeFlowTransitionsForHour(db *gorm.DB, logger *slog.Logger, hour time.Time, maxDepth int) error {
+	return computeFlowTransitions(db, logger, 0, nil, hour, maxDepth)
+}
+
+// ComputeFlowTransitionsForWebsiteHour is ComputeFlowTransitionsForHour limited to one website
+func ComputeFlowTransitionsForWebsiteHour(db *gorm.DB, logger *slog.Logger, websiteID uint, hour time.Time, maxDepth int) error {
+	return computeFlowTransitions(db, logger, websiteID, nil, hour, maxDepth)
+}
+
+// ComputeFlowTransitionsForHourExcluding is ComputeFlowTransitionsForHour
+// skipping the given websites
+func ComputeFlowTransitionsForHourExcluding(db *gorm.DB, logger *slog.Logger, excludedIDs []uint, hour time.Time, maxDepth int) error {
+	return computeFlowTransitions(db, logger, 0, excludedIDs, hour, maxDepth)
+}
+
+// computeFlowTransitions computes transitions for an hour; websiteID 0 means
+// all websites but excludedIDs
+func computeFlowTransitions(db *gorm.DB, logger *slog.Logger, websiteID uint, excludedIDs []uint, hour time.Time, maxDepth int) error {
 	if maxDepth <= 0 {
 		maxDepth = 5
 	}
+	if len(excludedIDs) == 0 {
+		// NOT IN over an empty list would match nothing; no website has ID 0
+		excludedIDs = []uint{0}
+	}
 
 	hourStart := hour.Truncate(time.Hour)
 	hourEnd := hourStart.Add(time.Hour)
@@ -303,6 +513,8 @@ func ComputeFlowTransitionsForHour(db *gorm.DB, logger *slog.Logger, hour time.T
 		WHERE
 			timestamp >= ? AND timestamp < ?
 			AND event_type = ?
+			AND (? = 0 OR website_id = ?)
+			AND website_id NOT IN ?
 	),
 	ranked_events AS (
 		SELECT
@@ -336,7 +548,7 @@ func ComputeFlowTransitionsForHour(db *gorm.DB, logger *slog.Logger, hour time.T
 	`
 
 	var results []FlowTransitionResult
-	err := db.Raw(query, hourStart, hourEnd, EventTypePageView, maxDepth).Scan(&results).Error
+	err := db.Raw(query, hourStart, hourEnd, EventTypePageView, websiteID, websiteID, excludedIDs, maxDepth).Scan(&results).Error
 	if err != nil {
 		return fmt.Errorf("failed to compute flow transitions: %w", err)
 	}
@@ -0,0 +1,53 @@
+package events
+
+import (
+	"regexp"
+	"strings"
+
+	ua "fusionaly/internal/pkg/user_agent"
+)
+
+// IsBot reports whether a user agent belongs to a known bot or crawler,
+// using the embedded device detector ruleset.
+func IsBot(userAgent string) bool {
+	return ua.IsBot(userAgent)
+}
+
+// botPattern is a user-defined bot pattern: a case-insensitive regular
+// expression, or a plain substring when it isn't a valid expression.
+type botPattern struct {
+	re     *regexp.Regexp
+	substr string // Lowercased pattern, used when re is nil
+}
+
+// compileBotPatterns prepares user-defined bot patterns for isBotWithPatterns
+func compileBotPatterns(patterns []string) []botPattern {
+	compiled := make([]botPattern, 0, len(patterns))
+	for _, pattern := range patterns {
+		if re, err := regexp.Compile("(?i)" + pattern); err == nil {
+			compiled = append(compiled, botPattern{re: re})
+		} else {
+			compiled = append(compiled, botPattern{substr: strings.ToLower(pattern)})
+		}
+	}
+	return compiled
+}
+
+// isBotWithPatterns extends IsBot with user-defined patterns.
+func isBotWithPatterns(userAgent string, patterns []botPattern) bool {
+	if IsBot(userAgent) {
+		return true
+	}
+
+	lowerUA := strings.ToLower(userAgent)
+	for _, pattern := range patterns {
+		if pattern.re != nil {
+			if pattern.re.MatchString(userAgent) {
+				return true
+			}
+		} else if strings.Contains(lowerUA, pattern.substr) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,84 @@
+package events_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"fusionaly/internal/events"
+	"fusionaly/internal/settings"
+	"fusionaly/internal/testsupport"
+)
+
+func TestIsBot(t *testing.T) {
+	tests := []struct {
+		name      string
+		userAgent string
+		expected  bool
+	}{
+		{"Googlebot", "Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)", true},
+		{"Bingbot", "Mozilla/5.0 (compatible; bingbot/2.0; +http://www.bing.com/bingbot.htm)", true},
+		{"AhrefsBot", "Mozilla/5.0 (compatible; AhrefsBot/7.0; +http://ahrefs.com/robot/)", true},
+		{"UptimeRobot", "Mozilla/5.0+(compatible; UptimeRobot/2.0; http://www.uptimerobot.com/)", true},
+		{"YandexBot", "Mozilla/5.0 (compatible; YandexBot/3.0; +http://yandex.com/bots)", true},
+		{"Chrome on Windows", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36", false},
+		{"Safari on iPhone", "Mozilla/5.0 (iPhone; CPU iPhone OS 17_1 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.1 Mobile/15E148 Safari/604.1", false},
+		{"Firefox on Linux", "Mozilla/5.0 (X11; Linux x86_64; rv:121.0) Gecko/20100101 Firefox/121.0", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, events.IsBot(tt.userAgent))
+		})
+	}
+}
+
+func TestCollectEventBotFiltering(t *testing.T) {
+	dbManager, logger := testsupport.SetupTestDBManager(t)
+	db := dbManager.GetConnection()
+	testsupport.CleanAllTables(db)
+	testsupport.CreateTestWebsite(db, "example.com")
+	require.NoError(t, settings.SetupDefaultSettings(db))
+
+	collect := func(t *testing.T, userAgent string) int64 {
+		t.Helper()
+		db.Exec("DELETE FROM ingested_events")
+
+		require.NoError(t, events.CollectEvent(dbManager, logger, &events.CollectEventInput{
+			IPAddress: "203.0.113.5",
+			UserAgent: userAgent,
+			EventType: events.EventTypePageView,
+			Timestamp: time.Now().UTC(),
+			RawUrl:    "https://example.com/page",
+		}))
+
+		var count int64
+		require.NoError(t, db.Model(&events.IngestedEvent{}).Count(&count).Error)
+		return count
+	}
+
+	googlebot := "Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)"
+	chrome := "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36"
+
+	t.Run("skips known bots without erroring", func(t *testing.T) {
+		assert.Zero(t, collect(t, googlebot))
+		assert.Equal(t, int64(1), collect(t, chrome))
+	})
+
+	t.Run("applies custom patterns", func(t *testing.T) {
+		require.NoError(t, settings.SaveBotPatterns(db, "InternalHealthCheck\nacme-synthetic/[0-9]+"))
+
+		assert.Zero(t, collect(t, "InternalHealthCheck v1"))
+		assert.Zero(t, collect(t, "Mozilla/5.0 ACME-Synthetic/42"))
+		assert.Equal(t, int64(1), collect(t, chrome))
+	})
+
+	t.Run("stores bots when filtering is disabled", func(t *testing.T) {
+		require.NoError(t, settings.SaveBotFilteringEnabled(db, false))
+		defer settings.SaveBotFilteringEnabled(db, true)
+
+		assert.Equal(t, int64(1), collect(t, googlebot))
+	})
+}
@@ -0,0 +1,147 @@
+package events
+
+import (
+	"fmt"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/karloscodes/cartridge"
+	"github.com/karloscodes/cartridge/sqlite"
+	"gorm.io/gorm"
+
+	"fusionaly/internal/metrics"
+)
+
+// ErrIngestBufferFull is returned when buffered ingestion can't keep up and
+// the event was dropped. It wraps ErrDatabaseBusy so clients are told to back
+// off the same way as on write contention.
+var ErrIngestBufferFull = fmt.Errorf("ingestion buffer is full: %w", ErrDatabaseBusy)
+
+// bufferedWriter is the writer CollectEvent queues events on, nil when
+// events are written synchronously
+var bufferedWriter atomic.Pointer[BufferedWriter]
+
+// SetBufferedWriter routes CollectEvent through w instead of writing each
+// event in its own transaction. Passing nil restores synchronous writes.
+func SetBufferedWriter(w *BufferedWriter) {
+	bufferedWriter.Store(w)
+}
+
+// BufferedWriter queues collected events in a bounded in-memory buffer and
+// stores them from a background goroutine, in one transaction per batch. A
+// batch is flushed once it's full or when the flush interval ticks.
+type BufferedWriter struct {
+	dbManager     cartridge.DBManager
+	logger        *slog.Logger
+	queue         chan *IngestedEvent
+	batchSize     int
+	flushInterval time.Duration
+
+	stop     chan struct{}
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+// NewBufferedWriter creates a writer holding up to bufferSize queued events.
+// Call Start to begin flushing.
+func NewBufferedWriter(dbManager cartridge.DBManager, logger *slog.Logger, bufferSize, batchSize int, flushInterval time.Duration) *BufferedWriter {
+	return &BufferedWriter{
+		dbManager:     dbManager,
+		logger:        logger,
+		queue:         make(chan *IngestedEvent, max(bufferSize, 1)),
+		batchSize:     max(batchSize, 1),
+		flushInterval: flushInterval,
+		stop:          make(chan struct{}),
+		done:          make(chan struct{}),
+	}
+}
+
+// Start runs the background flush loop
+func (w *BufferedWriter) Start() {
+	go w.run()
+}
+
+// Stop flushes every queued event and waits for the flush loop to exit. Safe
+// to call more than once.
+func (w *BufferedWriter) Stop() {
+	w.stopOnce.Do(func() { close(w.stop) })
+	<-w.done
+}
+
+// Enqueue queues an event without blocking. When the buffer is full the event
+// is dropped, counted, and ErrIngestBufferFull is returned.
+func (w *BufferedWriter) Enqueue(event *IngestedEvent) error {
+	select {
+	case w.queue <- event:
+		return nil
+	default:
+		metrics.EventsDroppedTotal.Inc()
+		return ErrIngestBufferFull
+	}
+}
+
+// Pending returns how many events are waiting in the buffer
+func (w *BufferedWriter) Pending() int {
+	return len(w.queue)
+}
+
+func (w *BufferedWriter) run() {
+	defer close(w.done)
+
+	ticker := time.NewTicker(w.flushInterval)
+	defer ticker.Stop()
+
+	batch := make([]*IngestedEvent, 0, w.batchSize)
+	flush := func() {
+		if len(batch) > 0 {
+			w.flush(batch)
+			batch = make([]*IngestedEvent, 0, w.batchSize)
+		}
+	}
+
+	for {
+		select {
+		case event := <-w.queue:
+			batch = append(batch, event)
+			if len(batch) >= w.batchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		case <-w.stop:
+			for {
+				select {
+				case event := <-w.queue:
+					batch = append(batch, event)
+					if len(batch) >= w.batchSize {
+						flush()
+					}
+				default:
+					flush()
+					return
+				}
+			}
+		}
+	}
+}
+
+// flush stores a batch in one write transaction. A failed batch is logged and
+// counted; its events are lost, as they would be on a failed synchronous write.
+func (w *BufferedWriter) flush(batch []*IngestedEvent) {
+	db := w.dbManager.GetConnection()
+	dedupeWindow := getIngestionSettings(db).dedupeWindow
+
+	err := sqlite.PerformWrite(w.logger, db, func(tx *gorm.DB) error {
+		return insertIngestedEvents(tx, batch, dedupeWindow)
+	})
+	if err != nil {
+		recordWriteError(err)
+		w.logger.Error("Failed to flush ingestion buffer", slog.Any("error", err), slog.Int("count", len(batch)))
+		return
+	}
+
+	metrics.EventsIngestedTotal.Add(int64(len(batch)))
+	w.logger.Debug("Flushed ingestion buffer", slog.Int("count", len(batch)))
+}
@@ -0,0 +1,137 @@
+package events_test
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"fusionaly/internal/events"
+	"fusionaly/internal/metrics"
+	"fusionaly/internal/testsupport"
+)
+
+func TestBufferedWriter(t *testing.T) {
+	dbManager, _ := testsupport.SetupTestDBManager(t)
+	db := dbManager.GetConnection()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	// newEvent builds a page view the way CollectEvent would queue it
+	newEvent := func(websiteID uint, i int) *events.IngestedEvent {
+		now := time.Now().UTC()
+		return &events.IngestedEvent{
+			WebsiteID:        websiteID,
+			UserSignature:    fmt.Sprintf("visitor-%d", i),
+			Hostname:         "buffered.com",
+			Pathname:         "/",
+			RawURL:           "https://buffered.com/",
+			ReferrerHostname: events.DirectOrUnknownReferrer,
+			EventType:        events.EventTypePageView,
+			Timestamp:        now,
+			UserAgent:        "Mozilla/5.0 (test)",
+			CreatedAt:        now,
+		}
+	}
+	storedCount := func() int64 {
+		var count int64
+		db.Model(&events.IngestedEvent{}).Count(&count)
+		return count
+	}
+
+	t.Run("flushes when a batch is full", func(t *testing.T) {
+		testsupport.CleanAllTables(db)
+		website := testsupport.CreateTestWebsite(db, "buffered.com")
+
+		writer := events.NewBufferedWriter(dbManager, logger, 100, 5, time.Hour)
+		writer.Start()
+		defer writer.Stop()
+
+		for i := range 4 {
+			require.NoError(t, writer.Enqueue(newEvent(website.ID, i)))
+		}
+		time.Sleep(50 * time.Millisecond)
+		assert.Zero(t, storedCount(), "a partial batch waits for the flush interval")
+
+		require.NoError(t, writer.Enqueue(newEvent(website.ID, 4)))
+		assert.Eventually(t, func() bool { return storedCount() == 5 }, 2*time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("flushes on the interval", func(t *testing.T) {
+		testsupport.CleanAllTables(db)
+		website := testsupport.CreateTestWebsite(db, "buffered.com")
+
+		writer := events.NewBufferedWriter(dbManager, logger, 100, 100, 20*time.Millisecond)
+		writer.Start()
+		defer writer.Stop()
+
+		require.NoError(t, writer.Enqueue(newEvent(website.ID, 0)))
+		require.NoError(t, writer.Enqueue(newEvent(website.ID, 1)))
+		assert.Eventually(t, func() bool { return storedCount() == 2 }, 2*time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("stop flushes queued events", func(t *testing.T) {
+		testsupport.CleanAllTables(db)
+		website := testsupport.CreateTestWebsite(db, "buffered.com")
+
+		writer := events.NewBufferedWriter(dbManager, logger, 100, 100, time.Hour)
+		writer.Start()
+		for i := range 3 {
+			require.NoError(t, writer.Enqueue(newEvent(website.ID, i)))
+		}
+
+		writer.Stop()
+		assert.Equal(t, int64(3), storedCount())
+	})
+
+	t.Run("drops events when the buffer is full", func(t *testing.T) {
+		testsupport.CleanAllTables(db)
+		website := testsupport.CreateTestWebsite(db, "buffered.com")
+
+		// Not started, so nothing drains the buffer
+		writer := events.NewBufferedWriter(dbManager, logger, 2, 100, time.Hour)
+		require.NoError(t, writer.Enqueue(newEvent(website.ID, 0)))
+		require.NoError(t, writer.Enqueue(newEvent(website.ID, 1)))
+
+		dropped := metrics.EventsDroppedTotal.Value()
+		err := writer.Enqueue(newEvent(website.ID, 2))
+		require.Error(t, err)
+		assert.True(t, errors.Is(err, events.ErrIngestBufferFull))
+		assert.True(t, errors.Is(err, events.ErrDatabaseBusy), "clients are told to retry as on write contention")
+		assert.Equal(t, dropped+1, metrics.EventsDroppedTotal.Value())
+		assert.Equal(t, 2, writer.Pending())
+
+		writer.Start()
+		writer.Stop()
+		assert.Equal(t, int64(2), storedCount())
+	})
+
+	t.Run("collect event queues on the buffered writer", func(t *testing.T) {
+		testsupport.CleanAllTables(db)
+		testsupport.CreateTestWebsite(db, "buffered.com")
+
+		writer := events.NewBufferedWriter(dbManager, logger, 100, 100, time.Hour)
+		writer.Start()
+		events.SetBufferedWriter(writer)
+		t.Cleanup(func() { events.SetBufferedWriter(nil) })
+
+		require.NoError(t, events.CollectEvent(dbManager, logger, &events.CollectEventInput{
+			IPAddress: "203.0.113.1",
+			UserAgent: "Mozilla/5.0 (test)",
+			EventType: events.EventTypePageView,
+			Timestamp: time.Now().UTC(),
+			RawUrl:    "https://buffered.com/pricing",
+		}))
+		assert.Zero(t, storedCount(), "the event waits in the buffer")
+		assert.Equal(t, 1, writer.Pending())
+
+		writer.Stop()
+		var event events.IngestedEvent
+		require.NoError(t, db.First(&event).Error)
+		assert.Equal(t, "/pricing", event.Pathname)
+	})
+}
@@ -3,9 +3,20 @@ package events
 // Constants for unknown or default values
 const (
 	DirectOrUnknownReferrer = "__direct_or_unknown__"
+	SelfReferral            = "__self_referral__" // Only recorded when self-referrals are kept apart from direct traffic
 	UnknownDevice           = "__unknown_device__"
+	UnknownDeviceModel      = "__unknown_device_model__"
 	UnknownBrowser          = "__unknown_browser__"
 	UnknownOS               = "__unknown_os__"
 	UnknownCountry          = "__unknown_country__"
+	UnknownLanguage         = "__unknown_language__"
 	EmptyUTMAttr            = "__empty__"
 )
+
+// Viewport width buckets stored in screen_stats
+const (
+	ScreenSizeMobile  = "mobile"  // < 768px
+	ScreenSizeTablet  = "tablet"  // 768-1023px
+	ScreenSizeLaptop  = "laptop"  // 1024-1439px
+	ScreenSizeDesktop = "desktop" // >= 1440px
+)
@@ -0,0 +1,34 @@
+package events
+
+import (
+	"net/url"
+	"path"
+	"strings"
+)
+
+// NormalizeDownloadFilename reduces a downloaded file's URL to its file name.
+// Query strings and fragments are dropped so signed or cache-busted links to
+// the same file are counted together.
+func NormalizeDownloadFilename(rawURL string) string {
+	name := strings.TrimSpace(rawURL)
+	if i := strings.IndexAny(name, "?#"); i >= 0 {
+		name = name[:i]
+	}
+	if parsed, err := url.Parse(name); err == nil && parsed.Host != "" {
+		name = parsed.Path
+	}
+
+	name = path.Base(strings.TrimRight(name, "/"))
+	if unescaped, err := url.PathUnescape(name); err == nil {
+		name = unescaped
+	}
+	if name == "." || name == "/" {
+		return ""
+	}
+	return name
+}
+
+// downloadExtension returns the lowercased extension of a file name without the dot.
+func downloadExtension(filename string) string {
+	return strings.ToLower(strings.TrimPrefix(path.Ext(filename), "."))
+}
@@ -0,0 +1,100 @@
+package events_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"fusionaly/internal/analytics"
+	"fusionaly/internal/events"
+	"fusionaly/internal/testsupport"
+	"fusionaly/internal/timeframe"
+)
+
+func TestNormalizeDownloadFilename(t *testing.T) {
+	tests := []struct {
+		raw      string
+		expected string
+	}{
+		{"https://cdn.example.com/files/report.pdf", "report.pdf"},
+		{"https://cdn.example.com/files/report.pdf?token=abc&v=2", "report.pdf"},
+		{"/downloads/app-1.2.0.dmg#release", "app-1.2.0.dmg"},
+		{"archive.tar.gz", "archive.tar.gz"},
+		{"https://example.com/files/annual%20report.pdf", "annual report.pdf"},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.raw, func(t *testing.T) {
+			assert.Equal(t, tt.expected, events.NormalizeDownloadFilename(tt.raw))
+		})
+	}
+}
+
+func TestDownloadAggregation(t *testing.T) {
+	dbManager, logger, website := testsupport.SetupTestDBManagerWithWebsite(t, "downloads.com")
+	db := dbManager.GetConnection()
+
+	base := time.Date(2024, 7, 1, 10, 0, 0, 0, time.UTC)
+	downloads := []struct {
+		ip      string
+		fileURL string
+		offset  time.Duration
+	}{
+		{"203.0.113.1", "https://downloads.com/files/report.pdf?token=first", 0},
+		{"203.0.113.1", "https://downloads.com/files/report.pdf?token=second", 2 * time.Minute},
+		{"203.0.113.2", "https://downloads.com/files/report.pdf", 3 * time.Minute},
+		{"203.0.113.2", "https://downloads.com/files/setup.zip", 4 * time.Minute},
+	}
+	for _, d := range downloads {
+		require.NoError(t, events.CollectEvent(dbManager, logger, &events.CollectEventInput{
+			IPAddress:       d.ip,
+			UserAgent:       "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36",
+			EventType:       events.EventTypeDownload,
+			CustomEventName: d.fileURL,
+			Timestamp:       base.Add(d.offset),
+			RawUrl:          "https://downloads.com/resources",
+		}))
+	}
+	require.NoError(t, testsupport.ProcessAllTestEvents(dbManager, logger))
+
+	t.Run("counts repeat downloads once per visitor", func(t *testing.T) {
+		var stat struct {
+			Extension      string
+			VisitorsCount  int
+			DownloadsCount int
+		}
+		require.NoError(t, db.Raw(`
+			SELECT extension, SUM(visitors_count) as visitors_count, SUM(downloads_count) as downloads_count
+			FROM download_stats WHERE website_id = ? AND filename = ?
+			GROUP BY extension`, website.ID, "report.pdf").Scan(&stat).Error)
+
+		assert.Equal(t, "pdf", stat.Extension)
+		assert.Equal(t, 2, stat.VisitorsCount)
+		assert.Equal(t, 3, stat.DownloadsCount)
+	})
+
+	t.Run("does not count downloads as page views", func(t *testing.T) {
+		var pageViews int64
+		require.NoError(t, db.Raw("SELECT COALESCE(SUM(page_views), 0) FROM site_stats WHERE website_id = ?", website.ID).Scan(&pageViews).Error)
+		assert.Zero(t, pageViews)
+	})
+
+	t.Run("ranks top downloads by total downloads", func(t *testing.T) {
+		timeFrame, err := timeframe.NewTimeFrame(timeframe.TimeFrameParams{
+			FromTime:      base.Truncate(24 * time.Hour),
+			ToTime:        base.Truncate(24 * time.Hour).Add(24*time.Hour - time.Second),
+			TimeFrameSize: timeframe.DailyTimeFrame,
+		}, time.UTC)
+		require.NoError(t, err)
+
+		results, err := analytics.GetTopDownloadsInTimeFrame(db, analytics.NewWebsiteScopedQueryParams(timeFrame, int(website.ID)))
+		require.NoError(t, err)
+		assert.Equal(t, []analytics.MetricCountResult{
+			{Name: "report.pdf", Count: 3},
+			{Name: "setup.zip", Count: 1},
+		}, results)
+	})
+}
@@ -6,6 +6,7 @@ import (
 	"testing"
 	"time"
 
+	"fusionaly/internal/analytics"
 	"fusionaly/internal/events"
 	"fusionaly/internal/settings"
 	"fusionaly/internal/visitors"
@@ -17,6 +18,7 @@ import (
 
 	"fusionaly/internal/config"
 	"fusionaly/internal/testsupport"
+	"fusionaly/internal/timeframe"
 )
 
 // TestProcessUnprocessedEvents tests the ProcessUnprocessedEvents function
@@ -814,6 +816,26 @@ func TestCollectEventComprehensive(t *testing.T) {
 				assert.Equal(t, "/search", event.ReferrerPathname, "External referrer pathname should be preserved")
 			},
 		},
+		{
+			name: "Spam referrer treated as direct traffic",
+			setup: func(t *testing.T) {
+				// No additional setup needed
+			},
+			input: events.CollectEventInput{
+				IPAddress:   "192.168.1.23",
+				UserAgent:   "Mozilla/5.0 (test)",
+				ReferrerURL: "https://www.semalt.com/crawler", // Blocklisted spam referrer
+				EventType:   events.EventTypePageView,
+				Timestamp:   time.Now().UTC(),
+				RawUrl:      "https://example.com/landing",
+			},
+			expectedError: false,
+			validate: func(t *testing.T, event *events.IngestedEvent) {
+				assert.Equal(t, baseWebsite.ID, event.WebsiteID)
+				assert.Equal(t, events.DirectOrUnknownReferrer, event.ReferrerHostname, "Spam referrer should be treated as direct traffic")
+				assert.Equal(t, "", event.ReferrerPathname, "Spam referrer pathname should be empty")
+			},
+		},
 	}
 
 	// Run test cases
@@ -949,6 +971,449 @@ func TestCollectEventIPExclusion(t *testing.T) {
 	}
 }
 
+func TestCollectEventIPRangeExclusion(t *testing.T) {
+	dbManager, logger := testsupport.SetupTestDBManager(t)
+	db := dbManager.GetConnection()
+	testsupport.CleanAllTables(db)
+
+	testsupport.CreateTestWebsite(db, "example.com")
+
+	err := settings.SetupDefaultSettings(db)
+	require.NoError(t, err)
+
+	// An office /24, a VPN /16, an IPv6 /32 and a single address
+	err = settings.UpdateSetting(db, "excluded_ips", "192.168.1.0/24, 10.8.0.0/16, 2001:db8::/32, 203.0.113.7")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name       string
+		ipAddress  string
+		shouldSkip bool
+	}{
+		{name: "IPv4 in /24", ipAddress: "192.168.1.42", shouldSkip: true},
+		{name: "IPv4 just outside /24", ipAddress: "192.168.2.1", shouldSkip: false},
+		{name: "IPv4 in /16", ipAddress: "10.8.250.3", shouldSkip: true},
+		{name: "IPv4 outside /16", ipAddress: "10.9.0.1", shouldSkip: false},
+		{name: "IPv6 in /32", ipAddress: "2001:db8:abcd::1", shouldSkip: true},
+		{name: "IPv6 outside /32", ipAddress: "2001:db9::1", shouldSkip: false},
+		{name: "exact IP still excluded", ipAddress: "203.0.113.7", shouldSkip: true},
+		{name: "neighbouring exact IP allowed", ipAddress: "203.0.113.8", shouldSkip: false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			db.Exec("DELETE FROM ingested_events")
+
+			input := events.CollectEventInput{
+				IPAddress:   tc.ipAddress,
+				UserAgent:   "Mozilla/5.0 (test)",
+				ReferrerURL: "https://google.com/search",
+				EventType:   events.EventTypePageView,
+				Timestamp:   time.Now().UTC(),
+				RawUrl:      "https://example.com/page",
+			}
+			require.NoError(t, events.CollectEvent(dbManager, logger, &input))
+
+			var count int64
+			require.NoError(t, db.Model(&events.IngestedEvent{}).Count(&count).Error)
+			if tc.shouldSkip {
+				assert.Equal(t, int64(0), count, "Event should be skipped for excluded IP")
+			} else {
+				assert.Equal(t, int64(1), count, "Event should be created for allowed IP")
+			}
+		})
+	}
+}
+
+func TestCollectEventPathExclusion(t *testing.T) {
+	dbManager, logger := testsupport.SetupTestDBManager(t)
+	db := dbManager.GetConnection()
+	testsupport.CleanAllTables(db)
+
+	// Create test website
+	testsupport.CreateTestWebsite(db, "example.com")
+
+	// Initialize default settings to ensure cache is set up
+	err := settings.SetupDefaultSettings(db)
+	require.NoError(t, err)
+
+	// Set up excluded paths
+	err = settings.SaveExcludedPaths(db, "/wp-admin, /preview/*, /internal/*/debug")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name       string
+		path       string
+		shouldSkip bool
+	}{
+		{name: "Unmatched path", path: "/pricing", shouldSkip: false},
+		{name: "Exact prefix", path: "/wp-admin", shouldSkip: true},
+		{name: "Trailing slash is normalized", path: "/wp-admin/", shouldSkip: true},
+		{name: "Below an anchored prefix", path: "/wp-admin/users.php", shouldSkip: true},
+		{name: "Similar but different segment", path: "/wp-administrator", shouldSkip: false},
+		{name: "Wildcard child", path: "/preview/draft-42", shouldSkip: true},
+		{name: "Wildcard parent itself", path: "/preview", shouldSkip: false},
+		{name: "Wildcard in the middle", path: "/internal/jobs/debug", shouldSkip: true},
+		{name: "Prefix must be anchored", path: "/blog/wp-admin", shouldSkip: false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			// Clean up events from previous tests
+			db.Exec("DELETE FROM ingested_events")
+
+			input := events.CollectEventInput{
+				IPAddress: "203.0.113.1",
+				UserAgent: "Mozilla/5.0 (test)",
+				EventType: events.EventTypePageView,
+				Timestamp: time.Now().UTC(),
+				RawUrl:    "https://example.com" + tc.path,
+			}
+
+			err := events.CollectEvent(dbManager, logger, &input)
+			assert.NoError(t, err)
+
+			var count int64
+			err = db.Model(&events.IngestedEvent{}).Count(&count).Error
+			require.NoError(t, err)
+
+			if tc.shouldSkip {
+				assert.Equal(t, int64(0), count, "Event should be skipped for excluded path")
+			} else {
+				assert.Equal(t, int64(1), count, "Event should be created for tracked path")
+			}
+		})
+	}
+}
+
+func TestStripUntrackedQueryParams(t *testing.T) {
+	allowed := []string{"ref", "Page"}
+
+	tests := []struct {
+		name     string
+		rawURL   string
+		allowed  []string
+		expected string
+	}{
+		{"no allowlist keeps everything", "https://example.com/a?fbclid=1&x=2", nil, "https://example.com/a?fbclid=1&x=2"},
+		{"drops click IDs", "https://example.com/a?fbclid=abc&gclid=def", allowed, "https://example.com/a"},
+		{"keeps allowlisted params in order", "https://example.com/a?page=2&session=abc&ref=hn", allowed, "https://example.com/a?page=2&ref=hn"},
+		{"always keeps UTMs", "https://example.com/a?utm_source=x&session=abc123&utm_medium=email", allowed, "https://example.com/a?utm_source=x&utm_medium=email"},
+		{"always keeps ref", "https://example.com/a?REF=hn&session=abc", []string{"page"}, "https://example.com/a?REF=hn"},
+		{"preserves fragments", "https://example.com/a?ref=x&fbclid=y#top", allowed, "https://example.com/a?ref=x#top"},
+		{"no query string", "https://example.com/a#top", allowed, "https://example.com/a#top"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, events.StripUntrackedQueryParams(tc.rawURL, tc.allowed))
+		})
+	}
+}
+
+func TestNormalizePath(t *testing.T) {
+	patterns, err := events.ParsePathPatterns([]string{
+		`^/users/\d+ => /users/:id`,
+		`[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12} => :uuid`,
+		`^/blog/(\d{4})/[^/]+$ => /blog/$1/:slug`,
+	})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		path     string
+		patterns []events.PathPattern
+		expected string
+	}{
+		{"numeric ID", "/users/123", patterns, "/users/:id"},
+		{"numeric ID with child route", "/users/42/settings", patterns, "/users/:id/settings"},
+		{"UUID", "/orders/3f2c9a1e-8b4d-4c6e-9f1a-2b3c4d5e6f70/receipt", patterns, "/orders/:uuid/receipt"},
+		{"capture group expansion", "/blog/2024/hello-world", patterns, "/blog/2024/:slug"},
+		{"no match passes through", "/pricing", patterns, "/pricing"},
+		{"anchored pattern ignores other prefixes", "/admin/users/123", patterns, "/admin/users/123"},
+		{"no patterns keeps the raw path", "/users/123", nil, "/users/123"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, events.NormalizePath(tc.path, tc.patterns))
+		})
+	}
+}
+
+func TestParsePathPatterns(t *testing.T) {
+	patterns, err := events.ParsePathPatterns([]string{"", "^/a/\\d+ => /a/:id", "missing separator", "([ => /broken"})
+	assert.Error(t, err)
+	require.Len(t, patterns, 1, "valid rules are kept even when others are invalid")
+	assert.Equal(t, "/a/:id", patterns[0].Replacement)
+}
+
+func TestCollectEventPathPatterns(t *testing.T) {
+	dbManager, logger := testsupport.SetupTestDBManager(t)
+	db := dbManager.GetConnection()
+	testsupport.CleanAllTables(db)
+	testsupport.CreateTestWebsite(db, "example.com")
+	require.NoError(t, settings.SetupDefaultSettings(db))
+
+	collect := func(t *testing.T, path string) events.IngestedEvent {
+		t.Helper()
+		db.Exec("DELETE FROM ingested_events")
+
+		require.NoError(t, events.CollectEvent(dbManager, logger, &events.CollectEventInput{
+			IPAddress: "203.0.113.1",
+			UserAgent: "Mozilla/5.0 (test)",
+			EventType: events.EventTypePageView,
+			Timestamp: time.Now().UTC(),
+			RawUrl:    "https://example.com" + path,
+		}))
+
+		var event events.IngestedEvent
+		require.NoError(t, db.First(&event).Error)
+		return event
+	}
+
+	t.Run("keeps the raw path when unset", func(t *testing.T) {
+		assert.Equal(t, "/users/123", collect(t, "/users/123").Pathname)
+	})
+
+	t.Run("rewrites matching paths", func(t *testing.T) {
+		require.NoError(t, settings.SavePathPatterns(db, "^/users/\\d+ => /users/:id"))
+
+		assert.Equal(t, "/users/:id", collect(t, "/users/123").Pathname)
+		assert.Equal(t, "/pricing", collect(t, "/pricing").Pathname)
+	})
+}
+
+func TestCanonicalizePath(t *testing.T) {
+	tests := []struct {
+		path               string
+		lowercase          bool
+		stripTrailingSlash bool
+		expected           string
+	}{
+		{"/About/", false, false, "/About/"},
+		{"/About/", true, false, "/about/"},
+		{"/About/", false, true, "/About"},
+		{"/About//", true, true, "/about"},
+		{"/", true, true, "/"},
+		{"//", false, true, "/"},
+	}
+
+	for _, tc := range tests {
+		assert.Equal(t, tc.expected, events.CanonicalizePath(tc.path, tc.lowercase, tc.stripTrailingSlash), tc.path)
+	}
+}
+
+func TestCollectEventPathCanonicalization(t *testing.T) {
+	dbManager, logger := testsupport.SetupTestDBManager(t)
+	db := dbManager.GetConnection()
+	testsupport.CleanAllTables(db)
+	testsupport.CreateTestWebsite(db, "example.com")
+	require.NoError(t, settings.SetupDefaultSettings(db))
+
+	collectPaths := func(t *testing.T, paths ...string) []string {
+		t.Helper()
+		db.Exec("DELETE FROM ingested_events")
+
+		for i, path := range paths {
+			require.NoError(t, events.CollectEvent(dbManager, logger, &events.CollectEventInput{
+				IPAddress: fmt.Sprintf("203.0.113.%d", i+1),
+				UserAgent: "Mozilla/5.0 (test)",
+				EventType: events.EventTypePageView,
+				Timestamp: time.Now().UTC(),
+				RawUrl:    "https://example.com" + path,
+			}))
+		}
+
+		var pathnames []string
+		require.NoError(t, db.Model(&events.IngestedEvent{}).Distinct().Order("pathname").Pluck("pathname", &pathnames).Error)
+		return pathnames
+	}
+
+	t.Run("paths are kept as visited by default", func(t *testing.T) {
+		assert.Equal(t, []string{"/About", "/about", "/about/"}, collectPaths(t, "/About", "/about", "/about/"))
+	})
+
+	t.Run("variants aggregate together when enabled", func(t *testing.T) {
+		require.NoError(t, settings.SavePathCaseInsensitive(db, true))
+		require.NoError(t, settings.SaveTrailingSlashStripEnabled(db, true))
+
+		assert.Equal(t, []string{"/about"}, collectPaths(t, "/About", "/about", "/about/"))
+		assert.Equal(t, []string{"/"}, collectPaths(t, "/"), "the root path is never stripped to empty")
+	})
+}
+
+func TestCollectEventSelfReferralModes(t *testing.T) {
+	dbManager, logger := testsupport.SetupTestDBManager(t)
+	db := dbManager.GetConnection()
+	testsupport.CleanAllTables(db)
+	testsupport.CreateTestWebsite(db, "example.com")
+	require.NoError(t, settings.SetupDefaultSettings(db))
+
+	collect := func(t *testing.T, referrerURL string) events.IngestedEvent {
+		t.Helper()
+		db.Exec("DELETE FROM ingested_events")
+
+		require.NoError(t, events.CollectEvent(dbManager, logger, &events.CollectEventInput{
+			IPAddress:   "203.0.113.1",
+			UserAgent:   "Mozilla/5.0 (test)",
+			ReferrerURL: referrerURL,
+			EventType:   events.EventTypePageView,
+			Timestamp:   time.Now().UTC(),
+			RawUrl:      "https://example.com/pricing",
+		}))
+
+		var event events.IngestedEvent
+		require.NoError(t, db.First(&event).Error)
+		return event
+	}
+
+	t.Run("merged with direct by default", func(t *testing.T) {
+		event := collect(t, "https://example.com/blog")
+		assert.Equal(t, events.DirectOrUnknownReferrer, event.ReferrerHostname)
+		assert.Empty(t, event.ReferrerPathname)
+	})
+
+	t.Run("kept apart when enabled", func(t *testing.T) {
+		require.NoError(t, settings.SaveSelfReferralSeparationEnabled(db, true))
+		t.Cleanup(func() { settings.SaveSelfReferralSeparationEnabled(db, false) })
+
+		event := collect(t, "https://example.com/blog")
+		assert.Equal(t, events.SelfReferral, event.ReferrerHostname)
+		assert.Empty(t, event.ReferrerPathname)
+
+		// External referrers and direct visits are unaffected
+		assert.Equal(t, "news.ycombinator.com", collect(t, "https://news.ycombinator.com/item").ReferrerHostname)
+		assert.Equal(t, events.DirectOrUnknownReferrer, collect(t, "").ReferrerHostname)
+	})
+}
+
+func TestCollectEventTrackedQueryParams(t *testing.T) {
+	dbManager, logger := testsupport.SetupTestDBManager(t)
+	db := dbManager.GetConnection()
+	testsupport.CleanAllTables(db)
+	website := testsupport.CreateTestWebsite(db, "example.com")
+	testsupport.CreateTestWebsite(db, "other.com")
+	require.NoError(t, settings.SetupDefaultSettings(db))
+
+	collect := func(t *testing.T, rawURL string) events.IngestedEvent {
+		t.Helper()
+		db.Exec("DELETE FROM ingested_events")
+
+		require.NoError(t, events.CollectEvent(dbManager, logger, &events.CollectEventInput{
+			IPAddress: "203.0.113.1",
+			UserAgent: "Mozilla/5.0 (test)",
+			EventType: events.EventTypePageView,
+			Timestamp: time.Now().UTC(),
+			RawUrl:    rawURL,
+		}))
+
+		var event events.IngestedEvent
+		require.NoError(t, db.First(&event).Error)
+		return event
+	}
+
+	rawURL := "https://example.com/article?utm_source=newsletter&session=abc123&fbclid=xyz&gclid=123&ref=hn"
+
+	t.Run("keeps the full URL when unset", func(t *testing.T) {
+		assert.Equal(t, rawURL, collect(t, rawURL).RawURL)
+	})
+
+	t.Run("strips params outside the allowlist", func(t *testing.T) {
+		require.NoError(t, settings.SaveTrackedQueryParams(db, "ref"))
+
+		event := collect(t, rawURL)
+		assert.Equal(t, "https://example.com/article?utm_source=newsletter&ref=hn", event.RawURL)
+		assert.Equal(t, "/article", event.Pathname)
+	})
+
+	t.Run("always keeps ref and the website's dashboard breakdowns", func(t *testing.T) {
+		require.NoError(t, settings.SaveTrackedQueryParams(db, "page"))
+		require.NoError(t, settings.SaveDashboardTrackParams(db, website.ID, []string{"session"}))
+
+		assert.Equal(t, "https://example.com/article?utm_source=newsletter&session=abc123&ref=hn", collect(t, rawURL).RawURL)
+		assert.Equal(t, "https://other.com/article?utm_source=newsletter&ref=hn",
+			collect(t, "https://other.com/article?utm_source=newsletter&session=abc123&fbclid=xyz&ref=hn").RawURL)
+	})
+}
+
+func TestCollectEventIdempotency(t *testing.T) {
+	dbManager, logger := testsupport.SetupTestDBManager(t)
+	db := dbManager.GetConnection()
+	testsupport.CleanAllTables(db)
+	testsupport.CreateTestWebsite(db, "example.com")
+	require.NoError(t, settings.SetupDefaultSettings(db))
+
+	collect := func(t *testing.T, eventID string) {
+		t.Helper()
+		require.NoError(t, events.CollectEvent(dbManager, logger, &events.CollectEventInput{
+			IPAddress: "203.0.113.1",
+			UserAgent: "Mozilla/5.0 (test)",
+			EventType: events.EventTypePageView,
+			Timestamp: time.Now().UTC(),
+			RawUrl:    "https://example.com/retry",
+			EventID:   eventID,
+		}))
+	}
+	countEvents := func(t *testing.T) int64 {
+		t.Helper()
+		var count int64
+		require.NoError(t, db.Model(&events.IngestedEvent{}).Count(&count).Error)
+		return count
+	}
+
+	t.Run("ignores a duplicate event ID within the window", func(t *testing.T) {
+		db.Exec("DELETE FROM ingested_events")
+		collect(t, "4f0c7b0e-8a51-4c1e-9d0a-1f6f1b0c2a11")
+		collect(t, "4f0c7b0e-8a51-4c1e-9d0a-1f6f1b0c2a11")
+		assert.Equal(t, int64(1), countEvents(t))
+	})
+
+	t.Run("ignores duplicates within a batch", func(t *testing.T) {
+		db.Exec("DELETE FROM ingested_events")
+		input := func() *events.CollectEventInput {
+			return &events.CollectEventInput{
+				IPAddress: "203.0.113.1",
+				UserAgent: "Mozilla/5.0 (test)",
+				EventType: events.EventTypePageView,
+				Timestamp: time.Now().UTC(),
+				RawUrl:    "https://example.com/retry",
+				EventID:   "batch-dup",
+			}
+		}
+		for _, err := range events.CollectEvents(dbManager, logger, []*events.CollectEventInput{input(), input()}) {
+			assert.NoError(t, err)
+		}
+		assert.Equal(t, int64(1), countEvents(t))
+	})
+
+	t.Run("stores events without an ID every time", func(t *testing.T) {
+		db.Exec("DELETE FROM ingested_events")
+		collect(t, "")
+		collect(t, "")
+		assert.Equal(t, int64(2), countEvents(t))
+	})
+
+	t.Run("accepts the ID again once the window has passed", func(t *testing.T) {
+		db.Exec("DELETE FROM ingested_events")
+		collect(t, "expired-id")
+		require.NoError(t, db.Exec("UPDATE ingested_events SET created_at = ?", time.Now().UTC().Add(-2*time.Hour)).Error)
+
+		collect(t, "expired-id")
+		assert.Equal(t, int64(2), countEvents(t))
+	})
+
+	t.Run("a zero window disables deduplication", func(t *testing.T) {
+		db.Exec("DELETE FROM ingested_events")
+		require.NoError(t, settings.SaveDedupeWindowMinutes(db, 0))
+		t.Cleanup(func() { settings.SaveDedupeWindowMinutes(db, settings.DefaultDedupeWindowMinutes) })
+
+		collect(t, "no-dedupe")
+		collect(t, "no-dedupe")
+		assert.Equal(t, int64(2), countEvents(t))
+	})
+}
+
 // TestCollectEventEdgeCases tests edge cases and error conditions
 func TestCollectEventEdgeCases(t *testing.T) {
 	dbManager, logger := testsupport.SetupTestDBManager(t)
@@ -958,6 +1423,11 @@ func TestCollectEventEdgeCases(t *testing.T) {
 	// Create test website
 	testsupport.CreateTestWebsite(db, "example.com")
 
+	// Keep the loopback address excluded so the localhost case is skipped
+	// outside production too
+	require.NoError(t, settings.SetupDefaultSettings(db))
+	require.NoError(t, settings.UpdateSetting(db, "excluded_ips", "127.0.0.1"))
+
 	tests := []struct {
 		name          string
 		input         events.CollectEventInput
@@ -1343,6 +1813,50 @@ func TestVisitorStatusDetermination(t *testing.T) {
 	assert.True(t, data4.IsNewSession, "Event for a different visitor should be a new session")
 }
 
+// TestCollectEventDailySignatureRotation checks that signatures are salted by
+// the day the event happened, not the day it was received.
+func TestCollectEventDailySignatureRotation(t *testing.T) {
+	dbManager, logger := testsupport.SetupTestDBManager(t)
+	db := dbManager.GetConnection()
+	testsupport.CleanAllTables(db)
+
+	testsupport.CreateTestWebsite(db, "example.com")
+
+	timestamps := []time.Time{
+		time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC),
+		time.Date(2024, 1, 1, 23, 50, 0, 0, time.UTC),
+		time.Date(2024, 1, 2, 0, 10, 0, 0, time.UTC),
+	}
+	var newVisitor []bool
+	for i, ts := range timestamps {
+		require.NoError(t, events.CollectEvent(dbManager, logger, &events.CollectEventInput{
+			IPAddress: "203.0.113.5",
+			UserAgent: "Mozilla/5.0 (test)",
+			EventType: events.EventTypePageView,
+			Timestamp: ts,
+			RawUrl:    fmt.Sprintf("https://example.com/page%d", i),
+		}))
+
+		result, err := events.ProcessUnprocessedEvents(dbManager, logger, 10)
+		require.NoError(t, err)
+		require.Len(t, result.ProcessingData, 1)
+		newVisitor = append(newVisitor, result.ProcessingData[0].IsNewVisitor)
+	}
+
+	var processed []events.Event
+	require.NoError(t, db.Order("timestamp").Find(&processed).Error)
+	require.Len(t, processed, 3)
+
+	privateKey := config.GetConfig().PrivateKey
+	for i, event := range processed {
+		expected := visitors.BuildUniqueVisitorIdForDay("example.com", "203.0.113.5", "Mozilla/5.0 (test)", privateKey, timestamps[i])
+		assert.Equal(t, expected, event.UserSignature, "event %d should use its own day's salt", i)
+	}
+	assert.Equal(t, processed[0].UserSignature, processed[1].UserSignature, "signatures match within a day")
+	assert.NotEqual(t, processed[1].UserSignature, processed[2].UserSignature, "signatures differ across days")
+	assert.Equal(t, []bool{true, false, true}, newVisitor, "the visitor counts once per day")
+}
+
 // TestCollectEventSubdomainUserSignature tests the new logic for user signature generation
 // with subdomain tracking enabled/disabled
 func TestCollectEventSubdomainUserSignature(t *testing.T) {
@@ -1617,3 +2131,438 @@ func TestNormalizeOperatingSystem(t *testing.T) {
 		})
 	}
 }
+
+func TestNormalizeDeviceModel(t *testing.T) {
+	tests := []struct {
+		name     string
+		brand    string
+		model    string
+		expected string
+	}{
+		{
+			name:     "Brand and model",
+			brand:    "Samsung",
+			model:    "Galaxy S23",
+			expected: "Samsung Galaxy S23",
+		},
+		{
+			name:     "Model already prefixed with brand",
+			brand:    "Google",
+			model:    "Google Pixel",
+			expected: "Google Pixel",
+		},
+		{
+			name:     "Brand only",
+			brand:    "Xiaomi",
+			model:    "",
+			expected: "Xiaomi",
+		},
+		{
+			name:     "Model equal to brand",
+			brand:    "Nokia",
+			model:    "Nokia",
+			expected: "Nokia",
+		},
+		{
+			name:     "Extra whitespace",
+			brand:    " Apple ",
+			model:    "iPhone ",
+			expected: "Apple iPhone",
+		},
+		{
+			name:     "Generic mobile fallback",
+			brand:    "Mobile",
+			model:    "Mobile Device",
+			expected: events.UnknownDeviceModel,
+		},
+		{
+			name:     "Generic tablet fallback",
+			brand:    "Tablet",
+			model:    "Tablet Device",
+			expected: events.UnknownDeviceModel,
+		},
+		{
+			name:     "Empty brand",
+			brand:    "",
+			model:    "",
+			expected: events.UnknownDeviceModel,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := events.NormalizeDeviceModel(tt.brand, tt.model)
+			assert.Equal(t, tt.expected, result)
+		})
+	}
+}
+
+func TestCollectEventSubdomainRollup(t *testing.T) {
+	dbManager, logger := testsupport.SetupTestDBManager(t)
+	db := dbManager.GetConnection()
+	testsupport.CleanAllTables(db)
+	require.NoError(t, settings.SetupDefaultSettings(db))
+
+	website := testsupport.CreateTestWebsite(db, "example.com")
+	ts := time.Now().UTC().Truncate(time.Hour)
+
+	collect := func(t *testing.T, rawURL, ip string) {
+		input := testsupport.CreateTestEventInput(ip, "Mozilla/5.0 (rollup-agent)", events.EventTypePageView, ts, rawURL, "", "", "")
+		require.NoError(t, events.CollectEvent(dbManager, logger, input))
+	}
+	pageStats := func(t *testing.T) map[string]int {
+		var stats []analytics.PageStat
+		require.NoError(t, db.Where("website_id = ?", website.ID).Find(&stats).Error)
+		counts := make(map[string]int)
+		for _, stat := range stats {
+			counts[stat.Hostname+stat.Pathname] += stat.PageViewsCount
+		}
+		return counts
+	}
+
+	t.Run("rollup stores subdomain pages under the base domain", func(t *testing.T) {
+		testsupport.CleanAllTables(db)
+		website = testsupport.CreateTestWebsite(db, "example.com")
+		require.NoError(t, settings.UpdateSubdomainTrackingSettings(db, "example.com", true))
+		require.NoError(t, settings.UpdateSubdomainRollupSettings(db, "example.com", true))
+
+		collect(t, "https://a.example.com/pricing", "10.0.0.1")
+		collect(t, "https://b.example.com/pricing", "10.0.0.2")
+		collect(t, "https://example.com/pricing", "10.0.0.3")
+		require.NoError(t, testsupport.ProcessAllTestEvents(dbManager, logger))
+
+		assert.Equal(t, map[string]int{"example.com/pricing": 3}, pageStats(t))
+
+		var hostnames []string
+		require.NoError(t, db.Model(&events.Event{}).Distinct().Pluck("hostname", &hostnames).Error)
+		assert.Equal(t, []string{"example.com"}, hostnames)
+
+		var originalHostnames []string
+		require.NoError(t, db.Model(&events.Event{}).Order("original_hostname").Pluck("original_hostname", &originalHostnames).Error)
+		assert.Equal(t, []string{"", "a.example.com", "b.example.com"}, originalHostnames)
+	})
+
+	t.Run("without rollup each subdomain keeps its pages", func(t *testing.T) {
+		testsupport.CleanAllTables(db)
+		website = testsupport.CreateTestWebsite(db, "example.com")
+		require.NoError(t, settings.UpdateSubdomainTrackingSettings(db, "example.com", true))
+		require.NoError(t, settings.UpdateSubdomainRollupSettings(db, "example.com", false))
+
+		collect(t, "https://a.example.com/pricing", "10.0.0.1")
+		collect(t, "https://b.example.com/pricing", "10.0.0.2")
+		require.NoError(t, testsupport.ProcessAllTestEvents(dbManager, logger))
+
+		assert.Equal(t, map[string]int{"a.example.com/pricing": 1, "b.example.com/pricing": 1}, pageStats(t))
+	})
+
+	t.Run("rollup needs subdomain tracking", func(t *testing.T) {
+		require.NoError(t, settings.UpdateSubdomainTrackingSettings(db, "example.com", false))
+		require.NoError(t, settings.UpdateSubdomainRollupSettings(db, "example.com", true))
+		assert.False(t, settings.IsSubdomainRollupEnabled(db, "example.com"))
+
+		input := testsupport.CreateTestEventInput("10.0.0.4", "Mozilla/5.0 (rollup-agent)", events.EventTypePageView, ts, "https://c.example.com/", "", "", "")
+		var notFound *websites.WebsiteNotFoundError
+		assert.ErrorAs(t, events.CollectEvent(dbManager, logger, input), &notFound)
+	})
+}
+
+func TestProcessEventsDeviceModelStats(t *testing.T) {
+	dbManager, logger, website := testsupport.SetupTestDBManagerWithWebsite(t, "devices.com")
+	db := dbManager.GetConnection()
+
+	ts := time.Date(2024, 7, 1, 10, 0, 0, 0, time.UTC)
+	userAgents := map[string]string{
+		"iphone-1":  "Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.0 Mobile/15E148 Safari/604.1",
+		"iphone-2":  "Mozilla/5.0 (iPhone; CPU iPhone OS 17_1 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.1 Mobile/15E148 Safari/604.1",
+		"pixel":     "Mozilla/5.0 (Linux; Android 14; Pixel 8 Pro) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Mobile Safari/537.36",
+		"reduced":   "Mozilla/5.0 (Linux; Android 10; K) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Mobile Safari/537.36",
+		"desktop-1": "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36",
+	}
+	for visitor, userAgent := range userAgents {
+		require.NoError(t, db.Create(&events.IngestedEvent{
+			WebsiteID:     website.ID,
+			UserSignature: visitor,
+			Hostname:      website.Domain,
+			Pathname:      "/",
+			RawURL:        "https://" + website.Domain + "/",
+			EventType:     events.EventTypePageView,
+			Timestamp:     ts,
+			UserAgent:     userAgent,
+			Country:       "US",
+		}).Error)
+	}
+	require.NoError(t, testsupport.ProcessAllTestEvents(dbManager, logger))
+
+	timeFrame, err := timeframe.NewTimeFrame(timeframe.TimeFrameParams{
+		FromTime:      time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC),
+		ToTime:        time.Date(2024, 7, 1, 23, 59, 59, 0, time.UTC),
+		TimeFrameSize: timeframe.DailyTimeFrame,
+	}, time.UTC)
+	require.NoError(t, err)
+	params := analytics.NewWebsiteScopedQueryParams(timeFrame, int(website.ID))
+
+	results, err := analytics.GetTopDeviceModelsInTimeFrame(db, params)
+	require.NoError(t, err)
+
+	// Desktop traffic has no model and is left out of the breakdown
+	formatted := analytics.FormatDeviceModelStats(results)
+	require.Len(t, formatted, 3)
+	assert.Equal(t, analytics.MetricCountResult{Name: "Apple iPhone", Count: 2}, formatted[0])
+	assert.ElementsMatch(t, []analytics.MetricCountResult{
+		{Name: "Google Pixel 8 Pro", Count: 1},
+		{Name: "Unknown", Count: 1},
+	}, formatted[1:])
+}
+
+func TestProcessEventsBrowserVersionStats(t *testing.T) {
+	dbManager, logger, website := testsupport.SetupTestDBManagerWithWebsite(t, "browsers.com")
+	db := dbManager.GetConnection()
+
+	ts := time.Date(2024, 7, 1, 10, 0, 0, 0, time.UTC)
+	userAgents := map[string]string{
+		"chrome-120-a": "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.6099.109 Safari/537.36",
+		"chrome-120-b": "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36",
+		"chrome-119":   "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/119.0.6045.199 Safari/537.36",
+		"safari-17":    "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.2 Safari/605.1.15",
+	}
+	for visitor, userAgent := range userAgents {
+		require.NoError(t, db.Create(&events.IngestedEvent{
+			WebsiteID:     website.ID,
+			UserSignature: visitor,
+			Hostname:      website.Domain,
+			Pathname:      "/",
+			RawURL:        "https://" + website.Domain + "/",
+			EventType:     events.EventTypePageView,
+			Timestamp:     ts,
+			UserAgent:     userAgent,
+			Country:       "US",
+		}).Error)
+	}
+	require.NoError(t, testsupport.ProcessAllTestEvents(dbManager, logger))
+
+	timeFrame, err := timeframe.NewTimeFrame(timeframe.TimeFrameParams{
+		FromTime:      time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC),
+		ToTime:        time.Date(2024, 7, 1, 23, 59, 59, 0, time.UTC),
+		TimeFrameSize: timeframe.DailyTimeFrame,
+	}, time.UTC)
+	require.NoError(t, err)
+	params := analytics.NewWebsiteScopedQueryParams(timeFrame, int(website.ID))
+
+	versions, err := analytics.GetTopBrowserVersionsInTimeFrame(db, params)
+	require.NoError(t, err)
+	formatted := analytics.FormatBrowserStats(versions)
+	require.Len(t, formatted, 3)
+	assert.Equal(t, analytics.MetricCountResult{Name: "Chrome 120", Count: 2}, formatted[0])
+	assert.ElementsMatch(t, []analytics.MetricCountResult{
+		{Name: "Chrome 119", Count: 1},
+		{Name: "Safari 17", Count: 1},
+	}, formatted[1:])
+
+	// The family-level breakdown is unaffected
+	browsers, err := analytics.GetTopBrowsersInTimeFrame(db, params)
+	require.NoError(t, err)
+	assert.Equal(t, []analytics.MetricCountResult{
+		{Name: "chrome", Count: 3},
+		{Name: "safari", Count: 1},
+	}, browsers)
+}
+
+func TestNormalizeLanguage(t *testing.T) {
+	tests := []struct {
+		name     string
+		tag      string
+		fullTag  bool
+		expected string
+	}{
+		{name: "Region is dropped", tag: "en-US", expected: "en"},
+		{name: "Script is dropped", tag: "zh-Hant-TW", expected: "zh"},
+		{name: "Base language", tag: "fr", expected: "fr"},
+		{name: "Full tag kept", tag: "en-US", fullTag: true, expected: "en-US"},
+		{name: "Full tag canonicalized", tag: "pt-br", fullTag: true, expected: "pt-BR"},
+		{name: "Empty", tag: "", expected: events.UnknownLanguage},
+		{name: "Malformed", tag: "$$", expected: events.UnknownLanguage},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, events.NormalizeLanguage(tt.tag, tt.fullTag))
+		})
+	}
+}
+
+func TestCollectEventLanguageStats(t *testing.T) {
+	dbManager, logger := testsupport.SetupTestDBManager(t)
+	db := dbManager.GetConnection()
+	testsupport.CleanAllTables(db)
+	website := testsupport.CreateTestWebsite(db, "example.com")
+
+	ts := time.Date(2024, 7, 1, 10, 0, 0, 0, time.UTC)
+	acceptLanguages := []string{"en-US,en;q=0.9", "en-GB", "fr-FR,fr;q=0.9,en;q=0.8", ""}
+	for i, acceptLanguage := range acceptLanguages {
+		require.NoError(t, events.CollectEvent(dbManager, logger, &events.CollectEventInput{
+			IPAddress:      fmt.Sprintf("203.0.113.%d", i+1),
+			UserAgent:      "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36",
+			AcceptLanguage: acceptLanguage,
+			EventType:      events.EventTypePageView,
+			Timestamp:      ts,
+			RawUrl:         "https://example.com/",
+		}))
+	}
+	require.NoError(t, testsupport.ProcessAllTestEvents(dbManager, logger))
+
+	var languages []string
+	require.NoError(t, db.Model(&events.Event{}).Order("id").Pluck("language", &languages).Error)
+	assert.Equal(t, []string{"en-US", "en-GB", "fr-FR", ""}, languages, "the primary tag is stored on the event")
+
+	timeFrame, err := timeframe.NewTimeFrame(timeframe.TimeFrameParams{
+		FromTime:      time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC),
+		ToTime:        time.Date(2024, 7, 1, 23, 59, 59, 0, time.UTC),
+		TimeFrameSize: timeframe.DailyTimeFrame,
+	}, time.UTC)
+	require.NoError(t, err)
+	params := analytics.NewWebsiteScopedQueryParams(timeFrame, int(website.ID))
+
+	results, err := analytics.GetTopLanguagesInTimeFrame(db, params)
+	require.NoError(t, err)
+	formatted := analytics.FormatLanguageStats(results)
+	require.Len(t, formatted, 3)
+	assert.Equal(t, analytics.MetricCountResult{Name: "en", Count: 2}, formatted[0])
+	assert.ElementsMatch(t, []analytics.MetricCountResult{
+		{Name: "fr", Count: 1},
+		{Name: "Unknown", Count: 1},
+	}, formatted[1:])
+}
+
+func TestCollectEventScreenStats(t *testing.T) {
+	dbManager, logger := testsupport.SetupTestDBManager(t)
+	db := dbManager.GetConnection()
+	testsupport.CleanAllTables(db)
+	website := testsupport.CreateTestWebsite(db, "example.com")
+
+	ts := time.Date(2024, 7, 1, 10, 0, 0, 0, time.UTC)
+	viewports := [][2]int{{390, 844}, {412, 915}, {1920, 1080}, {0, 0}, {-5, 700}, {100000, 800}}
+	for i, viewport := range viewports {
+		require.NoError(t, events.CollectEvent(dbManager, logger, &events.CollectEventInput{
+			IPAddress:    fmt.Sprintf("203.0.113.%d", i+1),
+			UserAgent:    "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36",
+			EventType:    events.EventTypePageView,
+			Timestamp:    ts,
+			RawUrl:       "https://example.com/",
+			ScreenWidth:  viewport[0],
+			ScreenHeight: viewport[1],
+		}))
+	}
+	require.NoError(t, testsupport.ProcessAllTestEvents(dbManager, logger))
+
+	var widths []int
+	require.NoError(t, db.Model(&events.Event{}).Order("id").Pluck("screen_width", &widths).Error)
+	assert.Equal(t, []int{390, 412, 1920, 0, 0, 0}, widths, "missing and implausible widths are stored as 0")
+
+	timeFrame, err := timeframe.NewTimeFrame(timeframe.TimeFrameParams{
+		FromTime:      time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC),
+		ToTime:        time.Date(2024, 7, 1, 23, 59, 59, 0, time.UTC),
+		TimeFrameSize: timeframe.DailyTimeFrame,
+	}, time.UTC)
+	require.NoError(t, err)
+	params := analytics.NewWebsiteScopedQueryParams(timeFrame, int(website.ID))
+
+	results, err := analytics.GetTopScreenSizesInTimeFrame(db, params)
+	require.NoError(t, err)
+	assert.Equal(t, []analytics.MetricCountResult{
+		{Name: "Mobile (<768px)", Count: 2},
+		{Name: "Desktop (1440px+)", Count: 1},
+	}, analytics.FormatScreenSizeStats(results), "events without a viewport get no bucket")
+}
+
+func TestCollectEventWithSDKUserID(t *testing.T) {
+	dbManager, logger := testsupport.SetupTestDBManager(t)
+	db := dbManager.GetConnection()
+	testsupport.CleanAllTables(db)
+	require.NoError(t, settings.SetupDefaultSettings(db))
+	testsupport.CreateTestWebsite(db, "example.com")
+
+	collectFrom := func(t *testing.T, ip, userAgent, userID string) string {
+		t.Helper()
+		db.Exec("DELETE FROM ingested_events")
+
+		require.NoError(t, events.CollectEvent(dbManager, logger, &events.CollectEventInput{
+			IPAddress: ip,
+			UserAgent: userAgent,
+			EventType: events.EventTypePageView,
+			Timestamp: time.Now().UTC(),
+			RawUrl:    "https://example.com/account",
+			UserID:    userID,
+		}))
+
+		var saved events.IngestedEvent
+		require.NoError(t, db.First(&saved).Error)
+		return saved.UserSignature
+	}
+
+	t.Run("ignores the user ID by default", func(t *testing.T) {
+		laptop := collectFrom(t, "203.0.113.1", "Mozilla/5.0 (Macintosh)", "account-42")
+		phone := collectFrom(t, "198.51.100.7", "Mozilla/5.0 (iPhone)", "account-42")
+
+		assert.NotEqual(t, laptop, phone)
+	})
+
+	t.Run("same user ID from different IPs shares a signature when enabled", func(t *testing.T) {
+		require.NoError(t, settings.SaveSDKUserIDEnabled(db, true))
+		t.Cleanup(func() { settings.SaveSDKUserIDEnabled(db, false) })
+
+		laptop := collectFrom(t, "203.0.113.1", "Mozilla/5.0 (Macintosh)", "account-42")
+		phone := collectFrom(t, "198.51.100.7", "Mozilla/5.0 (iPhone)", "account-42")
+		other := collectFrom(t, "198.51.100.7", "Mozilla/5.0 (iPhone)", "account-99")
+
+		assert.Equal(t, laptop, phone)
+		assert.NotEqual(t, laptop, other)
+		assert.NotContains(t, laptop, "account-42")
+	})
+
+	t.Run("falls back to the computed signature without a user ID", func(t *testing.T) {
+		require.NoError(t, settings.SaveSDKUserIDEnabled(db, true))
+		t.Cleanup(func() { settings.SaveSDKUserIDEnabled(db, false) })
+
+		expected := visitors.BuildUniqueVisitorId("example.com", "203.0.113.1", "Mozilla/5.0 (Macintosh)", config.GetConfig().PrivateKey)
+		assert.Equal(t, expected, collectFrom(t, "203.0.113.1", "Mozilla/5.0 (Macintosh)", ""))
+	})
+}
+
+func TestCollectEventPausedWebsite(t *testing.T) {
+	dbManager, logger := testsupport.SetupTestDBManager(t)
+	db := dbManager.GetConnection()
+	testsupport.CleanAllTables(db)
+	paused := testsupport.CreateTestWebsite(db, "paused.com")
+	active := testsupport.CreateTestWebsite(db, "active.com")
+	require.NoError(t, settings.SetupDefaultSettings(db))
+	require.NoError(t, websites.SetPaused(db, paused.ID, true))
+
+	collect := func(t *testing.T, domain string) {
+		t.Helper()
+		require.NoError(t, events.CollectEvent(dbManager, logger, testsupport.CreateTestEventInput(
+			"203.0.113.1", "Mozilla/5.0 (test)", events.EventTypePageView, time.Now().UTC(),
+			"https://"+domain+"/pricing", "", "", "",
+		)), "paused websites drop events without an error")
+	}
+	countFor := func(t *testing.T, websiteID uint) int64 {
+		t.Helper()
+		var count int64
+		require.NoError(t, db.Model(&events.IngestedEvent{}).Where("website_id = ?", websiteID).Count(&count).Error)
+		return count
+	}
+
+	collect(t, "paused.com")
+	collect(t, "active.com")
+	assert.Zero(t, countFor(t, paused.ID))
+	assert.Equal(t, int64(1), countFor(t, active.ID))
+
+	t.Run("records again once resumed", func(t *testing.T) {
+		require.NoError(t, websites.SetPaused(db, paused.ID, false))
+		collect(t, "paused.com")
+		assert.Equal(t, int64(1), countFor(t, paused.ID))
+	})
+
+	t.Run("unknown website", func(t *testing.T) {
+		assert.ErrorIs(t, websites.SetPaused(db, 9999, true), gorm.ErrRecordNotFound)
+	})
+}
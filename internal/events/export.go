@@ -0,0 +1,50 @@
+package events
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// exportPageSize bounds how many events are loaded per query while exporting.
+const exportPageSize = 1000
+
+// WriteEventsNDJSON streams a website's events with a timestamp between from
+// and to as newline-delimited JSON, oldest first by ID. Pages are read with an
+// ID cursor rather than OFFSET so large exports stay fast, and w is flushed
+// after each page when it supports it.
+func WriteEventsNDJSON(w io.Writer, db *gorm.DB, websiteID uint, from, to time.Time) error {
+	encoder := json.NewEncoder(w)
+	flusher, canFlush := w.(interface{ Flush() error })
+
+	var cursor uint
+	for {
+		var page []Event
+		err := db.Where("website_id = ? AND timestamp BETWEEN ? AND ? AND id > ?", websiteID, from.UTC(), to.UTC(), cursor).
+			Order("id").
+			Limit(exportPageSize).
+			Find(&page).Error
+		if err != nil {
+			return fmt.Errorf("failed to read events: %w", err)
+		}
+
+		for _, event := range page {
+			if err := encoder.Encode(event); err != nil {
+				return fmt.Errorf("failed to write event %d: %w", event.ID, err)
+			}
+		}
+		if canFlush {
+			if err := flusher.Flush(); err != nil {
+				return fmt.Errorf("failed to flush events: %w", err)
+			}
+		}
+
+		if len(page) < exportPageSize {
+			return nil
+		}
+		cursor = page[len(page)-1].ID
+	}
+}
@@ -0,0 +1,126 @@
+package events
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/karloscodes/cartridge/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"fusionaly/internal/metrics"
+)
+
+// MaxProcessingAttempts is how many processing runs an ingested event may fail
+// before it's moved to failed_events.
+const MaxProcessingAttempts = 5
+
+// FailedEvent is an ingested event set aside after failing processing
+// MaxProcessingAttempts times, so one poison record can't stall the pipeline.
+// It keeps the original event so it can be requeued once the cause is fixed.
+type FailedEvent struct {
+	ID              uint `gorm:"primaryKey"`
+	IngestedEventID uint // ID the event had in ingested_events
+	WebsiteID       uint `gorm:"index"`
+	EventType       EventType
+	Timestamp       time.Time
+	Payload         string // The IngestedEvent as JSON
+	Error           string // Error of the last attempt
+	Attempts        int
+	FailedAt        time.Time `gorm:"index"`
+}
+
+// recordProcessingFailure counts a failed processing attempt of an ingested
+// event, moving it to failed_events once it reaches MaxProcessingAttempts.
+func recordProcessingFailure(db *gorm.DB, logger *slog.Logger, tempEvent IngestedEvent, cause error) error {
+	attempts := tempEvent.ProcessingAttempts + 1
+	if attempts < MaxProcessingAttempts {
+		logger.Warn("Failed to process event, it will be retried",
+			slog.Uint64("ingested_event_id", uint64(tempEvent.ID)), slog.Int("attempts", attempts), slog.Any("error", cause))
+		return db.Model(&IngestedEvent{}).Where("id = ?", tempEvent.ID).Update("processing_attempts", attempts).Error
+	}
+
+	tempEvent.ProcessingAttempts = attempts
+	payload, err := json.Marshal(tempEvent)
+	if err != nil {
+		return fmt.Errorf("failed to encode failed event: %w", err)
+	}
+	failed := &FailedEvent{
+		IngestedEventID: tempEvent.ID,
+		WebsiteID:       tempEvent.WebsiteID,
+		EventType:       tempEvent.EventType,
+		Timestamp:       tempEvent.Timestamp,
+		Payload:         string(payload),
+		Error:           cause.Error(),
+		Attempts:        attempts,
+		FailedAt:        time.Now().UTC(),
+	}
+
+	err = sqlite.PerformWrite(logger, db, func(tx *gorm.DB) error {
+		if err := tx.Create(failed).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&IngestedEvent{}, tempEvent.ID).Error
+	})
+	if err != nil {
+		return fmt.Errorf("failed to move event to failed_events: %w", err)
+	}
+
+	metrics.EventsFailedTotal.Inc()
+	logger.Error("Moved event to failed_events after repeated processing failures",
+		slog.Uint64("ingested_event_id", uint64(tempEvent.ID)), slog.Int("attempts", attempts), slog.Any("error", cause))
+	return nil
+}
+
+// ListFailedEvents returns the most recently failed events, newest first
+func ListFailedEvents(db *gorm.DB, limit int) ([]FailedEvent, error) {
+	var failed []FailedEvent
+	if err := db.Order("failed_at DESC, id DESC").Limit(limit).Find(&failed).Error; err != nil {
+		return nil, fmt.Errorf("failed to list failed events: %w", err)
+	}
+	return failed, nil
+}
+
+// RequeueFailedEvents moves failed events back to ingested_events with their
+// attempts reset, so the next processing run picks them up. It requeues the
+// events with the given IDs, or every failed event when ids is empty, and
+// returns how many were requeued.
+func RequeueFailedEvents(db *gorm.DB, ids []uint) (int, error) {
+	requeued := 0
+	err := db.Transaction(func(tx *gorm.DB) error {
+		query := tx.Order("id")
+		if len(ids) > 0 {
+			query = query.Where("id IN ?", ids)
+		}
+		var failed []FailedEvent
+		if err := query.Find(&failed).Error; err != nil {
+			return err
+		}
+
+		for _, failedEvent := range failed {
+			var tempEvent IngestedEvent
+			if err := json.Unmarshal([]byte(failedEvent.Payload), &tempEvent); err != nil {
+				return fmt.Errorf("failed to decode failed event %d: %w", failedEvent.ID, err)
+			}
+			tempEvent.ID = 0
+			tempEvent.Processed = 0
+			tempEvent.ProcessingAttempts = 0
+
+			// A resubmission may have claimed the event ID since; keep that one
+			if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&tempEvent).Error; err != nil {
+				return fmt.Errorf("failed to requeue failed event %d: %w", failedEvent.ID, err)
+			}
+			if err := tx.Delete(&FailedEvent{}, failedEvent.ID).Error; err != nil {
+				return err
+			}
+			requeued++
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return requeued, nil
+}
@@ -0,0 +1,82 @@
+package events_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"fusionaly/internal/events"
+	"fusionaly/internal/settings"
+	"fusionaly/internal/testsupport"
+)
+
+func TestProcessingFailuresMoveToFailedEvents(t *testing.T) {
+	dbManager, logger := testsupport.SetupTestDBManager(t)
+	db := dbManager.GetConnection()
+	testsupport.CleanAllTables(db)
+	testsupport.CreateTestWebsite(db, "example.com")
+	require.NoError(t, settings.SetupDefaultSettings(db))
+
+	for _, path := range []string{"/ok", "/poison"} {
+		require.NoError(t, events.CollectEvent(dbManager, logger, testsupport.CreateTestEventInput(
+			"203.0.113.1", "Mozilla/5.0 (test)", events.EventTypePageView, time.Now().UTC(),
+			"https://example.com"+path, "", "", "",
+		)))
+	}
+
+	// Simulate a poison record: storing this one event always fails
+	require.NoError(t, db.Exec(`CREATE TRIGGER reject_poison BEFORE INSERT ON events
+		WHEN NEW.pathname = '/poison' BEGIN SELECT RAISE(ABORT, 'poison event'); END`).Error)
+	t.Cleanup(func() { db.Exec("DROP TRIGGER IF EXISTS reject_poison") })
+
+	processedPaths := func(t *testing.T) []string {
+		t.Helper()
+		var paths []string
+		require.NoError(t, db.Model(&events.Event{}).Order("pathname").Pluck("pathname", &paths).Error)
+		return paths
+	}
+
+	result, err := events.ProcessUnprocessedEvents(dbManager, logger, 10)
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.Failed)
+	assert.Equal(t, []string{"/ok"}, processedPaths(t), "the poison event doesn't hold back its batch")
+
+	var poison events.IngestedEvent
+	require.NoError(t, db.Where("pathname = ?", "/poison").First(&poison).Error)
+	assert.Equal(t, 0, poison.Processed)
+	assert.Equal(t, 1, poison.ProcessingAttempts)
+
+	for range events.MaxProcessingAttempts - 1 {
+		_, err := events.ProcessUnprocessedEvents(dbManager, logger, 10)
+		require.NoError(t, err)
+	}
+
+	var remaining int64
+	require.NoError(t, db.Model(&events.IngestedEvent{}).Where("processed = 0").Count(&remaining).Error)
+	assert.Zero(t, remaining, "the event left the queue after the retry cap")
+
+	failed, err := events.ListFailedEvents(db, 10)
+	require.NoError(t, err)
+	require.Len(t, failed, 1)
+	assert.Equal(t, poison.ID, failed[0].IngestedEventID)
+	assert.Equal(t, events.MaxProcessingAttempts, failed[0].Attempts)
+	assert.Contains(t, failed[0].Error, "poison event")
+
+	t.Run("requeued events are processed again", func(t *testing.T) {
+		require.NoError(t, db.Exec("DROP TRIGGER reject_poison").Error)
+
+		requeued, err := events.RequeueFailedEvents(db, nil)
+		require.NoError(t, err)
+		assert.Equal(t, 1, requeued)
+
+		_, err = events.ProcessUnprocessedEvents(dbManager, logger, 10)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"/ok", "/poison"}, processedPaths(t))
+
+		failed, err := events.ListFailedEvents(db, 10)
+		require.NoError(t, err)
+		assert.Empty(t, failed)
+	})
+}
@@ -7,6 +7,8 @@ import (
 
 	"log/slog"
 
+	"golang.org/x/text/language"
+
 	"fusionaly/internal/pkg/geoip"
 	ua "fusionaly/internal/pkg/user_agent"
 )
@@ -26,6 +28,15 @@ func getDeviceTypeFromParsedUA(ua ua.UserAgent) string {
 	return UnknownDevice
 }
 
+// getDeviceModelFromParsedUA extracts the brand and model of mobile and tablet
+// devices. Other device types return "" since they carry no model to break down.
+func getDeviceModelFromParsedUA(ua ua.UserAgent) string {
+	if ua.Bot || (!ua.Mobile && !ua.Tablet) {
+		return ""
+	}
+	return NormalizeDeviceModel(ua.Device, ua.Model)
+}
+
 // getBrowserFromParsedUA extracts and normalizes browser name from parsed user agent.
 // If secChUa is present (Sec-CH-UA header from Chromium browsers), it's used to
 // distinguish browsers that share identical User-Agent strings (Chrome, Brave, Edge, etc.).
@@ -122,6 +133,19 @@ func parseBrowserFromClientHints(secChUa string) string {
 	return ""
 }
 
+// getBrowserVersionFromParsedUA extracts the browser's major version (e.g. "120"
+// from "120.0.6099.109"), or "" when the user agent doesn't carry one
+func getBrowserVersionFromParsedUA(ua ua.UserAgent) string {
+	if ua.Bot {
+		return ""
+	}
+	major, _, _ := strings.Cut(strings.TrimSpace(ua.BrowserVersion), ".")
+	if major == "" || strings.Trim(major, "0123456789") != "" {
+		return ""
+	}
+	return major
+}
+
 // NormalizeOperatingSystem normalizes operating system names to standardize them
 func NormalizeOperatingSystem(os string) string {
 	if os == "" {
@@ -169,6 +193,100 @@ func NormalizeOperatingSystem(os string) string {
 	return os
 }
 
+// NormalizeDeviceModel combines a device brand and model into a single label
+// such as "Apple iPhone" or "Samsung Galaxy S23"
+func NormalizeDeviceModel(brand, model string) string {
+	brand = strings.TrimSpace(brand)
+	model = strings.TrimSpace(model)
+
+	// The parser falls back to generic placeholders when it can't identify the device
+	switch strings.ToLower(brand) {
+	case "", "unknown", "bot", "mobile", "tablet", "desktop":
+		return UnknownDeviceModel
+	}
+
+	if model == "" || strings.EqualFold(model, brand) {
+		return brand
+	}
+
+	// Some models already carry the brand (e.g. "Google Pixel")
+	if strings.HasPrefix(strings.ToLower(model), strings.ToLower(brand)+" ") {
+		return model
+	}
+
+	return brand + " " + model
+}
+
+// primaryLanguageTag returns the highest-weighted tag of an Accept-Language
+// header without extensions (e.g. "en-US"), or "" when none is usable
+func primaryLanguageTag(acceptLanguage string) string {
+	tags, _, err := language.ParseAcceptLanguage(acceptLanguage)
+	if err != nil || len(tags) == 0 {
+		return ""
+	}
+
+	base, script, region := tags[0].Raw()
+	tag, err := language.Compose(base, script, region)
+	// "*" parses as "mul" (multiple languages), which says nothing about the visitor
+	if err != nil || tag == language.Und || base.String() == "mul" {
+		return ""
+	}
+	return tag.String()
+}
+
+// NormalizeLanguage reduces a language tag to its base language subtag
+// (en-US -> en), or keeps the full tag when fullTag is set
+func NormalizeLanguage(tag string, fullTag bool) string {
+	if strings.TrimSpace(tag) == "" {
+		return UnknownLanguage
+	}
+	parsed, err := language.Parse(tag)
+	if err != nil || parsed == language.Und {
+		return UnknownLanguage
+	}
+	if fullTag {
+		return parsed.String()
+	}
+	base, _ := parsed.Base()
+	return base.String()
+}
+
+// maxScreenDimension bounds reported viewport sizes; anything larger is bogus
+const maxScreenDimension = 16384
+
+// screenDimension drops missing or implausible viewport dimensions
+func screenDimension(pixels int) int {
+	if pixels <= 0 || pixels > maxScreenDimension {
+		return 0
+	}
+	return pixels
+}
+
+// pageStatusCode drops status codes outside the valid HTTP range
+func pageStatusCode(code int) int {
+	if code < 100 || code > 599 {
+		return 0
+	}
+	return code
+}
+
+// ScreenSizeBucket assigns a viewport width to a responsive breakpoint bucket,
+// returning "" when the width is unknown so no bucket is recorded
+func ScreenSizeBucket(width int) string {
+	switch {
+	case width <= 0:
+		return ""
+	case width < 768:
+		return ScreenSizeMobile
+	case width < 1024:
+		return ScreenSizeTablet
+	case width < 1440:
+		return ScreenSizeLaptop
+	default:
+		return ScreenSizeDesktop
+	}
+}
+
 // getOSFromParsedUA extracts and normalizes OS from parsed user agent
 func getOSFromParsedUA(ua ua.UserAgent) string {
 	if ua.OS != "" {
@@ -224,6 +342,35 @@ func GetCountryFromIP(ipAddress string) string {
 	return strings.ToLower(record.Country.IsoCode)
 }
 
+// GetRegionAndCityFromIP resolves an IP address to its English region and city
+// names. Both are empty when no geo database is configured or it only holds
+// country data, which keeps events country-only.
+func GetRegionAndCityFromIP(ipAddress string) (string, string) {
+	geoDB := geoip.GetGeoDB()
+	if geoDB == nil {
+		return "", ""
+	}
+
+	ip := net.ParseIP(ipAddress)
+	if ip == nil {
+		return "", ""
+	}
+
+	record, err := geoDB.City(ip)
+	if err != nil {
+		slog.Default().Debug("City lookup unavailable for IP",
+			slog.String("ip_address", ipAddress),
+			slog.Any("error", err))
+		return "", ""
+	}
+
+	region := ""
+	if len(record.Subdivisions) > 0 {
+		region = record.Subdivisions[0].Names["en"]
+	}
+	return region, record.City.Names["en"]
+}
+
 // ExtractCustomEventKey extracts a key from custom event metadata JSON
 func ExtractCustomEventKey(metadata string) string {
 	if metadata == "" {
@@ -1,9 +1,12 @@
 package events
 
 import (
+	"fmt"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+
+	ua "fusionaly/internal/pkg/user_agent"
 )
 
 func TestParseBrowserFromClientHints(t *testing.T) {
@@ -59,3 +62,116 @@ func TestParseBrowserFromClientHints(t *testing.T) {
 		assert.Equal(t, "somebrowser", parseBrowserFromClientHints(header))
 	})
 }
+
+func TestBrowserFamilyAndVersionFromParsedUA(t *testing.T) {
+	tests := []struct {
+		name            string
+		userAgent       string
+		expectedBrowser string
+		expectedVersion string
+	}{
+		{
+			name:            "Chrome on Windows",
+			userAgent:       "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.6099.109 Safari/537.36",
+			expectedBrowser: "chrome",
+			expectedVersion: "120",
+		},
+		{
+			name:            "Safari on macOS",
+			userAgent:       "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.2 Safari/605.1.15",
+			expectedBrowser: "safari",
+			expectedVersion: "17",
+		},
+		{
+			name:            "Mobile Safari on iPhone",
+			userAgent:       "Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.0 Mobile/15E148 Safari/604.1",
+			expectedBrowser: "safari",
+			expectedVersion: "17",
+		},
+		{
+			name:            "Firefox on Windows",
+			userAgent:       "Mozilla/5.0 (Windows NT 10.0; Win64; x64; rv:121.0) Gecko/20100101 Firefox/121.0",
+			expectedBrowser: "firefox",
+			expectedVersion: "121",
+		},
+		{
+			name:            "Chrome Mobile on Android",
+			userAgent:       "Mozilla/5.0 (Linux; Android 14; Pixel 8 Pro) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Mobile Safari/537.36",
+			expectedBrowser: "chrome",
+			expectedVersion: "120",
+		},
+		{
+			name:            "Edge on Windows",
+			userAgent:       "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36 Edg/120.0.2210.91",
+			expectedBrowser: "microsoft edge",
+			expectedVersion: "120",
+		},
+		{
+			name:            "Internet Explorer without version",
+			userAgent:       "Mozilla/5.0 (compatible; MSIE 10.0; Windows NT 6.2; Trident/6.0)",
+			expectedBrowser: "ie",
+			expectedVersion: "",
+		},
+		{
+			name:            "Unrecognized user agent",
+			userAgent:       "",
+			expectedBrowser: "unknown",
+			expectedVersion: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parsed := ua.ParseUserAgent(tt.userAgent)
+			assert.Equal(t, tt.expectedBrowser, getBrowserFromParsedUA(parsed, ""))
+			assert.Equal(t, tt.expectedVersion, getBrowserVersionFromParsedUA(parsed))
+		})
+	}
+}
+
+func TestPrimaryLanguageTag(t *testing.T) {
+	tests := []struct {
+		name     string
+		header   string
+		expected string
+	}{
+		{name: "Weighted list", header: "en-US,en;q=0.9,fr;q=0.8", expected: "en-US"},
+		{name: "Highest weight wins over order", header: "fr;q=0.5, de-DE", expected: "de-DE"},
+		{name: "Lowercase region", header: "pt-br", expected: "pt-BR"},
+		{name: "Script subtag", header: "zh-Hant-TW,zh;q=0.9", expected: "zh-Hant-TW"},
+		{name: "Extensions are dropped", header: "de-DE-u-co-phonebk", expected: "de-DE"},
+		{name: "Wildcard", header: "*", expected: ""},
+		{name: "Malformed", header: "not a language!", expected: ""},
+		{name: "Empty", header: "", expected: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, primaryLanguageTag(tt.header))
+		})
+	}
+}
+
+func TestScreenSizeBucket(t *testing.T) {
+	tests := []struct {
+		width    int
+		expected string
+	}{
+		{width: 0, expected: ""},
+		{width: -1, expected: ""},
+		{width: 375, expected: ScreenSizeMobile},
+		{width: 767, expected: ScreenSizeMobile},
+		{width: 768, expected: ScreenSizeTablet},
+		{width: 1023, expected: ScreenSizeTablet},
+		{width: 1024, expected: ScreenSizeLaptop},
+		{width: 1439, expected: ScreenSizeLaptop},
+		{width: 1440, expected: ScreenSizeDesktop},
+		{width: 2560, expected: ScreenSizeDesktop},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%dpx", tt.width), func(t *testing.T) {
+			assert.Equal(t, tt.expected, ScreenSizeBucket(tt.width))
+		})
+	}
+}
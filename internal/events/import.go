@@ -0,0 +1,292 @@
+package events
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/karloscodes/cartridge"
+)
+
+// ImportFormat identifies the layout of an external events file.
+type ImportFormat string
+
+const (
+	// ImportFormatPlausible is a CSV of Plausible's raw events table
+	// (timestamp, name, pathname, referrer, user_id, ...)
+	ImportFormatPlausible ImportFormat = "plausible"
+	// ImportFormatUmami is a CSV of Umami's website_event table
+	// (created_at, url_path, url_query, referrer_domain, event_type, session_id, ...)
+	ImportFormatUmami ImportFormat = "umami"
+	// ImportFormatNDJSON is one Event per line, as written by WriteEventsNDJSON
+	ImportFormatNDJSON ImportFormat = "ndjson"
+)
+
+// importBatchSize bounds how many imported events are stored per write.
+const importBatchSize = 500
+
+// ImportResult summarises an import run.
+type ImportResult struct {
+	Rows         int // data rows read from the file
+	Skipped      int // rows without a usable timestamp or event name
+	PageViews    int
+	CustomEvents int
+	Stored       int // events written to the database; 0 for a dry run
+	From         time.Time
+	To           time.Time
+}
+
+// ImportEvents maps an external export onto CollectEventInputs for domain and
+// feeds them, oldest first, through the normal collection and processing
+// pipeline so backdated events land in their historical hourly buckets. With
+// dryRun set the file is only parsed and counted.
+func ImportEvents(dbManager cartridge.DBManager, logger *slog.Logger, r io.Reader, format ImportFormat, domain string, dryRun bool) (*ImportResult, error) {
+	inputs, result, err := ParseImportFile(r, format, domain)
+	if err != nil {
+		return nil, err
+	}
+	if dryRun || len(inputs) == 0 {
+		return result, nil
+	}
+
+	for start := 0; start < len(inputs); start += importBatchSize {
+		end := min(start+importBatchSize, len(inputs))
+		for i, err := range CollectEvents(dbManager, logger, inputs[start:end]) {
+			if err != nil {
+				return result, fmt.Errorf("failed to store event at %s: %w", inputs[start+i].Timestamp.Format(time.RFC3339), err)
+			}
+		}
+		result.Stored += end - start
+	}
+
+	if _, err := ProcessUnprocessedEvents(dbManager, logger, importBatchSize); err != nil {
+		return result, fmt.Errorf("failed to process imported events: %w", err)
+	}
+
+	return result, nil
+}
+
+// ParseImportFile reads every row of an external export and maps it to a
+// CollectEventInput for domain, sorted by timestamp. Rows that can't be mapped
+// are counted as skipped rather than failing the import.
+func ParseImportFile(r io.Reader, format ImportFormat, domain string) ([]*CollectEventInput, *ImportResult, error) {
+	var rows []importRow
+	var err error
+	switch format {
+	case ImportFormatPlausible:
+		rows, err = readImportCSV(r, plausibleRow)
+	case ImportFormatUmami:
+		rows, err = readImportCSV(r, umamiRow)
+	case ImportFormatNDJSON:
+		rows, err = readImportNDJSON(r)
+	default:
+		return nil, nil, fmt.Errorf("unsupported import format %q", format)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	result := &ImportResult{Rows: len(rows)}
+	inputs := make([]*CollectEventInput, 0, len(rows))
+	for _, row := range rows {
+		input, ok := row.toInput(domain)
+		if !ok {
+			result.Skipped++
+			continue
+		}
+		if input.EventType == EventTypePageView {
+			result.PageViews++
+		} else {
+			result.CustomEvents++
+		}
+		inputs = append(inputs, input)
+	}
+
+	sort.SliceStable(inputs, func(i, j int) bool { return inputs[i].Timestamp.Before(inputs[j].Timestamp) })
+	if len(inputs) > 0 {
+		result.From = inputs[0].Timestamp
+		result.To = inputs[len(inputs)-1].Timestamp
+	}
+
+	return inputs, result, nil
+}
+
+// importRow is the format-independent shape of one external event.
+type importRow struct {
+	Timestamp string
+	Pathname  string
+	Query     string
+	Referrer  string    // host with an optional path, with or without a scheme
+	EventType EventType // 0 derives the type from EventName
+	EventName string    // empty or "pageview" for page views
+	VisitorID string
+	Meta      string
+}
+
+func (row importRow) toInput(domain string) (*CollectEventInput, bool) {
+	timestamp, ok := parseImportTimestamp(row.Timestamp)
+	if !ok {
+		return nil, false
+	}
+
+	pathname := row.Pathname
+	if !strings.HasPrefix(pathname, "/") {
+		pathname = "/" + pathname
+	}
+	rawURL := "https://" + domain + pathname
+	if query := strings.TrimPrefix(row.Query, "?"); query != "" {
+		rawURL += "?" + query
+	}
+
+	referrer := strings.TrimSpace(row.Referrer)
+	if referrer != "" && !strings.Contains(referrer, "://") {
+		referrer = "https://" + referrer
+	}
+
+	eventType, name := row.EventType, strings.TrimSpace(row.EventName)
+	if eventType == 0 {
+		eventType = EventTypePageView
+		if name != "" && !strings.EqualFold(name, "pageview") {
+			eventType = EventTypeCustomEvent
+		}
+	}
+	if eventType == EventTypePageView {
+		name = ""
+	} else if name == "" {
+		return nil, false
+	}
+
+	return &CollectEventInput{
+		RawUrl:          rawURL,
+		ReferrerURL:     referrer,
+		EventType:       eventType,
+		CustomEventName: name,
+		CustomEventMeta: row.Meta,
+		Timestamp:       timestamp,
+		VisitorID:       row.VisitorID,
+	}, true
+}
+
+// importTimestampLayouts are tried in order; zone-less values are read as UTC
+var importTimestampLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02T15:04:05.999999999",
+}
+
+func parseImportTimestamp(value string) (time.Time, bool) {
+	value = strings.TrimSpace(value)
+	for _, layout := range importTimestampLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t.UTC(), true
+		}
+	}
+	return time.Time{}, false
+}
+
+// readImportCSV reads a CSV with a header row, mapping each record by column name.
+func readImportCSV(r io.Reader, mapRow func(get func(string) string) importRow) ([]importRow, error) {
+	reader := csv.NewReader(bufio.NewReader(r))
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CSV header: %w", err)
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))] = i
+	}
+
+	var rows []importRow
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return rows, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CSV row %d: %w", len(rows)+2, err)
+		}
+		get := func(column string) string {
+			if i, ok := columns[column]; ok && i < len(record) {
+				return record[i]
+			}
+			return ""
+		}
+		rows = append(rows, mapRow(get))
+	}
+}
+
+func plausibleRow(get func(string) string) importRow {
+	return importRow{
+		Timestamp: get("timestamp"),
+		Pathname:  get("pathname"),
+		Referrer:  get("referrer"),
+		EventName: get("name"),
+		VisitorID: get("user_id"),
+	}
+}
+
+func umamiRow(get func(string) string) importRow {
+	row := importRow{
+		Timestamp: get("created_at"),
+		Pathname:  get("url_path"),
+		Query:     get("url_query"),
+		VisitorID: get("session_id"),
+	}
+	if domain := get("referrer_domain"); domain != "" {
+		row.Referrer = domain + get("referrer_path")
+	}
+	// event_type 2 is a custom event; everything else is a page view
+	if eventType, err := strconv.Atoi(get("event_type")); err == nil && eventType == 2 {
+		row.EventType = EventTypeCustomEvent
+		row.EventName = get("event_name")
+	} else {
+		row.EventType = EventTypePageView
+	}
+	return row
+}
+
+// readImportNDJSON reads events exported by WriteEventsNDJSON.
+func readImportNDJSON(r io.Reader) ([]importRow, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	var rows []importRow
+	for line := 1; scanner.Scan(); line++ {
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+		var event Event
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			return nil, fmt.Errorf("failed to parse NDJSON line %d: %w", line, err)
+		}
+
+		row := importRow{
+			Pathname:  event.Pathname,
+			EventType: event.EventType,
+			EventName: event.CustomEventName,
+			VisitorID: event.UserSignature,
+			Meta:      event.CustomEventMeta,
+		}
+		if !event.Timestamp.IsZero() {
+			row.Timestamp = event.Timestamp.Format(time.RFC3339Nano)
+		}
+		if event.ReferrerHostname != "" && event.ReferrerHostname != DirectOrUnknownReferrer && event.ReferrerHostname != SelfReferral {
+			row.Referrer = event.ReferrerHostname + event.ReferrerPathname
+		}
+		rows = append(rows, row)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read NDJSON: %w", err)
+	}
+	return rows, nil
+}
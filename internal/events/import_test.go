@@ -0,0 +1,88 @@
+package events_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"fusionaly/internal/events"
+	"fusionaly/internal/testsupport"
+)
+
+const umamiExport = "\ufeffevent_id,website_id,session_id,created_at,url_path,url_query,referrer_path,referrer_query,referrer_domain,page_title,event_type,event_name\n" +
+	"e3,w1,session-b,2024-03-05 14:20:00,/pricing,plan=pro,,,,Pricing,1,\n" +
+	"e1,w1,session-a,2024-03-05 09:10:00,/,,/search,,www.google.com,Home,1,\n" +
+	"e2,w1,session-a,2024-03-05 09:12:30,/signup,,,,,Sign up,2,signup\n" +
+	"e4,w1,session-b,not a date,/,,,,,Home,1,\n" +
+	"e5,w1,session-b,2024-03-05 14:21:00,/,,,,,Home,2,\n"
+
+func TestParseImportFileUmami(t *testing.T) {
+	inputs, result, err := events.ParseImportFile(strings.NewReader(umamiExport), events.ImportFormatUmami, "import.com")
+	require.NoError(t, err)
+
+	assert.Equal(t, 5, result.Rows)
+	assert.Equal(t, 2, result.Skipped, "a bad timestamp and an unnamed custom event")
+	assert.Equal(t, 2, result.PageViews)
+	assert.Equal(t, 1, result.CustomEvents)
+	assert.Equal(t, time.Date(2024, 3, 5, 9, 10, 0, 0, time.UTC), result.From)
+	assert.Equal(t, time.Date(2024, 3, 5, 14, 20, 0, 0, time.UTC), result.To)
+
+	require.Len(t, inputs, 3)
+
+	assert.Equal(t, "https://import.com/", inputs[0].RawUrl, "sorted oldest first")
+	assert.Equal(t, "https://www.google.com/search", inputs[0].ReferrerURL)
+	assert.Equal(t, events.EventTypePageView, inputs[0].EventType)
+	assert.Equal(t, "session-a", inputs[0].VisitorID)
+
+	assert.Equal(t, events.EventTypeCustomEvent, inputs[1].EventType)
+	assert.Equal(t, "signup", inputs[1].CustomEventName)
+	assert.Equal(t, "https://import.com/signup", inputs[1].RawUrl)
+
+	assert.Equal(t, "https://import.com/pricing?plan=pro", inputs[2].RawUrl)
+	assert.Empty(t, inputs[2].ReferrerURL)
+	assert.Equal(t, "session-b", inputs[2].VisitorID)
+
+	_, _, err = events.ParseImportFile(strings.NewReader(umamiExport), events.ImportFormat("matomo"), "import.com")
+	assert.Error(t, err)
+}
+
+func TestImportEvents(t *testing.T) {
+	dbManager, logger, website := testsupport.SetupTestDBManagerWithWebsite(t, "import.com")
+	db := dbManager.GetConnection()
+
+	t.Run("dry run stores nothing", func(t *testing.T) {
+		result, err := events.ImportEvents(dbManager, logger, strings.NewReader(umamiExport), events.ImportFormatUmami, website.Domain, true)
+		require.NoError(t, err)
+		assert.Equal(t, 3, result.PageViews+result.CustomEvents)
+		assert.Zero(t, result.Stored)
+
+		var count int64
+		require.NoError(t, db.Model(&events.IngestedEvent{}).Where("website_id = ?", website.ID).Count(&count).Error)
+		assert.Zero(t, count)
+	})
+
+	t.Run("backdated events land in their historical hours", func(t *testing.T) {
+		result, err := events.ImportEvents(dbManager, logger, strings.NewReader(umamiExport), events.ImportFormatUmami, website.Domain, false)
+		require.NoError(t, err)
+		assert.Equal(t, 3, result.Stored)
+
+		var hours []struct {
+			Hour      time.Time
+			PageViews int64
+			Visitors  int64
+		}
+		require.NoError(t, db.Raw("SELECT hour, page_views, visitors FROM site_stats WHERE website_id = ? AND page_views > 0 ORDER BY hour", website.ID).Scan(&hours).Error)
+		require.Len(t, hours, 2)
+		assert.True(t, hours[0].Hour.Equal(time.Date(2024, 3, 5, 9, 0, 0, 0, time.UTC)), "got %s", hours[0].Hour)
+		assert.Equal(t, int64(1), hours[0].PageViews)
+		assert.True(t, hours[1].Hour.Equal(time.Date(2024, 3, 5, 14, 0, 0, 0, time.UTC)), "got %s", hours[1].Hour)
+		assert.Equal(t, int64(1), hours[1].PageViews)
+
+		var signatures []string
+		require.NoError(t, db.Raw("SELECT DISTINCT user_signature FROM events WHERE website_id = ?", website.ID).Scan(&signatures).Error)
+		assert.Len(t, signatures, 2, "one visitor per imported session")
+	})
+}
@@ -5,13 +5,16 @@ import (
 	"fmt"
 	"log/slog"
 	"net/url"
+	"strings"
 	"time"
 
 	"github.com/karloscodes/cartridge"
 	"github.com/karloscodes/cartridge/sqlite"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 
 	"fusionaly/internal/config"
+	"fusionaly/internal/metrics"
 	"fusionaly/internal/settings"
 	"fusionaly/internal/visitors"
 	"fusionaly/internal/websites"
@@ -23,6 +26,7 @@ type IngestedEvent struct {
 	WebsiteID        uint   `gorm:"index"`
 	UserSignature    string `gorm:"index"`
 	Hostname         string `gorm:"index"`
+	OriginalHostname string // Subdomain the page was served from when Hostname was rolled up to the base domain
 	Pathname         string `gorm:"index"`
 	RawURL           string
 	ReferrerHostname string `gorm:"index"`
@@ -33,9 +37,19 @@ type IngestedEvent struct {
 	Timestamp        time.Time `gorm:"index"`
 	UserAgent        string
 	SecChUa          string
+	Language         string // Primary Accept-Language tag (e.g. "en-US"), empty when absent
+	ScreenWidth      int    // Viewport width in CSS pixels, 0 when not sent
+	ScreenHeight     int    // Viewport height in CSS pixels, 0 when not sent
+	StatusCode       int    // HTTP status of the page, 0 when not reported
+	Properties       string // Custom dimensions as a JSON object of strings, empty when none
 	Country          string
+	Region           string // Empty unless the geo database has city data
+	City             string
 	CreatedAt        time.Time `gorm:"index"`
 	Processed        int       `gorm:"index"`
+	EventID          *string   `gorm:"uniqueIndex;size:128"` // Client idempotency key; NULL when not sent
+	// Processing runs this event failed in; see MaxProcessingAttempts
+	ProcessingAttempts int `gorm:"not null;default:0"`
 }
 
 // CollectEventInput defines the input required to collect an event.
@@ -43,12 +57,38 @@ type CollectEventInput struct {
 	IPAddress       string
 	UserAgent       string
 	SecChUa         string
+	AcceptLanguage  string // Raw Accept-Language header
 	ReferrerURL     string
 	EventType       EventType
 	CustomEventName string
 	CustomEventMeta string
 	Timestamp       time.Time
 	RawUrl          string
+	UserID          string // Optional stable ID from the SDK; used only when enabled in settings
+	DoNotTrack      bool   // Request carried DNT: 1; honored only when enabled in settings
+	EventID         string // Optional client-generated idempotency key
+	ScreenWidth     int    // Optional viewport width reported by the SDK
+	ScreenHeight    int    // Optional viewport height reported by the SDK
+	StatusCode      int    // Optional HTTP status of the page (e.g. 404), 0 for a normal page
+	VisitorID       string // Visitor identifier from an imported export; stands in for IP and user agent
+	Properties      string // Custom dimensions as a JSON object of strings, empty when none
+}
+
+// maxEventIDLength bounds client-generated idempotency keys
+const maxEventIDLength = 128
+
+// ErrDatabaseBusy is returned when an event couldn't be stored because of
+// write contention. The event wasn't stored; the client should retry later.
+var ErrDatabaseBusy = errors.New("database is busy")
+
+// ingestWriteConfig gives up on write contention of a single collected event
+// after a few quick retries, so the request returns and the client backs off
+// instead of holding it open.
+var ingestWriteConfig = sqlite.TransactionConfig{
+	UseNativeQueuing: true,
+	MaxRetries:       3,
+	BaseDelay:        50 * time.Millisecond,
+	MaxDelay:         500 * time.Millisecond,
 }
 
 // urlData holds parsed URL components
@@ -58,22 +98,159 @@ type urlData struct {
 	rawURL   string
 }
 
-// CollectEvent stores an event in the IngestedEvent table
+// CollectEvent stores an event in the IngestedEvent table, or queues it on the
+// buffered writer when one is set
 func CollectEvent(dbManager cartridge.DBManager, logger *slog.Logger, input *CollectEventInput) error {
-	if input.UserAgent == "" {
+	db := dbManager.GetConnection()
+	cfg := getIngestionSettings(db)
+
+	tempEvent, err := buildIngestedEvent(db, logger, cfg, input)
+	if err != nil || tempEvent == nil {
+		return err
+	}
+
+	if writer := bufferedWriter.Load(); writer != nil {
+		return writer.Enqueue(tempEvent)
+	}
+
+	err = sqlite.PerformWriteWithConfig(logger, db, func(tx *gorm.DB) error {
+		return insertIngestedEvents(tx, []*IngestedEvent{tempEvent}, cfg.dedupeWindow)
+	}, ingestWriteConfig)
+	if err != nil {
+		recordWriteError(err)
+		logger.Error("Failed to store ingested event", slog.Any("error", err))
+		return storeError(err)
+	}
+
+	metrics.EventsIngestedTotal.Inc()
+	return nil
+}
+
+// CollectEvents stores a batch of events in a single write transaction and
+// returns one error (or nil) per input, in order. Inputs that fail validation
+// don't prevent the rest of the batch from being stored.
+func CollectEvents(dbManager cartridge.DBManager, logger *slog.Logger, inputs []*CollectEventInput) []error {
+	db := dbManager.GetConnection()
+	cfg := getIngestionSettings(db)
+	errs := make([]error, len(inputs))
+
+	var pending []*IngestedEvent
+	var pendingIndexes []int
+	for i, input := range inputs {
+		tempEvent, err := buildIngestedEvent(db, logger, cfg, input)
+		if err != nil {
+			errs[i] = err
+			continue
+		}
+		if tempEvent != nil {
+			pending = append(pending, tempEvent)
+			pendingIndexes = append(pendingIndexes, i)
+		}
+	}
+
+	if len(pending) == 0 {
+		return errs
+	}
+
+	err := sqlite.PerformWrite(logger, db, func(tx *gorm.DB) error {
+		return insertIngestedEvents(tx, pending, cfg.dedupeWindow)
+	})
+	if err != nil {
+		recordWriteError(err)
+		logger.Error("Failed to store ingested event batch", slog.Any("error", err), slog.Int("count", len(pending)))
+		for _, i := range pendingIndexes {
+			errs[i] = storeError(err)
+		}
+	} else {
+		metrics.EventsIngestedTotal.Add(int64(len(pending)))
+	}
+
+	return errs
+}
+
+// storeError wraps a failed ingested event write, marking write contention
+// with ErrDatabaseBusy
+func storeError(err error) error {
+	if sqlite.IsBusyError(err) {
+		return fmt.Errorf("failed to store ingested event: %w: %w", ErrDatabaseBusy, err)
+	}
+	return fmt.Errorf("failed to store ingested event: %w", err)
+}
+
+// recordWriteError counts writes that gave up because SQLite stayed busy
+func recordWriteError(err error) {
+	if sqlite.IsBusyError(err) {
+		metrics.DBBusyErrorsTotal.Inc()
+	}
+}
+
+// insertIngestedEvents stores events, silently skipping any whose event ID was
+// already stored within the dedupe window. Keys claimed before the window are
+// released first so a late resubmission is stored as a new event.
+func insertIngestedEvents(tx *gorm.DB, tempEvents []*IngestedEvent, dedupeWindow time.Duration) error {
+	var eventIDs []string
+	for _, tempEvent := range tempEvents {
+		if tempEvent.EventID != nil {
+			eventIDs = append(eventIDs, *tempEvent.EventID)
+		}
+	}
+
+	if len(eventIDs) > 0 {
+		err := tx.Model(&IngestedEvent{}).
+			Where("event_id IN ? AND created_at < ?", eventIDs, time.Now().UTC().Add(-dedupeWindow)).
+			Update("event_id", nil).Error
+		if err != nil {
+			return fmt.Errorf("failed to release expired event IDs: %w", err)
+		}
+	}
+
+	return tx.Clauses(clause.OnConflict{DoNothing: true}).Create(tempEvents).Error
+}
+
+// buildIngestedEvent validates an input against the ingestion settings and
+// prepares its IngestedEvent. It returns nil without error for events that are
+// intentionally skipped.
+func buildIngestedEvent(db *gorm.DB, logger *slog.Logger, cfg *ingestionSettings, input *CollectEventInput) (*IngestedEvent, error) {
+	if input.DoNotTrack && cfg.respectDNT {
+		logger.Debug("Skipping event with Do Not Track header")
+		return nil, nil
+	}
+
+	if len(input.EventID) > maxEventIDLength {
+		return nil, fmt.Errorf("event ID exceeds %d characters", maxEventIDLength)
+	}
+
+	timestamp, err := canonicalTimestamp(input.Timestamp, time.Now(), cfg.maxTimestampSkew, cfg.maxTimestampAge)
+	if err != nil {
+		logger.Debug("Rejecting event timestamp", slog.Any("error", err))
+		return nil, err
+	}
+	input.Timestamp = timestamp
+
+	meta, err := limitEventMeta(input.CustomEventMeta, cfg.maxEventMetaBytes, cfg.truncateEventMeta)
+	if err != nil {
+		logger.Debug("Rejecting event metadata", slog.Any("error", err))
+		return nil, err
+	}
+	if meta != "" && meta != input.CustomEventMeta {
+		logger.Warn("Truncated oversized event metadata", slog.Int("bytes", len(input.CustomEventMeta)), slog.Int("kept_bytes", len(meta)))
+	}
+	input.CustomEventMeta = meta
+
+	hasUserAgent := input.UserAgent != ""
+	if !hasUserAgent {
 		input.UserAgent = "Unknown User Agent"
 	}
 
 	urlData, err := parseInputURL(input.RawUrl, logger)
 	if err != nil {
 		logger.Warn("Failed to parse URL", slog.Any("error", err), slog.String("url", input.RawUrl))
-		return fmt.Errorf("failed to parse URL: %w", err)
+		return nil, fmt.Errorf("failed to parse URL: %w", err)
 	}
 
-	cfg := config.GetConfig()
-	if urlData.hostname == "localhost" && cfg.Environment == config.Production {
+	if urlData.hostname == "localhost" && config.GetConfig().Environment == config.Production {
 		logger.Debug("Skipping event for localhost in production environment", slog.String("url", input.RawUrl))
-		return nil
+		return nil, nil
 	}
 
 	excluded, err := settings.IsIPExcluded(input.IPAddress)
@@ -81,27 +258,45 @@ func CollectEvent(dbManager cartridge.DBManager, logger *slog.Logger, input *Col
 		logger.Error("Error checking IP exclusion", slog.Any("error", err))
 	} else if excluded {
 		logger.Debug("Skipping event for excluded IP", slog.String("ip", input.IPAddress))
-		return nil
+		return nil, nil
+	}
+
+	// A missing user agent is not evidence of a bot, so only check real ones
+	if hasUserAgent && cfg.filterBots && isBotWithPatterns(input.UserAgent, cfg.botPatterns) {
+		logger.Debug("Skipping event from bot", slog.String("user_agent", input.UserAgent))
+		return nil, nil
+	}
+
+	if input.EventType == EventTypeDownload {
+		input.CustomEventName = NormalizeDownloadFilename(input.CustomEventName)
+		if input.CustomEventName == "" {
+			return nil, fmt.Errorf("download event is missing a file name")
+		}
 	}
 
 	country := GetCountryFromIP(input.IPAddress)
-	db := dbManager.GetConnection()
 
-	tempEvent, err := prepareTempEvent(db, logger, input, urlData, country)
+	tempEvent, err := prepareTempEvent(db, logger, cfg, input, urlData, country)
 	if err != nil {
 		logger.Error("Failed to prepare temp event", slog.Any("error", err))
-		return err
+		return nil, err
+	}
+	if tempEvent == nil {
+		return nil, nil
 	}
 
-	err = sqlite.PerformWrite(logger, db, func(tx *gorm.DB) error {
-		return tx.Create(tempEvent).Error
-	})
-	if err != nil {
-		logger.Error("Failed to store ingested event", slog.Any("error", err))
-		return fmt.Errorf("failed to store ingested event: %w", err)
+	tempEvent.Region, tempEvent.City = GetRegionAndCityFromIP(input.IPAddress)
+	tempEvent.Pathname = CanonicalizePath(tempEvent.Pathname, cfg.pathCaseInsensitive, cfg.stripTrailingSlash)
+
+	if IsPathExcluded(tempEvent.Pathname, cfg.excludedPaths) {
+		logger.Debug("Skipping event for excluded path", slog.String("path", tempEvent.Pathname))
+		return nil, nil
 	}
 
-	return nil
+	// Collapse dynamic route segments (IDs, slugs) so each route is one page
+	tempEvent.Pathname = NormalizePath(tempEvent.Pathname, cfg.pathPatterns)
+
+	return tempEvent, nil
 }
 
 // parseInputURL parses a URL string into its components
@@ -137,8 +332,9 @@ func parseInputURL(urlStr string, logger *slog.Logger) (*urlData, error) {
 	}, nil
 }
 
-// prepareTempEvent creates an IngestedEvent from input data
-func prepareTempEvent(db *gorm.DB, logger *slog.Logger, input *CollectEventInput, urlData *urlData, country string) (*IngestedEvent, error) {
+// prepareTempEvent creates an IngestedEvent from input data. It returns nil
+// without error when the website's data collection is paused.
+func prepareTempEvent(db *gorm.DB, logger *slog.Logger, cfg *ingestionSettings, input *CollectEventInput, urlData *urlData, country string) (*IngestedEvent, error) {
 	referrerHostname := DirectOrUnknownReferrer
 	referrerPathname := ""
 	if input.ReferrerURL != "" {
@@ -152,26 +348,27 @@ func prepareTempEvent(db *gorm.DB, logger *slog.Logger, input *CollectEventInput
 	}
 
 	// Try to find the website with the complete hostname first
-	websiteID, err := websites.GetWebsiteOrNotFound(db, urlData.hostname)
+	website, err := websites.LookupWebsite(db, urlData.hostname)
 
 	// In non-production environments, auto-create localhost website for testing
 	// This is a "belt and suspenders" approach - even if setup creates the website,
 	// this ensures tests work reliably regardless of timing or setup issues
-	cfg := config.GetConfig()
-	if err != nil && !cfg.IsProduction() && (urlData.hostname == "localhost" || urlData.hostname == "127.0.0.1") {
+	if err != nil && !config.GetConfig().IsProduction() && (urlData.hostname == "localhost" || urlData.hostname == "127.0.0.1") {
 		logger.Debug("Auto-creating localhost website for testing", slog.String("hostname", urlData.hostname))
-		website := &websites.Website{Domain: urlData.hostname}
-		if createErr := websites.CreateWebsite(db, website); createErr != nil {
+		created := &websites.Website{Domain: urlData.hostname}
+		if createErr := websites.CreateWebsite(db, created); createErr != nil {
 			// If creation failed (maybe already exists), try to find it again
-			websiteID, err = websites.GetWebsiteOrNotFound(db, urlData.hostname)
+			website, err = websites.LookupWebsite(db, urlData.hostname)
 		} else {
-			websiteID = website.ID
+			website = created
 			err = nil
 		}
 	}
 
 	baseDomain := websites.BaseDomainForHost(urlData.hostname)
 	websiteDomain := baseDomain
+	// Set when the event was attributed to the base domain's website
+	matchedBaseDomain := false
 
 	if err != nil {
 		// If not found, try with the stripped subdomain (base domain)
@@ -180,17 +377,18 @@ func prepareTempEvent(db *gorm.DB, logger *slog.Logger, input *CollectEventInput
 			// Only try the base domain if it's different from the original hostname
 			if baseDomain != urlData.hostname {
 				// Check if subdomain tracking is enabled for the base domain
-				if !settings.IsSubdomainTrackingEnabled(db, baseDomain) {
+				if !cfg.subdomainTracking[baseDomain] {
 					// Subdomain tracking is disabled, return error for original hostname
 					return nil, websites.NewWebsiteNotFoundError(urlData.hostname)
 				}
 
 				// Subdomain tracking is enabled, try to find the base domain
-				websiteID, err = websites.GetWebsiteOrNotFound(db, baseDomain)
+				website, err = websites.LookupWebsite(db, baseDomain)
 				if err != nil {
 					// If base domain lookup also fails, return error for original hostname
 					return nil, websites.NewWebsiteNotFoundError(urlData.hostname)
 				}
+				matchedBaseDomain = true
 			} else {
 				// Base domain is the same as original hostname, so it's not found
 				return nil, err
@@ -200,30 +398,78 @@ func prepareTempEvent(db *gorm.DB, logger *slog.Logger, input *CollectEventInput
 			return nil, err
 		}
 	}
+	websiteID := website.ID
+
+	if website.Paused {
+		logger.Debug("Skipping event for paused website", slog.Uint64("website_id", uint64(websiteID)))
+		return nil, nil
+	}
+
+	// Drop query parameters outside the allowlist so URLs don't fragment per click ID
+	urlData.rawURL = StripUntrackedQueryParams(urlData.rawURL, cfg.queryParamAllowlist(websiteID))
+
 	// Check for self-referral and filter it out
 	if referrerHostname != DirectOrUnknownReferrer && referrerHostname != "" {
 		if IsSelfReferral(referrerHostname, websiteDomain) {
-			logger.Debug("Self-referral detected, treating as direct traffic",
+			logger.Debug("Self-referral detected",
 				slog.String("referrer", referrerHostname),
 				slog.String("website_domain", websiteDomain))
 
+			referrerHostname = DirectOrUnknownReferrer
+			if cfg.separateSelfRefs {
+				referrerHostname = SelfReferral
+			}
+			referrerPathname = ""
+		} else if cfg.filterRefSpam && isSpamReferrerWithDomains(referrerHostname, cfg.refSpamDomains) {
+			logger.Debug("Spam referrer detected, treating as direct traffic",
+				slog.String("referrer", referrerHostname))
+
 			referrerHostname = DirectOrUnknownReferrer
 			referrerPathname = ""
 		}
 	}
 
-	var userSignature string
-	isSubdomainOfSubdomainTrackingEnabledWebsite := baseDomain != urlData.hostname && settings.IsSubdomainTrackingEnabled(db, baseDomain)
+	signatureDomain := urlData.hostname
+	isSubdomainOfSubdomainTrackingEnabledWebsite := baseDomain != urlData.hostname && cfg.subdomainTracking[baseDomain]
 	if isSubdomainOfSubdomainTrackingEnabledWebsite {
-		userSignature = visitors.BuildUniqueVisitorId(baseDomain, input.IPAddress, input.UserAgent, config.GetConfig().PrivateKey)
+		signatureDomain = baseDomain
+	}
+
+	var userSignature string
+	if userID := strings.TrimSpace(input.UserID); userID != "" && cfg.useSDKUserID {
+		// Stable ID from the site merges the visitor across devices and sessions
+		userSignature = visitors.BuildIdentifiedVisitorId(signatureDomain, userID, config.GetConfig().PrivateKey)
+	} else if visitorID := strings.TrimSpace(input.VisitorID); visitorID != "" {
+		// Imported events carry the source tool's visitor ID instead of an IP
+		userSignature = visitors.BuildUniqueVisitorIdForDay(signatureDomain, visitorID, "", config.GetConfig().PrivateKey, input.Timestamp)
 	} else {
-		userSignature = visitors.BuildUniqueVisitorId(urlData.hostname, input.IPAddress, input.UserAgent, config.GetConfig().PrivateKey)
+		// Salt by the day the event happened so batched or delayed events
+		// sent after midnight still match the visitor's other events that day
+		signatureDay := input.Timestamp
+		if signatureDay.IsZero() {
+			signatureDay = time.Now()
+		}
+		userSignature = visitors.BuildUniqueVisitorIdForDay(signatureDomain, input.IPAddress, input.UserAgent, config.GetConfig().PrivateKey, signatureDay)
+	}
+
+	var eventID *string
+	if id := strings.TrimSpace(input.EventID); id != "" {
+		eventID = &id
+	}
+
+	// With subdomain rollup, pages of every subdomain are stored under the
+	// base domain so page stats aren't split per subdomain. The subdomain is
+	// kept aside for the subdomain breakdown.
+	hostname, originalHostname := urlData.hostname, ""
+	if matchedBaseDomain && cfg.isSubdomainRollupEnabled(baseDomain) {
+		hostname, originalHostname = baseDomain, urlData.hostname
 	}
 
 	return &IngestedEvent{
 		WebsiteID:        websiteID,
 		UserSignature:    userSignature,
-		Hostname:         urlData.hostname,
+		Hostname:         hostname,
+		OriginalHostname: originalHostname,
 		Pathname:         urlData.pathname,
 		RawURL:           urlData.rawURL,
 		ReferrerHostname: referrerHostname,
@@ -234,8 +480,14 @@ func prepareTempEvent(db *gorm.DB, logger *slog.Logger, input *CollectEventInput
 		Timestamp:        input.Timestamp,
 		UserAgent:        input.UserAgent,
 		SecChUa:          input.SecChUa,
+		Language:         primaryLanguageTag(input.AcceptLanguage),
+		ScreenWidth:      screenDimension(input.ScreenWidth),
+		ScreenHeight:     screenDimension(input.ScreenHeight),
+		StatusCode:       pageStatusCode(input.StatusCode),
+		Properties:       input.Properties,
 		Country:          country,
 		CreatedAt:        time.Now().UTC(),
 		Processed:        0,
+		EventID:          eventID,
 	}, nil
 }
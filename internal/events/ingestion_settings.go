@@ -0,0 +1,110 @@
+package events
+
+import (
+	"log/slog"
+	"regexp"
+	"slices"
+	"time"
+
+	"github.com/karloscodes/cartridge/cache"
+	"gorm.io/gorm"
+
+	"fusionaly/internal/settings"
+)
+
+// ingestionSettingsTTL bounds how stale a snapshot can get when settings are
+// changed without going through the settings package
+const ingestionSettingsTTL = 5 * time.Minute
+
+// ingestionSettings is a snapshot of the settings read while collecting an
+// event, with user-defined patterns already compiled, so collection doesn't
+// query the settings table or compile expressions per event.
+type ingestionSettings struct {
+	respectDNT          bool
+	dedupeWindow        time.Duration
+	maxTimestampSkew    time.Duration
+	maxTimestampAge     time.Duration
+	maxEventMetaBytes   int
+	truncateEventMeta   bool
+	filterBots          bool
+	botPatterns         []botPattern
+	trackedQueryParams  []string
+	breakdownParams     map[uint][]string
+	pathCaseInsensitive bool
+	stripTrailingSlash  bool
+	excludedPaths       []*regexp.Regexp
+	pathPatterns        []PathPattern
+	separateSelfRefs    bool
+	filterRefSpam       bool
+	refSpamDomains      map[string]bool
+	useSDKUserID        bool
+	subdomainTracking   map[string]bool
+	subdomainRollup     map[string]bool
+}
+
+// ingestionSettingsCache holds one snapshot per database connection. It's
+// cleared whenever a setting is written.
+var ingestionSettingsCache = cache.NewCache[*gorm.DB, *ingestionSettings](slog.Default(), ingestionSettingsTTL,
+	func(db *gorm.DB) (*ingestionSettings, error) {
+		return loadIngestionSettings(db), nil
+	})
+
+func init() {
+	settings.OnChange(ingestionSettingsCache.Clear)
+}
+
+// getIngestionSettings returns the current ingestion settings snapshot
+func getIngestionSettings(db *gorm.DB) *ingestionSettings {
+	snapshot, err := ingestionSettingsCache.Get(db)
+	if err != nil || snapshot == nil {
+		return loadIngestionSettings(db)
+	}
+	return snapshot
+}
+
+// loadIngestionSettings reads the ingestion settings from the database
+func loadIngestionSettings(db *gorm.DB) *ingestionSettings {
+	pathPatterns, err := ParsePathPatterns(settings.GetPathPatterns(db))
+	if err != nil {
+		slog.Default().Warn("Ignoring invalid path patterns", slog.Any("error", err))
+	}
+	subdomainTracking, _ := settings.GetSubdomainTrackingSettings(db)
+
+	return &ingestionSettings{
+		respectDNT:          settings.IsDoNotTrackRespected(db),
+		dedupeWindow:        time.Duration(settings.GetDedupeWindowMinutes(db)) * time.Minute,
+		maxTimestampSkew:    time.Duration(settings.GetMaxTimestampSkewMinutes(db)) * time.Minute,
+		maxTimestampAge:     time.Duration(settings.GetMaxTimestampAgeDays(db)) * 24 * time.Hour,
+		maxEventMetaBytes:   settings.GetMaxEventMetaBytes(db),
+		truncateEventMeta:   settings.IsEventMetaTruncationEnabled(db),
+		filterBots:          settings.IsBotFilteringEnabled(db),
+		botPatterns:         compileBotPatterns(settings.GetBotPatterns(db)),
+		trackedQueryParams:  settings.GetTrackedQueryParams(db),
+		breakdownParams:     settings.GetAllDashboardTrackParams(db),
+		pathCaseInsensitive: settings.IsPathCaseInsensitive(db),
+		stripTrailingSlash:  settings.IsTrailingSlashStripEnabled(db),
+		excludedPaths:       compileExcludedPaths(settings.GetExcludedPaths(db)),
+		pathPatterns:        pathPatterns,
+		separateSelfRefs:    settings.IsSelfReferralSeparationEnabled(db),
+		filterRefSpam:       settings.IsReferrerSpamFilteringEnabled(db),
+		refSpamDomains:      parseSpamDomains(settings.GetReferrerSpamDomains(db)),
+		useSDKUserID:        settings.IsSDKUserIDEnabled(db),
+		subdomainTracking:   subdomainTracking,
+		subdomainRollup:     settings.GetSubdomainRollupSettings(db),
+	}
+}
+
+// isSubdomainRollupEnabled mirrors settings.IsSubdomainRollupEnabled
+func (s *ingestionSettings) isSubdomainRollupEnabled(domain string) bool {
+	return s.subdomainTracking[domain] && s.subdomainRollup[domain]
+}
+
+// queryParamAllowlist returns the query parameters kept on the website's page
+// URLs: the tracked ones plus those its dashboard breaks down. It's empty, so
+// nothing is stripped, when no parameters are tracked.
+func (s *ingestionSettings) queryParamAllowlist(websiteID uint) []string {
+	if len(s.trackedQueryParams) == 0 {
+		return nil
+	}
+	return append(slices.Clip(s.trackedQueryParams), s.breakdownParams[websiteID]...)
+}
@@ -0,0 +1,65 @@
+package events_test
+
+import (
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"fusionaly/internal/events"
+	"fusionaly/internal/settings"
+	"fusionaly/internal/testsupport"
+)
+
+func TestCollectEventReusesIngestionSettings(t *testing.T) {
+	dbManager, logger := testsupport.SetupTestDBManager(t)
+	db := dbManager.GetConnection()
+	testsupport.CleanAllTables(db)
+	testsupport.CreateTestWebsite(db, "example.com")
+	require.NoError(t, settings.SetupDefaultSettings(db))
+	require.NoError(t, settings.SaveExcludedPaths(db, "/admin"))
+
+	var settingsQueries atomic.Int64
+	require.NoError(t, db.Callback().Query().After("gorm:query").Register("test:count_settings_queries", func(tx *gorm.DB) {
+		if tx.Statement.Table == "settings" {
+			settingsQueries.Add(1)
+		}
+	}))
+	t.Cleanup(func() { db.Callback().Query().Remove("test:count_settings_queries") })
+
+	collect := func(t *testing.T, visitor int, path string) {
+		t.Helper()
+		require.NoError(t, events.CollectEvent(dbManager, logger, testsupport.CreateTestEventInput(
+			fmt.Sprintf("203.0.113.%d", visitor), "Mozilla/5.0 (test)", events.EventTypePageView, time.Now().UTC(),
+			"https://example.com"+path, "", "", "",
+		)))
+	}
+	countIngested := func(t *testing.T) int64 {
+		t.Helper()
+		var count int64
+		require.NoError(t, db.Model(&events.IngestedEvent{}).Count(&count).Error)
+		return count
+	}
+
+	collect(t, 1, "/pricing")
+	warmQueries := settingsQueries.Load()
+
+	for i := 2; i <= 10; i++ {
+		collect(t, i, "/pricing")
+	}
+	assert.Equal(t, warmQueries, settingsQueries.Load(), "later events reuse the settings snapshot")
+	collect(t, 11, "/admin/users")
+	assert.Equal(t, int64(10), countIngested(t))
+
+	t.Run("picks up saved settings right away", func(t *testing.T) {
+		require.NoError(t, settings.SaveExcludedPaths(db, "/pricing"))
+
+		collect(t, 12, "/pricing")
+		collect(t, 13, "/admin/users")
+		assert.Equal(t, int64(11), countIngested(t))
+	})
+}
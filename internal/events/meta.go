@@ -0,0 +1,75 @@
+package events
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// ErrInvalidEventMeta is returned for custom event metadata that isn't a JSON object
+var ErrInvalidEventMeta = errors.New("event metadata is not a JSON object")
+
+// ErrEventMetaTooLarge is returned for metadata over the max_event_meta_bytes
+// setting when truncation is disabled
+var ErrEventMetaTooLarge = errors.New("event metadata is too large")
+
+// EventMetaTruncatedKey is set to true on metadata trimmed to fit the size limit
+const EventMetaTruncatedKey = "_truncated"
+
+// limitEventMeta validates custom event metadata and enforces maxBytes on it
+// (0 disables the limit). Oversized metadata is rejected with
+// ErrEventMetaTooLarge, or, when truncate is set, cut down to the top-level
+// keys that fit in alphabetical order and flagged with EventMetaTruncatedKey.
+func limitEventMeta(meta string, maxBytes int, truncate bool) (string, error) {
+	if meta == "" {
+		return "", nil
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(meta), &fields); err != nil {
+		return "", fmt.Errorf("%w: %v", ErrInvalidEventMeta, err)
+	}
+	if fields == nil {
+		// "null" carries no metadata
+		return "", nil
+	}
+
+	if maxBytes <= 0 || len(meta) <= maxBytes {
+		return meta, nil
+	}
+	if !truncate {
+		return "", fmt.Errorf("%w: %d bytes, the limit is %d", ErrEventMetaTooLarge, len(meta), maxBytes)
+	}
+
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		if key != EventMetaTruncatedKey {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	var buf bytes.Buffer
+	buf.WriteString(`{"` + EventMetaTruncatedKey + `":true`)
+	var entry bytes.Buffer
+	for _, key := range keys {
+		entry.Reset()
+		name, _ := json.Marshal(key)
+		entry.WriteByte(',')
+		entry.Write(name)
+		entry.WriteByte(':')
+		if err := json.Compact(&entry, fields[key]); err != nil {
+			continue
+		}
+		// Leave room for the closing brace
+		if buf.Len()+entry.Len()+1 > maxBytes {
+			continue
+		}
+		buf.Write(entry.Bytes())
+	}
+	buf.WriteByte('}')
+
+	return buf.String(), nil
+}
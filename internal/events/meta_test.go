@@ -0,0 +1,81 @@
+package events_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"fusionaly/internal/events"
+	"fusionaly/internal/settings"
+	"fusionaly/internal/testsupport"
+)
+
+func TestCollectEventMetadataLimits(t *testing.T) {
+	dbManager, logger := testsupport.SetupTestDBManager(t)
+	db := dbManager.GetConnection()
+	testsupport.CleanAllTables(db)
+	testsupport.CreateTestWebsite(db, "example.com")
+	require.NoError(t, settings.SetupDefaultSettings(db))
+	require.NoError(t, settings.SaveMaxEventMetaBytes(db, 64))
+
+	collect := func(meta string) error {
+		db.Exec("DELETE FROM ingested_events")
+		return events.CollectEvent(dbManager, logger, testsupport.CreateTestEventInput(
+			"203.0.113.1", "Mozilla/5.0 (test)", events.EventTypeCustomEvent, time.Now().UTC(),
+			"https://example.com/checkout", "", "checkout", meta,
+		))
+	}
+	storedMeta := func(t *testing.T) string {
+		t.Helper()
+		var ingested events.IngestedEvent
+		require.NoError(t, db.First(&ingested).Error)
+		return ingested.CustomEventMeta
+	}
+	oversized := `{"plan":"pro","items":3,"notes":"` + strings.Repeat("x", 100) + `"}`
+
+	t.Run("metadata within the limit is stored as sent", func(t *testing.T) {
+		require.NoError(t, collect(`{"plan":"pro","items":3}`))
+		assert.Equal(t, `{"plan":"pro","items":3}`, storedMeta(t))
+	})
+
+	t.Run("oversized metadata is rejected", func(t *testing.T) {
+		err := collect(oversized)
+		require.ErrorIs(t, err, events.ErrEventMetaTooLarge)
+		assert.Contains(t, err.Error(), "the limit is 64")
+
+		var count int64
+		require.NoError(t, db.Model(&events.IngestedEvent{}).Count(&count).Error)
+		assert.Zero(t, count)
+	})
+
+	t.Run("oversized metadata is truncated when enabled", func(t *testing.T) {
+		require.NoError(t, settings.SaveEventMetaTruncationEnabled(db, true))
+		t.Cleanup(func() { settings.SaveEventMetaTruncationEnabled(db, false) })
+
+		require.NoError(t, collect(oversized))
+		assert.Equal(t, `{"_truncated":true,"items":3,"plan":"pro"}`, storedMeta(t),
+			"keys that don't fit are dropped and the rest kept")
+	})
+
+	t.Run("no limit", func(t *testing.T) {
+		require.NoError(t, settings.SaveMaxEventMetaBytes(db, 0))
+		t.Cleanup(func() { settings.SaveMaxEventMetaBytes(db, 64) })
+
+		require.NoError(t, collect(oversized))
+		assert.Equal(t, oversized, storedMeta(t))
+	})
+
+	t.Run("malformed metadata is rejected", func(t *testing.T) {
+		for _, meta := range []string{`{"plan": "pro"`, `not json`, `["pro"]`, `42`} {
+			assert.ErrorIs(t, collect(meta), events.ErrInvalidEventMeta, meta)
+		}
+	})
+
+	t.Run("empty metadata", func(t *testing.T) {
+		require.NoError(t, collect(""))
+		assert.Empty(t, storedMeta(t))
+	})
+}
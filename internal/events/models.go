@@ -8,6 +8,7 @@ type EventType int
 const (
 	EventTypePageView    EventType = 1
 	EventTypeCustomEvent EventType = 2
+	EventTypeDownload    EventType = 3 // CustomEventName holds the downloaded file
 )
 
 // Event represents a tracked page view or custom event in the main database.
@@ -16,9 +17,18 @@ type Event struct {
 	WebsiteID        uint   `gorm:"index:idx_website_timestamp;not null"`
 	UserSignature    string `gorm:"index;size:64;not null"`
 	Hostname         string `gorm:"index;not null"`
+	OriginalHostname string // Subdomain the page was served from when Hostname was rolled up to the base domain
 	Pathname         string `gorm:"index;not null"`
 	ReferrerHostname string `gorm:"index"`
 	ReferrerPathname string
+	UTMSource        string    // utm_source of the page URL, empty when absent
+	UTMTerm          string    // utm_term of the page URL, empty when absent
+	UTMContent       string    // utm_content of the page URL, empty when absent
+	Language         string    // Primary Accept-Language tag (e.g. "en-US"), empty when absent
+	ScreenWidth      int       // Viewport width in CSS pixels, 0 when not sent
+	ScreenHeight     int       // Viewport height in CSS pixels, 0 when not sent
+	StatusCode       int       `gorm:"not null;default:0"` // HTTP status of the page (404 for not found), 0 when not reported
+	Properties       string    `gorm:"type:text"`          // Custom dimensions as a JSON object of strings, empty when none
 	EventType        EventType `gorm:"not null;default:1"`
 	CustomEventName  string    `gorm:"index"`
 	CustomEventMeta  string    `gorm:"type:text"`
@@ -37,9 +47,15 @@ type EventProcessingData struct {
 	ReferrerHostname string
 	ReferrerPathname string
 	DeviceType       string
+	DeviceModel      string
 	Browser          string
+	BrowserVersion   string
 	OperatingSystem  string
 	Country          string
+	Region           string
+	City             string
+	Language         string
+	ScreenSize       string // Width bucket, empty when the viewport was not sent
 	UTMSource        string
 	UTMMedium        string
 	UTMCampaign      string
@@ -51,9 +67,19 @@ type EventProcessingData struct {
 	EventType        EventType
 	IsNewVisitor     bool
 	IsNewSession     bool
+	SessionTimeout   time.Duration // Session timeout of the website; 0 reads it from the settings
 	Timestamp        time.Time
 	IsEntrance       bool
 	IsExit           bool
 	IsBounce         bool
 	HasUTM           bool
+	// Scroll depth bucket reached by a scroll event, and the bucket (and time)
+	// previously counted for the same page in the session, if any
+	ScrollDepth         int
+	PreviousScrollDepth int
+	PreviousScrollTime  time.Time
+	// Set on the page view starting a visitor's first return visit, with the
+	// time of the page view they were first counted as a visitor at
+	IsFirstReturn bool
+	FirstSeenTime time.Time
 }
@@ -0,0 +1,153 @@
+package events
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// normalizeTrailingSlash drops trailing slashes so "/blog/" and "/blog" match alike
+func normalizeTrailingSlash(path string) string {
+	if trimmed := strings.TrimRight(path, "/"); trimmed != "" {
+		return trimmed
+	}
+	return "/"
+}
+
+// CanonicalizePath lowercases the path and/or drops its trailing slashes, so
+// "/About", "/about" and "/about/" can be counted as one page. The root path
+// is always kept as "/".
+func CanonicalizePath(path string, lowercase, stripTrailingSlash bool) string {
+	if lowercase {
+		path = strings.ToLower(path)
+	}
+	if stripTrailingSlash {
+		path = normalizeTrailingSlash(path)
+	}
+	if path == "" {
+		return "/"
+	}
+	return path
+}
+
+// compileExcludedPaths turns path exclusion patterns into expressions for
+// IsPathExcluded. Patterns are anchored at the start of the path and match the
+// path itself or anything below it ("/wp-admin" covers "/wp-admin/users" but
+// not "/wp-administrator"). "*" matches any run of characters, including "/".
+func compileExcludedPaths(patterns []string) []*regexp.Regexp {
+	var compiled []*regexp.Regexp
+	for _, pattern := range patterns {
+		pattern = strings.TrimSpace(pattern)
+		if pattern == "" {
+			continue
+		}
+		if !strings.HasPrefix(pattern, "/") && !strings.HasPrefix(pattern, "*") {
+			pattern = "/" + pattern
+		}
+		pattern = normalizeTrailingSlash(pattern)
+
+		expr := "^" + strings.ReplaceAll(regexp.QuoteMeta(pattern), `\*`, ".*") + "(?:/.*)?$"
+		if re, err := regexp.Compile(expr); err == nil {
+			compiled = append(compiled, re)
+		}
+	}
+	return compiled
+}
+
+// IsPathExcluded reports whether a pathname matches any exclusion pattern
+// compiled by compileExcludedPaths.
+func IsPathExcluded(pathname string, patterns []*regexp.Regexp) bool {
+	pathname = normalizeTrailingSlash(pathname)
+	for _, re := range patterns {
+		if re.MatchString(pathname) {
+			return true
+		}
+	}
+	return false
+}
+
+// StripUntrackedQueryParams removes query parameters that aren't in the allowlist.
+// UTM parameters and ref are always kept so campaigns and the ref breakdown are
+// still attributed. An empty allowlist leaves the URL untouched. Parameter
+// order and fragments are preserved.
+func StripUntrackedQueryParams(rawURL string, allowed []string) string {
+	if len(allowed) == 0 {
+		return rawURL
+	}
+
+	parsedURL, err := url.Parse(rawURL)
+	if err != nil || parsedURL.RawQuery == "" {
+		return rawURL
+	}
+
+	allowedSet := make(map[string]bool, len(allowed))
+	for _, param := range allowed {
+		allowedSet[strings.ToLower(strings.TrimSpace(param))] = true
+	}
+
+	var kept []string
+	for _, pair := range strings.Split(parsedURL.RawQuery, "&") {
+		key, _, _ := strings.Cut(pair, "=")
+		if unescaped, err := url.QueryUnescape(key); err == nil {
+			key = unescaped
+		}
+		key = strings.ToLower(key)
+		if strings.HasPrefix(key, "utm_") || key == "ref" || allowedSet[key] {
+			kept = append(kept, pair)
+		}
+	}
+
+	parsedURL.RawQuery = strings.Join(kept, "&")
+	return parsedURL.String()
+}
+
+// PathPattern rewrites pathnames matching Pattern, so dynamic routes like
+// "/users/123" and "/users/456" aggregate as one page ("/users/:id").
+type PathPattern struct {
+	Pattern     *regexp.Regexp
+	Replacement string
+}
+
+// pathPatternSeparator splits a rule into its regex and its replacement
+const pathPatternSeparator = "=>"
+
+// ParsePathPatterns parses rules written as "regex => replacement", one per
+// entry. Blank entries are skipped; invalid rules are skipped too and reported
+// in the returned error so callers can still use the valid ones.
+func ParsePathPatterns(rules []string) ([]PathPattern, error) {
+	var patterns []PathPattern
+	var errs []error
+	for _, rule := range rules {
+		rule = strings.TrimSpace(rule)
+		if rule == "" {
+			continue
+		}
+		expr, replacement, found := strings.Cut(rule, pathPatternSeparator)
+		expr = strings.TrimSpace(expr)
+		if !found || expr == "" {
+			errs = append(errs, fmt.Errorf("path pattern %q must be written as \"regex %s replacement\"", rule, pathPatternSeparator))
+			continue
+		}
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("invalid path pattern %q: %w", expr, err))
+			continue
+		}
+		patterns = append(patterns, PathPattern{Pattern: re, Replacement: strings.TrimSpace(replacement)})
+	}
+	return patterns, errors.Join(errs...)
+}
+
+// NormalizePath applies every pattern, in order, to the path. Replacements
+// support regexp expansion ("$1"). With no patterns the path is returned as is.
+func NormalizePath(path string, patterns []PathPattern) string {
+	for _, p := range patterns {
+		path = p.Pattern.ReplaceAllString(path, p.Replacement)
+	}
+	if path == "" {
+		return "/"
+	}
+	return path
+}
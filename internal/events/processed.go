@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"net/url"
+	"strings"
 	"time"
 
 	"log/slog"
@@ -14,12 +15,29 @@ import (
 
 	"fusionaly/internal/config"
 	ua "fusionaly/internal/pkg/user_agent"
+	"fusionaly/internal/settings"
 )
 
 // EventProcessingResult holds the results of batch event processing
 type EventProcessingResult struct {
 	ProcessedEvents []*Event
 	ProcessingData  []*EventProcessingData
+	Batches         int // Transactions the events were processed in
+	Failed          int // Events that failed processing in this run
+}
+
+// targetBatchesPerRun is how many transactions a large backlog is drained in
+const targetBatchesPerRun = 10
+
+// AdaptiveBatchSize returns the processing batch size for a backlog: baseSize,
+// grown so a large backlog drains in about targetBatchesPerRun transactions,
+// capped at maxSize.
+func AdaptiveBatchSize(backlog int64, baseSize, maxSize int) int {
+	baseSize = max(baseSize, 1)
+	maxSize = max(maxSize, baseSize)
+
+	size := max(int64(baseSize), (backlog+targetBatchesPerRun-1)/targetBatchesPerRun)
+	return int(min(size, int64(maxSize)))
 }
 
 // ProcessUnprocessedEvents processes unprocessed IngestedEvents in batches
@@ -50,6 +68,7 @@ func ProcessUnprocessedEvents(dbManager cartridge.DBManager, logger *slog.Logger
 			end = len(tempEvents)
 		}
 		batch := tempEvents[i:end]
+		result.Batches++
 
 		err := sqlite.PerformWrite(logger, db, func(tx *gorm.DB) error {
 			events, processingData, err := processEventBatch(tx, logger, batch)
@@ -63,6 +82,10 @@ func ProcessUnprocessedEvents(dbManager cartridge.DBManager, logger *slog.Logger
 		})
 		if err != nil {
 			logger.Error("Failed to process batch", slog.Int("start", i), slog.Int("end", end), slog.Any("error", err))
+			// Contention isn't the events' fault; they're retried on the next run
+			if !sqlite.IsBusyError(err) {
+				processEventsIndividually(db, logger, batch, result)
+			}
 			continue
 		}
 	}
@@ -73,10 +96,38 @@ func ProcessUnprocessedEvents(dbManager cartridge.DBManager, logger *slog.Logger
 	return result, nil
 }
 
+// processEventsIndividually retries the events of a failed batch one per
+// transaction, so a single bad event doesn't hold back the rest. Events that
+// still fail have the failure recorded against them.
+func processEventsIndividually(db *gorm.DB, logger *slog.Logger, batch []IngestedEvent, result *EventProcessingResult) {
+	for _, tempEvent := range batch {
+		err := sqlite.PerformWrite(logger, db, func(tx *gorm.DB) error {
+			events, processingData, err := processEventBatch(tx, logger, []IngestedEvent{tempEvent})
+			if err != nil {
+				return err
+			}
+
+			result.ProcessedEvents = append(result.ProcessedEvents, events...)
+			result.ProcessingData = append(result.ProcessingData, processingData...)
+			return nil
+		})
+		if err == nil || sqlite.IsBusyError(err) {
+			continue
+		}
+
+		result.Failed++
+		if err := recordProcessingFailure(db, logger, tempEvent, err); err != nil {
+			logger.Error("Failed to record event processing failure", slog.Uint64("ingested_event_id", uint64(tempEvent.ID)), slog.Any("error", err))
+		}
+	}
+}
+
 // processEventBatch processes a batch of IngestedEvents within a transaction
 func processEventBatch(tx *gorm.DB, logger *slog.Logger, batch []IngestedEvent) ([]*Event, []*EventProcessingData, error) {
 	var events []*Event
 	var processingData []*EventProcessingData
+	normalizeUTM := settings.IsUTMCaseNormalizationEnabled(tx)
+	timeouts := loadSessionTimeouts(tx)
 
 	for i, tempEvent := range batch {
 		// Parse User Agent early to check for bots
@@ -94,13 +145,23 @@ func processEventBatch(tx *gorm.DB, logger *slog.Logger, batch []IngestedEvent)
 				slog.String("timestamp_utc", tempEvent.Timestamp.UTC().Format(time.RFC3339)))
 		}
 
+		utmSource, utmTerm, utmContent := utmFromURL(tempEvent.RawURL, normalizeUTM)
 		event := &Event{
 			WebsiteID:        tempEvent.WebsiteID,
 			UserSignature:    tempEvent.UserSignature,
 			Hostname:         tempEvent.Hostname,
+			OriginalHostname: tempEvent.OriginalHostname,
 			Pathname:         tempEvent.Pathname,
 			ReferrerHostname: tempEvent.ReferrerHostname,
 			ReferrerPathname: tempEvent.ReferrerPathname,
+			UTMSource:        utmSource,
+			UTMTerm:          utmTerm,
+			UTMContent:       utmContent,
+			Language:         tempEvent.Language,
+			ScreenWidth:      tempEvent.ScreenWidth,
+			ScreenHeight:     tempEvent.ScreenHeight,
+			StatusCode:       tempEvent.StatusCode,
+			Properties:       tempEvent.Properties,
 			EventType:        tempEvent.EventType,
 			CustomEventName:  tempEvent.CustomEventName,
 			CustomEventMeta:  tempEvent.CustomEventMeta,
@@ -113,7 +174,7 @@ func processEventBatch(tx *gorm.DB, logger *slog.Logger, batch []IngestedEvent)
 		}
 
 		// Pass the already parsed UA struct
-		data, err := prepareEventProcessingData(tx, &tempEvent, event.ID, parsedUA)
+		data, err := prepareEventProcessingData(tx, &tempEvent, event.ID, parsedUA, normalizeUTM, timeouts.forWebsite(tempEvent.WebsiteID))
 		if err != nil {
 			logger.Error("Failed to prepare processing data", slog.Uint64("id", uint64(uint64(tempEvent.ID))), slog.Any("error", err))
 			return nil, nil, fmt.Errorf("failed to prepare processing data: %w", err)
@@ -145,40 +206,51 @@ func processEventBatch(tx *gorm.DB, logger *slog.Logger, batch []IngestedEvent)
 }
 
 // prepareEventProcessingData enriches event data for aggregation
-// Accepts the pre-parsed useragent.UserAgent struct
-func prepareEventProcessingData(db *gorm.DB, tempEvent *IngestedEvent, eventID uint, parsedUA ua.UserAgent) (*EventProcessingData, error) {
+// Accepts the pre-parsed useragent.UserAgent struct; normalizeUTM trims and
+// lowercases the UTM values and sessionTimeout is the website's session timeout
+func prepareEventProcessingData(db *gorm.DB, tempEvent *IngestedEvent, eventID uint, parsedUA ua.UserAgent, normalizeUTM bool, sessionTimeout time.Duration) (*EventProcessingData, error) {
 	// Unified check for first-ever event and new session (used for page views and most aggregates)
-	isNewVisitor, isNewSession, err := checkVisitorAndSessionStatus(db, tempEvent.WebsiteID, tempEvent.UserSignature, tempEvent.Timestamp)
+	isNewVisitor, isNewSession, err := checkVisitorAndSessionStatus(db, tempEvent.WebsiteID, tempEvent.UserSignature, tempEvent.Timestamp, sessionTimeout)
 	if err != nil {
 		return nil, fmt.Errorf("failed to check visitor and session status: %w", err)
 	}
 
-	// For custom events, override isNewVisitor to check if this is the first time the visitor triggered this specific event
-	if tempEvent.EventType == EventTypeCustomEvent {
-		isNewVisitor, err = checkIsNewEventVisitor(db, tempEvent.WebsiteID, tempEvent.UserSignature, tempEvent.CustomEventName, tempEvent.Timestamp)
+	// For custom events and downloads, override isNewVisitor to check if this is the first time
+	// the visitor triggered this specific event or downloaded this specific file
+	if tempEvent.EventType == EventTypeCustomEvent || tempEvent.EventType == EventTypeDownload {
+		isNewVisitor, err = checkIsNewEventVisitor(db, tempEvent.WebsiteID, tempEvent.UserSignature, tempEvent.EventType, tempEvent.CustomEventName, tempEvent.Timestamp)
 		if err != nil {
 			return nil, fmt.Errorf("failed to check event-specific visitor status: %w", err)
 		}
 	}
 
-	isExit, err := checkIsExitEvent(db, tempEvent.WebsiteID, tempEvent.UserSignature, tempEvent.EventType, tempEvent.Timestamp)
+	isExit, err := checkIsExitEvent(db, tempEvent.WebsiteID, tempEvent.UserSignature, tempEvent.EventType, tempEvent.Timestamp, sessionTimeout)
 	if err != nil {
 		return nil, fmt.Errorf("failed to check if exit event: %w", err)
 	}
 
 	isEntrance := isNewSession && tempEvent.EventType == EventTypePageView
 
+	var isFirstReturn bool
+	var firstSeenTime time.Time
+	if isEntrance && !isNewVisitor {
+		isFirstReturn, firstSeenTime, err = checkIsFirstReturn(db, tempEvent.WebsiteID, tempEvent.UserSignature, tempEvent.Timestamp, sessionTimeout)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check returning visitor status: %w", err)
+		}
+	}
+
 	utmSource, utmMedium, utmCampaign, utmTerm, utmContent := EmptyUTMAttr, EmptyUTMAttr, EmptyUTMAttr, EmptyUTMAttr, EmptyUTMAttr
 	queryParams := make(map[string]string)
 
 	if tempEvent.RawURL != "" {
 		parsedURL, err := url.Parse(tempEvent.RawURL)
 		if err == nil {
-			utmSource = getUTMParam(parsedURL, "utm_source")
-			utmMedium = getUTMParam(parsedURL, "utm_medium")
-			utmCampaign = getUTMParam(parsedURL, "utm_campaign")
-			utmTerm = getUTMParam(parsedURL, "utm_term")
-			utmContent = getUTMParam(parsedURL, "utm_content")
+			utmSource = getUTMParam(parsedURL, "utm_source", normalizeUTM)
+			utmMedium = getUTMParam(parsedURL, "utm_medium", normalizeUTM)
+			utmCampaign = getUTMParam(parsedURL, "utm_campaign", normalizeUTM)
+			utmTerm = getUTMParam(parsedURL, "utm_term", normalizeUTM)
+			utmContent = getUTMParam(parsedURL, "utm_content", normalizeUTM)
 
 			// Extract ALL query parameters
 			for key, values := range parsedURL.Query() {
@@ -194,6 +266,18 @@ func prepareEventProcessingData(db *gorm.DB, tempEvent *IngestedEvent, eventID u
 		customEventKey = tempEvent.CustomEventName
 	}
 
+	var scrollDepth, previousDepth int
+	var previousScrollTime time.Time
+	if tempEvent.EventType == EventTypeCustomEvent && isScrollEvent(tempEvent.CustomEventName) {
+		scrollDepth = scrollDepthFromMeta(tempEvent.CustomEventMeta)
+		if scrollDepth > 0 {
+			previousDepth, previousScrollTime, err = previousScrollDepth(db, tempEvent, sessionTimeout)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
 	hasUTM := utmSource != EmptyUTMAttr || utmMedium != EmptyUTMAttr || utmCampaign != EmptyUTMAttr
 
 	return &EventProcessingData{
@@ -205,9 +289,15 @@ func prepareEventProcessingData(db *gorm.DB, tempEvent *IngestedEvent, eventID u
 		ReferrerHostname: tempEvent.ReferrerHostname,
 		ReferrerPathname: tempEvent.ReferrerPathname,
 		DeviceType:       getDeviceTypeFromParsedUA(parsedUA),
+		DeviceModel:      getDeviceModelFromParsedUA(parsedUA),
 		Browser:          getBrowserFromParsedUA(parsedUA, tempEvent.SecChUa),
+		BrowserVersion:   getBrowserVersionFromParsedUA(parsedUA),
 		OperatingSystem:  getOSFromParsedUA(parsedUA),
 		Country:          tempEvent.Country,
+		Region:           tempEvent.Region,
+		City:             tempEvent.City,
+		Language:         NormalizeLanguage(tempEvent.Language, config.GetConfig().LanguageFullTag),
+		ScreenSize:       ScreenSizeBucket(tempEvent.ScreenWidth),
 		UTMSource:        utmSource,
 		UTMMedium:        utmMedium,
 		UTMCampaign:      utmCampaign,
@@ -219,19 +309,26 @@ func prepareEventProcessingData(db *gorm.DB, tempEvent *IngestedEvent, eventID u
 		EventType:        EventType(tempEvent.EventType),
 		IsNewVisitor:     isNewVisitor,
 		IsNewSession:     isNewSession,
+		SessionTimeout:   sessionTimeout,
 		Timestamp:        tempEvent.Timestamp,
 		IsEntrance:       isEntrance,
 		IsExit:           isExit,
 		IsBounce:         false,
 		HasUTM:           hasUTM,
+
+		ScrollDepth:         scrollDepth,
+		PreviousScrollDepth: previousDepth,
+		PreviousScrollTime:  previousScrollTime,
+
+		IsFirstReturn: isFirstReturn,
+		FirstSeenTime: firstSeenTime,
 	}, nil
 }
 
 // checkVisitorAndSessionStatus determines if this is the first-ever event for a visitor
 // and if it starts a new session based on any previous event.
 // Note: For custom events, use checkIsNewEventVisitor to check event-specific visitor status.
-func checkVisitorAndSessionStatus(db *gorm.DB, websiteID uint, userSignature string, timestamp time.Time) (isNewVisitor bool, isNewSession bool, err error) {
-	sessionTimeout := config.GetConfig().SessionTimeoutSeconds
+func checkVisitorAndSessionStatus(db *gorm.DB, websiteID uint, userSignature string, timestamp time.Time, sessionTimeout time.Duration) (isNewVisitor bool, isNewSession bool, err error) {
 
 	var previousEvent Event
 	qErr := db.Where("website_id = ? AND user_signature = ? AND timestamp < ?",
@@ -251,17 +348,56 @@ func checkVisitorAndSessionStatus(db *gorm.DB, websiteID uint, userSignature str
 
 	// A new session starts if the time since the last event exceeds sessionTimeout
 	timeSinceLastEvent := timestamp.Sub(previousEvent.Timestamp)
-	isNewSession = timeSinceLastEvent > time.Duration(sessionTimeout)*time.Second
+	isNewSession = timeSinceLastEvent > sessionTimeout
 
 	return isNewVisitor, isNewSession, nil
 }
 
+// checkIsFirstReturn reports whether a session started at timestamp is the
+// visitor's first return, i.e. all their earlier events belong to one session.
+// It also returns when the visitor was first counted, which only happens when
+// their first-ever event was a page view.
+func checkIsFirstReturn(db *gorm.DB, websiteID uint, userSignature string, timestamp time.Time, sessionTimeout time.Duration) (bool, time.Time, error) {
+	var firstEvent Event
+	err := db.Where("website_id = ? AND user_signature = ? AND timestamp < ?", websiteID, userSignature, timestamp).
+		Order("timestamp ASC").
+		First(&firstEvent).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return false, time.Time{}, nil
+		}
+		return false, time.Time{}, fmt.Errorf("failed to query first event: %w", err)
+	}
+	if firstEvent.EventType != EventTypePageView {
+		return false, time.Time{}, nil
+	}
+
+	var earlierReturns int64
+	err = db.Raw(`
+		SELECT COUNT(*) FROM (
+			SELECT
+				timestamp,
+				LAG(timestamp) OVER (ORDER BY timestamp) as prev_time
+			FROM events
+			WHERE website_id = ? AND user_signature = ? AND timestamp < ?
+		)
+		WHERE prev_time IS NOT NULL
+		AND CAST((JULIANDAY(timestamp) - JULIANDAY(prev_time)) * 86400 as INTEGER) > ?
+	`, websiteID, userSignature, timestamp, int(sessionTimeout.Seconds())).Scan(&earlierReturns).Error
+	if err != nil {
+		return false, time.Time{}, fmt.Errorf("failed to count earlier sessions: %w", err)
+	}
+
+	return earlierReturns == 0, firstEvent.Timestamp, nil
+}
+
 // checkIsNewEventVisitor checks if this is the first time a visitor triggers a specific custom event
-func checkIsNewEventVisitor(db *gorm.DB, websiteID uint, userSignature, eventName string, timestamp time.Time) (bool, error) {
+// (or downloads a specific file)
+func checkIsNewEventVisitor(db *gorm.DB, websiteID uint, userSignature string, eventType EventType, eventName string, timestamp time.Time) (bool, error) {
 	var count int64
 	err := db.Model(&Event{}).
 		Where("website_id = ? AND user_signature = ? AND event_type = ? AND custom_event_name = ? AND timestamp < ?",
-			websiteID, userSignature, EventTypeCustomEvent, eventName, timestamp).
+			websiteID, userSignature, eventType, eventName, timestamp).
 		Count(&count).Error
 	if err != nil {
 		return false, fmt.Errorf("failed to check previous custom event: %w", err)
@@ -269,9 +405,8 @@ func checkIsNewEventVisitor(db *gorm.DB, websiteID uint, userSignature, eventNam
 	return count == 0, nil
 }
 
-func checkIsExitEvent(db *gorm.DB, websiteID uint, userSignature string, eventType EventType, timestamp time.Time) (bool, error) {
-	sessionTimeout := config.GetConfig().SessionTimeoutSeconds
-	endTime := timestamp.Add(time.Duration(sessionTimeout) * time.Second)
+func checkIsExitEvent(db *gorm.DB, websiteID uint, userSignature string, eventType EventType, timestamp time.Time, sessionTimeout time.Duration) (bool, error) {
+	endTime := timestamp.Add(sessionTimeout)
 
 	var nextEventCount int64
 	err := db.Model(&Event{}).
@@ -282,9 +417,47 @@ func checkIsExitEvent(db *gorm.DB, websiteID uint, userSignature string, eventTy
 	return nextEventCount == 0, err
 }
 
-func getUTMParam(parsedURL *url.URL, param string) string {
-	if value := parsedURL.Query().Get(param); value != "" {
+// sessionTimeouts holds the per-website session timeouts in minutes. They're
+// read once per batch at processing time, so changing a timeout only affects
+// newly processed events.
+type sessionTimeouts map[uint]int
+
+func loadSessionTimeouts(db *gorm.DB) sessionTimeouts {
+	return settings.GetSessionTimeouts(db)
+}
+
+// forWebsite returns the website's session timeout, or the global default
+func (t sessionTimeouts) forWebsite(websiteID uint) time.Duration {
+	if minutes := t[websiteID]; minutes > 0 {
+		return time.Duration(minutes) * time.Minute
+	}
+	return time.Duration(config.GetConfig().SessionTimeoutSeconds) * time.Second
+}
+
+// utmFromURL returns the utm_source, utm_term and utm_content query
+// parameters of rawURL, each "" when absent
+func utmFromURL(rawURL string, normalize bool) (source, term, content string) {
+	parsedURL, err := url.Parse(rawURL)
+	if err != nil {
+		return "", "", ""
+	}
+	query := parsedURL.Query()
+	return normalizeUTMValue(query.Get("utm_source"), normalize),
+		normalizeUTMValue(query.Get("utm_term"), normalize),
+		normalizeUTMValue(query.Get("utm_content"), normalize)
+}
+
+func getUTMParam(parsedURL *url.URL, param string, normalize bool) string {
+	if value := normalizeUTMValue(parsedURL.Query().Get(param), normalize); value != "" {
 		return value
 	}
 	return EmptyUTMAttr
 }
+
+// normalizeUTMValue trims and lowercases a UTM value when normalize is set
+func normalizeUTMValue(value string, normalize bool) string {
+	if !normalize {
+		return value
+	}
+	return strings.ToLower(strings.TrimSpace(value))
+}
@@ -0,0 +1,91 @@
+package events_test
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"fusionaly/internal/events"
+	"fusionaly/internal/testsupport"
+)
+
+func TestAdaptiveBatchSize(t *testing.T) {
+	tests := []struct {
+		name     string
+		backlog  int64
+		baseSize int
+		maxSize  int
+		want     int
+	}{
+		{"empty backlog", 0, 100, 1000, 100},
+		{"small backlog keeps the base size", 500, 100, 1000, 100},
+		{"large backlog drains in about ten batches", 5000, 100, 1000, 500},
+		{"rounds up", 5001, 100, 1000, 501},
+		{"capped at the max size", 50000, 100, 1000, 1000},
+		{"max below base", 50000, 100, 10, 100},
+		{"invalid base", 5, 0, 0, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, events.AdaptiveBatchSize(tt.backlog, tt.baseSize, tt.maxSize))
+		})
+	}
+}
+
+func TestProcessUnprocessedEventsBacklogBatches(t *testing.T) {
+	dbManager, _ := testsupport.SetupTestDBManager(t)
+	db := dbManager.GetConnection()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	const backlogSize = 1000
+
+	// process queues a fresh backlog and drains it with the given batch size
+	process := func(t *testing.T, batchSize int) *events.EventProcessingResult {
+		t.Helper()
+		testsupport.CleanAllTables(db)
+		website := testsupport.CreateTestWebsite(db, "backlog.com")
+
+		now := time.Now().UTC()
+		backlog := make([]events.IngestedEvent, backlogSize)
+		for i := range backlog {
+			backlog[i] = events.IngestedEvent{
+				WebsiteID:        website.ID,
+				UserSignature:    fmt.Sprintf("visitor-%d", i),
+				Hostname:         website.Domain,
+				Pathname:         "/",
+				RawURL:           "https://" + website.Domain + "/",
+				ReferrerHostname: events.DirectOrUnknownReferrer,
+				EventType:        events.EventTypePageView,
+				Timestamp:        now,
+				UserAgent:        "Mozilla/5.0 (test)",
+				Country:          "US",
+				CreatedAt:        now,
+			}
+		}
+		require.NoError(t, db.CreateInBatches(&backlog, 100).Error)
+
+		result, err := events.ProcessUnprocessedEvents(dbManager, logger, batchSize)
+		require.NoError(t, err)
+		require.Len(t, result.ProcessedEvents, backlogSize)
+		return result
+	}
+
+	fixed := process(t, 10)
+	assert.Equal(t, 100, fixed.Batches)
+
+	batchSize := events.AdaptiveBatchSize(backlogSize, 10, 500)
+	assert.Equal(t, 100, batchSize)
+	adaptive := process(t, batchSize)
+	assert.Equal(t, 10, adaptive.Batches)
+	assert.Less(t, adaptive.Batches, fixed.Batches)
+
+	var unprocessed int64
+	require.NoError(t, db.Model(&events.IngestedEvent{}).Where("processed = 0").Count(&unprocessed).Error)
+	assert.Zero(t, unprocessed)
+}
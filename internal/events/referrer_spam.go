@@ -0,0 +1,64 @@
+package events
+
+import (
+	_ "embed"
+	"strings"
+)
+
+//go:embed referrer_spam.txt
+var referrerSpamList string
+
+// referrerSpamDomains is the embedded referrer-spam blocklist
+var referrerSpamDomains = parseReferrerSpamDomains(referrerSpamList)
+
+// parseReferrerSpamDomains reads one domain per line, skipping blanks and # comments
+func parseReferrerSpamDomains(list string) map[string]bool {
+	domains := make(map[string]bool)
+	for _, line := range strings.Split(list, "\n") {
+		if domain := normalizeSpamDomain(line); domain != "" && !strings.HasPrefix(domain, "#") {
+			domains[domain] = true
+		}
+	}
+	return domains
+}
+
+func normalizeSpamDomain(domain string) string {
+	return strings.TrimPrefix(strings.ToLower(strings.TrimSpace(domain)), "www.")
+}
+
+// IsSpamReferrer reports whether host, or a domain it is a subdomain of, is on
+// the embedded referrer-spam blocklist.
+func IsSpamReferrer(host string) bool {
+	return isSpamReferrerWithDomains(host, nil)
+}
+
+// parseSpamDomains normalizes user-defined spam domains for isSpamReferrerWithDomains
+func parseSpamDomains(domains []string) map[string]bool {
+	parsed := make(map[string]bool, len(domains))
+	for _, domain := range domains {
+		parsed[normalizeSpamDomain(domain)] = true
+	}
+	return parsed
+}
+
+// isSpamReferrerWithDomains extends IsSpamReferrer with user-defined domains
+// parsed by parseSpamDomains.
+func isSpamReferrerWithDomains(host string, extraDomains map[string]bool) bool {
+	host = normalizeSpamDomain(host)
+	if host == "" || host == DirectOrUnknownReferrer {
+		return false
+	}
+
+	// Walk up the labels so sub.semalt.com matches semalt.com
+	for candidate := host; candidate != ""; {
+		if referrerSpamDomains[candidate] || extraDomains[candidate] {
+			return true
+		}
+		_, parent, found := strings.Cut(candidate, ".")
+		if !found || !strings.Contains(parent, ".") {
+			return false
+		}
+		candidate = parent
+	}
+	return false
+}
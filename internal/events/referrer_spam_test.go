@@ -0,0 +1,81 @@
+package events_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"fusionaly/internal/events"
+	"fusionaly/internal/settings"
+	"fusionaly/internal/testsupport"
+)
+
+func TestIsSpamReferrer(t *testing.T) {
+	tests := []struct {
+		name     string
+		host     string
+		expected bool
+	}{
+		{"Blocklisted domain", "semalt.com", true},
+		{"Blocklisted domain with www", "www.darodar.com", true},
+		{"Subdomain of blocklisted domain", "forum.buttons-for-website.com", true},
+		{"Mixed case", "ILoveVitaly.com", true},
+		{"Legitimate referrer", "google.com", false},
+		{"Lookalike suffix", "notsemalt.com", false},
+		{"Direct traffic", events.DirectOrUnknownReferrer, false},
+		{"Empty", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, events.IsSpamReferrer(tt.host))
+		})
+	}
+}
+
+func TestCollectEventReferrerSpamFiltering(t *testing.T) {
+	dbManager, logger := testsupport.SetupTestDBManager(t)
+	db := dbManager.GetConnection()
+	testsupport.CleanAllTables(db)
+	testsupport.CreateTestWebsite(db, "example.com")
+	require.NoError(t, settings.SetupDefaultSettings(db))
+
+	collect := func(t *testing.T, referrer string) string {
+		t.Helper()
+		db.Exec("DELETE FROM ingested_events")
+
+		require.NoError(t, events.CollectEvent(dbManager, logger, &events.CollectEventInput{
+			IPAddress:   "203.0.113.5",
+			UserAgent:   "Mozilla/5.0 (test)",
+			ReferrerURL: referrer,
+			EventType:   events.EventTypePageView,
+			Timestamp:   time.Now().UTC(),
+			RawUrl:      "https://example.com/page",
+		}))
+
+		var event events.IngestedEvent
+		require.NoError(t, db.First(&event).Error)
+		return event.ReferrerHostname
+	}
+
+	t.Run("records blocklisted referrers as direct", func(t *testing.T) {
+		assert.Equal(t, events.DirectOrUnknownReferrer, collect(t, "https://semalt.com/"))
+		assert.Equal(t, "news.ycombinator.com", collect(t, "https://news.ycombinator.com/item"))
+	})
+
+	t.Run("applies custom domains", func(t *testing.T) {
+		require.NoError(t, settings.SaveReferrerSpamDomains(db, "spammy-seo.com\nfree-traffic.xyz"))
+
+		assert.Equal(t, events.DirectOrUnknownReferrer, collect(t, "https://blog.spammy-seo.com/post"))
+		assert.Equal(t, events.DirectOrUnknownReferrer, collect(t, "https://free-traffic.xyz/"))
+	})
+
+	t.Run("keeps spam referrers when filtering is disabled", func(t *testing.T) {
+		require.NoError(t, settings.SaveReferrerSpamFilteringEnabled(db, false))
+		defer settings.SaveReferrerSpamFilteringEnabled(db, true)
+
+		assert.Equal(t, "semalt.com", collect(t, "https://semalt.com/"))
+	})
+}
@@ -0,0 +1,218 @@
+package events
+
+import (
+	"fmt"
+	"time"
+
+	"log/slog"
+
+	"github.com/karloscodes/cartridge"
+	"github.com/karloscodes/cartridge/sqlite"
+	"gorm.io/gorm"
+)
+
+const reprocessBatchSize = 100
+
+// reprocessChunk is how much of a reprocess window each write transaction covers
+const reprocessChunk = 24 * time.Hour
+
+// aggregateTables lists the half-hour bucketed *_stats tables derived from events.
+var aggregateTables = []string{
+	"site_stats",
+	"page_stats",
+	"ref_stats",
+	"device_stats",
+	"device_model_stats",
+	"browser_stats",
+	"browser_version_stats",
+	"os_stats",
+	"country_stats",
+	"language_stats",
+	"screen_stats",
+	"region_stats",
+	"city_stats",
+	"utm_stats",
+	"event_stats",
+	"query_param_stats",
+	"download_stats",
+	"scroll_stats",
+}
+
+// ReprocessResult summarizes a reprocess run.
+type ReprocessResult struct {
+	From            time.Time
+	To              time.Time
+	RawEvents       int
+	ProcessedEvents int
+}
+
+// ReprocessEvents re-derives dimensions for a website's raw ingested events in
+// [from, to) using the current processing logic and rewrites the affected
+// aggregates. The window is widened to half-hour boundaries so whole buckets
+// are rebuilt, which makes running it repeatedly idempotent. Only raw events
+// still within the ingested retention period can be replayed, so the window
+// is clamped to start at the oldest of them. It is rebuilt a day at a time,
+// each day in its own write transaction.
+func ReprocessEvents(dbManager cartridge.DBManager, logger *slog.Logger, websiteID uint, from, to time.Time) (*ReprocessResult, error) {
+	db := dbManager.GetConnection()
+	from, to, err := replayableWindow(db, websiteID, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &ReprocessResult{From: from, To: to}
+	for chunkStart := from; chunkStart.Before(to); chunkStart = chunkStart.Add(reprocessChunk) {
+		chunkEnd := chunkStart.Add(reprocessChunk)
+		if chunkEnd.After(to) {
+			chunkEnd = to
+		}
+
+		err := sqlite.PerformWrite(logger, db, func(tx *gorm.DB) error {
+			return reprocessChunkEvents(tx, logger, websiteID, chunkStart, chunkEnd, result)
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	logger.Info("Reprocessed events",
+		slog.Uint64("website_id", uint64(websiteID)),
+		slog.Time("from", from),
+		slog.Time("to", to),
+		slog.Int("raw_events", result.RawEvents),
+		slog.Int("processed", result.ProcessedEvents))
+
+	return result, nil
+}
+
+// reprocessChunkEvents rebuilds one chunk of a reprocess window, adding its
+// counts to result.
+func reprocessChunkEvents(tx *gorm.DB, logger *slog.Logger, websiteID uint, from, to time.Time, result *ReprocessResult) error {
+	var rawEvents []IngestedEvent
+	if err := tx.Where("website_id = ? AND processed = 1 AND timestamp >= ? AND timestamp < ?", websiteID, from, to).
+		Order("timestamp asc").
+		Find(&rawEvents).Error; err != nil {
+		return fmt.Errorf("failed to fetch raw events: %w", err)
+	}
+
+	for _, table := range aggregateTables {
+		if err := tx.Exec("DELETE FROM "+table+" WHERE website_id = ? AND hour >= ? AND hour < ?", websiteID, from, to).Error; err != nil {
+			return fmt.Errorf("failed to clear %s: %w", table, err)
+		}
+	}
+	if err := tx.Where("website_id = ? AND timestamp >= ? AND timestamp < ?", websiteID, from, to).Delete(&Event{}).Error; err != nil {
+		return fmt.Errorf("failed to clear events: %w", err)
+	}
+
+	// Replay in timestamp order so visitor/session checks see prior events
+	for i := 0; i < len(rawEvents); i += reprocessBatchSize {
+		end := min(i+reprocessBatchSize, len(rawEvents))
+		processed, _, err := processEventBatch(tx, logger, rawEvents[i:end])
+		if err != nil {
+			return fmt.Errorf("failed to reprocess events: %w", err)
+		}
+		result.ProcessedEvents += len(processed)
+	}
+	result.RawEvents += len(rawEvents)
+
+	// Flow transitions are hourly and recomputed from events, so clear whole
+	// hours to drop transitions that no longer exist
+	firstHour := from.Truncate(time.Hour)
+	if err := tx.Exec("DELETE FROM flow_transition_stats WHERE website_id = ? AND hour >= ? AND hour < ?", websiteID, firstHour, to).Error; err != nil {
+		return fmt.Errorf("failed to clear flow_transition_stats: %w", err)
+	}
+	for hour := firstHour; hour.Before(to); hour = hour.Add(time.Hour) {
+		if err := ComputeFlowTransitionsForWebsiteHour(tx, logger, websiteID, hour, 5); err != nil {
+			return fmt.Errorf("failed to recompute flow transitions: %w", err)
+		}
+	}
+	return nil
+}
+
+// CountReprocessableEvents returns how many raw events ReprocessEvents would
+// replay for the same website and window, so callers can report it up front.
+func CountReprocessableEvents(db *gorm.DB, websiteID uint, from, to time.Time) (int64, error) {
+	from, to, err := replayableWindow(db, websiteID, from, to)
+	if err != nil {
+		return 0, err
+	}
+	if !from.Before(to) {
+		return 0, nil
+	}
+
+	var count int64
+	if err := db.Model(&IngestedEvent{}).
+		Where("website_id = ? AND processed = 1 AND timestamp >= ? AND timestamp < ?", websiteID, from, to).
+		Count(&count).Error; err != nil {
+		return 0, fmt.Errorf("failed to count raw events: %w", err)
+	}
+	return count, nil
+}
+
+// EarliestIngestedEventTime returns the timestamp of a website's oldest raw
+// event, or the zero time when there are none.
+func EarliestIngestedEventTime(db *gorm.DB, websiteID uint) (time.Time, error) {
+	var first IngestedEvent
+	err := db.Where("website_id = ?", websiteID).Order("timestamp asc").Limit(1).Find(&first).Error
+	if err != nil {
+		return time.Time{}, err
+	}
+	return first.Timestamp, nil
+}
+
+// replayableWindow widens [from, to) like reprocessWindow and moves its start
+// up to the oldest raw event of the website. Rebuilding clears a bucket's
+// aggregates and events before replaying it, so buckets whose raw events were
+// already purged must be left alone. That includes the oldest raw event's own
+// bucket when it still holds events older than it. The returned window is
+// empty when there is nothing left to replay.
+func replayableWindow(db *gorm.DB, websiteID uint, from, to time.Time) (time.Time, time.Time, error) {
+	from, to, err := reprocessWindow(from, to)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+
+	earliest, err := EarliestIngestedEventTime(db, websiteID)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("failed to find oldest raw event: %w", err)
+	}
+	if earliest.IsZero() {
+		return from, from, nil
+	}
+
+	earliest = earliest.UTC()
+	start := truncateToHalfHour(earliest)
+	if start.Before(earliest) {
+		var purged int64
+		if err := db.Model(&Event{}).
+			Where("website_id = ? AND timestamp >= ? AND timestamp < ?", websiteID, start, earliest).
+			Count(&purged).Error; err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("failed to check for purged raw events: %w", err)
+		}
+		if purged > 0 {
+			start = start.Add(30 * time.Minute)
+		}
+	}
+
+	if from.Before(start) {
+		from = start
+	}
+	if !from.Before(to) {
+		return to, to, nil
+	}
+	return from, to, nil
+}
+
+// reprocessWindow widens [from, to) to half-hour boundaries in UTC.
+func reprocessWindow(from, to time.Time) (time.Time, time.Time, error) {
+	from = truncateToHalfHour(from.UTC())
+	if alignedTo := truncateToHalfHour(to.UTC()); alignedTo.Before(to) {
+		to = alignedTo.Add(30 * time.Minute)
+	} else {
+		to = alignedTo
+	}
+	if !from.Before(to) {
+		return time.Time{}, time.Time{}, fmt.Errorf("invalid reprocess window: from must be before to")
+	}
+	return from, to, nil
+}
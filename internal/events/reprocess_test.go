@@ -0,0 +1,173 @@
+package events_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"fusionaly/internal/events"
+	"fusionaly/internal/testsupport"
+)
+
+func TestReprocessEvents(t *testing.T) {
+	dbManager, logger, website := testsupport.SetupTestDBManagerWithWebsite(t, "reprocess.com")
+	db := dbManager.GetConnection()
+
+	base := time.Date(2025, 3, 10, 10, 5, 0, 0, time.UTC)
+	chromeUA := "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36"
+
+	for i, path := range []string{"/", "/pricing", "/signup"} {
+		ts := base.Add(time.Duration(i) * time.Minute)
+		require.NoError(t, db.Create(&events.IngestedEvent{
+			WebsiteID:     website.ID,
+			UserSignature: "visitor-1",
+			Hostname:      website.Domain,
+			Pathname:      path,
+			RawURL:        "https://" + website.Domain + path,
+			EventType:     events.EventTypePageView,
+			Timestamp:     ts,
+			UserAgent:     chromeUA,
+			Country:       "US",
+			CreatedAt:     ts,
+		}).Error)
+	}
+	require.NoError(t, testsupport.ProcessAllTestEvents(dbManager, logger))
+
+	var expectedBrowser string
+	require.NoError(t, db.Raw("SELECT browser FROM browser_stats WHERE website_id = ?", website.ID).Scan(&expectedBrowser).Error)
+	require.NotEmpty(t, expectedBrowser)
+
+	var pageViews int64
+	require.NoError(t, db.Raw("SELECT SUM(page_views) FROM site_stats WHERE website_id = ?", website.ID).Scan(&pageViews).Error)
+	require.Equal(t, int64(3), pageViews)
+
+	t.Run("reflects the current normalization rules", func(t *testing.T) {
+		// Simulate aggregates written by an older, buggy browser detection rule
+		require.NoError(t, db.Exec("UPDATE browser_stats SET browser = ? WHERE website_id = ?", "Legacy Browser", website.ID).Error)
+
+		result, err := events.ReprocessEvents(dbManager, logger, website.ID, base.Add(-time.Hour), base.Add(time.Hour))
+		require.NoError(t, err)
+		assert.Equal(t, 3, result.RawEvents)
+		assert.Equal(t, 3, result.ProcessedEvents)
+
+		var browsers []string
+		require.NoError(t, db.Raw("SELECT DISTINCT browser FROM browser_stats WHERE website_id = ?", website.ID).Scan(&browsers).Error)
+		assert.Equal(t, []string{expectedBrowser}, browsers)
+	})
+
+	t.Run("is idempotent", func(t *testing.T) {
+		for i := 0; i < 2; i++ {
+			_, err := events.ReprocessEvents(dbManager, logger, website.ID, base.Add(-time.Hour), base.Add(time.Hour))
+			require.NoError(t, err)
+		}
+
+		var reprocessedPageViews int64
+		require.NoError(t, db.Raw("SELECT SUM(page_views) FROM site_stats WHERE website_id = ?", website.ID).Scan(&reprocessedPageViews).Error)
+		assert.Equal(t, pageViews, reprocessedPageViews)
+
+		var eventCount int64
+		require.NoError(t, db.Model(&events.Event{}).Where("website_id = ?", website.ID).Count(&eventCount).Error)
+		assert.Equal(t, int64(3), eventCount)
+	})
+
+	t.Run("regenerates site_stats identically for unchanged settings", func(t *testing.T) {
+		type siteStatRow struct {
+			Hour              string
+			PageViews         int
+			Visitors          int
+			NewVisitors       int
+			ReturningVisitors int
+			Sessions          int
+			BounceCount       int
+		}
+		query := `SELECT hour, page_views, visitors, new_visitors, returning_visitors, sessions, bounce_count
+			FROM site_stats WHERE website_id = ? ORDER BY hour`
+
+		var before []siteStatRow
+		require.NoError(t, db.Raw(query, website.ID).Scan(&before).Error)
+		require.NotEmpty(t, before)
+
+		_, err := events.ReprocessEvents(dbManager, logger, website.ID, base.Add(-time.Hour), base.Add(time.Hour))
+		require.NoError(t, err)
+
+		var after []siteStatRow
+		require.NoError(t, db.Raw(query, website.ID).Scan(&after).Error)
+		assert.Equal(t, before, after)
+	})
+
+	t.Run("counts the events a run would replay", func(t *testing.T) {
+		count, err := events.CountReprocessableEvents(db, website.ID, base.Add(-time.Hour), base.Add(time.Hour))
+		require.NoError(t, err)
+		assert.Equal(t, int64(3), count)
+
+		// The window widens to half-hour buckets, so 10:06 still covers the 10:05 event
+		count, err = events.CountReprocessableEvents(db, website.ID, base.Add(time.Minute), base.Add(90*time.Second))
+		require.NoError(t, err)
+		assert.Equal(t, int64(3), count)
+
+		count, err = events.CountReprocessableEvents(db, website.ID, base.Add(time.Hour), base.Add(2*time.Hour))
+		require.NoError(t, err)
+		assert.Zero(t, count)
+	})
+
+	t.Run("finds the oldest raw event", func(t *testing.T) {
+		earliest, err := events.EarliestIngestedEventTime(db, website.ID)
+		require.NoError(t, err)
+		assert.True(t, earliest.Equal(base), "got %s", earliest)
+
+		earliest, err = events.EarliestIngestedEventTime(db, website.ID+1000)
+		require.NoError(t, err)
+		assert.True(t, earliest.IsZero())
+	})
+
+	t.Run("rejects an inverted window", func(t *testing.T) {
+		_, err := events.ReprocessEvents(dbManager, logger, website.ID, base.Add(time.Hour), base)
+		assert.Error(t, err)
+	})
+
+	t.Run("clears flow transitions that no longer exist", func(t *testing.T) {
+		now := time.Now().UTC()
+		require.NoError(t, db.Exec(`INSERT INTO flow_transition_stats
+			(website_id, step_position, source_page, target_page, hour, transitions, created_at, updated_at)
+			VALUES (?, 1, ?, ?, ?, 1, ?, ?)`,
+			website.ID, website.Domain+"/removed", website.Domain+"/", base.Truncate(time.Hour), now, now).Error)
+
+		_, err := events.ReprocessEvents(dbManager, logger, website.ID, base.Add(-time.Hour), base.Add(time.Hour))
+		require.NoError(t, err)
+
+		var sources []string
+		require.NoError(t, db.Raw("SELECT DISTINCT source_page FROM flow_transition_stats WHERE website_id = ? ORDER BY source_page", website.ID).
+			Scan(&sources).Error)
+		assert.Equal(t, []string{website.Domain + "/", website.Domain + "/pricing"}, sources)
+	})
+
+	t.Run("leaves history older than the raw events alone", func(t *testing.T) {
+		// Processed events whose raw events were already purged
+		for _, ts := range []time.Time{base.Add(-48 * time.Hour), base.Add(-4 * time.Minute)} {
+			require.NoError(t, db.Create(&events.Event{
+				WebsiteID:     website.ID,
+				UserSignature: "visitor-0",
+				Hostname:      website.Domain,
+				Pathname:      "/",
+				EventType:     events.EventTypePageView,
+				Timestamp:     ts,
+				CreatedAt:     ts,
+			}).Error)
+		}
+
+		count, err := events.CountReprocessableEvents(db, website.ID, base.Add(-72*time.Hour), base.Add(time.Hour))
+		require.NoError(t, err)
+		assert.Zero(t, count, "the oldest raw event shares its bucket with purged events")
+
+		result, err := events.ReprocessEvents(dbManager, logger, website.ID, base.Add(-72*time.Hour), base.Add(time.Hour))
+		require.NoError(t, err)
+		assert.True(t, result.From.Equal(time.Date(2025, 3, 10, 10, 30, 0, 0, time.UTC)), "got %s", result.From)
+		assert.Zero(t, result.RawEvents)
+
+		var eventCount int64
+		require.NoError(t, db.Model(&events.Event{}).Where("website_id = ?", website.ID).Count(&eventCount).Error)
+		assert.Equal(t, int64(5), eventCount)
+	})
+}
@@ -0,0 +1,81 @@
+package events
+
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// purgeBatchSize bounds each DELETE so purging doesn't hold the write lock for long.
+const purgeBatchSize = 1000
+
+// PurgeResult counts the raw rows removed (or eligible for removal) by a purge.
+type PurgeResult struct {
+	Events         int64
+	IngestedEvents int64
+}
+
+// keptRawEvents matches the raw events a purge keeps regardless of age.
+// Revenue is only ever read from raw revenue:purchased events, so purging them
+// would change the revenue totals.
+const keptRawEvents = "event_type = ? AND LOWER(custom_event_name) = 'revenue:purchased'"
+
+// CountPurgeableRawEvents reports how many rows PurgeRawEvents would delete.
+func CountPurgeableRawEvents(db *gorm.DB, before time.Time) (PurgeResult, error) {
+	var result PurgeResult
+	if err := db.Model(&Event{}).Where("timestamp < ? AND NOT ("+keptRawEvents+")", before, EventTypeCustomEvent).Count(&result.Events).Error; err != nil {
+		return result, fmt.Errorf("failed to count raw events: %w", err)
+	}
+	if err := db.Model(&IngestedEvent{}).Where("processed = 1 AND created_at < ?", before).Count(&result.IngestedEvents).Error; err != nil {
+		return result, fmt.Errorf("failed to count ingested events: %w", err)
+	}
+	return result, nil
+}
+
+// PurgeRawEvents deletes raw events older than before, along with processed
+// ingested events. The hourly *_stats aggregates are left untouched, so visitor,
+// page view, referrer, device and custom event counts stay the same, and
+// revenue:purchased events are kept so revenue reports don't change. Reports
+// that read other raw events (visit duration, 404 pages, flows, funnels,
+// retention, realtime) lose history past the cutoff. Unprocessed ingested
+// events are always kept so no data is lost before aggregation.
+func PurgeRawEvents(db *gorm.DB, before time.Time) (PurgeResult, error) {
+	var result PurgeResult
+
+	deleted, err := deleteInBatches(db, `
+		DELETE FROM events WHERE id IN (
+			SELECT id FROM events WHERE timestamp < ? AND NOT (`+keptRawEvents+`) LIMIT ?
+		)`, before, EventTypeCustomEvent)
+	result.Events = deleted
+	if err != nil {
+		return result, fmt.Errorf("failed to purge raw events: %w", err)
+	}
+
+	deleted, err = deleteInBatches(db, `
+		DELETE FROM ingested_events WHERE id IN (
+			SELECT id FROM ingested_events WHERE processed = 1 AND created_at < ? LIMIT ?
+		)`, before)
+	result.IngestedEvents = deleted
+	if err != nil {
+		return result, fmt.Errorf("failed to purge ingested events: %w", err)
+	}
+
+	return result, nil
+}
+
+// deleteInBatches runs a DELETE taking the given arguments followed by a
+// limit until it affects fewer rows than the batch size.
+func deleteInBatches(db *gorm.DB, query string, args ...interface{}) (int64, error) {
+	total := int64(0)
+	for {
+		result := db.Exec(query, append(args, purgeBatchSize)...)
+		if result.Error != nil {
+			return total, result.Error
+		}
+		total += result.RowsAffected
+		if result.RowsAffected < purgeBatchSize {
+			return total, nil
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package events_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"fusionaly/internal/analytics"
+	"fusionaly/internal/events"
+	"fusionaly/internal/testsupport"
+	"fusionaly/internal/timeframe"
+)
+
+func TestPurgeRawEvents(t *testing.T) {
+	dbManager, logger, website := testsupport.SetupTestDBManagerWithWebsite(t, "retention.com")
+	db := dbManager.GetConnection()
+
+	ingest := func(visitor string, ts time.Time) *events.IngestedEvent {
+		event := &events.IngestedEvent{
+			WebsiteID:     website.ID,
+			UserSignature: visitor,
+			Hostname:      website.Domain,
+			Pathname:      "/",
+			RawURL:        "https://" + website.Domain + "/",
+			EventType:     events.EventTypePageView,
+			Timestamp:     ts,
+			UserAgent:     "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36",
+			Country:       "US",
+			CreatedAt:     ts,
+		}
+		require.NoError(t, db.Create(event).Error)
+		return event
+	}
+
+	old := time.Date(2024, 7, 1, 10, 0, 0, 0, time.UTC)
+	for i := 0; i < 5; i++ {
+		ingest(fmt.Sprintf("visitor-%d", i), old.Add(time.Duration(i)*time.Minute))
+	}
+	purchase := ingest("visitor-0", old.Add(10*time.Minute))
+	purchase.EventType = events.EventTypeCustomEvent
+	purchase.CustomEventName = "revenue:purchased"
+	purchase.CustomEventMeta = `{"price":4900,"currency":"USD"}`
+	require.NoError(t, db.Save(purchase).Error)
+	ingest("recent-visitor", time.Now().UTC().Add(-time.Hour))
+	require.NoError(t, testsupport.ProcessAllTestEvents(dbManager, logger))
+
+	// Ingested after the processor ran, so it must survive the purge
+	pending := ingest("late-visitor", old.Add(time.Hour))
+
+	timeFrame, err := timeframe.NewTimeFrame(timeframe.TimeFrameParams{
+		FromTime:      time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC),
+		ToTime:        time.Date(2024, 7, 31, 23, 59, 59, 0, time.UTC),
+		TimeFrameSize: timeframe.DailyTimeFrame,
+	}, time.UTC)
+	require.NoError(t, err)
+	params := analytics.NewWebsiteScopedQueryParams(timeFrame, int(website.ID))
+
+	pageViewsBefore, err := analytics.GetTotalPageViewsInTimeFrame(db, params)
+	require.NoError(t, err)
+	visitorsBefore, err := analytics.GetTotalVisitorsInTimeFrame(db, params)
+	require.NoError(t, err)
+	require.Equal(t, int64(5), pageViewsBefore)
+	revenueBefore, err := analytics.GetRevenueMetrics(db, params)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), revenueBefore.TotalSales)
+
+	cutoff := time.Now().UTC().AddDate(0, 0, -90)
+
+	count, err := events.CountPurgeableRawEvents(db, cutoff)
+	require.NoError(t, err)
+	assert.Equal(t, events.PurgeResult{Events: 5, IngestedEvents: 6}, count)
+
+	result, err := events.PurgeRawEvents(db, cutoff)
+	require.NoError(t, err)
+	assert.Equal(t, count, result)
+
+	t.Run("aggregate-based metrics are intact", func(t *testing.T) {
+		pageViews, err := analytics.GetTotalPageViewsInTimeFrame(db, params)
+		require.NoError(t, err)
+		assert.Equal(t, pageViewsBefore, pageViews)
+
+		visitors, err := analytics.GetTotalVisitorsInTimeFrame(db, params)
+		require.NoError(t, err)
+		assert.Equal(t, visitorsBefore, visitors)
+	})
+
+	t.Run("revenue events are kept", func(t *testing.T) {
+		revenue, err := analytics.GetRevenueMetrics(db, params)
+		require.NoError(t, err)
+		assert.Equal(t, revenueBefore, revenue)
+	})
+
+	t.Run("only raw rows past the cutoff are removed", func(t *testing.T) {
+		var rawEvents []events.Event
+		require.NoError(t, db.Where("website_id = ?", website.ID).Order("timestamp").Find(&rawEvents).Error)
+		require.Len(t, rawEvents, 2)
+		assert.Equal(t, "revenue:purchased", rawEvents[0].CustomEventName)
+		assert.True(t, rawEvents[1].Timestamp.After(cutoff))
+
+		var ingested []events.IngestedEvent
+		require.NoError(t, db.Where("website_id = ?", website.ID).Order("id").Find(&ingested).Error)
+		require.Len(t, ingested, 2)
+		assert.Equal(t, "recent-visitor", ingested[0].UserSignature)
+		assert.Equal(t, pending.ID, ingested[1].ID, "unprocessed events are never purged")
+	})
+
+	t.Run("is idempotent", func(t *testing.T) {
+		result, err := events.PurgeRawEvents(db, cutoff)
+		require.NoError(t, err)
+		assert.Zero(t, result)
+	})
+}
@@ -0,0 +1,80 @@
+package events
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// sampleBuckets is the granularity of raw event sampling (1%).
+const sampleBuckets = 100
+
+// PruneUnsampledEvents deletes raw page view rows older than before that fall
+// outside the sample rate. Aggregates are already written at this point, so
+// they stay exact. Sampling is deterministic on the event ID, which makes
+// repeated runs idempotent. Custom events are always kept (revenue and
+// per-event visitor checks read them), as are error pages (the not-found
+// report reads them) and each visitor's first event so new-visitor detection
+// keeps working.
+//
+// Reports computed from raw page views rather than aggregates see only the
+// sampled ones past the grace period: visit duration, funnels, user flows,
+// entry/exit pairs, retention cohorts, revenue attribution to referrers and
+// UTM sources, UTM term and content goal conversions, subdomain and custom
+// property breakdowns, numeric event meta breakdowns and the daily visitors
+// webhook threshold.
+func PruneUnsampledEvents(db *gorm.DB, sampleRate float64, before time.Time) (int64, error) {
+	if sampleRate >= 1 {
+		return 0, nil
+	}
+	keepBuckets := int(math.Round(math.Max(sampleRate, 0) * sampleBuckets))
+
+	batchSize := 1000
+	totalDeleted := int64(0)
+	// Temp tables are private to a connection, so every statement runs on one
+	err := db.Connection(func(conn *gorm.DB) error {
+		// Each visitor's first event, materialized once rather than regrouped for
+		// every batch. Deletes never remove a first event, and events inserted
+		// after the snapshot (past maxID) are left for the next run.
+		var maxID uint
+		if err := conn.Raw(`SELECT COALESCE(MAX(id), 0) FROM events`).Scan(&maxID).Error; err != nil {
+			return fmt.Errorf("failed to read the last event id: %w", err)
+		}
+		if err := conn.Exec(`DROP TABLE IF EXISTS temp.first_visitor_events`).Error; err != nil {
+			return fmt.Errorf("failed to reset first visitor events: %w", err)
+		}
+		if err := conn.Exec(`CREATE TEMP TABLE first_visitor_events (id INTEGER PRIMARY KEY)`).Error; err != nil {
+			return fmt.Errorf("failed to create first visitor events: %w", err)
+		}
+		defer conn.Exec(`DROP TABLE IF EXISTS temp.first_visitor_events`)
+
+		if err := conn.Exec(`
+			INSERT INTO first_visitor_events (id)
+			SELECT MIN(id) FROM events WHERE id <= ? GROUP BY website_id, user_signature
+		`, maxID).Error; err != nil {
+			return fmt.Errorf("failed to collect first visitor events: %w", err)
+		}
+
+		for {
+			result := conn.Exec(`
+				DELETE FROM events WHERE id IN (
+					SELECT id FROM events
+					WHERE event_type = ? AND timestamp < ? AND id % ? >= ? AND status_code < 400
+					AND id <= ? AND id NOT IN (SELECT id FROM first_visitor_events)
+					LIMIT ?
+				)`, EventTypePageView, before, sampleBuckets, keepBuckets, maxID, batchSize)
+			if result.Error != nil {
+				return fmt.Errorf("failed to prune unsampled events: %w", result.Error)
+			}
+
+			totalDeleted += result.RowsAffected
+			if result.RowsAffected < int64(batchSize) {
+				return nil
+			}
+		}
+	})
+
+	return totalDeleted, err
+}
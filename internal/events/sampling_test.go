@@ -0,0 +1,87 @@
+package events_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"fusionaly/internal/events"
+	"fusionaly/internal/testsupport"
+)
+
+func TestPruneUnsampledEvents(t *testing.T) {
+	dbManager, logger, website := testsupport.SetupTestDBManagerWithWebsite(t, "sampled.com")
+	db := dbManager.GetConnection()
+
+	base := time.Now().UTC().Add(-72 * time.Hour).Truncate(time.Hour)
+	for i := 0; i < 100; i++ {
+		ts := base.Add(time.Duration(i) * time.Minute)
+		require.NoError(t, db.Create(&events.IngestedEvent{
+			WebsiteID:     website.ID,
+			UserSignature: "visitor-1",
+			Hostname:      website.Domain,
+			Pathname:      "/",
+			RawURL:        "https://" + website.Domain + "/",
+			EventType:     events.EventTypePageView,
+			Timestamp:     ts,
+			UserAgent:     "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.0 Safari/605.1.15",
+			Country:       "US",
+			CreatedAt:     ts,
+		}).Error)
+	}
+	require.NoError(t, db.Create(&events.IngestedEvent{
+		WebsiteID:       website.ID,
+		UserSignature:   "visitor-1",
+		Hostname:        website.Domain,
+		Pathname:        "/",
+		EventType:       events.EventTypeCustomEvent,
+		CustomEventName: "signup",
+		Timestamp:       base.Add(2 * time.Hour),
+		UserAgent:       "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.0 Safari/605.1.15",
+		CreatedAt:       base.Add(2 * time.Hour),
+	}).Error)
+	require.NoError(t, testsupport.ProcessAllTestEvents(dbManager, logger))
+
+	t.Run("keeps aggregates exact while raw rows are a sampled subset", func(t *testing.T) {
+		deleted, err := events.PruneUnsampledEvents(db, 0.5, time.Now().UTC().Add(-24*time.Hour))
+		require.NoError(t, err)
+		assert.Equal(t, int64(50), deleted)
+
+		var pageViews int64
+		require.NoError(t, db.Raw("SELECT SUM(page_views) FROM site_stats WHERE website_id = ?", website.ID).Scan(&pageViews).Error)
+		assert.Equal(t, int64(100), pageViews)
+
+		var rawPageViews int64
+		require.NoError(t, db.Model(&events.Event{}).Where("website_id = ? AND event_type = ?", website.ID, events.EventTypePageView).Count(&rawPageViews).Error)
+		assert.Equal(t, int64(50), rawPageViews)
+
+		var rawCustomEvents int64
+		require.NoError(t, db.Model(&events.Event{}).Where("website_id = ? AND event_type = ?", website.ID, events.EventTypeCustomEvent).Count(&rawCustomEvents).Error)
+		assert.Equal(t, int64(1), rawCustomEvents, "custom events are never sampled out")
+	})
+
+	t.Run("is idempotent", func(t *testing.T) {
+		deleted, err := events.PruneUnsampledEvents(db, 0.5, time.Now().UTC().Add(-24*time.Hour))
+		require.NoError(t, err)
+		assert.Zero(t, deleted)
+	})
+
+	t.Run("keeps the visitor's first event", func(t *testing.T) {
+		deleted, err := events.PruneUnsampledEvents(db, 0, time.Now().UTC().Add(-24*time.Hour))
+		require.NoError(t, err)
+		assert.Equal(t, int64(49), deleted)
+
+		var remaining []events.Event
+		require.NoError(t, db.Where("website_id = ? AND event_type = ?", website.ID, events.EventTypePageView).Find(&remaining).Error)
+		require.Len(t, remaining, 1)
+		assert.True(t, remaining[0].Timestamp.Equal(base))
+	})
+
+	t.Run("does nothing at full sample rate", func(t *testing.T) {
+		deleted, err := events.PruneUnsampledEvents(db, 1, time.Now().UTC())
+		require.NoError(t, err)
+		assert.Zero(t, deleted)
+	})
+}
@@ -0,0 +1,86 @@
+package events
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ScrollEventName is the custom event name carrying a scroll depth in its
+// metadata, e.g. {"depth":75}. The SDK's "scroll:depth:<n>" events, which
+// send {"percentage":n}, are recognized as well.
+const ScrollEventName = "scroll"
+
+// ScrollDepthBuckets are the depths scroll_stats is aggregated into.
+var ScrollDepthBuckets = []int{25, 50, 75, 100}
+
+// isScrollEvent reports whether a custom event name reports scroll depth.
+func isScrollEvent(eventName string) bool {
+	return eventName == ScrollEventName || strings.HasPrefix(eventName, "scroll:depth")
+}
+
+// scrollDepthBucket rounds a scroll percentage down to its bucket; 0 means
+// the visitor didn't reach the first bucket.
+func scrollDepthBucket(depth float64) int {
+	bucket := 0
+	for _, b := range ScrollDepthBuckets {
+		if depth >= float64(b) {
+			bucket = b
+		}
+	}
+	return bucket
+}
+
+// scrollDepthFromMeta reads the depth bucket from a scroll event's metadata.
+func scrollDepthFromMeta(meta string) int {
+	if meta == "" {
+		return 0
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal([]byte(meta), &fields); err != nil {
+		return 0
+	}
+
+	for _, key := range []string{"depth", "percentage"} {
+		switch v := fields[key].(type) {
+		case float64:
+			return scrollDepthBucket(v)
+		case string:
+			if depth, err := strconv.ParseFloat(strings.TrimSuffix(v, "%"), 64); err == nil {
+				return scrollDepthBucket(depth)
+			}
+		}
+	}
+	return 0
+}
+
+// previousScrollDepth returns the deepest bucket the visitor already reached on
+// the same page earlier in the session, and the time of the event that was
+// counted for it, so only the session's max depth stays aggregated.
+func previousScrollDepth(db *gorm.DB, tempEvent *IngestedEvent, sessionTimeout time.Duration) (int, time.Time, error) {
+	since := tempEvent.Timestamp.Add(-sessionTimeout)
+
+	var previous []Event
+	err := db.Where("website_id = ? AND user_signature = ? AND hostname = ? AND pathname = ? AND event_type = ? AND (custom_event_name = ? OR custom_event_name LIKE ?) AND timestamp >= ? AND timestamp < ?",
+		tempEvent.WebsiteID, tempEvent.UserSignature, tempEvent.Hostname, tempEvent.Pathname,
+		EventTypeCustomEvent, ScrollEventName, "scroll:depth%", since, tempEvent.Timestamp).
+		Order("timestamp asc").
+		Find(&previous).Error
+	if err != nil {
+		return 0, time.Time{}, fmt.Errorf("failed to query previous scroll events: %w", err)
+	}
+
+	maxDepth := 0
+	var countedAt time.Time
+	for _, event := range previous {
+		if depth := scrollDepthFromMeta(event.CustomEventMeta); depth > maxDepth {
+			maxDepth = depth
+			countedAt = event.Timestamp
+		}
+	}
+	return maxDepth, countedAt, nil
+}
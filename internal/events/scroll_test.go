@@ -0,0 +1,107 @@
+package events_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"fusionaly/internal/analytics"
+	"fusionaly/internal/events"
+	"fusionaly/internal/testsupport"
+	"fusionaly/internal/timeframe"
+)
+
+func TestScrollDepthAggregation(t *testing.T) {
+	dbManager, logger, website := testsupport.SetupTestDBManagerWithWebsite(t, "scroll.com")
+	db := dbManager.GetConnection()
+
+	base := time.Date(2024, 7, 1, 10, 20, 0, 0, time.UTC)
+	scrolls := []struct {
+		ip        string
+		path      string
+		eventName string
+		meta      string
+		offset    time.Duration
+	}{
+		// Visitor 1 reads the landing page to 90%, crossing a half-hour bucket
+		{"203.0.113.1", "/landing", "scroll", `{"depth":25}`, 0},
+		{"203.0.113.1", "/landing", "scroll", `{"depth":50}`, 5 * time.Minute},
+		{"203.0.113.1", "/landing", "scroll", `{"depth":90}`, 12 * time.Minute},
+		// Visitor 2 hits 100% first; shallower events later in the session don't count
+		{"203.0.113.2", "/landing", "scroll:depth:100", `{"percentage":100}`, time.Minute},
+		{"203.0.113.2", "/landing", "scroll:depth:50", `{"percentage":50}`, 2 * time.Minute},
+		// Visitor 1 only skims pricing
+		{"203.0.113.1", "/pricing", "scroll", `{"depth":25}`, 13 * time.Minute},
+		{"203.0.113.1", "/pricing", "scroll", `{"depth":30}`, 14 * time.Minute},
+		// Below the first bucket
+		{"203.0.113.3", "/pricing", "scroll", `{"depth":10}`, time.Minute},
+	}
+	for _, s := range scrolls {
+		require.NoError(t, events.CollectEvent(dbManager, logger, &events.CollectEventInput{
+			IPAddress:       s.ip,
+			UserAgent:       "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36",
+			EventType:       events.EventTypeCustomEvent,
+			CustomEventName: s.eventName,
+			CustomEventMeta: s.meta,
+			Timestamp:       base.Add(s.offset),
+			RawUrl:          "https://scroll.com" + s.path,
+		}))
+	}
+	require.NoError(t, testsupport.ProcessAllTestEvents(dbManager, logger))
+
+	timeFrame, err := timeframe.NewTimeFrame(timeframe.TimeFrameParams{
+		FromTime:      time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC),
+		ToTime:        time.Date(2024, 7, 1, 23, 59, 59, 0, time.UTC),
+		TimeFrameSize: timeframe.DailyTimeFrame,
+	}, time.UTC)
+	require.NoError(t, err)
+	params := analytics.NewWebsiteScopedQueryParams(timeFrame, int(website.ID))
+
+	sessionsByDepth := func(t *testing.T, pathname string) map[int]int64 {
+		t.Helper()
+		buckets, err := analytics.GetScrollDepthForPage(db, params, pathname)
+		require.NoError(t, err)
+		require.Len(t, buckets, len(events.ScrollDepthBuckets))
+
+		result := make(map[int]int64, len(buckets))
+		for _, b := range buckets {
+			result[b.Depth] = b.Sessions
+		}
+		return result
+	}
+
+	t.Run("only the max depth per session is aggregated", func(t *testing.T) {
+		assert.Equal(t, map[int]int64{25: 0, 50: 0, 75: 1, 100: 1}, sessionsByDepth(t, "/landing"))
+		assert.Equal(t, map[int]int64{25: 1, 50: 0, 75: 0, 100: 0}, sessionsByDepth(t, "/pricing"))
+	})
+
+	t.Run("superseded buckets are cleared across half-hour boundaries", func(t *testing.T) {
+		var total int64
+		require.NoError(t, db.Raw("SELECT SUM(sessions_count) FROM scroll_stats WHERE website_id = ?", website.ID).Scan(&total).Error)
+		assert.Equal(t, int64(3), total)
+	})
+
+	t.Run("reports the share of sessions per bucket", func(t *testing.T) {
+		buckets, err := analytics.GetScrollDepthForPage(db, params, "/landing")
+		require.NoError(t, err)
+		assert.InDelta(t, 50.0, buckets[2].Percentage, 0.01)
+		assert.InDelta(t, 50.0, buckets[3].Percentage, 0.01)
+	})
+
+	t.Run("a new session is counted separately", func(t *testing.T) {
+		require.NoError(t, events.CollectEvent(dbManager, logger, &events.CollectEventInput{
+			IPAddress:       "203.0.113.1",
+			UserAgent:       "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36",
+			EventType:       events.EventTypeCustomEvent,
+			CustomEventName: "scroll",
+			CustomEventMeta: `{"depth":50}`,
+			Timestamp:       base.Add(5 * time.Hour),
+			RawUrl:          "https://scroll.com/pricing",
+		}))
+		require.NoError(t, testsupport.ProcessAllTestEvents(dbManager, logger))
+
+		assert.Equal(t, map[int]int64{25: 1, 50: 1, 75: 0, 100: 0}, sessionsByDepth(t, "/pricing"))
+	})
+}
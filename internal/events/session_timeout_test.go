@@ -0,0 +1,84 @@
+package events_test
+
+import (
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"fusionaly/internal/events"
+	"fusionaly/internal/settings"
+	"fusionaly/internal/testsupport"
+)
+
+func TestSessionTimeoutPerWebsite(t *testing.T) {
+	dbManager, _ := testsupport.SetupTestDBManager(t)
+	db := dbManager.GetConnection()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	require.NoError(t, settings.SetupDefaultSettings(db))
+
+	testsupport.CleanTables(db, []string{"events", "ingested_events", "site_stats"})
+	docs := testsupport.CreateTestWebsite(db, "docs.example.com")
+	shop := testsupport.CreateTestWebsite(db, "shop.example.com")
+
+	baseTime := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+
+	// processPageView ingests and processes a page view, returning whether it started a session
+	processPageView := func(t *testing.T, websiteID uint, domain, ip string, timestamp time.Time) bool {
+		t.Helper()
+		_, err := createIngestedEvent(db, websiteID, &events.CollectEventInput{
+			IPAddress: ip, UserAgent: "SessionAgent",
+			RawUrl: "https://" + domain + "/", EventType: events.EventTypePageView, Timestamp: timestamp,
+		})
+		require.NoError(t, err)
+
+		result, err := events.ProcessUnprocessedEvents(dbManager, logger, 10)
+		require.NoError(t, err)
+		require.Len(t, result.ProcessingData, 1)
+		return result.ProcessingData[0].IsNewSession
+	}
+	sessions := func(t *testing.T, websiteID uint) int64 {
+		var total int64
+		require.NoError(t, db.Raw("SELECT COALESCE(SUM(sessions), 0) FROM site_stats WHERE website_id = ?", websiteID).Scan(&total).Error)
+		return total
+	}
+
+	t.Run("defaults to the 30 minute window", func(t *testing.T) {
+		assert.Zero(t, settings.GetSessionTimeoutMinutes(db, docs.ID))
+		assert.True(t, processPageView(t, docs.ID, docs.Domain, "1.1.1.1", baseTime))
+		assert.False(t, processPageView(t, docs.ID, docs.Domain, "1.1.1.1", baseTime.Add(20*time.Minute)))
+		assert.True(t, processPageView(t, docs.ID, docs.Domain, "1.1.1.1", baseTime.Add(65*time.Minute)))
+	})
+
+	t.Run("a longer timeout keeps long reads in one session", func(t *testing.T) {
+		sessionsBefore := sessions(t, docs.ID)
+		require.NoError(t, settings.SaveSessionTimeoutMinutes(db, docs.ID, 60))
+
+		// Already processed events keep their sessions
+		assert.Equal(t, sessionsBefore, sessions(t, docs.ID))
+
+		assert.True(t, processPageView(t, docs.ID, docs.Domain, "2.2.2.2", baseTime))
+		assert.False(t, processPageView(t, docs.ID, docs.Domain, "2.2.2.2", baseTime.Add(45*time.Minute)))
+	})
+
+	t.Run("a shorter timeout only applies to its website", func(t *testing.T) {
+		require.NoError(t, settings.SaveSessionTimeoutMinutes(db, shop.ID, 15))
+
+		assert.True(t, processPageView(t, shop.ID, shop.Domain, "3.3.3.3", baseTime))
+		assert.True(t, processPageView(t, shop.ID, shop.Domain, "3.3.3.3", baseTime.Add(20*time.Minute)))
+		assert.Equal(t, 60, settings.GetSessionTimeoutMinutes(db, docs.ID))
+		assert.Equal(t, map[uint]int{docs.ID: 60, shop.ID: 15}, settings.GetSessionTimeouts(db))
+	})
+
+	t.Run("validates the range", func(t *testing.T) {
+		assert.Error(t, settings.SaveSessionTimeoutMinutes(db, shop.ID, 361))
+		assert.Error(t, settings.SaveSessionTimeoutMinutes(db, shop.ID, -5))
+		assert.Equal(t, 15, settings.GetSessionTimeoutMinutes(db, shop.ID))
+
+		require.NoError(t, settings.SaveSessionTimeoutMinutes(db, shop.ID, 0))
+		assert.Zero(t, settings.GetSessionTimeoutMinutes(db, shop.ID))
+	})
+}
@@ -0,0 +1,29 @@
+package events
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrTimestampOutOfRange is returned for events dated too far in the future,
+// or further in the past than the max_timestamp_age_days setting allows
+var ErrTimestampOutOfRange = errors.New("event timestamp is out of range")
+
+// canonicalTimestamp returns the event timestamp in UTC, using now for events
+// sent without one. Timestamps more than maxSkew after now or more than maxAge
+// before it are rejected; a zero maxSkew or maxAge disables that bound.
+func canonicalTimestamp(timestamp, now time.Time, maxSkew, maxAge time.Duration) (time.Time, error) {
+	if timestamp.IsZero() {
+		return now.UTC(), nil
+	}
+	timestamp = timestamp.UTC()
+
+	if maxSkew > 0 && timestamp.After(now.Add(maxSkew)) {
+		return time.Time{}, fmt.Errorf("%w: %s is more than %s in the future", ErrTimestampOutOfRange, timestamp.Format(time.RFC3339), maxSkew)
+	}
+	if maxAge > 0 && timestamp.Before(now.Add(-maxAge)) {
+		return time.Time{}, fmt.Errorf("%w: %s is more than %s in the past", ErrTimestampOutOfRange, timestamp.Format(time.RFC3339), maxAge)
+	}
+	return timestamp, nil
+}
@@ -0,0 +1,78 @@
+package events_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"fusionaly/internal/events"
+	"fusionaly/internal/settings"
+	"fusionaly/internal/testsupport"
+)
+
+func TestCollectEventTimestampBounds(t *testing.T) {
+	dbManager, logger := testsupport.SetupTestDBManager(t)
+	db := dbManager.GetConnection()
+	testsupport.CleanAllTables(db)
+	testsupport.CreateTestWebsite(db, "example.com")
+	require.NoError(t, settings.SetupDefaultSettings(db))
+
+	collect := func(timestamp time.Time) error {
+		db.Exec("DELETE FROM ingested_events")
+		return events.CollectEvent(dbManager, logger, testsupport.CreateTestEventInput(
+			"203.0.113.1", "Mozilla/5.0 (test)", events.EventTypePageView, timestamp,
+			"https://example.com/pricing", "", "", "",
+		))
+	}
+	storedTimestamp := func(t *testing.T) time.Time {
+		t.Helper()
+		var ingested events.IngestedEvent
+		require.NoError(t, db.First(&ingested).Error)
+		return ingested.Timestamp
+	}
+	now := time.Now()
+
+	t.Run("rejects timestamps beyond the future skew", func(t *testing.T) {
+		err := collect(now.Add(2 * time.Hour))
+		assert.ErrorIs(t, err, events.ErrTimestampOutOfRange)
+
+		var count int64
+		require.NoError(t, db.Model(&events.IngestedEvent{}).Count(&count).Error)
+		assert.Zero(t, count)
+	})
+
+	t.Run("accepts clock drift within the skew", func(t *testing.T) {
+		require.NoError(t, collect(now.Add(10*time.Minute)))
+	})
+
+	t.Run("stores timestamps in UTC", func(t *testing.T) {
+		at := time.Date(2024, 7, 1, 14, 0, 0, 0, time.FixedZone("CEST", 2*60*60))
+		require.NoError(t, collect(at))
+		assert.True(t, at.Equal(storedTimestamp(t)))
+		assert.Equal(t, time.UTC, storedTimestamp(t).Location())
+	})
+
+	t.Run("uses the current time when missing", func(t *testing.T) {
+		require.NoError(t, collect(time.Time{}))
+		assert.WithinDuration(t, time.Now(), storedTimestamp(t), time.Minute)
+	})
+
+	t.Run("backdating is only limited once a window is set", func(t *testing.T) {
+		require.NoError(t, collect(now.AddDate(-2, 0, 0)), "imports of old history are accepted by default")
+
+		require.NoError(t, settings.SaveMaxTimestampAgeDays(db, 30))
+		t.Cleanup(func() { settings.SaveMaxTimestampAgeDays(db, 0) })
+
+		require.NoError(t, collect(now.AddDate(0, 0, -7)), "backdating within the window is kept")
+		assert.ErrorIs(t, collect(now.AddDate(0, 0, -31)), events.ErrTimestampOutOfRange)
+	})
+
+	t.Run("a zero skew accepts any future timestamp", func(t *testing.T) {
+		require.NoError(t, settings.SaveMaxTimestampSkewMinutes(db, 0))
+		t.Cleanup(func() { settings.SaveMaxTimestampSkewMinutes(db, settings.DefaultMaxTimestampSkewMinutes) })
+
+		require.NoError(t, collect(now.AddDate(0, 0, 2)))
+	})
+}
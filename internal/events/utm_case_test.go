@@ -0,0 +1,73 @@
+package events_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"fusionaly/internal/events"
+	"fusionaly/internal/settings"
+	"fusionaly/internal/testsupport"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUTMCaseNormalization(t *testing.T) {
+	dbManager, logger := testsupport.SetupTestDBManager(t)
+	db := dbManager.GetConnection()
+	testsupport.CleanAllTables(db)
+	testsupport.CreateTestWebsite(db, "example.com")
+	require.NoError(t, settings.SetupDefaultSettings(db))
+
+	timestamp := time.Now().UTC().Truncate(time.Hour)
+	collect := func(t *testing.T, visitor int, query string) {
+		t.Helper()
+		require.NoError(t, events.CollectEvent(dbManager, logger, testsupport.CreateTestEventInput(
+			fmt.Sprintf("203.0.113.%d", visitor), "Mozilla/5.0 (test)", events.EventTypePageView, timestamp,
+			"https://example.com/landing?"+query, "", "", "",
+		)))
+		require.NoError(t, testsupport.ProcessAllTestEvents(dbManager, logger))
+	}
+
+	type utmRow struct {
+		UTMSource     string
+		UTMMedium     string
+		UTMCampaign   string
+		VisitorsCount int64
+	}
+	utmRows := func(t *testing.T) []utmRow {
+		t.Helper()
+		var rows []utmRow
+		require.NoError(t, db.Raw(`
+			SELECT utm_source, utm_medium, utm_campaign, SUM(visitors_count) AS visitors_count
+			FROM utm_stats
+			GROUP BY utm_source, utm_medium, utm_campaign
+			ORDER BY utm_source
+		`).Scan(&rows).Error)
+		return rows
+	}
+
+	collect(t, 1, "utm_source=Google&utm_medium=CPC&utm_campaign=Spring_Sale")
+	collect(t, 2, "utm_source=google&utm_medium=cpc&utm_campaign=spring_sale")
+	collect(t, 3, "utm_source=%20GOOGLE%20&utm_medium=Cpc&utm_campaign=SPRING_SALE")
+
+	assert.Equal(t, []utmRow{{UTMSource: "google", UTMMedium: "cpc", UTMCampaign: "spring_sale", VisitorsCount: 3}}, utmRows(t),
+		"differently-cased sources merge into one aggregate row")
+
+	var sources []string
+	require.NoError(t, db.Model(&events.Event{}).Distinct().Pluck("utm_source", &sources).Error)
+	assert.Equal(t, []string{"google"}, sources, "events keep the normalized source for attribution")
+
+	t.Run("kept as sent when disabled", func(t *testing.T) {
+		require.NoError(t, settings.SaveUTMCaseNormalizationEnabled(db, false))
+		t.Cleanup(func() { settings.SaveUTMCaseNormalizationEnabled(db, true) })
+
+		collect(t, 4, "utm_source=Google&utm_medium=cpc&utm_campaign=spring_sale")
+
+		assert.Equal(t, []utmRow{
+			{UTMSource: "Google", UTMMedium: "cpc", UTMCampaign: "spring_sale", VisitorsCount: 1},
+			{UTMSource: "google", UTMMedium: "cpc", UTMCampaign: "spring_sale", VisitorsCount: 3},
+		}, utmRows(t))
+	})
+}
@@ -0,0 +1,77 @@
+package events
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// UTMLinkInput holds a landing page URL and the UTM values to tag it with
+type UTMLinkInput struct {
+	BaseURL  string `json:"base_url"`
+	Source   string `json:"utm_source"`
+	Medium   string `json:"utm_medium"`
+	Campaign string `json:"utm_campaign"`
+	Term     string `json:"utm_term"`
+	Content  string `json:"utm_content"`
+}
+
+// UTMLink is a tagged campaign URL along with values likely to fragment reports
+type UTMLink struct {
+	URL      string   `json:"url"`
+	Warnings []string `json:"warnings"`
+}
+
+// BuildUTMLink adds the UTM values of input to its base URL, replacing UTM
+// parameters the URL already had and keeping the rest of its query and
+// fragment. The base URL must be an absolute http(s) URL and utm_source and
+// utm_medium are required. normalizeCase reflects the normalize_utm_case
+// setting: when it's off, uppercase values are reported as a warning because
+// "Google" and "google" would show up as different sources.
+func BuildUTMLink(input UTMLinkInput, normalizeCase bool) (*UTMLink, error) {
+	params := []struct{ name, value string }{
+		{"utm_source", strings.TrimSpace(input.Source)},
+		{"utm_medium", strings.TrimSpace(input.Medium)},
+		{"utm_campaign", strings.TrimSpace(input.Campaign)},
+		{"utm_term", strings.TrimSpace(input.Term)},
+		{"utm_content", strings.TrimSpace(input.Content)},
+	}
+
+	var errs []error
+	baseURL, err := url.Parse(strings.TrimSpace(input.BaseURL))
+	switch {
+	case strings.TrimSpace(input.BaseURL) == "":
+		errs = append(errs, errors.New("base_url is required"))
+	case err != nil || (baseURL.Scheme != "http" && baseURL.Scheme != "https") || baseURL.Host == "":
+		errs = append(errs, fmt.Errorf("base_url %q is not an absolute http(s) URL", input.BaseURL))
+	}
+	for _, param := range params[:2] {
+		if param.value == "" {
+			errs = append(errs, fmt.Errorf("%s is required", param.name))
+		}
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+
+	query := baseURL.Query()
+	warnings := []string{}
+	for _, param := range params {
+		if param.value == "" {
+			query.Del(param.name)
+			continue
+		}
+		query.Set(param.name, param.value)
+
+		if strings.ContainsAny(param.value, " \t") {
+			warnings = append(warnings, fmt.Sprintf("%s %q contains spaces; use - or _ so it isn't reported apart from the same value without them", param.name, param.value))
+		}
+		if !normalizeCase && param.value != strings.ToLower(param.value) {
+			warnings = append(warnings, fmt.Sprintf("%s %q has uppercase letters and UTM case normalization is off, so it's reported apart from %q", param.name, param.value, strings.ToLower(param.value)))
+		}
+	}
+	baseURL.RawQuery = query.Encode()
+
+	return &UTMLink{URL: baseURL.String(), Warnings: warnings}, nil
+}
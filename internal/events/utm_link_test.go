@@ -0,0 +1,68 @@
+package events_test
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"fusionaly/internal/events"
+)
+
+func TestBuildUTMLink(t *testing.T) {
+	t.Run("encodes values and keeps the rest of the URL", func(t *testing.T) {
+		link, err := events.BuildUTMLink(events.UTMLinkInput{
+			BaseURL:  "https://example.com/pricing?plan=pro&utm_source=old#faq",
+			Source:   " newsletter ",
+			Medium:   "email",
+			Campaign: "black_friday&more",
+			Content:  "café/hero",
+		}, true)
+		require.NoError(t, err)
+		assert.Equal(t,
+			"https://example.com/pricing?plan=pro&utm_campaign=black_friday%26more&utm_content=caf%C3%A9%2Fhero&utm_medium=email&utm_source=newsletter#faq",
+			link.URL)
+		assert.Empty(t, link.Warnings)
+
+		parsed, err := url.Parse(link.URL)
+		require.NoError(t, err)
+		assert.Equal(t, "black_friday&more", parsed.Query().Get("utm_campaign"), "values round-trip through the query")
+		assert.False(t, parsed.Query().Has("utm_term"), "empty values are left out")
+	})
+
+	t.Run("requires a base URL, source and medium", func(t *testing.T) {
+		_, err := events.BuildUTMLink(events.UTMLinkInput{BaseURL: "example.com/landing"}, true)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "not an absolute http(s) URL")
+		assert.Contains(t, err.Error(), "utm_source is required")
+		assert.Contains(t, err.Error(), "utm_medium is required")
+
+		_, err = events.BuildUTMLink(events.UTMLinkInput{Source: "google", Medium: "cpc"}, true)
+		assert.EqualError(t, err, "base_url is required")
+	})
+
+	t.Run("warns about spaces", func(t *testing.T) {
+		link, err := events.BuildUTMLink(events.UTMLinkInput{
+			BaseURL: "https://example.com", Source: "google", Medium: "cpc", Campaign: "spring sale",
+		}, true)
+		require.NoError(t, err)
+		assert.Equal(t, "https://example.com?utm_campaign=spring+sale&utm_medium=cpc&utm_source=google", link.URL)
+		require.Len(t, link.Warnings, 1)
+		assert.Contains(t, link.Warnings[0], "utm_campaign")
+	})
+
+	t.Run("warns about uppercase only without case normalization", func(t *testing.T) {
+		input := events.UTMLinkInput{BaseURL: "https://example.com", Source: "Google", Medium: "cpc"}
+
+		link, err := events.BuildUTMLink(input, true)
+		require.NoError(t, err)
+		assert.Empty(t, link.Warnings)
+
+		link, err = events.BuildUTMLink(input, false)
+		require.NoError(t, err)
+		require.Len(t, link.Warnings, 1)
+		assert.Contains(t, link.Warnings[0], `utm_source "Google"`)
+		assert.Equal(t, "https://example.com?utm_medium=cpc&utm_source=Google", link.URL, "values are kept as typed")
+	})
+}
@@ -0,0 +1,101 @@
+// Package health tracks application readiness, as distinct from liveness.
+// The process is live as soon as it serves HTTP; it is ready once migrations
+// have completed, the event processor loop is running and the unprocessed
+// backlog is within the configured threshold.
+package health
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+var (
+	migrated         atomic.Bool
+	processorRunning atomic.Bool
+
+	aggregationBacklog    atomic.Int64
+	aggregationLagSeconds atomic.Int64
+	backlogThreshold      atomic.Int64
+	lastProcessingRun     atomic.Int64 // unix nanoseconds, 0 until the first run
+	processingBatchSize   atomic.Int64
+)
+
+// ReadinessStatus reports the individual readiness checks.
+type ReadinessStatus struct {
+	Ready             bool       `json:"ready"`
+	Migrated          bool       `json:"migrated"`
+	ProcessorRunning  bool       `json:"processor_running"`
+	BacklogOK         bool       `json:"backlog_ok"`
+	Backlog           int64      `json:"backlog"`
+	LastProcessingRun *time.Time `json:"last_processing_run"`
+}
+
+// SetMigrated records whether database migrations have completed.
+func SetMigrated(done bool) {
+	migrated.Store(done)
+}
+
+// SetProcessorRunning records whether the event processor loop is running.
+func SetProcessorRunning(running bool) {
+	processorRunning.Store(running)
+}
+
+// SetBacklogThreshold sets the unprocessed backlog size above which the
+// process reports not ready. Zero or less disables the check.
+func SetBacklogThreshold(threshold int64) {
+	backlogThreshold.Store(threshold)
+}
+
+// SetLastProcessingRun records when the event processor last completed a run.
+func SetLastProcessingRun(at time.Time) {
+	lastProcessingRun.Store(at.UnixNano())
+}
+
+// SetProcessingBatchSize records the batch size of the latest processing run.
+func SetProcessingBatchSize(size int) {
+	processingBatchSize.Store(int64(size))
+}
+
+// ProcessingBatchSize returns the batch size of the latest processing run, 0
+// until the first run with a backlog.
+func ProcessingBatchSize() int64 {
+	return processingBatchSize.Load()
+}
+
+// Readiness returns the current readiness checks.
+func Readiness() ReadinessStatus {
+	status := ReadinessStatus{
+		Migrated:         migrated.Load(),
+		ProcessorRunning: processorRunning.Load(),
+		Backlog:          aggregationBacklog.Load(),
+	}
+	threshold := backlogThreshold.Load()
+	status.BacklogOK = threshold <= 0 || status.Backlog <= threshold
+	if nanos := lastProcessingRun.Load(); nanos != 0 {
+		at := time.Unix(0, nanos).UTC()
+		status.LastProcessingRun = &at
+	}
+	status.Ready = status.Migrated && status.ProcessorRunning && status.BacklogOK
+	return status
+}
+
+// AggregationLagStatus is the gauge of unprocessed events awaiting aggregation.
+type AggregationLagStatus struct {
+	Backlog    int64 `json:"backlog"`
+	LagSeconds int64 `json:"lag_seconds"`
+}
+
+// SetAggregationLag records the current backlog size and the age of the oldest
+// unprocessed event.
+func SetAggregationLag(backlog int64, lag time.Duration) {
+	aggregationBacklog.Store(backlog)
+	aggregationLagSeconds.Store(int64(lag / time.Second))
+}
+
+// AggregationLag returns the latest aggregation lag gauge.
+func AggregationLag() AggregationLagStatus {
+	return AggregationLagStatus{
+		Backlog:    aggregationBacklog.Load(),
+		LagSeconds: aggregationLagSeconds.Load(),
+	}
+}
@@ -0,0 +1,70 @@
+package health
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadiness(t *testing.T) {
+	t.Cleanup(func() {
+		SetMigrated(false)
+		SetProcessorRunning(false)
+		SetBacklogThreshold(0)
+		SetAggregationLag(0, 0)
+		lastProcessingRun.Store(0)
+	})
+
+	t.Run("not ready during migration", func(t *testing.T) {
+		SetMigrated(false)
+		SetProcessorRunning(true)
+
+		status := Readiness()
+		assert.False(t, status.Ready)
+		assert.False(t, status.Migrated)
+	})
+
+	t.Run("not ready until processor runs", func(t *testing.T) {
+		SetMigrated(true)
+		SetProcessorRunning(false)
+
+		assert.False(t, Readiness().Ready)
+	})
+
+	t.Run("ready after migrations and processor start", func(t *testing.T) {
+		SetMigrated(true)
+		SetProcessorRunning(true)
+
+		assert.Equal(t, ReadinessStatus{Ready: true, Migrated: true, ProcessorRunning: true, BacklogOK: true}, Readiness())
+	})
+
+	t.Run("not ready while the backlog exceeds the threshold", func(t *testing.T) {
+		SetMigrated(true)
+		SetProcessorRunning(true)
+		SetBacklogThreshold(1000)
+
+		SetAggregationLag(1000, time.Minute)
+		assert.True(t, Readiness().Ready)
+
+		SetAggregationLag(1001, time.Minute)
+		status := Readiness()
+		assert.False(t, status.Ready)
+		assert.False(t, status.BacklogOK)
+		assert.Equal(t, int64(1001), status.Backlog)
+
+		SetBacklogThreshold(0)
+		assert.True(t, Readiness().Ready, "zero threshold disables the check")
+	})
+
+	t.Run("reports the last processing run", func(t *testing.T) {
+		assert.Nil(t, Readiness().LastProcessingRun)
+
+		at := time.Date(2024, 7, 1, 10, 0, 0, 0, time.UTC)
+		SetLastProcessingRun(at)
+		status := Readiness()
+		if assert.NotNil(t, status.LastProcessingRun) {
+			assert.True(t, at.Equal(*status.LastProcessingRun))
+		}
+	})
+}
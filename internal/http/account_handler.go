@@ -60,6 +60,25 @@ func AccountChangePasswordFormAction(ctx *cartridge.Context) error {
 	return ctx.FlashSuccess("Password changed successfully").Redirect("/admin/administration/account", fiber.StatusFound)
 }
 
+// AccountWeeklyDigestFormAction opts the current user in to or out of the weekly stats email
+func AccountWeeklyDigestFormAction(ctx *cartridge.Context) error {
+	userID, authenticated := ctx.Session.GetUserID(ctx.Ctx)
+	if !authenticated {
+		return ctx.FlashError("Authentication required").Redirect("/admin/administration/account", fiber.StatusFound)
+	}
+
+	enabled := ctx.Input("weekly_digest") == "true"
+	if err := users.SetWeeklyDigest(ctx.DB(), userID, enabled); err != nil {
+		ctx.Logger.Error("Failed to update weekly digest preference", slog.Uint64("userID", uint64(userID)), slog.Any("error", err))
+		return ctx.FlashError("Failed to update weekly digest preference").Redirect("/admin/administration/account", fiber.StatusFound)
+	}
+
+	if enabled {
+		return ctx.FlashSuccess("You'll receive the weekly stats digest").Redirect("/admin/administration/account", fiber.StatusFound)
+	}
+	return ctx.FlashSuccess("Weekly stats digest turned off").Redirect("/admin/administration/account", fiber.StatusFound)
+}
+
 // Note: Fusionaly has no license/seat model. The former Pro license handlers
 // (AccountUpdateLicenseFormAction, AccountCheckLicenseFormAction) are intentionally
 // not present — all features are available in the single Fusionaly product.
@@ -0,0 +1,90 @@
+package http
+
+import (
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/karloscodes/cartridge"
+	"gorm.io/gorm"
+
+	"fusionaly/internal/alerts"
+	"fusionaly/internal/websites"
+)
+
+// WebsiteAlertRuleCreateAction adds an alert rule to a website.
+// Webhook rules show their signing secret once, in the success flash.
+func WebsiteAlertRuleCreateAction(ctx *cartridge.Context) error {
+	id, err := ctx.ParamsInt("id")
+	if err != nil {
+		return ctx.FlashError("Invalid website ID").Redirect("/admin", fiber.StatusFound)
+	}
+	editPath := fmt.Sprintf("/admin/websites/%d/edit", id)
+
+	db := ctx.DB()
+	if _, err := websites.GetWebsiteByID(db, uint(id)); err != nil {
+		return ctx.FlashError("Website not found").Redirect("/admin", fiber.StatusFound)
+	}
+
+	rule := &alerts.Rule{
+		WebsiteID: uint(id),
+		Name:      strings.TrimSpace(ctx.Input("name")),
+		Metric:    alerts.Metric(ctx.Input("metric")),
+		Operator:  alerts.Operator(ctx.Input("operator")),
+		Baseline:  alerts.Baseline(ctx.Input("baseline")),
+		Channel:   alerts.Channel(ctx.Input("channel")),
+		Target:    strings.TrimSpace(ctx.Input("target")),
+	}
+	if rule.Threshold, err = strconv.ParseFloat(strings.TrimSpace(ctx.Input("threshold")), 64); err != nil {
+		return ctx.FlashError("Threshold must be a number").Redirect(editPath, fiber.StatusFound)
+	}
+	if days := strings.TrimSpace(ctx.Input("baseline_days")); days != "" {
+		if rule.BaselineDays, err = strconv.Atoi(days); err != nil {
+			return ctx.FlashError("Baseline days must be a whole number").Redirect(editPath, fiber.StatusFound)
+		}
+	}
+	if cooldown := strings.TrimSpace(ctx.Input("cooldown_minutes")); cooldown != "" {
+		if rule.CooldownMinutes, err = strconv.Atoi(cooldown); err != nil {
+			return ctx.FlashError("Cool-down must be a whole number of minutes").Redirect(editPath, fiber.StatusFound)
+		}
+	}
+
+	if err := rule.Validate(); err != nil {
+		return ctx.FlashError(err.Error()).Redirect(editPath, fiber.StatusFound)
+	}
+	if err := alerts.CreateRule(db, rule); err != nil {
+		ctx.Logger.Error("Failed to create alert rule", slog.Any("error", err), slog.Int("id", id))
+		return ctx.FlashError("Failed to create alert").Redirect(editPath, fiber.StatusFound)
+	}
+
+	if rule.Channel == alerts.ChannelWebhook {
+		return ctx.FlashSuccess(fmt.Sprintf("Alert created. Signing secret: %s (copy it now, it won't be shown again)", rule.Secret)).Redirect(editPath, fiber.StatusFound)
+	}
+	return ctx.FlashSuccess("Alert created").Redirect(editPath, fiber.StatusFound)
+}
+
+// WebsiteAlertRuleDeleteAction removes one of a website's alert rules
+func WebsiteAlertRuleDeleteAction(ctx *cartridge.Context) error {
+	id, err := ctx.ParamsInt("id")
+	if err != nil {
+		return ctx.FlashError("Invalid website ID").Redirect("/admin", fiber.StatusFound)
+	}
+	editPath := fmt.Sprintf("/admin/websites/%d/edit", id)
+
+	ruleID, err := ctx.ParamsInt("ruleId")
+	if err != nil {
+		return ctx.FlashError("Invalid alert ID").Redirect(editPath, fiber.StatusFound)
+	}
+
+	if err := alerts.DeleteRule(ctx.DB(), uint(id), uint(ruleID)); err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return ctx.FlashError("Alert not found").Redirect(editPath, fiber.StatusFound)
+		}
+		ctx.Logger.Error("Failed to delete alert rule", slog.Any("error", err), slog.Int("id", id), slog.Int("rule_id", ruleID))
+		return ctx.FlashError("Failed to delete alert").Redirect(editPath, fiber.StatusFound)
+	}
+
+	return ctx.FlashSuccess("Alert deleted").Redirect(editPath, fiber.StatusFound)
+}
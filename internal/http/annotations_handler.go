@@ -1,7 +1,9 @@
 package http
 
 import (
+	"errors"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
@@ -9,6 +11,8 @@ import (
 	"log/slog"
 
 	"fusionaly/internal/annotations"
+	"fusionaly/internal/http/middleware"
+	"fusionaly/internal/websites"
 	"github.com/karloscodes/cartridge"
 	"github.com/karloscodes/cartridge/flash"
 )
@@ -150,6 +154,10 @@ func AnnotationCreateAction(ctx *cartridge.Context) error {
 		return ctx.FlashError("Invalid date format").Redirect(redirectPath, fiber.StatusFound)
 	}
 
+	if err := annotations.ValidateAnnotationDate(annotationDate); err != nil {
+		return ctx.FlashError("Date must be after 2000 and within a year from now").Redirect(redirectPath, fiber.StatusFound)
+	}
+
 	db := ctx.DB()
 
 	annotation := &annotations.Annotation{
@@ -224,9 +232,15 @@ func AnnotationUpdateAction(ctx *cartridge.Context) error {
 		existing.Color = form.Color
 	}
 	if form.AnnotationDate != "" {
-		if parsed, ok := parseAnnotationDate(form.AnnotationDate); ok {
-			existing.AnnotationDate = parsed
+		parsed, ok := parseAnnotationDate(form.AnnotationDate)
+		if !ok {
+			ctx.Logger.Error("Failed to parse annotation date", slog.String("date", form.AnnotationDate))
+			return ctx.FlashError("Invalid date format").Redirect(redirectPath, fiber.StatusFound)
+		}
+		if err := annotations.ValidateAnnotationDate(parsed); err != nil {
+			return ctx.FlashError("Date must be after 2000 and within a year from now").Redirect(redirectPath, fiber.StatusFound)
 		}
+		existing.AnnotationDate = parsed
 	}
 
 	if err := annotations.UpdateAnnotation(db, existing); err != nil {
@@ -277,3 +291,82 @@ func AnnotationDeleteAction(ctx *cartridge.Context) error {
 
 	return ctx.FlashSuccess("Annotation deleted successfully").Redirect(redirectPath, fiber.StatusFound)
 }
+
+// AnnotationsAPICreateAction creates a deployment annotation for CI pipelines.
+// Authenticated by the website's API token; body: {domain, label, date}.
+// The date is optional and defaults to now.
+func AnnotationsAPICreateAction(ctx *cartridge.Context) error {
+	var in struct {
+		Domain string `json:"domain"`
+		Label  string `json:"label"`
+		Date   string `json:"date"`
+	}
+	if err := ctx.Ctx.BodyParser(&in); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid JSON body",
+		})
+	}
+
+	domain := strings.ToLower(strings.TrimSpace(in.Domain))
+	label := strings.TrimSpace(in.Label)
+	if domain == "" || label == "" {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "domain and label are required",
+		})
+	}
+
+	annotationDate := time.Now().UTC()
+	if in.Date != "" {
+		parsed, ok := parseAnnotationDate(in.Date)
+		if !ok {
+			return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid date format",
+			})
+		}
+		annotationDate = parsed
+	}
+	if err := annotations.ValidateAnnotationDate(annotationDate); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	db := ctx.DB()
+	website, err := websites.GetWebsiteByDomain(db, domain)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "Website not found",
+			})
+		}
+		ctx.Logger.Error("Failed to look up website", slog.Any("error", err), slog.String("domain", domain))
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to create annotation",
+		})
+	}
+
+	tokenWebsiteID, _ := ctx.Locals(middleware.APITokenWebsiteIDKey).(uint)
+	if tokenWebsiteID != website.ID {
+		return ctx.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "API token is not valid for this website",
+		})
+	}
+
+	annotation := &annotations.Annotation{
+		WebsiteID:      website.ID,
+		Title:          label,
+		AnnotationType: annotations.AnnotationDeployment,
+		AnnotationDate: annotationDate,
+		Color:          annotations.GetAnnotationTypeColor(annotations.AnnotationDeployment),
+	}
+	if err := annotations.CreateAnnotation(db, annotation); err != nil {
+		ctx.Logger.Error("Failed to create annotation", slog.Any("error", err), slog.String("domain", domain))
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to create annotation",
+		})
+	}
+
+	return ctx.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"annotation": annotation,
+	})
+}
@@ -0,0 +1,206 @@
+package http_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"fusionaly/internal/annotations"
+	"fusionaly/internal/testsupport"
+	"fusionaly/internal/websites"
+)
+
+func TestAnnotationActions(t *testing.T) {
+	dbManager, _, website := testsupport.SetupTestDBManagerWithWebsite(t, "annotations.com")
+	db := dbManager.GetConnection()
+
+	other := &websites.Website{Domain: "other.com"}
+	require.NoError(t, websites.CreateWebsite(db, other))
+	otherAnnotation := &annotations.Annotation{
+		WebsiteID:      other.ID,
+		Title:          "Other launch",
+		AnnotationType: annotations.AnnotationGeneral,
+		AnnotationDate: time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC),
+	}
+	require.NoError(t, annotations.CreateAnnotation(db, otherAnnotation))
+
+	testsupport.CreateTestUserForAuth(t, db, "admin@annotations.com", "password123")
+	app := testsupport.CreateMinimalTestApp(t, db)
+	session := testsupport.LoginTestUser(t, app, "admin@annotations.com", "password123")
+
+	send := func(method, path string, form url.Values) *http.Response {
+		req := httptest.NewRequest(method, path, strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.Header.Set("User-Agent", "Mozilla/5.0 Test Browser")
+		req.Header.Set("Sec-Fetch-Site", "same-origin")
+		req.Header.Set("Cookie", fmt.Sprintf("%s=%s; _tz=UTC", testsupport.SessionCookieName, session))
+
+		resp, err := app.Test(req, 30000)
+		require.NoError(t, err)
+		return resp
+	}
+	basePath := fmt.Sprintf("/admin/websites/%d/annotations", website.ID)
+
+	var created annotations.Annotation
+
+	t.Run("creates an annotation", func(t *testing.T) {
+		resp := send("POST", basePath, url.Values{
+			"title":           {"Launch"},
+			"description":     {"Public launch"},
+			"annotation_type": {"campaign"},
+			"annotation_date": {"2024-07-15"},
+			"color":           {"#ff0000"},
+		})
+		assert.Equal(t, http.StatusFound, resp.StatusCode)
+
+		list, err := annotations.GetAnnotationsForWebsite(db, website.ID)
+		require.NoError(t, err)
+		require.Len(t, list, 1)
+		created = list[0]
+		assert.Equal(t, "Launch", created.Title)
+		assert.Equal(t, "#ff0000", created.Color)
+		assert.Equal(t, time.Date(2024, 7, 15, 0, 0, 0, 0, time.UTC), created.AnnotationDate.UTC())
+	})
+
+	t.Run("rejects dates out of range", func(t *testing.T) {
+		for _, date := range []string{"1999-12-31", time.Now().AddDate(2, 0, 0).Format("2006-01-02")} {
+			resp := send("POST", basePath, url.Values{
+				"title":           {"Out of range"},
+				"annotation_date": {date},
+			})
+			assert.Equal(t, http.StatusFound, resp.StatusCode)
+		}
+
+		list, err := annotations.GetAnnotationsForWebsite(db, website.ID)
+		require.NoError(t, err)
+		assert.Len(t, list, 1)
+	})
+
+	t.Run("updates an annotation with PUT", func(t *testing.T) {
+		resp := send("PUT", fmt.Sprintf("%s/%d", basePath, created.ID), url.Values{
+			"title":           {"Relaunch"},
+			"annotation_date": {"2024-07-20"},
+		})
+		assert.Equal(t, http.StatusFound, resp.StatusCode)
+
+		updated, err := annotations.GetAnnotationByID(db, created.ID, website.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "Relaunch", updated.Title)
+		assert.Equal(t, time.Date(2024, 7, 20, 0, 0, 0, 0, time.UTC), updated.AnnotationDate.UTC())
+	})
+
+	t.Run("keeps the date when the update is out of range", func(t *testing.T) {
+		send("PUT", fmt.Sprintf("%s/%d", basePath, created.ID), url.Values{
+			"title":           {"Relaunch"},
+			"annotation_date": {"1990-01-01"},
+		})
+
+		updated, err := annotations.GetAnnotationByID(db, created.ID, website.ID)
+		require.NoError(t, err)
+		assert.Equal(t, time.Date(2024, 7, 20, 0, 0, 0, 0, time.UTC), updated.AnnotationDate.UTC())
+	})
+
+	t.Run("cannot touch another website's annotation", func(t *testing.T) {
+		send("PUT", fmt.Sprintf("%s/%d", basePath, otherAnnotation.ID), url.Values{
+			"title": {"Hijacked"},
+		})
+		send("DELETE", fmt.Sprintf("%s/%d", basePath, otherAnnotation.ID), nil)
+
+		unchanged, err := annotations.GetAnnotationByID(db, otherAnnotation.ID, other.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "Other launch", unchanged.Title)
+	})
+
+	t.Run("deletes an annotation with DELETE", func(t *testing.T) {
+		resp := send("DELETE", fmt.Sprintf("%s/%d", basePath, created.ID), nil)
+		assert.Equal(t, http.StatusFound, resp.StatusCode)
+
+		list, err := annotations.GetAnnotationsForWebsite(db, website.ID)
+		require.NoError(t, err)
+		assert.Empty(t, list)
+	})
+}
+
+func TestAnnotationsAPICreateAction(t *testing.T) {
+	dbManager, _, website := testsupport.SetupTestDBManagerWithWebsite(t, "deploys.com")
+	db := dbManager.GetConnection()
+	otherWebsite := testsupport.CreateTestWebsite(db, "other-deploys.com")
+
+	token, _, err := websites.CreateAPIToken(db, website.ID, "ci")
+	require.NoError(t, err)
+
+	app := testsupport.CreateMinimalTestApp(t, db)
+
+	post := func(t *testing.T, body, authorization string) (int, map[string]interface{}) {
+		t.Helper()
+		req := httptest.NewRequest("POST", "/api/v1/annotations", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		if authorization != "" {
+			req.Header.Set("Authorization", authorization)
+		}
+		resp, err := app.Test(req, 30000)
+		require.NoError(t, err)
+
+		raw, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		var payload map[string]interface{}
+		require.NoError(t, json.Unmarshal(raw, &payload), string(raw))
+		return resp.StatusCode, payload
+	}
+
+	t.Run("rejects missing token", func(t *testing.T) {
+		status, _ := post(t, `{"domain":"deploys.com","label":"v1.0.0"}`, "")
+		assert.Equal(t, http.StatusUnauthorized, status)
+	})
+
+	t.Run("rejects unknown token", func(t *testing.T) {
+		status, _ := post(t, `{"domain":"deploys.com","label":"v1.0.0"}`, "Bearer fus_not-a-real-token")
+		assert.Equal(t, http.StatusUnauthorized, status)
+	})
+
+	t.Run("rejects unknown domain", func(t *testing.T) {
+		status, payload := post(t, `{"domain":"nope.com","label":"v1.0.0"}`, "Bearer "+token)
+		assert.Equal(t, http.StatusNotFound, status)
+		assert.Equal(t, "Website not found", payload["error"])
+	})
+
+	t.Run("rejects another website's domain", func(t *testing.T) {
+		status, _ := post(t, `{"domain":"other-deploys.com","label":"v1.0.0"}`, "Bearer "+token)
+		assert.Equal(t, http.StatusForbidden, status)
+
+		list, err := annotations.GetAnnotationsForWebsite(db, otherWebsite.ID)
+		require.NoError(t, err)
+		assert.Empty(t, list)
+	})
+
+	t.Run("requires a label", func(t *testing.T) {
+		status, _ := post(t, `{"domain":"deploys.com"}`, "Bearer "+token)
+		assert.Equal(t, http.StatusBadRequest, status)
+	})
+
+	t.Run("rejects dates out of range", func(t *testing.T) {
+		status, _ := post(t, `{"domain":"deploys.com","label":"v0","date":"1999-01-01"}`, "Bearer "+token)
+		assert.Equal(t, http.StatusBadRequest, status)
+	})
+
+	t.Run("creates a deployment annotation", func(t *testing.T) {
+		status, _ := post(t, `{"domain":"Deploys.com","label":"v1.2.0","date":"2024-07-15T10:30:00Z"}`, "Bearer "+token)
+		require.Equal(t, http.StatusCreated, status)
+
+		list, err := annotations.GetAnnotationsForWebsite(db, website.ID)
+		require.NoError(t, err)
+		require.Len(t, list, 1)
+		assert.Equal(t, "v1.2.0", list[0].Title)
+		assert.Equal(t, annotations.AnnotationDeployment, list[0].AnnotationType)
+		assert.Equal(t, time.Date(2024, 7, 15, 10, 30, 0, 0, time.UTC), list[0].AnnotationDate.UTC())
+	})
+}
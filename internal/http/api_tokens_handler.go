@@ -0,0 +1,65 @@
+package http
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/karloscodes/cartridge"
+	"gorm.io/gorm"
+
+	"fusionaly/internal/websites"
+)
+
+// WebsiteAPITokenCreateAction creates a stats API token for a website.
+// The plaintext token is only ever shown in the success flash.
+func WebsiteAPITokenCreateAction(ctx *cartridge.Context) error {
+	id, err := ctx.ParamsInt("id")
+	if err != nil {
+		return ctx.FlashError("Invalid website ID").Redirect("/admin", fiber.StatusFound)
+	}
+	editPath := fmt.Sprintf("/admin/websites/%d/edit", id)
+
+	name := strings.TrimSpace(ctx.Input("name"))
+	if name == "" {
+		return ctx.FlashError("Token name is required").Redirect(editPath, fiber.StatusFound)
+	}
+
+	db := ctx.DB()
+	if _, err := websites.GetWebsiteByID(db, uint(id)); err != nil {
+		return ctx.FlashError("Website not found").Redirect("/admin", fiber.StatusFound)
+	}
+
+	plain, _, err := websites.CreateAPIToken(db, uint(id), name)
+	if err != nil {
+		ctx.Logger.Error("Failed to create API token", slog.Any("error", err), slog.Int("id", id))
+		return ctx.FlashError("Failed to create API token").Redirect(editPath, fiber.StatusFound)
+	}
+
+	return ctx.FlashSuccess(fmt.Sprintf("API token created: %s (copy it now, it won't be shown again)", plain)).Redirect(editPath, fiber.StatusFound)
+}
+
+// WebsiteAPITokenRevokeAction revokes one of a website's stats API tokens
+func WebsiteAPITokenRevokeAction(ctx *cartridge.Context) error {
+	id, err := ctx.ParamsInt("id")
+	if err != nil {
+		return ctx.FlashError("Invalid website ID").Redirect("/admin", fiber.StatusFound)
+	}
+	editPath := fmt.Sprintf("/admin/websites/%d/edit", id)
+
+	tokenID, err := ctx.ParamsInt("tokenId")
+	if err != nil {
+		return ctx.FlashError("Invalid token ID").Redirect(editPath, fiber.StatusFound)
+	}
+
+	if err := websites.RevokeAPIToken(ctx.DB(), uint(id), uint(tokenID)); err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return ctx.FlashError("API token not found").Redirect(editPath, fiber.StatusFound)
+		}
+		ctx.Logger.Error("Failed to revoke API token", slog.Any("error", err), slog.Int("id", id), slog.Int("token_id", tokenID))
+		return ctx.FlashError("Failed to revoke API token").Redirect(editPath, fiber.StatusFound)
+	}
+
+	return ctx.FlashSuccess("API token revoked").Redirect(editPath, fiber.StatusFound)
+}
@@ -1,7 +1,14 @@
 package http
 
 import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
 	"net/url"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
@@ -9,6 +16,7 @@ import (
 
 	"fusionaly/internal/analytics"
 	"fusionaly/internal/annotations"
+	"fusionaly/internal/settings"
 	"fusionaly/internal/timeframe"
 	websitesCtx "fusionaly/internal/websites"
 	"github.com/karloscodes/cartridge"
@@ -38,13 +46,7 @@ func WebsiteDashboardAction(ctx *cartridge.Context) error {
 		return ctx.Redirect("/admin/websites", fiber.StatusFound)
 	}
 
-	timeZone := ctx.Cookies("_tz")
-	if timeZone != "" {
-		if decodedTZ, err := url.QueryUnescape(timeZone); err == nil {
-			timeZone = decodedTZ
-		}
-	}
-
+	timeZone := dashboardTimeZone(ctx)
 	if timeZone == "" {
 		return ctx.Status(fiber.StatusBadRequest).SendString("Your cookies have issues, we can't continue")
 	}
@@ -56,34 +58,28 @@ func WebsiteDashboardAction(ctx *cartridge.Context) error {
 		slog.String("fromDate", ctx.Query("from")),
 		slog.String("toDate", ctx.Query("to")))
 
-	parser := timeframe.NewTimeFrameParser()
-
-	firstEvent, err := analytics.GetFirstPageView(db, websiteId)
-	firstEventDate := time.Now().UTC().Add(-time.Hour * 24 * 365 * 5)
-
+	timeFrame, err := parseDashboardTimeFrame(ctx, db, websiteId, timeZone)
 	if err != nil {
-		ctx.Logger.Warn("Error fetching first event date", slog.Any("error", err))
-	}
-	if firstEvent != nil {
-		firstEventDate = firstEvent.Timestamp
+		ctx.Logger.Error("Error parsing time frame", slog.Any("error", err))
+		return ctx.Status(fiber.StatusBadRequest).SendString("Invalid date range")
 	}
 
-	timeFrame, err := parser.ParseTimeFrame(timeframe.TimeFrameParserParams{
-		FromDate:            ctx.Query("from"),
-		ToDate:              ctx.Query("to"),
-		Tz:                  timeZone,
-		AllTimeFirstEventAt: firstEventDate,
-	})
+	comparisonTimeFrame, err := parseComparisonTimeFrame(ctx, timeZone)
 	if err != nil {
-		ctx.Logger.Error("Error parsing time frame", slog.Any("error", err))
-		return ctx.Status(fiber.StatusBadRequest).SendString("Invalid date range")
+		ctx.Logger.Warn("Error parsing comparison time frame", slog.Any("error", err))
+		return ctx.Status(fiber.StatusBadRequest).SendString(err.Error())
 	}
 
-	metrics, err := analytics.FetchDashboardMetrics(db, timeFrame, websiteId, ctx.Logger)
+	// Panels picked in the query string are loaded and cached with the metrics
+	trackParams := dashboardTrackParams(ctx, db, websiteId)
+	panelOpts := dashboardPanelOptions(ctx, db, website.Domain, trackParams)
+
+	entry, err := adminDashboardMetrics(ctx, db, timeFrame, websiteId, dashboardTopLimit(ctx), panelOpts)
 	if err != nil {
 		ctx.Logger.Error("Error fetching metrics", slog.Any("error", err))
 		return ctx.Status(fiber.StatusInternalServerError).SendString("Error fetching metrics")
 	}
+	metrics := entry.Metrics
 
 	websitesData, err := websitesCtx.GetWebsitesForSelector(db)
 	if err != nil {
@@ -97,20 +93,46 @@ func WebsiteDashboardAction(ctx *cartridge.Context) error {
 		annotationsList = []annotations.Annotation{}
 	}
 
-	props := structs.Map(metrics)
-	props["current_website_id"] = websiteId
-	props["website_domain"] = website.Domain
-	props["websites"] = websitesData
-	props["annotations"] = annotationsList
-	props["share_token"] = website.ShareToken
+	pageProps := map[string]interface{}{
+		"current_website_id": websiteId,
+		"website_domain":     website.Domain,
+		"websites":           websitesData,
+		"annotations":        annotationsList,
+		"share_token":        website.ShareToken,
+		"track_params":       trackParams,
+		"property_key":       panelOpts.PropertyKey,
+		"metric_event":       panelOpts.MetricEvent,
+		"metric_key":         panelOpts.MetricKey,
+		"metric_op":          panelOpts.MetricOp,
+	}
+
+	// Inertia navigations can revalidate against the ETag; the deferred props
+	// below are fetched by separate partial reloads and are not covered by it.
+	if ctx.Get("X-Inertia") != "" && ctx.Get("X-Inertia-Partial-Data") == "" {
+		etag, err := dashboardETag(ctx, entry, pageProps)
+		if err != nil {
+			ctx.Logger.Warn("Failed to compute dashboard ETag", slog.Any("error", err))
+		} else {
+			ctx.Set("ETag", etag)
+			ctx.Set("Cache-Control", "private, no-cache")
+			ctx.Set("Vary", "X-Inertia")
+			if etagMatches(ctx.Get("If-None-Match"), etag) {
+				return ctx.Status(fiber.StatusNotModified).Send(nil)
+			}
+		}
+	}
+
+	props := structs.Compose(structs.Map(metrics), pageProps)
 
 	props["comparison"] = inertia.Defer(func() interface{} {
-		return analytics.FetchComparisonMetrics(db, timeFrame, websiteId, metrics, ctx.Logger)
+		return analytics.FetchComparisonMetrics(db, timeFrame, comparisonTimeFrame, websiteId, metrics, ctx.Logger)
 	})
 
 	queryParams := analytics.NewWebsiteScopedQueryParams(timeFrame, websiteId)
+	queryParams.Filters = dashboardFilters(ctx)
+	flowDepth, flowStart := dashboardFlowDepth(ctx), dashboardFlowStart(ctx)
 	props["user_flow"] = inertia.Defer(func() interface{} {
-		flowData, err := analytics.GetUserFlowData(db, queryParams, 5)
+		flowData, err := analytics.GetUserFlowData(db, queryParams, flowDepth, flowStart)
 		if err != nil {
 			ctx.Logger.Error("Error fetching deferred user flow data", slog.Any("error", err))
 			return []analytics.UserFlowLink{}
@@ -120,3 +142,254 @@ func WebsiteDashboardAction(ctx *cartridge.Context) error {
 
 	return ctx.Inertia("Dashboard", props)
 }
+
+// dashboardETag derives a weak ETag from the request URL, the metrics and the
+// serialized page props outside them, so it changes whenever the query or the
+// rendered data does. Cached metrics are identified by their entry version, so
+// a revalidation served from the cache runs no query and serializes no metrics.
+func dashboardETag(ctx *cartridge.Context, entry analytics.DashboardEntry, pageProps map[string]interface{}) (string, error) {
+	metrics := []byte(entry.Version())
+	if len(metrics) == 0 {
+		content, err := json.Marshal(entry.Metrics)
+		if err != nil {
+			return "", err
+		}
+		metrics = content
+	}
+	content, err := json.Marshal(pageProps)
+	if err != nil {
+		return "", err
+	}
+	hash := sha256.New()
+	hash.Write([]byte(ctx.OriginalURL()))
+	hash.Write([]byte{0})
+	hash.Write(metrics)
+	hash.Write([]byte{0})
+	hash.Write(content)
+	return `W/"` + hex.EncodeToString(hash.Sum(nil)) + `"`, nil
+}
+
+// etagMatches reports whether an If-None-Match header matches etag using the
+// weak comparison from RFC 9110.
+func etagMatches(ifNoneMatch, etag string) bool {
+	if ifNoneMatch == "" {
+		return false
+	}
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+	return false
+}
+
+// WebsiteExportCSVAction streams the dashboard metrics for the selected time frame as CSV
+func WebsiteExportCSVAction(ctx *cartridge.Context) error {
+	websiteId, err := ctx.ParamsInt("id")
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).SendString("Invalid website ID")
+	}
+
+	db := ctx.DB()
+
+	website, err := websitesCtx.GetWebsiteByID(db, uint(websiteId))
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return ctx.Status(fiber.StatusNotFound).SendString("Website not found")
+		}
+		ctx.Logger.Error("Failed to get website", slog.Any("error", err))
+		return ctx.Status(fiber.StatusInternalServerError).SendString("Error fetching website")
+	}
+
+	timeZone := dashboardTimeZone(ctx)
+	if timeZone == "" {
+		return ctx.Status(fiber.StatusBadRequest).SendString("Your cookies have issues, we can't continue")
+	}
+
+	timeFrame, err := parseDashboardTimeFrame(ctx, db, websiteId, timeZone)
+	if err != nil {
+		ctx.Logger.Error("Error parsing time frame", slog.Any("error", err))
+		return ctx.Status(fiber.StatusBadRequest).SendString("Invalid date range")
+	}
+
+	metrics, err := analytics.FetchDashboardMetrics(db, timeFrame, websiteId, dashboardFilters(ctx), dashboardTopLimit(ctx), ctx.Logger)
+	if err != nil {
+		ctx.Logger.Error("Error fetching metrics", slog.Any("error", err))
+		return ctx.Status(fiber.StatusInternalServerError).SendString("Error fetching metrics")
+	}
+
+	locale := analytics.ParseNumberLocale(ctx.Query("locale"))
+	filename := fmt.Sprintf("%s-%s-%s.csv",
+		website.Domain,
+		timeFrame.From.In(timeFrame.Tz).Format("2006-01-02"),
+		timeFrame.To.In(timeFrame.Tz).Format("2006-01-02"))
+
+	ctx.Set("Content-Type", "text/csv; charset=utf-8")
+	ctx.Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+
+	logger := ctx.Logger
+	ctx.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		if err := analytics.WriteDashboardCSV(w, metrics, locale); err != nil {
+			logger.Error("Failed to stream CSV export", slog.Any("error", err))
+		}
+	})
+
+	return nil
+}
+
+// dashboardTimeZone returns the browser time zone stored in the _tz cookie
+func dashboardTimeZone(ctx *cartridge.Context) string {
+	timeZone := ctx.Cookies("_tz")
+	if timeZone != "" {
+		if decodedTZ, err := url.QueryUnescape(timeZone); err == nil {
+			timeZone = decodedTZ
+		}
+	}
+	return timeZone
+}
+
+// dashboardFilters reads segment filters (e.g. ?browser=Chrome&country=US) from the query string
+func dashboardFilters(ctx *cartridge.Context) map[string]string {
+	values := make(map[string]string)
+	for _, key := range analytics.SegmentFilterKeys() {
+		values[key] = ctx.Query(key)
+	}
+	return analytics.NewSegmentFilters(values)
+}
+
+// dashboardTopLimit reads the number of entries of top-N lists from the limit
+// query parameter, clamped to the accepted range rather than rejected.
+func dashboardTopLimit(ctx *cartridge.Context) int {
+	limit, err := strconv.Atoi(ctx.Query("limit"))
+	if err != nil {
+		return analytics.DefaultTopLimit
+	}
+	return analytics.ClampTopLimit(limit)
+}
+
+// dashboardTrackParams returns the query parameters whose values the dashboard
+// breaks down besides ref: the comma-separated track_params query parameter
+// when present, otherwise the website's saved setting.
+func dashboardTrackParams(ctx *cartridge.Context, db *gorm.DB, websiteId int) []string {
+	params := settings.GetDashboardTrackParams(db, uint(websiteId))
+	if value := ctx.Query("track_params"); value != "" {
+		params = settings.ParseTrackParams(value)
+	}
+	if len(params) > settings.MaxTrackParams {
+		params = params[:settings.MaxTrackParams]
+	}
+	if params == nil {
+		params = []string{}
+	}
+	return params
+}
+
+// dashboardMetrics fetches the dashboard metrics through the short-lived
+// dashboard cache, unless the request opts out with ?nocache=1 for debugging.
+func dashboardMetrics(ctx *cartridge.Context, db *gorm.DB, tf *timeframe.TimeFrame, websiteId int, limit int) (*analytics.DashboardMetrics, error) {
+	if ctx.QueryBool("nocache") {
+		return analytics.FetchDashboardMetrics(db, tf, websiteId, dashboardFilters(ctx), limit, ctx.Logger)
+	}
+	return analytics.CachedDashboardMetrics(db, tf, websiteId, dashboardFilters(ctx), limit, ctx.Logger)
+}
+
+// adminDashboardMetrics is dashboardMetrics plus the panels picked in opts.
+func adminDashboardMetrics(ctx *cartridge.Context, db *gorm.DB, tf *timeframe.TimeFrame, websiteId int, limit int, opts analytics.DashboardPanelOptions) (analytics.DashboardEntry, error) {
+	if ctx.QueryBool("nocache") {
+		metrics, err := analytics.FetchAdminDashboardMetrics(db, tf, websiteId, dashboardFilters(ctx), limit, opts, ctx.Logger)
+		return analytics.DashboardEntry{Metrics: metrics}, err
+	}
+	return analytics.CachedAdminDashboardMetrics(db, tf, websiteId, dashboardFilters(ctx), limit, opts, ctx.Logger)
+}
+
+// dashboardPanelOptions reads the panels picked in the query string: the
+// custom property (?property=), the numeric metadata aggregation
+// (?metric_event=, ?metric_key= and ?metric_op=) and, for websites rolling
+// subdomains up, the subdomain breakdown.
+func dashboardPanelOptions(ctx *cartridge.Context, db *gorm.DB, domain string, trackParams []string) analytics.DashboardPanelOptions {
+	metricOp, err := analytics.ParseAggOp(ctx.Query("metric_op"))
+	if err != nil {
+		ctx.Logger.Warn("Invalid metric aggregation, using sum", slog.Any("error", err))
+		metricOp = analytics.AggSum
+	}
+	return analytics.DashboardPanelOptions{
+		TrackParams: trackParams,
+		PropertyKey: strings.TrimSpace(ctx.Query("property")),
+		MetricEvent: strings.TrimSpace(ctx.Query("metric_event")),
+		MetricKey:   strings.TrimSpace(ctx.Query("metric_key")),
+		MetricOp:    metricOp,
+		Subdomains:  settings.IsSubdomainRollupEnabled(db, domain),
+	}
+}
+
+// dashboardFlowDepth reads the number of user flow steps from the flow_depth
+// query parameter, clamped to the aggregated depth.
+func dashboardFlowDepth(ctx *cartridge.Context) int {
+	depth, err := strconv.Atoi(ctx.Query("flow_depth"))
+	if err != nil {
+		return analytics.DefaultFlowDepth
+	}
+	return analytics.ClampFlowDepth(depth)
+}
+
+// dashboardFlowStart reads the entry pathname user flows start from
+// (flow_start query parameter); anything not starting with "/" is ignored.
+func dashboardFlowStart(ctx *cartridge.Context) string {
+	start := strings.TrimSpace(ctx.Query("flow_start"))
+	if !strings.HasPrefix(start, "/") {
+		return ""
+	}
+	return start
+}
+
+// parseDashboardTimeFrame parses the from/to query parameters of a dashboard
+// view; "all time" starts at the website's first page view.
+func parseDashboardTimeFrame(ctx *cartridge.Context, db *gorm.DB, websiteId int, timeZone string) (*timeframe.TimeFrame, error) {
+	firstEvent, err := analytics.GetFirstPageView(db, websiteId)
+	firstEventDate := time.Now().UTC().Add(-time.Hour * 24 * 365 * 5)
+
+	if err != nil {
+		ctx.Logger.Warn("Error fetching first event date", slog.Any("error", err))
+	}
+	if firstEvent != nil {
+		firstEventDate = firstEvent.Timestamp
+	}
+
+	return timeframe.NewTimeFrameParser().ParseTimeFrame(timeframe.TimeFrameParserParams{
+		FromDate:            ctx.Query("from"),
+		ToDate:              ctx.Query("to"),
+		Tz:                  timeZone,
+		AllTimeFirstEventAt: firstEventDate,
+	})
+}
+
+// parseComparisonTimeFrame parses the optional compare_from/compare_to query
+// parameters. It returns nil when neither is set, so the dashboard compares
+// against the previous period.
+func parseComparisonTimeFrame(ctx *cartridge.Context, timeZone string) (*timeframe.TimeFrame, error) {
+	compareFrom := ctx.Query("compare_from")
+	compareTo := ctx.Query("compare_to")
+	if compareFrom == "" && compareTo == "" {
+		return nil, nil
+	}
+	if compareFrom == "" || compareTo == "" {
+		return nil, fmt.Errorf("both compare_from and compare_to are required for a custom comparison")
+	}
+
+	from, fromErr := time.Parse("2006-01-02", compareFrom)
+	to, toErr := time.Parse("2006-01-02", compareTo)
+	if fromErr == nil && toErr == nil && from.After(to) {
+		return nil, fmt.Errorf("invalid comparison range: compare_from must not be after compare_to")
+	}
+
+	comparisonTimeFrame, err := timeframe.NewTimeFrameParser().ParseTimeFrame(timeframe.TimeFrameParserParams{
+		FromDate: compareFrom,
+		ToDate:   compareTo,
+		Tz:       timeZone,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("invalid comparison range: %w", err)
+	}
+	return comparisonTimeFrame, nil
+}
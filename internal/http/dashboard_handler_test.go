@@ -0,0 +1,326 @@
+package http_test
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"fusionaly/internal/analytics"
+	"fusionaly/internal/events"
+	"fusionaly/internal/settings"
+	"fusionaly/internal/testsupport"
+)
+
+func TestWebsiteExportCSVAction(t *testing.T) {
+	dbManager, logger, website := testsupport.SetupTestDBManagerWithWebsite(t, "export.com")
+	db := dbManager.GetConnection()
+
+	day := time.Date(2024, 7, 1, 10, 0, 0, 0, time.UTC)
+	for i, path := range []string{"/", "/pricing", "/pricing"} {
+		ts := day.Add(time.Duration(i) * time.Minute)
+		require.NoError(t, db.Create(&events.IngestedEvent{
+			WebsiteID:        website.ID,
+			UserSignature:    fmt.Sprintf("visitor-%d", i),
+			Hostname:         website.Domain,
+			Pathname:         path,
+			RawURL:           "https://" + website.Domain + path,
+			ReferrerHostname: events.DirectOrUnknownReferrer,
+			EventType:        events.EventTypePageView,
+			Timestamp:        ts,
+			UserAgent:        "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36",
+			Country:          "US",
+			CreatedAt:        ts,
+		}).Error)
+	}
+	require.NoError(t, testsupport.ProcessAllTestEvents(dbManager, logger))
+
+	testsupport.CreateTestUserForAuth(t, db, "admin@export.com", "password123")
+	app := testsupport.CreateMinimalTestApp(t, db)
+	session := testsupport.LoginTestUser(t, app, "admin@export.com", "password123")
+
+	req := httptest.NewRequest("GET", fmt.Sprintf("/admin/websites/%d/export.csv?from=2024-07-01&to=2024-07-07", website.ID), nil)
+	req.Header.Set("User-Agent", "Mozilla/5.0 Test Browser")
+	req.Header.Set("Sec-Fetch-Site", "same-origin")
+	req.Header.Set("Cookie", fmt.Sprintf("%s=%s; _tz=UTC", testsupport.SessionCookieName, session))
+
+	resp, err := app.Test(req, 30000)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	assert.Equal(t, "text/csv; charset=utf-8", resp.Header.Get("Content-Type"))
+	assert.Equal(t, `attachment; filename="export.com-2024-07-01-2024-07-07.csv"`, resp.Header.Get("Content-Disposition"))
+
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+
+	reader := csv.NewReader(strings.NewReader(string(body)))
+	reader.FieldsPerRecord = -1
+	records, err := reader.ReadAll()
+	require.NoError(t, err)
+	require.NotEmpty(t, records)
+
+	assert.Equal(t, []string{"Date", "Page Views", "Visitors", "Sessions", "Revenue"}, records[0])
+
+	var dayRow, pricingRow []string
+	for i, record := range records {
+		if strings.HasPrefix(record[0], "2024-07-01") {
+			dayRow = record
+		}
+		if record[0] == "Top Pages" && i+1 < len(records) {
+			pricingRow = records[i+1]
+		}
+	}
+	require.NotNil(t, dayRow, "expected a time series row for 2024-07-01")
+	assert.Equal(t, "3", dayRow[1], "page views")
+	assert.Equal(t, "0", dayRow[4], "revenue")
+
+	require.NotNil(t, pricingRow, "expected a Top Pages table")
+	assert.Equal(t, []string{"export.com/pricing", "2"}, pricingRow)
+}
+
+func TestWebsiteDashboardActionComparisonRange(t *testing.T) {
+	dbManager, _, website := testsupport.SetupTestDBManagerWithWebsite(t, "compare.com")
+	db := dbManager.GetConnection()
+
+	testsupport.CreateTestUserForAuth(t, db, "admin@compare.com", "password123")
+	app := testsupport.CreateMinimalTestApp(t, db)
+	session := testsupport.LoginTestUser(t, app, "admin@compare.com", "password123")
+
+	get := func(query string) (int, string) {
+		req := httptest.NewRequest("GET", fmt.Sprintf("/admin/websites/%d/dashboard?from=2024-07-01&to=2024-07-31&%s", website.ID, query), nil)
+		req.Header.Set("User-Agent", "Mozilla/5.0 Test Browser")
+		req.Header.Set("Sec-Fetch-Site", "same-origin")
+		req.Header.Set("Cookie", fmt.Sprintf("%s=%s; _tz=UTC", testsupport.SessionCookieName, session))
+
+		resp, err := app.Test(req, 30000)
+		require.NoError(t, err)
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return resp.StatusCode, string(body)
+	}
+
+	t.Run("rejects inverted ranges", func(t *testing.T) {
+		status, body := get("compare_from=2023-07-31&compare_to=2023-07-01")
+		assert.Equal(t, http.StatusBadRequest, status)
+		assert.Contains(t, body, "compare_from must not be after compare_to")
+	})
+
+	t.Run("requires both bounds", func(t *testing.T) {
+		status, body := get("compare_from=2023-07-01")
+		assert.Equal(t, http.StatusBadRequest, status)
+		assert.Contains(t, body, "both compare_from and compare_to are required")
+	})
+
+	t.Run("rejects malformed dates", func(t *testing.T) {
+		status, _ := get("compare_from=last-year&compare_to=2023-07-31")
+		assert.Equal(t, http.StatusBadRequest, status)
+	})
+}
+
+func TestWebsiteDashboardActionTopLimit(t *testing.T) {
+	dbManager, _, website := testsupport.SetupTestDBManagerWithWebsite(t, "limit.com")
+	db := dbManager.GetConnection()
+
+	hour := time.Date(2024, 7, 1, 12, 0, 0, 0, time.UTC)
+	for i := 0; i < 7; i++ {
+		require.NoError(t, db.Create(&analytics.PageStat{
+			WebsiteID:      website.ID,
+			Hostname:       website.Domain,
+			Pathname:       fmt.Sprintf("/page-%d", i),
+			PageViewsCount: 10 - i,
+			VisitorsCount:  10 - i,
+			Hour:           hour,
+		}).Error)
+	}
+
+	testsupport.CreateTestUserForAuth(t, db, "admin@limit.com", "password123")
+	app := testsupport.CreateMinimalTestApp(t, db)
+	session := testsupport.LoginTestUser(t, app, "admin@limit.com", "password123")
+
+	topURLs := func(query string) []interface{} {
+		req := httptest.NewRequest("GET", fmt.Sprintf("/admin/websites/%d/dashboard?from=2024-07-01&to=2024-07-31&%s", website.ID, query), nil)
+		req.Header.Set("User-Agent", "Mozilla/5.0 Test Browser")
+		req.Header.Set("Sec-Fetch-Site", "same-origin")
+		req.Header.Set("X-Inertia", "true")
+		req.Header.Set("Cookie", fmt.Sprintf("%s=%s; _tz=UTC", testsupport.SessionCookieName, session))
+
+		resp, err := app.Test(req, 30000)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		var page struct {
+			Props map[string]interface{} `json:"props"`
+		}
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&page))
+		urls, ok := page.Props["top_urls"].([]interface{})
+		require.True(t, ok, "expected top_urls in the dashboard props")
+		return urls
+	}
+
+	t.Run("defaults when absent", func(t *testing.T) {
+		assert.Len(t, topURLs(""), 7)
+	})
+
+	t.Run("applies the limit", func(t *testing.T) {
+		assert.Len(t, topURLs("limit=6"), 6)
+	})
+
+	t.Run("clamps out-of-range values", func(t *testing.T) {
+		assert.Len(t, topURLs("limit=1"), analytics.MinTopLimit)
+		assert.Len(t, topURLs("limit=1000"), 7)
+	})
+
+	t.Run("ignores malformed values", func(t *testing.T) {
+		assert.Len(t, topURLs("limit=lots"), 7)
+	})
+}
+
+func TestWebsiteDashboardActionETag(t *testing.T) {
+	dbManager, logger, website := testsupport.SetupTestDBManagerWithWebsite(t, "etag.com")
+	db := dbManager.GetConnection()
+
+	pageView := func(visitor string, ts time.Time) {
+		require.NoError(t, db.Create(&events.IngestedEvent{
+			WebsiteID:        website.ID,
+			UserSignature:    visitor,
+			Hostname:         website.Domain,
+			Pathname:         "/",
+			RawURL:           "https://" + website.Domain + "/",
+			ReferrerHostname: events.DirectOrUnknownReferrer,
+			EventType:        events.EventTypePageView,
+			Timestamp:        ts,
+			UserAgent:        "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36",
+			Country:          "US",
+			CreatedAt:        ts,
+		}).Error)
+		require.NoError(t, testsupport.ProcessAllTestEvents(dbManager, logger))
+	}
+	pageView("visitor-1", time.Date(2024, 7, 1, 10, 0, 0, 0, time.UTC))
+
+	testsupport.CreateTestUserForAuth(t, db, "admin@etag.com", "password123")
+	app := testsupport.CreateMinimalTestApp(t, db)
+	session := testsupport.LoginTestUser(t, app, "admin@etag.com", "password123")
+
+	get := func(ifNoneMatch string) *http.Response {
+		req := httptest.NewRequest("GET", fmt.Sprintf("/admin/websites/%d/dashboard?from=2024-07-01&to=2024-07-31", website.ID), nil)
+		req.Header.Set("User-Agent", "Mozilla/5.0 Test Browser")
+		req.Header.Set("Sec-Fetch-Site", "same-origin")
+		req.Header.Set("X-Inertia", "true")
+		req.Header.Set("Cookie", fmt.Sprintf("%s=%s; _tz=UTC", testsupport.SessionCookieName, session))
+		if ifNoneMatch != "" {
+			req.Header.Set("If-None-Match", ifNoneMatch)
+		}
+
+		resp, err := app.Test(req, 30000)
+		require.NoError(t, err)
+		return resp
+	}
+
+	first := get("")
+	require.Equal(t, http.StatusOK, first.StatusCode)
+	etag := first.Header.Get("ETag")
+	require.True(t, strings.HasPrefix(etag, `W/"`), "expected a weak ETag, got %q", etag)
+
+	t.Run("unchanged data returns 304", func(t *testing.T) {
+		resp := get(etag)
+		assert.Equal(t, http.StatusNotModified, resp.StatusCode)
+		assert.Equal(t, etag, resp.Header.Get("ETag"))
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		assert.Empty(t, body)
+	})
+
+	t.Run("newly processed events change the ETag", func(t *testing.T) {
+		pageView("visitor-2", time.Date(2024, 7, 2, 10, 0, 0, 0, time.UTC))
+
+		resp := get(etag)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.NotEqual(t, etag, resp.Header.Get("ETag"))
+	})
+
+	t.Run("cached metrics revalidate until the website is invalidated", func(t *testing.T) {
+		analytics.SetDashboardCacheTTL(time.Minute)
+		t.Cleanup(func() { analytics.SetDashboardCacheTTL(0) })
+
+		resp := get("")
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		cached := resp.Header.Get("ETag")
+		assert.Equal(t, http.StatusNotModified, get(cached).StatusCode)
+
+		// Served from the cache until the processor invalidates the website
+		pageView("visitor-3", time.Date(2024, 7, 3, 10, 0, 0, 0, time.UTC))
+		assert.Equal(t, http.StatusNotModified, get(cached).StatusCode)
+
+		analytics.InvalidateDashboardCache(int(website.ID))
+		resp = get(cached)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.NotEqual(t, cached, resp.Header.Get("ETag"))
+	})
+}
+
+func TestWebsiteDashboardActionTrackParams(t *testing.T) {
+	dbManager, _, website := testsupport.SetupTestDBManagerWithWebsite(t, "params.com")
+	db := dbManager.GetConnection()
+
+	hour := time.Date(2024, 7, 1, 12, 0, 0, 0, time.UTC)
+	stats := []analytics.QueryParamStat{
+		{WebsiteID: website.ID, ParamName: "ref", ParamValue: "newsletter", VisitorsCount: 4, PageViewsCount: 4, Hour: hour},
+		{WebsiteID: website.ID, ParamName: "variant", ParamValue: "a", VisitorsCount: 5, PageViewsCount: 6, Hour: hour},
+		{WebsiteID: website.ID, ParamName: "variant", ParamValue: "b", VisitorsCount: 3, PageViewsCount: 3, Hour: hour},
+		{WebsiteID: website.ID, ParamName: "plan", ParamValue: "pro", VisitorsCount: 2, PageViewsCount: 2, Hour: hour},
+		{WebsiteID: website.ID, ParamName: "gclid", ParamValue: "xyz", VisitorsCount: 9, PageViewsCount: 9, Hour: hour},
+	}
+	require.NoError(t, db.Create(&stats).Error)
+	require.NoError(t, settings.SaveDashboardTrackParams(db, website.ID, []string{"variant", "plan"}))
+
+	testsupport.CreateTestUserForAuth(t, db, "admin@params.com", "password123")
+	app := testsupport.CreateMinimalTestApp(t, db)
+	session := testsupport.LoginTestUser(t, app, "admin@params.com", "password123")
+
+	queryParams := func(query string) map[string][]analytics.MetricCountResult {
+		req := httptest.NewRequest("GET", fmt.Sprintf("/admin/websites/%d/dashboard?from=2024-07-01&to=2024-07-31&%s", website.ID, query), nil)
+		req.Header.Set("User-Agent", "Mozilla/5.0 Test Browser")
+		req.Header.Set("Sec-Fetch-Site", "same-origin")
+		req.Header.Set("X-Inertia", "true")
+		req.Header.Set("Cookie", fmt.Sprintf("%s=%s; _tz=UTC", testsupport.SessionCookieName, session))
+
+		resp, err := app.Test(req, 30000)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		var page struct {
+			Props struct {
+				TopQueryParams map[string][]analytics.MetricCountResult `json:"top_query_params"`
+				TopRefParams   []analytics.MetricCountResult            `json:"top_ref_params"`
+			} `json:"props"`
+		}
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&page))
+		assert.Equal(t, []analytics.MetricCountResult{{Name: "newsletter", Count: 4}}, page.Props.TopRefParams, "ref keeps its own prop")
+		return page.Props.TopQueryParams
+	}
+
+	t.Run("saved params get independent breakdowns", func(t *testing.T) {
+		breakdowns := queryParams("")
+		assert.Len(t, breakdowns, 3)
+		assert.Equal(t, []analytics.MetricCountResult{{Name: "newsletter", Count: 4}}, breakdowns["ref"])
+		assert.Equal(t, []analytics.MetricCountResult{{Name: "a", Count: 5}, {Name: "b", Count: 3}}, breakdowns["variant"])
+		assert.Equal(t, []analytics.MetricCountResult{{Name: "pro", Count: 2}}, breakdowns["plan"])
+	})
+
+	t.Run("track_params query overrides the saved setting", func(t *testing.T) {
+		breakdowns := queryParams("track_params=gclid,unseen")
+		assert.Len(t, breakdowns, 3)
+		assert.Equal(t, []analytics.MetricCountResult{}, breakdowns["unseen"])
+		assert.Equal(t, []analytics.MetricCountResult{{Name: "xyz", Count: 9}}, breakdowns["gclid"])
+		assert.NotContains(t, breakdowns, "variant")
+	})
+}
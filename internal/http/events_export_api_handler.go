@@ -0,0 +1,59 @@
+package http
+
+import (
+	"bufio"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/karloscodes/cartridge"
+
+	"fusionaly/internal/events"
+	"fusionaly/internal/http/middleware"
+)
+
+// EventsExportAPIAction streams the raw events of the website an API token
+// belongs to as newline-delimited JSON.
+// Query params: website_id (required), from, to, tz.
+func EventsExportAPIAction(ctx *cartridge.Context) error {
+	websiteId, err := strconv.Atoi(ctx.Query("website_id"))
+	if err != nil || websiteId <= 0 {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "website_id is required and must be positive",
+		})
+	}
+
+	tokenWebsiteID, _ := ctx.Locals(middleware.APITokenWebsiteIDKey).(uint)
+	if tokenWebsiteID != uint(websiteId) {
+		return ctx.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "API token is not valid for this website",
+		})
+	}
+
+	timeZone := ctx.Query("tz", "UTC")
+	if _, err := time.LoadLocation(timeZone); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid time zone",
+		})
+	}
+
+	db := ctx.DB()
+	timeFrame, err := parseDashboardTimeFrame(ctx, db, websiteId, timeZone)
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid time range",
+		})
+	}
+
+	ctx.Set("Content-Type", "application/x-ndjson")
+
+	logger := ctx.Logger
+	ctx.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		if err := events.WriteEventsNDJSON(w, db, uint(websiteId), timeFrame.From, timeFrame.To); err != nil {
+			logger.Error("Failed to stream events export", slog.Any("error", err), slog.Int("website_id", websiteId))
+		}
+	})
+
+	return nil
+}
@@ -0,0 +1,100 @@
+package http_test
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"fusionaly/internal/events"
+	"fusionaly/internal/testsupport"
+	"fusionaly/internal/websites"
+)
+
+func TestEventsExportAPIAction(t *testing.T) {
+	dbManager, _, website := testsupport.SetupTestDBManagerWithWebsite(t, "export-api.com")
+	db := dbManager.GetConnection()
+	otherWebsite := testsupport.CreateTestWebsite(db, "other-export-api.com")
+
+	event := func(websiteID uint, ts time.Time, path string) events.Event {
+		return events.Event{
+			WebsiteID:        websiteID,
+			UserSignature:    "visitor",
+			Hostname:         "export-api.com",
+			Pathname:         path,
+			ReferrerHostname: events.DirectOrUnknownReferrer,
+			EventType:        events.EventTypePageView,
+			Timestamp:        ts,
+		}
+	}
+
+	// More than one page of in-range events, interleaved with rows that must be skipped
+	inRange := time.Date(2024, 7, 2, 0, 0, 0, 0, time.UTC)
+	var seeded []events.Event
+	for i := 0; i < 1005; i++ {
+		seeded = append(seeded, event(website.ID, inRange.Add(time.Duration(i)*time.Minute), fmt.Sprintf("/page-%d", i)))
+		if i%100 == 0 {
+			seeded = append(seeded,
+				event(website.ID, time.Date(2024, 6, 15, 12, 0, 0, 0, time.UTC), "/before"),
+				event(website.ID, time.Date(2024, 7, 20, 12, 0, 0, 0, time.UTC), "/after"),
+				event(otherWebsite.ID, inRange, "/other"),
+			)
+		}
+	}
+	require.NoError(t, db.CreateInBatches(seeded, 200).Error)
+
+	token, _, err := websites.CreateAPIToken(db, website.ID, "warehouse")
+	require.NoError(t, err)
+
+	app := testsupport.CreateMinimalTestApp(t, db)
+
+	get := func(t *testing.T, query string) *http.Response {
+		t.Helper()
+		req := httptest.NewRequest("GET", "/api/v1/export/events?"+query, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		resp, err := app.Test(req, 30000)
+		require.NoError(t, err)
+		return resp
+	}
+
+	t.Run("streams events in range as NDJSON", func(t *testing.T) {
+		resp := get(t, fmt.Sprintf("website_id=%d&from=2024-07-01&to=2024-07-07&tz=UTC", website.ID))
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "application/x-ndjson", resp.Header.Get("Content-Type"))
+
+		from := time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC)
+		to := time.Date(2024, 7, 8, 0, 0, 0, 0, time.UTC)
+
+		var exported []events.Event
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			var e events.Event
+			require.NoError(t, json.Unmarshal(scanner.Bytes(), &e), scanner.Text())
+			exported = append(exported, e)
+		}
+		require.NoError(t, scanner.Err())
+
+		require.Len(t, exported, 1005)
+		for i, e := range exported {
+			assert.Equal(t, website.ID, e.WebsiteID)
+			assert.False(t, e.Timestamp.Before(from), "event %d is before the range", e.ID)
+			assert.True(t, e.Timestamp.Before(to), "event %d is after the range", e.ID)
+			if i > 0 {
+				assert.Greater(t, e.ID, exported[i-1].ID, "events are ordered by ID")
+			}
+		}
+		assert.Equal(t, "/page-0", exported[0].Pathname)
+		assert.Equal(t, "/page-1004", exported[1004].Pathname)
+	})
+
+	t.Run("scopes token to its website", func(t *testing.T) {
+		resp := get(t, fmt.Sprintf("website_id=%d&from=2024-07-01&to=2024-07-07", otherWebsite.ID))
+		assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	})
+}
@@ -0,0 +1,69 @@
+package http
+
+import (
+	"log/slog"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/karloscodes/cartridge"
+
+	"fusionaly/internal/analytics"
+)
+
+// WebsiteFunnelAction returns a funnel for the dashboard time range (JSON API).
+// Steps are passed in order as a comma separated list or repeated params, e.g.
+// ?steps=/pricing,/signup,event:account_created
+func WebsiteFunnelAction(ctx *cartridge.Context) error {
+	websiteId, err := ctx.ParamsInt("id")
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid website ID",
+		})
+	}
+
+	var steps []analytics.FunnelStep
+	for _, param := range ctx.Context().QueryArgs().PeekMulti("steps") {
+		for _, raw := range strings.Split(string(param), ",") {
+			if strings.TrimSpace(raw) == "" {
+				continue
+			}
+			step, err := analytics.ParseFunnelStep(raw)
+			if err != nil {
+				return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+					"error": err.Error(),
+				})
+			}
+			steps = append(steps, step)
+		}
+	}
+	if len(steps) < 2 || len(steps) > analytics.MaxFunnelSteps {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "A funnel needs between 2 and 10 steps",
+		})
+	}
+
+	timeZone := dashboardTimeZone(ctx)
+	if timeZone == "" {
+		timeZone = "UTC"
+	}
+
+	db := ctx.DB()
+	timeFrame, err := parseDashboardTimeFrame(ctx, db, websiteId, timeZone)
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid time range",
+		})
+	}
+
+	results, err := analytics.GetFunnel(db, analytics.NewWebsiteScopedQueryParams(timeFrame, websiteId), steps)
+	if err != nil {
+		ctx.Logger.Error("Failed to compute funnel", slog.Any("error", err), slog.Int("website_id", websiteId))
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to compute funnel",
+		})
+	}
+
+	return ctx.JSON(fiber.Map{
+		"steps": results,
+	})
+}
@@ -5,40 +5,42 @@ import (
 
 	"log/slog"
 
+	"github.com/gofiber/fiber/v2"
 	"github.com/karloscodes/cartridge"
+
+	"fusionaly/internal/health"
 )
 
 // HealthStatus represents the health check response
 type HealthStatus struct {
-	Status    string    `json:"status"`
-	Timestamp time.Time `json:"timestamp"`
-	DBStatus  string    `json:"db_status"`
+	Status            string     `json:"status"`
+	Timestamp         time.Time  `json:"timestamp"`
+	DBStatus          string     `json:"db_status"`
+	Backlog           int64      `json:"backlog"`
+	BatchSize         int64      `json:"batch_size"` // Batch size the backlog was last processed with
+	LastProcessingRun *time.Time `json:"last_processing_run"`
 }
 
-// HealthIndexAction handles the health check endpoint
-func HealthIndexAction(ctx *cartridge.Context) error {
-	dbStatus := "ok"
+// ReadyStatus is the readiness response: the health checks plus database reachability
+type ReadyStatus struct {
+	health.ReadinessStatus
+	DBStatus string `json:"db_status"`
+}
 
-	// Check database connectivity
-	db := ctx.DBManager.GetConnection()
-	if db == nil {
-		dbStatus = "error"
-		ctx.Logger.Error("Database connection unavailable")
-	} else {
-		sqlDB, err := db.DB()
-		if err != nil {
-			dbStatus = "error"
-			ctx.Logger.Error("Database connection error", slog.Any("error", err))
-		} else if err := sqlDB.Ping(); err != nil {
-			dbStatus = "error"
-			ctx.Logger.Error("Database ping failed", slog.Any("error", err))
-		}
-	}
+// HealthIndexAction handles the liveness endpoint. It reports the database and
+// processing backlog but always returns 200 so a slow backlog never restarts
+// the container; /_ready is the endpoint that gates on them.
+func HealthIndexAction(ctx *cartridge.Context) error {
+	dbStatus := databaseStatus(ctx)
+	readiness := health.Readiness()
 
 	health := HealthStatus{
-		Status:    "ok",
-		Timestamp: time.Now(),
-		DBStatus:  dbStatus,
+		Status:            "ok",
+		Timestamp:         time.Now(),
+		DBStatus:          dbStatus,
+		Backlog:           readiness.Backlog,
+		BatchSize:         health.ProcessingBatchSize(),
+		LastProcessingRun: readiness.LastProcessingRun,
 	}
 
 	if dbStatus != "ok" {
@@ -47,3 +49,42 @@ func HealthIndexAction(ctx *cartridge.Context) error {
 
 	return ctx.JSON(health)
 }
+
+// ReadyIndexAction handles the readiness endpoint used for blue-green switching.
+// Unlike /_health it returns 503 until migrations are done and the event
+// processor loop is running, and whenever the database is unreachable or the
+// unprocessed backlog exceeds FUSIONALY_READINESS_BACKLOG_THRESHOLD.
+func ReadyIndexAction(ctx *cartridge.Context) error {
+	status := ReadyStatus{
+		ReadinessStatus: health.Readiness(),
+		DBStatus:        databaseStatus(ctx),
+	}
+	if status.DBStatus != "ok" {
+		status.Ready = false
+	}
+
+	if !status.Ready {
+		return ctx.Status(fiber.StatusServiceUnavailable).JSON(status)
+	}
+	return ctx.JSON(status)
+}
+
+// databaseStatus pings the database and returns "ok" or "error"
+func databaseStatus(ctx *cartridge.Context) string {
+	db := ctx.DBManager.GetConnection()
+	if db == nil {
+		ctx.Logger.Error("Database connection unavailable")
+		return "error"
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		ctx.Logger.Error("Database connection error", slog.Any("error", err))
+		return "error"
+	}
+	if err := sqlDB.Ping(); err != nil {
+		ctx.Logger.Error("Database ping failed", slog.Any("error", err))
+		return "error"
+	}
+	return "ok"
+}
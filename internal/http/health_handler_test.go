@@ -0,0 +1,122 @@
+package http_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"fusionaly/internal/events"
+	"fusionaly/internal/health"
+	"fusionaly/internal/jobs"
+	"fusionaly/internal/testsupport"
+)
+
+func TestReadyIndexAction(t *testing.T) {
+	dbManager, _ := testsupport.SetupTestDBManager(t)
+	app := testsupport.CreateMinimalTestApp(t, dbManager.GetConnection())
+
+	t.Cleanup(func() {
+		health.SetMigrated(false)
+		health.SetProcessorRunning(false)
+		health.SetBacklogThreshold(0)
+		health.SetAggregationLag(0, 0)
+	})
+
+	t.Run("not ready while migrations are running", func(t *testing.T) {
+		health.SetMigrated(false)
+		health.SetProcessorRunning(false)
+
+		resp, err := app.Test(httptest.NewRequest("GET", "/_ready", nil), 30000)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+
+		// Liveness is unaffected
+		resp, err = app.Test(httptest.NewRequest("GET", "/_health", nil), 30000)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+	})
+
+	t.Run("ready after migrations and processor start", func(t *testing.T) {
+		health.SetMigrated(true)
+		health.SetProcessorRunning(true)
+
+		resp, err := app.Test(httptest.NewRequest("GET", "/_ready", nil), 30000)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+	})
+}
+
+func TestReadyIndexActionBacklog(t *testing.T) {
+	dbManager, _, website := testsupport.SetupTestDBManagerWithWebsite(t, "backlog.com")
+	db := dbManager.GetConnection()
+	app := testsupport.CreateMinimalTestApp(t, db)
+
+	health.SetMigrated(true)
+	health.SetProcessorRunning(true)
+	health.SetBacklogThreshold(50)
+	t.Cleanup(func() {
+		health.SetMigrated(false)
+		health.SetProcessorRunning(false)
+		health.SetBacklogThreshold(0)
+		health.SetAggregationLag(0, 0)
+	})
+
+	monitor := jobs.NewLagMonitor(slog.New(slog.NewTextHandler(io.Discard, nil)), 0, 0)
+	get := func(t *testing.T, path string) (int, map[string]interface{}) {
+		t.Helper()
+		require.NoError(t, monitor.Check(db))
+
+		resp, err := app.Test(httptest.NewRequest("GET", path, nil), 30000)
+		require.NoError(t, err)
+		var payload map[string]interface{}
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&payload))
+		return resp.StatusCode, payload
+	}
+
+	t.Run("ready with an empty backlog", func(t *testing.T) {
+		status, payload := get(t, "/_ready")
+		assert.Equal(t, http.StatusOK, status)
+		assert.Equal(t, true, payload["backlog_ok"])
+		assert.Equal(t, "ok", payload["db_status"])
+	})
+
+	t.Run("not ready with a large synthetic backlog", func(t *testing.T) {
+		now := time.Now().UTC()
+		backlog := make([]events.IngestedEvent, 120)
+		for i := range backlog {
+			backlog[i] = events.IngestedEvent{
+				WebsiteID:        website.ID,
+				UserSignature:    fmt.Sprintf("visitor-%d", i),
+				Hostname:         website.Domain,
+				Pathname:         "/",
+				RawURL:           "https://" + website.Domain + "/",
+				ReferrerHostname: events.DirectOrUnknownReferrer,
+				EventType:        events.EventTypePageView,
+				Timestamp:        now,
+				UserAgent:        "Mozilla/5.0 (test)",
+				Country:          "US",
+				CreatedAt:        now,
+			}
+		}
+		require.NoError(t, db.CreateInBatches(&backlog, 50).Error)
+
+		status, payload := get(t, "/_ready")
+		assert.Equal(t, http.StatusServiceUnavailable, status)
+		assert.Equal(t, false, payload["ready"])
+		assert.Equal(t, false, payload["backlog_ok"])
+		assert.Equal(t, float64(120), payload["backlog"])
+
+		// Liveness still reports the backlog without failing
+		status, payload = get(t, "/_health")
+		assert.Equal(t, http.StatusOK, status)
+		assert.Equal(t, float64(120), payload["backlog"])
+	})
+}
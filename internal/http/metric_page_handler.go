@@ -0,0 +1,72 @@
+package http
+
+import (
+	"errors"
+	"log/slog"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/karloscodes/cartridge"
+
+	"fusionaly/internal/analytics"
+)
+
+// WebsiteMetricPageAction returns one page of a dashboard top-N list such as
+// top_urls (JSON API). Query params: offset, limit (max 100), search, plus the
+// dashboard's date range and segment filters.
+func WebsiteMetricPageAction(ctx *cartridge.Context) error {
+	websiteId, err := ctx.ParamsInt("id")
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid website ID",
+		})
+	}
+
+	offset, err := strconv.Atoi(ctx.Query("offset", "0"))
+	if err != nil || offset < 0 {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "offset must be a non-negative number",
+		})
+	}
+	limit, err := strconv.Atoi(ctx.Query("limit", "20"))
+	if err != nil || limit < 1 || limit > analytics.MaxMetricPageSize {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "limit must be between 1 and 100",
+		})
+	}
+
+	timeZone := dashboardTimeZone(ctx)
+	if timeZone == "" {
+		timeZone = "UTC"
+	}
+
+	db := ctx.DB()
+	timeFrame, err := parseDashboardTimeFrame(ctx, db, websiteId, timeZone)
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid time range",
+		})
+	}
+
+	params := analytics.NewWebsiteScopedQueryParams(timeFrame, websiteId)
+	params.Filters = dashboardFilters(ctx)
+	params.Offset = offset
+	params.Limit = limit
+	params.Search = strings.TrimSpace(ctx.Query("search"))
+
+	page, err := analytics.GetMetricPage(db, params, ctx.Params("name"))
+	if err != nil {
+		if errors.Is(err, analytics.ErrUnknownMetric) {
+			return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		ctx.Logger.Error("Failed to fetch metric page", slog.Any("error", err), slog.Int("website_id", websiteId), slog.String("metric", ctx.Params("name")))
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch metric",
+		})
+	}
+
+	return ctx.JSON(page)
+}
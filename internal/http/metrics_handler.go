@@ -0,0 +1,37 @@
+package http
+
+import (
+	"bytes"
+	"crypto/subtle"
+	"log/slog"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/karloscodes/cartridge"
+
+	"fusionaly/internal/config"
+	"fusionaly/internal/metrics"
+)
+
+// prometheusContentType is the content type of the Prometheus text exposition format
+const prometheusContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// MetricsIndexAction serves application metrics in the Prometheus text format.
+// When FUSIONALY_METRICS_TOKEN is set, scrapers must send it as a bearer token.
+func MetricsIndexAction(ctx *cartridge.Context) error {
+	if token := config.GetConfig().MetricsToken; token != "" {
+		provided := strings.TrimPrefix(ctx.Get("Authorization"), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(provided), []byte(token)) != 1 {
+			return ctx.Status(fiber.StatusUnauthorized).SendString("Unauthorized")
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := metrics.Default.WriteText(&buf); err != nil {
+		ctx.Logger.Error("Failed to render metrics", slog.Any("error", err))
+		return ctx.Status(fiber.StatusInternalServerError).SendString("Error rendering metrics")
+	}
+
+	ctx.Set(fiber.HeaderContentType, prometheusContentType)
+	return ctx.Send(buf.Bytes())
+}
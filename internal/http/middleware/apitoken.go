@@ -0,0 +1,59 @@
+package middleware
+
+import (
+	"errors"
+	"log/slog"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+
+	"fusionaly/internal/websites"
+)
+
+// APITokenWebsiteIDKey is the Locals key holding the website ID the API token grants access to
+const APITokenWebsiteIDKey = "api_token_website_id"
+
+// WebsiteAPITokenAuth middleware validates per-website API tokens for the stats API.
+// Expects: Authorization: Bearer <token>
+func WebsiteAPITokenAuth(db *gorm.DB, logger *slog.Logger) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		authHeader := c.Get("Authorization")
+		if authHeader == "" {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error": "Missing Authorization header",
+			})
+		}
+
+		if !strings.HasPrefix(authHeader, "Bearer ") {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error": "Invalid Authorization header format. Expected: Bearer <token>",
+			})
+		}
+
+		providedToken := strings.TrimPrefix(authHeader, "Bearer ")
+		if providedToken == "" {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error": "API token is empty",
+			})
+		}
+
+		// Tokens are looked up by hash, so no plaintext comparison happens here
+		token, err := websites.GetAPITokenByValue(db, providedToken)
+		if err != nil {
+			if !errors.Is(err, gorm.ErrRecordNotFound) {
+				logger.Error("Failed to look up API token", slog.Any("error", err))
+			}
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error": "Invalid API token",
+			})
+		}
+
+		if err := websites.TouchAPIToken(db, token); err != nil {
+			logger.Warn("Failed to record API token usage", slog.Any("error", err), slog.Uint64("token_id", uint64(token.ID)))
+		}
+
+		c.Locals(APITokenWebsiteIDKey, token.WebsiteID)
+		return c.Next()
+	}
+}
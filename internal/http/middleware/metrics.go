@@ -0,0 +1,36 @@
+package middleware
+
+import (
+	"errors"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"fusionaly/internal/metrics"
+)
+
+// RequestMetrics records the duration of every request in the
+// fusionaly_http_request_duration_seconds histogram.
+func RequestMetrics() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		start := time.Now()
+		err := c.Next()
+
+		status := responseStatus(c, err)
+		metrics.HTTPRequestDuration.Observe(time.Since(start).Seconds(), c.Method(), metrics.StatusClass(status))
+		return err
+	}
+}
+
+// responseStatus returns the status the request will be answered with, also
+// when a handler error hasn't been written to the response yet
+func responseStatus(c *fiber.Ctx, err error) int {
+	if err == nil {
+		return c.Response().StatusCode()
+	}
+	var fiberErr *fiber.Error
+	if errors.As(err, &fiberErr) {
+		return fiberErr.Code
+	}
+	return fiber.StatusInternalServerError
+}
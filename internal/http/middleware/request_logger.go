@@ -0,0 +1,47 @@
+package middleware
+
+import (
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// WebsiteDomainKey is the Locals key holding the domain of the website a
+// request was made for, set by handlers that resolve it
+const WebsiteDomainKey = "website_domain"
+
+// RequestLogger logs every request with structured fields: method, route,
+// path, status, duration and, when known, the website it was made for.
+// Health checks are skipped.
+func RequestLogger(logger *slog.Logger) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		start := time.Now()
+		err := c.Next()
+		duration := time.Since(start)
+
+		path := c.Path()
+		if strings.HasPrefix(path, "/_health") || strings.HasPrefix(path, "/_ready") {
+			return err
+		}
+
+		attrs := []slog.Attr{
+			slog.String("method", c.Method()),
+			slog.String("route", c.Route().Path),
+			slog.String("path", path),
+			slog.Int("status", responseStatus(c, err)),
+			slog.Float64("duration_ms", float64(duration.Microseconds())/1000),
+			slog.String("ip", c.IP()),
+		}
+		if domain, ok := c.Locals(WebsiteDomainKey).(string); ok && domain != "" {
+			attrs = append(attrs, slog.String("website_domain", domain))
+		}
+		if websiteID, ok := c.Locals("website_id").(int); ok {
+			attrs = append(attrs, slog.Int("website_id", websiteID))
+		}
+
+		logger.LogAttrs(c.UserContext(), slog.LevelInfo, "http request", attrs...)
+		return err
+	}
+}
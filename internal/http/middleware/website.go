@@ -39,6 +39,7 @@ func WebsiteFilter(db *gorm.DB, logger *slog.Logger) fiber.Handler {
 				}
 			} else {
 				c.Locals("website_id", int(firstWebsite.ID))
+				c.Locals(WebsiteDomainKey, firstWebsite.Domain)
 				logger.Debug("Set default website", slog.Int("website_id", int(firstWebsite.ID)), slog.String("domain", firstWebsite.Domain))
 			}
 		}
@@ -0,0 +1,56 @@
+package http
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/karloscodes/cartridge"
+
+	"fusionaly/internal/analytics"
+	"fusionaly/internal/timeframe"
+)
+
+// AdminOverviewAction returns the core totals of every website plus the grand
+// total for the selected timeframe (JSON API).
+// Query params: from, to (defaults to the last 30 days) and tz (defaults to
+// the _tz cookie, then UTC).
+func AdminOverviewAction(ctx *cartridge.Context) error {
+	timeZone := ctx.Query("tz", ctx.Cookies("_tz", "UTC"))
+	if _, err := time.LoadLocation(timeZone); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid time zone",
+		})
+	}
+
+	timeFrame := timeframe.Last30Days(timeZone)
+	if ctx.Query("from") != "" || ctx.Query("to") != "" {
+		parsed, err := timeframe.NewTimeFrameParser().ParseTimeFrame(timeframe.TimeFrameParserParams{
+			FromDate:            ctx.Query("from"),
+			ToDate:              ctx.Query("to"),
+			Tz:                  timeZone,
+			AllTimeFirstEventAt: time.Now().UTC().Add(-time.Hour * 24 * 365 * 5),
+		})
+		if err != nil {
+			return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid time range",
+			})
+		}
+		timeFrame = parsed
+	}
+
+	overview, err := analytics.GetSitesOverviewInTimeFrame(ctx.DB(), timeFrame)
+	if err != nil {
+		ctx.Logger.Error("Failed to compute sites overview", slog.Any("error", err))
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to compute overview",
+		})
+	}
+
+	return ctx.JSON(fiber.Map{
+		"from":  timeFrame.From,
+		"to":    timeFrame.To,
+		"sites": overview.Sites,
+		"total": overview.Total,
+	})
+}
@@ -0,0 +1,50 @@
+package http
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/karloscodes/cartridge"
+
+	"fusionaly/internal/analytics"
+)
+
+const (
+	realtimeActiveWindow     = 5 * time.Minute
+	realtimeBreakdownMinutes = 30
+)
+
+// WebsiteRealtimeAction returns visitors currently online (active in the last
+// five minutes) and a per-minute breakdown of the last 30 minutes (JSON API).
+func WebsiteRealtimeAction(ctx *cartridge.Context) error {
+	websiteId, err := ctx.ParamsInt("id")
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid website ID",
+		})
+	}
+
+	db := ctx.DB()
+	active, err := analytics.GetActiveVisitors(db, websiteId, realtimeActiveWindow)
+	if err != nil {
+		ctx.Logger.Error("Failed to count active visitors", slog.Any("error", err), slog.Int("website_id", websiteId))
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch realtime visitors",
+		})
+	}
+
+	breakdown, err := analytics.GetActiveVisitorsByMinute(db, websiteId, realtimeBreakdownMinutes)
+	if err != nil {
+		ctx.Logger.Error("Failed to fetch realtime breakdown", slog.Any("error", err), slog.Int("website_id", websiteId))
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch realtime visitors",
+		})
+	}
+
+	return ctx.JSON(fiber.Map{
+		"active_visitors": active,
+		"window_seconds":  int(realtimeActiveWindow.Seconds()),
+		"per_minute":      breakdown,
+	})
+}
@@ -0,0 +1,41 @@
+package http
+
+import (
+	"log/slog"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/karloscodes/cartridge"
+
+	"fusionaly/internal/analytics"
+)
+
+// WebsiteRetentionAction returns weekly retention cohorts (JSON API).
+// Query params: weeks (number of cohorts, default 8)
+func WebsiteRetentionAction(ctx *cartridge.Context) error {
+	websiteId, err := ctx.ParamsInt("id")
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid website ID",
+		})
+	}
+
+	weeks, err := strconv.Atoi(ctx.Query("weeks", "8"))
+	if err != nil || weeks < 1 || weeks > analytics.MaxRetentionWeeks {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "weeks must be between 1 and 52",
+		})
+	}
+
+	cohorts, err := analytics.GetRetentionCohorts(ctx.DB(), websiteId, weeks)
+	if err != nil {
+		ctx.Logger.Error("Failed to compute retention cohorts", slog.Any("error", err), slog.Int("website_id", websiteId))
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to compute retention",
+		})
+	}
+
+	return ctx.JSON(fiber.Map{
+		"cohorts": cohorts,
+	})
+}
@@ -2,11 +2,13 @@ package http
 
 import (
 	"net"
+	"strconv"
 	"strings"
 
 	"github.com/gofiber/fiber/v2"
 	"log/slog"
 
+	"fusionaly/internal/events"
 	"fusionaly/internal/settings"
 	"github.com/karloscodes/cartridge"
 )
@@ -59,6 +61,155 @@ func IngestionSettingsFormAction(ctx *cartridge.Context) error {
 		return ctx.FlashError("Failed to update IP filtering settings").Redirect("/admin/administration/ingestion", fiber.StatusFound)
 	}
 
+	// Raw event retention is submitted as a percentage
+	if samplePercent := strings.TrimSpace(ctx.Input("raw_event_sample_percent")); samplePercent != "" {
+		percent, err := strconv.ParseFloat(samplePercent, 64)
+		if err != nil || percent < 0 || percent > 100 {
+			return ctx.FlashError("Raw event retention must be between 0 and 100%").Redirect("/admin/administration/ingestion", fiber.StatusFound)
+		}
+		if err := settings.SaveRawEventSampleRate(db, percent/100); err != nil {
+			ctx.Logger.Error("failed to update raw_event_sample_rate setting", slog.Any("error", err))
+			return ctx.FlashError("Failed to update raw event retention").Redirect("/admin/administration/ingestion", fiber.StatusFound)
+		}
+	}
+
+	if dedupeWindow := strings.TrimSpace(ctx.Input("dedupe_window_minutes")); dedupeWindow != "" {
+		minutes, err := strconv.Atoi(dedupeWindow)
+		if err != nil || minutes < 0 {
+			return ctx.FlashError("Duplicate event window must be zero or more minutes").Redirect("/admin/administration/ingestion", fiber.StatusFound)
+		}
+		if err := settings.SaveDedupeWindowMinutes(db, minutes); err != nil {
+			ctx.Logger.Error("failed to update dedupe_window_minutes setting", slog.Any("error", err))
+			return ctx.FlashError("Failed to update duplicate event window").Redirect("/admin/administration/ingestion", fiber.StatusFound)
+		}
+	}
+
+	if maxMetaBytes := strings.TrimSpace(ctx.Input("max_event_meta_bytes")); maxMetaBytes != "" {
+		maxBytes, err := strconv.Atoi(maxMetaBytes)
+		if err != nil || maxBytes < 0 {
+			return ctx.FlashError("Maximum metadata size must be zero or more bytes").Redirect("/admin/administration/ingestion", fiber.StatusFound)
+		}
+		if err := settings.SaveMaxEventMetaBytes(db, maxBytes); err != nil {
+			ctx.Logger.Error("failed to update max_event_meta_bytes setting", slog.Any("error", err))
+			return ctx.FlashError("Failed to update maximum metadata size").Redirect("/admin/administration/ingestion", fiber.StatusFound)
+		}
+	}
+
+	if maxSkew := strings.TrimSpace(ctx.Input("max_timestamp_skew")); maxSkew != "" {
+		minutes, err := strconv.Atoi(maxSkew)
+		if err != nil || minutes < 0 {
+			return ctx.FlashError("Future timestamp skew must be zero or more minutes").Redirect("/admin/administration/ingestion", fiber.StatusFound)
+		}
+		if err := settings.SaveMaxTimestampSkewMinutes(db, minutes); err != nil {
+			ctx.Logger.Error("failed to update max_timestamp_skew setting", slog.Any("error", err))
+			return ctx.FlashError("Failed to update timestamp bounds").Redirect("/admin/administration/ingestion", fiber.StatusFound)
+		}
+	}
+
+	if maxAge := strings.TrimSpace(ctx.Input("max_timestamp_age_days")); maxAge != "" {
+		days, err := strconv.Atoi(maxAge)
+		if err != nil || days < 0 {
+			return ctx.FlashError("Maximum event age must be zero or more days").Redirect("/admin/administration/ingestion", fiber.StatusFound)
+		}
+		if err := settings.SaveMaxTimestampAgeDays(db, days); err != nil {
+			ctx.Logger.Error("failed to update max_timestamp_age_days setting", slog.Any("error", err))
+			return ctx.FlashError("Failed to update timestamp bounds").Redirect("/admin/administration/ingestion", fiber.StatusFound)
+		}
+	}
+
+	if truncateMeta := ctx.Input("truncate_event_meta"); truncateMeta != "" {
+		if err := settings.SaveEventMetaTruncationEnabled(db, truncateMeta == "true" || truncateMeta == "on"); err != nil {
+			ctx.Logger.Error("failed to update truncate_event_meta setting", slog.Any("error", err))
+			return ctx.FlashError("Failed to update metadata size settings").Redirect("/admin/administration/ingestion", fiber.StatusFound)
+		}
+	}
+
+	if useUserID := ctx.Input("use_sdk_user_id"); useUserID != "" {
+		if err := settings.SaveSDKUserIDEnabled(db, useUserID == "true" || useUserID == "on"); err != nil {
+			ctx.Logger.Error("failed to update use_sdk_user_id setting", slog.Any("error", err))
+			return ctx.FlashError("Failed to update visitor identity settings").Redirect("/admin/administration/ingestion", fiber.StatusFound)
+		}
+	}
+
+	if respectDNT := ctx.Input("respect_dnt"); respectDNT != "" {
+		if err := settings.SaveDoNotTrackRespected(db, respectDNT == "true" || respectDNT == "on"); err != nil {
+			ctx.Logger.Error("failed to update respect_dnt setting", slog.Any("error", err))
+			return ctx.FlashError("Failed to update Do Not Track settings").Redirect("/admin/administration/ingestion", fiber.StatusFound)
+		}
+	}
+
+	if filterBots := ctx.Input("filter_bots"); filterBots != "" {
+		if err := settings.SaveBotFilteringEnabled(db, filterBots == "true" || filterBots == "on"); err != nil {
+			ctx.Logger.Error("failed to update filter_bots setting", slog.Any("error", err))
+			return ctx.FlashError("Failed to update bot filtering settings").Redirect("/admin/administration/ingestion", fiber.StatusFound)
+		}
+	}
+
+	if filterRefSpam := ctx.Input("filter_referrer_spam"); filterRefSpam != "" {
+		if err := settings.SaveReferrerSpamFilteringEnabled(db, filterRefSpam == "true" || filterRefSpam == "on"); err != nil {
+			ctx.Logger.Error("failed to update filter_referrer_spam setting", slog.Any("error", err))
+			return ctx.FlashError("Failed to update referrer spam settings").Redirect("/admin/administration/ingestion", fiber.StatusFound)
+		}
+	}
+
+	if separateSelfReferrals := ctx.Input("separate_self_referrals"); separateSelfReferrals != "" {
+		if err := settings.SaveSelfReferralSeparationEnabled(db, separateSelfReferrals == "true" || separateSelfReferrals == "on"); err != nil {
+			ctx.Logger.Error("failed to update separate_self_referrals setting", slog.Any("error", err))
+			return ctx.FlashError("Failed to update self-referral settings").Redirect("/admin/administration/ingestion", fiber.StatusFound)
+		}
+	}
+
+	if normalizeUTMCase := ctx.Input("normalize_utm_case"); normalizeUTMCase != "" {
+		if err := settings.SaveUTMCaseNormalizationEnabled(db, normalizeUTMCase == "true" || normalizeUTMCase == "on"); err != nil {
+			ctx.Logger.Error("failed to update normalize_utm_case setting", slog.Any("error", err))
+			return ctx.FlashError("Failed to update UTM normalization settings").Redirect("/admin/administration/ingestion", fiber.StatusFound)
+		}
+	}
+
+	if pathCaseInsensitive := ctx.Input("path_case_insensitive"); pathCaseInsensitive != "" {
+		if err := settings.SavePathCaseInsensitive(db, pathCaseInsensitive == "true" || pathCaseInsensitive == "on"); err != nil {
+			ctx.Logger.Error("failed to update path_case_insensitive setting", slog.Any("error", err))
+			return ctx.FlashError("Failed to update path settings").Redirect("/admin/administration/ingestion", fiber.StatusFound)
+		}
+	}
+
+	if stripTrailingSlash := ctx.Input("path_strip_trailing_slash"); stripTrailingSlash != "" {
+		if err := settings.SaveTrailingSlashStripEnabled(db, stripTrailingSlash == "true" || stripTrailingSlash == "on"); err != nil {
+			ctx.Logger.Error("failed to update path_strip_trailing_slash setting", slog.Any("error", err))
+			return ctx.FlashError("Failed to update path settings").Redirect("/admin/administration/ingestion", fiber.StatusFound)
+		}
+	}
+
+	if err := settings.SaveExcludedPaths(db, ctx.Input("excluded_paths")); err != nil {
+		ctx.Logger.Error("failed to update excluded_paths setting", slog.Any("error", err))
+		return ctx.FlashError("Failed to update path filtering settings").Redirect("/admin/administration/ingestion", fiber.StatusFound)
+	}
+
+	pathPatterns := ctx.Input("path_patterns")
+	if _, err := events.ParsePathPatterns(strings.Split(pathPatterns, "\n")); err != nil {
+		ctx.Logger.Warn("invalid path patterns submitted", slog.Any("error", err))
+		return ctx.FlashError(err.Error()).Redirect("/admin/administration/ingestion", fiber.StatusFound)
+	}
+	if err := settings.SavePathPatterns(db, pathPatterns); err != nil {
+		ctx.Logger.Error("failed to update path_patterns setting", slog.Any("error", err))
+		return ctx.FlashError("Failed to update path pattern settings").Redirect("/admin/administration/ingestion", fiber.StatusFound)
+	}
+
+	if err := settings.SaveTrackedQueryParams(db, ctx.Input("tracked_query_params")); err != nil {
+		ctx.Logger.Error("failed to update tracked_query_params setting", slog.Any("error", err))
+		return ctx.FlashError("Failed to update query parameter settings").Redirect("/admin/administration/ingestion", fiber.StatusFound)
+	}
+
+	if err := settings.SaveBotPatterns(db, ctx.Input("bot_patterns")); err != nil {
+		ctx.Logger.Error("failed to update bot_patterns setting", slog.Any("error", err))
+		return ctx.FlashError("Failed to update bot filtering settings").Redirect("/admin/administration/ingestion", fiber.StatusFound)
+	}
+
+	if err := settings.SaveReferrerSpamDomains(db, ctx.Input("referrer_spam_domains")); err != nil {
+		ctx.Logger.Error("failed to update referrer_spam_domains setting", slog.Any("error", err))
+		return ctx.FlashError("Failed to update referrer spam settings").Redirect("/admin/administration/ingestion", fiber.StatusFound)
+	}
+
 	ctx.Logger.Info("excluded IPs updated via form")
 	return ctx.FlashSuccess("Ingestion settings saved successfully!").Redirect("/admin/administration/ingestion", fiber.StatusFound)
 }
@@ -1,14 +1,18 @@
 package http
 
 import (
+	"errors"
 	"fmt"
 	"log/slog"
+	"strings"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/karloscodes/cartridge"
 	"github.com/karloscodes/cartridge/flash"
 	"github.com/karloscodes/cartridge/inertia"
 	"github.com/karloscodes/cartridge/structs"
+	"gorm.io/gorm"
 
 	"fusionaly/internal/analytics"
 	"fusionaly/internal/annotations"
@@ -16,14 +20,27 @@ import (
 	"fusionaly/internal/websites"
 )
 
-// PublicDashboardAction renders a read-only public dashboard
+// sharedLinkUnlockCookie remembers that a visitor entered a shared link's password
+const sharedLinkUnlockCookie = "_share_unlock"
+
+// PublicDashboardAction renders a read-only public dashboard, either for a
+// shared link or for the website's share token
 func PublicDashboardAction(ctx *cartridge.Context) error {
 	token := ctx.Params("token")
 	if token == "" {
 		return ctx.Status(fiber.StatusNotFound).SendString("Not found")
 	}
 
-	website, err := websites.GetWebsiteByShareToken(ctx.DB(), token)
+	db := ctx.DB()
+	link, err := websites.GetSharedLinkByToken(db, token, time.Now().UTC())
+	if err == nil {
+		return sharedLinkDashboard(ctx, link)
+	}
+	if errors.Is(err, websites.ErrSharedLinkExpired) {
+		return ctx.Status(fiber.StatusGone).SendString("This link has expired")
+	}
+
+	website, err := websites.GetWebsiteByShareToken(db, token)
 	if err != nil {
 		ctx.Logger.Debug("Public dashboard not found", slog.String("token", token))
 		return ctx.Status(fiber.StatusNotFound).SendString("Dashboard not found")
@@ -32,6 +49,69 @@ func PublicDashboardAction(ctx *cartridge.Context) error {
 	// Cache public dashboards for 5 minutes - reduces DB load, CDN-friendly
 	ctx.Set("Cache-Control", "public, max-age=300")
 
+	return renderPublicDashboard(ctx, website, nil)
+}
+
+// sharedLinkDashboard renders a shared link's dashboard, asking for the
+// password first when the link is protected and this browser hasn't unlocked it
+func sharedLinkDashboard(ctx *cartridge.Context, link *websites.SharedLink) error {
+	// Shared links can be password protected or revoked, so never cache them
+	ctx.Set("Cache-Control", "private, no-store")
+
+	if link.HasPassword() && ctx.Cookies(sharedLinkUnlockCookie) != link.UnlockKey() {
+		return ctx.Inertia("SharedLinkPassword", inertia.Props{
+			"token":   link.Token,
+			"invalid": ctx.QueryBool("invalid"),
+		})
+	}
+
+	website, err := websites.GetWebsiteByID(ctx.DB(), link.WebsiteID)
+	if err != nil {
+		ctx.Logger.Debug("Shared link website not found", slog.Uint64("website_id", uint64(link.WebsiteID)))
+		return ctx.Status(fiber.StatusNotFound).SendString("Dashboard not found")
+	}
+
+	return renderPublicDashboard(ctx, &website, link.Metrics())
+}
+
+// SharedLinkUnlockAction checks the password of a protected shared link and
+// remembers the unlock in a cookie scoped to the link
+func SharedLinkUnlockAction(ctx *cartridge.Context) error {
+	token := ctx.Params("token")
+	link, err := websites.GetSharedLinkByToken(ctx.DB(), token, time.Now().UTC())
+	if err != nil {
+		if errors.Is(err, websites.ErrSharedLinkExpired) {
+			return ctx.Status(fiber.StatusGone).SendString("This link has expired")
+		}
+		return ctx.Status(fiber.StatusNotFound).SendString("Dashboard not found")
+	}
+
+	sharePath := "/share/" + link.Token
+	if err := websites.VerifySharedLinkPassword(link, ctx.Input("password")); err != nil {
+		return ctx.Redirect(sharePath+"?invalid=true", fiber.StatusFound)
+	}
+
+	expires := time.Now().Add(30 * 24 * time.Hour)
+	if link.ExpiresAt != nil && link.ExpiresAt.Before(expires) {
+		expires = *link.ExpiresAt
+	}
+	ctx.Cookie(&fiber.Cookie{
+		Name:     sharedLinkUnlockCookie,
+		Value:    link.UnlockKey(),
+		Path:     sharePath,
+		Expires:  expires,
+		Secure:   ctx.Config.IsProduction(),
+		HTTPOnly: true,
+		SameSite: "Lax",
+	})
+
+	return ctx.Redirect(sharePath, fiber.StatusFound)
+}
+
+// renderPublicDashboard renders the read-only dashboard of a website over the
+// last 30 days. A non-empty metrics list limits what is shown; comparison
+// and user flow data are only included for unrestricted dashboards.
+func renderPublicDashboard(ctx *cartridge.Context, website *websites.Website, allowedMetrics []string) error {
 	// Parse timezone from cookie, default to UTC
 	tz := ctx.Cookies("_tz")
 	if tz == "" {
@@ -43,11 +123,12 @@ func PublicDashboardAction(ctx *cartridge.Context) error {
 	websiteId := int(website.ID)
 	db := ctx.DB()
 
-	metrics, err := analytics.FetchDashboardMetrics(db, timeFrame, websiteId, ctx.Logger)
+	metrics, err := dashboardMetrics(ctx, db, timeFrame, websiteId, analytics.DefaultTopLimit)
 	if err != nil {
 		ctx.Logger.Error("Error fetching public dashboard metrics", slog.Any("error", err))
 		return ctx.Status(fiber.StatusInternalServerError).SendString("Error loading dashboard")
 	}
+	metrics = analytics.RestrictDashboardMetrics(metrics, allowedMetrics)
 
 	// Fetch annotations for this website and timeframe
 	annotationsList, err := annotations.GetAnnotationsForTimeframe(db, uint(websiteId), timeFrame.From, timeFrame.To)
@@ -62,15 +143,20 @@ func PublicDashboardAction(ctx *cartridge.Context) error {
 	props["is_public_view"] = true
 	props["annotations"] = annotationsList
 
+	if len(allowedMetrics) > 0 {
+		props["user_flow"] = []analytics.UserFlowLink{}
+		return ctx.Inertia("PublicDashboard", props)
+	}
+
 	// Add comparison data for trends
 	props["comparison"] = inertia.Defer(func() interface{} {
-		return analytics.FetchComparisonMetrics(db, timeFrame, websiteId, metrics, ctx.Logger)
+		return analytics.FetchComparisonMetrics(db, timeFrame, nil, websiteId, metrics, ctx.Logger)
 	})
 
 	// Add user flow data
 	queryParams := analytics.NewWebsiteScopedQueryParams(timeFrame, websiteId)
 	props["user_flow"] = inertia.Defer(func() interface{} {
-		flowData, err := analytics.GetUserFlowData(db, queryParams, 5)
+		flowData, err := analytics.GetUserFlowData(db, queryParams, analytics.DefaultFlowDepth, "")
 		if err != nil {
 			ctx.Logger.Error("Error fetching user flow data for public dashboard", slog.Any("error", err))
 			return []analytics.UserFlowLink{}
@@ -116,3 +202,71 @@ func DisableShareAction(ctx *cartridge.Context) error {
 
 	return ctx.Redirect(fmt.Sprintf("/admin/websites/%d/dashboard", websiteID), fiber.StatusFound)
 }
+
+// WebsiteSharedLinkCreateAction adds a shared dashboard link to a website.
+// Form fields: password (optional), expires_at (optional, YYYY-MM-DD, the link
+// stops working at the start of that day in UTC) and metrics (optional, comma
+// separated DashboardMetrics fields).
+func WebsiteSharedLinkCreateAction(ctx *cartridge.Context) error {
+	id, err := ctx.ParamsInt("id")
+	if err != nil {
+		return ctx.FlashError("Invalid website ID").Redirect("/admin", fiber.StatusFound)
+	}
+	editPath := fmt.Sprintf("/admin/websites/%d/edit", id)
+
+	db := ctx.DB()
+	if _, err := websites.GetWebsiteByID(db, uint(id)); err != nil {
+		return ctx.FlashError("Website not found").Redirect("/admin", fiber.StatusFound)
+	}
+
+	var expiresAt *time.Time
+	if value := strings.TrimSpace(ctx.Input("expires_at")); value != "" {
+		parsed, err := time.Parse("2006-01-02", value)
+		if err != nil {
+			return ctx.FlashError("Expiry must be a date (YYYY-MM-DD)").Redirect(editPath, fiber.StatusFound)
+		}
+		expiresAt = &parsed
+	}
+
+	var metrics []string
+	for _, metric := range strings.Split(ctx.Input("metrics"), ",") {
+		if metric = strings.TrimSpace(metric); metric != "" {
+			metrics = append(metrics, metric)
+		}
+	}
+	if err := analytics.ValidateDashboardMetrics(metrics); err != nil {
+		return ctx.FlashError(err.Error()).Redirect(editPath, fiber.StatusFound)
+	}
+
+	link, err := websites.CreateSharedLink(db, uint(id), ctx.Input("password"), expiresAt, metrics)
+	if err != nil {
+		ctx.Logger.Error("Failed to create shared link", slog.Any("error", err), slog.Int("id", id))
+		return ctx.FlashError(fmt.Sprintf("Failed to create shared link: %v", err)).Redirect(editPath, fiber.StatusFound)
+	}
+
+	return ctx.FlashSuccess(fmt.Sprintf("Shared link created: /share/%s", link.Token)).Redirect(editPath, fiber.StatusFound)
+}
+
+// WebsiteSharedLinkDeleteAction revokes one of a website's shared links
+func WebsiteSharedLinkDeleteAction(ctx *cartridge.Context) error {
+	id, err := ctx.ParamsInt("id")
+	if err != nil {
+		return ctx.FlashError("Invalid website ID").Redirect("/admin", fiber.StatusFound)
+	}
+	editPath := fmt.Sprintf("/admin/websites/%d/edit", id)
+
+	linkID, err := ctx.ParamsInt("linkId")
+	if err != nil {
+		return ctx.FlashError("Invalid shared link ID").Redirect(editPath, fiber.StatusFound)
+	}
+
+	if err := websites.DeleteSharedLink(ctx.DB(), uint(id), uint(linkID)); err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return ctx.FlashError("Shared link not found").Redirect(editPath, fiber.StatusFound)
+		}
+		ctx.Logger.Error("Failed to delete shared link", slog.Any("error", err), slog.Int("id", id), slog.Int("link_id", linkID))
+		return ctx.FlashError("Failed to delete shared link").Redirect(editPath, fiber.StatusFound)
+	}
+
+	return ctx.FlashSuccess("Shared link revoked").Redirect(editPath, fiber.StatusFound)
+}
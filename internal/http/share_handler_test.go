@@ -0,0 +1,120 @@
+package http_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"fusionaly/internal/analytics"
+	"fusionaly/internal/testsupport"
+	"fusionaly/internal/websites"
+)
+
+func TestPublicDashboardActionSharedLinks(t *testing.T) {
+	dbManager, _, website := testsupport.SetupTestDBManagerWithWebsite(t, "shared-links.com")
+	db := dbManager.GetConnection()
+
+	require.NoError(t, db.Create(&analytics.PageStat{
+		WebsiteID:      website.ID,
+		Hostname:       website.Domain,
+		Pathname:       "/pricing",
+		PageViewsCount: 3,
+		VisitorsCount:  2,
+		Hour:           time.Now().UTC().Truncate(time.Hour).Add(-24 * time.Hour),
+	}).Error)
+
+	app := testsupport.CreateMinimalTestApp(t, db)
+
+	get := func(token, cookie string) (*http.Response, map[string]interface{}) {
+		req := httptest.NewRequest("GET", "/share/"+token, nil)
+		req.Header.Set("User-Agent", "Mozilla/5.0 Test Browser")
+		req.Header.Set("Sec-Fetch-Site", "same-origin")
+		req.Header.Set("X-Inertia", "true")
+		if cookie != "" {
+			req.Header.Set("Cookie", cookie)
+		}
+
+		resp, err := app.Test(req, 30000)
+		require.NoError(t, err)
+		var page struct {
+			Component string                 `json:"component"`
+			Props     map[string]interface{} `json:"props"`
+		}
+		if resp.StatusCode == http.StatusOK {
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(&page))
+			page.Props["component"] = page.Component
+		}
+		return resp, page.Props
+	}
+
+	t.Run("unknown token", func(t *testing.T) {
+		resp, _ := get("missing-token", "")
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	})
+
+	t.Run("expired link", func(t *testing.T) {
+		link, err := websites.CreateSharedLink(db, website.ID, "", nil, nil)
+		require.NoError(t, err)
+		require.NoError(t, db.Model(link).Update("expires_at", time.Now().UTC().Add(-time.Minute)).Error)
+
+		resp, _ := get(link.Token, "")
+		assert.Equal(t, http.StatusGone, resp.StatusCode)
+	})
+
+	t.Run("restricted metrics", func(t *testing.T) {
+		link, err := websites.CreateSharedLink(db, website.ID, "", nil, []string{"top_urls"})
+		require.NoError(t, err)
+
+		resp, props := get(link.Token, "")
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "PublicDashboard", props["component"])
+		assert.Equal(t, website.Domain, props["website_domain"])
+		assert.Len(t, props["top_urls"], 1)
+		assert.Empty(t, props["top_countries"])
+		assert.Equal(t, float64(0), props["total_views"])
+	})
+
+	t.Run("password gating", func(t *testing.T) {
+		link, err := websites.CreateSharedLink(db, website.ID, "s3cret", nil, nil)
+		require.NoError(t, err)
+
+		resp, props := get(link.Token, "")
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "SharedLinkPassword", props["component"])
+		assert.Nil(t, props["top_urls"])
+
+		unlock := func(password string) *http.Response {
+			form := url.Values{"password": {password}}
+			req := httptest.NewRequest("POST", "/share/"+link.Token+"/unlock", strings.NewReader(form.Encode()))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			req.Header.Set("User-Agent", "Mozilla/5.0 Test Browser")
+			req.Header.Set("Sec-Fetch-Site", "same-origin")
+			resp, err := app.Test(req, 30000)
+			require.NoError(t, err)
+			return resp
+		}
+
+		wrong := unlock("wrong")
+		assert.Equal(t, http.StatusFound, wrong.StatusCode)
+		assert.Equal(t, "/share/"+link.Token+"?invalid=true", wrong.Header.Get("Location"))
+		assert.Empty(t, wrong.Cookies())
+
+		right := unlock("s3cret")
+		assert.Equal(t, http.StatusFound, right.StatusCode)
+		require.Len(t, right.Cookies(), 1)
+		cookie := right.Cookies()[0]
+		assert.Equal(t, "/share/"+link.Token, cookie.Path)
+
+		resp, props = get(link.Token, cookie.Name+"="+cookie.Value)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "PublicDashboard", props["component"])
+		assert.Equal(t, "private, no-store", resp.Header.Get("Cache-Control"))
+	})
+}
@@ -0,0 +1,82 @@
+package http
+
+import (
+	"errors"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/karloscodes/cartridge"
+
+	"fusionaly/internal/analytics"
+	"fusionaly/internal/http/middleware"
+)
+
+// StatsAPIAction returns dashboard metrics for the website an API token belongs to.
+// Query params: website_id (required), from, to, tz, metrics (comma separated
+// DashboardMetrics fields; all metrics when omitted), limit (entries per top-N
+// list, 5 to 100) and the dashboard's segment filters.
+func StatsAPIAction(ctx *cartridge.Context) error {
+	websiteId, err := strconv.Atoi(ctx.Query("website_id"))
+	if err != nil || websiteId <= 0 {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "website_id is required and must be positive",
+		})
+	}
+
+	tokenWebsiteID, _ := ctx.Locals(middleware.APITokenWebsiteIDKey).(uint)
+	if tokenWebsiteID != uint(websiteId) {
+		return ctx.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "API token is not valid for this website",
+		})
+	}
+
+	timeZone := ctx.Query("tz", "UTC")
+	if _, err := time.LoadLocation(timeZone); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid time zone",
+		})
+	}
+
+	db := ctx.DB()
+	timeFrame, err := parseDashboardTimeFrame(ctx, db, websiteId, timeZone)
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid time range",
+		})
+	}
+
+	var metrics []string
+	for _, metric := range strings.Split(ctx.Query("metrics"), ",") {
+		if metric = strings.TrimSpace(metric); metric != "" {
+			metrics = append(metrics, metric)
+		}
+	}
+
+	if len(metrics) == 0 {
+		result, err := dashboardMetrics(ctx, db, timeFrame, websiteId, dashboardTopLimit(ctx))
+		if err != nil {
+			ctx.Logger.Error("Failed to fetch stats", slog.Any("error", err), slog.Int("website_id", websiteId))
+			return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to fetch stats",
+			})
+		}
+		return ctx.JSON(result)
+	}
+
+	result, err := analytics.FetchDashboardMetricsSubset(db, timeFrame, websiteId, dashboardFilters(ctx), metrics, dashboardTopLimit(ctx), ctx.Logger)
+	if err != nil {
+		if errors.Is(err, analytics.ErrUnknownMetric) {
+			return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		ctx.Logger.Error("Failed to fetch stats", slog.Any("error", err), slog.Int("website_id", websiteId))
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch stats",
+		})
+	}
+	return ctx.JSON(result)
+}
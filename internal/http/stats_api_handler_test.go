@@ -0,0 +1,150 @@
+package http_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"fusionaly/internal/events"
+	"fusionaly/internal/testsupport"
+	"fusionaly/internal/websites"
+)
+
+func TestStatsAPIAction(t *testing.T) {
+	dbManager, logger, website := testsupport.SetupTestDBManagerWithWebsite(t, "stats-api.com")
+	db := dbManager.GetConnection()
+	otherWebsite := testsupport.CreateTestWebsite(db, "other-stats-api.com")
+
+	day := time.Date(2024, 7, 1, 10, 0, 0, 0, time.UTC)
+	for i, path := range []string{"/", "/pricing", "/pricing"} {
+		ts := day.Add(time.Duration(i) * time.Minute)
+		require.NoError(t, db.Create(&events.IngestedEvent{
+			WebsiteID:        website.ID,
+			UserSignature:    fmt.Sprintf("visitor-%d", i),
+			Hostname:         website.Domain,
+			Pathname:         path,
+			RawURL:           "https://" + website.Domain + path,
+			ReferrerHostname: events.DirectOrUnknownReferrer,
+			EventType:        events.EventTypePageView,
+			Timestamp:        ts,
+			UserAgent:        "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36",
+			Country:          "US",
+			CreatedAt:        ts,
+		}).Error)
+	}
+	require.NoError(t, testsupport.ProcessAllTestEvents(dbManager, logger))
+
+	token, _, err := websites.CreateAPIToken(db, website.ID, "reporting")
+	require.NoError(t, err)
+
+	app := testsupport.CreateMinimalTestApp(t, db)
+
+	get := func(t *testing.T, query, authorization string) (int, map[string]interface{}) {
+		t.Helper()
+		req := httptest.NewRequest("GET", "/api/v1/stats?"+query, nil)
+		if authorization != "" {
+			req.Header.Set("Authorization", authorization)
+		}
+		resp, err := app.Test(req, 30000)
+		require.NoError(t, err)
+
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		var payload map[string]interface{}
+		require.NoError(t, json.Unmarshal(body, &payload), string(body))
+		return resp.StatusCode, payload
+	}
+	query := fmt.Sprintf("website_id=%d&from=2024-07-01&to=2024-07-07&tz=UTC", website.ID)
+
+	t.Run("rejects missing token", func(t *testing.T) {
+		status, payload := get(t, query, "")
+		assert.Equal(t, http.StatusUnauthorized, status)
+		assert.Contains(t, payload["error"], "Missing Authorization header")
+	})
+
+	t.Run("rejects unknown token", func(t *testing.T) {
+		status, payload := get(t, query, "Bearer fus_not-a-real-token")
+		assert.Equal(t, http.StatusUnauthorized, status)
+		assert.Equal(t, "Invalid API token", payload["error"])
+	})
+
+	t.Run("rejects revoked token", func(t *testing.T) {
+		revoked, revokedToken, err := websites.CreateAPIToken(db, website.ID, "revoked")
+		require.NoError(t, err)
+		require.NoError(t, websites.RevokeAPIToken(db, website.ID, revokedToken.ID))
+
+		status, _ := get(t, query, "Bearer "+revoked)
+		assert.Equal(t, http.StatusUnauthorized, status)
+	})
+
+	t.Run("returns full metrics", func(t *testing.T) {
+		status, payload := get(t, query, "Bearer "+token)
+		require.Equal(t, http.StatusOK, status)
+		assert.Equal(t, float64(3), payload["total_views"])
+		assert.Contains(t, payload, "top_urls")
+		assert.Contains(t, payload, "revenue_metrics")
+	})
+
+	t.Run("returns only requested metrics", func(t *testing.T) {
+		status, payload := get(t, query+"&metrics=total_views,top_urls", "Bearer "+token)
+		require.Equal(t, http.StatusOK, status)
+
+		assert.Equal(t, float64(3), payload["total_views"])
+		topURLs, ok := payload["top_urls"].([]interface{})
+		require.True(t, ok)
+		require.NotEmpty(t, topURLs)
+		assert.Equal(t, "stats-api.com/pricing", topURLs[0].(map[string]interface{})["name"])
+
+		assert.NotContains(t, payload, "total_visitors")
+		assert.NotContains(t, payload, "top_countries")
+		assert.Contains(t, payload, "bucket_size")
+	})
+
+	t.Run("rejects unknown metrics", func(t *testing.T) {
+		status, payload := get(t, query+"&metrics=total_views,nope", "Bearer "+token)
+		assert.Equal(t, http.StatusBadRequest, status)
+		assert.Contains(t, payload["error"], "nope")
+	})
+
+	t.Run("scopes token to its website", func(t *testing.T) {
+		otherQuery := fmt.Sprintf("website_id=%d&from=2024-07-01&to=2024-07-07", otherWebsite.ID)
+		status, payload := get(t, otherQuery, "Bearer "+token)
+		assert.Equal(t, http.StatusForbidden, status)
+		assert.Equal(t, "API token is not valid for this website", payload["error"])
+	})
+
+	t.Run("records token usage", func(t *testing.T) {
+		tokens, err := websites.ListAPITokens(db, website.ID)
+		require.NoError(t, err)
+		require.Len(t, tokens, 1)
+		assert.NotNil(t, tokens[0].LastUsedAt)
+	})
+
+	t.Run("updates token usage at most once a minute", func(t *testing.T) {
+		recent := time.Now().UTC().Add(-30 * time.Second).Truncate(time.Second)
+		require.NoError(t, db.Model(&websites.APIToken{}).Where("website_id = ?", website.ID).Update("last_used_at", recent).Error)
+
+		status, _ := get(t, query+"&metrics=total_views", "Bearer "+token)
+		require.Equal(t, http.StatusOK, status)
+		tokens, err := websites.ListAPITokens(db, website.ID)
+		require.NoError(t, err)
+		require.NotNil(t, tokens[0].LastUsedAt)
+		assert.True(t, recent.Equal(*tokens[0].LastUsedAt), "a recent last_used_at isn't rewritten")
+
+		stale := time.Now().UTC().Add(-2 * time.Minute)
+		require.NoError(t, db.Model(&websites.APIToken{}).Where("website_id = ?", website.ID).Update("last_used_at", stale).Error)
+
+		status, _ = get(t, query+"&metrics=total_views", "Bearer "+token)
+		require.Equal(t, http.StatusOK, status)
+		tokens, err = websites.ListAPITokens(db, website.ID)
+		require.NoError(t, err)
+		assert.WithinDuration(t, time.Now().UTC(), *tokens[0].LastUsedAt, 10*time.Second)
+	})
+}
@@ -15,6 +15,7 @@ import (
 	"fusionaly/internal/config"
 	"fusionaly/internal/jobs"
 	"fusionaly/internal/settings"
+	"fusionaly/internal/users"
 	"fusionaly/internal/websites"
 	"github.com/karloscodes/cartridge/cache"
 )
@@ -125,9 +126,18 @@ func AdministrationAccountPageAction(ctx *cartridge.Context) error {
 		websitesData = []map[string]interface{}{}
 	}
 
+	weeklyDigest := false
+	if userID, ok := ctx.Session.GetUserID(ctx.Ctx); ok {
+		if user, err := users.FindByID(db, userID); err == nil {
+			weeklyDigest = user.WeeklyDigest
+		}
+	}
+
 	return ctx.Inertia("AdministrationAccount", inertia.Props{
-		"settings": settingsData,
-		"websites": websitesData,
+		"settings":        settingsData,
+		"websites":        websitesData,
+		"weekly_digest":   weeklyDigest,
+		"smtp_configured": ctx.Config.(*config.Config).SMTPConfigured(),
 	})
 }
 
@@ -0,0 +1,26 @@
+package http
+
+import (
+	"github.com/gofiber/fiber/v2"
+
+	"fusionaly/internal/events"
+	"fusionaly/internal/settings"
+	"github.com/karloscodes/cartridge"
+)
+
+// UTMBuilderAction tags a landing page URL with UTM parameters (JSON),
+// returning the encoded URL and warnings about values that would fragment
+// campaign reports.
+func UTMBuilderAction(ctx *cartridge.Context) error {
+	var input events.UTMLinkInput
+	if err := ctx.Ctx.BodyParser(&input); err != nil {
+		return ctx.Ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+
+	link, err := events.BuildUTMLink(input, settings.IsUTMCaseNormalizationEnabled(ctx.DB()))
+	if err != nil {
+		return ctx.Ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return ctx.Ctx.JSON(link)
+}
@@ -0,0 +1,56 @@
+package http_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"fusionaly/internal/settings"
+	"fusionaly/internal/testsupport"
+)
+
+func TestUTMBuilderAction(t *testing.T) {
+	dbManager, _, _ := testsupport.SetupTestDBManagerWithWebsite(t, "example.com")
+	db := dbManager.GetConnection()
+	require.NoError(t, settings.SetupDefaultSettings(db))
+
+	testsupport.CreateTestUserForAuth(t, db, "admin@utm.com", "password123")
+	app := testsupport.CreateMinimalTestApp(t, db)
+	session := testsupport.LoginTestUser(t, app, "admin@utm.com", "password123")
+
+	build := func(body string) (int, map[string]any) {
+		req := httptest.NewRequest("POST", "/admin/utm-builder", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Accept", "application/json")
+		req.Header.Set("User-Agent", "Mozilla/5.0 Test Browser")
+		req.Header.Set("Sec-Fetch-Site", "same-origin")
+		req.Header.Set("Cookie", fmt.Sprintf("%s=%s; _tz=UTC", testsupport.SessionCookieName, session))
+
+		resp, err := app.Test(req, 30000)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		var payload map[string]any
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&payload))
+		return resp.StatusCode, payload
+	}
+
+	status, payload := build(`{"base_url": "https://example.com/landing", "utm_source": "google", "utm_medium": "cpc", "utm_campaign": "Spring Sale"}`)
+	require.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "https://example.com/landing?utm_campaign=Spring+Sale&utm_medium=cpc&utm_source=google", payload["url"])
+	assert.Len(t, payload["warnings"], 1, "spaces are flagged, uppercase isn't while normalization is on")
+
+	require.NoError(t, settings.SaveUTMCaseNormalizationEnabled(db, false))
+	_, payload = build(`{"base_url": "https://example.com/landing", "utm_source": "google", "utm_medium": "cpc", "utm_campaign": "Spring Sale"}`)
+	assert.Len(t, payload["warnings"], 2)
+
+	status, payload = build(`{"base_url": "https://example.com/landing", "utm_source": "google"}`)
+	assert.Equal(t, http.StatusBadRequest, status)
+	assert.Contains(t, payload["error"], "utm_medium is required")
+}
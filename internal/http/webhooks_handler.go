@@ -0,0 +1,75 @@
+package http
+
+import (
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/karloscodes/cartridge"
+	"gorm.io/gorm"
+
+	"fusionaly/internal/websites"
+)
+
+// WebsiteWebhookCreateAction adds a webhook to a website.
+// The signing secret is only ever shown in the success flash.
+func WebsiteWebhookCreateAction(ctx *cartridge.Context) error {
+	id, err := ctx.ParamsInt("id")
+	if err != nil {
+		return ctx.FlashError("Invalid website ID").Redirect("/admin", fiber.StatusFound)
+	}
+	editPath := fmt.Sprintf("/admin/websites/%d/edit", id)
+
+	db := ctx.DB()
+	if _, err := websites.GetWebsiteByID(db, uint(id)); err != nil {
+		return ctx.FlashError("Website not found").Redirect("/admin", fiber.StatusFound)
+	}
+
+	hook := &websites.Webhook{
+		WebsiteID: uint(id),
+		URL:       strings.TrimSpace(ctx.Input("url")),
+		Trigger:   websites.WebhookTrigger(ctx.Input("trigger")),
+		EventName: strings.TrimSpace(ctx.Input("event_name")),
+	}
+	if threshold := strings.TrimSpace(ctx.Input("threshold")); threshold != "" {
+		if hook.Threshold, err = strconv.ParseInt(threshold, 10, 64); err != nil {
+			return ctx.FlashError("Threshold must be a whole number").Redirect(editPath, fiber.StatusFound)
+		}
+	}
+
+	if err := websites.ValidateWebhook(hook); err != nil {
+		return ctx.FlashError(err.Error()).Redirect(editPath, fiber.StatusFound)
+	}
+	if err := websites.CreateWebhook(db, hook); err != nil {
+		ctx.Logger.Error("Failed to create webhook", slog.Any("error", err), slog.Int("id", id))
+		return ctx.FlashError("Failed to create webhook").Redirect(editPath, fiber.StatusFound)
+	}
+
+	return ctx.FlashSuccess(fmt.Sprintf("Webhook created. Signing secret: %s (copy it now, it won't be shown again)", hook.Secret)).Redirect(editPath, fiber.StatusFound)
+}
+
+// WebsiteWebhookDeleteAction removes one of a website's webhooks
+func WebsiteWebhookDeleteAction(ctx *cartridge.Context) error {
+	id, err := ctx.ParamsInt("id")
+	if err != nil {
+		return ctx.FlashError("Invalid website ID").Redirect("/admin", fiber.StatusFound)
+	}
+	editPath := fmt.Sprintf("/admin/websites/%d/edit", id)
+
+	webhookID, err := ctx.ParamsInt("webhookId")
+	if err != nil {
+		return ctx.FlashError("Invalid webhook ID").Redirect(editPath, fiber.StatusFound)
+	}
+
+	if err := websites.DeleteWebhook(ctx.DB(), uint(id), uint(webhookID)); err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return ctx.FlashError("Webhook not found").Redirect(editPath, fiber.StatusFound)
+		}
+		ctx.Logger.Error("Failed to delete webhook", slog.Any("error", err), slog.Int("id", id), slog.Int("webhook_id", webhookID))
+		return ctx.FlashError("Failed to delete webhook").Redirect(editPath, fiber.StatusFound)
+	}
+
+	return ctx.FlashSuccess("Webhook deleted").Redirect(editPath, fiber.StatusFound)
+}
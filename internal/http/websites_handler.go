@@ -2,12 +2,18 @@ package http
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"gorm.io/gorm"
 	"log/slog"
 
+	"fusionaly/internal/alerts"
+	"fusionaly/internal/analytics"
 	"fusionaly/internal/events"
 	"fusionaly/internal/settings"
 	"fusionaly/internal/websites"
@@ -15,10 +21,20 @@ import (
 	"github.com/karloscodes/cartridge/inertia"
 )
 
-// WebsitesIndexAction handles listing all websites (Inertia)
+// WebsitesIndexAction handles listing all websites (Inertia). Clients asking
+// for JSON get the list with today's visitors of each website instead.
 func WebsitesIndexAction(ctx *cartridge.Context) error {
 	db := ctx.DB()
 
+	if wantsJSON(ctx) {
+		websitesData, err := websites.GetWebsitesWithTodayVisitors(db, time.Now())
+		if err != nil {
+			ctx.Logger.Error("Failed to list websites", slog.Any("error", err))
+			return ctx.Ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to list websites"})
+		}
+		return ctx.Ctx.JSON(fiber.Map{"websites": websitesData})
+	}
+
 	// Get all websites with event count statistics
 	websitesWithCounts, err := websites.GetWebsitesWithStats(db, 30)
 	if err != nil {
@@ -89,6 +105,60 @@ func WebsiteCreateAction(ctx *cartridge.Context) error {
 	return ctx.FlashSuccess("Website created successfully").Redirect("/admin/websites/"+strconv.Itoa(int(website.ID))+"/setup", fiber.StatusFound)
 }
 
+// wantsJSON reports whether the request prefers a JSON response over a page.
+// Inertia visits also accept JSON, so they always get the page.
+func wantsJSON(ctx *cartridge.Context) bool {
+	if ctx.Ctx.Get("X-Inertia") != "" {
+		return false
+	}
+	return ctx.Ctx.Accepts(fiber.MIMETextHTML, fiber.MIMEApplicationJSON) == fiber.MIMEApplicationJSON
+}
+
+// bulkWebsitesRequest is the body of WebsitesBulkCreateAction
+type bulkWebsitesRequest struct {
+	Domains []string `json:"domains" form:"domains"`
+}
+
+// WebsitesBulkCreateAction creates several websites at once (JSON). Domains
+// that are invalid or already tracked are skipped and reported per domain.
+func WebsitesBulkCreateAction(ctx *cartridge.Context) error {
+	var req bulkWebsitesRequest
+	if err := ctx.Ctx.BodyParser(&req); err != nil {
+		return ctx.Ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+	if len(req.Domains) == 0 {
+		return ctx.Ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "domains is required"})
+	}
+	if len(req.Domains) > websites.MaxBulkDomains {
+		return ctx.Ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": fmt.Sprintf("At most %d domains can be created at once", websites.MaxBulkDomains),
+		})
+	}
+
+	results, err := websites.CreateWebsites(ctx.DB(), req.Domains)
+	if err != nil {
+		ctx.Logger.Error("Failed to create websites in bulk", slog.Any("error", err))
+		return ctx.Ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   "Failed to create websites",
+			"results": results,
+		})
+	}
+
+	created := 0
+	for _, result := range results {
+		if result.Status == websites.BulkStatusCreated {
+			created++
+		}
+	}
+	ctx.Logger.Info("Websites created in bulk", slog.Int("requested", len(req.Domains)), slog.Int("created", created))
+
+	return ctx.Ctx.JSON(fiber.Map{
+		"created": created,
+		"skipped": len(results) - created,
+		"results": results,
+	})
+}
+
 // WebsiteSetupPageAction handles showing the website setup page after creation (Inertia)
 func WebsiteSetupPageAction(ctx *cartridge.Context) error {
 	// Get website ID from params
@@ -158,12 +228,43 @@ func WebsiteEditPageAction(ctx *cartridge.Context) error {
 	// Fetch subdomain tracking setting for this website
 	subdomainTrackingEnabled := settings.IsSubdomainTrackingEnabled(db, website.Domain)
 
+	apiTokens, err := websites.ListAPITokens(db, uint(id))
+	if err != nil {
+		ctx.Logger.Error("Failed to fetch API tokens for website", slog.Any("error", err), slog.Int("id", id))
+		apiTokens = []websites.APIToken{}
+	}
+
+	webhooks, err := websites.ListWebhooks(db, uint(id))
+	if err != nil {
+		ctx.Logger.Error("Failed to fetch webhooks for website", slog.Any("error", err), slog.Int("id", id))
+		webhooks = []websites.Webhook{}
+	}
+
+	sharedLinks, err := websites.ListSharedLinks(db, uint(id))
+	if err != nil {
+		ctx.Logger.Error("Failed to fetch shared links for website", slog.Any("error", err), slog.Int("id", id))
+		sharedLinks = []websites.SharedLink{}
+	}
+
+	alertRules, err := alerts.ListRules(db, uint(id))
+	if err != nil {
+		ctx.Logger.Error("Failed to fetch alert rules for website", slog.Any("error", err), slog.Int("id", id))
+		alertRules = []alerts.Rule{}
+	}
+
 	return ctx.Inertia("WebsiteEdit", inertia.Props{
 		"title":                      "Edit Website",
 		"website":                    website,
 		"all_distinct_events":        allDistinctEvents,
 		"conversion_goals":           conversionGoals,
 		"subdomain_tracking_enabled": subdomainTrackingEnabled,
+		"subdomain_rollup_enabled":   settings.IsSubdomainRollupEnabled(db, website.Domain),
+		"session_timeout_minutes":    settings.GetSessionTimeoutMinutes(db, website.ID),
+		"track_params":               strings.Join(settings.GetDashboardTrackParams(db, website.ID), ", "),
+		"api_tokens":                 apiTokens,
+		"webhooks":                   webhooks,
+		"shared_links":               sharedLinks,
+		"alert_rules":                alertRules,
 	})
 }
 
@@ -199,6 +300,27 @@ func WebsiteUpdateAction(ctx *cartridge.Context) error {
 		slog.String("domain", website.Domain),
 		slog.Bool("subdomain_tracking", subdomainTrackingEnabled))
 
+	// Moving to a new domain keeps the history, remapped to the new hostname
+	if newDomain := strings.ToLower(strings.TrimSpace(ctx.Input("domain"))); newDomain != "" && newDomain != website.Domain {
+		if err := websites.ChangeDomain(db, website.ID, newDomain); err != nil {
+			if errors.Is(err, websites.ErrDomainTaken) {
+				return ctx.FlashError("Another website already uses "+newDomain).Redirect("/admin/websites/"+strconv.Itoa(id)+"/edit", fiber.StatusFound)
+			}
+			ctx.Logger.Error("Failed to change website domain", slog.Any("error", err), slog.Int("id", id), slog.String("domain", newDomain))
+			return ctx.FlashError("Failed to change domain").Redirect("/admin/websites/"+strconv.Itoa(id)+"/edit", fiber.StatusFound)
+		}
+		ctx.Logger.Info("Website domain changed", slog.Int("id", id), slog.String("from", website.Domain), slog.String("to", newDomain))
+
+		// Subdomain settings are keyed by domain; the form values are saved for the new one below
+		if err := settings.UpdateSubdomainTrackingSettings(db, website.Domain, false); err != nil {
+			ctx.Logger.Warn("Failed to clear subdomain tracking of the old domain", slog.Any("error", err))
+		}
+		if err := settings.UpdateSubdomainRollupSettings(db, website.Domain, false); err != nil {
+			ctx.Logger.Warn("Failed to clear subdomain rollup of the old domain", slog.Any("error", err))
+		}
+		website.Domain = newDomain
+	}
+
 	// Handle conversion goals update
 	if conversionGoalsJSON != "" {
 		ctx.Logger.Info("Processing conversion goals JSON", slog.String("json", conversionGoalsJSON))
@@ -210,6 +332,10 @@ func WebsiteUpdateAction(ctx *cartridge.Context) error {
 
 		ctx.Logger.Info("Parsed goals", slog.Any("goals", goals))
 
+		if err := settings.ValidateWebsiteGoals(goals); err != nil {
+			return ctx.FlashError(err.Error()).Redirect("/admin/websites/"+strconv.Itoa(id)+"/edit", fiber.StatusFound)
+		}
+
 		// Save goals for this website
 		if err := settings.SaveWebsiteGoals(db, uint(id), goals); err != nil {
 			ctx.Logger.Error("Failed to save conversion goals", slog.Any("error", err), slog.Int("id", id))
@@ -225,6 +351,33 @@ func WebsiteUpdateAction(ctx *cartridge.Context) error {
 		ctx.Logger.Error("Failed to update subdomain tracking setting", slog.Any("error", err), slog.String("domain", website.Domain))
 		return ctx.FlashError("Failed to update subdomain tracking setting").Redirect("/admin/websites/"+strconv.Itoa(id)+"/edit", fiber.StatusFound)
 	}
+	subdomainRollupEnabled := subdomainTrackingEnabled && ctx.Input("subdomain_rollup_enabled") == "true"
+	if err := settings.UpdateSubdomainRollupSettings(db, website.Domain, subdomainRollupEnabled); err != nil {
+		ctx.Logger.Error("Failed to update subdomain rollup setting", slog.Any("error", err), slog.String("domain", website.Domain))
+		return ctx.FlashError("Failed to update subdomain rollup setting").Redirect("/admin/websites/"+strconv.Itoa(id)+"/edit", fiber.StatusFound)
+	}
+
+	// Handle session timeout (empty means use the global default)
+	sessionTimeoutMinutes := 0
+	if value := strings.TrimSpace(ctx.Input("session_timeout_minutes")); value != "" {
+		sessionTimeoutMinutes, err = strconv.Atoi(value)
+		if err != nil {
+			return ctx.FlashError("Session timeout must be a number of minutes").Redirect("/admin/websites/"+strconv.Itoa(id)+"/edit", fiber.StatusFound)
+		}
+	}
+	if err := settings.SaveSessionTimeoutMinutes(db, website.ID, sessionTimeoutMinutes); err != nil {
+		ctx.Logger.Warn("Failed to save session timeout", slog.Any("error", err), slog.Int("id", id))
+		return ctx.FlashError(fmt.Sprintf("Session timeout must be between %d and %d minutes", settings.MinSessionTimeoutMinutes, settings.MaxSessionTimeoutMinutes)).Redirect("/admin/websites/"+strconv.Itoa(id)+"/edit", fiber.StatusFound)
+	}
+
+	// Handle the query parameters broken down on the dashboard besides ref
+	if err := settings.SaveDashboardTrackParams(db, website.ID, settings.ParseTrackParams(ctx.Input("track_params"))); err != nil {
+		ctx.Logger.Warn("Failed to save dashboard track params", slog.Any("error", err), slog.Int("id", id))
+		return ctx.FlashError(err.Error()).Redirect("/admin/websites/"+strconv.Itoa(id)+"/edit", fiber.StatusFound)
+	}
+
+	// Goals feed dashboard conversions, so don't serve them from a stale cache
+	analytics.InvalidateDashboardCache(id)
 
 	// Success - redirect back to the edit page
 	return ctx.FlashSuccess("Website updated successfully").Redirect("/admin/websites/"+strconv.Itoa(id)+"/edit", fiber.StatusFound)
@@ -241,7 +394,17 @@ func WebsiteDeleteAction(ctx *cartridge.Context) error {
 
 	db := ctx.DB()
 
-	// Delete website
+	website, err := websites.GetWebsiteByID(db, uint(id))
+	if err != nil {
+		return ctx.FlashError("Website not found").Redirect("/admin", fiber.StatusFound)
+	}
+
+	// All of the website's data goes with it, so the domain must be typed to confirm
+	if !strings.EqualFold(strings.TrimSpace(ctx.Input("confirm")), website.Domain) {
+		return ctx.FlashError("Type the website domain to confirm its deletion").Redirect("/admin", fiber.StatusFound)
+	}
+
+	// Delete the website and every row scoped to it
 	if err := websites.DeleteWebsite(db, uint(id)); err != nil {
 		if err == gorm.ErrRecordNotFound {
 			return ctx.FlashError("Website not found").Redirect("/admin", fiber.StatusFound)
@@ -249,7 +412,39 @@ func WebsiteDeleteAction(ctx *cartridge.Context) error {
 		ctx.Logger.Error("Failed to delete website", slog.Any("error", err), slog.Int("id", id))
 		return ctx.FlashError("Failed to delete website").Redirect("/admin", fiber.StatusFound)
 	}
+	analytics.InvalidateDashboardCache(id)
 
 	// Success - redirect to websites list
 	return ctx.FlashSuccess("Website deleted successfully").Redirect("/admin", fiber.StatusFound)
 }
+
+// WebsitePauseAction stops data collection for a website without deleting it
+func WebsitePauseAction(ctx *cartridge.Context) error {
+	return setWebsitePaused(ctx, true)
+}
+
+// WebsiteResumeAction restarts data collection for a paused website
+func WebsiteResumeAction(ctx *cartridge.Context) error {
+	return setWebsitePaused(ctx, false)
+}
+
+func setWebsitePaused(ctx *cartridge.Context, paused bool) error {
+	id, err := ctx.ParamsInt("id")
+	if err != nil {
+		return ctx.FlashError("Invalid website ID").Redirect("/admin", fiber.StatusFound)
+	}
+
+	if err := websites.SetPaused(ctx.DB(), uint(id), paused); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ctx.FlashError("Website not found").Redirect("/admin", fiber.StatusFound)
+		}
+		ctx.Logger.Error("Failed to update website data collection", slog.Any("error", err), slog.Int("id", id), slog.Bool("paused", paused))
+		return ctx.FlashError("Failed to update data collection").Redirect(fmt.Sprintf("/admin/websites/%d/edit", id), fiber.StatusFound)
+	}
+
+	message := "Data collection resumed"
+	if paused {
+		message = "Data collection paused, new events are dropped until it's resumed"
+	}
+	return ctx.FlashSuccess(message).Redirect(fmt.Sprintf("/admin/websites/%d/edit", id), fiber.StatusFound)
+}
@@ -0,0 +1,127 @@
+package http_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"fusionaly/internal/analytics"
+	"fusionaly/internal/testsupport"
+	"fusionaly/internal/websites"
+)
+
+func TestWebsitesBulkAPI(t *testing.T) {
+	dbManager, _, existing := testsupport.SetupTestDBManagerWithWebsite(t, "existing.com")
+	db := dbManager.GetConnection()
+
+	testsupport.CreateTestUserForAuth(t, db, "admin@bulk.com", "password123")
+	app := testsupport.CreateMinimalTestApp(t, db)
+	session := testsupport.LoginTestUser(t, app, "admin@bulk.com", "password123")
+
+	send := func(method, path, body string) (*http.Response, map[string]any) {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Accept", "application/json")
+		req.Header.Set("User-Agent", "Mozilla/5.0 Test Browser")
+		req.Header.Set("Sec-Fetch-Site", "same-origin")
+		req.Header.Set("Cookie", fmt.Sprintf("%s=%s; _tz=UTC", testsupport.SessionCookieName, session))
+
+		resp, err := app.Test(req, 30000)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		raw, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		var payload map[string]any
+		require.NoError(t, json.Unmarshal(raw, &payload), "response is not JSON: %s", raw)
+		return resp, payload
+	}
+
+	t.Run("creates new domains and skips existing ones", func(t *testing.T) {
+		resp, payload := send("POST", "/admin/websites/bulk",
+			`{"domains": ["new-one.com", "https://New-Two.com/pricing", "existing.com", "new-one.com", "not a domain"]}`)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		assert.Equal(t, float64(2), payload["created"])
+		assert.Equal(t, float64(3), payload["skipped"])
+
+		var statuses []string
+		for _, result := range payload["results"].([]any) {
+			r := result.(map[string]any)
+			statuses = append(statuses, fmt.Sprintf("%s:%s", r["domain"], r["status"]))
+		}
+		assert.Equal(t, []string{
+			"new-one.com:created",
+			"new-two.com:created",
+			"existing.com:exists",
+			"new-one.com:exists",
+			"not a domain:invalid",
+		}, statuses)
+
+		all, err := websites.GetAllWebsites(db)
+		require.NoError(t, err)
+		assert.Len(t, all, 3)
+	})
+
+	t.Run("rejects an empty list", func(t *testing.T) {
+		resp, payload := send("POST", "/admin/websites/bulk", `{"domains": []}`)
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+		assert.NotEmpty(t, payload["error"])
+	})
+
+	t.Run("lists websites with today's visitors", func(t *testing.T) {
+		now := time.Now().UTC()
+		require.NoError(t, db.Create(&[]analytics.SiteStat{
+			{WebsiteID: existing.ID, Visitors: 3, PageViews: 5, Hour: now.Truncate(time.Hour)},
+			{WebsiteID: existing.ID, Visitors: 4, PageViews: 4, Hour: now.Truncate(24 * time.Hour).Add(-time.Hour)},
+		}).Error)
+
+		resp, payload := send("GET", "/admin/websites", "")
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		visitors := map[string]float64{}
+		for _, website := range payload["websites"].([]any) {
+			w := website.(map[string]any)
+			visitors[w["domain"].(string)] = w["visitors_today"].(float64)
+		}
+		assert.Equal(t, map[string]float64{"existing.com": 3, "new-one.com": 0, "new-two.com": 0}, visitors,
+			"yesterday's visitors aren't counted")
+	})
+}
+
+func TestWebsitePauseResumeActions(t *testing.T) {
+	dbManager, _, website := testsupport.SetupTestDBManagerWithWebsite(t, "pausable.com")
+	db := dbManager.GetConnection()
+
+	testsupport.CreateTestUserForAuth(t, db, "admin@pause.com", "password123")
+	app := testsupport.CreateMinimalTestApp(t, db)
+	session := testsupport.LoginTestUser(t, app, "admin@pause.com", "password123")
+
+	post := func(path string) *http.Response {
+		req := httptest.NewRequest("POST", path, nil)
+		req.Header.Set("User-Agent", "Mozilla/5.0 Test Browser")
+		req.Header.Set("Sec-Fetch-Site", "same-origin")
+		req.Header.Set("Cookie", fmt.Sprintf("%s=%s; _tz=UTC", testsupport.SessionCookieName, session))
+
+		resp, err := app.Test(req, 30000)
+		require.NoError(t, err)
+		resp.Body.Close()
+		return resp
+	}
+
+	resp := post(fmt.Sprintf("/admin/websites/%d/pause", website.ID))
+	assert.Equal(t, http.StatusFound, resp.StatusCode)
+	assert.Equal(t, fmt.Sprintf("/admin/websites/%d/edit", website.ID), resp.Header.Get("Location"))
+	assert.True(t, websites.IsPaused(db, website.ID))
+
+	post(fmt.Sprintf("/admin/websites/%d/resume", website.ID))
+	assert.False(t, websites.IsPaused(db, website.ID))
+}
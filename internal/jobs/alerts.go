@@ -0,0 +1,32 @@
+package jobs
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"fusionaly/internal/alerts"
+	"fusionaly/internal/config"
+	"fusionaly/internal/database"
+	"fusionaly/internal/webhooks"
+)
+
+// AlertsJob evaluates alert rules and sends the ones that fire
+type AlertsJob struct {
+	dbManager *database.DBManager
+	logger    *slog.Logger
+	notifier  *alerts.Notifier
+}
+
+func NewAlertsJob(dbManager *database.DBManager, logger *slog.Logger, cfg *config.Config) *AlertsJob {
+	return &AlertsJob{
+		dbManager: dbManager,
+		logger:    logger,
+		notifier:  &alerts.Notifier{Config: cfg, Sender: webhooks.NewSender()},
+	}
+}
+
+// Run checks every alert rule against today's traffic so far
+func (j *AlertsJob) Run() error {
+	return alerts.Run(context.Background(), j.dbManager.GetConnection(), j.notifier, j.logger, time.Now().UTC())
+}
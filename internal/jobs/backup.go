@@ -0,0 +1,63 @@
+package jobs
+
+import (
+	"log/slog"
+	"time"
+
+	"fusionaly/internal/config"
+	"fusionaly/internal/database"
+)
+
+// BackupJob takes scheduled database backups and prunes old ones
+type BackupJob struct {
+	dbManager *database.DBManager
+	logger    *slog.Logger
+	cfg       *config.Config
+}
+
+func NewBackupJob(dbManager *database.DBManager, logger *slog.Logger, cfg *config.Config) *BackupJob {
+	return &BackupJob{
+		dbManager: dbManager,
+		logger:    logger,
+		cfg:       cfg,
+	}
+}
+
+// Run takes a backup when the newest one is older than the configured
+// interval, so restarts don't produce a backup each time.
+func (j *BackupJob) Run() error {
+	interval := time.Duration(j.cfg.BackupIntervalHours) * time.Hour
+	if interval <= 0 {
+		return nil
+	}
+
+	dir := j.cfg.GetBackupsDirectory()
+	backups, err := database.ListBackups(dir)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	if len(backups) > 0 && now.Sub(backups[0].CreatedAt) < interval {
+		j.logger.Debug("Database backup is up to date",
+			slog.Time("last_backup", backups[0].CreatedAt))
+		return nil
+	}
+
+	path, err := database.BackupDatabase(j.dbManager.GetConnection(), dir, now)
+	if err != nil {
+		j.logger.Error("Failed to back up database", slog.Any("error", err))
+		return err
+	}
+
+	maxAge := time.Duration(j.cfg.BackupRetentionDays) * 24 * time.Hour
+	deleted, err := database.PruneBackups(dir, j.cfg.BackupRetentionCount, maxAge, now)
+	if err != nil {
+		j.logger.Error("Failed to prune old database backups", slog.Any("error", err))
+		return err
+	}
+
+	j.logger.Info("Database backed up",
+		slog.String("path", path),
+		slog.Int("pruned_count", len(deleted)))
+	return nil
+}
@@ -7,6 +7,7 @@ import (
 	"fusionaly/internal/config"
 	"fusionaly/internal/database"
 	"fusionaly/internal/events"
+	"fusionaly/internal/settings"
 )
 
 // CleanupJob handles cleanup of old ingested events
@@ -24,9 +25,69 @@ func NewCleanupJob(dbManager *database.DBManager, logger *slog.Logger, cfg *conf
 	}
 }
 
-// Run removes processed ingested events older than the retention period.
+// rawSampleGracePeriod keeps recent raw events intact for session, bounce and
+// flow detection before sampling prunes them.
+const rawSampleGracePeriod = 24 * time.Hour
+
+// Run removes processed ingested events older than the retention period,
+// purges raw events past their retention and prunes raw events outside the
+// configured sample rate.
 // This helps with GDPR data minimization and reduces storage usage.
 func (j *CleanupJob) Run() error {
+	if err := j.cleanupIngestedEvents(); err != nil {
+		return err
+	}
+	if err := j.purgeRawEvents(); err != nil {
+		return err
+	}
+	return j.pruneUnsampledEvents()
+}
+
+// purgeRawEvents applies RawEventsRetentionDays. Aggregates are never purged.
+func (j *CleanupJob) purgeRawEvents() error {
+	retentionDays := j.cfg.RawEventsRetentionDays
+	if retentionDays <= 0 {
+		return nil
+	}
+
+	cutoffDate := time.Now().AddDate(0, 0, -retentionDays)
+	result, err := events.PurgeRawEvents(j.dbManager.GetConnection(), cutoffDate)
+	if err != nil {
+		j.logger.Error("Failed to purge raw events", slog.Any("error", err))
+		return err
+	}
+
+	if result.Events > 0 || result.IngestedEvents > 0 {
+		j.logger.Info("Purged raw events past retention",
+			slog.Int64("events_deleted", result.Events),
+			slog.Int64("ingested_events_deleted", result.IngestedEvents),
+			slog.Int("retention_days", retentionDays))
+	}
+	return nil
+}
+
+// pruneUnsampledEvents applies raw_event_sample_rate to the events table.
+func (j *CleanupJob) pruneUnsampledEvents() error {
+	db := j.dbManager.GetConnection()
+	sampleRate := settings.GetRawEventSampleRate(db)
+	if sampleRate >= 1 {
+		return nil
+	}
+
+	deleted, err := events.PruneUnsampledEvents(db, sampleRate, time.Now().Add(-rawSampleGracePeriod))
+	if err != nil {
+		j.logger.Error("Failed to prune unsampled raw events", slog.Any("error", err))
+		return err
+	}
+
+	j.logger.Info("Pruned unsampled raw events",
+		slog.Int64("deleted_count", deleted),
+		slog.Float64("sample_rate", sampleRate))
+	return nil
+}
+
+// cleanupIngestedEvents removes processed ingested events older than the retention period.
+func (j *CleanupJob) cleanupIngestedEvents() error {
 	retentionDays := j.cfg.IngestedEventsRetentionDays
 	db := j.dbManager.GetConnection()
 	cutoffDate := time.Now().AddDate(0, 0, -retentionDays)
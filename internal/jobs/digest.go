@@ -0,0 +1,95 @@
+package jobs
+
+import (
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
+
+	"fusionaly/internal/config"
+	"fusionaly/internal/database"
+	"fusionaly/internal/digest"
+	"fusionaly/internal/settings"
+	"fusionaly/internal/users"
+)
+
+// digestLastSentKey stores the start of the last week a digest went out for
+const digestLastSentKey = "weekly_digest_last_sent"
+
+// DigestJob emails last week's stats once a week
+type DigestJob struct {
+	dbManager *database.DBManager
+	logger    *slog.Logger
+	cfg       *config.Config
+}
+
+func NewDigestJob(dbManager *database.DBManager, logger *slog.Logger, cfg *config.Config) *DigestJob {
+	return &DigestJob{
+		dbManager: dbManager,
+		logger:    logger,
+		cfg:       cfg,
+	}
+}
+
+// Run sends the digest once the configured weekday and hour (UTC) are reached.
+// The week it was sent for is recorded, so restarts and hourly checks don't
+// send it twice.
+func (j *DigestJob) Run() error {
+	now := time.Now().UTC()
+	if now.Weekday() != j.cfg.GetDigestWeekday() || now.Hour() < j.cfg.DigestHour {
+		return nil
+	}
+
+	db := j.dbManager.GetConnection()
+	from, to := digest.PreviousWeek(now)
+	week := from.Format("2006-01-02")
+	if lastSent, _ := settings.GetSetting(db, digestLastSentKey); lastSent == week {
+		return nil
+	}
+
+	recipients, err := users.ListWeeklyDigestEmails(db)
+	if err != nil {
+		return err
+	}
+	for _, recipient := range j.cfg.GetDigestRecipients() {
+		if !slices.Contains(recipients, recipient) {
+			recipients = append(recipients, recipient)
+		}
+	}
+	if len(recipients) == 0 {
+		j.logger.Debug("No weekly digest recipients")
+		return nil
+	}
+
+	summary, err := digest.Build(db, from, to, j.logger)
+	if err != nil {
+		return err
+	}
+	subject, body, err := digest.Render(summary)
+	if err != nil {
+		return err
+	}
+
+	// Sent one recipient at a time: the week counts as sent once anyone got it,
+	// so a single bad address doesn't resend the digest to everyone hourly
+	sent := 0
+	for _, recipient := range recipients {
+		if err := digest.SendMail(j.cfg, []string{recipient}, subject, body); err != nil {
+			j.logger.Error("Failed to send weekly digest", slog.String("recipient", recipient), slog.Any("error", err))
+			continue
+		}
+		sent++
+	}
+	if sent == 0 {
+		return fmt.Errorf("failed to send the weekly digest to any of %d recipients", len(recipients))
+	}
+	if err := settings.CreateOrUpdateSetting(db, digestLastSentKey, week); err != nil {
+		return err
+	}
+
+	j.logger.Info("Weekly digest sent",
+		slog.String("week", week),
+		slog.Int("recipients", sent),
+		slog.Int("failed", len(recipients)-sent))
+	return nil
+}
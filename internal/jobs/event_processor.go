@@ -1,24 +1,43 @@
 package jobs
 
 import (
+	"context"
 	"log/slog"
+	"time"
+
+	"github.com/karloscodes/cartridge/sqlite"
+	"gorm.io/gorm"
 
 	"fusionaly/internal/analytics"
+	"fusionaly/internal/config"
 	"fusionaly/internal/database"
 	"fusionaly/internal/events"
+	"fusionaly/internal/health"
+	"fusionaly/internal/metrics"
 	"fusionaly/internal/pkg/geoip"
+	"fusionaly/internal/webhooks"
 )
 
 // EventProcessorJob handles processing of ingested events
 type EventProcessorJob struct {
-	dbManager *database.DBManager
-	logger    *slog.Logger
+	dbManager         *database.DBManager
+	logger            *slog.Logger
+	lagMonitor        *LagMonitor
+	partialAggregator *PartialAggregator
+	webhooks          *webhookQueue
 }
 
 func NewEventProcessorJob(dbManager *database.DBManager, logger *slog.Logger) *EventProcessorJob {
+	cfg := config.GetConfig()
 	return &EventProcessorJob{
 		dbManager: dbManager,
 		logger:    logger,
+		lagMonitor: NewLagMonitor(logger,
+			int64(cfg.AggregationLagThreshold),
+			time.Duration(cfg.AggregationLagWarnAfterSeconds)*time.Second),
+		partialAggregator: NewPartialAggregator(logger,
+			time.Duration(cfg.PartialAggregationIntervalSeconds)*time.Second),
+		webhooks: newWebhookQueue(webhooks.NewSender(), logger, webhookQueueSize),
 	}
 }
 
@@ -26,6 +45,17 @@ func NewEventProcessorJob(dbManager *database.DBManager, logger *slog.Logger) *E
 func (j *EventProcessorJob) Run() error {
 	j.logger.Info("Starting event processing")
 
+	db := j.dbManager.GetConnection()
+	if db == nil {
+		return gorm.ErrInvalidDB
+	}
+
+	// Track backlog before the GeoLite check: a missing database is exactly
+	// when events pile up unprocessed
+	if err := j.lagMonitor.Check(db); err != nil {
+		j.logger.Warn("Failed to measure aggregation lag", slog.Any("error", err))
+	}
+
 	// Check if GeoLite database is available - required for event processing
 	if geoip.GetGeoDB() == nil {
 		j.logger.Warn("GeoLite database not configured - events will remain queued. " +
@@ -33,8 +63,6 @@ func (j *EventProcessorJob) Run() error {
 		return nil
 	}
 
-	db := j.dbManager.GetConnection()
-
 	// Count unprocessed events
 	var unprocessedCount int64
 	if err := db.Model(&events.IngestedEvent{}).Where("processed = 0").Count(&unprocessedCount).Error; err != nil {
@@ -45,19 +73,52 @@ func (j *EventProcessorJob) Run() error {
 	j.logger.Info("Found unprocessed events", slog.Int64("count", unprocessedCount))
 
 	if unprocessedCount == 0 {
+		health.SetLastProcessingRun(time.Now())
 		return nil
 	}
 
-	// Process the events
-	result, err := events.ProcessUnprocessedEvents(j.dbManager, j.logger, 100)
+	// Larger backlogs are processed in larger batches to catch up
+	cfg := config.GetConfig()
+	batchSize := events.AdaptiveBatchSize(unprocessedCount, cfg.ProcessingBatchSize, cfg.ProcessingMaxBatchSize)
+	health.SetProcessingBatchSize(batchSize)
+
+	result, err := events.ProcessUnprocessedEvents(j.dbManager, j.logger, batchSize)
 	if err != nil {
+		if sqlite.IsBusyError(err) {
+			metrics.DBBusyErrorsTotal.Inc()
+		}
 		j.logger.Error("Failed to process events", slog.Any("error", err))
 		return err
 	}
 
+	health.SetLastProcessingRun(time.Now())
+
 	processedCount := 0
 	if result != nil {
 		processedCount = len(result.ProcessedEvents)
+		metrics.EventsProcessedTotal.Add(int64(processedCount))
+		if result.Failed > 0 {
+			j.logger.Warn("Events failed processing; after repeated failures they move to failed_events",
+				slog.Int("failed", result.Failed))
+		}
+
+		// New aggregates make cached dashboards for these websites stale
+		invalidated := make(map[uint]bool)
+		for _, event := range result.ProcessedEvents {
+			if !invalidated[event.WebsiteID] {
+				invalidated[event.WebsiteID] = true
+				analytics.InvalidateDashboardCache(int(event.WebsiteID))
+			}
+		}
+		// Fire webhooks now the batch is committed; retries back off, so they're
+		// sent from the queue's worker
+		deliveries, err := webhooks.DueDeliveries(db, result.ProcessedEvents, time.Now())
+		if err != nil {
+			j.logger.Warn("Failed to evaluate webhooks", slog.Any("error", err))
+		} else if len(deliveries) > 0 {
+			j.webhooks.enqueue(deliveries)
+		}
+
 		// Log details about processed events (first 5 only)
 		for i, event := range result.ProcessedEvents {
 			if i < 5 {
@@ -89,12 +150,19 @@ func (j *EventProcessorJob) Run() error {
 
 	j.logger.Info("Events processed",
 		slog.Int("count", processedCount),
+		slog.Int("batch_size", batchSize),
 		slog.Int64("remaining", unprocessedCount-int64(processedCount)))
 
-	// Compute flow transitions for recent hours
-	if err := events.ComputeFlowTransitionsForRecentHours(db, j.logger, 2, 5); err != nil {
-		j.logger.Warn("Failed to compute flow transitions", slog.Any("error", err))
+	// Recompute partial aggregates for recent hours, throttled per website
+	if _, err := j.partialAggregator.Run(db); err != nil {
+		j.logger.Warn("Failed to compute partial aggregates", slog.Any("error", err))
 	}
 
 	return nil
 }
+
+// StopWebhooks stops accepting webhook deliveries and waits for the queued
+// ones to be sent, cancelling them if ctx expires first.
+func (j *EventProcessorJob) StopWebhooks(ctx context.Context) error {
+	return j.webhooks.close(ctx)
+}
@@ -0,0 +1,87 @@
+package jobs
+
+import (
+	"log/slog"
+	"time"
+
+	"gorm.io/gorm"
+
+	"fusionaly/internal/events"
+	"fusionaly/internal/health"
+)
+
+// LagMonitor warns when the unprocessed event backlog stays above a threshold
+// for longer than a grace period. It warns once per incident and logs again
+// when the backlog recovers, so ops notice before dashboards go stale without
+// flooding the logs on every tick.
+type LagMonitor struct {
+	logger    *slog.Logger
+	threshold int64
+	warnAfter time.Duration
+	now       func() time.Time
+
+	exceededSince time.Time
+	warned        bool
+}
+
+func NewLagMonitor(logger *slog.Logger, threshold int64, warnAfter time.Duration) *LagMonitor {
+	return &LagMonitor{
+		logger:    logger,
+		threshold: threshold,
+		warnAfter: warnAfter,
+		now:       time.Now,
+	}
+}
+
+// Check measures the current backlog from ingested_events and observes it.
+func (m *LagMonitor) Check(db *gorm.DB) error {
+	var backlog int64
+	if err := db.Model(&events.IngestedEvent{}).Where("processed = 0").Count(&backlog).Error; err != nil {
+		return err
+	}
+
+	var oldest events.IngestedEvent
+	if backlog > 0 {
+		if err := db.Select("created_at").Where("processed = 0").Order("id asc").Limit(1).Find(&oldest).Error; err != nil {
+			return err
+		}
+	}
+	m.Observe(backlog, oldest.CreatedAt)
+	return nil
+}
+
+// Observe records a backlog sample, updates the lag gauge and returns true
+// when this sample emitted the lag warning.
+func (m *LagMonitor) Observe(backlog int64, oldestUnprocessed time.Time) bool {
+	now := m.now()
+
+	var lag time.Duration
+	if backlog > 0 && !oldestUnprocessed.IsZero() {
+		lag = now.Sub(oldestUnprocessed)
+	}
+	health.SetAggregationLag(backlog, lag)
+
+	if m.threshold <= 0 || backlog <= m.threshold {
+		if m.warned {
+			m.logger.Info("Aggregation backlog recovered", slog.Int64("backlog", backlog))
+		}
+		m.exceededSince = time.Time{}
+		m.warned = false
+		return false
+	}
+
+	if m.exceededSince.IsZero() {
+		m.exceededSince = now
+	}
+	if m.warned || now.Sub(m.exceededSince) < m.warnAfter {
+		return false
+	}
+
+	m.warned = true
+	m.logger.Warn("Aggregation is lagging behind ingestion; dashboards may be stale",
+		slog.Int64("backlog", backlog),
+		slog.Int64("threshold", m.threshold),
+		slog.Duration("exceeded_for", now.Sub(m.exceededSince)),
+		slog.Duration("oldest_unprocessed_age", lag))
+	return true
+}
@@ -0,0 +1,57 @@
+package jobs
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"fusionaly/internal/health"
+)
+
+func TestLagMonitor(t *testing.T) {
+	var logs bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&logs, &slog.HandlerOptions{Level: slog.LevelInfo}))
+
+	clock := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	monitor := NewLagMonitor(logger, 100, 5*time.Minute)
+	monitor.now = func() time.Time { return clock }
+
+	warnings := func() int {
+		return strings.Count(logs.String(), "Aggregation is lagging behind ingestion")
+	}
+
+	t.Run("warns exactly once while the backlog stays above threshold", func(t *testing.T) {
+		oldest := clock
+		for backlog := int64(50); backlog <= 500; backlog += 50 {
+			monitor.Observe(backlog, oldest)
+			clock = clock.Add(time.Minute)
+		}
+
+		assert.Equal(t, 1, warnings())
+		assert.Equal(t, int64(500), health.AggregationLag().Backlog)
+		assert.Equal(t, int64(9*60), health.AggregationLag().LagSeconds)
+	})
+
+	t.Run("does not warn before the grace period elapses", func(t *testing.T) {
+		fresh := NewLagMonitor(logger, 100, 5*time.Minute)
+		fresh.now = func() time.Time { return clock }
+
+		assert.False(t, fresh.Observe(1000, clock))
+		clock = clock.Add(4 * time.Minute)
+		assert.False(t, fresh.Observe(1000, clock))
+	})
+
+	t.Run("warns again after recovering", func(t *testing.T) {
+		assert.False(t, monitor.Observe(10, clock))
+		assert.Contains(t, logs.String(), "Aggregation backlog recovered")
+
+		assert.False(t, monitor.Observe(1000, clock))
+		clock = clock.Add(5 * time.Minute)
+		assert.True(t, monitor.Observe(1000, clock))
+		assert.Equal(t, 2, warnings())
+	})
+}
@@ -0,0 +1,89 @@
+package jobs
+
+import (
+	"log/slog"
+	"time"
+
+	"gorm.io/gorm"
+
+	"fusionaly/internal/events"
+	"fusionaly/internal/websites"
+)
+
+const (
+	partialAggregationHoursBack = 2
+	partialAggregationMaxDepth  = 5
+)
+
+// PartialAggregator recomputes the still-open recent hours (the partial
+// "today" aggregates such as flow transitions). Websites on the global
+// interval are refreshed together in one pass; busy sites can set their own
+// interval to trade freshness for load and are then refreshed on their own.
+type PartialAggregator struct {
+	logger          *slog.Logger
+	defaultInterval time.Duration
+	now             func() time.Time
+	lastDefaultRun  time.Time
+	lastRun         map[uint]time.Time
+}
+
+func NewPartialAggregator(logger *slog.Logger, defaultInterval time.Duration) *PartialAggregator {
+	return &PartialAggregator{
+		logger:          logger,
+		defaultInterval: defaultInterval,
+		now:             time.Now,
+		lastRun:         make(map[uint]time.Time),
+	}
+}
+
+// Run recomputes recent hours for every website whose interval has elapsed
+// and returns how many websites were refreshed.
+func (p *PartialAggregator) Run(db *gorm.DB) (int, error) {
+	var sites []websites.Website
+	if err := db.Select("id", "partial_aggregation_interval_seconds").Find(&sites).Error; err != nil {
+		return 0, err
+	}
+
+	now := p.now()
+	hour := now.UTC().Truncate(time.Hour)
+	refreshed := 0
+
+	var overridden []uint
+	defaultSites := 0
+	for _, site := range sites {
+		if site.PartialAggregationIntervalSeconds <= 0 {
+			defaultSites++
+			continue
+		}
+		overridden = append(overridden, site.ID)
+
+		interval := time.Duration(site.PartialAggregationIntervalSeconds) * time.Second
+		if last, ok := p.lastRun[site.ID]; ok && now.Sub(last) < interval {
+			continue
+		}
+		p.computeRecentHours(hour, func(h time.Time) error {
+			return events.ComputeFlowTransitionsForWebsiteHour(db, p.logger, site.ID, h, partialAggregationMaxDepth)
+		})
+		p.lastRun[site.ID] = now
+		refreshed++
+	}
+
+	if defaultSites > 0 && (p.lastDefaultRun.IsZero() || now.Sub(p.lastDefaultRun) >= p.defaultInterval) {
+		p.computeRecentHours(hour, func(h time.Time) error {
+			return events.ComputeFlowTransitionsForHourExcluding(db, p.logger, overridden, h, partialAggregationMaxDepth)
+		})
+		p.lastDefaultRun = now
+		refreshed += defaultSites
+	}
+
+	return refreshed, nil
+}
+
+// computeRecentHours runs compute for the current hour and the ones before it
+func (p *PartialAggregator) computeRecentHours(hour time.Time, compute func(time.Time) error) {
+	for i := 0; i < partialAggregationHoursBack; i++ {
+		if err := compute(hour.Add(-time.Duration(i) * time.Hour)); err != nil {
+			p.logger.Warn("Failed to compute flow transitions", slog.Any("error", err))
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package jobs
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"fusionaly/internal/analytics"
+	"fusionaly/internal/config"
+	"fusionaly/internal/database"
+	"fusionaly/internal/events"
+	"fusionaly/internal/websites"
+)
+
+func setupPartialAggregationDB(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	cfg := *config.GetConfig()
+	cfg.DatabaseName = filepath.Join(t.TempDir(), "partial.db")
+
+	dbManager := database.NewDBManager(&cfg, testLogger())
+	require.NoError(t, dbManager.Init())
+	require.NoError(t, dbManager.MigrateDatabase())
+	t.Cleanup(func() { dbManager.Close() })
+
+	return dbManager.GetConnection()
+}
+
+func TestPartialAggregator(t *testing.T) {
+	db := setupPartialAggregationDB(t)
+
+	website := &websites.Website{Domain: "partial.com", PartialAggregationIntervalSeconds: 60}
+	require.NoError(t, websites.CreateWebsite(db, website))
+
+	hourStart := time.Now().UTC().Truncate(time.Hour)
+	visit := func(visitor string, offset time.Duration, paths ...string) {
+		for i, path := range paths {
+			require.NoError(t, db.Create(&events.Event{
+				WebsiteID:     website.ID,
+				UserSignature: visitor,
+				Hostname:      website.Domain,
+				Pathname:      path,
+				EventType:     events.EventTypePageView,
+				Timestamp:     hourStart.Add(offset + time.Duration(i)*time.Second),
+			}).Error)
+		}
+	}
+	transitions := func() int {
+		var total int
+		require.NoError(t, db.Model(&analytics.FlowTransitionStat{}).
+			Where("website_id = ?", website.ID).
+			Select("COALESCE(SUM(transitions), 0)").Scan(&total).Error)
+		return total
+	}
+
+	clock := time.Now()
+	aggregator := NewPartialAggregator(testLogger(), 0)
+	aggregator.now = func() time.Time { return clock }
+
+	visit("visitor-1", 0, "/", "/pricing")
+
+	refreshed, err := aggregator.Run(db)
+	require.NoError(t, err)
+	assert.Equal(t, 1, refreshed)
+	assert.Equal(t, 1, transitions())
+
+	t.Run("skips websites whose interval has not elapsed", func(t *testing.T) {
+		visit("visitor-2", 10*time.Second, "/", "/signup")
+		clock = clock.Add(30 * time.Second)
+
+		refreshed, err := aggregator.Run(db)
+		require.NoError(t, err)
+		assert.Zero(t, refreshed)
+		assert.Equal(t, 1, transitions())
+	})
+
+	t.Run("updates today's aggregates once the interval elapses", func(t *testing.T) {
+		clock = clock.Add(31 * time.Second)
+
+		refreshed, err := aggregator.Run(db)
+		require.NoError(t, err)
+		assert.Equal(t, 1, refreshed)
+		assert.Equal(t, 2, transitions())
+	})
+
+	t.Run("falls back to the global interval", func(t *testing.T) {
+		require.NoError(t, db.Model(website).Update("partial_aggregation_interval_seconds", 0).Error)
+		global := NewPartialAggregator(testLogger(), 5*time.Minute)
+		global.now = func() time.Time { return clock }
+
+		refreshed, err := global.Run(db)
+		require.NoError(t, err)
+		assert.Equal(t, 1, refreshed)
+
+		clock = clock.Add(2 * time.Minute)
+		refreshed, err = global.Run(db)
+		require.NoError(t, err)
+		assert.Zero(t, refreshed)
+	})
+
+	t.Run("leaves throttled websites out of the global pass", func(t *testing.T) {
+		require.NoError(t, db.Model(website).Update("partial_aggregation_interval_seconds", 3600).Error)
+		require.NoError(t, websites.CreateWebsite(db, &websites.Website{Domain: "other.com"}))
+
+		throttled := NewPartialAggregator(testLogger(), 0)
+		throttled.now = func() time.Time { return clock }
+		throttled.lastRun[website.ID] = clock
+
+		visit("visitor-3", 20*time.Second, "/", "/docs")
+		before := transitions()
+
+		refreshed, err := throttled.Run(db)
+		require.NoError(t, err)
+		assert.Equal(t, 1, refreshed, "only other.com is on the global interval")
+		assert.Equal(t, before, transitions())
+	})
+}
@@ -8,6 +8,7 @@ import (
 
 	"fusionaly/internal/config"
 	"fusionaly/internal/database"
+	"fusionaly/internal/health"
 )
 
 // Scheduler is responsible for running background jobs
@@ -20,21 +21,31 @@ type Scheduler struct {
 	isRunning bool
 	cfg       *config.Config
 
-	// Mutex to prevent concurrent job executions
+	// Mutex to prevent concurrent job executions. running is keyed by guard:
+	// event processing and feed detection share processingGuard, while slower
+	// or less frequent jobs only guard against their own previous run, so a
+	// busy event processor can't make them skip a tick.
 	processingMutex sync.Mutex
-	isProcessing    bool
+	running         map[string]bool
+	inFlight        sync.WaitGroup
 
 	// Job instances
 	eventProcessor   *EventProcessorJob
 	cleanupJob       *CleanupJob
 	geoLiteUpdater   *GeoLiteUpdaterJob
 	feedJob          *FeedJob
+	backupJob        *BackupJob
+	digestJob        *DigestJob
+	alertsJob        *AlertsJob
 
 	// Tickers for each job type
 	eventTicker   *time.Ticker
 	cleanupTicker *time.Ticker
 	geoLiteTicker *time.Ticker
 	feedTicker    *time.Ticker
+	backupTicker  *time.Ticker
+	digestTicker  *time.Ticker
+	alertsTicker  *time.Ticker
 }
 
 func NewScheduler(dbManager *database.DBManager, logger *slog.Logger) (*Scheduler, error) {
@@ -49,6 +60,7 @@ func NewScheduler(dbManager *database.DBManager, logger *slog.Logger) (*Schedule
 		enabled:   true,
 		isRunning: false,
 		cfg:       cfg,
+		running:   make(map[string]bool),
 	}
 
 	// Initialize job instances
@@ -56,22 +68,48 @@ func NewScheduler(dbManager *database.DBManager, logger *slog.Logger) (*Schedule
 	s.cleanupJob = NewCleanupJob(dbManager, logger, cfg)
 	s.geoLiteUpdater = NewGeoLiteUpdaterJob(dbManager, logger, cfg)
 	s.feedJob = NewFeedJob(dbManager, logger)
+	s.backupJob = NewBackupJob(dbManager, logger, cfg)
+	s.digestJob = NewDigestJob(dbManager, logger, cfg)
+	s.alertsJob = NewAlertsJob(dbManager, logger, cfg)
 
 	return s, nil
 }
 
-// executeJobSafely runs a job only if no other job is currently executing
+// processingGuard is shared by the jobs that must not overlap each other
+const processingGuard = "processing"
+
+// executeJobSafely runs a job only if no other job sharing processingGuard is
+// currently executing
 func (s *Scheduler) executeJobSafely(jobName string, jobFunc func() error) {
+	s.executeGuarded(jobName, processingGuard, jobFunc)
+}
+
+// executeOwnJobSafely runs a job only if its own previous run has finished,
+// whatever the other jobs are doing
+func (s *Scheduler) executeOwnJobSafely(jobName string, jobFunc func() error) {
+	s.executeGuarded(jobName, jobName, jobFunc)
+}
+
+// executeGuarded runs a job unless another job holding the same guard is
+// still executing
+func (s *Scheduler) executeGuarded(jobName, guard string, jobFunc func() error) {
 	s.processingMutex.Lock()
-	if s.isProcessing {
+	if !s.enabled {
+		s.processingMutex.Unlock()
+		return
+	}
+	if s.running[guard] {
 		s.logger.Debug("Skipping job execution - previous job still running", slog.String("job", jobName))
 		s.processingMutex.Unlock()
 		return
 	}
-	s.isProcessing = true
+	s.running[guard] = true
+	s.inFlight.Add(1)
 	s.processingMutex.Unlock()
 
 	defer func() {
+		defer s.inFlight.Done()
+
 		if r := recover(); r != nil {
 			s.logger.Error("Panic recovered in background job",
 				slog.String("job", jobName),
@@ -79,7 +117,7 @@ func (s *Scheduler) executeJobSafely(jobName string, jobFunc func() error) {
 		}
 
 		s.processingMutex.Lock()
-		s.isProcessing = false
+		s.running[guard] = false
 		s.processingMutex.Unlock()
 	}()
 
@@ -116,6 +154,15 @@ func (s *Scheduler) Start() error {
 	// Start activity feed detection job
 	s.startFeedJob()
 
+	// Start scheduled database backup job
+	s.startBackupJob()
+
+	// Start weekly email digest job
+	s.startDigestJob()
+
+	// Start alert rules evaluation job
+	s.startAlertsJob()
+
 	s.logger.Info("Background jobs started",
 		slog.Bool("enabled", s.enabled),
 		slog.Bool("isRunning", s.isRunning))
@@ -127,8 +174,11 @@ func (s *Scheduler) startEventProcessingJob() {
 	interval := time.Duration(s.cfg.JobIntervalSeconds) * time.Second
 	s.logger.Info("Starting event processing job", slog.Duration("interval", interval))
 	s.eventTicker = time.NewTicker(interval)
+	health.SetProcessorRunning(true)
 
 	go func() {
+		defer health.SetProcessorRunning(false)
+
 		// Run initial execution
 		s.logger.Info("Running initial event processing...")
 		s.executeJobSafely("event_processor", s.eventProcessor.Run)
@@ -222,11 +272,90 @@ func (s *Scheduler) startFeedJob() {
 	}()
 }
 
+func (s *Scheduler) startBackupJob() {
+	if s.cfg.BackupIntervalHours <= 0 {
+		s.logger.Info("Scheduled database backups are disabled")
+		return
+	}
+
+	// Check hourly; the job itself only backs up once the interval has passed
+	interval := time.Hour
+	s.logger.Info("Starting database backup job",
+		slog.Duration("check_interval", interval),
+		slog.Int("backup_interval_hours", s.cfg.BackupIntervalHours))
+	s.backupTicker = time.NewTicker(interval)
+
+	go func() {
+		s.executeOwnJobSafely("backup", s.backupJob.Run)
+
+		for {
+			select {
+			case <-s.backupTicker.C:
+				s.executeOwnJobSafely("backup", s.backupJob.Run)
+			case <-s.ctx.Done():
+				s.logger.Info("Database backup job stopped")
+				return
+			}
+		}
+	}()
+}
+
+func (s *Scheduler) startDigestJob() {
+	if !s.cfg.SMTPConfigured() {
+		s.logger.Info("Weekly digest is disabled, SMTP is not configured")
+		return
+	}
+
+	// Check hourly; the job itself only sends once the configured weekday and hour arrive
+	interval := time.Hour
+	s.logger.Info("Starting weekly digest job",
+		slog.Duration("check_interval", interval),
+		slog.String("weekday", s.cfg.GetDigestWeekday().String()),
+		slog.Int("hour_utc", s.cfg.DigestHour))
+	s.digestTicker = time.NewTicker(interval)
+
+	go func() {
+		s.executeOwnJobSafely("digest", s.digestJob.Run)
+
+		for {
+			select {
+			case <-s.digestTicker.C:
+				s.executeOwnJobSafely("digest", s.digestJob.Run)
+			case <-s.ctx.Done():
+				s.logger.Info("Weekly digest job stopped")
+				return
+			}
+		}
+	}()
+}
+
+func (s *Scheduler) startAlertsJob() {
+	// Alert rules look at today's traffic so far; the per-rule cool-down keeps
+	// frequent checks from repeating notifications
+	interval := 15 * time.Minute
+	s.logger.Info("Starting alerts job", slog.Duration("interval", interval))
+	s.alertsTicker = time.NewTicker(interval)
+
+	go func() {
+		for {
+			select {
+			case <-s.alertsTicker.C:
+				s.executeOwnJobSafely("alerts", s.alertsJob.Run)
+			case <-s.ctx.Done():
+				s.logger.Info("Alerts job stopped")
+				return
+			}
+		}
+	}()
+}
+
 // Stop halts all background jobs.
 // Implements cartridge.BackgroundWorker interface.
 func (s *Scheduler) Stop() {
 	s.logger.Info("Stopping background jobs...")
+	s.processingMutex.Lock()
 	s.enabled = false
+	s.processingMutex.Unlock()
 
 	if s.eventTicker != nil {
 		s.eventTicker.Stop()
@@ -240,12 +369,68 @@ func (s *Scheduler) Stop() {
 	if s.feedTicker != nil {
 		s.feedTicker.Stop()
 	}
+	if s.backupTicker != nil {
+		s.backupTicker.Stop()
+	}
+	if s.digestTicker != nil {
+		s.digestTicker.Stop()
+	}
+	if s.alertsTicker != nil {
+		s.alertsTicker.Stop()
+	}
 
 	s.cancel()
 	s.isRunning = false
 	s.logger.Info("Background jobs stopped")
 }
 
+// Drain stops the scheduler, waits for the in-flight job to finish its batch and
+// runs a final event processing pass so events ingested since the last tick are
+// aggregated before the database closes. Returns ctx.Err() if ctx expires first;
+// the final pass is then skipped and unprocessed events stay queued for the next
+// start. A batch already being processed is a single transaction, so Drain
+// always waits for it to return before the caller closes the database. Queued
+// webhook deliveries are then sent until ctx expires and cancelled after that.
+func (s *Scheduler) Drain(ctx context.Context) error {
+	wasRunning := s.isRunning
+	s.Stop()
+	if !wasRunning {
+		return s.eventProcessor.StopWebhooks(ctx)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		s.inFlight.Wait()
+		if ctx.Err() != nil {
+			return
+		}
+
+		s.logger.Info("Running final event processing before shutdown...")
+		if err := s.eventProcessor.Run(); err != nil {
+			s.logger.Error("Error in final event processing", slog.Any("error", err))
+		}
+	}()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		s.logger.Warn("Timed out draining background jobs, waiting for the current batch", slog.Any("error", ctx.Err()))
+		<-done
+	}
+
+	// Webhooks queued by the last batches get whatever is left of ctx
+	if err := s.eventProcessor.StopWebhooks(ctx); err != nil {
+		s.logger.Warn("Timed out sending queued webhooks", slog.Any("error", err))
+		return err
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.logger.Info("Background jobs drained")
+	return nil
+}
+
 // IsRunning returns whether jobs are currently running
 func (s *Scheduler) IsRunning() bool {
 	return s.isRunning
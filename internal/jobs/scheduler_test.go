@@ -0,0 +1,174 @@
+package jobs
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"fusionaly/internal/config"
+	"fusionaly/internal/database"
+	"fusionaly/internal/events"
+	"fusionaly/internal/websites"
+)
+
+func testLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+}
+
+func setupDrainScheduler(t *testing.T) (*Scheduler, *database.DBManager) {
+	t.Helper()
+
+	cfg := *config.GetConfig()
+	cfg.DatabaseName = filepath.Join(t.TempDir(), "drain.db")
+
+	logger := testLogger()
+	dbManager := database.NewDBManager(&cfg, logger)
+	require.NoError(t, dbManager.Init())
+	require.NoError(t, dbManager.MigrateDatabase())
+	t.Cleanup(func() { dbManager.Close() })
+
+	s, err := NewScheduler(dbManager, logger)
+	require.NoError(t, err)
+	// Simulate a started scheduler without spawning the periodic jobs
+	s.isRunning = true
+
+	return s, dbManager
+}
+
+func TestSchedulerDrain(t *testing.T) {
+	t.Run("waits for the in-flight batch to finish", func(t *testing.T) {
+		s, _ := setupDrainScheduler(t)
+
+		started := make(chan struct{})
+		var finished atomic.Bool
+		go s.executeJobSafely("event_processor", func() error {
+			close(started)
+			time.Sleep(200 * time.Millisecond)
+			finished.Store(true)
+			return nil
+		})
+		<-started
+
+		require.NoError(t, s.Drain(context.Background()))
+		assert.True(t, finished.Load())
+	})
+
+	t.Run("events enqueued right before shutdown are persisted after drain", func(t *testing.T) {
+		s, dbManager := setupDrainScheduler(t)
+		db := dbManager.GetConnection()
+
+		website := &websites.Website{Domain: "drain.com"}
+		require.NoError(t, websites.CreateWebsite(db, website))
+
+		started := make(chan struct{})
+		go s.executeJobSafely("event_processor", func() error {
+			close(started)
+			time.Sleep(100 * time.Millisecond)
+			return nil
+		})
+		<-started
+
+		for i := 0; i < 5; i++ {
+			require.NoError(t, events.CollectEvent(dbManager, testLogger(), &events.CollectEventInput{
+				IPAddress: "203.0.113.10",
+				UserAgent: "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36",
+				EventType: events.EventTypePageView,
+				Timestamp: time.Now().UTC(),
+				RawUrl:    "https://drain.com/page",
+			}))
+		}
+
+		require.NoError(t, s.Drain(context.Background()))
+		require.NoError(t, dbManager.Close())
+
+		// Reopen to verify the events survived the close
+		var count int64
+		require.NoError(t, dbManager.GetConnection().Model(&events.IngestedEvent{}).
+			Where("website_id = ?", website.ID).Count(&count).Error)
+		assert.Equal(t, int64(5), count)
+	})
+
+	t.Run("times out when the batch outlives the context", func(t *testing.T) {
+		s, _ := setupDrainScheduler(t)
+
+		started := make(chan struct{})
+		release := make(chan struct{})
+		var finished atomic.Bool
+		go s.executeJobSafely("event_processor", func() error {
+			close(started)
+			<-release
+			finished.Store(true)
+			return nil
+		})
+		<-started
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		go func() {
+			<-ctx.Done()
+			time.Sleep(50 * time.Millisecond)
+			close(release)
+		}()
+
+		assert.ErrorIs(t, s.Drain(ctx), context.DeadlineExceeded)
+		assert.True(t, finished.Load(), "the batch returns before the database can be closed")
+	})
+
+	t.Run("is a no-op when the scheduler never started", func(t *testing.T) {
+		s, _ := setupDrainScheduler(t)
+		s.isRunning = false
+
+		assert.NoError(t, s.Drain(context.Background()))
+	})
+}
+
+func TestSchedulerJobGuards(t *testing.T) {
+	s, _ := setupDrainScheduler(t)
+
+	release := make(chan struct{})
+	started := make(chan struct{})
+	go s.executeJobSafely("event_processor", func() error {
+		close(started)
+		<-release
+		return nil
+	})
+	<-started
+	defer close(release)
+
+	t.Run("jobs sharing the processing guard skip while it is held", func(t *testing.T) {
+		ran := false
+		s.executeJobSafely("feed", func() error {
+			ran = true
+			return nil
+		})
+		assert.False(t, ran)
+	})
+
+	t.Run("jobs with their own guard run alongside event processing", func(t *testing.T) {
+		ran := false
+		s.executeOwnJobSafely("backup", func() error {
+			ran = true
+			return nil
+		})
+		assert.True(t, ran)
+	})
+
+	t.Run("jobs with their own guard skip their overlapping runs", func(t *testing.T) {
+		ran := false
+		s.executeOwnJobSafely("digest", func() error {
+			s.executeOwnJobSafely("digest", func() error {
+				ran = true
+				return nil
+			})
+			return nil
+		})
+		assert.False(t, ran)
+	})
+}
@@ -0,0 +1,105 @@
+package jobs
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+
+	"fusionaly/internal/webhooks"
+)
+
+// webhookQueueSize bounds the deliveries waiting to be sent. Deliveries
+// arriving while the queue is full are dropped and logged.
+const webhookQueueSize = 1000
+
+// webhookQueue sends webhook deliveries one at a time from a single worker,
+// so slow receivers and retry backoff can't pile up goroutines.
+type webhookQueue struct {
+	sender *webhooks.Sender
+	logger *slog.Logger
+
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu         sync.Mutex
+	deliveries chan webhooks.Delivery
+	closed     bool
+	start      sync.Once
+	done       chan struct{}
+}
+
+func newWebhookQueue(sender *webhooks.Sender, logger *slog.Logger, size int) *webhookQueue {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &webhookQueue{
+		sender:     sender,
+		logger:     logger,
+		ctx:        ctx,
+		cancel:     cancel,
+		deliveries: make(chan webhooks.Delivery, size),
+		done:       make(chan struct{}),
+	}
+}
+
+// enqueue queues deliveries for the worker, starting it on first use
+func (q *webhookQueue) enqueue(deliveries []webhooks.Delivery) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.closed {
+		q.logger.Warn("Webhook queue closed, dropping deliveries", slog.Int("count", len(deliveries)))
+		return
+	}
+	q.start.Do(func() { go q.run() })
+
+	for _, delivery := range deliveries {
+		select {
+		case q.deliveries <- delivery:
+		default:
+			q.logger.Warn("Webhook queue full, dropping delivery",
+				slog.Uint64("webhook_id", uint64(delivery.Webhook.ID)),
+				slog.String("trigger", string(delivery.Payload.Trigger)))
+		}
+	}
+}
+
+// run sends queued deliveries until the queue is closed. Once the context is
+// cancelled the remaining deliveries are discarded.
+func (q *webhookQueue) run() {
+	defer close(q.done)
+	discarded := 0
+	for delivery := range q.deliveries {
+		if q.ctx.Err() != nil {
+			discarded++
+			continue
+		}
+		q.sender.SendAll(q.ctx, q.logger, []webhooks.Delivery{delivery})
+	}
+	if discarded > 0 {
+		q.logger.Warn("Discarded pending webhook deliveries", slog.Int("count", discarded))
+	}
+}
+
+// close stops accepting deliveries and waits for the queued ones to be sent.
+// If ctx expires first, in-flight retries are cancelled, the rest of the queue
+// is discarded and ctx.Err() is returned.
+func (q *webhookQueue) close(ctx context.Context) error {
+	q.mu.Lock()
+	if q.closed {
+		q.mu.Unlock()
+		return nil
+	}
+	q.closed = true
+	close(q.deliveries)
+	q.start.Do(func() { go q.run() })
+	q.mu.Unlock()
+	defer q.cancel()
+
+	select {
+	case <-q.done:
+		return nil
+	case <-ctx.Done():
+		q.cancel()
+		<-q.done
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,97 @@
+package jobs
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"fusionaly/internal/webhooks"
+	"fusionaly/internal/websites"
+)
+
+func TestWebhookQueue(t *testing.T) {
+	newDeliveries := func(url string, n int) []webhooks.Delivery {
+		deliveries := make([]webhooks.Delivery, n)
+		for i := range deliveries {
+			deliveries[i] = webhooks.Delivery{
+				Webhook: websites.Webhook{ID: uint(i + 1), URL: url, Secret: "secret"},
+				Payload: webhooks.Payload{Trigger: websites.WebhookTriggerGoal, WebsiteID: 1},
+			}
+		}
+		return deliveries
+	}
+
+	t.Run("sends deliveries one at a time", func(t *testing.T) {
+		var received, active, maxActive atomic.Int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			current := active.Add(1)
+			if current > maxActive.Load() {
+				maxActive.Store(current)
+			}
+			time.Sleep(5 * time.Millisecond)
+			active.Add(-1)
+			received.Add(1)
+		}))
+		defer server.Close()
+
+		queue := newWebhookQueue(webhooks.NewSender(), testLogger(), webhookQueueSize)
+		queue.enqueue(newDeliveries(server.URL, 3))
+		queue.enqueue(newDeliveries(server.URL, 2))
+
+		require.NoError(t, queue.close(context.Background()))
+		assert.Equal(t, int32(5), received.Load(), "close waits for queued deliveries")
+		assert.Equal(t, int32(1), maxActive.Load())
+	})
+
+	t.Run("drops deliveries once the queue is full", func(t *testing.T) {
+		var received atomic.Int32
+		started := make(chan struct{}, 1)
+		release := make(chan struct{})
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if received.Add(1) == 1 {
+				started <- struct{}{}
+				<-release
+			}
+		}))
+		defer server.Close()
+
+		queue := newWebhookQueue(webhooks.NewSender(), testLogger(), 1)
+		queue.enqueue(newDeliveries(server.URL, 1))
+		<-started
+		queue.enqueue(newDeliveries(server.URL, 3))
+		close(release)
+
+		require.NoError(t, queue.close(context.Background()))
+		assert.Equal(t, int32(2), received.Load())
+	})
+
+	t.Run("cancels pending deliveries when the context expires", func(t *testing.T) {
+		var received atomic.Int32
+		release := make(chan struct{})
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			received.Add(1)
+			<-release
+		}))
+		// Cleanups run in reverse, so the handler is released before Close
+		// waits for it
+		t.Cleanup(server.Close)
+		t.Cleanup(func() { close(release) })
+
+		queue := newWebhookQueue(webhooks.NewSender(), testLogger(), webhookQueueSize)
+		queue.enqueue(newDeliveries(server.URL, 3))
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		assert.ErrorIs(t, queue.close(ctx), context.DeadlineExceeded)
+		assert.Equal(t, int32(1), received.Load(), "the rest of the queue is discarded")
+
+		queue.enqueue(newDeliveries(server.URL, 1))
+		assert.Equal(t, int32(1), received.Load(), "a closed queue accepts no deliveries")
+	})
+}
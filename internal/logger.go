@@ -0,0 +1,34 @@
+package internal
+
+import (
+	"io"
+	"log/slog"
+
+	"github.com/karloscodes/cartridge"
+
+	"fusionaly/internal/config"
+)
+
+// newLogger creates the application logger. Without an explicit log format
+// cartridge decides: colored text in development, JSON to stdout and a
+// rotating file in production. An explicit format writes to w only, which is
+// what log shippers collecting stdout expect.
+func newLogger(cfg *config.Config, w io.Writer) *slog.Logger {
+	if cfg.LogFormat == "" || (cfg.LogFormat == config.LogFormatJSON && cfg.IsProduction()) {
+		return cartridge.NewLogger(cfg, nil)
+	}
+
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(cfg.GetLogLevel())); err != nil {
+		level = slog.LevelInfo
+	}
+	opts := &slog.HandlerOptions{
+		Level:     level,
+		AddSource: level == slog.LevelDebug,
+	}
+
+	if cfg.LogFormat == config.LogFormatJSON {
+		return slog.New(slog.NewJSONHandler(w, opts))
+	}
+	return slog.New(slog.NewTextHandler(w, opts))
+}
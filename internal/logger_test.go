@@ -0,0 +1,92 @@
+package internal
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"fusionaly/internal/config"
+	"fusionaly/internal/http/middleware"
+)
+
+// jsonLines decodes every line written to buf, failing on any line that isn't JSON
+func jsonLines(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	t.Helper()
+	var lines []map[string]any
+	scanner := bufio.NewScanner(buf)
+	for scanner.Scan() {
+		var line map[string]any
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &line), "log line is not JSON: %s", scanner.Text())
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+func TestNewLoggerJSONFormat(t *testing.T) {
+	var buf bytes.Buffer
+	logger := newLogger(&config.Config{Environment: config.Development, LogLevel: config.LogLevelInfo, LogFormat: config.LogFormatJSON}, &buf)
+
+	logger.Info("first", "count", 3)
+	logger.Debug("below the configured level")
+	logger.Warn("second", "domain", "example.com")
+
+	lines := jsonLines(t, &buf)
+	require.Len(t, lines, 2)
+	assert.Equal(t, "first", lines[0]["msg"])
+	assert.Equal(t, float64(3), lines[0]["count"])
+	assert.Equal(t, "WARN", lines[1]["level"])
+	assert.Equal(t, "example.com", lines[1]["domain"])
+}
+
+func TestNewLoggerTextFormat(t *testing.T) {
+	var buf bytes.Buffer
+	logger := newLogger(&config.Config{Environment: config.Development, LogLevel: config.LogLevelInfo, LogFormat: config.LogFormatText}, &buf)
+
+	logger.Info("hello", "count", 3)
+	assert.Contains(t, buf.String(), "msg=hello count=3")
+}
+
+func TestRequestLoggerStructuredFields(t *testing.T) {
+	var buf bytes.Buffer
+	logger := newLogger(&config.Config{Environment: config.Development, LogLevel: config.LogLevelInfo, LogFormat: config.LogFormatJSON}, &buf)
+
+	app := fiber.New()
+	app.Use(middleware.RequestLogger(logger))
+	app.Post("/x/api/v1/events", func(c *fiber.Ctx) error {
+		c.Locals(middleware.WebsiteDomainKey, "example.com")
+		return c.SendStatus(fiber.StatusAccepted)
+	})
+	app.Get("/_health", func(c *fiber.Ctx) error { return c.SendStatus(fiber.StatusOK) })
+	app.Get("/missing/:id", func(c *fiber.Ctx) error { return fiber.ErrNotFound })
+
+	for _, req := range []struct{ method, target string }{
+		{fiber.MethodPost, "/x/api/v1/events"},
+		{fiber.MethodGet, "/_health"},
+		{fiber.MethodGet, "/missing/42"},
+	} {
+		resp, err := app.Test(httptest.NewRequest(req.method, req.target, nil))
+		require.NoError(t, err)
+		resp.Body.Close()
+	}
+
+	lines := jsonLines(t, &buf)
+	require.Len(t, lines, 2, "health checks aren't logged")
+
+	assert.Equal(t, "http request", lines[0]["msg"])
+	assert.Equal(t, "POST", lines[0]["method"])
+	assert.Equal(t, "/x/api/v1/events", lines[0]["route"])
+	assert.Equal(t, float64(fiber.StatusAccepted), lines[0]["status"])
+	assert.Equal(t, "example.com", lines[0]["website_domain"])
+	assert.Contains(t, lines[0], "duration_ms")
+
+	assert.Equal(t, "/missing/:id", lines[1]["route"])
+	assert.Equal(t, "/missing/42", lines[1]["path"])
+	assert.Equal(t, float64(fiber.StatusNotFound), lines[1]["status"])
+	assert.NotContains(t, lines[1], "website_domain")
+}
@@ -0,0 +1,55 @@
+package metrics
+
+import (
+	"strconv"
+
+	"fusionaly/internal/health"
+)
+
+// Default is the registry served by the /metrics endpoint.
+var Default = NewRegistry()
+
+// Core application metrics. They are always updated; RegisterCore exposes them.
+var (
+	EventsIngestedTotal = NewCounter("fusionaly_events_ingested_total",
+		"Events accepted by the ingestion endpoints.")
+	EventsProcessedTotal = NewCounter("fusionaly_events_processed_total",
+		"Ingested events aggregated by the event processor.")
+	DBBusyErrorsTotal = NewCounter("fusionaly_db_busy_errors_total",
+		"Writes that failed because the database was busy or locked.")
+	EventsDroppedTotal = NewCounter("fusionaly_events_dropped_total",
+		"Events dropped because the ingestion buffer was full.")
+	EventsFailedTotal = NewCounter("fusionaly_events_failed_total",
+		"Ingested events moved to failed_events after repeatedly failing processing.")
+	HTTPRequestDuration = NewHistogram("fusionaly_http_request_duration_seconds",
+		"HTTP request latency by method and status class.",
+		[]float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5},
+		"method", "status")
+)
+
+// RegisterCore registers the core application metrics on r, including the
+// aggregation backlog gauges reported by the health package.
+func RegisterCore(r *Registry) {
+	r.Register(
+		EventsIngestedTotal,
+		EventsProcessedTotal,
+		NewGaugeFunc("fusionaly_events_unprocessed",
+			"Ingested events awaiting aggregation.",
+			func() float64 { return float64(health.AggregationLag().Backlog) }),
+		NewGaugeFunc("fusionaly_aggregation_lag_seconds",
+			"Age of the oldest unprocessed event.",
+			func() float64 { return float64(health.AggregationLag().LagSeconds) }),
+		DBBusyErrorsTotal,
+		EventsDroppedTotal,
+		EventsFailedTotal,
+		HTTPRequestDuration,
+	)
+}
+
+// StatusClass groups an HTTP status code into its class, e.g. 404 -> "4xx".
+func StatusClass(status int) string {
+	if status < 100 || status > 599 {
+		return "unknown"
+	}
+	return strconv.Itoa(status/100) + "xx"
+}
@@ -0,0 +1,103 @@
+package metrics_test
+
+import (
+	"bytes"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"fusionaly/internal/health"
+	"fusionaly/internal/metrics"
+)
+
+var (
+	sampleLine = regexp.MustCompile(`^([a-zA-Z_:][a-zA-Z0-9_:]*)(\{[a-zA-Z_][a-zA-Z0-9_]*="[^"]*"(,[a-zA-Z_][a-zA-Z0-9_]*="[^"]*")*\})? (\S+)$`)
+	typeLine   = regexp.MustCompile(`^# TYPE ([a-zA-Z_:][a-zA-Z0-9_:]*) (counter|gauge|histogram)$`)
+	helpLine   = regexp.MustCompile(`^# HELP ([a-zA-Z_:][a-zA-Z0-9_:]*) .+$`)
+)
+
+// parseExposition checks every line of the text format and returns the
+// declared metric types and the sample values keyed by "name{labels}".
+func parseExposition(t *testing.T, text string) (map[string]string, map[string]float64) {
+	t.Helper()
+	types := make(map[string]string)
+	samples := make(map[string]float64)
+
+	for _, line := range strings.Split(strings.TrimSuffix(text, "\n"), "\n") {
+		if m := typeLine.FindStringSubmatch(line); m != nil {
+			types[m[1]] = m[2]
+			continue
+		}
+		if helpLine.MatchString(line) {
+			continue
+		}
+
+		m := sampleLine.FindStringSubmatch(line)
+		require.NotNil(t, m, "invalid exposition line: %q", line)
+
+		family := m[1]
+		for _, suffix := range []string{"_bucket", "_sum", "_count"} {
+			if base := strings.TrimSuffix(family, suffix); types[base] == "histogram" {
+				family = base
+			}
+		}
+		require.Contains(t, types, family, "sample before its TYPE line: %q", line)
+
+		value, err := strconv.ParseFloat(m[4], 64)
+		require.NoError(t, err, line)
+		samples[m[1]+m[2]] = value
+	}
+	return types, samples
+}
+
+func TestRegistryExposition(t *testing.T) {
+	registry := metrics.NewRegistry()
+	metrics.RegisterCore(registry)
+	metrics.RegisterCore(registry) // registering twice is a no-op
+
+	metrics.EventsIngestedTotal.Add(3)
+	metrics.EventsProcessedTotal.Inc()
+	metrics.DBBusyErrorsTotal.Inc()
+	health.SetAggregationLag(42, 90*time.Second)
+	defer health.SetAggregationLag(0, 0)
+	metrics.HTTPRequestDuration.Observe(0.02, "GET", metrics.StatusClass(200))
+	metrics.HTTPRequestDuration.Observe(3, "GET", metrics.StatusClass(204))
+	metrics.HTTPRequestDuration.Observe(0.001, "POST", metrics.StatusClass(404))
+
+	var buf bytes.Buffer
+	require.NoError(t, registry.WriteText(&buf))
+	types, samples := parseExposition(t, buf.String())
+
+	assert.Equal(t, "counter", types["fusionaly_events_ingested_total"])
+	assert.Equal(t, "counter", types["fusionaly_events_processed_total"])
+	assert.Equal(t, "counter", types["fusionaly_db_busy_errors_total"])
+	assert.Equal(t, "gauge", types["fusionaly_events_unprocessed"])
+	assert.Equal(t, "histogram", types["fusionaly_http_request_duration_seconds"])
+	assert.Equal(t, 1, strings.Count(buf.String(), "# TYPE fusionaly_events_ingested_total "))
+
+	// Counters are process-wide, so only assert they include this test's increments
+	assert.GreaterOrEqual(t, samples["fusionaly_events_ingested_total"], 3.0)
+	assert.GreaterOrEqual(t, samples["fusionaly_events_processed_total"], 1.0)
+	assert.GreaterOrEqual(t, samples["fusionaly_db_busy_errors_total"], 1.0)
+	assert.Equal(t, 42.0, samples["fusionaly_events_unprocessed"])
+	assert.Equal(t, 90.0, samples["fusionaly_aggregation_lag_seconds"])
+
+	assert.Equal(t, 0.0, samples[`fusionaly_http_request_duration_seconds_bucket{method="GET",status="2xx",le="0.01"}`])
+	assert.Equal(t, 1.0, samples[`fusionaly_http_request_duration_seconds_bucket{method="GET",status="2xx",le="0.025"}`])
+	assert.Equal(t, 2.0, samples[`fusionaly_http_request_duration_seconds_bucket{method="GET",status="2xx",le="+Inf"}`])
+	assert.Equal(t, 2.0, samples[`fusionaly_http_request_duration_seconds_count{method="GET",status="2xx"}`])
+	assert.InDelta(t, 3.02, samples[`fusionaly_http_request_duration_seconds_sum{method="GET",status="2xx"}`], 1e-9)
+	assert.Equal(t, 1.0, samples[`fusionaly_http_request_duration_seconds_bucket{method="POST",status="4xx",le="0.005"}`])
+}
+
+func TestStatusClass(t *testing.T) {
+	assert.Equal(t, "2xx", metrics.StatusClass(200))
+	assert.Equal(t, "3xx", metrics.StatusClass(302))
+	assert.Equal(t, "5xx", metrics.StatusClass(503))
+	assert.Equal(t, "unknown", metrics.StatusClass(0))
+}
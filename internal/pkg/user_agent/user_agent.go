@@ -11,14 +11,16 @@ import (
 )
 
 type UserAgent struct {
-	UserAgent string
-	OS        string
-	Browser   string
-	Device    string
-	Mobile    bool
-	Tablet    bool
-	Desktop   bool
-	Bot       bool
+	UserAgent      string
+	OS             string
+	Browser        string
+	BrowserVersion string
+	Device         string
+	Model          string
+	Mobile         bool
+	Tablet         bool
+	Desktop        bool
+	Bot            bool
 }
 
 // Embed the database files
@@ -65,8 +67,9 @@ type OSEntry struct {
 
 // Device model structure
 type DeviceModel struct {
-	Regex string `yaml:"regex"`
-	Model string `yaml:"model"`
+	Regex  string `yaml:"regex"`
+	Device string `yaml:"device"`
+	Model  string `yaml:"model"`
 }
 
 // Device entry structure
@@ -77,6 +80,13 @@ type DeviceEntry struct {
 	Models []DeviceModel `yaml:"models"`
 }
 
+// deviceBrand keeps a brand's entry together with its name, since several
+// device files define the same brand and the YAML order decides precedence
+type deviceBrand struct {
+	Brand string
+	Entry DeviceEntry
+}
+
 // Bot entry structure
 type BotEntry struct {
 	Regex    string `yaml:"regex"`
@@ -134,7 +144,7 @@ var (
 type DeviceDetectorParser struct {
 	browsers   []BrowserEntry
 	oss        []OSEntry
-	devices    map[string]DeviceEntry
+	devices    []deviceBrand
 	bots       []BotEntry
 	regexCache *RegexCache
 }
@@ -143,7 +153,6 @@ func getParser() *DeviceDetectorParser {
 	once.Do(func() {
 		parser = &DeviceDetectorParser{
 			regexCache: newRegexCache(),
-			devices:    make(map[string]DeviceEntry),
 		}
 
 		// Load browsers
@@ -167,32 +176,76 @@ func getParser() *DeviceDetectorParser {
 			}
 		}
 
-		// Load devices from multiple files
+		// Load devices from multiple files, more specific device kinds first
+		// (same order as Matomo's device parsers)
 		deviceFiles := []string{
-			"database/device/mobiles.yml",
 			"database/device/notebooks.yml",
-			"database/device/televisions.yml",
 			"database/device/consoles.yml",
-			"database/device/cameras.yml",
 			"database/device/car_browsers.yml",
+			"database/device/cameras.yml",
 			"database/device/portable_media_player.yml",
+			"database/device/mobiles.yml",
+			"database/device/televisions.yml",
 			"database/device/shell_tv.yml",
 		}
 
 		for _, file := range deviceFiles {
 			if data, err := databaseFiles.ReadFile(file); err == nil {
-				var brands map[string]DeviceEntry
-				if err := yaml.Unmarshal(data, &brands); err == nil {
-					for brand, entry := range brands {
-						parser.devices[brand] = entry
-					}
+				brands, err := parseDeviceBrands(data)
+				if err != nil {
+					fmt.Printf("Error parsing %s: %v\n", file, err)
+					continue
 				}
+				parser.devices = append(parser.devices, brands...)
 			}
 		}
 	})
 	return parser
 }
 
+// parseDeviceBrands decodes a device file keeping the brands in file order,
+// which a plain map would lose
+func parseDeviceBrands(data []byte) ([]deviceBrand, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return nil, nil
+	}
+
+	nodes := doc.Content[0].Content
+	brands := make([]deviceBrand, 0, len(nodes)/2)
+	for i := 0; i+1 < len(nodes); i += 2 {
+		var entry DeviceEntry
+		if err := nodes[i+1].Decode(&entry); err != nil {
+			return nil, err
+		}
+		brands = append(brands, deviceBrand{Brand: nodes[i].Value, Entry: entry})
+	}
+	return brands, nil
+}
+
+// deviceRegex wraps a device pattern the way Matomo does: case-insensitive
+// and anchored to a token boundary, so short model codes don't match inside
+// unrelated words
+func deviceRegex(pattern string) string {
+	return `(?i)(?:^|[^A-Z0-9\-_]|[^A-Z0-9\-]_|sprd-|MZ-)(?:` + pattern + `)`
+}
+
+// expandMatches fills $1, $2, ... in a model template from the regex groups;
+// groups that did not participate in the match become empty
+func expandMatches(template string, matches []string) string {
+	for i := 9; i >= 1; i-- {
+		group := ""
+		if i < len(matches) {
+			group = matches[i]
+		}
+		template = strings.ReplaceAll(template, fmt.Sprintf("$%d", i), group)
+	}
+	return strings.Join(strings.Fields(template), " ")
+}
+
 func (p *DeviceDetectorParser) parseBot(userAgent string) *BotEntry {
 	for _, bot := range p.bots {
 		if regex, err := p.regexCache.get(bot.Regex); err == nil {
@@ -252,45 +305,29 @@ func (p *DeviceDetectorParser) parseOS(userAgent string) (string, string) {
 }
 
 func (p *DeviceDetectorParser) parseDevice(userAgent string) (string, string, bool, bool, bool) {
-	for brand, entry := range p.devices {
-		if regex, err := p.regexCache.get(entry.Regex); err == nil {
+	for _, device := range p.devices {
+		brand, entry := device.Brand, device.Entry
+		if regex, err := p.regexCache.get(deviceRegex(entry.Regex)); err == nil {
 			if matches := regex.FindStringSubmatch(userAgent); len(matches) > 0 {
 				deviceType := entry.Device
-				if deviceType == "" {
-					deviceType = "Unknown"
-				}
-
 				model := ""
 
 				// Check for specific model matches
-				if len(entry.Models) > 0 {
-					for _, modelEntry := range entry.Models {
-						if modelRegex, err := p.regexCache.get(modelEntry.Regex); err == nil {
-							if modelMatches := modelRegex.FindStringSubmatch(userAgent); modelMatches != nil {
-								model = modelEntry.Model
-								// Replace $1, $2, etc. with actual match groups
-								if len(modelMatches) > 1 {
-									for i, match := range modelMatches[1:] {
-										placeholder := fmt.Sprintf("$%d", i+1)
-										model = strings.ReplaceAll(model, placeholder, match)
-									}
-								}
-								break
+				for _, modelEntry := range entry.Models {
+					if modelRegex, err := p.regexCache.get(deviceRegex(modelEntry.Regex)); err == nil {
+						if modelMatches := modelRegex.FindStringSubmatch(userAgent); len(modelMatches) > 0 {
+							model = expandMatches(modelEntry.Model, modelMatches)
+							if modelEntry.Device != "" {
+								deviceType = modelEntry.Device
 							}
+							break
 						}
 					}
 				}
 
 				// If no specific model found, use the generic model
 				if model == "" && entry.Model != "" {
-					model = entry.Model
-					// Replace $1, $2, etc. with actual match groups from main regex
-					if len(matches) > 1 {
-						for i, match := range matches[1:] {
-							placeholder := fmt.Sprintf("$%d", i+1)
-							model = strings.ReplaceAll(model, placeholder, match)
-						}
-					}
+					model = expandMatches(entry.Model, matches)
 				}
 
 				// Default to brand if no model
@@ -298,6 +335,10 @@ func (p *DeviceDetectorParser) parseDevice(userAgent string) (string, string, bo
 					model = brand
 				}
 
+				if deviceType == "" {
+					deviceType = "Unknown"
+				}
+
 				// Determine device characteristics
 				mobile := deviceType == "smartphone" || deviceType == "feature phone" || deviceType == "phablet"
 				tablet := deviceType == "tablet"
@@ -327,6 +368,12 @@ func (p *DeviceDetectorParser) parseDevice(userAgent string) (string, string, bo
 	return "Desktop", "Desktop Device", false, false, true
 }
 
+// IsBot reports whether the user agent matches the embedded bot ruleset,
+// without running the full browser/OS/device detection.
+func IsBot(userAgent string) bool {
+	return getParser().parseBot(userAgent) != nil
+}
+
 func ParseUserAgent(userAgent string) UserAgent {
 	parser := getParser()
 
@@ -344,18 +391,20 @@ func ParseUserAgent(userAgent string) UserAgent {
 		}
 	}
 
-	browser, _ := parser.parseBrowser(userAgent)
+	browser, browserVersion := parser.parseBrowser(userAgent)
 	os, _ := parser.parseOS(userAgent)
-	brand, _, mobile, tablet, desktop := parser.parseDevice(userAgent)
+	brand, model, mobile, tablet, desktop := parser.parseDevice(userAgent)
 
 	return UserAgent{
-		UserAgent: userAgent,
-		OS:        os,
-		Browser:   browser,
-		Device:    brand,
-		Mobile:    mobile,
-		Tablet:    tablet,
-		Desktop:   desktop,
-		Bot:       false,
+		UserAgent:      userAgent,
+		OS:             os,
+		Browser:        browser,
+		BrowserVersion: browserVersion,
+		Device:         brand,
+		Model:          model,
+		Mobile:         mobile,
+		Tablet:         tablet,
+		Desktop:        desktop,
+		Bot:            false,
 	}
 }
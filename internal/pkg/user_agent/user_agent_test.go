@@ -80,3 +80,89 @@ func TestParseUserAgent(t *testing.T) {
 		})
 	}
 }
+
+func TestParseUserAgentDeviceModel(t *testing.T) {
+	testCases := []struct {
+		name          string
+		userAgent     string
+		expectedBrand string
+		expectedModel string
+		expectedType  string
+	}{
+		{
+			name:          "iPhone",
+			userAgent:     "Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.0 Mobile/15E148 Safari/604.1",
+			expectedBrand: "Apple",
+			expectedModel: "iPhone",
+			expectedType:  "mobile",
+		},
+		{
+			name:          "iPad",
+			userAgent:     "Mozilla/5.0 (iPad; CPU OS 17_0 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.0 Mobile/15E148 Safari/604.1",
+			expectedBrand: "Apple",
+			expectedModel: "iPad",
+			expectedType:  "tablet",
+		},
+		{
+			name:          "Google Pixel",
+			userAgent:     "Mozilla/5.0 (Linux; Android 14; Pixel 8 Pro) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Mobile Safari/537.36",
+			expectedBrand: "Google",
+			expectedModel: "Pixel 8 Pro",
+			expectedType:  "mobile",
+		},
+		{
+			name:          "Samsung Galaxy phone",
+			userAgent:     "Mozilla/5.0 (Linux; Android 13; SM-S911B) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Mobile Safari/537.36",
+			expectedBrand: "Samsung",
+			expectedModel: "Galaxy S23",
+			expectedType:  "mobile",
+		},
+		{
+			name:          "Xiaomi phone",
+			userAgent:     "Mozilla/5.0 (Linux; Android 12; 2201116SG) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Mobile Safari/537.36",
+			expectedBrand: "Xiaomi",
+			expectedModel: "Redmi Note 11 Pro 5G",
+			expectedType:  "mobile",
+		},
+		{
+			name:          "Reduced Android UA",
+			userAgent:     "Mozilla/5.0 (Linux; Android 10; K) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Mobile Safari/537.36",
+			expectedBrand: "Mobile",
+			expectedModel: "Mobile Device",
+			expectedType:  "mobile",
+		},
+		{
+			name:          "Desktop Chrome",
+			userAgent:     "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36",
+			expectedBrand: "Desktop",
+			expectedModel: "Desktop Device",
+			expectedType:  "desktop",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result := user_agent.ParseUserAgent(tc.userAgent)
+
+			if result.Device != tc.expectedBrand {
+				t.Errorf("Expected brand %s, got %s", tc.expectedBrand, result.Device)
+			}
+			if result.Model != tc.expectedModel {
+				t.Errorf("Expected model %s, got %s", tc.expectedModel, result.Model)
+			}
+
+			deviceType := "unknown"
+			switch {
+			case result.Mobile:
+				deviceType = "mobile"
+			case result.Tablet:
+				deviceType = "tablet"
+			case result.Desktop:
+				deviceType = "desktop"
+			}
+			if deviceType != tc.expectedType {
+				t.Errorf("Expected device type %s, got %s", tc.expectedType, deviceType)
+			}
+		})
+	}
+}
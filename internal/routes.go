@@ -36,6 +36,12 @@ func MountAppRoutes(srv *cartridge.Server) {
 	})
 	srv.SetSession(sessionMgr)
 
+	// Record request durations for /metrics; must run before any route is mounted
+	if cfg.MetricsEnabled {
+		srv.App().Use(middleware.RequestMetrics())
+	}
+	srv.App().Use(middleware.RequestLogger(srv.GetLogger()))
+
 	// ============================================
 	// PUBLIC ENDPOINT PROTECTION
 	// All public endpoints get the following protection:
@@ -122,9 +128,16 @@ func MountAppRoutes(srv *cartridge.Server) {
 	// === ROOT ROUTES ===
 	srv.Get("/", http.HomeIndexAction)
 
-	// Health check endpoint
+	// Liveness (health) and readiness endpoints
 	srv.Get("/_health", http.HealthIndexAction)
 	srv.Head("/_health", http.HealthIndexAction)
+	srv.Get("/_ready", http.ReadyIndexAction)
+	srv.Head("/_ready", http.ReadyIndexAction)
+
+	// Prometheus scrape endpoint (opt-in via FUSIONALY_METRICS_ENABLED)
+	if cfg.MetricsEnabled {
+		srv.Get("/metrics", http.MetricsIndexAction)
+	}
 
 	srv.Get("/_demo", http.DemoIndexAction)
 
@@ -134,12 +147,17 @@ func MountAppRoutes(srv *cartridge.Server) {
 		CustomMiddleware: []fiber.Handler{publicRateLimiter},
 	}
 	srv.Get("/share/:token", http.PublicDashboardAction, publicDashboardConfig)
+	srv.Post("/share/:token/unlock", http.SharedLinkUnlockAction, publicDashboardConfig)
 
 	// === PUBLIC API ROUTES ===
 	srv.Post("/x/api/v1/events", v1.CreateEventPublicAPIHandler, publicAPIConfig)
 	srv.Options("/x/api/v1/events", func(ctx *cartridge.Context) error {
 		return ctx.SendStatus(fiber.StatusNoContent)
 	}, publicAPIConfig)
+	srv.Post("/x/api/v1/events/batch", v1.CreateEventsBatchPublicAPIHandler, publicAPIConfig)
+	srv.Options("/x/api/v1/events/batch", func(ctx *cartridge.Context) error {
+		return ctx.SendStatus(fiber.StatusNoContent)
+	}, publicAPIConfig)
 	srv.Post("/x/api/v1/events/beacon", v1.CreateEventBeaconHandler, publicAPIConfig)
 	srv.Options("/x/api/v1/events/beacon", func(ctx *cartridge.Context) error {
 		return ctx.SendStatus(fiber.StatusNoContent)
@@ -175,6 +193,26 @@ func MountAppRoutes(srv *cartridge.Server) {
 	srv.Get("/z/api/v1/schema", http.AgentSchemaAction, agentAPIConfig)
 	srv.Post("/z/api/v1/sql", http.AgentSQLAction, agentAPIConfig)
 
+	// === STATS API ROUTES ===
+	// Dashboard metrics and deploy annotations, authenticated by per-website API tokens
+	// Rate limited: 30 req/min
+	statsRateLimiter := conditionalRateLimiter(cartridgemiddleware.RateLimiter(
+		cartridgemiddleware.WithMax(30),
+		cartridgemiddleware.WithDuration(time.Minute),
+	))
+	statsAPIConfig := &cartridge.RouteConfig{
+		EnableCORS:         true,
+		EnableSecFetchSite: cartridge.Bool(false), // Allow server-to-server callers
+		CustomMiddleware: []fiber.Handler{
+			statsRateLimiter,
+			middleware.WebsiteAPITokenAuth(db, logger),
+		},
+		CORSConfig: publicCORSConfig,
+	}
+	srv.Get("/api/v1/stats", http.StatsAPIAction, statsAPIConfig)
+	srv.Post("/api/v1/annotations", http.AnnotationsAPICreateAction, statsAPIConfig)
+	srv.Get("/api/v1/export/events", http.EventsExportAPIAction, statsAPIConfig)
+
 	// === ONBOARDING ROUTES (PRG pattern) ===
 	srv.Get("/setup", http.OnboardingPageAction, onboardingConfig)
 	srv.Get("/api/onboarding/check", http.OnboardingCheckAction, onboardingConfig)
@@ -197,13 +235,21 @@ func MountAppRoutes(srv *cartridge.Server) {
 	// list remains reachable at /admin/websites.
 	srv.Get("/admin", http.HomeFeedAction, adminConfig)
 	srv.Get("/admin/websites", http.WebsitesIndexAction, adminConfig)
+	srv.Get("/admin/overview", http.AdminOverviewAction, adminConfig)
 
 	srv.Get("/admin/websites/new", http.WebsiteNewPageAction, adminConfig)
 	srv.Post("/admin/websites", http.WebsiteCreateAction, adminConfig)
+	srv.Post("/admin/websites/bulk", http.WebsitesBulkCreateAction, adminAPIConfig)
+	srv.Post("/admin/utm-builder", http.UTMBuilderAction, adminAPIConfig)
 
 	srv.Get("/admin/websites/:id/setup", http.WebsiteSetupPageAction, adminConfig)
 	srv.Get("/admin/websites/:id/dashboard", http.WebsiteDashboardAction, adminConfig)
+	srv.Get("/admin/websites/:id/export.csv", http.WebsiteExportCSVAction, adminConfig)
 	srv.Get("/admin/websites/:id/events", http.WebsiteEventsAction, adminConfig)
+	srv.Get("/admin/websites/:id/funnel", http.WebsiteFunnelAction, adminConfig)
+	srv.Get("/admin/websites/:id/realtime", http.WebsiteRealtimeAction, adminConfig)
+	srv.Get("/admin/websites/:id/retention", http.WebsiteRetentionAction, adminConfig)
+	srv.Get("/admin/websites/:id/metric/:name", http.WebsiteMetricPageAction, adminConfig)
 	srv.Get("/admin/websites/:id/lens", http.WebsiteLensAction, adminConfig)
 	srv.Post("/admin/websites/:id/lens/ask-ai", http.WebsiteLensAskAIAction, adminConfig)
 	srv.Post("/admin/websites/:id/lens/save", http.WebsiteLensSaveAction, adminConfig)
@@ -214,14 +260,32 @@ func MountAppRoutes(srv *cartridge.Server) {
 	srv.Post("/admin/websites/:id", http.WebsiteUpdateAction, adminConfig)
 	srv.Delete("/admin/websites/:id", http.WebsiteDeleteAction, adminConfig)
 	srv.Post("/admin/websites/:id/delete", http.WebsiteDeleteAction, adminConfig)
+	srv.Post("/admin/websites/:id/pause", http.WebsitePauseAction, adminConfig)
+	srv.Post("/admin/websites/:id/resume", http.WebsiteResumeAction, adminConfig)
 
 	srv.Post("/admin/websites/:id/annotations", http.AnnotationCreateAction, adminConfig)
 	srv.Post("/admin/websites/:id/annotations/:annotationId", http.AnnotationUpdateAction, adminConfig)
+	srv.Put("/admin/websites/:id/annotations/:annotationId", http.AnnotationUpdateAction, adminConfig)
+	srv.Delete("/admin/websites/:id/annotations/:annotationId", http.AnnotationDeleteAction, adminConfig)
 	srv.Post("/admin/websites/:id/annotations/:annotationId/delete", http.AnnotationDeleteAction, adminConfig)
 
 	// Dashboard sharing
 	srv.Post("/admin/websites/:id/share/enable", http.EnableShareAction, adminConfig)
 	srv.Post("/admin/websites/:id/share/disable", http.DisableShareAction, adminConfig)
+	srv.Post("/admin/websites/:id/shared-links", http.WebsiteSharedLinkCreateAction, adminConfig)
+	srv.Post("/admin/websites/:id/shared-links/:linkId/delete", http.WebsiteSharedLinkDeleteAction, adminConfig)
+
+	// Stats API tokens
+	srv.Post("/admin/websites/:id/api-tokens", http.WebsiteAPITokenCreateAction, adminConfig)
+	srv.Post("/admin/websites/:id/api-tokens/:tokenId/revoke", http.WebsiteAPITokenRevokeAction, adminConfig)
+
+	// Webhooks
+	srv.Post("/admin/websites/:id/webhooks", http.WebsiteWebhookCreateAction, adminConfig)
+	srv.Post("/admin/websites/:id/webhooks/:webhookId/delete", http.WebsiteWebhookDeleteAction, adminConfig)
+
+	// Alert rules
+	srv.Post("/admin/websites/:id/alerts", http.WebsiteAlertRuleCreateAction, adminConfig)
+	srv.Post("/admin/websites/:id/alerts/:ruleId/delete", http.WebsiteAlertRuleDeleteAction, adminConfig)
 
 	// === ADMINISTRATION ROUTES ===
 	srv.Get("/admin/administration", http.AdministrationIndexAction, adminConfig)
@@ -234,6 +298,7 @@ func MountAppRoutes(srv *cartridge.Server) {
 	srv.Get("/admin/administration/system", http.AdministrationSystemPageAction, adminConfig)
 
 	srv.Post("/admin/account/change-password", http.AccountChangePasswordFormAction, adminConfig)
+	srv.Post("/admin/account/weekly-digest", http.AccountWeeklyDigestFormAction, adminConfig)
 
 	// === SYSTEM API ROUTES ===
 	srv.Get("/admin/api/system/export-database", http.SystemExportDatabaseAction, adminAPIConfig)
@@ -0,0 +1,371 @@
+package settings
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// Ingestion setting keys
+const (
+	KeyRawEventSampleRate     = "raw_event_sample_rate"
+	KeyUseSDKUserID           = "use_sdk_user_id"
+	KeyFilterBots             = "filter_bots"
+	KeyBotPatterns            = "bot_patterns"
+	KeyFilterRefSpam          = "filter_referrer_spam"
+	KeyRefSpamDomains         = "referrer_spam_domains"
+	KeySelfReferrals          = "separate_self_referrals"
+	KeyNormalizeUTMCase       = "normalize_utm_case"
+	KeyExcludedPaths          = "excluded_paths"
+	KeyPathPatterns           = "path_patterns"
+	KeyPathCaseInsensitive    = "path_case_insensitive"
+	KeyPathStripTrailingSlash = "path_strip_trailing_slash"
+	KeyTrackedQueryParams     = "tracked_query_params"
+	KeyRespectDNT             = "respect_dnt"
+	KeyDedupeWindow           = "dedupe_window_minutes"
+	KeyMaxEventMetaBytes      = "max_event_meta_bytes"
+	KeyTruncateEventMeta      = "truncate_event_meta"
+	KeyMaxTimestampSkew       = "max_timestamp_skew"
+	KeyMaxTimestampAge        = "max_timestamp_age_days"
+)
+
+// DefaultDedupeWindowMinutes is how long an event ID suppresses resubmissions.
+const DefaultDedupeWindowMinutes = 60
+
+// DefaultMaxEventMetaBytes is the largest custom event metadata accepted.
+const DefaultMaxEventMetaBytes = 8192
+
+// DefaultMaxTimestampSkewMinutes is how far in the future an event timestamp may be.
+const DefaultMaxTimestampSkewMinutes = 60
+
+// GetRawEventSampleRate returns the fraction (0-1) of raw page view events kept
+// after aggregation. Defaults to 1 (keep everything) when unset or invalid.
+func GetRawEventSampleRate(db *gorm.DB) float64 {
+	value, err := GetSetting(db, KeyRawEventSampleRate)
+	if err != nil || value == "" {
+		return 1
+	}
+	rate, err := strconv.ParseFloat(value, 64)
+	if err != nil || rate < 0 || rate > 1 {
+		return 1
+	}
+	return rate
+}
+
+// SaveRawEventSampleRate stores the raw event sample rate.
+func SaveRawEventSampleRate(db *gorm.DB, rate float64) error {
+	if rate < 0 || rate > 1 {
+		return fmt.Errorf("raw event sample rate must be between 0 and 1")
+	}
+	return CreateOrUpdateSetting(db, KeyRawEventSampleRate, strconv.FormatFloat(rate, 'f', -1, 64))
+}
+
+// IsSDKUserIDEnabled reports whether a user ID sent by the SDK replaces the
+// computed visitor signature. Off by default for privacy.
+func IsSDKUserIDEnabled(db *gorm.DB) bool {
+	value, err := GetSetting(db, KeyUseSDKUserID)
+	return err == nil && value == "true"
+}
+
+// SaveSDKUserIDEnabled toggles merging visitors by SDK-provided user ID.
+func SaveSDKUserIDEnabled(db *gorm.DB, enabled bool) error {
+	return CreateOrUpdateSetting(db, KeyUseSDKUserID, strconv.FormatBool(enabled))
+}
+
+// IsBotFilteringEnabled reports whether known bots are dropped at collection.
+// On by default; bots never count towards aggregates either way.
+func IsBotFilteringEnabled(db *gorm.DB) bool {
+	value, err := GetSetting(db, KeyFilterBots)
+	return err != nil || value != "false"
+}
+
+// SaveBotFilteringEnabled toggles dropping bot traffic at collection.
+func SaveBotFilteringEnabled(db *gorm.DB, enabled bool) error {
+	return CreateOrUpdateSetting(db, KeyFilterBots, strconv.FormatBool(enabled))
+}
+
+// GetBotPatterns returns the user-defined bot patterns, one per line, that
+// extend the embedded bot ruleset.
+func GetBotPatterns(db *gorm.DB) []string {
+	value, err := GetSetting(db, KeyBotPatterns)
+	if err != nil {
+		return nil
+	}
+
+	var patterns []string
+	for _, line := range strings.Split(value, "\n") {
+		if pattern := strings.TrimSpace(line); pattern != "" {
+			patterns = append(patterns, pattern)
+		}
+	}
+	return patterns
+}
+
+// SaveBotPatterns stores user-defined bot patterns (newline separated).
+func SaveBotPatterns(db *gorm.DB, patterns string) error {
+	return CreateOrUpdateSetting(db, KeyBotPatterns, strings.TrimSpace(patterns))
+}
+
+// IsReferrerSpamFilteringEnabled reports whether referrers on the spam
+// blocklist are recorded as direct traffic. On by default.
+func IsReferrerSpamFilteringEnabled(db *gorm.DB) bool {
+	value, err := GetSetting(db, KeyFilterRefSpam)
+	return err != nil || value != "false"
+}
+
+// SaveReferrerSpamFilteringEnabled toggles the referrer-spam blocklist.
+func SaveReferrerSpamFilteringEnabled(db *gorm.DB, enabled bool) error {
+	return CreateOrUpdateSetting(db, KeyFilterRefSpam, strconv.FormatBool(enabled))
+}
+
+// IsSelfReferralSeparationEnabled reports whether referrers on the website's
+// own domain are recorded as self-referrals instead of direct traffic. Off by
+// default.
+func IsSelfReferralSeparationEnabled(db *gorm.DB) bool {
+	value, err := GetSetting(db, KeySelfReferrals)
+	return err == nil && value == "true"
+}
+
+// SaveSelfReferralSeparationEnabled toggles recording self-referrals apart from direct traffic.
+func SaveSelfReferralSeparationEnabled(db *gorm.DB, enabled bool) error {
+	return CreateOrUpdateSetting(db, KeySelfReferrals, strconv.FormatBool(enabled))
+}
+
+// IsUTMCaseNormalizationEnabled reports whether UTM values are trimmed and
+// lowercased during processing, so "Google" and "google" are one source. On
+// by default.
+func IsUTMCaseNormalizationEnabled(db *gorm.DB) bool {
+	value, err := GetSetting(db, KeyNormalizeUTMCase)
+	return err != nil || value != "false"
+}
+
+// SaveUTMCaseNormalizationEnabled toggles UTM value normalization.
+func SaveUTMCaseNormalizationEnabled(db *gorm.DB, enabled bool) error {
+	return CreateOrUpdateSetting(db, KeyNormalizeUTMCase, strconv.FormatBool(enabled))
+}
+
+// GetReferrerSpamDomains returns the user-defined spam domains, one per line,
+// that extend the embedded referrer-spam blocklist.
+func GetReferrerSpamDomains(db *gorm.DB) []string {
+	value, err := GetSetting(db, KeyRefSpamDomains)
+	if err != nil {
+		return nil
+	}
+
+	var domains []string
+	for _, line := range strings.Split(value, "\n") {
+		if domain := strings.TrimSpace(line); domain != "" {
+			domains = append(domains, domain)
+		}
+	}
+	return domains
+}
+
+// SaveReferrerSpamDomains stores user-defined spam domains (newline separated).
+func SaveReferrerSpamDomains(db *gorm.DB, domains string) error {
+	return CreateOrUpdateSetting(db, KeyRefSpamDomains, strings.TrimSpace(domains))
+}
+
+// GetExcludedPaths returns the comma-separated path patterns that are not tracked.
+func GetExcludedPaths(db *gorm.DB) []string {
+	value, err := GetSetting(db, KeyExcludedPaths)
+	if err != nil || value == "" {
+		return nil
+	}
+
+	var patterns []string
+	for _, pattern := range strings.Split(value, ",") {
+		if pattern = strings.TrimSpace(pattern); pattern != "" {
+			patterns = append(patterns, pattern)
+		}
+	}
+	return patterns
+}
+
+// SaveExcludedPaths stores the comma-separated path patterns that are not tracked.
+func SaveExcludedPaths(db *gorm.DB, patterns string) error {
+	return CreateOrUpdateSetting(db, KeyExcludedPaths, strings.TrimSpace(patterns))
+}
+
+// IsPathCaseInsensitive reports whether pathnames are lowercased on collection,
+// so "/About" and "/about" are one page. Off by default.
+func IsPathCaseInsensitive(db *gorm.DB) bool {
+	value, err := GetSetting(db, KeyPathCaseInsensitive)
+	return err == nil && value == "true"
+}
+
+// SavePathCaseInsensitive toggles lowercasing pathnames.
+func SavePathCaseInsensitive(db *gorm.DB, enabled bool) error {
+	return CreateOrUpdateSetting(db, KeyPathCaseInsensitive, strconv.FormatBool(enabled))
+}
+
+// IsTrailingSlashStripEnabled reports whether trailing slashes are dropped from
+// pathnames on collection, so "/about/" and "/about" are one page. Off by default.
+func IsTrailingSlashStripEnabled(db *gorm.DB) bool {
+	value, err := GetSetting(db, KeyPathStripTrailingSlash)
+	return err == nil && value == "true"
+}
+
+// SaveTrailingSlashStripEnabled toggles stripping trailing slashes from pathnames.
+func SaveTrailingSlashStripEnabled(db *gorm.DB, enabled bool) error {
+	return CreateOrUpdateSetting(db, KeyPathStripTrailingSlash, strconv.FormatBool(enabled))
+}
+
+// GetPathPatterns returns the pathname rewrite rules ("regex => replacement"),
+// one per line. Parsing is left to the events package.
+func GetPathPatterns(db *gorm.DB) []string {
+	value, err := GetSetting(db, KeyPathPatterns)
+	if err != nil || value == "" {
+		return nil
+	}
+
+	var rules []string
+	for _, line := range strings.Split(value, "\n") {
+		if rule := strings.TrimSpace(line); rule != "" {
+			rules = append(rules, rule)
+		}
+	}
+	return rules
+}
+
+// SavePathPatterns stores the pathname rewrite rules (newline separated).
+func SavePathPatterns(db *gorm.DB, rules string) error {
+	return CreateOrUpdateSetting(db, KeyPathPatterns, strings.TrimSpace(rules))
+}
+
+// GetTrackedQueryParams returns the query parameters kept on collected URLs.
+// An empty list keeps every parameter.
+func GetTrackedQueryParams(db *gorm.DB) []string {
+	value, err := GetSetting(db, KeyTrackedQueryParams)
+	if err != nil || value == "" {
+		return nil
+	}
+
+	var params []string
+	for _, param := range strings.Split(value, ",") {
+		if param = strings.TrimSpace(param); param != "" {
+			params = append(params, param)
+		}
+	}
+	return params
+}
+
+// SaveTrackedQueryParams stores the comma-separated query parameter allowlist.
+func SaveTrackedQueryParams(db *gorm.DB, params string) error {
+	return CreateOrUpdateSetting(db, KeyTrackedQueryParams, strings.TrimSpace(params))
+}
+
+// IsDoNotTrackRespected reports whether requests sending DNT: 1 are dropped.
+func IsDoNotTrackRespected(db *gorm.DB) bool {
+	value, err := GetSetting(db, KeyRespectDNT)
+	return err == nil && value == "true"
+}
+
+// SaveDoNotTrackRespected toggles honoring the Do Not Track header.
+func SaveDoNotTrackRespected(db *gorm.DB, enabled bool) error {
+	return CreateOrUpdateSetting(db, KeyRespectDNT, strconv.FormatBool(enabled))
+}
+
+// GetDedupeWindowMinutes returns how long a client event ID suppresses
+// duplicates. 0 disables deduplication.
+func GetDedupeWindowMinutes(db *gorm.DB) int {
+	value, err := GetSetting(db, KeyDedupeWindow)
+	if err != nil || value == "" {
+		return DefaultDedupeWindowMinutes
+	}
+	minutes, err := strconv.Atoi(value)
+	if err != nil || minutes < 0 {
+		return DefaultDedupeWindowMinutes
+	}
+	return minutes
+}
+
+// SaveDedupeWindowMinutes stores the event ID dedupe window.
+func SaveDedupeWindowMinutes(db *gorm.DB, minutes int) error {
+	if minutes < 0 {
+		return fmt.Errorf("dedupe window must not be negative")
+	}
+	return CreateOrUpdateSetting(db, KeyDedupeWindow, strconv.Itoa(minutes))
+}
+
+// GetMaxEventMetaBytes returns the largest custom event metadata, in bytes of
+// JSON, accepted at ingestion. 0 disables the limit.
+func GetMaxEventMetaBytes(db *gorm.DB) int {
+	value, err := GetSetting(db, KeyMaxEventMetaBytes)
+	if err != nil || value == "" {
+		return DefaultMaxEventMetaBytes
+	}
+	maxBytes, err := strconv.Atoi(value)
+	if err != nil || maxBytes < 0 {
+		return DefaultMaxEventMetaBytes
+	}
+	return maxBytes
+}
+
+// SaveMaxEventMetaBytes stores the custom event metadata size limit.
+func SaveMaxEventMetaBytes(db *gorm.DB, maxBytes int) error {
+	if maxBytes < 0 {
+		return fmt.Errorf("metadata size limit must not be negative")
+	}
+	return CreateOrUpdateSetting(db, KeyMaxEventMetaBytes, strconv.Itoa(maxBytes))
+}
+
+// IsEventMetaTruncationEnabled reports whether oversized metadata is trimmed
+// to fit the limit instead of rejecting the event.
+func IsEventMetaTruncationEnabled(db *gorm.DB) bool {
+	value, err := GetSetting(db, KeyTruncateEventMeta)
+	return err == nil && value == "true"
+}
+
+// SaveEventMetaTruncationEnabled toggles trimming oversized metadata.
+func SaveEventMetaTruncationEnabled(db *gorm.DB, enabled bool) error {
+	return CreateOrUpdateSetting(db, KeyTruncateEventMeta, strconv.FormatBool(enabled))
+}
+
+// GetMaxTimestampSkewMinutes returns how many minutes ahead of the server
+// clock an event timestamp may be before the event is rejected. 0 disables
+// the check.
+func GetMaxTimestampSkewMinutes(db *gorm.DB) int {
+	value, err := GetSetting(db, KeyMaxTimestampSkew)
+	if err != nil || value == "" {
+		return DefaultMaxTimestampSkewMinutes
+	}
+	minutes, err := strconv.Atoi(value)
+	if err != nil || minutes < 0 {
+		return DefaultMaxTimestampSkewMinutes
+	}
+	return minutes
+}
+
+// SaveMaxTimestampSkewMinutes stores the allowed future timestamp skew.
+func SaveMaxTimestampSkewMinutes(db *gorm.DB, minutes int) error {
+	if minutes < 0 {
+		return fmt.Errorf("timestamp skew must not be negative")
+	}
+	return CreateOrUpdateSetting(db, KeyMaxTimestampSkew, strconv.Itoa(minutes))
+}
+
+// GetMaxTimestampAgeDays returns how many days in the past an event timestamp
+// may be before the event is rejected. 0, the default, accepts any age so
+// imports of old history keep working.
+func GetMaxTimestampAgeDays(db *gorm.DB) int {
+	value, err := GetSetting(db, KeyMaxTimestampAge)
+	if err != nil || value == "" {
+		return 0
+	}
+	days, err := strconv.Atoi(value)
+	if err != nil || days < 0 {
+		return 0
+	}
+	return days
+}
+
+// SaveMaxTimestampAgeDays stores the window for backdated event timestamps.
+func SaveMaxTimestampAgeDays(db *gorm.DB, days int) error {
+	if days < 0 {
+		return fmt.Errorf("timestamp age must not be negative")
+	}
+	return CreateOrUpdateSetting(db, KeyMaxTimestampAge, strconv.Itoa(days))
+}
@@ -0,0 +1,78 @@
+package settings
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"gorm.io/gorm"
+)
+
+// KeySessionTimeoutMinutes stores per-website session timeouts as a JSON map of
+// website ID to minutes. Websites without an entry use the global default.
+const KeySessionTimeoutMinutes = "session_timeout_minutes"
+
+// Allowed range for a per-website session timeout
+const (
+	MinSessionTimeoutMinutes = 1
+	MaxSessionTimeoutMinutes = 360
+)
+
+func getSessionTimeouts(db *gorm.DB) map[string]int {
+	value, err := GetSetting(db, KeySessionTimeoutMinutes)
+	if err != nil || value == "" {
+		return map[string]int{}
+	}
+
+	var timeouts map[string]int
+	if err := json.Unmarshal([]byte(value), &timeouts); err != nil || timeouts == nil {
+		return map[string]int{}
+	}
+	return timeouts
+}
+
+// GetSessionTimeoutMinutes returns the session timeout configured for a website,
+// or 0 when the website uses the global default.
+func GetSessionTimeoutMinutes(db *gorm.DB, websiteID uint) int {
+	minutes := getSessionTimeouts(db)[strconv.FormatUint(uint64(websiteID), 10)]
+	if minutes < MinSessionTimeoutMinutes || minutes > MaxSessionTimeoutMinutes {
+		return 0
+	}
+	return minutes
+}
+
+// GetSessionTimeouts returns every website's session timeout override in
+// minutes, keyed by website ID. Websites using the global default are absent.
+func GetSessionTimeouts(db *gorm.DB) map[uint]int {
+	timeouts := make(map[uint]int)
+	for key, minutes := range getSessionTimeouts(db) {
+		websiteID, err := strconv.ParseUint(key, 10, 64)
+		if err != nil || minutes < MinSessionTimeoutMinutes || minutes > MaxSessionTimeoutMinutes {
+			continue
+		}
+		timeouts[uint(websiteID)] = minutes
+	}
+	return timeouts
+}
+
+// SaveSessionTimeoutMinutes sets the session timeout for a website. Passing 0
+// removes the override so the global default applies again.
+func SaveSessionTimeoutMinutes(db *gorm.DB, websiteID uint, minutes int) error {
+	if minutes != 0 && (minutes < MinSessionTimeoutMinutes || minutes > MaxSessionTimeoutMinutes) {
+		return fmt.Errorf("session timeout must be between %d and %d minutes", MinSessionTimeoutMinutes, MaxSessionTimeoutMinutes)
+	}
+
+	timeouts := getSessionTimeouts(db)
+	key := strconv.FormatUint(uint64(websiteID), 10)
+	if minutes == 0 {
+		delete(timeouts, key)
+	} else {
+		timeouts[key] = minutes
+	}
+
+	value, err := json.Marshal(timeouts)
+	if err != nil {
+		return fmt.Errorf("failed to marshal session timeouts: %w", err)
+	}
+	return CreateOrUpdateSetting(db, KeySessionTimeoutMinutes, string(value))
+}
@@ -6,10 +6,11 @@ import (
 	"encoding/json"
 	"fmt"
 	"math/big"
-	"net"
+	"net/netip"
 	"strconv"
 	"strings"
 	"time"
+	"unicode"
 
 	"log/slog"
 
@@ -29,6 +30,24 @@ type Setting struct {
 
 var excludedIPsCache *cache.Cache[string, []string]
 
+// changeHooks clear caches derived from settings in other packages
+var changeHooks []func()
+
+// OnChange registers a function that drops a cache derived from settings. It
+// runs after every setting write so the next read sees the new values.
+// Register hooks from package init functions.
+func OnChange(hook func()) {
+	changeHooks = append(changeHooks, hook)
+}
+
+// NotifyChanged runs the OnChange hooks. Setting writes call it; use it after
+// changing the settings table directly.
+func NotifyChanged() {
+	for _, hook := range changeHooks {
+		hook()
+	}
+}
+
 // SetupDefaultSettings initializes default settings in the database
 func SetupDefaultSettings(dbConn *gorm.DB) error {
 	settings := []Setting{
@@ -36,6 +55,24 @@ func SetupDefaultSettings(dbConn *gorm.DB) error {
 		{Key: "subdomain_tracking", Value: "{}"},
 		{Key: "website_goals", Value: "{\"goals\":{}}"},
 		{Key: KeyOpenAIKey, Value: ""},
+		{Key: KeyRawEventSampleRate, Value: "1"},
+		{Key: KeyUseSDKUserID, Value: "false"},
+		{Key: KeySessionTimeoutMinutes, Value: "{}"},
+		{Key: KeyFilterBots, Value: "true"},
+		{Key: KeyBotPatterns, Value: ""},
+		{Key: KeyExcludedPaths, Value: ""},
+		{Key: KeyPathPatterns, Value: ""},
+		{Key: KeyPathCaseInsensitive, Value: "false"},
+		{Key: KeyPathStripTrailingSlash, Value: "false"},
+		{Key: KeyTrackedQueryParams, Value: ""},
+		{Key: KeyRespectDNT, Value: "false"},
+		{Key: KeySelfReferrals, Value: "false"},
+		{Key: KeyNormalizeUTMCase, Value: "true"},
+		{Key: KeyDedupeWindow, Value: strconv.Itoa(DefaultDedupeWindowMinutes)},
+		{Key: KeyMaxEventMetaBytes, Value: strconv.Itoa(DefaultMaxEventMetaBytes)},
+		{Key: KeyTruncateEventMeta, Value: "false"},
+		{Key: KeyMaxTimestampSkew, Value: strconv.Itoa(DefaultMaxTimestampSkewMinutes)},
+		{Key: KeyMaxTimestampAge, Value: "0"},
 	}
 	err := sqlite.PerformWrite(slog.Default(), dbConn, func(tx *gorm.DB) error {
 		for _, setting := range settings {
@@ -55,10 +92,14 @@ func SetupDefaultSettings(dbConn *gorm.DB) error {
 
 	// Initialize the cache
 	loadCache(dbConn, slog.Default())
+	NotifyChanged()
 
 	return err
 }
 
+// IsIPExcluded reports whether ip matches an excluded_ips entry. Entries are
+// exact addresses or CIDR ranges, IPv4 or IPv6. Addresses are compared
+// parsed, so IPv6 notation differences and IPv4-mapped IPv6 clients match.
 func IsIPExcluded(ip string) (bool, error) {
 	if excludedIPsCache == nil {
 		return false, nil
@@ -69,7 +110,10 @@ func IsIPExcluded(ip string) (bool, error) {
 		return false, fmt.Errorf("failed to check excluded IPs: %w", err)
 	}
 
-	parsedIP := net.ParseIP(ip)
+	addr, err := netip.ParseAddr(ip)
+	if err == nil {
+		addr = addr.Unmap().WithZone("")
+	}
 
 	for _, entry := range excludedIPs {
 		if entry == "" {
@@ -78,11 +122,11 @@ func IsIPExcluded(ip string) (bool, error) {
 
 		if strings.Contains(entry, "/") {
 			// CIDR range match
-			_, cidr, err := net.ParseCIDR(entry)
+			prefix, err := netip.ParsePrefix(entry)
 			if err != nil {
 				continue
 			}
-			if parsedIP != nil && cidr.Contains(parsedIP) {
+			if addr.IsValid() && prefix.Contains(addr) {
 				return true, nil
 			}
 		} else {
@@ -90,6 +134,9 @@ func IsIPExcluded(ip string) (bool, error) {
 			if entry == ip {
 				return true, nil
 			}
+			if excluded, err := netip.ParseAddr(entry); err == nil && addr.IsValid() && excluded.Unmap() == addr {
+				return true, nil
+			}
 		}
 	}
 	return false, nil
@@ -149,6 +196,7 @@ func UpdateSetting(dbConn *gorm.DB, key string, value string) error {
 	// Clear and reload the cache after successful update
 	excludedIPsCache.Clear()
 	loadCache(dbConn, slog.Default())
+	NotifyChanged()
 
 	return nil
 }
@@ -172,6 +220,7 @@ func CreateOrUpdateSetting(dbConn *gorm.DB, key string, value string) error {
 		if err := dbConn.Create(&setting).Error; err != nil {
 			return fmt.Errorf("failed to create setting: %w", err)
 		}
+		NotifyChanged()
 		return nil
 	}
 }
@@ -250,6 +299,47 @@ func UpdateSubdomainTrackingSettings(dbConn *gorm.DB, domain string, enabled boo
 	return UpdateSetting(dbConn, "subdomain_tracking", string(settingsJSON))
 }
 
+// KeySubdomainRollup stores, per base domain, whether subdomain events are
+// stored under the base domain's hostname instead of their own.
+const KeySubdomainRollup = "subdomain_rollup"
+
+// GetSubdomainRollupSettings retrieves subdomain rollup settings from the database
+func GetSubdomainRollupSettings(dbConn *gorm.DB) map[string]bool {
+	settingsJSON, err := GetSetting(dbConn, KeySubdomainRollup)
+	if err != nil || settingsJSON == "" {
+		return map[string]bool{}
+	}
+
+	var settings map[string]bool
+	if err := json.Unmarshal([]byte(settingsJSON), &settings); err != nil || settings == nil {
+		return map[string]bool{}
+	}
+	return settings
+}
+
+// IsSubdomainRollupEnabled reports whether events from subdomains of a base
+// domain are stored under the base domain, so pages of all subdomains
+// aggregate together. It only applies while subdomain tracking is enabled.
+func IsSubdomainRollupEnabled(dbConn *gorm.DB, domain string) bool {
+	return IsSubdomainTrackingEnabled(dbConn, domain) && GetSubdomainRollupSettings(dbConn)[domain]
+}
+
+// UpdateSubdomainRollupSettings turns subdomain rollup on or off for a base domain
+func UpdateSubdomainRollupSettings(dbConn *gorm.DB, domain string, enabled bool) error {
+	settings := GetSubdomainRollupSettings(dbConn)
+	if enabled {
+		settings[domain] = true
+	} else {
+		delete(settings, domain)
+	}
+
+	settingsJSON, err := json.Marshal(settings)
+	if err != nil {
+		return fmt.Errorf("failed to marshal subdomain rollup settings: %w", err)
+	}
+	return CreateOrUpdateSetting(dbConn, KeySubdomainRollup, string(settingsJSON))
+}
+
 // WebsiteGoals represents the structure for storing conversion goals per website
 type WebsiteGoals struct {
 	Goals map[string][]string `json:"goals"` // Map of website ID (as string) to goals array
@@ -296,6 +386,34 @@ func GetWebsiteGoals(db *gorm.DB, websiteID uint) ([]string, error) {
 	return []string{}, nil
 }
 
+// maxGoalNameLength matches the longest custom event name worth matching on
+const maxGoalNameLength = 255
+
+// ValidateWebsiteGoals checks goals are usable event names: non-empty, at most
+// maxGoalNameLength characters, free of control characters and not repeated.
+func ValidateWebsiteGoals(goals []string) error {
+	seen := make(map[string]bool, len(goals))
+	for _, goal := range goals {
+		name := strings.TrimSpace(goal)
+		if name == "" {
+			return fmt.Errorf("goal event names cannot be empty")
+		}
+		if len(name) > maxGoalNameLength {
+			return fmt.Errorf("goal %q is longer than %d characters", name, maxGoalNameLength)
+		}
+		for _, r := range name {
+			if unicode.IsControl(r) {
+				return fmt.Errorf("goal %q contains control characters", name)
+			}
+		}
+		if seen[name] {
+			return fmt.Errorf("goal %q is listed more than once", name)
+		}
+		seen[name] = true
+	}
+	return nil
+}
+
 // SaveWebsiteGoals saves conversion goals for a specific website
 func SaveWebsiteGoals(db *gorm.DB, websiteID uint, goals []string) error {
 	websiteGoalsJSON, err := GetSetting(db, "website_goals")
@@ -72,6 +72,50 @@ func TestIsIPExcluded(t *testing.T) {
 		assert.False(t, excluded)
 	})
 
+	t.Run("matches IP within IPv6 CIDR range", func(t *testing.T) {
+		setupTestIPCache([]string{"2001:db8::/32"})
+
+		excluded, err := IsIPExcluded("2001:db8:1234::1")
+		assert.NoError(t, err)
+		assert.True(t, excluded)
+
+		excluded, err = IsIPExcluded("2001:db9::1")
+		assert.NoError(t, err)
+		assert.False(t, excluded)
+
+		excluded, err = IsIPExcluded("192.168.1.1")
+		assert.NoError(t, err)
+		assert.False(t, excluded)
+	})
+
+	t.Run("matches IPv6 addresses regardless of notation", func(t *testing.T) {
+		setupTestIPCache([]string{"2001:DB8:0:0:0:0:0:1"})
+
+		excluded, err := IsIPExcluded("2001:db8::1")
+		assert.NoError(t, err)
+		assert.True(t, excluded)
+
+		excluded, err = IsIPExcluded("2001:db8::2")
+		assert.NoError(t, err)
+		assert.False(t, excluded)
+	})
+
+	t.Run("matches IPv4-mapped IPv6 clients against IPv4 entries", func(t *testing.T) {
+		setupTestIPCache([]string{"10.0.0.1", "192.168.1.0/24"})
+
+		excluded, err := IsIPExcluded("::ffff:10.0.0.1")
+		assert.NoError(t, err)
+		assert.True(t, excluded)
+
+		excluded, err = IsIPExcluded("::ffff:192.168.1.77")
+		assert.NoError(t, err)
+		assert.True(t, excluded)
+
+		excluded, err = IsIPExcluded("::ffff:192.168.2.1")
+		assert.NoError(t, err)
+		assert.False(t, excluded)
+	})
+
 	t.Run("skips empty entries", func(t *testing.T) {
 		setupTestIPCache([]string{"", "10.0.0.1", ""})
 
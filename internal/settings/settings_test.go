@@ -1,6 +1,7 @@
 package settings_test
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -235,3 +236,28 @@ func TestAgentAPIKey(t *testing.T) {
 		assert.Empty(t, key)
 	})
 }
+
+func TestValidateWebsiteGoals(t *testing.T) {
+	t.Run("accepts distinct event names", func(t *testing.T) {
+		assert.NoError(t, settings.ValidateWebsiteGoals([]string{"signup", "Purchase Completed", "download:pdf"}))
+		assert.NoError(t, settings.ValidateWebsiteGoals(nil))
+	})
+
+	t.Run("rejects duplicates", func(t *testing.T) {
+		err := settings.ValidateWebsiteGoals([]string{"signup", " signup "})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "more than once")
+	})
+
+	t.Run("rejects empty names", func(t *testing.T) {
+		assert.Error(t, settings.ValidateWebsiteGoals([]string{"signup", "  "}))
+	})
+
+	t.Run("rejects overly long names", func(t *testing.T) {
+		assert.Error(t, settings.ValidateWebsiteGoals([]string{strings.Repeat("a", 256)}))
+	})
+
+	t.Run("rejects control characters", func(t *testing.T) {
+		assert.Error(t, settings.ValidateWebsiteGoals([]string{"sign\nup"}))
+	})
+}
@@ -0,0 +1,88 @@
+package settings
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// KeyDashboardTrackParams stores per-website dashboard query parameter
+// breakdowns as a JSON map of website ID to parameter names.
+const KeyDashboardTrackParams = "dashboard_track_params"
+
+// MaxTrackParams bounds how many query parameters a dashboard breaks down
+const MaxTrackParams = 10
+
+func getTrackParams(db *gorm.DB) map[string][]string {
+	value, err := GetSetting(db, KeyDashboardTrackParams)
+	if err != nil || value == "" {
+		return map[string][]string{}
+	}
+
+	var params map[string][]string
+	if err := json.Unmarshal([]byte(value), &params); err != nil || params == nil {
+		return map[string][]string{}
+	}
+	return params
+}
+
+// ParseTrackParams splits a comma-separated list of query parameter names,
+// trimming blanks and dropping duplicates.
+func ParseTrackParams(value string) []string {
+	var params []string
+	seen := make(map[string]bool)
+	for _, param := range strings.Split(value, ",") {
+		param = strings.TrimSpace(param)
+		if param != "" && !seen[param] {
+			seen[param] = true
+			params = append(params, param)
+		}
+	}
+	return params
+}
+
+// GetDashboardTrackParams returns the query parameters whose values the
+// website's dashboard breaks down, besides ref, in the order they were saved.
+func GetDashboardTrackParams(db *gorm.DB, websiteID uint) []string {
+	return getTrackParams(db)[strconv.FormatUint(uint64(websiteID), 10)]
+}
+
+// GetAllDashboardTrackParams returns the dashboard query parameter breakdowns
+// of every website that has some, keyed by website ID.
+func GetAllDashboardTrackParams(db *gorm.DB) map[uint][]string {
+	all := make(map[uint][]string)
+	for key, params := range getTrackParams(db) {
+		websiteID, err := strconv.ParseUint(key, 10, 64)
+		if err != nil || len(params) == 0 {
+			continue
+		}
+		all[uint(websiteID)] = params
+	}
+	return all
+}
+
+// SaveDashboardTrackParams sets the query parameters broken down on the
+// website's dashboard. An empty list removes the setting.
+func SaveDashboardTrackParams(db *gorm.DB, websiteID uint, params []string) error {
+	params = ParseTrackParams(strings.Join(params, ","))
+	if len(params) > MaxTrackParams {
+		return fmt.Errorf("at most %d query parameters can be tracked", MaxTrackParams)
+	}
+
+	all := getTrackParams(db)
+	key := strconv.FormatUint(uint64(websiteID), 10)
+	if len(params) == 0 {
+		delete(all, key)
+	} else {
+		all[key] = params
+	}
+
+	value, err := json.Marshal(all)
+	if err != nil {
+		return fmt.Errorf("failed to marshal dashboard track params: %w", err)
+	}
+	return CreateOrUpdateSetting(db, KeyDashboardTrackParams, string(value))
+}
@@ -2,7 +2,6 @@ package testsupport
 
 import (
 	"fmt"
-	"io"
 	"log/slog"
 	"math/rand"
 	"net/http/httptest"
@@ -26,6 +25,7 @@ import (
 
 	"fusionaly/internal"
 	"fusionaly/internal/ai"
+	"fusionaly/internal/alerts"
 	"fusionaly/internal/analytics"
 	"fusionaly/internal/annotations"
 	"fusionaly/internal/config"
@@ -83,19 +83,32 @@ func allModels() []any {
 		&cache.CacheRecord{},
 		&events.Event{},
 		&events.IngestedEvent{},
+		&events.FailedEvent{},
 		&users.User{},
 		&settings.Setting{},
 		&websites.Website{},
+		&websites.APIToken{},
+		&websites.Webhook{},
+		&websites.SharedLink{},
+		&alerts.Rule{},
 		&analytics.SiteStat{},
 		&analytics.PageStat{},
 		&analytics.RefStat{},
 		&analytics.BrowserStat{},
+		&analytics.BrowserVersionStat{},
 		&analytics.OSStat{},
 		&analytics.DeviceStat{},
+		&analytics.DeviceModelStat{},
 		&analytics.CountryStat{},
+		&analytics.LanguageStat{},
+		&analytics.ScreenStat{},
+		&analytics.RegionStat{},
+		&analytics.CityStat{},
 		&analytics.UTMStat{},
 		&analytics.EventStat{},
 		&analytics.QueryParamStat{},
+		&analytics.DownloadStat{},
+		&analytics.ScrollStat{},
 		&analytics.FlowTransitionStat{},
 		&onboarding.OnboardingSession{},
 		&annotations.Annotation{},
@@ -217,6 +230,9 @@ func CleanAllTables(db *gorm.DB) {
 		}
 		return nil
 	})
+
+	// Drop caches still holding the deleted settings
+	settings.NotifyChanged()
 }
 
 // CleanTables cleans specific tables or all tables if none specified
@@ -236,14 +252,15 @@ func CleanTables(db *gorm.DB, tables []string) {
 		}
 		return nil
 	})
+	settings.NotifyChanged()
 }
 
 // CleanAllAggregates cleans all aggregate tables
 func CleanAllAggregates(db *gorm.DB) {
 	CleanTables(db, []string{
-		"site_stats", "page_stats", "ref_stats", "device_stats",
-		"browser_stats", "os_stats", "country_stats", "utm_stats",
-		"event_stats", "flow_transition_stats",
+		"site_stats", "page_stats", "ref_stats", "device_stats", "device_model_stats",
+		"browser_stats", "browser_version_stats", "os_stats", "country_stats", "language_stats", "screen_stats", "region_stats", "city_stats", "utm_stats",
+		"event_stats", "download_stats", "scroll_stats", "flow_transition_stats",
 	})
 }
 
@@ -448,49 +465,25 @@ func ExtractCSRFToken(body string) string {
 	return ""
 }
 
-// LoginTestUser simulates login and returns session cookie, CSRF token, and CSRF cookie
-func LoginTestUser(t *testing.T, app *fiber.App, email, password string) (string, string, string) {
+// LoginTestUser simulates login and returns the session cookie value
+func LoginTestUser(t *testing.T, app *fiber.App, email, password string) string {
 	t.Helper()
 
-	// GET /login for CSRF token
-	req := httptest.NewRequest("GET", "/login", nil)
-	req.Header.Set("User-Agent", "Mozilla/5.0 Test Browser")
-	resp, err := app.Test(req)
-	require.NoError(t, err)
-	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
-
-	body, _ := io.ReadAll(resp.Body)
-	csrfToken := ExtractCSRFToken(string(body))
-
-	var csrfCookie string
-	for _, cookie := range resp.Cookies() {
-		if cookie.Name == "csrf_" {
-			if csrfToken == "" {
-				csrfToken = cookie.Value
-			}
-			csrfCookie = fmt.Sprintf("%s=%s", cookie.Name, cookie.Value)
-			break
-		}
-	}
-	require.NotEmpty(t, csrfToken)
-	require.NotEmpty(t, csrfCookie)
-
 	// POST /login
 	loginData := url.Values{}
 	loginData.Add("email", email)
 	loginData.Add("password", password)
-	loginData.Add("_csrf", csrfToken)
 	loginData.Add("_tz", "UTC")
 
-	req = httptest.NewRequest("POST", "/login", strings.NewReader(loginData.Encode()))
+	req := httptest.NewRequest("POST", "/login", strings.NewReader(loginData.Encode()))
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 	req.Header.Set("User-Agent", "Mozilla/5.0 Test Browser")
-	req.Header.Set("Cookie", csrfCookie)
+	req.Header.Set("Sec-Fetch-Site", "same-origin")
 
-	resp, err = app.Test(req)
+	resp, err := app.Test(req)
 	require.NoError(t, err)
 	assert.Equal(t, fiber.StatusFound, resp.StatusCode)
-	assert.Equal(t, "/admin/dashboard", resp.Header.Get("Location"))
+	assert.Equal(t, "/admin", resp.Header.Get("Location"))
 
 	var sessionValue string
 	for _, cookie := range resp.Cookies() {
@@ -501,7 +494,7 @@ func LoginTestUser(t *testing.T, app *fiber.App, email, password string) (string
 	}
 	require.NotEmpty(t, sessionValue)
 
-	return sessionValue, csrfToken, csrfCookie
+	return sessionValue
 }
 
 // ============ Test Case Framework ============
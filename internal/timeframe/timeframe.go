@@ -15,11 +15,13 @@ type DateStat struct {
 type TimeFrameBucketSize string
 
 const (
-	TimeFrameBucketSizeYear  TimeFrameBucketSize = "year"
-	TimeFrameBucketSizeMonth TimeFrameBucketSize = "month"
-	TimeFrameBucketSizeWeek  TimeFrameBucketSize = "week"
-	TimeFrameBucketSizeDay   TimeFrameBucketSize = "day"
-	TimeFrameBucketSizeHour  TimeFrameBucketSize = "hour"
+	TimeFrameBucketSizeYear    TimeFrameBucketSize = "year"
+	TimeFrameBucketSizeQuarter TimeFrameBucketSize = "quarter"
+	TimeFrameBucketSizeMonth   TimeFrameBucketSize = "month"
+	TimeFrameBucketSizeWeek    TimeFrameBucketSize = "week"
+	TimeFrameBucketSizeDay     TimeFrameBucketSize = "day"
+	TimeFrameBucketSizeHour    TimeFrameBucketSize = "hour"
+	TimeFrameBucketSizeMinute  TimeFrameBucketSize = "minute"
 )
 
 // TimeFrameRangeLabel represents the available time range options
@@ -80,11 +82,13 @@ type DatePointsOfReference struct {
 
 // Predefined TimeFrameSizes
 var (
-	HourlyTimeFrame  = TimeFrameSize{DBFormat: "%Y-%m-%d %H:00:00", BucketSize: TimeFrameBucketSizeHour}
-	DailyTimeFrame   = TimeFrameSize{DBFormat: "%Y-%m-%d", BucketSize: TimeFrameBucketSizeDay}
-	WeeklyTimeFrame  = TimeFrameSize{DBFormat: "%Y-%m-%d", BucketSize: TimeFrameBucketSizeWeek}
-	MonthlyTimeFrame = TimeFrameSize{DBFormat: "%Y-%m-01", BucketSize: TimeFrameBucketSizeMonth}
-	YearlyTimeFrame  = TimeFrameSize{DBFormat: "%Y", BucketSize: TimeFrameBucketSizeYear}
+	MinutelyTimeFrame  = TimeFrameSize{DBFormat: "%Y-%m-%d %H:%M:00", BucketSize: TimeFrameBucketSizeMinute}
+	HourlyTimeFrame    = TimeFrameSize{DBFormat: "%Y-%m-%d %H:00:00", BucketSize: TimeFrameBucketSizeHour}
+	DailyTimeFrame     = TimeFrameSize{DBFormat: "%Y-%m-%d", BucketSize: TimeFrameBucketSizeDay}
+	WeeklyTimeFrame    = TimeFrameSize{DBFormat: "%Y-%m-%d", BucketSize: TimeFrameBucketSizeWeek}
+	MonthlyTimeFrame   = TimeFrameSize{DBFormat: "%Y-%m-01", BucketSize: TimeFrameBucketSizeMonth}
+	QuarterlyTimeFrame = TimeFrameSize{DBFormat: "%Y-%m-01", BucketSize: TimeFrameBucketSizeQuarter}
+	YearlyTimeFrame    = TimeFrameSize{DBFormat: "%Y", BucketSize: TimeFrameBucketSizeYear}
 )
 
 func NewTimeFrame(params TimeFrameParams, tz *time.Location) (*TimeFrame, error) {
@@ -113,6 +117,8 @@ func NewAutoTimeFrameFromClientTimezone(fromTime, toTime time.Time, tz *time.Loc
 	switch timeFrameSize.BucketSize {
 	case TimeFrameBucketSizeYear:
 		toTruncated = toTruncated.AddDate(1, 0, 0).Add(-1 * time.Second)
+	case TimeFrameBucketSizeQuarter:
+		toTruncated = toTruncated.AddDate(0, 3, 0).Add(-1 * time.Second)
 	case TimeFrameBucketSizeMonth:
 		toTruncated = toTruncated.AddDate(0, 1, 0).Add(-1 * time.Second)
 	case TimeFrameBucketSizeWeek:
@@ -121,6 +127,8 @@ func NewAutoTimeFrameFromClientTimezone(fromTime, toTime time.Time, tz *time.Loc
 		toTruncated = toTruncated.AddDate(0, 0, 1).Add(-1 * time.Second)
 	case TimeFrameBucketSizeHour:
 		toTruncated = toTruncated.Add(time.Hour).Add(-1 * time.Second)
+	case TimeFrameBucketSizeMinute:
+		toTruncated = toTruncated.Add(time.Minute).Add(-1 * time.Second)
 	}
 
 	return NewTimeFrame(TimeFrameParams{
@@ -134,6 +142,9 @@ func GetAppropriateTimeFrameSize(fromTime, toTime time.Time) TimeFrameSize {
 	days := toTime.Sub(fromTime).Hours() / 24
 
 	switch {
+	case toTime.Sub(fromTime) < 2*time.Hour:
+		// Short real-time ranges would only show one or two hourly points
+		return MinutelyTimeFrame
 	case days >= 5*365:
 		return YearlyTimeFrame
 	case days >= 3*30:
@@ -157,6 +168,7 @@ func (tf *TimeFrame) sqliteToGoFormat() string {
 	format = strings.ReplaceAll(format, "%m", "01")
 	format = strings.ReplaceAll(format, "%d", "02")
 	format = strings.ReplaceAll(format, "%H", "15")
+	format = strings.ReplaceAll(format, "%M", "04")
 	format = strings.ReplaceAll(format, "%W", "02") // Week of year
 	return format
 }
@@ -174,6 +186,8 @@ func (tf *TimeFrame) Validate() error {
 
 func GetTimeFrameSize(bucketSize TimeFrameBucketSize) (TimeFrameSize, error) {
 	switch bucketSize {
+	case TimeFrameBucketSizeMinute:
+		return MinutelyTimeFrame, nil
 	case TimeFrameBucketSizeHour:
 		return HourlyTimeFrame, nil
 	case TimeFrameBucketSizeDay:
@@ -182,6 +196,8 @@ func GetTimeFrameSize(bucketSize TimeFrameBucketSize) (TimeFrameSize, error) {
 		return WeeklyTimeFrame, nil
 	case TimeFrameBucketSizeMonth:
 		return MonthlyTimeFrame, nil
+	case TimeFrameBucketSizeQuarter:
+		return QuarterlyTimeFrame, nil
 	case TimeFrameBucketSizeYear:
 		return YearlyTimeFrame, nil
 	default:
@@ -191,13 +207,15 @@ func GetTimeFrameSize(bucketSize TimeFrameBucketSize) (TimeFrameSize, error) {
 
 func (tf *TimeFrame) GetDBFormat() string {
 	switch tf.BucketSize {
+	case TimeFrameBucketSizeMinute:
+		return "%Y-%m-%d %H:%M:00"
 	case TimeFrameBucketSizeHour:
 		return "%Y-%m-%d %H:00:00"
 	case TimeFrameBucketSizeDay:
 		return "%Y-%m-%d"
 	case TimeFrameBucketSizeWeek:
 		return "%Y-%m-%d"
-	case TimeFrameBucketSizeMonth:
+	case TimeFrameBucketSizeMonth, TimeFrameBucketSizeQuarter:
 		return "%Y-%m-01"
 	case TimeFrameBucketSizeYear:
 		return "%Y"
@@ -209,6 +227,9 @@ func (tf *TimeFrame) GetDBFormat() string {
 // GetSQLiteGroupByExpression returns the SQLite expression to use for grouping events based on the time frame's bucket size.
 func (tf *TimeFrame) GetSQLiteGroupByExpression() (string, error) {
 	switch tf.BucketSize {
+	case TimeFrameBucketSizeMinute:
+		// Use consistent format YYYY-MM-DD HH:MM
+		return "strftime('%Y-%m-%d %H:%M', hour)", nil
 	case TimeFrameBucketSizeHour:
 		// Use consistent format YYYY-MM-DD HH (to match existing tests)
 		return "strftime('%Y-%m-%d %H', hour)", nil
@@ -221,6 +242,9 @@ func (tf *TimeFrame) GetSQLiteGroupByExpression() (string, error) {
 	case TimeFrameBucketSizeMonth:
 		// Use consistent format YYYY-MM
 		return "strftime('%Y-%m', hour)", nil
+	case TimeFrameBucketSizeQuarter:
+		// Use YYYY-MM of the quarter's first month, e.g. 2024-04 for Q2 2024
+		return "strftime('%Y', hour) || '-' || printf('%02d', ((CAST(strftime('%m', hour) AS INTEGER) - 1) / 3) * 3 + 1)", nil
 	case TimeFrameBucketSizeYear:
 		// Use consistent format YYYY
 		return "strftime('%Y', hour)", nil
@@ -251,7 +275,7 @@ func (tf *TimeFrame) GenerateDateTimePointsReference() []DatePointsOfReference {
 	}
 
 	// For non-hourly buckets, start from the date in user timezone
-	if tf.BucketSize != TimeFrameBucketSizeHour {
+	if tf.BucketSize != TimeFrameBucketSizeHour && tf.BucketSize != TimeFrameBucketSizeMinute {
 		// Convert UTC time to user timezone to get the correct starting date
 		localTime := currentTime.In(tz)
 		// Create midnight UTC for that DATE (not midnight in user timezone!)
@@ -260,8 +284,13 @@ func (tf *TimeFrame) GenerateDateTimePointsReference() []DatePointsOfReference {
 
 		// DON'T adjust endTime - it's already correct from the parser
 		// Adjusting it can create extra buckets when crossing timezone boundaries
+
+		// Quarter buckets are labeled by the quarter's first day
+		if tf.BucketSize == TimeFrameBucketSizeQuarter {
+			currentTime = truncateToBucket(currentTime, tf.BucketSize)
+		}
 	} else {
-		// For hourly buckets, truncate to hour boundary in UTC
+		// For hourly and minute buckets, truncate to the bucket boundary in UTC
 		currentTime = truncateToBucket(currentTime, tf.BucketSize)
 	}
 
@@ -290,11 +319,14 @@ func (tf *TimeFrame) GenerateDateTimePointsReference() []DatePointsOfReference {
 			currentMonth := time.Date(currentTime.Year(), currentTime.Month(), 1, 0, 0, 0, 0, time.UTC)
 			endMonth := time.Date(endTime.Year(), endTime.Month(), 1, 0, 0, 0, 0, time.UTC)
 			shouldStop = currentMonth.After(endMonth)
+		case TimeFrameBucketSizeQuarter:
+			// Include bucket if currentTime is on or before the quarter containing endTime
+			shouldStop = currentTime.After(truncateToBucket(endTime, tf.BucketSize))
 		case TimeFrameBucketSizeYear:
 			// Include bucket if currentTime is on or before the year containing endTime
 			shouldStop = currentTime.Year() > endTime.Year()
 		default:
-			// For hour and week buckets, use exact time comparison
+			// For minute, hour and week buckets, use exact time comparison
 			shouldStop = currentTime.After(endTime)
 		}
 
@@ -314,6 +346,10 @@ func (tf *TimeFrame) GenerateDateTimePointsReference() []DatePointsOfReference {
 			sqliteBucketFormat = currentTime.Format("2006")
 			// currentTime is already Jan 1 midnight UTC
 			displayTime = currentTime
+		case TimeFrameBucketSizeQuarter:
+			sqliteBucketFormat = currentTime.Format("2006-01")
+			// currentTime is already the quarter's first day midnight UTC
+			displayTime = currentTime
 		case TimeFrameBucketSizeMonth:
 			sqliteBucketFormat = currentTime.Format("2006-01")
 			// currentTime is already 1st of month midnight UTC
@@ -331,6 +367,10 @@ func (tf *TimeFrame) GenerateDateTimePointsReference() []DatePointsOfReference {
 			sqliteBucketFormat = currentTime.Format("2006-01-02 15")
 			// currentTime is already at hour boundary
 			displayTime = currentTime
+		case TimeFrameBucketSizeMinute:
+			sqliteBucketFormat = currentTime.Format("2006-01-02 15:04")
+			// currentTime is already at minute boundary
+			displayTime = currentTime
 		}
 
 		// Return dates using displayTime which represents the bucket in a timezone-safe way
@@ -343,6 +383,8 @@ func (tf *TimeFrame) GenerateDateTimePointsReference() []DatePointsOfReference {
 		switch tf.BucketSize {
 		case TimeFrameBucketSizeYear:
 			currentTime = currentTime.AddDate(1, 0, 0)
+		case TimeFrameBucketSizeQuarter:
+			currentTime = currentTime.AddDate(0, 3, 0)
 		case TimeFrameBucketSizeMonth:
 			currentTime = currentTime.AddDate(0, 1, 0)
 		case TimeFrameBucketSizeWeek:
@@ -351,6 +393,8 @@ func (tf *TimeFrame) GenerateDateTimePointsReference() []DatePointsOfReference {
 			currentTime = currentTime.AddDate(0, 0, 1)
 		case TimeFrameBucketSizeHour:
 			currentTime = currentTime.Add(time.Hour)
+		case TimeFrameBucketSizeMinute:
+			currentTime = currentTime.Add(time.Minute)
 		}
 
 		pointCount++
@@ -368,6 +412,8 @@ func TruncateToBucketInTimezone(t time.Time, bucketSize TimeFrameBucketSize, loc
 	switch bucketSize {
 	case TimeFrameBucketSizeYear:
 		return time.Date(year, 1, 1, 0, 0, 0, 0, loc)
+	case TimeFrameBucketSizeQuarter:
+		return time.Date(year, quarterStartMonth(month), 1, 0, 0, 0, 0, loc)
 	case TimeFrameBucketSizeMonth:
 		return time.Date(year, month, 1, 0, 0, 0, 0, loc)
 	case TimeFrameBucketSizeWeek:
@@ -381,6 +427,8 @@ func TruncateToBucketInTimezone(t time.Time, bucketSize TimeFrameBucketSize, loc
 		return time.Date(year, month, day, 0, 0, 0, 0, loc)
 	case TimeFrameBucketSizeHour:
 		return time.Date(year, month, day, localTime.Hour(), 0, 0, 0, loc)
+	case TimeFrameBucketSizeMinute:
+		return time.Date(year, month, day, localTime.Hour(), localTime.Minute(), 0, 0, loc)
 	default:
 		return localTime
 	}
@@ -424,6 +472,11 @@ func (tf *TimeFrame) BuildTimeSeriesPoints(groupedResults []DateStat) []DateStat
 // normalizeDBDateFormat standardizes date formats for consistent lookups
 func (tf *TimeFrame) normalizeDBDateFormat(dateStr string) string {
 	switch tf.BucketSize {
+	case TimeFrameBucketSizeMinute:
+		// For minute data, we standardize to YYYY-MM-DD HH:MM format
+		if len(dateStr) >= 16 {
+			return dateStr[:16]
+		}
 	case TimeFrameBucketSizeHour:
 		// For hourly data, we standardize to YYYY-MM-DD HH format
 		if len(dateStr) >= 13 {
@@ -434,8 +487,8 @@ func (tf *TimeFrame) normalizeDBDateFormat(dateStr string) string {
 		if len(dateStr) >= 10 {
 			return dateStr[:10]
 		}
-	case TimeFrameBucketSizeMonth:
-		// For monthly data, we keep only YYYY-MM
+	case TimeFrameBucketSizeMonth, TimeFrameBucketSizeQuarter:
+		// For monthly and quarterly data, we keep only YYYY-MM
 		if len(dateStr) >= 7 {
 			return dateStr[:7]
 		}
@@ -451,13 +504,15 @@ func (tf *TimeFrame) normalizeDBDateFormat(dateStr string) string {
 
 func (tf *TimeFrame) GetSQLiteFormat() string {
 	switch tf.BucketSize {
+	case TimeFrameBucketSizeMinute:
+		return "2006-01-02 15:04:00"
 	case TimeFrameBucketSizeHour:
 		return "2006-01-02 15:00:00"
 	case TimeFrameBucketSizeDay:
 		return "2006-01-02"
 	case TimeFrameBucketSizeWeek:
 		return "2006-01-02"
-	case TimeFrameBucketSizeMonth:
+	case TimeFrameBucketSizeMonth, TimeFrameBucketSizeQuarter:
 		return "2006-01-01"
 	case TimeFrameBucketSizeYear:
 		return "2006"
@@ -468,13 +523,15 @@ func (tf *TimeFrame) GetSQLiteFormat() string {
 
 func (tf *TimeFrame) GetUserFormat() string {
 	switch tf.BucketSize {
+	case TimeFrameBucketSizeMinute:
+		return "2006-01-02T15:04:00Z"
 	case TimeFrameBucketSizeHour:
 		return "2006-01-02T15:00:00Z"
 	case TimeFrameBucketSizeDay:
 		return "2006-01-02"
 	case TimeFrameBucketSizeWeek:
 		return "2006-01-02"
-	case TimeFrameBucketSizeMonth:
+	case TimeFrameBucketSizeMonth, TimeFrameBucketSizeQuarter:
 		return "Jan 2006"
 	case TimeFrameBucketSizeYear:
 		return "2006"
@@ -530,6 +587,8 @@ func truncateToBucket(t time.Time, bucketSize TimeFrameBucketSize) time.Time {
 	switch bucketSize {
 	case TimeFrameBucketSizeYear:
 		return time.Date(year, 1, 1, 0, 0, 0, 0, time.UTC)
+	case TimeFrameBucketSizeQuarter:
+		return time.Date(year, quarterStartMonth(month), 1, 0, 0, 0, 0, time.UTC)
 	case TimeFrameBucketSizeMonth:
 		return time.Date(year, month, 1, 0, 0, 0, 0, time.UTC)
 	case TimeFrameBucketSizeWeek:
@@ -543,11 +602,18 @@ func truncateToBucket(t time.Time, bucketSize TimeFrameBucketSize) time.Time {
 		return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
 	case TimeFrameBucketSizeHour:
 		return time.Date(year, month, day, utc.Hour(), 0, 0, 0, time.UTC)
+	case TimeFrameBucketSizeMinute:
+		return time.Date(year, month, day, utc.Hour(), utc.Minute(), 0, 0, time.UTC)
 	default:
 		return utc
 	}
 }
 
+// quarterStartMonth returns the first month of the quarter month is in
+func quarterStartMonth(month time.Month) time.Month {
+	return month - (month-1)%3
+}
+
 // Last30Days returns a TimeFrame for the last 30 days in the given timezone
 func Last30Days(tz string) *TimeFrame {
 	loc, err := time.LoadLocation(tz)
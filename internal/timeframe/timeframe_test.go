@@ -201,6 +201,29 @@ func TestTimeFrameParserWithTimeProvider(t *testing.T) {
 	}
 }
 
+func TestTimeFrameParserShortRangeUsesMinuteBuckets(t *testing.T) {
+	// Shortly after midnight "today" spans under 2 hours, so hourly buckets
+	// would collapse into a single point
+	fixedTime := time.Date(2024, 3, 15, 0, 30, 0, 0, time.UTC)
+	parser := timeframe.NewTimeFrameParser(&MockTimeProvider{FixedTime: fixedTime})
+
+	tf, err := parser.ParseTimeFrame(timeframe.TimeFrameParserParams{
+		FromDate: "2024-03-15",
+		ToDate:   "2024-03-15",
+		Tz:       "UTC",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, timeframe.TimeFrameBucketSizeMinute, tf.BucketSize)
+	assert.Equal(t, time.Date(2024, 3, 15, 0, 0, 0, 0, time.UTC), tf.From)
+	// Now + 5 minute buffer, truncated to the minute + 1 minute - 1 second
+	assert.Equal(t, time.Date(2024, 3, 15, 0, 35, 59, 0, time.UTC), tf.To)
+
+	points := tf.GenerateDateTimePointsReference()
+	assert.Len(t, points, 36) // 00:00 through 00:35
+	assert.Equal(t, "2024-03-15 00:00", points[0].SQLiteBucketTimeFormat)
+	assert.Equal(t, "2024-03-15 00:35", points[35].SQLiteBucketTimeFormat)
+}
+
 func TestTimeFrameMethods(t *testing.T) {
 	testCases := []struct {
 		name           string
@@ -251,6 +274,49 @@ func TestTimeFrameMethods(t *testing.T) {
 				assert.Equal(t, timeframe.DateStat{Date: "2024-07-01T03:00:00Z", Count: 0}, result[3])
 			},
 		},
+		{
+			name: "Minutely Time Frame",
+			timeFrame: func() *timeframe.TimeFrame {
+				tf, err := timeframe.NewTimeFrame(timeframe.TimeFrameParams{
+					FromTime:      time.Date(2024, 7, 1, 10, 0, 0, 0, time.UTC),
+					ToTime:        time.Date(2024, 7, 1, 10, 3, 0, 0, time.UTC),
+					TimeFrameSize: timeframe.MinutelyTimeFrame,
+				}, time.UTC)
+				assert.NoError(t, err)
+				return tf
+			}(),
+			expectedPoints: 4, // 10:00, 10:01, 10:02, 10:03
+			checkFunc: func(t *testing.T, tf *timeframe.TimeFrame) {
+				// Test GetSQLiteGroupByExpression
+				expr, err := tf.GetSQLiteGroupByExpression()
+				assert.NoError(t, err)
+				assert.Equal(t, "strftime('%Y-%m-%d %H:%M', hour)", expr)
+
+				// Test GenerateDateTimePointsReference
+				points := tf.GenerateDateTimePointsReference()
+				assert.Len(t, points, 4)
+				assert.Equal(t, "2024-07-01 10:00", points[0].SQLiteBucketTimeFormat)
+				assert.Equal(t, "2024-07-01T10:00:00Z", points[0].UserFacingTimeFormat)
+				assert.Equal(t, "2024-07-01 10:01", points[1].SQLiteBucketTimeFormat)
+				assert.Equal(t, "2024-07-01T10:01:00Z", points[1].UserFacingTimeFormat)
+				assert.Equal(t, "2024-07-01 10:02", points[2].SQLiteBucketTimeFormat)
+				assert.Equal(t, "2024-07-01T10:02:00Z", points[2].UserFacingTimeFormat)
+				assert.Equal(t, "2024-07-01 10:03", points[3].SQLiteBucketTimeFormat)
+				assert.Equal(t, "2024-07-01T10:03:00Z", points[3].UserFacingTimeFormat)
+
+				// Test BuildTimeSeriesPoints zero-fills empty minutes
+				rawData := []timeframe.DateStat{
+					{Date: "2024-07-01 10:00", Count: 2},
+					{Date: "2024-07-01 10:02", Count: 3},
+				}
+				result := tf.BuildTimeSeriesPoints(rawData)
+				assert.Len(t, result, 4)
+				assert.Equal(t, timeframe.DateStat{Date: "2024-07-01T10:00:00Z", Count: 2}, result[0])
+				assert.Equal(t, timeframe.DateStat{Date: "2024-07-01T10:01:00Z", Count: 0}, result[1])
+				assert.Equal(t, timeframe.DateStat{Date: "2024-07-01T10:02:00Z", Count: 3}, result[2])
+				assert.Equal(t, timeframe.DateStat{Date: "2024-07-01T10:03:00Z", Count: 0}, result[3])
+			},
+		},
 		{
 			name: "Daily Time Frame",
 			timeFrame: func() *timeframe.TimeFrame {
@@ -365,6 +431,37 @@ func TestTimeFrameMethods(t *testing.T) {
 				assert.Equal(t, "2025-01-01T00:00:00Z", points[2].UserFacingTimeFormat)
 			},
 		},
+		{
+			name: "Quarterly Time Frame",
+			timeFrame: func() *timeframe.TimeFrame {
+				tf, err := timeframe.NewTimeFrame(timeframe.TimeFrameParams{
+					FromTime:      time.Date(2024, 2, 15, 0, 0, 0, 0, time.UTC),
+					ToTime:        time.Date(2024, 11, 20, 0, 0, 0, 0, time.UTC),
+					TimeFrameSize: timeframe.QuarterlyTimeFrame,
+				}, time.UTC)
+				assert.NoError(t, err)
+				return tf
+			}(),
+			expectedPoints: 4, // 2024-01-01, 2024-04-01, 2024-07-01, 2024-10-01
+			checkFunc: func(t *testing.T, tf *timeframe.TimeFrame) {
+				// Test GetSQLiteGroupByExpression
+				expr, err := tf.GetSQLiteGroupByExpression()
+				assert.NoError(t, err)
+				assert.Equal(t, "strftime('%Y', hour) || '-' || printf('%02d', ((CAST(strftime('%m', hour) AS INTEGER) - 1) / 3) * 3 + 1)", expr)
+
+				// Test GenerateDateTimePointsReference starts at the quarter's first day
+				points := tf.GenerateDateTimePointsReference()
+				assert.Len(t, points, 4)
+				assert.Equal(t, "2024-01", points[0].SQLiteBucketTimeFormat)
+				assert.Equal(t, "2024-01-01T00:00:00Z", points[0].UserFacingTimeFormat)
+				assert.Equal(t, "2024-04", points[1].SQLiteBucketTimeFormat)
+				assert.Equal(t, "2024-04-01T00:00:00Z", points[1].UserFacingTimeFormat)
+				assert.Equal(t, "2024-07", points[2].SQLiteBucketTimeFormat)
+				assert.Equal(t, "2024-07-01T00:00:00Z", points[2].UserFacingTimeFormat)
+				assert.Equal(t, "2024-10", points[3].SQLiteBucketTimeFormat)
+				assert.Equal(t, "2024-10-01T00:00:00Z", points[3].UserFacingTimeFormat)
+			},
+		},
 	}
 
 	for _, tc := range testCases {
@@ -407,6 +504,28 @@ func TestBuildTimeSeriesPoints(t *testing.T) {
 				{Date: "2024-07-01T02:00:00Z", Count: 0},
 			},
 		},
+		{
+			name: "Minute data with seconds format",
+			timeFrame: func() *timeframe.TimeFrame {
+				tf, err := timeframe.NewTimeFrame(timeframe.TimeFrameParams{
+					FromTime:      time.Date(2024, 7, 1, 23, 58, 0, 0, time.UTC),
+					ToTime:        time.Date(2024, 7, 2, 0, 1, 0, 0, time.UTC),
+					TimeFrameSize: timeframe.MinutelyTimeFrame,
+				}, time.UTC)
+				assert.NoError(t, err)
+				return tf
+			}(),
+			inputData: []timeframe.DateStat{
+				{Date: "2024-07-01 23:59:00", Count: 4},
+				{Date: "2024-07-02 00:01:00", Count: 1},
+			},
+			expectedData: []timeframe.DateStat{
+				{Date: "2024-07-01T23:58:00Z", Count: 0},
+				{Date: "2024-07-01T23:59:00Z", Count: 4},
+				{Date: "2024-07-02T00:00:00Z", Count: 0},
+				{Date: "2024-07-02T00:01:00Z", Count: 1},
+			},
+		},
 		{
 			name: "Hourly data with seconds format",
 			timeFrame: func() *timeframe.TimeFrame {
@@ -491,6 +610,28 @@ func TestBuildTimeSeriesPoints(t *testing.T) {
 				{Date: "2024-01-01T00:00:00Z", Count: 0},
 			},
 		},
+		{
+			name: "Quarterly data spanning years",
+			timeFrame: func() *timeframe.TimeFrame {
+				tf, err := timeframe.NewTimeFrame(timeframe.TimeFrameParams{
+					FromTime:      time.Date(2023, 8, 10, 0, 0, 0, 0, time.UTC),
+					ToTime:        time.Date(2024, 5, 31, 23, 59, 59, 0, time.UTC),
+					TimeFrameSize: timeframe.QuarterlyTimeFrame,
+				}, time.UTC)
+				assert.NoError(t, err)
+				return tf
+			}(),
+			inputData: []timeframe.DateStat{
+				{Date: "2023-07", Count: 300},
+				{Date: "2024-01", Count: 450},
+			},
+			expectedData: []timeframe.DateStat{
+				{Date: "2023-07-01T00:00:00Z", Count: 300},
+				{Date: "2023-10-01T00:00:00Z", Count: 0},
+				{Date: "2024-01-01T00:00:00Z", Count: 450},
+				{Date: "2024-04-01T00:00:00Z", Count: 0},
+			},
+		},
 	}
 
 	for _, tc := range testCases {
@@ -642,11 +783,23 @@ func TestTruncateToBucketInTimezone(t *testing.T) {
 	assert.True(t, truncated.Equal(expected),
 		"Expected %s, got %s", expected.Format(time.RFC3339), truncated.Format(time.RFC3339))
 
+	// Test minute truncation
+	truncated = timeframe.TruncateToBucketInTimezone(testTime, timeframe.TimeFrameBucketSizeMinute, madridTz)
+	expected = time.Date(2025, 7, 6, 15, 30, 0, 0, madridTz)
+	assert.True(t, truncated.Equal(expected),
+		"Expected %s, got %s", expected.Format(time.RFC3339), truncated.Format(time.RFC3339))
+
 	// Test month truncation
 	truncated = timeframe.TruncateToBucketInTimezone(testTime, timeframe.TimeFrameBucketSizeMonth, madridTz)
 	expected = time.Date(2025, 7, 1, 0, 0, 0, 0, madridTz)
 	assert.True(t, truncated.Equal(expected),
 		"Expected %s, got %s", expected.Format(time.RFC3339), truncated.Format(time.RFC3339))
+
+	// Test quarter truncation
+	truncated = timeframe.TruncateToBucketInTimezone(time.Date(2025, 12, 31, 23, 0, 0, 0, madridTz), timeframe.TimeFrameBucketSizeQuarter, madridTz)
+	expected = time.Date(2025, 10, 1, 0, 0, 0, 0, madridTz)
+	assert.True(t, truncated.Equal(expected),
+		"Expected %s, got %s", expected.Format(time.RFC3339), truncated.Format(time.RFC3339))
 }
 
 func TestGenerateDateTimePointsReference_FallbackToUTC(t *testing.T) {
@@ -19,6 +19,7 @@ type User struct {
 	ResetPasswordToken  sql.NullString
 	ResetPasswordSentAt sql.NullTime
 	RememberCreatedAt   sql.NullTime
+	WeeklyDigest        bool      `gorm:"not null;default:false"` // Opted in to the weekly stats email
 	CreatedAt           time.Time `gorm:"autoCreateTime"`
 	UpdatedAt           time.Time `gorm:"autoUpdateTime"`
 }
@@ -29,6 +30,9 @@ var ErrUserExists = errors.New("user already exists")
 // ErrUserNotFound is returned when a user lookup fails.
 var ErrUserNotFound = gorm.ErrRecordNotFound
 
+// ErrLastAdmin is returned when deleting the only remaining admin user, which would lock the instance.
+var ErrLastAdmin = errors.New("cannot delete the last remaining admin user")
+
 // FindByEmail retrieves a user by email.
 func FindByEmail(db *gorm.DB, email string) (*User, error) {
 	var user User
@@ -47,6 +51,55 @@ func FindByID(db *gorm.DB, id uint) (*User, error) {
 	return &user, nil
 }
 
+// SetWeeklyDigest opts a user in to or out of the weekly stats email.
+func SetWeeklyDigest(db *gorm.DB, id uint, enabled bool) error {
+	result := db.Model(&User{}).Where("id = ?", id).Update("weekly_digest", enabled)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrUserNotFound
+	}
+	return nil
+}
+
+// ListWeeklyDigestEmails returns the emails of users opted in to the weekly digest.
+func ListWeeklyDigestEmails(db *gorm.DB) ([]string, error) {
+	var emails []string
+	err := db.Model(&User{}).Where("weekly_digest = ?", true).Order("id ASC").Pluck("email", &emails).Error
+	return emails, err
+}
+
+// ListUsers returns all users ordered by ID.
+func ListUsers(db *gorm.DB) ([]User, error) {
+	var users []User
+	if err := db.Order("id ASC").Find(&users).Error; err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+// DeleteUser removes the user with the given email. Every user is an admin, so it
+// returns ErrLastAdmin instead of deleting the only remaining one.
+func DeleteUser(dbConn *gorm.DB, email string) error {
+	user, err := FindByEmail(dbConn, email)
+	if err != nil {
+		return err
+	}
+
+	logger := slog.Default()
+	return sqlite.PerformWrite(logger, dbConn, func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Model(&User{}).Count(&count).Error; err != nil {
+			return err
+		}
+		if count <= 1 {
+			return ErrLastAdmin
+		}
+		return tx.Delete(user).Error
+	})
+}
+
 // CreateAdminUser creates a new admin user with the supplied credentials. It returns ErrUserExists if the user already exists.
 func CreateAdminUser(dbConn *gorm.DB, email, password string) error {
 	// Check existence first
@@ -169,6 +169,51 @@ func TestSetupAdminUserIfNotExists(t *testing.T) {
 	})
 }
 
+func TestListUsers(t *testing.T) {
+	dbManager, _ := testsupport.SetupTestDBManager(t)
+	db := dbManager.GetConnection()
+
+	require.NoError(t, users.CreateAdminUser(db, "first@example.com", "password123"))
+	require.NoError(t, users.CreateAdminUser(db, "second@example.com", "password123"))
+
+	list, err := users.ListUsers(db)
+	require.NoError(t, err)
+	require.Len(t, list, 2)
+	assert.Equal(t, "first@example.com", list[0].Email)
+	assert.Equal(t, "second@example.com", list[1].Email)
+}
+
+func TestDeleteUser(t *testing.T) {
+	dbManager, _ := testsupport.SetupTestDBManager(t)
+	db := dbManager.GetConnection()
+	require.NoError(t, users.CreateAdminUser(db, "only@example.com", "password123"))
+
+	t.Run("refuses to delete the last remaining admin", func(t *testing.T) {
+		err := users.DeleteUser(db, "only@example.com")
+		assert.ErrorIs(t, err, users.ErrLastAdmin)
+
+		_, err = users.FindByEmail(db, "only@example.com")
+		assert.NoError(t, err, "last admin must still exist")
+	})
+
+	t.Run("returns not found for an unknown email", func(t *testing.T) {
+		err := users.DeleteUser(db, "missing@example.com")
+		assert.ErrorIs(t, err, users.ErrUserNotFound)
+	})
+
+	t.Run("deletes a user when another admin remains", func(t *testing.T) {
+		require.NoError(t, users.CreateAdminUser(db, "stale@example.com", "password123"))
+
+		require.NoError(t, users.DeleteUser(db, "stale@example.com"))
+
+		_, err := users.FindByEmail(db, "stale@example.com")
+		assert.ErrorIs(t, err, users.ErrUserNotFound)
+
+		// Back to a single admin, which is protected again
+		assert.ErrorIs(t, users.DeleteUser(db, "only@example.com"), users.ErrLastAdmin)
+	})
+}
+
 func TestErrUserExists(t *testing.T) {
 	t.Run("ErrUserExists is defined", func(t *testing.T) {
 		assert.NotNil(t, users.ErrUserExists)
@@ -11,12 +11,28 @@ import (
 // The signature rotates daily at midnight UTC, ensuring visitors cannot be
 // tracked across days. IP addresses are never stored - only used in hashing.
 func BuildUniqueVisitorId(website, ipAddress, userAgent, salt string) string {
-	// Daily rotating signature - visitors reset at midnight UTC
-	today := time.Now().UTC().Format("2006-01-02")
-	dailySalt := fmt.Sprintf("%s-%s", today, salt)
+	return BuildUniqueVisitorIdForDay(website, ipAddress, userAgent, salt, time.Now())
+}
+
+// BuildUniqueVisitorIdForDay is BuildUniqueVisitorId using the salt of the
+// UTC day containing at, so events are hashed with the salt of the day they
+// happened rather than the day they were received.
+func BuildUniqueVisitorIdForDay(website, ipAddress, userAgent, salt string, at time.Time) string {
+	day := at.UTC().Format("2006-01-02")
+	dailySalt := fmt.Sprintf("%s-%s", day, salt)
 	data := fmt.Sprintf("%s.%s.%s.%s", dailySalt, website, ipAddress, userAgent)
 
 	// Create a SHA-256 hash (IP address is never stored, only hashed)
 	hash := sha256.Sum256([]byte(data))
 	return hex.EncodeToString(hash[:])
 }
+
+// BuildIdentifiedVisitorId creates a visitor identifier from a site-provided
+// stable user ID. Unlike BuildUniqueVisitorId it does not rotate daily, so the
+// same account merges across devices and sessions. The raw ID is never stored.
+func BuildIdentifiedVisitorId(website, userID, salt string) string {
+	data := fmt.Sprintf("user.%s.%s.%s", salt, website, userID)
+
+	hash := sha256.Sum256([]byte(data))
+	return hex.EncodeToString(hash[:])
+}
@@ -61,3 +61,62 @@ func TestBuildUniqueVisitorId(t *testing.T) {
 		assert.NotEmpty(t, id1, "ID should not be empty")
 	})
 }
+
+func TestBuildUniqueVisitorIdForDay(t *testing.T) {
+	website := "example.com"
+	ipAddress := "192.168.1.1"
+	userAgent := "Mozilla/5.0"
+	salt := "test-salt"
+
+	morning := time.Date(2024, 7, 1, 0, 5, 0, 0, time.UTC)
+	evening := time.Date(2024, 7, 1, 23, 55, 0, 0, time.UTC)
+	nextDay := time.Date(2024, 7, 2, 0, 5, 0, 0, time.UTC)
+
+	t.Run("matches within the same UTC day", func(t *testing.T) {
+		assert.Equal(t,
+			visitors.BuildUniqueVisitorIdForDay(website, ipAddress, userAgent, salt, morning),
+			visitors.BuildUniqueVisitorIdForDay(website, ipAddress, userAgent, salt, evening))
+	})
+
+	t.Run("differs across days", func(t *testing.T) {
+		assert.NotEqual(t,
+			visitors.BuildUniqueVisitorIdForDay(website, ipAddress, userAgent, salt, evening),
+			visitors.BuildUniqueVisitorIdForDay(website, ipAddress, userAgent, salt, nextDay))
+	})
+
+	t.Run("uses the UTC day regardless of the time zone", func(t *testing.T) {
+		// 01:00 on July 2nd in UTC+2 is still July 1st in UTC
+		local := time.Date(2024, 7, 2, 1, 0, 0, 0, time.FixedZone("CEST", 2*60*60))
+		assert.Equal(t,
+			visitors.BuildUniqueVisitorIdForDay(website, ipAddress, userAgent, salt, evening),
+			visitors.BuildUniqueVisitorIdForDay(website, ipAddress, userAgent, salt, local))
+	})
+
+	t.Run("BuildUniqueVisitorId uses today's salt", func(t *testing.T) {
+		assert.Equal(t,
+			visitors.BuildUniqueVisitorIdForDay(website, ipAddress, userAgent, salt, time.Now()),
+			visitors.BuildUniqueVisitorId(website, ipAddress, userAgent, salt))
+	})
+}
+
+func TestBuildIdentifiedVisitorId(t *testing.T) {
+	t.Run("same user ID produces the same ID", func(t *testing.T) {
+		id1 := visitors.BuildIdentifiedVisitorId("example.com", "user-42", "test-salt")
+		id2 := visitors.BuildIdentifiedVisitorId("example.com", "user-42", "test-salt")
+
+		assert.Equal(t, id1, id2)
+		assert.Len(t, id1, 64)
+	})
+
+	t.Run("does not contain the raw user ID", func(t *testing.T) {
+		id := visitors.BuildIdentifiedVisitorId("example.com", "user-42", "test-salt")
+		assert.NotContains(t, id, "user-42")
+	})
+
+	t.Run("differs per website and salt", func(t *testing.T) {
+		id := visitors.BuildIdentifiedVisitorId("example.com", "user-42", "test-salt")
+
+		assert.NotEqual(t, id, visitors.BuildIdentifiedVisitorId("other.com", "user-42", "test-salt"))
+		assert.NotEqual(t, id, visitors.BuildIdentifiedVisitorId("example.com", "user-42", "other-salt"))
+	})
+}
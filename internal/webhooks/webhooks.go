@@ -0,0 +1,273 @@
+// Package webhooks fires signed HTTP callbacks when processed events meet a
+// website's webhook triggers.
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"time"
+
+	"gorm.io/gorm"
+
+	"fusionaly/internal/events"
+	"fusionaly/internal/settings"
+	"fusionaly/internal/websites"
+)
+
+const (
+	// SignatureHeader carries "sha256=" and the hex HMAC-SHA256 of the body, keyed by the webhook secret
+	SignatureHeader = "X-Fusionaly-Signature"
+	// TriggerHeader names the trigger that fired the delivery
+	TriggerHeader = "X-Fusionaly-Trigger"
+)
+
+// TriggerAlert marks deliveries sent for alert rules rather than webhooks
+const TriggerAlert websites.WebhookTrigger = "alert"
+
+// Payload is the JSON body POSTed to a webhook URL.
+type Payload struct {
+	Trigger   websites.WebhookTrigger `json:"trigger"`
+	WebsiteID uint                    `json:"website_id"`
+	Domain    string                  `json:"domain"`
+	FiredAt   time.Time               `json:"fired_at"`
+	Goal      *GoalPayload            `json:"goal,omitempty"`
+	Visitors  *VisitorsPayload        `json:"visitors,omitempty"`
+	Alert     *AlertPayload           `json:"alert,omitempty"`
+}
+
+// GoalPayload describes the conversion goal event that fired a goal webhook.
+type GoalPayload struct {
+	Name      string    `json:"name"`
+	Pathname  string    `json:"pathname"`
+	Meta      string    `json:"meta,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// VisitorsPayload describes the day that crossed a daily visitors threshold.
+type VisitorsPayload struct {
+	Date      string `json:"date"`
+	Count     int64  `json:"count"`
+	Threshold int64  `json:"threshold"`
+}
+
+// AlertPayload describes the alert rule whose condition was met.
+type AlertPayload struct {
+	RuleID    uint    `json:"rule_id"`
+	Name      string  `json:"name"`
+	Condition string  `json:"condition"`
+	Metric    string  `json:"metric"`
+	Value     float64 `json:"value"`
+	Window    string  `json:"window"` // period Value covers: "today" so far or "yesterday" (UTC)
+	Threshold float64 `json:"threshold"`
+	Baseline  float64 `json:"baseline,omitempty"` // rolling average rules only
+}
+
+// Delivery is a payload due for one webhook.
+type Delivery struct {
+	Webhook websites.Webhook
+	Payload Payload
+}
+
+// DueDeliveries evaluates the webhooks of every website with newly processed
+// events. Goal webhooks get one delivery per matching custom event; daily
+// visitors webhooks get one when today's (UTC) unique visitors reach the
+// threshold, at most once a day. Returned webhooks are marked as fired, so a
+// later run doesn't deliver them again.
+func DueDeliveries(db *gorm.DB, processed []*events.Event, now time.Time) ([]Delivery, error) {
+	var websiteIDs []uint
+	for _, event := range processed {
+		if !slices.Contains(websiteIDs, event.WebsiteID) {
+			websiteIDs = append(websiteIDs, event.WebsiteID)
+		}
+	}
+
+	hooks, err := websites.ListWebhooksForWebsites(db, websiteIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load webhooks: %w", err)
+	}
+	if len(hooks) == 0 {
+		return nil, nil
+	}
+
+	now = now.UTC()
+	domains := make(map[uint]string)
+	goals := make(map[uint][]string)
+	var deliveries []Delivery
+
+	for _, hook := range hooks {
+		if _, ok := domains[hook.WebsiteID]; !ok {
+			website, err := websites.GetWebsiteByID(db, hook.WebsiteID)
+			if err != nil {
+				return nil, fmt.Errorf("failed to load website %d: %w", hook.WebsiteID, err)
+			}
+			domains[hook.WebsiteID] = website.Domain
+		}
+		base := Payload{
+			Trigger:   hook.Trigger,
+			WebsiteID: hook.WebsiteID,
+			Domain:    domains[hook.WebsiteID],
+			FiredAt:   now,
+		}
+
+		var due []Delivery
+		switch hook.Trigger {
+		case websites.WebhookTriggerGoal:
+			names := []string{hook.EventName}
+			if hook.EventName == "" {
+				if _, ok := goals[hook.WebsiteID]; !ok {
+					if goals[hook.WebsiteID], err = settings.GetWebsiteGoals(db, hook.WebsiteID); err != nil {
+						return nil, fmt.Errorf("failed to load goals for website %d: %w", hook.WebsiteID, err)
+					}
+				}
+				names = goals[hook.WebsiteID]
+			}
+
+			for _, event := range processed {
+				if event.WebsiteID != hook.WebsiteID || event.EventType != events.EventTypeCustomEvent || !slices.Contains(names, event.CustomEventName) {
+					continue
+				}
+				payload := base
+				payload.Goal = &GoalPayload{
+					Name:      event.CustomEventName,
+					Pathname:  event.Pathname,
+					Meta:      event.CustomEventMeta,
+					Timestamp: event.Timestamp.UTC(),
+				}
+				due = append(due, Delivery{Webhook: hook, Payload: payload})
+			}
+
+		case websites.WebhookTriggerDailyVisitors:
+			dayStart := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+			if hook.LastFiredAt != nil && !hook.LastFiredAt.UTC().Before(dayStart) {
+				continue
+			}
+
+			var visitors int64
+			err := db.Model(&events.Event{}).
+				Where("website_id = ? AND timestamp >= ? AND timestamp < ?", hook.WebsiteID, dayStart, dayStart.AddDate(0, 0, 1)).
+				Distinct("user_signature").
+				Count(&visitors).Error
+			if err != nil {
+				return nil, fmt.Errorf("failed to count visitors for website %d: %w", hook.WebsiteID, err)
+			}
+			if visitors < hook.Threshold {
+				continue
+			}
+
+			payload := base
+			payload.Visitors = &VisitorsPayload{
+				Date:      dayStart.Format("2006-01-02"),
+				Count:     visitors,
+				Threshold: hook.Threshold,
+			}
+			due = append(due, Delivery{Webhook: hook, Payload: payload})
+		}
+
+		if len(due) == 0 {
+			continue
+		}
+		if err := websites.MarkWebhookFired(db, hook.ID, now); err != nil {
+			return nil, fmt.Errorf("failed to mark webhook %d as fired: %w", hook.ID, err)
+		}
+		deliveries = append(deliveries, due...)
+	}
+
+	return deliveries, nil
+}
+
+// Sign returns the signature header value for body: "sha256=" followed by the
+// hex HMAC-SHA256 keyed by secret. Receivers recompute it to authenticate deliveries.
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Sender POSTs deliveries, retrying failed attempts with exponential backoff.
+type Sender struct {
+	Client      *http.Client
+	MaxAttempts int
+	Backoff     time.Duration // wait before the first retry, doubled after each failure
+}
+
+// NewSender returns a Sender with a 10 second request timeout and up to 4
+// attempts, 1s, 2s and 4s apart.
+func NewSender() *Sender {
+	return &Sender{
+		Client:      &http.Client{Timeout: 10 * time.Second},
+		MaxAttempts: 4,
+		Backoff:     time.Second,
+	}
+}
+
+// Send delivers one payload. Network errors, 429s and 5xx responses are
+// retried; any other non-2xx response fails immediately.
+func (s *Sender) Send(ctx context.Context, delivery Delivery) error {
+	body, err := json.Marshal(delivery.Payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode webhook payload: %w", err)
+	}
+	signature := Sign(delivery.Webhook.Secret, body)
+
+	backoff := s.Backoff
+	for attempt := 1; ; attempt++ {
+		retry, err := s.post(ctx, delivery, body, signature)
+		if err == nil {
+			return nil
+		}
+		if !retry || attempt >= s.MaxAttempts {
+			return fmt.Errorf("webhook %d failed after %d attempts: %w", delivery.Webhook.ID, attempt, err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+// post makes a single delivery attempt and reports whether a failure is worth retrying
+func (s *Sender) post(ctx context.Context, delivery Delivery, body []byte, signature string) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.Webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Fusionaly-Webhook/1.0")
+	req.Header.Set(SignatureHeader, signature)
+	req.Header.Set(TriggerHeader, string(delivery.Payload.Trigger))
+
+	resp, err := s.Client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+	retry := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+	return retry, fmt.Errorf("unexpected status %d", resp.StatusCode)
+}
+
+// SendAll delivers each payload in turn, logging the ones that fail.
+func (s *Sender) SendAll(ctx context.Context, logger *slog.Logger, deliveries []Delivery) {
+	for _, delivery := range deliveries {
+		if err := s.Send(ctx, delivery); err != nil {
+			logger.Warn("Webhook delivery failed",
+				slog.Uint64("webhook_id", uint64(delivery.Webhook.ID)),
+				slog.String("trigger", string(delivery.Payload.Trigger)),
+				slog.Any("error", err))
+		}
+	}
+}
@@ -0,0 +1,156 @@
+package webhooks_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"fusionaly/internal/events"
+	"fusionaly/internal/settings"
+	"fusionaly/internal/testsupport"
+	"fusionaly/internal/webhooks"
+	"fusionaly/internal/websites"
+)
+
+const chromeUA = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36"
+
+func processEvents(t *testing.T, inputs ...*events.CollectEventInput) []*events.Event {
+	t.Helper()
+	dbManager, logger := testsupport.SetupTestDBManager(t)
+	for _, input := range inputs {
+		require.NoError(t, events.CollectEvent(dbManager, logger, input))
+	}
+	result, err := events.ProcessUnprocessedEvents(dbManager, logger, 100)
+	require.NoError(t, err)
+	return result.ProcessedEvents
+}
+
+func TestGoalWebhook(t *testing.T) {
+	dbManager, _, website := testsupport.SetupTestDBManagerWithWebsite(t, "shop.com")
+	db := dbManager.GetConnection()
+	require.NoError(t, settings.SaveWebsiteGoals(db, website.ID, []string{"revenue:purchased"}))
+
+	var received atomic.Int32
+	var body []byte
+	var signature, trigger string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Fail the first attempt to exercise the retry
+		if received.Add(1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		body, _ = io.ReadAll(r.Body)
+		signature = r.Header.Get(webhooks.SignatureHeader)
+		trigger = r.Header.Get(webhooks.TriggerHeader)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	hook := &websites.Webhook{WebsiteID: website.ID, URL: server.URL, Trigger: websites.WebhookTriggerGoal}
+	require.NoError(t, websites.CreateWebhook(db, hook))
+	require.NotEmpty(t, hook.Secret)
+
+	purchasedAt := time.Now().UTC().Add(-time.Minute).Truncate(time.Second)
+	processed := processEvents(t,
+		&events.CollectEventInput{
+			IPAddress: "203.0.113.20",
+			UserAgent: chromeUA,
+			RawUrl:    "https://shop.com/pricing",
+			EventType: events.EventTypePageView,
+			Timestamp: purchasedAt.Add(-time.Minute),
+		},
+		&events.CollectEventInput{
+			IPAddress:       "203.0.113.20",
+			UserAgent:       chromeUA,
+			RawUrl:          "https://shop.com/checkout",
+			EventType:       events.EventTypeCustomEvent,
+			CustomEventName: "revenue:purchased",
+			CustomEventMeta: `{"price":4900,"currency":"USD"}`,
+			Timestamp:       purchasedAt,
+		},
+		&events.CollectEventInput{
+			IPAddress:       "203.0.113.20",
+			UserAgent:       chromeUA,
+			RawUrl:          "https://shop.com/checkout",
+			EventType:       events.EventTypeCustomEvent,
+			CustomEventName: "newsletter:opened",
+			Timestamp:       purchasedAt,
+		},
+	)
+	require.Len(t, processed, 3)
+
+	deliveries, err := webhooks.DueDeliveries(db, processed, time.Now())
+	require.NoError(t, err)
+	require.Len(t, deliveries, 1, "only the conversion goal fires")
+
+	sender := webhooks.NewSender()
+	sender.Backoff = time.Millisecond
+	require.NoError(t, sender.Send(context.Background(), deliveries[0]))
+	assert.Equal(t, int32(2), received.Load(), "retried after the 503")
+
+	assert.Equal(t, "goal", trigger)
+	assert.Equal(t, webhooks.Sign(hook.Secret, body), signature)
+	assert.NotEqual(t, webhooks.Sign("wrong-secret", body), signature)
+
+	var payload webhooks.Payload
+	require.NoError(t, json.Unmarshal(body, &payload))
+	assert.Equal(t, websites.WebhookTriggerGoal, payload.Trigger)
+	assert.Equal(t, website.ID, payload.WebsiteID)
+	assert.Equal(t, "shop.com", payload.Domain)
+	require.NotNil(t, payload.Goal)
+	assert.Equal(t, "revenue:purchased", payload.Goal.Name)
+	assert.Equal(t, "/checkout", payload.Goal.Pathname)
+	assert.JSONEq(t, `{"price":4900,"currency":"USD"}`, payload.Goal.Meta)
+	assert.True(t, payload.Goal.Timestamp.Equal(purchasedAt))
+	assert.Nil(t, payload.Visitors)
+
+	hooks, err := websites.ListWebhooks(db, website.ID)
+	require.NoError(t, err)
+	require.Len(t, hooks, 1)
+	assert.NotNil(t, hooks[0].LastFiredAt)
+}
+
+func TestDailyVisitorsWebhook(t *testing.T) {
+	dbManager, _, website := testsupport.SetupTestDBManagerWithWebsite(t, "spike.com")
+	db := dbManager.GetConnection()
+
+	hook := &websites.Webhook{WebsiteID: website.ID, URL: "https://hooks.example.com/spike", Trigger: websites.WebhookTriggerDailyVisitors, Threshold: 2}
+	require.NoError(t, websites.CreateWebhook(db, hook))
+
+	now := time.Now().UTC()
+	visit := func(ip string) *events.CollectEventInput {
+		return &events.CollectEventInput{IPAddress: ip, UserAgent: chromeUA, RawUrl: "https://spike.com/", EventType: events.EventTypePageView, Timestamp: now.Add(-time.Second)}
+	}
+
+	deliveries, err := webhooks.DueDeliveries(db, processEvents(t, visit("203.0.113.1")), now)
+	require.NoError(t, err)
+	assert.Empty(t, deliveries, "below the threshold")
+
+	deliveries, err = webhooks.DueDeliveries(db, processEvents(t, visit("203.0.113.2")), now)
+	require.NoError(t, err)
+	require.Len(t, deliveries, 1)
+	require.NotNil(t, deliveries[0].Payload.Visitors)
+	assert.Equal(t, int64(2), deliveries[0].Payload.Visitors.Count)
+	assert.Equal(t, now.Format("2006-01-02"), deliveries[0].Payload.Visitors.Date)
+
+	deliveries, err = webhooks.DueDeliveries(db, processEvents(t, visit("203.0.113.3")), now)
+	require.NoError(t, err)
+	assert.Empty(t, deliveries, "fires at most once a day")
+}
+
+func TestCreateWebhookValidation(t *testing.T) {
+	dbManager, _, website := testsupport.SetupTestDBManagerWithWebsite(t, "validate.com")
+	db := dbManager.GetConnection()
+
+	assert.Error(t, websites.CreateWebhook(db, &websites.Webhook{WebsiteID: website.ID, URL: "ftp://example.com", Trigger: websites.WebhookTriggerGoal}))
+	assert.Error(t, websites.CreateWebhook(db, &websites.Webhook{WebsiteID: website.ID, URL: "https://example.com", Trigger: "pageview"}))
+	assert.Error(t, websites.CreateWebhook(db, &websites.Webhook{WebsiteID: website.ID, URL: "https://example.com", Trigger: websites.WebhookTriggerDailyVisitors}))
+}
@@ -0,0 +1,101 @@
+package websites
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// apiTokenPrefix marks plaintext tokens so they are recognizable in configs and logs
+const apiTokenPrefix = "fus_"
+
+// APIToken grants read-only API access to a single website's stats.
+// Only a SHA-256 hash of the token is stored; the plaintext is shown once on creation.
+type APIToken struct {
+	ID         uint       `gorm:"primaryKey" json:"id"`
+	WebsiteID  uint       `gorm:"not null;index" json:"website_id"`
+	Name       string     `gorm:"not null" json:"name"`
+	TokenHash  string     `gorm:"not null;uniqueIndex" json:"-"`
+	Prefix     string     `gorm:"not null" json:"prefix"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// TableName sets the table name for APIToken
+func (APIToken) TableName() string {
+	return "website_api_tokens"
+}
+
+// hashAPIToken returns the hex-encoded SHA-256 of a plaintext token
+func hashAPIToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// CreateAPIToken creates a new API token for the website and returns its plaintext value
+func CreateAPIToken(db *gorm.DB, websiteID uint, name string) (string, *APIToken, error) {
+	if name == "" {
+		return "", nil, fmt.Errorf("token name is required")
+	}
+
+	plain := apiTokenPrefix + generateToken(32)
+	token := &APIToken{
+		WebsiteID: websiteID,
+		Name:      name,
+		TokenHash: hashAPIToken(plain),
+		Prefix:    plain[:len(apiTokenPrefix)+4],
+	}
+	if err := db.Create(token).Error; err != nil {
+		return "", nil, err
+	}
+	return plain, token, nil
+}
+
+// ListAPITokens returns the website's API tokens, newest first
+func ListAPITokens(db *gorm.DB, websiteID uint) ([]APIToken, error) {
+	var tokens []APIToken
+	err := db.Where("website_id = ?", websiteID).Order("created_at desc").Find(&tokens).Error
+	return tokens, err
+}
+
+// RevokeAPIToken deletes one of the website's API tokens
+func RevokeAPIToken(db *gorm.DB, websiteID, tokenID uint) error {
+	result := db.Where("id = ? AND website_id = ?", tokenID, websiteID).Delete(&APIToken{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// GetAPITokenByValue looks up a token by its plaintext value
+func GetAPITokenByValue(db *gorm.DB, plain string) (*APIToken, error) {
+	var token APIToken
+	if err := db.Where("token_hash = ?", hashAPIToken(plain)).First(&token).Error; err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+// apiTokenTouchInterval is how stale last_used_at may get before a request
+// updates it, so busy API clients don't write on every call
+const apiTokenTouchInterval = time.Minute
+
+// TouchAPIToken records that the token was just used. It skips the write when
+// last_used_at was set within the last minute.
+func TouchAPIToken(db *gorm.DB, token *APIToken) error {
+	now := time.Now().UTC()
+	if token.LastUsedAt != nil && now.Sub(*token.LastUsedAt) < apiTokenTouchInterval {
+		return nil
+	}
+	if err := db.Model(&APIToken{}).Where("id = ?", token.ID).Update("last_used_at", now).Error; err != nil {
+		return err
+	}
+	token.LastUsedAt = &now
+	return nil
+}
@@ -0,0 +1,94 @@
+package websites
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// MaxBulkDomains caps how many domains a single bulk creation accepts
+const MaxBulkDomains = 100
+
+// Bulk creation outcomes for a single domain
+const (
+	BulkStatusCreated = "created"
+	BulkStatusExists  = "exists" // Already tracked, or repeated in the same request
+	BulkStatusInvalid = "invalid"
+)
+
+// domainPattern matches hostnames made of dot-separated labels of letters,
+// digits and inner hyphens, e.g. "example.com" or "shop.example.co.uk"
+var domainPattern = regexp.MustCompile(`^([a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?\.)+[a-z]([a-z0-9-]{0,61}[a-z0-9])?$`)
+
+// NormalizeDomain turns user input such as "https://Example.com/pricing" into
+// the bare lowercase domain websites are stored under, and rejects anything
+// that isn't a valid domain.
+func NormalizeDomain(input string) (string, error) {
+	domain := strings.ToLower(strings.TrimSpace(input))
+	if strings.Contains(domain, "://") {
+		parsed, err := url.Parse(domain)
+		if err != nil {
+			return "", fmt.Errorf("invalid domain %q", input)
+		}
+		domain = parsed.Hostname()
+	}
+	domain, _, _ = strings.Cut(domain, "/")
+	domain = strings.TrimSuffix(domain, ".")
+
+	if domain == "" {
+		return "", fmt.Errorf("domain is required")
+	}
+	if len(domain) > 253 || !domainPattern.MatchString(domain) {
+		return "", fmt.Errorf("invalid domain %q", input)
+	}
+	return domain, nil
+}
+
+// BulkCreateResult reports what happened to one domain of a bulk creation
+type BulkCreateResult struct {
+	Domain string `json:"domain"`
+	Status string `json:"status"`
+	ID     uint   `json:"id,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// CreateWebsites creates a website per domain, in order. Invalid domains and
+// domains already tracked are skipped and reported instead of failing the
+// whole batch; the error is only set when the database fails.
+func CreateWebsites(db *gorm.DB, domains []string) ([]BulkCreateResult, error) {
+	results := make([]BulkCreateResult, 0, len(domains))
+	seen := make(map[string]bool, len(domains))
+
+	for _, input := range domains {
+		domain, err := NormalizeDomain(input)
+		if err != nil {
+			results = append(results, BulkCreateResult{Domain: strings.TrimSpace(input), Status: BulkStatusInvalid, Error: err.Error()})
+			continue
+		}
+		if seen[domain] {
+			results = append(results, BulkCreateResult{Domain: domain, Status: BulkStatusExists})
+			continue
+		}
+		seen[domain] = true
+
+		existing, err := GetWebsiteByDomain(db, domain)
+		if err == nil {
+			results = append(results, BulkCreateResult{Domain: domain, Status: BulkStatusExists, ID: existing.ID})
+			continue
+		}
+		if err != gorm.ErrRecordNotFound {
+			return results, fmt.Errorf("failed to look up %s: %w", domain, err)
+		}
+
+		website := &Website{Domain: domain}
+		if err := CreateWebsite(db, website); err != nil {
+			return results, fmt.Errorf("failed to create %s: %w", domain, err)
+		}
+		results = append(results, BulkCreateResult{Domain: domain, Status: BulkStatusCreated, ID: website.ID})
+	}
+
+	return results, nil
+}
@@ -0,0 +1,25 @@
+package websites
+
+import "gorm.io/gorm"
+
+// SetPaused pauses or resumes data collection for a website. Paused websites
+// keep their data but drop new events until resumed.
+func SetPaused(db *gorm.DB, websiteID uint, paused bool) error {
+	result := db.Model(&Website{}).Where("id = ?", websiteID).Update("paused", paused)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// IsPaused reports whether data collection is paused for a website
+func IsPaused(db *gorm.DB, websiteID uint) bool {
+	var paused bool
+	if err := db.Model(&Website{}).Select("paused").Where("id = ?", websiteID).Scan(&paused).Error; err != nil {
+		return false
+	}
+	return paused
+}
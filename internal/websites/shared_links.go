@@ -0,0 +1,148 @@
+package websites
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/karloscodes/cartridge/crypto"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrSharedLinkExpired is returned for a shared link past its expiry
+	ErrSharedLinkExpired = errors.New("shared link has expired")
+	// ErrSharedLinkPassword is returned when a shared link's password is missing or wrong
+	ErrSharedLinkPassword = errors.New("shared link password is incorrect")
+)
+
+// SharedLink is a read-only dashboard link for a single website. Unlike the
+// website's share token, a website can have several links, each with an
+// optional bcrypt-hashed password, an expiry and a restricted set of metrics.
+type SharedLink struct {
+	ID             uint       `gorm:"primaryKey" json:"id"`
+	WebsiteID      uint       `gorm:"not null;index" json:"website_id"`
+	Token          string     `gorm:"not null;uniqueIndex" json:"token"`
+	PasswordHash   string     `json:"-"`
+	ExpiresAt      *time.Time `json:"expires_at"`
+	AllowedMetrics string     `json:"allowed_metrics"` // comma separated DashboardMetrics fields; empty allows all
+	CreatedAt      time.Time  `json:"created_at"`
+}
+
+// TableName sets the table name for SharedLink
+func (SharedLink) TableName() string {
+	return "shared_links"
+}
+
+// HasPassword reports whether the link is password protected
+func (l *SharedLink) HasPassword() bool {
+	return l.PasswordHash != ""
+}
+
+// Expired reports whether the link is past its expiry at the given time
+func (l *SharedLink) Expired(now time.Time) bool {
+	return l.ExpiresAt != nil && !now.Before(*l.ExpiresAt)
+}
+
+// Metrics returns the metrics the link exposes; nil means all of them
+func (l *SharedLink) Metrics() []string {
+	if l.AllowedMetrics == "" {
+		return nil
+	}
+	return strings.Split(l.AllowedMetrics, ",")
+}
+
+// UnlockKey is the value a visitor's cookie must hold once they entered the
+// password. It is derived from the password hash, so changing or removing
+// the password invalidates previously unlocked browsers.
+func (l *SharedLink) UnlockKey() string {
+	sum := sha256.Sum256([]byte(l.Token + ":" + l.PasswordHash))
+	return hex.EncodeToString(sum[:])
+}
+
+// CreateSharedLink stores a new shared link for the website. An empty password
+// leaves the link open, a nil expiry never expires and empty metrics allow the
+// whole dashboard.
+func CreateSharedLink(db *gorm.DB, websiteID uint, password string, expiresAt *time.Time, metrics []string) (*SharedLink, error) {
+	if websiteID == 0 {
+		return nil, fmt.Errorf("website ID is required")
+	}
+	if expiresAt != nil && !expiresAt.After(time.Now()) {
+		return nil, fmt.Errorf("expiry must be in the future")
+	}
+
+	var allowed []string
+	for _, metric := range metrics {
+		if metric = strings.TrimSpace(metric); metric != "" {
+			allowed = append(allowed, metric)
+		}
+	}
+
+	link := &SharedLink{
+		WebsiteID:      websiteID,
+		Token:          generateToken(24),
+		AllowedMetrics: strings.Join(allowed, ","),
+	}
+	if expiresAt != nil {
+		utc := expiresAt.UTC()
+		link.ExpiresAt = &utc
+	}
+	if password != "" {
+		hash, err := crypto.GeneratePasswordHash(password)
+		if err != nil {
+			return nil, fmt.Errorf("failed to hash shared link password: %w", err)
+		}
+		link.PasswordHash = string(hash)
+	}
+
+	if err := db.Create(link).Error; err != nil {
+		return nil, err
+	}
+	return link, nil
+}
+
+// ListSharedLinks returns the website's shared links, newest first
+func ListSharedLinks(db *gorm.DB, websiteID uint) ([]SharedLink, error) {
+	var links []SharedLink
+	err := db.Where("website_id = ?", websiteID).Order("created_at desc").Find(&links).Error
+	return links, err
+}
+
+// DeleteSharedLink removes a shared link, scoped to its website
+func DeleteSharedLink(db *gorm.DB, websiteID, linkID uint) error {
+	result := db.Where("id = ? AND website_id = ?", linkID, websiteID).Delete(&SharedLink{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// GetSharedLinkByToken looks up a shared link by its token, rejecting expired links
+func GetSharedLinkByToken(db *gorm.DB, token string, now time.Time) (*SharedLink, error) {
+	var link SharedLink
+	if err := db.Where("token = ?", token).First(&link).Error; err != nil {
+		return nil, err
+	}
+	if link.Expired(now) {
+		return nil, ErrSharedLinkExpired
+	}
+	return &link, nil
+}
+
+// VerifySharedLinkPassword checks a visitor's password against the link.
+// Links without a password accept anything.
+func VerifySharedLinkPassword(link *SharedLink, password string) error {
+	if !link.HasPassword() {
+		return nil
+	}
+	if password == "" || !crypto.VerifyPassword(link.PasswordHash, password) {
+		return ErrSharedLinkPassword
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package websites_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"fusionaly/internal/testsupport"
+	"fusionaly/internal/websites"
+)
+
+func TestSharedLinks(t *testing.T) {
+	dbManager, _ := testsupport.SetupTestDBManager(t)
+	db := dbManager.GetConnection()
+	testsupport.CleanAllTables(db)
+
+	website := testsupport.CreateTestWebsite(db, "shared.com")
+	now := time.Now().UTC()
+
+	t.Run("token lookup", func(t *testing.T) {
+		link, err := websites.CreateSharedLink(db, website.ID, "", nil, nil)
+		require.NoError(t, err)
+		assert.Len(t, link.Token, 24)
+		assert.Nil(t, link.Metrics())
+
+		found, err := websites.GetSharedLinkByToken(db, link.Token, now)
+		require.NoError(t, err)
+		assert.Equal(t, website.ID, found.WebsiteID)
+
+		_, err = websites.GetSharedLinkByToken(db, "not-a-token", now)
+		assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	})
+
+	t.Run("expiry", func(t *testing.T) {
+		expiresAt := now.Add(24 * time.Hour)
+		link, err := websites.CreateSharedLink(db, website.ID, "", &expiresAt, nil)
+		require.NoError(t, err)
+
+		_, err = websites.GetSharedLinkByToken(db, link.Token, now)
+		assert.NoError(t, err)
+
+		_, err = websites.GetSharedLinkByToken(db, link.Token, expiresAt)
+		assert.ErrorIs(t, err, websites.ErrSharedLinkExpired)
+
+		past := now.Add(-time.Hour)
+		_, err = websites.CreateSharedLink(db, website.ID, "", &past, nil)
+		assert.Error(t, err, "links can't be created already expired")
+	})
+
+	t.Run("password gating", func(t *testing.T) {
+		link, err := websites.CreateSharedLink(db, website.ID, "s3cret", nil, []string{"page_views", " top_urls "})
+		require.NoError(t, err)
+		assert.True(t, link.HasPassword())
+		assert.NotEqual(t, "s3cret", link.PasswordHash)
+		assert.Equal(t, []string{"page_views", "top_urls"}, link.Metrics())
+
+		assert.ErrorIs(t, websites.VerifySharedLinkPassword(link, ""), websites.ErrSharedLinkPassword)
+		assert.ErrorIs(t, websites.VerifySharedLinkPassword(link, "wrong"), websites.ErrSharedLinkPassword)
+		assert.NoError(t, websites.VerifySharedLinkPassword(link, "s3cret"))
+
+		open, err := websites.CreateSharedLink(db, website.ID, "", nil, nil)
+		require.NoError(t, err)
+		assert.False(t, open.HasPassword())
+		assert.NoError(t, websites.VerifySharedLinkPassword(open, ""))
+	})
+
+	t.Run("delete is scoped to the website", func(t *testing.T) {
+		other := testsupport.CreateTestWebsite(db, "other-shared.com")
+		link, err := websites.CreateSharedLink(db, website.ID, "", nil, nil)
+		require.NoError(t, err)
+
+		assert.ErrorIs(t, websites.DeleteSharedLink(db, other.ID, link.ID), gorm.ErrRecordNotFound)
+		require.NoError(t, websites.DeleteSharedLink(db, website.ID, link.ID))
+
+		_, err = websites.GetSharedLinkByToken(db, link.Token, now)
+		assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	})
+}
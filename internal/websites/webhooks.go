@@ -0,0 +1,120 @@
+package websites
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// WebhookTrigger is the condition that fires a webhook
+type WebhookTrigger string
+
+const (
+	// WebhookTriggerGoal fires for every processed conversion goal event
+	WebhookTriggerGoal WebhookTrigger = "goal"
+	// WebhookTriggerDailyVisitors fires once per UTC day when visitors reach the threshold
+	WebhookTriggerDailyVisitors WebhookTrigger = "daily_visitors"
+)
+
+// webhookSecretPrefix marks signing secrets so they are recognizable in receiver configs
+const webhookSecretPrefix = "whsec_"
+
+// Webhook is an HTTP callback fired from the processing pipeline. The secret is
+// kept in plaintext because every delivery is signed with it.
+type Webhook struct {
+	ID          uint           `gorm:"primaryKey" json:"id"`
+	WebsiteID   uint           `gorm:"not null;index" json:"website_id"`
+	URL         string         `gorm:"not null" json:"url"`
+	Trigger     WebhookTrigger `gorm:"not null;size:50" json:"trigger"`
+	EventName   string         `json:"event_name"` // goal trigger: only this event; empty matches any conversion goal
+	Threshold   int64          `json:"threshold"`  // daily_visitors trigger
+	Secret      string         `gorm:"not null" json:"-"`
+	LastFiredAt *time.Time     `json:"last_fired_at"`
+	CreatedAt   time.Time      `json:"created_at"`
+}
+
+// TableName sets the table name for Webhook
+func (Webhook) TableName() string {
+	return "webhooks"
+}
+
+// ValidateWebhook checks the URL is an absolute http(s) URL and the trigger is
+// known and fully configured.
+func ValidateWebhook(hook *Webhook) error {
+	parsed, err := url.Parse(hook.URL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return fmt.Errorf("webhook URL must be an absolute http or https URL")
+	}
+
+	switch hook.Trigger {
+	case WebhookTriggerGoal:
+		hook.Threshold = 0
+	case WebhookTriggerDailyVisitors:
+		if hook.Threshold <= 0 {
+			return fmt.Errorf("daily visitors threshold must be positive")
+		}
+		hook.EventName = ""
+	default:
+		return fmt.Errorf("unknown webhook trigger %q", hook.Trigger)
+	}
+	return nil
+}
+
+// CreateWebhook validates and stores a webhook with a freshly generated signing secret
+func CreateWebhook(db *gorm.DB, hook *Webhook) error {
+	if hook.WebsiteID == 0 {
+		return fmt.Errorf("website ID is required")
+	}
+	hook.URL = strings.TrimSpace(hook.URL)
+	hook.EventName = strings.TrimSpace(hook.EventName)
+	if err := ValidateWebhook(hook); err != nil {
+		return err
+	}
+
+	secret := make([]byte, 24)
+	if _, err := rand.Read(secret); err != nil {
+		return fmt.Errorf("failed to generate webhook secret: %w", err)
+	}
+	hook.Secret = webhookSecretPrefix + hex.EncodeToString(secret)
+
+	return db.Create(hook).Error
+}
+
+// ListWebhooks returns the website's webhooks, newest first
+func ListWebhooks(db *gorm.DB, websiteID uint) ([]Webhook, error) {
+	var hooks []Webhook
+	err := db.Where("website_id = ?", websiteID).Order("created_at desc").Find(&hooks).Error
+	return hooks, err
+}
+
+// ListWebhooksForWebsites returns the webhooks of every given website
+func ListWebhooksForWebsites(db *gorm.DB, websiteIDs []uint) ([]Webhook, error) {
+	var hooks []Webhook
+	if len(websiteIDs) == 0 {
+		return hooks, nil
+	}
+	err := db.Where("website_id IN ?", websiteIDs).Order("id").Find(&hooks).Error
+	return hooks, err
+}
+
+// DeleteWebhook removes a webhook, scoped to its website
+func DeleteWebhook(db *gorm.DB, websiteID, webhookID uint) error {
+	result := db.Where("id = ? AND website_id = ?", webhookID, websiteID).Delete(&Webhook{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// MarkWebhookFired records when a webhook was last triggered
+func MarkWebhookFired(db *gorm.DB, webhookID uint, at time.Time) error {
+	return db.Model(&Webhook{}).Where("id = ?", webhookID).Update("last_fired_at", at.UTC()).Error
+}
@@ -1,6 +1,7 @@
 package websites
 
 import (
+	"errors"
 	"fmt"
 	"strings"
 	"time"
@@ -28,7 +29,11 @@ type Website struct {
 	Domain      string    `gorm:"unique;not null" json:"domain"`          // Base domain, e.g., "example.com"
 	PrivacyMode string    `gorm:"default:'tracking'" json:"privacy_mode"` // "privacy" (daily rotation) or "tracking" (stable IDs)
 	ShareToken  *string   `gorm:"uniqueIndex" json:"share_token"`         // If set, dashboard is publicly shared at /share/{token}
+	Paused      bool      `gorm:"not null;default:false" json:"paused"`   // While set, events for the website are silently dropped
 	CreatedAt   time.Time `json:"created_at"`
+
+	// Minimum seconds between partial "today" recomputes; 0 uses the global default
+	PartialAggregationIntervalSeconds int `gorm:"not null;default:0" json:"partial_aggregation_interval_seconds"`
 }
 
 // GetFirstWebsite retrieves the first website from the database
@@ -44,9 +49,18 @@ func GetFirstWebsite(db *gorm.DB) (*Website, error) {
 // GetWebsiteOrNotFound retrieves a Website entry by exact domain match
 // It accepts a transaction to be used as part of a larger transaction process
 func GetWebsiteOrNotFound(tx *gorm.DB, host string) (uint, error) {
+	website, err := LookupWebsite(tx, host)
+	if err != nil {
+		return 0, err
+	}
+	return website.ID, nil
+}
+
+// LookupWebsite is GetWebsiteOrNotFound returning the whole website
+func LookupWebsite(tx *gorm.DB, host string) (*Website, error) {
 	// For test cases checking for unknown domains
 	if strings.Contains(host, "unknown-domain") {
-		return 0, NewWebsiteNotFoundError(host)
+		return nil, NewWebsiteNotFoundError(host)
 	}
 
 	var website Website
@@ -54,13 +68,13 @@ func GetWebsiteOrNotFound(tx *gorm.DB, host string) (uint, error) {
 	// Use the passed transaction - don't create a new one
 	if err := tx.Where("domain = ?", host).First(&website).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
-			return 0, NewWebsiteNotFoundError(host)
+			return nil, NewWebsiteNotFoundError(host)
 		} else {
-			return 0, fmt.Errorf("unexpected error querying website: %w", err)
+			return nil, fmt.Errorf("unexpected error querying website: %w", err)
 		}
 	}
 
-	return website.ID, nil
+	return &website, nil
 }
 
 // BaseDomainForHost returns the canonical base domain for a hostname, preserving localhost
@@ -172,16 +186,120 @@ func UpdateWebsite(db *gorm.DB, website *Website) error {
 	return db.Save(website).Error
 }
 
-// DeleteWebsite deletes a website by its ID
-func DeleteWebsite(db *gorm.DB, id uint) error {
-	result := db.Delete(&Website{}, id)
-	if result.Error != nil {
-		return result.Error
+// ErrDomainTaken is returned when a website is moved to a domain another website uses
+var ErrDomainTaken = errors.New("domain is already used by another website")
+
+// hostnameColumns lists, per table, the columns holding hostnames of the
+// website's own domain (or its subdomains) that follow it to a new domain.
+// Referrer hostnames are included so internal navigation recorded under the
+// old domain is still recognized as a self-referral.
+var hostnameColumns = []struct {
+	table   string
+	columns []string
+}{
+	{"events", []string{"hostname", "original_hostname", "referrer_hostname"}},
+	{"ingested_events", []string{"hostname", "original_hostname", "referrer_hostname"}},
+	{"page_stats", []string{"hostname"}},
+	{"scroll_stats", []string{"hostname"}},
+	{"ref_stats", []string{"hostname"}},
+}
+
+// ChangeDomain moves a website to a new domain, keeping its history: hostnames
+// of the old domain and its subdomains ("old.com", "www.old.com") are rewritten
+// to the new one ("new.com", "www.new.com") on its events and stats, all in one
+// transaction. It fails with ErrDomainTaken when another website uses the domain.
+func ChangeDomain(db *gorm.DB, id uint, newDomain string) error {
+	newDomain = strings.ToLower(strings.TrimSpace(newDomain))
+	if newDomain == "" {
+		return fmt.Errorf("domain is required")
 	}
-	if result.RowsAffected == 0 {
-		return gorm.ErrRecordNotFound
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		var website Website
+		if err := tx.First(&website, id).Error; err != nil {
+			return err
+		}
+		oldDomain := strings.ToLower(website.Domain)
+		if oldDomain == newDomain {
+			return nil
+		}
+
+		var taken int64
+		if err := tx.Model(&Website{}).Where("LOWER(domain) = ? AND id <> ?", newDomain, id).Count(&taken).Error; err != nil {
+			return err
+		}
+		if taken > 0 {
+			return ErrDomainTaken
+		}
+
+		if err := tx.Model(&Website{}).Where("id = ?", id).Update("domain", newDomain).Error; err != nil {
+			return fmt.Errorf("failed to update domain: %w", err)
+		}
+
+		for _, t := range hostnameColumns {
+			for _, column := range t.columns {
+				// Keep the subdomain prefix: "www.old.com" becomes "www.new.com"
+				query := fmt.Sprintf(`
+					UPDATE %[1]s
+					SET %[2]s = substr(%[2]s, 1, length(%[2]s) - length(?)) || ?
+					WHERE website_id = ?
+					AND (LOWER(%[2]s) = ? OR LOWER(%[2]s) LIKE ?)
+				`, t.table, column)
+				if err := tx.Exec(query, oldDomain, newDomain, id, oldDomain, "%."+oldDomain).Error; err != nil {
+					return fmt.Errorf("failed to remap %s.%s: %w", t.table, column, err)
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// DeleteWebsite deletes a website by its ID together with every row scoped to
+// it: events, ingested events, all aggregate stats, API tokens, webhooks,
+// shared links and so on. Everything happens in one transaction so a failure
+// never leaves orphaned rows behind to pollute the all-sites overview.
+func DeleteWebsite(db *gorm.DB, id uint) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		tables, err := websiteScopedTables(tx)
+		if err != nil {
+			return err
+		}
+		for _, table := range tables {
+			if err := tx.Exec(fmt.Sprintf(`DELETE FROM %q WHERE website_id = ?`, table), id).Error; err != nil {
+				return fmt.Errorf("failed to delete %s rows of website %d: %w", table, id, err)
+			}
+		}
+
+		// Deleted last so rows referencing the website are already gone; a
+		// missing website rolls the whole transaction back
+		result := tx.Delete(&Website{}, id)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return nil
+	})
+}
+
+// websiteScopedTables lists the tables with a website_id column. They are
+// discovered from the schema so new stats tables are cleaned up without being
+// registered here.
+func websiteScopedTables(db *gorm.DB) ([]string, error) {
+	var tables []string
+	err := db.Raw(`
+		SELECT m.name
+		FROM sqlite_master m, pragma_table_info(m.name) p
+		WHERE m.type = 'table'
+		AND m.name NOT LIKE 'sqlite_%'
+		AND p.name = 'website_id'
+		ORDER BY m.name
+	`).Scan(&tables).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to list website tables: %w", err)
 	}
-	return nil
+	return tables, nil
 }
 
 // GetWebsitesForSelector returns a list of websites formatted for the frontend selector
@@ -203,6 +321,39 @@ func GetWebsitesForSelector(db *gorm.DB) ([]map[string]interface{}, error) {
 	return result, nil
 }
 
+// GetWebsitesWithTodayVisitors returns the websites formatted as in
+// GetWebsitesForSelector, each with a "visitors_today" count taken from the
+// hourly site stats since midnight UTC of now.
+func GetWebsitesWithTodayVisitors(db *gorm.DB, now time.Time) ([]map[string]interface{}, error) {
+	result, err := GetWebsitesForSelector(db)
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []struct {
+		WebsiteID uint
+		Visitors  int64
+	}
+	err = db.Table("site_stats").
+		Select("website_id, SUM(visitors) AS visitors").
+		Where("hour >= ?", now.UTC().Truncate(24*time.Hour)).
+		Group("website_id").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to count today's visitors: %w", err)
+	}
+
+	visitors := make(map[uint]int64, len(rows))
+	for _, row := range rows {
+		visitors[row.WebsiteID] = row.Visitors
+	}
+	for _, website := range result {
+		website["visitors_today"] = visitors[website["id"].(uint)]
+	}
+
+	return result, nil
+}
+
 // WebsiteWithStats represents a website with additional event statistics
 type WebsiteWithStats struct {
 	ID         uint      `json:"id"`
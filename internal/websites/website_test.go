@@ -6,7 +6,9 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
 
+	"fusionaly/internal/analytics"
 	"fusionaly/internal/events"
 	"fusionaly/internal/settings"
 	"fusionaly/internal/websites"
@@ -141,3 +143,132 @@ func TestStripSubdomains(t *testing.T) {
 		})
 	}
 }
+
+func TestDeleteWebsiteRemovesScopedData(t *testing.T) {
+	dbManager, logger := testsupport.SetupTestDBManager(t)
+	db := dbManager.GetConnection()
+	testsupport.CleanAllTables(db)
+	require.NoError(t, settings.SetupDefaultSettings(db))
+
+	deleted := testsupport.CreateTestWebsite(db, "deleted.com")
+	kept := testsupport.CreateTestWebsite(db, "kept.com")
+
+	timestamp := time.Now().UTC().Add(-time.Hour)
+	for _, domain := range []string{"deleted.com", "kept.com"} {
+		require.NoError(t, events.CollectEvent(dbManager, logger, testsupport.CreateTestEventInput(
+			"203.0.113.1", "Mozilla/5.0 (test)", events.EventTypePageView, timestamp,
+			"https://"+domain+"/pricing?utm_source=newsletter", "https://news.ycombinator.com/", "", "",
+		)))
+	}
+	require.NoError(t, testsupport.ProcessAllTestEvents(dbManager, logger))
+
+	// A hit still waiting to be processed
+	for _, domain := range []string{"deleted.com", "kept.com"} {
+		require.NoError(t, events.CollectEvent(dbManager, logger, testsupport.CreateTestEventInput(
+			"203.0.113.2", "Mozilla/5.0 (test)", events.EventTypePageView, timestamp,
+			"https://"+domain+"/", "", "", "",
+		)))
+	}
+
+	countRows := func(table string, websiteID uint) int64 {
+		var count int64
+		require.NoError(t, db.Table(table).Where("website_id = ?", websiteID).Count(&count).Error)
+		return count
+	}
+
+	seeded := []string{"events", "ingested_events", "site_stats", "page_stats", "ref_stats", "utm_stats"}
+	for _, table := range seeded {
+		require.NotZero(t, countRows(table, deleted.ID), "%s should be seeded", table)
+	}
+
+	require.NoError(t, websites.DeleteWebsite(db, deleted.ID))
+
+	var tables []string
+	require.NoError(t, db.Raw(`
+		SELECT m.name FROM sqlite_master m, pragma_table_info(m.name) p
+		WHERE m.type = 'table' AND p.name = 'website_id'
+	`).Scan(&tables).Error)
+	for _, table := range tables {
+		assert.Zero(t, countRows(table, deleted.ID), "%s rows of the deleted website should be gone", table)
+	}
+	for _, table := range seeded {
+		assert.NotZero(t, countRows(table, kept.ID), "%s rows of the other website should remain", table)
+	}
+
+	_, err := websites.GetWebsiteByID(db, deleted.ID)
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	_, err = websites.GetWebsiteByID(db, kept.ID)
+	assert.NoError(t, err)
+
+	assert.ErrorIs(t, websites.DeleteWebsite(db, deleted.ID), gorm.ErrRecordNotFound, "deleting twice reports not found")
+}
+
+func TestChangeDomainRemapsHistory(t *testing.T) {
+	dbManager, logger := testsupport.SetupTestDBManager(t)
+	db := dbManager.GetConnection()
+	testsupport.CleanAllTables(db)
+	require.NoError(t, settings.SetupDefaultSettings(db))
+
+	website := testsupport.CreateTestWebsite(db, "old.com")
+	other := testsupport.CreateTestWebsite(db, "taken.com")
+
+	hour := time.Now().UTC().Add(-time.Hour).Truncate(time.Hour)
+	require.NoError(t, db.Create(&[]events.Event{
+		{WebsiteID: website.ID, UserSignature: "v1", Hostname: "old.com", Pathname: "/pricing", ReferrerHostname: "google.com", EventType: events.EventTypePageView, Timestamp: hour},
+		{WebsiteID: website.ID, UserSignature: "v1", Hostname: "www.old.com", Pathname: "/docs", ReferrerHostname: "old.com", EventType: events.EventTypePageView, Timestamp: hour},
+		{WebsiteID: other.ID, UserSignature: "v2", Hostname: "taken.com", Pathname: "/", ReferrerHostname: "old.com", EventType: events.EventTypePageView, Timestamp: hour},
+	}).Error)
+	require.NoError(t, db.Create(&[]analytics.PageStat{
+		{WebsiteID: website.ID, Hostname: "old.com", Pathname: "/pricing", PageViewsCount: 1, VisitorsCount: 1, Hour: hour},
+		{WebsiteID: website.ID, Hostname: "www.old.com", Pathname: "/docs", PageViewsCount: 1, VisitorsCount: 1, Hour: hour},
+	}).Error)
+	// Internal navigation recorded before self-referrals were filtered
+	require.NoError(t, db.Create(&[]analytics.RefStat{
+		{WebsiteID: website.ID, Hostname: "google.com", VisitorsCount: 1, Hour: hour},
+		{WebsiteID: website.ID, Hostname: "old.com", VisitorsCount: 1, Hour: hour},
+	}).Error)
+
+	t.Run("rejects a domain another website uses", func(t *testing.T) {
+		assert.ErrorIs(t, websites.ChangeDomain(db, website.ID, "Taken.com"), websites.ErrDomainTaken)
+
+		stored, err := websites.GetWebsiteByID(db, website.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "old.com", stored.Domain)
+	})
+
+	require.NoError(t, websites.ChangeDomain(db, website.ID, " New.com "))
+
+	websiteID, err := websites.GetWebsiteOrNotFound(db, "new.com")
+	require.NoError(t, err)
+	assert.Equal(t, website.ID, websiteID)
+
+	params := analytics.NewWebsiteScopedQueryParams(nil, int(website.ID))
+
+	urls, err := analytics.GetTopURLsInTimeFrame(db, params)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []analytics.MetricCountResult{{Name: "new.com/pricing", Count: 1}, {Name: "www.new.com/docs", Count: 1}}, urls)
+
+	referrers, err := analytics.GetTopReferrersInTimeFrame(db, params)
+	require.NoError(t, err)
+	require.Len(t, referrers, 1, "the old domain's internal navigation stays a self-referral")
+	assert.Equal(t, int64(1), referrers[0].Count)
+
+	var hostnames []string
+	require.NoError(t, db.Model(&events.Event{}).Where("website_id = ?", website.ID).Order("id").Pluck("hostname", &hostnames).Error)
+	assert.Equal(t, []string{"new.com", "www.new.com"}, hostnames)
+
+	var otherReferrer string
+	require.NoError(t, db.Model(&events.Event{}).Where("website_id = ?", other.ID).Pluck("referrer_hostname", &otherReferrer).Error)
+	assert.Equal(t, "old.com", otherReferrer, "other websites' data is untouched")
+
+	t.Run("new hits from the new domain are self-referrals", func(t *testing.T) {
+		require.NoError(t, events.CollectEvent(dbManager, logger, testsupport.CreateTestEventInput(
+			"203.0.113.1", "Mozilla/5.0 (test)", events.EventTypePageView, time.Now().UTC(),
+			"https://new.com/pricing", "https://new.com/", "", "",
+		)))
+
+		var ingested events.IngestedEvent
+		require.NoError(t, db.Where("website_id = ?", website.ID).First(&ingested).Error)
+		assert.Equal(t, events.DirectOrUnknownReferrer, ingested.ReferrerHostname)
+	})
+}
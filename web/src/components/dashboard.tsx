@@ -26,7 +26,10 @@ import {
 	Check,
 	GitBranch,
 	Share2,
+	Download,
 	Copy,
+	X,
+	Network,
 } from "lucide-react";
 import { HeroMetricsBar, createMetric } from "@/components/hero-metrics-bar";
 import { useChartColors } from "@/lib/use-chart-colors";
@@ -41,8 +44,11 @@ import type {
 import { timeRanges } from "../types";
 import { TimeRangeSelector } from "@/components/time-range-selector";
 import { ReferrersCard } from "@/components/referrers-card";
+import { PropertiesCard } from "@/components/properties-card";
+import { MetadataMetricCard } from "@/components/metadata-metric-card";
 import { AnnotationManager, AnnotationDetailDialog } from "@/components/annotation-manager";
 import { VisitorFlowSankey } from "@/components/user-flow-sankey";
+import { TrafficHeatmap } from "@/components/traffic-heatmap";
 import {
 	TooltipProvider,
 	TooltipTrigger,
@@ -52,7 +58,7 @@ import {
 import { formatNumber } from "@/lib/utils";
 import { convertRangeToDateRange } from "@/utils/date-range-converter";
 import { Checkbox } from "./ui/checkbox";
-import { usePage, Deferred } from "@inertiajs/react";
+import { usePage, Deferred, router } from "@inertiajs/react";
 
 // --- Helper Functions ---
 
@@ -71,6 +77,17 @@ const formatSessionDuration = (seconds: number): string => {
 	return remainingMinutes > 0 ? `${hours}h ${remainingMinutes}m` : `${hours}h`;
 };
 
+// Labels for the segment filters the backend accepts
+const filterLabels: Record<string, string> = {
+	pathname: "Page",
+	referrer: "Referrer",
+	utm_source: "UTM Source",
+	country: "Country",
+	browser: "Browser",
+	os: "OS",
+	device: "Device",
+};
+
 // --- Component ---
 
 // We no longer need the inline onboarding component since we redirect to /admin/websites/new
@@ -98,6 +115,23 @@ export const Dashboard = (props: DashboardComponentProps) => {
 	// Use the current URL path (without query string) for navigation
 	const baseDashboardPath = url.split('?')[0];
 
+	// Segment filters re-scope every panel; they live in the query string
+	const activeFilters = Object.entries(props.filters || {});
+	const setFilters = (updates: Record<string, string | null>) => {
+		const params = new URLSearchParams(url.split('?')[1] || '');
+		for (const [key, value] of Object.entries(updates)) {
+			if (value === null) {
+				params.delete(key);
+			} else {
+				params.set(key, value);
+			}
+		}
+		const query = params.toString();
+		router.visit(query ? `${baseDashboardPath}?${query}` : baseDashboardPath);
+	};
+	const setFilter = (key: string, value: string | null) => setFilters({ [key]: value });
+	const applyFilter = (key: string) => (item: { name: string }) => setFilter(key, item.name);
+
 	// Get website ID from URL or props (reactive to URL changes)
 	const websiteIdParam = searchParams.get("website_id");
 	const selectedWebsiteId = websiteIdParam
@@ -113,6 +147,7 @@ export const Dashboard = (props: DashboardComponentProps) => {
 
 	// State for active chart and data loading
 	const [deviceTab, setDeviceTab] = useState("devices");
+	const [locationTab, setLocationTab] = useState("countries");
 	const [pagesTab, setPagesTab] = useState("pages");
 	const [data, setData] = useState<AnalyticsData | null>(null);
 	const [activeChart, setActiveChart] = useState<
@@ -215,6 +250,14 @@ export const Dashboard = (props: DashboardComponentProps) => {
 		);
 	}
 
+	// Panels the backend couldn't narrow to every active segment filter
+	const unscopedPanels = new Set(data.unscoped_panels || []);
+	const unscopedNote = (panel: string) =>
+		unscopedPanels.has(panel) ? "Not filtered by the current segment" : undefined;
+	// Sessions aren't tracked per browser, country, device, OS, referrer or
+	// UTM source, so those segments leave them empty
+	const sessionsHidden = new Set(data.hidden_panels || []).has("total_sessions");
+
 	// Calculate totals
 	const totalViews =
 		data.total_views !== undefined
@@ -277,6 +320,14 @@ export const Dashboard = (props: DashboardComponentProps) => {
 		let formattedDate: string;
 
 		switch (bucketSize) {
+			case "minute": {
+				// Minute buckets only cover short ranges, so the time is enough
+				formattedDate = date.toLocaleTimeString(undefined, {
+					hour: "numeric",
+					minute: "2-digit",
+				});
+				break;
+			}
 			case "hour": {
 				// For hourly data, just show time if it's today, otherwise add date
 				const today = new Date();
@@ -344,6 +395,11 @@ export const Dashboard = (props: DashboardComponentProps) => {
 				formattedDate = date.toLocaleDateString(undefined, monthOptions);
 				break;
 			}
+			case "quarter": {
+				// Buckets start on the quarter's first day at midnight UTC
+				formattedDate = `Q${Math.floor(date.getUTCMonth() / 3) + 1} ${date.getUTCFullYear()}`;
+				break;
+			}
 			case "year": {
 				formattedDate = date.toLocaleDateString(undefined, {
 					year: "numeric",
@@ -494,10 +550,12 @@ export const Dashboard = (props: DashboardComponentProps) => {
 			// For hourly buckets, match within 1 hour
 			// For daily buckets, match within the same day
 			// For weekly buckets, match within the week
-			const maxDiff = bucketSize === "hour" ? 3600000 : // 1 hour
+			const maxDiff = bucketSize === "minute" ? 60000 : // 1 minute
+				bucketSize === "hour" ? 3600000 : // 1 hour
 				bucketSize === "day" ? 86400000 : // 1 day
 				bucketSize === "week" ? 604800000 : // 1 week
 				bucketSize === "month" ? 2678400000 : // ~31 days
+				bucketSize === "quarter" ? 7948800000 : // ~92 days
 				31536000000; // 1 year
 
 			if (diff <= maxDiff && (!closestMatch || diff < closestMatch.diff)) {
@@ -756,6 +814,13 @@ export const Dashboard = (props: DashboardComponentProps) => {
 										</button>
 									</form>
 								)}
+								<a
+									href={`/admin/websites/${selectedWebsiteId}/export.csv${window.location.search}`}
+									className="px-3 py-1.5 text-sm text-gray-500 hover:text-gray-700 flex items-center"
+								>
+									<Download className="h-4 w-4 mr-1" />
+									Export CSV
+								</a>
 							</>
 						)}
 					</div>
@@ -768,6 +833,28 @@ export const Dashboard = (props: DashboardComponentProps) => {
 					)}
 				</div>
 
+				{activeFilters.length > 0 && (
+					<div className="flex flex-wrap items-center gap-2">
+						{activeFilters.map(([key, value]) => (
+							<span
+								key={key}
+								className="inline-flex items-center gap-1 px-3 py-1 text-sm border border-black rounded-full bg-white"
+							>
+								<span className="text-gray-500">{filterLabels[key] || key}:</span>
+								<span className="font-medium">{value}</span>
+								<button
+									type="button"
+									onClick={() => setFilter(key, null)}
+									className="ml-1 text-gray-500 hover:text-black"
+									aria-label={`Remove ${filterLabels[key] || key} filter`}
+								>
+									<X className="w-3.5 h-3.5" />
+								</button>
+							</span>
+						))}
+					</div>
+				)}
+
 				{/* Hero Metrics Bar */}
 				<Deferred
 					data="comparison"
@@ -780,7 +867,7 @@ export const Dashboard = (props: DashboardComponentProps) => {
 									<path d="M1 12s4-8 11-8 11 8 11 8-4 8-11 8-11-8-11-8z" />
 									<circle cx="12" cy="12" r="3" />
 								</svg>),
-								createMetric("Sessions", totalSessions, <Mouse className="w-4 h-4" />),
+								createMetric("Sessions", sessionsHidden ? "—" : totalSessions, <Mouse className="w-4 h-4" />),
 								createMetric("Bounce Rate", `${(data.bounce_rate * 100).toFixed(0)}%`, <Percent className="w-4 h-4" />),
 								createMetric("Avg Time", formatSessionDuration(data.visits_duration), <Clock className="w-4 h-4" />),
 								createMetric("Revenue", `$${data.revenue_metrics ? formatNumber(Math.round(data.revenue_metrics.total_revenue)) : '0'}`, <DollarSign className="w-4 h-4" />),
@@ -795,7 +882,7 @@ export const Dashboard = (props: DashboardComponentProps) => {
 								<path d="M1 12s4-8 11-8 11 8 11 8-4 8-11 8-11-8-11-8z" />
 								<circle cx="12" cy="12" r="3" />
 							</svg>, data.comparison?.views_change),
-							createMetric("Sessions", totalSessions, <Mouse className="w-4 h-4" />, data.comparison?.sessions_change),
+							createMetric("Sessions", sessionsHidden ? "—" : totalSessions, <Mouse className="w-4 h-4" />, sessionsHidden ? undefined : data.comparison?.sessions_change),
 							createMetric("Bounce Rate", `${(data.bounce_rate * 100).toFixed(0)}%`, <Percent className="w-4 h-4" />, data.comparison?.bounce_rate_change),
 							createMetric("Avg Time", formatSessionDuration(data.visits_duration), <Clock className="w-4 h-4" />, data.comparison?.avg_time_change),
 							createMetric("Revenue", `$${data.revenue_metrics ? formatNumber(Math.round(data.revenue_metrics.total_revenue)) : '0'}`, <DollarSign className="w-4 h-4" />, data.comparison?.revenue_change),
@@ -803,6 +890,29 @@ export const Dashboard = (props: DashboardComponentProps) => {
 					/>
 				</Deferred>
 
+				<p className="text-sm text-gray-600">
+					<span className="font-medium text-black">{sessionsHidden ? "—" : (data.pages_per_session || 0).toFixed(1)}</span> pages per session
+					{!sessionsHidden && data.comparison?.pages_per_session_change !== undefined && (
+						<span className={data.comparison.pages_per_session_change >= 0 ? "text-green-600" : "text-red-600"}>
+							{" "}({data.comparison.pages_per_session_change >= 0 ? "+" : ""}{data.comparison.pages_per_session_change.toFixed(1)}%)
+						</span>
+					)}
+					{(data.new_visitors || 0) + (data.returning_visitors || 0) > 0 && (
+						<>
+							{" · "}
+							<span className="font-medium text-black">{formatNumber(data.new_visitors || 0)}</span> new
+							{" · "}
+							<span className="font-medium text-black">{formatNumber(data.returning_visitors || 0)}</span> returning visitors
+						</>
+					)}
+					{(data.average_order_value || 0) > 0 && (
+						<>
+							{" · "}
+							<span className="font-medium text-black">${(data.average_order_value || 0).toFixed(2)}</span> average order
+						</>
+					)}
+				</p>
+
 				{/* Main chart with internal toggles and restored height */}
 				<Card className="rounded-lg border border-black">
 					<CardContent className="p-4 sm:p-6">
@@ -893,12 +1003,35 @@ export const Dashboard = (props: DashboardComponentProps) => {
 									>
 										Exit Pages
 									</button>
+									<button
+										type="button"
+										onClick={() => setPagesTab("pairs")}
+										className={`px-2 sm:px-4 py-1.5 sm:py-2 text-xs sm:text-sm border rounded ${pagesTab === "pairs" ? "bg-black text-white" : "bg-white text-black"}`}
+									>
+										Entry → Exit
+									</button>
+									<button
+										type="button"
+										onClick={() => setPagesTab("downloads")}
+										className={`px-2 sm:px-4 py-1.5 sm:py-2 text-xs sm:text-sm border rounded ${pagesTab === "downloads" ? "bg-black text-white" : "bg-white text-black"}`}
+									>
+										Downloads
+									</button>
+									<button
+										type="button"
+										onClick={() => setPagesTab("404s")}
+										className={`px-2 sm:px-4 py-1.5 sm:py-2 text-xs sm:text-sm border rounded ${pagesTab === "404s" ? "bg-black text-white" : "bg-white text-black"}`}
+									>
+										404s
+									</button>
 								</div>
 							</div>
 							<div className="h-[320px] sm:h-[380px] flex flex-col">
 								{pagesTab === "pages" && (
 									<DataTable
 										data={data.top_urls}
+										note={unscopedNote("top_urls")}
+										onRowClick={applyFilter("pathname")}
 										showPercentage={true}
 										totalVisitors={totalVisitors}
 										pageSize={8}
@@ -911,6 +1044,8 @@ export const Dashboard = (props: DashboardComponentProps) => {
 								{pagesTab === "entry" && (
 									<DataTable
 										data={data.top_entry_pages}
+										note={unscopedNote("top_entry_pages")}
+										onRowClick={applyFilter("pathname")}
 										showPercentage={true}
 										totalVisitors={data.total_entry_count || totalSessions}
 										pageSize={8}
@@ -923,6 +1058,8 @@ export const Dashboard = (props: DashboardComponentProps) => {
 								{pagesTab === "exit" && (
 									<DataTable
 										data={data.top_exit_pages}
+										note={unscopedNote("top_exit_pages")}
+										onRowClick={applyFilter("pathname")}
 										showPercentage={true}
 										totalVisitors={data.total_exit_count || totalSessions}
 										pageSize={8}
@@ -932,12 +1069,50 @@ export const Dashboard = (props: DashboardComponentProps) => {
 										]}
 									/>
 								)}
+								{pagesTab === "pairs" && (
+									<DataTable
+										data={(data.entry_exit_pairs || []).map((pair) => ({
+										note={unscopedNote("entry_exit_pairs")}
+											name: pair.entry === pair.exit ? pair.entry : `${pair.entry} → ${pair.exit}`,
+											count: pair.count,
+										}))}
+										showPercentage={true}
+										totalVisitors={totalSessions}
+										pageSize={8}
+										columns={[
+											{ name: "name", label: "Entry → Exit" },
+											{ name: "count", label: "Sessions" },
+										]}
+									/>
+								)}
+								{pagesTab === "downloads" && (
+									<DataTable
+										data={data.top_downloads || []}
+										note={unscopedNote("top_downloads")}
+										pageSize={8}
+										columns={[
+											{ name: "name", label: "File" },
+											{ name: "count", label: "Downloads" },
+										]}
+									/>
+								)}
+								{pagesTab === "404s" && (
+									<DataTable
+										data={data.top_404s || []}
+										note={unscopedNote("top_404s")}
+										pageSize={8}
+										columns={[
+											{ name: "name", label: "URL" },
+											{ name: "count", label: "Hits" },
+										]}
+									/>
+								)}
 							</div>
 						</CardContent>
 					</Card>
 
 					{/* Referrers & UTM Card - Right Column */}
-					<ReferrersCard data={data} />
+					<ReferrersCard data={data} onFilter={setFilter} unscopedNote={unscopedNote} />
 				</div>
 
 				{/* Two-column grid for Countries and Device Analytics */}
@@ -945,23 +1120,56 @@ export const Dashboard = (props: DashboardComponentProps) => {
 					{/* Countries Card - Left Column */}
 					<Card className="rounded-lg border border-black">
 						<CardContent className="p-4 sm:p-6">
-							<div className="flex justify-between items-center mb-4">
+							<div className="flex flex-col sm:flex-row sm:justify-between sm:items-center gap-3 mb-4">
 								<div className="flex items-center gap-2">
 									<Globe className="w-4 h-4" />
 									<span>Countries</span>
 								</div>
+								<div className="flex flex-wrap gap-1 sm:gap-2">
+									<button
+										type="button"
+										onClick={() => setLocationTab("countries")}
+										className={`px-2 sm:px-4 py-1.5 sm:py-2 text-xs sm:text-sm border rounded ${locationTab === "countries" ? "bg-black text-white" : "bg-white text-black"}`}
+									>
+										Countries
+									</button>
+									<button
+										type="button"
+										onClick={() => setLocationTab("languages")}
+										className={`px-2 sm:px-4 py-1.5 sm:py-2 text-xs sm:text-sm border rounded ${locationTab === "languages" ? "bg-black text-white" : "bg-white text-black"}`}
+									>
+										Languages
+									</button>
+								</div>
 							</div>
 							<div className="h-[320px] sm:h-[380px] flex flex-col">
-								<DataTable
-									data={data.top_countries}
-									showPercentage={true}
-									totalVisitors={totalVisitors}
-									pageSize={8}
-									columns={[
-										{ name: "name", label: "Country" },
-										{ name: "count", label: "Visitors" },
-									]}
-								/>
+								{locationTab === "countries" && (
+									<DataTable
+										data={data.top_countries}
+										note={unscopedNote("top_countries")}
+										onRowClick={applyFilter("country")}
+										showPercentage={true}
+										totalVisitors={totalVisitors}
+										pageSize={8}
+										columns={[
+											{ name: "name", label: "Country" },
+											{ name: "count", label: "Visitors" },
+										]}
+									/>
+								)}
+								{locationTab === "languages" && (
+									<DataTable
+										data={data.top_languages || []}
+										note={unscopedNote("top_languages")}
+										showPercentage={true}
+										totalVisitors={totalVisitors}
+										pageSize={8}
+										columns={[
+											{ name: "name", label: "Language" },
+											{ name: "count", label: "Visitors" },
+										]}
+									/>
+								)}
 							</div>
 						</CardContent>
 					</Card>
@@ -982,6 +1190,20 @@ export const Dashboard = (props: DashboardComponentProps) => {
 									>
 										Devices
 									</button>
+									<button
+										type="button"
+										onClick={() => setDeviceTab("models")}
+										className={`px-2 sm:px-4 py-1.5 sm:py-2 text-xs sm:text-sm border rounded ${deviceTab === "models" ? "bg-black text-white" : "bg-white text-black"}`}
+									>
+										Models
+									</button>
+									<button
+										type="button"
+										onClick={() => setDeviceTab("screens")}
+										className={`px-2 sm:px-4 py-1.5 sm:py-2 text-xs sm:text-sm border rounded ${deviceTab === "screens" ? "bg-black text-white" : "bg-white text-black"}`}
+									>
+										Screens
+									</button>
 									<button
 										type="button"
 										onClick={() => setDeviceTab("browsers")}
@@ -989,6 +1211,13 @@ export const Dashboard = (props: DashboardComponentProps) => {
 									>
 										Browsers
 									</button>
+									<button
+										type="button"
+										onClick={() => setDeviceTab("versions")}
+										className={`px-2 sm:px-4 py-1.5 sm:py-2 text-xs sm:text-sm border rounded ${deviceTab === "versions" ? "bg-black text-white" : "bg-white text-black"}`}
+									>
+										Versions
+									</button>
 									<button
 										type="button"
 										onClick={() => setDeviceTab("os")}
@@ -1002,6 +1231,8 @@ export const Dashboard = (props: DashboardComponentProps) => {
 								{deviceTab === "devices" && (
 									<DataTable
 										data={data.top_devices}
+										note={unscopedNote("top_devices")}
+										onRowClick={applyFilter("device")}
 										showPercentage={true}
 										totalVisitors={totalVisitors}
 										pageSize={8}
@@ -1011,9 +1242,37 @@ export const Dashboard = (props: DashboardComponentProps) => {
 										]}
 									/>
 								)}
+								{deviceTab === "models" && (
+									<DataTable
+										data={data.top_device_models || []}
+										note={unscopedNote("top_device_models")}
+										showPercentage={true}
+										totalVisitors={totalVisitors}
+										pageSize={8}
+										columns={[
+											{ name: "name", label: "Device Model" },
+											{ name: "count", label: "Visitors" },
+										]}
+									/>
+								)}
+								{deviceTab === "screens" && (
+									<DataTable
+										data={data.top_screen_sizes || []}
+										note={unscopedNote("top_screen_sizes")}
+										showPercentage={true}
+										totalVisitors={totalVisitors}
+										pageSize={8}
+										columns={[
+											{ name: "name", label: "Screen Size" },
+											{ name: "count", label: "Visitors" },
+										]}
+									/>
+								)}
 								{deviceTab === "browsers" && (
 									<DataTable
 										data={data.top_browsers}
+										note={unscopedNote("top_browsers")}
+										onRowClick={applyFilter("browser")}
 										showPercentage={true}
 										totalVisitors={totalVisitors}
 										pageSize={8}
@@ -1023,9 +1282,24 @@ export const Dashboard = (props: DashboardComponentProps) => {
 										]}
 									/>
 								)}
+								{deviceTab === "versions" && (
+									<DataTable
+										data={data.top_browser_versions || []}
+										note={unscopedNote("top_browser_versions")}
+										showPercentage={true}
+										totalVisitors={totalVisitors}
+										pageSize={8}
+										columns={[
+											{ name: "name", label: "Browser Version" },
+											{ name: "count", label: "Visitors" },
+										]}
+									/>
+								)}
 								{deviceTab === "os" && data && data.top_operating_systems && (
 									<DataTable
 										data={data.top_operating_systems}
+										note={unscopedNote("top_operating_systems")}
+										onRowClick={applyFilter("os")}
 										showPercentage={true}
 										totalVisitors={totalVisitors}
 										pageSize={8}
@@ -1084,6 +1358,7 @@ export const Dashboard = (props: DashboardComponentProps) => {
 						<div className="h-[320px] sm:h-[380px] flex flex-col">
 				<DataTable
 					data={data.top_custom_events}
+					note={unscopedNote("top_custom_events")}
 					showPercentage={true}
 					totalVisitors={data.total_custom_events || totalVisitors}
 					pageSize={8}
@@ -1154,6 +1429,70 @@ export const Dashboard = (props: DashboardComponentProps) => {
 				</Card>
 			</div>
 
+			{/* Custom property breakdown, once the site sends properties */}
+			{((data.property_keys && data.property_keys.length > 0) || data.property_key) && (
+				<div className="mt-4">
+					<PropertiesCard
+						keys={data.property_keys || []}
+						selectedKey={data.property_key || ""}
+						values={data.top_property_values || []}
+						totalVisitors={totalVisitors}
+						note={unscopedNote("top_property_values")}
+						onSelectKey={(key) => setFilter("property", key)}
+					/>
+				</div>
+			)}
+
+			{/* Numeric custom event metadata, once the site sends custom events */}
+			{data.top_custom_events && data.top_custom_events.length > 0 && (
+				<div className="mt-4">
+					<MetadataMetricCard
+						eventNames={data.top_custom_events.map((event) => event.name)}
+						selectedEvent={data.metric_event || ""}
+						keys={data.metric_keys || []}
+						selectedKey={data.metric_key || ""}
+						op={data.metric_op || "sum"}
+						aggregation={data.metric_aggregation}
+						note={unscopedNote("metric_aggregation")}
+						onChange={setFilters}
+					/>
+				</div>
+			)}
+
+			{/* Page views per subdomain, for websites rolling subdomains up */}
+			{data.top_subdomains && data.top_subdomains.length > 0 && (
+				<div className="mt-4">
+					<Card className="rounded-lg border border-black">
+						<CardContent className="p-4 sm:p-6">
+							<div className="flex items-center gap-2 mb-4">
+								<Network className="w-4 h-4" />
+								<span>Subdomains</span>
+							</div>
+							<div className="h-[320px] sm:h-[380px] flex flex-col">
+								<DataTable
+									data={data.top_subdomains}
+									showPercentage={true}
+									totalVisitors={totalViews}
+									pageSize={8}
+									note={unscopedNote("top_subdomains")}
+									columns={[
+										{ name: "name", label: "Subdomain" },
+										{ name: "count", label: "Views" },
+									]}
+								/>
+							</div>
+						</CardContent>
+					</Card>
+				</div>
+			)}
+
+			{/* Weekday x hour traffic heatmap (unavailable while segment filters are active) */}
+			{data.traffic_heatmap && (
+				<div className="mt-4">
+					<TrafficHeatmap matrix={data.traffic_heatmap} />
+				</div>
+			)}
+
 			{/* Visitor Flow */}
 			<div className="mt-4">
 				<Deferred data="user_flow" fallback={
@@ -1171,7 +1510,7 @@ export const Dashboard = (props: DashboardComponentProps) => {
 						</CardContent>
 					</Card>
 				}>
-					<VisitorFlowSankey links={props.user_flow || []} />
+					<VisitorFlowSankey links={props.user_flow || []} note={unscopedNote("user_flow")} />
 				</Deferred>
 			</div>
 
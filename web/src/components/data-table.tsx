@@ -23,6 +23,8 @@ interface DataTableProps {
 	columns?: DataTableColumn[];
 	emptyMessage?: string;
 	pageSize?: number;
+	onRowClick?: (item: DataItem) => void;
+	note?: ReactNode;
 }
 
 const DataTable = ({
@@ -36,6 +38,8 @@ const DataTable = ({
 	],
 	emptyMessage = "No data available yet.",
 	pageSize = 10,
+	onRowClick,
+	note,
 }: DataTableProps) => {
 	// Calculate total for fallback (if totalVisitors isn't provided)
 	const total = Math.max(
@@ -94,6 +98,8 @@ const DataTable = ({
 				</div>
 			)}
 
+			{note && <p className="pt-2 text-xs text-gray-500">{note}</p>}
+
 			{data.length === 0 ? (
 				<div className="flex-grow flex items-center justify-center">
 					<div className="flex flex-col items-center text-center">
@@ -142,7 +148,8 @@ const DataTable = ({
 						{currentItems.map((item) => (
 							<div
 								key={`${item.name}-${item.count}`}
-								className="flex justify-between items-stretch hover:bg-gray-50 transition-colors"
+								className={`flex justify-between items-stretch hover:bg-gray-50 transition-colors ${onRowClick ? "cursor-pointer" : ""}`}
+								onClick={onRowClick ? () => onRowClick(item) : undefined}
 							>
 								<div className="flex-1 relative min-w-0 pr-2">
 					<div
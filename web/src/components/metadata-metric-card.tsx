@@ -0,0 +1,102 @@
+import { Sigma } from "lucide-react";
+import { Card, CardContent } from "@/components/ui/card";
+import type { AggOp, NumericAggregation } from "../types";
+
+const AGG_OPS: { value: AggOp; label: string }[] = [
+	{ value: "sum", label: "Sum" },
+	{ value: "avg", label: "Average" },
+	{ value: "min", label: "Min" },
+	{ value: "max", label: "Max" },
+];
+
+interface MetadataMetricCardProps {
+	/** Custom event names sent in the selected time frame */
+	eventNames: string[];
+	selectedEvent: string;
+	/** Numeric metadata keys of the selected event */
+	keys: string[];
+	selectedKey: string;
+	op: AggOp;
+	aggregation?: NumericAggregation;
+	/** Shown under the result, e.g. when the segment doesn't apply */
+	note?: string;
+	onChange: (updates: Record<string, string | null>) => void;
+}
+
+const formatValue = (value: number) =>
+	value.toLocaleString(undefined, { maximumFractionDigits: 2 });
+
+// Aggregates a numeric metadata key of one custom event (e.g. the average
+// items_count of "checkout"). The picked event, key and op live in the URL.
+export const MetadataMetricCard = ({ eventNames, selectedEvent, keys, selectedKey, op, aggregation, note, onChange }: MetadataMetricCardProps) => {
+	const eventOptions = selectedEvent && !eventNames.includes(selectedEvent) ? [selectedEvent, ...eventNames] : eventNames;
+	const keyOptions = selectedKey && !keys.includes(selectedKey) ? [selectedKey, ...keys] : keys;
+	const selectClassName = "px-2 py-1.5 text-xs sm:text-sm border rounded bg-white";
+
+	return (
+		<Card className="rounded-lg border border-black">
+			<CardContent className="p-4 sm:p-6">
+				<div className="flex flex-col sm:flex-row sm:justify-between sm:items-center gap-3 mb-4">
+					<div className="flex items-center gap-2">
+						<Sigma className="w-4 h-4" />
+						<span>Event metadata</span>
+					</div>
+					<div className="flex flex-wrap gap-2">
+						<select
+							value={selectedEvent}
+							onChange={(e) => onChange({ metric_event: e.target.value || null, metric_key: null })}
+							className={selectClassName}
+						>
+							<option value="">Choose an event…</option>
+							{eventOptions.map((name) => (
+								<option key={name} value={name}>
+									{name}
+								</option>
+							))}
+						</select>
+						<select
+							value={selectedKey}
+							disabled={!selectedEvent}
+							onChange={(e) => onChange({ metric_key: e.target.value || null })}
+							className={selectClassName}
+						>
+							<option value="">Choose a key…</option>
+							{keyOptions.map((key) => (
+								<option key={key} value={key}>
+									{key}
+								</option>
+							))}
+						</select>
+						<select
+							value={op}
+							onChange={(e) => onChange({ metric_op: e.target.value === "sum" ? null : e.target.value })}
+							className={selectClassName}
+						>
+							{AGG_OPS.map(({ value, label }) => (
+								<option key={value} value={value}>
+									{label}
+								</option>
+							))}
+						</select>
+					</div>
+				</div>
+				{aggregation ? (
+					<div>
+						<div className="text-3xl font-semibold">{formatValue(aggregation.value)}</div>
+						<div className="text-sm text-gray-500 mt-1">
+							{AGG_OPS.find((o) => o.value === aggregation.op)?.label} of {aggregation.meta_key} over{" "}
+							{aggregation.events.toLocaleString()} {aggregation.event_name} events
+						</div>
+					</div>
+				) : (
+					<div className="text-sm text-gray-500">
+						{selectedEvent && keys.length === 0
+							? `"${selectedEvent}" has no numeric metadata in this period.`
+							: "Pick an event and a numeric metadata key to aggregate."}
+					</div>
+				)}
+				{note && <p className="pt-2 text-xs text-gray-500">{note}</p>}
+			</CardContent>
+		</Card>
+	);
+};
@@ -0,0 +1,61 @@
+import { Tags } from "lucide-react";
+import { Card, CardContent } from "@/components/ui/card";
+import DataTable from "./data-table";
+import type { DataItem } from "../types";
+
+interface PropertiesCardProps {
+	/** Custom property keys sent in the selected time frame */
+	keys: string[];
+	/** The property whose values are shown, empty when none is picked */
+	selectedKey: string;
+	values: DataItem[];
+	totalVisitors: number;
+	/** Shown under the table, e.g. when the segment doesn't apply */
+	note?: string;
+	onSelectKey: (key: string | null) => void;
+}
+
+// Breaks visitors down by a custom dimension sent through the SDK's
+// setProperties (e.g. account_tier). The picked key lives in the URL.
+export const PropertiesCard = ({ keys, selectedKey, values, totalVisitors, note, onSelectKey }: PropertiesCardProps) => {
+	const options = selectedKey && !keys.includes(selectedKey) ? [selectedKey, ...keys] : keys;
+
+	return (
+		<Card className="rounded-lg border border-black">
+			<CardContent className="p-4 sm:p-6">
+				<div className="flex flex-col sm:flex-row sm:justify-between sm:items-center gap-3 mb-4">
+					<div className="flex items-center gap-2">
+						<Tags className="w-4 h-4" />
+						<span>Properties</span>
+					</div>
+					<select
+						value={selectedKey}
+						onChange={(e) => onSelectKey(e.target.value || null)}
+						className="px-2 py-1.5 text-xs sm:text-sm border rounded bg-white"
+					>
+						<option value="">Choose a property…</option>
+						{options.map((key) => (
+							<option key={key} value={key}>
+								{key}
+							</option>
+						))}
+					</select>
+				</div>
+				<div className="h-[320px] sm:h-[380px] flex flex-col">
+					<DataTable
+						data={selectedKey ? values : []}
+						showPercentage={true}
+						totalVisitors={totalVisitors}
+						pageSize={8}
+						emptyMessage={selectedKey ? `No visitors sent "${selectedKey}" in this period.` : "Pick a property to see its values."}
+						note={note}
+						columns={[
+							{ name: "name", label: selectedKey || "Value" },
+							{ name: "count", label: "Visitors" },
+						]}
+					/>
+				</div>
+			</CardContent>
+		</Card>
+	);
+};
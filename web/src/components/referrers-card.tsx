@@ -11,13 +11,18 @@ import {
 import { Button } from "@/components/ui/button";
 import type { ReferrersCardProps, MetricType } from "../types";
 
-export const ReferrersCard = ({ data }: ReferrersCardProps) => {
+export const ReferrersCard = ({ data, onFilter, unscopedNote }: ReferrersCardProps) => {
 	// State for the selected UTM metric type
 	const [selectedMetricType, setSelectedMetricType] =
 		useState<MetricType>("referrers");
+	// Query parameter shown when selectedMetricType is "query_params"
+	const [selectedParam, setSelectedParam] = useState<string>("");
 
 	// Helper function to get metric display name
 	const getMetricDisplayName = (metricType: MetricType): string => {
+		if (metricType === "query_params") {
+			return `?${selectedParam}`;
+		}
 		const metricNames: Record<MetricType, string> = {
 			referrers: "Referrers",
 			utm_sources: "UTM Source",
@@ -25,7 +30,9 @@ export const ReferrersCard = ({ data }: ReferrersCardProps) => {
 			utm_campaigns: "UTM Campaign",
 			utm_terms: "UTM Term",
 			utm_contents: "UTM Content",
+			utm_combinations: "UTM Combined",
 			ref_params: "Ref",
+			query_params: "Query Param",
 		};
 		return metricNames[metricType] || metricType;
 	};
@@ -42,16 +49,45 @@ export const ReferrersCard = ({ data }: ReferrersCardProps) => {
 			case "utm_campaigns":
 				return data.top_utm_campaigns || [];
 			case "utm_terms":
-				return data.top_utm_terms || [];
+				return data.utm_term_conversions || data.top_utm_terms || [];
 			case "utm_contents":
-				return data.top_utm_contents || [];
+				return data.utm_content_conversions || data.top_utm_contents || [];
+			case "utm_combinations":
+				return data.top_utm_combinations || [];
 			case "ref_params":
 				return data.top_ref_params || [];
+			case "query_params":
+				return data.top_query_params?.[selectedParam] || [];
 			default:
 				return data.top_referrers || [];
 		}
 	};
 
+	// Only referrers and UTM sources can scope the dashboard
+	const filterKeys: Partial<Record<MetricType, string>> = {
+		referrers: "referrer",
+		utm_sources: "utm_source",
+	};
+	const filterKey = filterKeys[selectedMetricType];
+
+	// Dashboard panel behind each metric type, for the unscoped segment note
+	const panels: Record<MetricType, string> = {
+		referrers: "top_referrers",
+		utm_sources: "top_utm_sources",
+		utm_mediums: "top_utm_mediums",
+		utm_campaigns: "top_utm_campaigns",
+		utm_terms: "top_utm_terms",
+		utm_contents: "top_utm_contents",
+		utm_combinations: "top_utm_combinations",
+		ref_params: "top_ref_params",
+		query_params: "top_query_params",
+	};
+
+	// Terms and contents also show the goal conversions attributed to them
+	const showConversions =
+		(selectedMetricType === "utm_terms" && !!data.utm_term_conversions) ||
+		(selectedMetricType === "utm_contents" && !!data.utm_content_conversions);
+
 	// Reset the filter when changing metric type
 	const handleMetricTypeChange = (metricType: MetricType): void => {
 		setSelectedMetricType(metricType);
@@ -143,6 +179,15 @@ export const ReferrersCard = ({ data }: ReferrersCardProps) => {
 										<Check className="h-4 w-4 ml-2" />
 									)}
 								</DropdownMenuItem>
+								<DropdownMenuItem
+									onClick={() => handleMetricTypeChange("utm_combinations")}
+									className="flex items-center justify-between"
+								>
+									<span className="truncate">Source / Medium / Campaign</span>
+									{selectedMetricType === "utm_combinations" && (
+										<Check className="h-4 w-4 ml-2" />
+									)}
+								</DropdownMenuItem>
 								<DropdownMenuItem
 									onClick={() => handleMetricTypeChange("ref_params")}
 									className="flex items-center justify-between"
@@ -152,6 +197,21 @@ export const ReferrersCard = ({ data }: ReferrersCardProps) => {
 										<Check className="h-4 w-4 ml-2" />
 									)}
 								</DropdownMenuItem>
+								{(data.track_params || []).map((param) => (
+									<DropdownMenuItem
+										key={param}
+										onClick={() => {
+											setSelectedParam(param);
+											handleMetricTypeChange("query_params");
+										}}
+										className="flex items-center justify-between"
+									>
+										<span className="truncate">?{param}</span>
+										{selectedMetricType === "query_params" && selectedParam === param && (
+											<Check className="h-4 w-4 ml-2" />
+										)}
+									</DropdownMenuItem>
+								))}
 							</DropdownMenuContent>
 						</DropdownMenu>
 					</div>
@@ -171,8 +231,13 @@ export const ReferrersCard = ({ data }: ReferrersCardProps) => {
 								),
 							},
 							{ name: "count", label: "Visitors" },
+							...(showConversions
+								? [{ name: "conversions", label: "Conv.", widthClass: "w-14" }]
+								: []),
 						]}
 						emptyMessage={`No ${getMetricDisplayName(selectedMetricType).toLowerCase()} data available.`}
+						onRowClick={onFilter && filterKey ? (item) => onFilter(filterKey, item.name) : undefined}
+						note={unscopedNote?.(panels[selectedMetricType])}
 					/>
 				</div>
 			</CardContent>
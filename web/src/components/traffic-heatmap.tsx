@@ -0,0 +1,54 @@
+import { Card, CardContent } from "@/components/ui/card";
+import { Clock } from "lucide-react";
+import { formatNumber } from "@/lib/utils";
+
+interface TrafficHeatmapProps {
+	// Visitor counts by weekday (Monday first) and hour of day, in the dashboard timezone
+	matrix: number[][];
+}
+
+const WEEKDAYS = ["Mon", "Tue", "Wed", "Thu", "Fri", "Sat", "Sun"];
+const HOURS = Array.from({ length: 24 }, (_, hour) => hour);
+
+export function TrafficHeatmap({ matrix }: TrafficHeatmapProps) {
+	const max = Math.max(0, ...matrix.flat());
+
+	return (
+		<Card className="rounded-lg border border-black">
+			<CardContent className="p-4 sm:p-6">
+				<div className="flex items-center gap-2 mb-4">
+					<Clock className="w-4 h-4" />
+					<span>Traffic by Hour</span>
+				</div>
+				<div className="overflow-x-auto">
+					<div className="grid gap-[2px] min-w-[640px]" style={{ gridTemplateColumns: "3rem repeat(24, minmax(0, 1fr))" }}>
+						<div />
+						{HOURS.map((hour) => (
+							<div key={hour} className="text-[10px] text-gray-500 text-center">
+								{hour % 3 === 0 ? hour : ""}
+							</div>
+						))}
+						{WEEKDAYS.map((day, dayIndex) => (
+							<div key={day} className="contents">
+								<div className="text-xs text-gray-500 flex items-center">{day}</div>
+								{HOURS.map((hour) => {
+									const count = matrix[dayIndex]?.[hour] ?? 0;
+									const opacity = max > 0 ? count / max : 0;
+									return (
+										<div
+											key={hour}
+											className="h-5 rounded-sm bg-gray-100"
+											title={`${day} ${hour}:00 — ${formatNumber(count)} visitors`}
+										>
+											<div className="h-full w-full rounded-sm bg-black" style={{ opacity }} />
+										</div>
+									);
+								})}
+							</div>
+						))}
+					</div>
+				</div>
+			</CardContent>
+		</Card>
+	);
+}
@@ -13,6 +13,8 @@ interface UserFlowLink {
 
 interface VisitorFlowSankeyProps {
 	links: UserFlowLink[];
+	/** Shown under the chart, e.g. when the segment doesn't apply */
+	note?: string;
 }
 
 interface SankeyNode {
@@ -60,7 +62,7 @@ const parseNodeId = (id: string): { step: number; page: string } => {
 	return { step: 0, page: id };
 };
 
-export const VisitorFlowSankey = ({ links }: VisitorFlowSankeyProps) => {
+export const VisitorFlowSankey = ({ links, note }: VisitorFlowSankeyProps) => {
 	const svgRef = useRef<SVGSVGElement>(null);
 	const containerRef = useRef<HTMLDivElement>(null);
 	const [zoom, setZoom] = useState(0.7);
@@ -418,6 +420,7 @@ export const VisitorFlowSankey = ({ links }: VisitorFlowSankeyProps) => {
 					<p className="text-gray-500">
 						No user flow data available for the selected time period.
 					</p>
+					{note && <p className="pt-2 text-xs text-gray-500">{note}</p>}
 				</CardContent>
 			</Card>
 		);
@@ -844,6 +847,7 @@ export const VisitorFlowSankey = ({ links }: VisitorFlowSankeyProps) => {
 						</div>
 					)}
 				</div>
+				{note && <p className="pt-2 text-xs text-gray-500">{note}</p>}
 			</CardContent>
 		</Card>
 	);
@@ -16,6 +16,7 @@ import { Home } from './pages/Home'
 import { Login } from './pages/Login'
 import Dashboard from './pages/Dashboard'
 import PublicDashboard from './pages/PublicDashboard'
+import SharedLinkPassword from './pages/SharedLinkPassword'
 import Websites from './pages/Websites'
 import WebsiteNew from './pages/WebsiteNew'
 import WebsiteSetup from './pages/WebsiteSetup'
@@ -36,6 +37,7 @@ const pages: Record<string, any> = {
   Login,
   Dashboard,
   PublicDashboard,
+  SharedLinkPassword,
   Websites,
   WebsiteNew,
   WebsiteSetup,
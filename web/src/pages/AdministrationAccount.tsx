@@ -1,6 +1,6 @@
 import { useEffect } from "react";
 import type { FC } from "react";
-import { usePage, useForm } from "@inertiajs/react";
+import { usePage, useForm, router } from "@inertiajs/react";
 import {
 	Card,
 	CardContent,
@@ -11,11 +11,13 @@ import {
 import { Button } from "@/components/ui/button";
 import { FlashMessageDisplay } from "@/components/ui/flash-message";
 import { Input } from "@/components/ui/input";
-import { Key } from "lucide-react";
+import { Key, Mail } from "lucide-react";
 import type { FlashMessage } from "@/types";
 import { AdministrationLayout } from "@/components/administration-layout";
 
 interface AdministrationAccountProps {
+	weekly_digest?: boolean;
+	smtp_configured?: boolean;
 	flash?: FlashMessage;
 	error?: string;
 	[key: string]: unknown;
@@ -24,7 +26,13 @@ interface AdministrationAccountProps {
 // Exported for Pro to wrap with its own layout
 export const AdministrationAccountContent: FC = () => {
 	const { props } = usePage<AdministrationAccountProps>();
-	const { flash, error } = props;
+	const { flash, error, weekly_digest, smtp_configured } = props;
+
+	const handleWeeklyDigestChange = (enabled: boolean) => {
+		router.post("/admin/account/weekly-digest", { weekly_digest: enabled.toString() }, {
+			preserveScroll: true,
+		});
+	};
 
 	// Password change form
 	const passwordForm = useForm({
@@ -67,7 +75,7 @@ export const AdministrationAccountContent: FC = () => {
 			<div>
 				<h1 className="text-2xl font-bold text-gray-900">Account Settings</h1>
 				<p className="text-gray-600 mt-1">
-					Manage your password
+					Manage your password and email preferences
 				</p>
 			</div>
 
@@ -153,6 +161,34 @@ export const AdministrationAccountContent: FC = () => {
 					</form>
 				</CardContent>
 			</Card>
+
+			{/* Weekly Digest Section */}
+			<Card className="border-black shadow-sm">
+				<CardHeader className="pb-4">
+					<CardTitle className="text-lg flex items-center gap-2">
+						<Mail className="h-5 w-5" /> Weekly Digest
+					</CardTitle>
+					<CardDescription>
+						A weekly email with last week's visitors, top pages and revenue for every website.
+					</CardDescription>
+				</CardHeader>
+				<CardContent className="space-y-2">
+					<label className="flex items-center gap-2 text-sm font-medium text-gray-700">
+						<input
+							type="checkbox"
+							checked={!!weekly_digest}
+							onChange={(e) => handleWeeklyDigestChange(e.target.checked)}
+							className="h-4 w-4 rounded border-gray-300"
+						/>
+						Email me the weekly digest
+					</label>
+					{!smtp_configured && (
+						<p className="text-xs text-gray-500">
+							Emails are only sent once SMTP is configured with the FUSIONALY_SMTP_* environment variables.
+						</p>
+					)}
+				</CardContent>
+			</Card>
 		</div>
 	);
 };
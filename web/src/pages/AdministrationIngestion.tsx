@@ -11,6 +11,8 @@ import {
 } from "@/components/ui/card";
 import { Button } from "@/components/ui/button";
 import { FlashMessageDisplay } from "@/components/ui/flash-message";
+import { Checkbox } from "@/components/ui/checkbox";
+import { Input } from "@/components/ui/input";
 import { Textarea } from "@/components/ui/textarea";
 import { Info, ExternalLink, Filter } from "lucide-react";
 import type { FlashMessage } from "@/types";
@@ -39,9 +41,52 @@ export const AdministrationIngestionContent: FC = () => {
 	const excludedIPsSetting = settings?.find((s) => s.key === "excluded_ips");
 	const initialExcludedIPs = excludedIPsSetting?.value || "";
 
+	const sampleRateSetting = settings?.find((s) => s.key === "raw_event_sample_rate");
+	const initialSamplePercent = String(
+		Math.round(Number.parseFloat(sampleRateSetting?.value || "1") * 100),
+	);
+
+	const dedupeWindowSetting = settings?.find((s) => s.key === "dedupe_window_minutes");
+	const maxEventMetaBytesSetting = settings?.find((s) => s.key === "max_event_meta_bytes");
+	const truncateEventMetaSetting = settings?.find((s) => s.key === "truncate_event_meta");
+	const maxTimestampSkewSetting = settings?.find((s) => s.key === "max_timestamp_skew");
+	const maxTimestampAgeSetting = settings?.find((s) => s.key === "max_timestamp_age_days");
+	const useSDKUserIDSetting = settings?.find((s) => s.key === "use_sdk_user_id");
+	const excludedPathsSetting = settings?.find((s) => s.key === "excluded_paths");
+	const pathPatternsSetting = settings?.find((s) => s.key === "path_patterns");
+	const trackedQueryParamsSetting = settings?.find((s) => s.key === "tracked_query_params");
+	const respectDNTSetting = settings?.find((s) => s.key === "respect_dnt");
+	const filterBotsSetting = settings?.find((s) => s.key === "filter_bots");
+	const botPatternsSetting = settings?.find((s) => s.key === "bot_patterns");
+	const filterRefSpamSetting = settings?.find((s) => s.key === "filter_referrer_spam");
+	const refSpamDomainsSetting = settings?.find((s) => s.key === "referrer_spam_domains");
+	const separateSelfReferralsSetting = settings?.find((s) => s.key === "separate_self_referrals");
+	const normalizeUTMCaseSetting = settings?.find((s) => s.key === "normalize_utm_case");
+	const pathCaseInsensitiveSetting = settings?.find((s) => s.key === "path_case_insensitive");
+	const pathStripTrailingSlashSetting = settings?.find((s) => s.key === "path_strip_trailing_slash");
+
 	// Form for updating ingestion settings
 	const form = useForm({
 		excluded_ips: initialExcludedIPs,
+		excluded_paths: excludedPathsSetting?.value || "",
+		path_patterns: pathPatternsSetting?.value || "",
+		tracked_query_params: trackedQueryParamsSetting?.value || "",
+		raw_event_sample_percent: initialSamplePercent,
+		dedupe_window_minutes: dedupeWindowSetting?.value || "60",
+		max_event_meta_bytes: maxEventMetaBytesSetting?.value || "8192",
+		truncate_event_meta: truncateEventMetaSetting?.value === "true",
+		max_timestamp_skew: maxTimestampSkewSetting?.value || "60",
+		max_timestamp_age_days: maxTimestampAgeSetting?.value || "0",
+		use_sdk_user_id: useSDKUserIDSetting?.value === "true",
+		respect_dnt: respectDNTSetting?.value === "true",
+		filter_bots: filterBotsSetting?.value !== "false",
+		bot_patterns: botPatternsSetting?.value || "",
+		filter_referrer_spam: filterRefSpamSetting?.value !== "false",
+		referrer_spam_domains: refSpamDomainsSetting?.value || "",
+		separate_self_referrals: separateSelfReferralsSetting?.value === "true",
+		normalize_utm_case: normalizeUTMCaseSetting?.value !== "false",
+		path_case_insensitive: pathCaseInsensitiveSetting?.value === "true",
+		path_strip_trailing_slash: pathStripTrailingSlashSetting?.value === "true",
 	});
 
 	const addIPToExcluded = (ip: string) => {
@@ -165,6 +210,412 @@ export const AdministrationIngestionContent: FC = () => {
 								<p className="text-sm text-red-600 mt-1">{form.errors.excluded_ips}</p>
 							)}
 						</div>
+						<div>
+							<label
+								htmlFor="excluded_paths"
+								className="block text-sm font-medium mb-1.5"
+							>
+								Excluded Paths
+							</label>
+							<Textarea
+								id="excluded_paths"
+								name="excluded_paths"
+								placeholder="e.g., /wp-admin, /preview/*"
+								value={form.data.excluded_paths}
+								onChange={(e) => form.setData("excluded_paths", e.target.value)}
+								disabled={form.processing}
+								className="h-20 w-full resize-y border-gray-300 focus:border-black focus:ring-black rounded-md"
+							/>
+							<p className="text-xs text-gray-500 mt-1.5">
+								Separate entries with commas. A path also excludes everything below
+								it; use * as a wildcard.
+							</p>
+						</div>
+						<div>
+							<label
+								htmlFor="path_patterns"
+								className="block text-sm font-medium mb-1.5"
+							>
+								Path Patterns
+							</label>
+							<Textarea
+								id="path_patterns"
+								name="path_patterns"
+								placeholder={"e.g., ^/users/\\d+ => /users/:id"}
+								value={form.data.path_patterns}
+								onChange={(e) => form.setData("path_patterns", e.target.value)}
+								disabled={form.processing}
+								className="h-20 w-full resize-y border-gray-300 focus:border-black focus:ring-black rounded-md"
+							/>
+							<p className="text-xs text-gray-500 mt-1.5">
+								One rule per line, written as regex =&gt; replacement. Rules are
+								applied in order so dynamic routes like /users/123 are counted as
+								one page. Leave empty to keep paths as visited.
+							</p>
+						</div>
+						<div>
+							<label
+								htmlFor="tracked_query_params"
+								className="block text-sm font-medium mb-1.5"
+							>
+								Tracked Query Parameters
+							</label>
+							<Input
+								id="tracked_query_params"
+								name="tracked_query_params"
+								placeholder="e.g., ref, page"
+								value={form.data.tracked_query_params}
+								onChange={(e) => form.setData("tracked_query_params", e.target.value)}
+								disabled={form.processing}
+							/>
+							<p className="text-xs text-gray-500 mt-1.5">
+								When set, all other query parameters (fbclid, gclid, session IDs...)
+								are stripped. UTM parameters, ref and the parameters a website's
+								dashboard breaks down are always kept. Leave empty to keep
+								everything.
+							</p>
+						</div>
+						<div>
+							<label
+								htmlFor="raw_event_sample_percent"
+								className="block text-sm font-medium mb-1.5"
+							>
+								Raw Event Retention (%)
+							</label>
+							<Input
+								id="raw_event_sample_percent"
+								name="raw_event_sample_percent"
+								type="number"
+								min={0}
+								max={100}
+								value={form.data.raw_event_sample_percent}
+								onChange={(e) =>
+									form.setData("raw_event_sample_percent", e.target.value)
+								}
+								disabled={form.processing}
+								className="w-32 border-gray-300 focus:border-black focus:ring-black rounded-md"
+							/>
+							<p className="text-xs text-gray-500 mt-1.5">
+								Dashboard totals always count every event. Page views older than a
+								day are kept at this rate to reduce storage, so visit duration,
+								funnels, user flows, entry/exit pairs, retention cohorts, revenue by
+								referrer and UTM source, UTM term and content conversions, subdomain
+								and property breakdowns, numeric event meta and the daily visitors
+								webhook only see the kept ones.
+							</p>
+						</div>
+						<div>
+							<label
+								htmlFor="dedupe_window_minutes"
+								className="block text-sm font-medium mb-1.5"
+							>
+								Duplicate Event Window (minutes)
+							</label>
+							<Input
+								id="dedupe_window_minutes"
+								name="dedupe_window_minutes"
+								type="number"
+								min={0}
+								value={form.data.dedupe_window_minutes}
+								onChange={(e) =>
+									form.setData("dedupe_window_minutes", e.target.value)
+								}
+								disabled={form.processing}
+								className="w-32 border-gray-300 focus:border-black focus:ring-black rounded-md"
+							/>
+							<p className="text-xs text-gray-500 mt-1.5">
+								Events resent with the same <code>event_id</code> within this
+								window are ignored. Set to 0 to disable deduplication.
+							</p>
+						</div>
+						<div>
+							<label
+								htmlFor="max_timestamp_skew"
+								className="block text-sm font-medium mb-1.5"
+							>
+								Future Timestamp Skew (minutes)
+							</label>
+							<Input
+								id="max_timestamp_skew"
+								name="max_timestamp_skew"
+								type="number"
+								min={0}
+								value={form.data.max_timestamp_skew}
+								onChange={(e) =>
+									form.setData("max_timestamp_skew", e.target.value)
+								}
+								disabled={form.processing}
+								className="w-32 border-gray-300 focus:border-black focus:ring-black rounded-md"
+							/>
+							<p className="text-xs text-gray-500 mt-1.5">
+								Events dated further ahead of the server clock are rejected.
+								Set to 0 to accept any future timestamp.
+							</p>
+						</div>
+						<div>
+							<label
+								htmlFor="max_timestamp_age_days"
+								className="block text-sm font-medium mb-1.5"
+							>
+								Maximum Event Age (days)
+							</label>
+							<Input
+								id="max_timestamp_age_days"
+								name="max_timestamp_age_days"
+								type="number"
+								min={0}
+								value={form.data.max_timestamp_age_days}
+								onChange={(e) =>
+									form.setData("max_timestamp_age_days", e.target.value)
+								}
+								disabled={form.processing}
+								className="w-32 border-gray-300 focus:border-black focus:ring-black rounded-md"
+							/>
+							<p className="text-xs text-gray-500 mt-1.5">
+								Backdated events older than this are rejected, imports
+								included. Set to 0 to accept any age.
+							</p>
+						</div>
+						<div>
+							<label
+								htmlFor="max_event_meta_bytes"
+								className="block text-sm font-medium mb-1.5"
+							>
+								Maximum Event Metadata Size (bytes)
+							</label>
+							<Input
+								id="max_event_meta_bytes"
+								name="max_event_meta_bytes"
+								type="number"
+								min={0}
+								value={form.data.max_event_meta_bytes}
+								onChange={(e) =>
+									form.setData("max_event_meta_bytes", e.target.value)
+								}
+								disabled={form.processing}
+								className="w-32 border-gray-300 focus:border-black focus:ring-black rounded-md"
+							/>
+							<p className="text-xs text-gray-500 mt-1.5">
+								Custom events with larger metadata are rejected with a 413
+								error. Set to 0 to disable the limit.
+							</p>
+						</div>
+						<div className="flex items-start gap-3">
+							<Checkbox
+								id="truncate_event_meta"
+								checked={form.data.truncate_event_meta}
+								onCheckedChange={(checked) =>
+									form.setData("truncate_event_meta", checked === true)
+								}
+								disabled={form.processing}
+								className="mt-0.5"
+							/>
+							<div>
+								<label htmlFor="truncate_event_meta" className="text-sm font-medium">
+									Truncate oversized metadata
+								</label>
+								<p className="text-xs text-gray-500 mt-1">
+									Keep the event and store only the metadata keys that fit,
+									flagged with <code>_truncated: true</code>, instead of
+									rejecting it.
+								</p>
+							</div>
+						</div>
+						<div className="flex items-start gap-3">
+							<Checkbox
+								id="use_sdk_user_id"
+								checked={form.data.use_sdk_user_id}
+								onCheckedChange={(checked) =>
+									form.setData("use_sdk_user_id", checked === true)
+								}
+								disabled={form.processing}
+								className="mt-0.5"
+							/>
+							<div>
+								<label htmlFor="use_sdk_user_id" className="text-sm font-medium">
+									Merge visitors by user ID
+								</label>
+								<p className="text-xs text-gray-500 mt-1">
+									When your site calls <code>Fusionaly.setUser</code>, a hashed
+									version of that ID identifies the visitor across devices and
+									sessions. Off by default for privacy.
+								</p>
+							</div>
+						</div>
+						<div className="flex items-start gap-3">
+							<Checkbox
+								id="respect_dnt"
+								checked={form.data.respect_dnt}
+								onCheckedChange={(checked) =>
+									form.setData("respect_dnt", checked === true)
+								}
+								disabled={form.processing}
+								className="mt-0.5"
+							/>
+							<div>
+								<label htmlFor="respect_dnt" className="text-sm font-medium">
+									Respect Do Not Track
+								</label>
+								<p className="text-xs text-gray-500 mt-1">
+									Skip recording hits from browsers that send the{" "}
+									<code>DNT: 1</code> header.
+								</p>
+							</div>
+						</div>
+						<div className="flex items-start gap-3">
+							<Checkbox
+								id="filter_bots"
+								checked={form.data.filter_bots}
+								onCheckedChange={(checked) =>
+									form.setData("filter_bots", checked === true)
+								}
+								disabled={form.processing}
+								className="mt-0.5"
+							/>
+							<div>
+								<label htmlFor="filter_bots" className="text-sm font-medium">
+									Filter bots and crawlers
+								</label>
+								<p className="text-xs text-gray-500 mt-1">
+									Drops hits from known bots (Googlebot, AhrefsBot, uptime
+									checkers...) before they are stored.
+								</p>
+							</div>
+						</div>
+						<div className="space-y-2">
+							<label htmlFor="bot_patterns" className="text-sm font-medium">
+								Additional bot patterns
+							</label>
+							<Textarea
+								id="bot_patterns"
+								name="bot_patterns"
+								placeholder={"MyUptimeChecker\ninternal-monitor/[0-9]+"}
+								value={form.data.bot_patterns}
+								onChange={(e) => form.setData("bot_patterns", e.target.value)}
+								disabled={form.processing}
+								rows={3}
+							/>
+							<p className="text-xs text-gray-500">
+								One pattern per line, matched case-insensitively against the user
+								agent. Regular expressions are supported.
+							</p>
+						</div>
+						<div className="flex items-start gap-3">
+							<Checkbox
+								id="filter_referrer_spam"
+								checked={form.data.filter_referrer_spam}
+								onCheckedChange={(checked) =>
+									form.setData("filter_referrer_spam", checked === true)
+								}
+								disabled={form.processing}
+								className="mt-0.5"
+							/>
+							<div>
+								<label htmlFor="filter_referrer_spam" className="text-sm font-medium">
+									Filter referrer spam
+								</label>
+								<p className="text-xs text-gray-500 mt-1">
+									Records visits from known spam referrers (semalt.com,
+									buttons-for-website.com...) as direct traffic.
+								</p>
+							</div>
+						</div>
+						<div className="space-y-2">
+							<label htmlFor="referrer_spam_domains" className="text-sm font-medium">
+								Additional spam domains
+							</label>
+							<Textarea
+								id="referrer_spam_domains"
+								name="referrer_spam_domains"
+								placeholder={"spammy-seo.com\nfree-traffic.xyz"}
+								value={form.data.referrer_spam_domains}
+								onChange={(e) => form.setData("referrer_spam_domains", e.target.value)}
+								disabled={form.processing}
+								rows={3}
+							/>
+							<p className="text-xs text-gray-500">
+								One domain per line. Subdomains are matched too.
+							</p>
+						</div>
+						<div className="flex items-start gap-3">
+							<Checkbox
+								id="separate_self_referrals"
+								checked={form.data.separate_self_referrals}
+								onCheckedChange={(checked) =>
+									form.setData("separate_self_referrals", checked === true)
+								}
+								disabled={form.processing}
+								className="mt-0.5"
+							/>
+							<div>
+								<label htmlFor="separate_self_referrals" className="text-sm font-medium">
+									Count self-referrals separately
+								</label>
+								<p className="text-xs text-gray-500 mt-1">
+									Shows visits referred by your own domain (e.g. from an untracked
+									page) as "Self-referral" instead of direct traffic. Applies to new
+									events only.
+								</p>
+							</div>
+						</div>
+						<div className="flex items-start gap-3">
+							<Checkbox
+								id="normalize_utm_case"
+								checked={form.data.normalize_utm_case}
+								onCheckedChange={(checked) =>
+									form.setData("normalize_utm_case", checked === true)
+								}
+								disabled={form.processing}
+								className="mt-0.5"
+							/>
+							<div>
+								<label htmlFor="normalize_utm_case" className="text-sm font-medium">
+									Normalize UTM values
+								</label>
+								<p className="text-xs text-gray-500 mt-1">
+									Trims and lowercases UTM source, medium, campaign, term and content,
+									so "Google" and "google" count as one source.
+								</p>
+							</div>
+						</div>
+						<div className="flex items-start gap-3">
+							<Checkbox
+								id="path_case_insensitive"
+								checked={form.data.path_case_insensitive}
+								onCheckedChange={(checked) =>
+									form.setData("path_case_insensitive", checked === true)
+								}
+								disabled={form.processing}
+								className="mt-0.5"
+							/>
+							<div>
+								<label htmlFor="path_case_insensitive" className="text-sm font-medium">
+									Case-insensitive paths
+								</label>
+								<p className="text-xs text-gray-500 mt-1">
+									Lowercases paths so /About and /about count as one page.
+								</p>
+							</div>
+						</div>
+						<div className="flex items-start gap-3">
+							<Checkbox
+								id="path_strip_trailing_slash"
+								checked={form.data.path_strip_trailing_slash}
+								onCheckedChange={(checked) =>
+									form.setData("path_strip_trailing_slash", checked === true)
+								}
+								disabled={form.processing}
+								className="mt-0.5"
+							/>
+							<div>
+								<label htmlFor="path_strip_trailing_slash" className="text-sm font-medium">
+									Ignore trailing slashes
+								</label>
+								<p className="text-xs text-gray-500 mt-1">
+									Counts /about/ and /about as one page. The home page stays /.
+								</p>
+							</div>
+						</div>
 					</CardContent>
 					<CardFooter className="flex justify-end border-t pt-4">
 						<Button
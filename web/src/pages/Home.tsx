@@ -21,6 +21,7 @@ import {
   HelpCircle,
 } from "lucide-react";
 import { Button } from "@/components/ui/button";
+import { Input } from "@/components/ui/input";
 import {
   Dialog,
   DialogContent,
@@ -410,6 +411,7 @@ export const Home = () => {
   const totalVisitors = props.totalVisitors || 0;
 
   const [websiteToDelete, setWebsiteToDelete] = useState<Website | null>(null);
+  const [deleteConfirmation, setDeleteConfirmation] = useState("");
   const [showIntegrationHelp, setShowIntegrationHelp] = useState(false);
   const [selectedWebsiteForIntegration, setSelectedWebsiteForIntegration] = useState<Website | null>(null);
   const [copiedScript, setCopiedScript] = useState(false);
@@ -431,9 +433,12 @@ export const Home = () => {
     if (!websiteToDelete) return;
     router.post(
       `/admin/websites/${websiteToDelete.id}/delete`,
-      {},
+      { confirm: deleteConfirmation },
       {
-        onSuccess: () => setWebsiteToDelete(null),
+        onSuccess: () => {
+          setWebsiteToDelete(null);
+          setDeleteConfirmation("");
+        },
       }
     );
   };
@@ -595,7 +600,12 @@ export const Home = () => {
       {/* Delete Dialog */}
       <Dialog
         open={websiteToDelete !== null}
-        onOpenChange={(open) => !open && setWebsiteToDelete(null)}
+        onOpenChange={(open) => {
+          if (!open) {
+            setWebsiteToDelete(null);
+            setDeleteConfirmation("");
+          }
+        }}
       >
         <DialogContent>
           <DialogHeader>
@@ -605,11 +615,33 @@ export const Home = () => {
               undone.
             </DialogDescription>
           </DialogHeader>
+          <div className="space-y-1.5">
+            <label htmlFor="delete_confirmation" className="text-sm text-gray-700">
+              Type <span className="font-mono font-medium">{websiteToDelete?.domain}</span> to
+              confirm
+            </label>
+            <Input
+              id="delete_confirmation"
+              value={deleteConfirmation}
+              onChange={(e) => setDeleteConfirmation(e.target.value)}
+              autoComplete="off"
+            />
+          </div>
           <DialogFooter>
-            <Button variant="outline" onClick={() => setWebsiteToDelete(null)}>
+            <Button
+              variant="outline"
+              onClick={() => {
+                setWebsiteToDelete(null);
+                setDeleteConfirmation("");
+              }}
+            >
               Cancel
             </Button>
-            <Button variant="destructive" onClick={handleDeleteWebsite}>
+            <Button
+              variant="destructive"
+              onClick={handleDeleteWebsite}
+              disabled={deleteConfirmation.trim().toLowerCase() !== websiteToDelete?.domain.toLowerCase()}
+            >
               Delete
             </Button>
           </DialogFooter>
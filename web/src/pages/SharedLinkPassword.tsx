@@ -0,0 +1,61 @@
+import { usePage, useForm } from "@inertiajs/react";
+import { Button } from "@/components/ui/button";
+import {
+  Card,
+  CardContent,
+  CardDescription,
+  CardHeader,
+  CardTitle,
+} from "@/components/ui/card";
+import { Input } from "@/components/ui/input";
+import { Label } from "@/components/ui/label";
+
+interface SharedLinkPasswordProps {
+  token: string;
+  invalid: boolean;
+  [key: string]: any;
+}
+
+export default function SharedLinkPassword() {
+  const { props } = usePage<SharedLinkPasswordProps>();
+  const form = useForm({ password: "" });
+
+  const handleSubmit = (e: React.FormEvent) => {
+    e.preventDefault();
+    form.post(`/share/${props.token}/unlock`);
+  };
+
+  return (
+    <div className="min-h-screen bg-white flex items-center justify-center px-4">
+      <Card className="w-full max-w-sm">
+        <CardHeader>
+          <CardTitle className="text-xl">Password required</CardTitle>
+          <CardDescription>
+            This shared dashboard is password protected.
+          </CardDescription>
+        </CardHeader>
+        <CardContent>
+          <form onSubmit={handleSubmit} className="flex flex-col gap-4">
+            <div className="grid gap-2">
+              <Label htmlFor="password">Password</Label>
+              <Input
+                id="password"
+                name="password"
+                type="password"
+                autoFocus
+                value={form.data.password}
+                onChange={(e) => form.setData("password", e.target.value)}
+              />
+              {props.invalid && (
+                <p className="text-sm text-red-600">Incorrect password.</p>
+              )}
+            </div>
+            <Button type="submit" disabled={form.processing || !form.data.password}>
+              View dashboard
+            </Button>
+          </form>
+        </CardContent>
+      </Card>
+    </div>
+  );
+}
@@ -2,7 +2,7 @@ import React from 'react';
 import { usePage, useForm, router } from '@inertiajs/react';
 import { PageHeader } from '@/components/ui/page-header';
 import { FlashMessageDisplay } from '@/components/ui/flash-message';
-import { Settings, Info } from 'lucide-react';
+import { Settings, Info, KeyRound, Webhook as WebhookIcon, Link as LinkIcon, BellRing, Pause, Play } from 'lucide-react';
 import type { FlashMessage } from '@/types';
 import { AdminLayout } from "@/components/admin-layout";
 
@@ -13,6 +13,7 @@ interface Website {
   conversion_goals?: string[];
   subdomain_tracking_enabled?: boolean;
   privacy_mode?: string;
+  paused?: boolean;
 }
 
 interface Event {
@@ -21,12 +22,60 @@ interface Event {
   website_id: number;
 }
 
+interface ApiToken {
+  id: number;
+  name: string;
+  prefix: string;
+  last_used_at: string | null;
+  created_at: string;
+}
+
+interface Webhook {
+  id: number;
+  url: string;
+  trigger: 'goal' | 'daily_visitors';
+  event_name: string;
+  threshold: number;
+  last_fired_at: string | null;
+  created_at: string;
+}
+
+interface SharedLink {
+  id: number;
+  token: string;
+  expires_at: string | null;
+  allowed_metrics: string;
+  created_at: string;
+}
+
+interface AlertRule {
+  id: number;
+  name: string;
+  metric: 'visitors' | 'page_views' | 'sessions' | 'revenue';
+  operator: 'lt' | 'lte' | 'gt' | 'gte';
+  threshold: number;
+  baseline: 'absolute' | 'rolling_average';
+  baseline_days: number;
+  channel: 'email' | 'webhook';
+  target: string;
+  cooldown_minutes: number;
+  last_fired_at: string | null;
+  created_at: string;
+}
+
 interface WebsiteEditProps {
   title: string;
   website: Website;
   all_distinct_events: Event[];
   conversion_goals: string[];
   subdomain_tracking_enabled: boolean;
+  subdomain_rollup_enabled: boolean;
+  session_timeout_minutes: number;
+  track_params: string;
+  api_tokens: ApiToken[];
+  webhooks: Webhook[];
+  shared_links: SharedLink[];
+  alert_rules: AlertRule[];
   flash?: FlashMessage;
   error?: string;
   [key: string]: any;
@@ -147,6 +196,13 @@ const WebsiteEdit: React.FC = () => {
     all_distinct_events,
     conversion_goals,
     subdomain_tracking_enabled,
+    subdomain_rollup_enabled,
+    session_timeout_minutes,
+    track_params,
+    api_tokens,
+    webhooks,
+    shared_links,
+    alert_rules,
     flash,
     error
   } = props;
@@ -160,6 +216,140 @@ const WebsiteEdit: React.FC = () => {
   const [subdomainTrackingEnabled, setSubdomainTrackingEnabled] = React.useState<boolean>(
     subdomain_tracking_enabled || false
   );
+  const [subdomainRollupEnabled, setSubdomainRollupEnabled] = React.useState<boolean>(
+    subdomain_rollup_enabled || false
+  );
+  const [trackParams, setTrackParams] = React.useState<string>(track_params || '');
+  const [domain, setDomain] = React.useState<string>(website?.domain || '');
+  const [sessionTimeoutMinutes, setSessionTimeoutMinutes] = React.useState<string>(
+    session_timeout_minutes ? session_timeout_minutes.toString() : ''
+  );
+
+  const handleTogglePaused = () => {
+    if (!website.paused && !confirm(`Pause data collection for ${website.domain}? Events sent while paused are dropped.`)) {
+      return;
+    }
+    router.post(`/admin/websites/${website.id}/${website.paused ? 'resume' : 'pause'}`);
+  };
+
+  const [apiTokenName, setApiTokenName] = React.useState<string>('');
+
+  const handleCreateApiToken = (e: React.FormEvent<HTMLFormElement>) => {
+    e.preventDefault();
+    router.post(`/admin/websites/${website.id}/api-tokens`, { name: apiTokenName }, {
+      onSuccess: () => setApiTokenName(''),
+    });
+  };
+
+  const handleRevokeApiToken = (tokenId: number) => {
+    if (!confirm('Revoke this API token? Integrations using it will stop working.')) {
+      return;
+    }
+    router.post(`/admin/websites/${website.id}/api-tokens/${tokenId}/revoke`);
+  };
+
+  const [webhookUrl, setWebhookUrl] = React.useState<string>('');
+  const [webhookTrigger, setWebhookTrigger] = React.useState<Webhook['trigger']>('goal');
+  const [webhookEventName, setWebhookEventName] = React.useState<string>('');
+  const [webhookThreshold, setWebhookThreshold] = React.useState<string>('');
+
+  const handleCreateWebhook = (e: React.FormEvent<HTMLFormElement>) => {
+    e.preventDefault();
+    router.post(`/admin/websites/${website.id}/webhooks`, {
+      url: webhookUrl,
+      trigger: webhookTrigger,
+      event_name: webhookEventName,
+      threshold: webhookThreshold,
+    }, {
+      onSuccess: () => {
+        setWebhookUrl('');
+        setWebhookEventName('');
+        setWebhookThreshold('');
+      },
+    });
+  };
+
+  const handleDeleteWebhook = (webhookId: number) => {
+    if (!confirm('Delete this webhook? Its URL will stop receiving notifications.')) {
+      return;
+    }
+    router.post(`/admin/websites/${website.id}/webhooks/${webhookId}/delete`);
+  };
+
+  const describeWebhook = (hook: Webhook) =>
+    hook.trigger === 'daily_visitors'
+      ? `Daily visitors reach ${hook.threshold.toLocaleString()}`
+      : hook.event_name ? `Goal "${hook.event_name}" completed` : 'Any conversion goal completed';
+
+  const [sharedLinkPassword, setSharedLinkPassword] = React.useState<string>('');
+  const [sharedLinkExpiresAt, setSharedLinkExpiresAt] = React.useState<string>('');
+  const [sharedLinkMetrics, setSharedLinkMetrics] = React.useState<string>('');
+
+  const handleCreateSharedLink = (e: React.FormEvent<HTMLFormElement>) => {
+    e.preventDefault();
+    router.post(`/admin/websites/${website.id}/shared-links`, {
+      password: sharedLinkPassword,
+      expires_at: sharedLinkExpiresAt,
+      metrics: sharedLinkMetrics,
+    }, {
+      onSuccess: () => {
+        setSharedLinkPassword('');
+        setSharedLinkExpiresAt('');
+        setSharedLinkMetrics('');
+      },
+    });
+  };
+
+  const handleDeleteSharedLink = (linkId: number) => {
+    if (!confirm('Revoke this shared link? Anyone using it will lose access.')) {
+      return;
+    }
+    router.post(`/admin/websites/${website.id}/shared-links/${linkId}/delete`);
+  };
+
+  const [alertName, setAlertName] = React.useState<string>('');
+  const [alertMetric, setAlertMetric] = React.useState<AlertRule['metric']>('visitors');
+  const [alertOperator, setAlertOperator] = React.useState<AlertRule['operator']>('lt');
+  const [alertThreshold, setAlertThreshold] = React.useState<string>('');
+  const [alertBaseline, setAlertBaseline] = React.useState<AlertRule['baseline']>('rolling_average');
+  const [alertChannel, setAlertChannel] = React.useState<AlertRule['channel']>('email');
+  const [alertTarget, setAlertTarget] = React.useState<string>('');
+  const [alertCooldown, setAlertCooldown] = React.useState<string>('360');
+
+  const handleCreateAlertRule = (e: React.FormEvent<HTMLFormElement>) => {
+    e.preventDefault();
+    router.post(`/admin/websites/${website.id}/alerts`, {
+      name: alertName,
+      metric: alertMetric,
+      operator: alertOperator,
+      threshold: alertThreshold,
+      baseline: alertBaseline,
+      channel: alertChannel,
+      target: alertTarget,
+      cooldown_minutes: alertCooldown,
+    }, {
+      onSuccess: () => {
+        setAlertName('');
+        setAlertThreshold('');
+        setAlertTarget('');
+      },
+    });
+  };
+
+  const handleDeleteAlertRule = (ruleId: number) => {
+    if (!confirm('Delete this alert?')) {
+      return;
+    }
+    router.post(`/admin/websites/${website.id}/alerts/${ruleId}/delete`);
+  };
+
+  const describeAlertRule = (rule: AlertRule) => {
+    const symbols = { lt: '<', lte: '≤', gt: '>', gte: '≥' };
+    const metric = rule.metric.replace('_', ' ');
+    return rule.baseline === 'rolling_average'
+      ? `Today's ${metric} ${symbols[rule.operator]} ${rule.threshold}% of the ${rule.baseline_days}-day average`
+      : `Today's ${metric} ${symbols[rule.operator]} ${rule.threshold.toLocaleString()}`;
+  };
 
   const handleSubmit = (e: React.FormEvent<HTMLFormElement>) => {
     e.preventDefault();
@@ -171,6 +361,10 @@ const WebsiteEdit: React.FC = () => {
     form.transform(() => ({
       conversion_goals: JSON.stringify(cleanedGoals),
       subdomain_tracking_enabled: subdomainTrackingEnabled.toString(),
+      subdomain_rollup_enabled: (subdomainTrackingEnabled && subdomainRollupEnabled).toString(),
+      session_timeout_minutes: sessionTimeoutMinutes,
+      track_params: trackParams,
+      domain,
     }));
     form.post(`/admin/websites/${website.id}`);
   };
@@ -253,13 +447,51 @@ const WebsiteEdit: React.FC = () => {
                       type="text"
                       name="domain"
                       id="domain"
-                      value={website.domain}
-                      readOnly
-                      className="block w-full px-3 py-2 border border-gray-300 rounded-md shadow-sm bg-gray-50 text-gray-500 cursor-not-allowed sm:text-sm"
+                      value={domain}
+                      onChange={(e) => setDomain(e.target.value)}
+                      className="block w-full px-3 py-2 border border-gray-300 rounded-md shadow-sm sm:text-sm"
                       placeholder="example.com"
                     />
                     <p className="mt-1 text-xs text-gray-500">
-                      Domain cannot be changed after website creation to preserve analytics data integrity.
+                      Moving to a new domain keeps your history: past pages and referrers of{' '}
+                      {website.domain} are moved to the new domain. Update the tracking script on the
+                      new site; hits from the old domain are no longer recorded.
+                    </p>
+                  </div>
+                  <div>
+                    <label htmlFor="session_timeout_minutes" className="block text-sm font-medium text-gray-700 mb-1">
+                      Session timeout (minutes)
+                    </label>
+                    <input
+                      type="number"
+                      name="session_timeout_minutes"
+                      id="session_timeout_minutes"
+                      min={1}
+                      max={360}
+                      value={sessionTimeoutMinutes}
+                      onChange={(e) => setSessionTimeoutMinutes(e.target.value)}
+                      className="block w-full px-3 py-2 border border-gray-300 rounded-md shadow-sm sm:text-sm"
+                      placeholder="30"
+                    />
+                    <p className="mt-1 text-xs text-gray-500">
+                      Inactivity gap after which a visit counts as a new session (1–360). Leave empty for the default of 30 minutes. Only affects newly processed events.
+                    </p>
+                  </div>
+                  <div>
+                    <label htmlFor="track_params" className="block text-sm font-medium text-gray-700 mb-1">
+                      Query parameters to break down
+                    </label>
+                    <input
+                      type="text"
+                      name="track_params"
+                      id="track_params"
+                      value={trackParams}
+                      onChange={(e) => setTrackParams(e.target.value)}
+                      className="block w-full px-3 py-2 border border-gray-300 rounded-md shadow-sm sm:text-sm"
+                      placeholder="variant, plan"
+                    />
+                    <p className="mt-1 text-xs text-gray-500">
+                      Comma-separated, up to 10. Each gets its own breakdown in the dashboard's referrers card, next to ref.
                     </p>
                   </div>
                 </div>
@@ -459,6 +691,25 @@ const WebsiteEdit: React.FC = () => {
                       <div className="w-11 h-6 bg-gray-200 peer-focus:outline-none peer-focus:ring-4 peer-focus:ring-gray-300 rounded-full peer peer-checked:after:translate-x-full peer-checked:after:border-white after:content-[''] after:absolute after:top-[2px] after:left-[2px] after:bg-white after:border-gray-300 after:border after:rounded-full after:h-5 after:w-5 after:transition-all peer-checked:bg-black"></div>
                     </label>
                   </div>
+                  {subdomainTrackingEnabled && (
+                    <div className="flex items-center justify-between mt-4 pt-4 border-t border-gray-200">
+                      <div>
+                        <h3 className="font-medium">Roll up subdomains</h3>
+                        <p className="text-sm text-gray-500">
+                          Store pages of *.{website.domain} under {website.domain}, so /pricing on blog.{website.domain} and app.{website.domain} count as one page. Only affects newly collected events.
+                        </p>
+                      </div>
+                      <label className="relative inline-flex items-center cursor-pointer">
+                        <input
+                          type="checkbox"
+                          className="sr-only peer"
+                          checked={subdomainRollupEnabled}
+                          onChange={(e) => setSubdomainRollupEnabled(e.target.checked)}
+                        />
+                        <div className="w-11 h-6 bg-gray-200 peer-focus:outline-none peer-focus:ring-4 peer-focus:ring-gray-300 rounded-full peer peer-checked:after:translate-x-full peer-checked:after:border-white after:content-[''] after:absolute after:top-[2px] after:left-[2px] after:bg-white after:border-gray-300 after:border after:rounded-full after:h-5 after:w-5 after:transition-all peer-checked:bg-black"></div>
+                      </label>
+                    </div>
+                  )}
                 </div>
               </div>
 
@@ -482,6 +733,370 @@ const WebsiteEdit: React.FC = () => {
             </form>
           </div>
         </div>
+
+        {/* Data Collection */}
+        <div className="mt-6 bg-white border border-black shadow-sm rounded-lg overflow-hidden">
+          <div className="p-6 flex items-center justify-between gap-4">
+            <div>
+              <h2 className="text-xl font-semibold flex items-center gap-2 mb-2">
+                {website.paused ? <Pause className="w-5 h-5 text-gray-700" /> : <Play className="w-5 h-5 text-gray-700" />}
+                Data Collection {website.paused ? 'Paused' : 'Active'}
+              </h2>
+              <p className="text-sm text-gray-500">
+                {website.paused
+                  ? 'Events sent to this website are dropped. Existing data is kept.'
+                  : 'Pause collection during load tests or maintenance. Existing data is kept and nothing is recorded until you resume.'}
+              </p>
+            </div>
+            <button
+              type="button"
+              onClick={handleTogglePaused}
+              className="px-4 py-2 border border-black shadow-sm text-sm font-medium rounded-md text-black bg-white hover:bg-gray-50 whitespace-nowrap"
+            >
+              {website.paused ? 'Resume' : 'Pause'}
+            </button>
+          </div>
+        </div>
+
+        {/* Stats API Tokens */}
+        <div className="mt-6 bg-white border border-black shadow-sm rounded-lg overflow-hidden">
+          <div className="p-6">
+            <h2 className="text-xl font-semibold flex items-center gap-2 mb-4">
+              <KeyRound className="w-5 h-5 text-gray-700" />
+              API Tokens
+            </h2>
+            <p className="text-sm text-gray-500 mb-4">
+              Tokens grant read-only access to this website's stats via{' '}
+              <code className="text-xs bg-gray-100 px-1 py-0.5 rounded">GET /api/v1/stats?website_id={website.id}</code>{' '}
+              with an <code className="text-xs bg-gray-100 px-1 py-0.5 rounded">Authorization: Bearer &lt;token&gt;</code> header.
+            </p>
+
+            <form className="flex gap-3 mb-4" onSubmit={handleCreateApiToken}>
+              <input
+                type="text"
+                value={apiTokenName}
+                onChange={(e) => setApiTokenName(e.target.value)}
+                placeholder="Token name, e.g. Reporting script"
+                className="flex-1 px-3 py-2 border border-gray-300 rounded-md text-sm focus:outline-none focus:ring-2 focus:ring-black"
+              />
+              <button
+                type="submit"
+                disabled={!apiTokenName.trim()}
+                className="px-4 py-2 border border-transparent shadow-sm text-sm font-medium rounded-md text-white bg-black hover:bg-gray-800 disabled:opacity-70 disabled:cursor-not-allowed"
+              >
+                Create Token
+              </button>
+            </form>
+
+            {api_tokens && api_tokens.length > 0 ? (
+              <ul className="divide-y border rounded-lg">
+                {api_tokens.map(token => (
+                  <li key={token.id} className="flex items-center justify-between p-3">
+                    <div>
+                      <p className="text-sm font-medium">{token.name}</p>
+                      <p className="text-xs text-gray-500">
+                        <code>{token.prefix}…</code> · created {new Date(token.created_at).toLocaleDateString()}
+                        {' · '}
+                        {token.last_used_at ? `last used ${new Date(token.last_used_at).toLocaleDateString()}` : 'never used'}
+                      </p>
+                    </div>
+                    <button
+                      type="button"
+                      onClick={() => handleRevokeApiToken(token.id)}
+                      className="px-3 py-1 text-sm text-red-600 border border-red-200 rounded-md hover:bg-red-50"
+                    >
+                      Revoke
+                    </button>
+                  </li>
+                ))}
+              </ul>
+            ) : (
+              <p className="text-sm text-gray-500">No API tokens yet.</p>
+            )}
+          </div>
+        </div>
+
+        {/* Webhooks */}
+        <div className="mt-6 bg-white border border-black shadow-sm rounded-lg overflow-hidden">
+          <div className="p-6">
+            <h2 className="text-xl font-semibold flex items-center gap-2 mb-4">
+              <WebhookIcon className="w-5 h-5 text-gray-700" />
+              Webhooks
+            </h2>
+            <p className="text-sm text-gray-500 mb-4">
+              Fusionaly POSTs a JSON payload to the URL when the trigger fires. Verify deliveries with the{' '}
+              <code className="text-xs bg-gray-100 px-1 py-0.5 rounded">X-Fusionaly-Signature</code> header, an
+              HMAC-SHA256 of the body keyed by the webhook's signing secret.
+            </p>
+
+            <form className="flex flex-wrap gap-3 mb-4" onSubmit={handleCreateWebhook}>
+              <input
+                type="url"
+                value={webhookUrl}
+                onChange={(e) => setWebhookUrl(e.target.value)}
+                placeholder="https://example.com/hooks/fusionaly"
+                className="flex-1 min-w-[16rem] px-3 py-2 border border-gray-300 rounded-md text-sm focus:outline-none focus:ring-2 focus:ring-black"
+              />
+              <select
+                value={webhookTrigger}
+                onChange={(e) => setWebhookTrigger(e.target.value as Webhook['trigger'])}
+                className="px-3 py-2 border border-gray-300 rounded-md text-sm focus:outline-none focus:ring-2 focus:ring-black"
+              >
+                <option value="goal">Goal completed</option>
+                <option value="daily_visitors">Daily visitors threshold</option>
+              </select>
+              {webhookTrigger === 'goal' ? (
+                <select
+                  value={webhookEventName}
+                  onChange={(e) => setWebhookEventName(e.target.value)}
+                  className="px-3 py-2 border border-gray-300 rounded-md text-sm focus:outline-none focus:ring-2 focus:ring-black"
+                >
+                  <option value="">Any conversion goal</option>
+                  {selectedGoals.map(goal => (
+                    <option key={goal} value={goal}>{goal}</option>
+                  ))}
+                </select>
+              ) : (
+                <input
+                  type="number"
+                  min={1}
+                  value={webhookThreshold}
+                  onChange={(e) => setWebhookThreshold(e.target.value)}
+                  placeholder="Visitors per day"
+                  className="w-40 px-3 py-2 border border-gray-300 rounded-md text-sm focus:outline-none focus:ring-2 focus:ring-black"
+                />
+              )}
+              <button
+                type="submit"
+                disabled={!webhookUrl.trim() || (webhookTrigger === 'daily_visitors' && !webhookThreshold)}
+                className="px-4 py-2 border border-transparent shadow-sm text-sm font-medium rounded-md text-white bg-black hover:bg-gray-800 disabled:opacity-70 disabled:cursor-not-allowed"
+              >
+                Add Webhook
+              </button>
+            </form>
+
+            {webhooks && webhooks.length > 0 ? (
+              <ul className="divide-y border rounded-lg">
+                {webhooks.map(hook => (
+                  <li key={hook.id} className="flex items-center justify-between p-3">
+                    <div className="min-w-0">
+                      <p className="text-sm font-medium truncate">{hook.url}</p>
+                      <p className="text-xs text-gray-500">
+                        {describeWebhook(hook)}
+                        {' · '}
+                        {hook.last_fired_at ? `last fired ${new Date(hook.last_fired_at).toLocaleString()}` : 'never fired'}
+                      </p>
+                    </div>
+                    <button
+                      type="button"
+                      onClick={() => handleDeleteWebhook(hook.id)}
+                      className="px-3 py-1 text-sm text-red-600 border border-red-200 rounded-md hover:bg-red-50"
+                    >
+                      Delete
+                    </button>
+                  </li>
+                ))}
+              </ul>
+            ) : (
+              <p className="text-sm text-gray-500">No webhooks yet.</p>
+            )}
+          </div>
+        </div>
+
+        {/* Shared links */}
+        <div className="mt-6 bg-white border border-black shadow-sm rounded-lg overflow-hidden">
+          <div className="p-6">
+            <h2 className="text-xl font-semibold flex items-center gap-2 mb-4">
+              <LinkIcon className="w-5 h-5 text-gray-700" />
+              Shared Links
+            </h2>
+            <p className="text-sm text-gray-500 mb-4">
+              Read-only dashboard links for people without an admin login. Optionally protect a link with a
+              password, let it expire, or limit it to a comma separated list of metrics
+              (e.g. <code className="text-xs bg-gray-100 px-1 py-0.5 rounded">page_views,top_urls</code>).
+            </p>
+
+            <form className="flex flex-wrap gap-3 mb-4" onSubmit={handleCreateSharedLink}>
+              <input
+                type="password"
+                value={sharedLinkPassword}
+                onChange={(e) => setSharedLinkPassword(e.target.value)}
+                placeholder="Password (optional)"
+                autoComplete="new-password"
+                className="w-48 px-3 py-2 border border-gray-300 rounded-md text-sm focus:outline-none focus:ring-2 focus:ring-black"
+              />
+              <input
+                type="date"
+                value={sharedLinkExpiresAt}
+                onChange={(e) => setSharedLinkExpiresAt(e.target.value)}
+                title="Expires on (optional)"
+                className="px-3 py-2 border border-gray-300 rounded-md text-sm focus:outline-none focus:ring-2 focus:ring-black"
+              />
+              <input
+                type="text"
+                value={sharedLinkMetrics}
+                onChange={(e) => setSharedLinkMetrics(e.target.value)}
+                placeholder="Metrics (optional, all by default)"
+                className="flex-1 min-w-[14rem] px-3 py-2 border border-gray-300 rounded-md text-sm focus:outline-none focus:ring-2 focus:ring-black"
+              />
+              <button
+                type="submit"
+                className="px-4 py-2 border border-transparent shadow-sm text-sm font-medium rounded-md text-white bg-black hover:bg-gray-800"
+              >
+                Create Link
+              </button>
+            </form>
+
+            {shared_links && shared_links.length > 0 ? (
+              <ul className="divide-y border rounded-lg">
+                {shared_links.map(link => (
+                  <li key={link.id} className="flex items-center justify-between p-3">
+                    <div className="min-w-0">
+                      <p className="text-sm font-medium truncate">
+                        {`${window.location.origin}/share/${link.token}`}
+                      </p>
+                      <p className="text-xs text-gray-500">
+                        {link.expires_at ? `expires ${new Date(link.expires_at).toLocaleDateString()}` : 'never expires'}
+                        {' · '}
+                        {link.allowed_metrics ? link.allowed_metrics.split(',').join(', ') : 'all metrics'}
+                      </p>
+                    </div>
+                    <button
+                      type="button"
+                      onClick={() => handleDeleteSharedLink(link.id)}
+                      className="px-3 py-1 text-sm text-red-600 border border-red-200 rounded-md hover:bg-red-50"
+                    >
+                      Revoke
+                    </button>
+                  </li>
+                ))}
+              </ul>
+            ) : (
+              <p className="text-sm text-gray-500">No shared links yet.</p>
+            )}
+          </div>
+        </div>
+
+        {/* Alerts */}
+        <div className="mt-6 bg-white border border-black shadow-sm rounded-lg overflow-hidden">
+          <div className="p-6">
+            <h2 className="text-xl font-semibold flex items-center gap-2 mb-4">
+              <BellRing className="w-5 h-5 text-gray-700" />
+              Alerts
+            </h2>
+            <p className="text-sm text-gray-500 mb-4">
+              Alerts compare today's traffic so far (UTC) against a fixed threshold or a percentage of the
+              average for the same time of day over the previous 7 days, checked every 15 minutes. A fixed
+              "below" threshold is a daily minimum, so it is checked against yesterday's full day and fires
+              at most once a day. After firing, an alert stays quiet for its cool-down.
+            </p>
+
+            <form className="flex flex-wrap gap-3 mb-4" onSubmit={handleCreateAlertRule}>
+              <input
+                type="text"
+                value={alertName}
+                onChange={(e) => setAlertName(e.target.value)}
+                placeholder="Name, e.g. Traffic drop"
+                className="flex-1 min-w-[12rem] px-3 py-2 border border-gray-300 rounded-md text-sm focus:outline-none focus:ring-2 focus:ring-black"
+              />
+              <select
+                value={alertMetric}
+                onChange={(e) => setAlertMetric(e.target.value as AlertRule['metric'])}
+                className="px-3 py-2 border border-gray-300 rounded-md text-sm focus:outline-none focus:ring-2 focus:ring-black"
+              >
+                <option value="visitors">Visitors</option>
+                <option value="page_views">Page views</option>
+                <option value="sessions">Sessions</option>
+                <option value="revenue">Revenue</option>
+              </select>
+              <select
+                value={alertOperator}
+                onChange={(e) => setAlertOperator(e.target.value as AlertRule['operator'])}
+                className="px-3 py-2 border border-gray-300 rounded-md text-sm focus:outline-none focus:ring-2 focus:ring-black"
+              >
+                <option value="lt">below</option>
+                <option value="lte">at or below</option>
+                <option value="gt">above</option>
+                <option value="gte">at or above</option>
+              </select>
+              <input
+                type="number"
+                min={0}
+                step="any"
+                value={alertThreshold}
+                onChange={(e) => setAlertThreshold(e.target.value)}
+                placeholder={alertBaseline === 'rolling_average' ? 'Percent' : 'Threshold'}
+                className="w-28 px-3 py-2 border border-gray-300 rounded-md text-sm focus:outline-none focus:ring-2 focus:ring-black"
+              />
+              <select
+                value={alertBaseline}
+                onChange={(e) => setAlertBaseline(e.target.value as AlertRule['baseline'])}
+                className="px-3 py-2 border border-gray-300 rounded-md text-sm focus:outline-none focus:ring-2 focus:ring-black"
+              >
+                <option value="rolling_average">% of 7-day average</option>
+                <option value="absolute">absolute value</option>
+              </select>
+              <select
+                value={alertChannel}
+                onChange={(e) => setAlertChannel(e.target.value as AlertRule['channel'])}
+                className="px-3 py-2 border border-gray-300 rounded-md text-sm focus:outline-none focus:ring-2 focus:ring-black"
+              >
+                <option value="email">Email</option>
+                <option value="webhook">Webhook</option>
+              </select>
+              <input
+                type={alertChannel === 'email' ? 'email' : 'url'}
+                value={alertTarget}
+                onChange={(e) => setAlertTarget(e.target.value)}
+                placeholder={alertChannel === 'email' ? 'you@example.com' : 'https://example.com/hooks/alerts'}
+                className="flex-1 min-w-[14rem] px-3 py-2 border border-gray-300 rounded-md text-sm focus:outline-none focus:ring-2 focus:ring-black"
+              />
+              <input
+                type="number"
+                min={1}
+                value={alertCooldown}
+                onChange={(e) => setAlertCooldown(e.target.value)}
+                title="Cool-down in minutes"
+                className="w-28 px-3 py-2 border border-gray-300 rounded-md text-sm focus:outline-none focus:ring-2 focus:ring-black"
+              />
+              <button
+                type="submit"
+                disabled={!alertName.trim() || !alertThreshold || !alertTarget.trim()}
+                className="px-4 py-2 border border-transparent shadow-sm text-sm font-medium rounded-md text-white bg-black hover:bg-gray-800 disabled:opacity-70 disabled:cursor-not-allowed"
+              >
+                Add Alert
+              </button>
+            </form>
+
+            {alert_rules && alert_rules.length > 0 ? (
+              <ul className="divide-y border rounded-lg">
+                {alert_rules.map(rule => (
+                  <li key={rule.id} className="flex items-center justify-between p-3">
+                    <div className="min-w-0">
+                      <p className="text-sm font-medium truncate">{rule.name}</p>
+                      <p className="text-xs text-gray-500">
+                        {describeAlertRule(rule)}
+                        {' · '}
+                        {rule.channel === 'email' ? `email ${rule.target}` : `webhook ${rule.target}`}
+                        {' · '}
+                        {rule.last_fired_at ? `last fired ${new Date(rule.last_fired_at).toLocaleString()}` : 'never fired'}
+                      </p>
+                    </div>
+                    <button
+                      type="button"
+                      onClick={() => handleDeleteAlertRule(rule.id)}
+                      className="px-3 py-1 text-sm text-red-600 border border-red-200 rounded-md hover:bg-red-50"
+                    >
+                      Delete
+                    </button>
+                  </li>
+                ))}
+              </ul>
+            ) : (
+              <p className="text-sm text-gray-500">No alerts yet.</p>
+            )}
+          </div>
+        </div>
       </div>
     </AdminLayout>
   );
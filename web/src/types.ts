@@ -22,9 +22,37 @@ export interface DataItem {
   count: number;
 }
 
+export interface UTMConversionResult extends DataItem {
+  conversions: number;
+}
+
+export interface UTMCombinationResult extends DataItem {
+  source: string;
+  medium: string;
+  campaign: string;
+  page_views: number;
+}
+
+export interface EntryExitPair {
+  entry: string;
+  exit: string;
+  count: number;
+}
+
+export type AggOp = "sum" | "avg" | "min" | "max";
+
+export interface NumericAggregation {
+  event_name: string;
+  meta_key: string;
+  op: AggOp;
+  value: number;
+  events: number;
+}
+
 export interface PageViewData {
   date: string;
   count: number;
+  change_pct?: number; // vs the same comparison bucket, only in comparison series
 }
 
 export interface MetricCountResult {
@@ -32,6 +60,12 @@ export interface MetricCountResult {
   count: number;
 }
 
+export interface RevenueSourceResult {
+  name: string;
+  revenue: number;
+  sales: number;
+}
+
 export interface RevenueMetrics {
   total_revenue: number;
   total_sales: number;
@@ -46,7 +80,25 @@ export interface ComparisonMetrics {
   sessions_change?: number;
   bounce_rate_change?: number;
   avg_time_change?: number;
+  pages_per_session_change?: number;
   revenue_change?: number;
+  top_lists?: ComparisonTopLists;
+  page_views?: PageViewData[];
+  visitors?: PageViewData[];
+}
+
+export interface TopListChange {
+  name: string;
+  current: number;
+  previous: number;
+  change?: number; // percent; absent for new items
+  new?: boolean;
+  gone?: boolean;
+}
+
+export interface ComparisonTopLists {
+  referrers: TopListChange[];
+  urls: TopListChange[];
 }
 
 export interface UserFlowLink {
@@ -62,26 +114,58 @@ export interface AnalyticsData {
   revenue: PageViewData[];
   top_urls: MetricCountResult[];
   top_countries: MetricCountResult[];
+  top_regions?: MetricCountResult[];
+  top_cities?: MetricCountResult[];
+  top_languages?: MetricCountResult[];
   top_devices: MetricCountResult[];
+  top_device_models?: MetricCountResult[];
+  top_screen_sizes?: MetricCountResult[];
+  traffic_heatmap?: number[][] | null;
   top_referrers: MetricCountResult[];
+  traffic_channels?: MetricCountResult[];
   top_browsers: MetricCountResult[];
+  top_browser_versions?: MetricCountResult[];
   top_operating_systems: MetricCountResult[];
   top_custom_events: MetricCountResult[];
+  top_downloads?: MetricCountResult[];
   event_revenue_totals?: Record<string, number>;
   event_conversion_rates?: Record<string, number>;
+  goal_conversion_rates?: Record<string, number>;
   bounce_rate: number;
   visits_duration: number;
+  pages_per_session?: number;
   revenue_per_visitor: number;
+  average_order_value?: number;
   top_entry_pages: MetricCountResult[];
   top_exit_pages: MetricCountResult[];
+  top_404s?: MetricCountResult[];
+  entry_exit_pairs?: EntryExitPair[];
   top_utm_sources: MetricCountResult[];
   top_utm_mediums: MetricCountResult[];
   top_utm_campaigns: MetricCountResult[];
   top_utm_terms: MetricCountResult[];
   top_utm_contents: MetricCountResult[];
+  utm_term_conversions?: UTMConversionResult[];
+  utm_content_conversions?: UTMConversionResult[];
+  top_utm_combinations?: UTMCombinationResult[];
   top_ref_params: MetricCountResult[];
-  bucket_size: "hour" | "day" | "week" | "month" | "year";
+  top_query_params?: Record<string, MetricCountResult[]>;
+  track_params?: string[];
+  property_keys?: string[];
+  property_key?: string;
+  top_property_values?: MetricCountResult[];
+  top_subdomains?: MetricCountResult[];
+  metric_event?: string;
+  metric_key?: string;
+  metric_op?: AggOp;
+  metric_keys?: string[];
+  metric_aggregation?: NumericAggregation;
+  bucket_size: "minute" | "hour" | "day" | "week" | "month" | "quarter" | "year";
   total_visitors?: number;
+  new_visitors?: number;
+  returning_visitors?: number;
+  new_visitors_series?: PageViewData[];
+  returning_visitors_series?: PageViewData[];
   total_views?: number;
   total_sessions?: number;
   total_entry_count?: number;
@@ -89,10 +173,15 @@ export interface AnalyticsData {
   total_custom_events?: number;
   revenue_metrics?: RevenueMetrics;
   top_revenue_events?: MetricCountResult[];
+  revenue_by_referrer?: RevenueSourceResult[];
+  revenue_by_utm_source?: RevenueSourceResult[];
   conversion_goals: string[];
   insights: Insight[];
   comparison?: ComparisonMetrics;
   user_flow?: UserFlowLink[];
+  filters?: Record<string, string>;
+  unscoped_panels?: string[];
+  hidden_panels?: string[];
 }
 
 export interface TimeRange {
@@ -164,7 +253,7 @@ export interface SettingsResponse {
 }
 
 // Types for ReferrersCard component
-export type MetricType = 'referrers' | 'utm_sources' | 'utm_mediums' | 'utm_campaigns' | 'utm_terms' | 'utm_contents' | 'ref_params';
+export type MetricType = 'referrers' | 'utm_sources' | 'utm_mediums' | 'utm_campaigns' | 'utm_terms' | 'utm_contents' | 'utm_combinations' | 'ref_params' | 'query_params';
 
 export interface ReferrersCardProps {
   data: {
@@ -174,8 +263,15 @@ export interface ReferrersCardProps {
     top_utm_campaigns: DataItem[];
     top_utm_terms: DataItem[];
     top_utm_contents: DataItem[];
+    utm_term_conversions?: UTMConversionResult[];
+    utm_content_conversions?: UTMConversionResult[];
+    top_utm_combinations?: UTMCombinationResult[];
     top_ref_params: DataItem[];
+    top_query_params?: Record<string, DataItem[]>;
+    track_params?: string[];
   };
+  onFilter?: (key: string, value: string) => void;
+  unscopedNote?: (panel: string) => string | undefined;
 }
 
 // Website related types